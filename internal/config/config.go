@@ -68,6 +68,14 @@ type SyncConfig struct {
 	RetryDelay     time.Duration `mapstructure:"retry_delay"`
 	JobTimeout     time.Duration `mapstructure:"job_timeout"`
 	CleanupAge     time.Duration `mapstructure:"cleanup_age"`
+
+	// AllowRawWhereClause lets table mappings select rows with a raw SQL where_clause. It is
+	// concatenated into queries as is, so only enable it when every user creating mappings is trusted.
+	AllowRawWhereClause bool `mapstructure:"allow_raw_where_clause"`
+
+	// MaskingSecret keys the hash, fake and date_shift masking strategies. Jobs masking columns with
+	// them fail while it is empty; set it from DBT_SYNC_MASKING_SECRET rather than a config file.
+	MaskingSecret string `mapstructure:"masking_secret"`
 }
 
 // LoadOptions contains options for loading configuration
@@ -199,4 +207,6 @@ func setDefaults() {
 	viper.SetDefault("sync.retry_delay", "30s")
 	viper.SetDefault("sync.job_timeout", "1h")
 	viper.SetDefault("sync.cleanup_age", "720h")
+	viper.SetDefault("sync.allow_raw_where_clause", false)
+	viper.SetDefault("sync.masking_secret", "")
 }
@@ -0,0 +1,23 @@
+-- Version: 5
+-- Name: sync_config_schedule_state
+-- Description: Track scheduler state (last/next run) on sync_configs so cron schedules survive restarts
+
+-- Add last_run_at column if it doesn't exist
+SET @exist := (SELECT COUNT(*) FROM information_schema.columns
+               WHERE table_schema = DATABASE()
+                 AND table_name = 'sync_configs'
+                 AND column_name = 'last_run_at');
+SET @sqlstmt := IF(@exist = 0, 'ALTER TABLE `sync_configs` ADD COLUMN `last_run_at` TIMESTAMP NULL DEFAULT NULL AFTER `options`', 'SELECT 1');
+PREPARE stmt FROM @sqlstmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- Add next_run_at column if it doesn't exist
+SET @exist := (SELECT COUNT(*) FROM information_schema.columns
+               WHERE table_schema = DATABASE()
+                 AND table_name = 'sync_configs'
+                 AND column_name = 'next_run_at');
+SET @sqlstmt := IF(@exist = 0, 'ALTER TABLE `sync_configs` ADD COLUMN `next_run_at` TIMESTAMP NULL DEFAULT NULL AFTER `last_run_at`', 'SELECT 1');
+PREPARE stmt FROM @sqlstmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
@@ -0,0 +1,9 @@
+-- Version: 6
+-- Name: sync_mode_cdc
+-- Description: Allow the binlog-based 'cdc' sync mode on sync_configs and table_mappings
+
+ALTER TABLE `sync_configs`
+    MODIFY COLUMN `sync_mode` ENUM('full', 'incremental', 'cdc') NOT NULL DEFAULT 'full';
+
+ALTER TABLE `table_mappings`
+    MODIFY COLUMN `sync_mode` ENUM('full', 'incremental', 'cdc') NOT NULL DEFAULT 'full';
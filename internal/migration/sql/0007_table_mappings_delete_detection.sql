@@ -0,0 +1,6 @@
+-- Version: 7
+-- Name: table_mappings_delete_detection
+-- Description: Per-mapping delete propagation for incremental sync (hard, soft or report-only)
+ALTER TABLE `table_mappings`
+ADD COLUMN `delete_detection` VARCHAR(16) NOT NULL DEFAULT '' AFTER `sort_order`,
+ADD COLUMN `soft_delete_column` VARCHAR(64) NOT NULL DEFAULT '' AFTER `delete_detection`;
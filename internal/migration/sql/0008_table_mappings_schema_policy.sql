@@ -0,0 +1,6 @@
+-- Version: 8
+-- Name: table_mappings_schema_policy
+-- Description: Per-mapping policy for evolving the target schema when the source table changes
+ALTER TABLE `table_mappings`
+ADD COLUMN `schema_policy` VARCHAR(16) NOT NULL DEFAULT '' AFTER `soft_delete_column`,
+ADD COLUMN `approve_schema_changes` BOOLEAN NOT NULL DEFAULT FALSE AFTER `schema_policy`;
@@ -0,0 +1,16 @@
+-- Version: 9
+-- Name: column_mapping_rules
+-- Description: Per-mapping column rules (include/exclude, rename, constant and expression columns)
+CREATE TABLE IF NOT EXISTS `column_mapping_rules` (
+`id` VARCHAR(36) PRIMARY KEY,
+`table_mapping_id` VARCHAR(36) NOT NULL,
+`rule_type` VARCHAR(16) NOT NULL,
+`source_column` VARCHAR(64) NOT NULL DEFAULT '',
+`target_column` VARCHAR(64) NOT NULL DEFAULT '',
+`value` TEXT NOT NULL,
+`column_type` VARCHAR(64) NOT NULL DEFAULT '',
+`sort_order` INT NOT NULL DEFAULT 0,
+`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+FOREIGN KEY (`table_mapping_id`) REFERENCES `table_mappings`(`id`) ON DELETE CASCADE,
+INDEX `idx_column_mapping_rules_table_mapping` (`table_mapping_id`, `sort_order`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,17 @@
+-- Version: 10
+-- Name: column_masking_rules
+-- Description: Per-mapping column masking rules (redact, nullify, fake, hash, partial and date shift)
+CREATE TABLE IF NOT EXISTS `column_masking_rules` (
+`id` VARCHAR(36) PRIMARY KEY,
+`table_mapping_id` VARCHAR(36) NOT NULL,
+`column_name` VARCHAR(64) NOT NULL,
+`strategy` VARCHAR(16) NOT NULL,
+`replacement` VARCHAR(255) NOT NULL DEFAULT '',
+`keep_first` INT NOT NULL DEFAULT 0,
+`keep_last` INT NOT NULL DEFAULT 0,
+`shift_days` INT NOT NULL DEFAULT 0,
+`sort_order` INT NOT NULL DEFAULT 0,
+`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+FOREIGN KEY (`table_mapping_id`) REFERENCES `table_mappings`(`id`) ON DELETE CASCADE,
+INDEX `idx_column_masking_rules_table_mapping` (`table_mapping_id`, `sort_order`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,32 @@
+-- Version: 11
+-- Name: verify_jobs
+-- Description: Verify jobs comparing source and target tables in checksummed chunks, with per-table reports
+ALTER TABLE `sync_jobs`
+ADD COLUMN `job_type` VARCHAR(16) NOT NULL DEFAULT 'sync',
+ADD COLUMN `verify_repair` VARCHAR(16) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS `table_verify_reports` (
+`id` VARCHAR(36) PRIMARY KEY,
+`job_id` VARCHAR(36) NOT NULL,
+`table_mapping_id` VARCHAR(36) NOT NULL,
+`source_table` VARCHAR(255) NOT NULL,
+`target_table` VARCHAR(255) NOT NULL,
+`status` VARCHAR(16) NOT NULL,
+`chunks` BIGINT NOT NULL DEFAULT 0,
+`mismatched_chunks` BIGINT NOT NULL DEFAULT 0,
+`source_rows` BIGINT NOT NULL DEFAULT 0,
+`target_rows` BIGINT NOT NULL DEFAULT 0,
+`missing_rows` BIGINT NOT NULL DEFAULT 0,
+`extra_rows` BIGINT NOT NULL DEFAULT 0,
+`different_rows` BIGINT NOT NULL DEFAULT 0,
+`repaired_rows` BIGINT NOT NULL DEFAULT 0,
+`skipped_columns` TEXT,
+`repair_sql` LONGTEXT,
+`repair_truncated` BOOLEAN NOT NULL DEFAULT FALSE,
+`error_message` TEXT,
+`started_at` TIMESTAMP NULL,
+`finished_at` TIMESTAMP NULL,
+`created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+FOREIGN KEY (`job_id`) REFERENCES `sync_jobs`(`id`) ON DELETE CASCADE,
+INDEX `idx_table_verify_reports_job` (`job_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
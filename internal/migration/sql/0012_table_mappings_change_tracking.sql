@@ -0,0 +1,6 @@
+-- Version: 12
+-- Name: table_mappings_change_tracking
+-- Description: Per-mapping change tracking column and look-back window for incremental sync
+ALTER TABLE `table_mappings`
+ADD COLUMN `tracking_column` VARCHAR(64) NOT NULL DEFAULT '' AFTER `approve_schema_changes`,
+ADD COLUMN `lookback_seconds` INT NOT NULL DEFAULT 0 AFTER `tracking_column`;
@@ -0,0 +1,5 @@
+-- Version: 13
+-- Name: table_mappings_row_filter
+-- Description: Structured row filters of table mappings, stored as JSON
+ALTER TABLE `table_mappings`
+ADD COLUMN `row_filter` TEXT NULL AFTER `where_clause`;
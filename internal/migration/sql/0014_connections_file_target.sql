@@ -0,0 +1,7 @@
+-- Version: 14
+-- Name: connections_file_target
+-- Description: File connections, a local directory tables are exported to as CSV, JSON Lines or Parquet files
+ALTER TABLE `connections`
+ADD COLUMN `connection_type` VARCHAR(16) NOT NULL DEFAULT 'mysql' AFTER `name`,
+ADD COLUMN `path` VARCHAR(1024) NOT NULL DEFAULT '' AFTER `ssl`,
+ADD COLUMN `file_options` TEXT NULL AFTER `path`;
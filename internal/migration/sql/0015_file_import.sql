@@ -0,0 +1,18 @@
+-- Version: 15
+-- Name: file_import
+-- Description: File sources: table mappings loading CSV and JSON Lines files, and the files each mapping has loaded
+ALTER TABLE `table_mappings`
+ADD COLUMN `file_source` TEXT NULL AFTER `row_filter`;
+
+CREATE TABLE IF NOT EXISTS `imported_files` (
+`table_mapping_id` VARCHAR(36) NOT NULL,
+`file_name` VARCHAR(512) NOT NULL,
+`sha256` CHAR(64) NOT NULL,
+`file_size` BIGINT NOT NULL DEFAULT 0,
+`loaded_rows` BIGINT NOT NULL DEFAULT 0,
+`rejected_rows` BIGINT NOT NULL DEFAULT 0,
+`job_id` VARCHAR(36) NOT NULL DEFAULT '',
+`imported_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+PRIMARY KEY (`table_mapping_id`, `file_name`, `sha256`),
+FOREIGN KEY (`table_mapping_id`) REFERENCES `table_mappings`(`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- Version: 16
+-- Name: table_mappings_write_mode
+-- Description: Per-mapping write mode and the columns upsert write modes update
+ALTER TABLE `table_mappings`
+ADD COLUMN `write_mode` VARCHAR(16) NOT NULL DEFAULT '' AFTER `lookback_seconds`,
+ADD COLUMN `update_columns` TEXT NULL AFTER `write_mode`;
@@ -195,10 +195,13 @@ func (s *Server) registerRoutes() {
 					configs.GET("/:id/mappings", s.getTableMappings)
 					configs.POST("/:id/mappings", s.addTableMapping)
 					configs.PUT("/:id/mappings/reorder", s.reorderTableMappings)
+					configs.POST("/:id/mappings/sort-by-dependencies", s.sortTableMappingsByDependencies)
 					configs.PUT("/:id/mappings/:mapping_id", s.updateTableMapping)
 					configs.DELETE("/:id/mappings/:mapping_id", s.removeTableMapping)
 					configs.POST("/:id/mappings/:mapping_id/toggle", s.toggleTableMapping)
 					configs.POST("/:id/mappings/:mapping_id/sync-mode", s.setTableSyncMode)
+					configs.POST("/:id/verify", s.verifySyncConfig)
+					configs.POST("/:id/plan", s.planSync)
 				}
 
 				// Job management routes
@@ -212,6 +215,7 @@ func (s *Server) registerRoutes() {
 					jobs.POST("/:id/cancel", s.cancelSyncJob)
 					jobs.GET("/:id/logs", s.getSyncJobLogs)
 					jobs.GET("/:id/progress", s.getSyncJobProgress)
+					jobs.GET("/:id/verify", s.getSyncJobVerifyReports)
 					jobs.GET("/active", s.getActiveSyncJobs)
 					jobs.GET("/history", s.getSyncJobHistory)
 				}
@@ -1290,6 +1294,58 @@ func (s *Server) reorderTableMappings(c *gin.Context) {
 	})
 }
 
+func (s *Server) sortTableMappingsByDependencies(c *gin.Context) {
+	if s.syncManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Sync system not available",
+		})
+		return
+	}
+
+	configID := c.Param("id")
+	order, err := s.syncManager.GetSyncManager().SortTableMappingsByDependencies(c.Request.Context(), configID)
+	if err != nil {
+		s.logger.WithError(err).WithField("config_id", configID).Error("Failed to sort table mappings by dependencies")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    order,
+	})
+}
+
+func (s *Server) planSync(c *gin.Context) {
+	if s.syncManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Sync system not available",
+		})
+		return
+	}
+
+	configID := c.Param("id")
+	plan, err := s.syncManager.GetSyncManager().PlanSync(c.Request.Context(), configID)
+	if err != nil {
+		s.logger.WithError(err).WithField("config_id", configID).Error("Failed to plan sync")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plan,
+	})
+}
+
 // Job management handlers
 
 func (s *Server) getSyncJobs(c *gin.Context) {
@@ -1374,6 +1430,85 @@ func (s *Server) startSyncJob(c *gin.Context) {
 	})
 }
 
+// verifySyncConfig starts a job comparing the config's target tables with their source tables
+func (s *Server) verifySyncConfig(c *gin.Context) {
+	if s.syncManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Sync system not available",
+		})
+		return
+	}
+
+	configID := c.Param("id")
+	var request struct {
+		Repair sync.VerifyRepair `json:"repair"`
+	}
+
+	// The body is optional, an empty one verifies without repairing
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	switch request.Repair {
+	case sync.VerifyRepairNone, sync.VerifyRepairEmit, sync.VerifyRepairApply:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid repair mode, must be empty, emit or apply",
+		})
+		return
+	}
+
+	job, err := s.syncManager.GetSyncManager().StartVerify(c.Request.Context(), configID, request.Repair)
+	if err != nil {
+		s.logger.WithError(err).WithField("config_id", configID).Error("Failed to start verify job")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// getSyncJobVerifyReports returns the per-table reports of a verify job
+func (s *Server) getSyncJobVerifyReports(c *gin.Context) {
+	if s.syncManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "Sync system not available",
+		})
+		return
+	}
+
+	jobID := c.Param("id")
+	reports, err := s.syncManager.GetSyncManager().GetVerifyReports(c.Request.Context(), jobID)
+	if err != nil {
+		s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to get verify reports")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reports,
+	})
+}
+
 func (s *Server) getSyncJob(c *gin.Context) {
 	if s.syncManager == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
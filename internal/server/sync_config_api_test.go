@@ -123,6 +123,38 @@ func (m *MockSyncManagerService) StartSync(ctx context.Context, configID string)
 	return args.Get(0).(*sync.SyncJob), args.Error(1)
 }
 
+func (m *MockSyncManagerService) SortTableMappingsByDependencies(ctx context.Context, syncConfigID string) (*sync.TableDependencyOrder, error) {
+	args := m.Called(ctx, syncConfigID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sync.TableDependencyOrder), args.Error(1)
+}
+
+func (m *MockSyncManagerService) PlanSync(ctx context.Context, configID string) (*sync.SyncPlan, error) {
+	args := m.Called(ctx, configID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sync.SyncPlan), args.Error(1)
+}
+
+func (m *MockSyncManagerService) StartVerify(ctx context.Context, configID string, repair sync.VerifyRepair) (*sync.SyncJob, error) {
+	args := m.Called(ctx, configID, repair)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sync.SyncJob), args.Error(1)
+}
+
+func (m *MockSyncManagerService) GetVerifyReports(ctx context.Context, jobID string) ([]*sync.TableVerifyReport, error) {
+	args := m.Called(ctx, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*sync.TableVerifyReport), args.Error(1)
+}
+
 func (m *MockSyncManagerService) StopSync(ctx context.Context, jobID string) error {
 	args := m.Called(ctx, jobID)
 	return args.Error(0)
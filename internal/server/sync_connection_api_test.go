@@ -132,6 +132,22 @@ func (m *mockSyncManager) StartSync(ctx context.Context, configID string) (*sync
 	return nil, nil
 }
 
+func (m *mockSyncManager) SortTableMappingsByDependencies(ctx context.Context, syncConfigID string) (*sync.TableDependencyOrder, error) {
+	return nil, nil
+}
+
+func (m *mockSyncManager) PlanSync(ctx context.Context, configID string) (*sync.SyncPlan, error) {
+	return nil, nil
+}
+
+func (m *mockSyncManager) StartVerify(ctx context.Context, configID string, repair sync.VerifyRepair) (*sync.SyncJob, error) {
+	return nil, nil
+}
+
+func (m *mockSyncManager) GetVerifyReports(ctx context.Context, jobID string) ([]*sync.TableVerifyReport, error) {
+	return nil, nil
+}
+
 func (m *mockSyncManager) StopSync(ctx context.Context, jobID string) error {
 	return nil
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
@@ -232,13 +231,11 @@ func (bp *BatchProcessor) calculateOptimalBatchSize(totalRows int64) int {
 
 // getRowCount gets the total row count for a table
 func (bp *BatchProcessor) getRowCount(ctx context.Context, remoteDB *sqlx.DB, mapping *TableMapping) (int64, error) {
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		countQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`%s", mapping.SourceTable, filter.where())
 
 	var count int64
-	if err := remoteDB.GetContext(ctx, &count, countQuery); err != nil {
+	if err := remoteDB.GetContext(ctx, &count, countQuery, filter.args...); err != nil {
 		return 0, err
 	}
 
@@ -271,13 +268,11 @@ func (bp *BatchProcessor) fetchBatch(
 	offset int64,
 	limit int,
 ) ([]map[string]interface{}, error) {
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	selectQuery := fmt.Sprintf("SELECT * FROM `%s`%s", mapping.SourceTable, filter.where())
 	selectQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery)
+	rows, err := remoteDB.QueryxContext(ctx, selectQuery, filter.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -308,42 +303,13 @@ func (bp *BatchProcessor) insertBatchOptimized(
 		return nil
 	}
 
-	// Build optimized INSERT statement with multi-row VALUES
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	// Add column names
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
-
-	sb.WriteString(") VALUES ")
-
-	// Add value placeholders
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
 	// Execute with timeout to prevent long locks
 	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	if _, err := bp.localDB.ExecContext(execCtx, sb.String(), args...); err != nil {
+	if _, err := bp.localDB.ExecContext(execCtx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch insert: %w", err)
 	}
 
@@ -378,12 +344,13 @@ func (bp *BatchProcessor) ProcessIncrementalBatch(
 
 	// Build query for changed records
 	selectQuery := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` > ?", mapping.SourceTable, changeColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	filter := mapping.rowFilter()
+	if filter.clause != "" {
+		selectQuery += fmt.Sprintf(" AND (%s)", filter.clause)
 	}
 	selectQuery += fmt.Sprintf(" ORDER BY `%s`", changeColumn)
 
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery, lastSyncValue)
+	rows, err := remoteDB.QueryxContext(ctx, selectQuery, append([]interface{}{lastSyncValue}, filter.args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query changed data: %w", err)
 	}
@@ -488,72 +455,18 @@ func (bp *BatchProcessor) upsertBatchOptimized(
 		return nil
 	}
 
-	conflictResolution := ConflictResolutionOverwrite
-	if options != nil {
-		conflictResolution = options.ConflictResolution
-	}
+	conflictResolution := options.conflictResolution()
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
-	sb.WriteString(") VALUES ")
-
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
-
-	// Add conflict resolution
-	switch conflictResolution {
-	case ConflictResolutionOverwrite:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		first := true
-		for _, col := range columns {
-			isPrimaryKey := false
-			for _, pk := range primaryKeys {
-				if col == pk {
-					isPrimaryKey = true
-					break
-				}
-			}
-			if isPrimaryKey {
-				continue
-			}
-
-			if !first {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(fmt.Sprintf("`%s` = VALUES(`%s`)", col, col))
-			first = false
-		}
-	case ConflictResolutionSkip:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		sb.WriteString(fmt.Sprintf("`%s` = `%s`", primaryKeys[0], primaryKeys[0]))
-	}
+	// Add conflict resolution clause
+	query += conflictClause(localDialect, columns, primaryKeys, conflictResolution)
 
 	// Execute with timeout
 	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	if _, err := bp.localDB.ExecContext(execCtx, sb.String(), args...); err != nil {
+	if _, err := bp.localDB.ExecContext(execCtx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch upsert: %w", err)
 	}
 
@@ -579,6 +492,16 @@ func (wp *WorkerPool) Acquire() {
 	wp.semaphore <- struct{}{}
 }
 
+// AcquireUntil acquires a worker slot unless done is closed first, and reports whether it did
+func (wp *WorkerPool) AcquireUntil(done <-chan struct{}) bool {
+	select {
+	case wp.semaphore <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
 // Release releases a worker slot
 func (wp *WorkerPool) Release() {
 	<-wp.semaphore
@@ -0,0 +1,198 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	adaptiveBatchLatency    = time.Second // Write latency batch sizes are steered towards
+	maxAdaptiveBatchSize    = 50000       // Rows of the largest batch, however narrow the rows
+	maxAdaptiveBatchBytes   = 32 << 20    // Bytes of the largest batch, however large max_allowed_packet is
+	defaultMaxAllowedPacket = 4 << 20     // max_allowed_packet assumed when the target doesn't tell
+	errNetPacketTooLarge    = 1153        // ER_NET_PACKET_TOO_LARGE
+)
+
+// batchSizer sizes the batches of a table sync by their estimated bytes and the latency of their
+// writes: batches grow while writes are fast, shrink when they are slow, and stay under half of the
+// target's max_allowed_packet. A nil batchSizer keeps the configured batch size.
+type batchSizer struct {
+	mu       sync.Mutex
+	size     int            // Rows of the next batch
+	maxBytes int64          // Estimated bytes a batch stays under
+	rowBytes int64          // Moving average of the estimated bytes of a row, 0 until a batch is written
+	report   func(size int) // Records a new batch size
+}
+
+// newBatchSizer returns a batch sizer starting at size rows for a target with the given max_allowed_packet
+func newBatchSizer(size int, maxAllowedPacket int64, report func(size int)) *batchSizer {
+	// Half of the packet is left to the statement text and the encoding of the values
+	maxBytes := maxAllowedPacket / 2
+	if maxBytes <= 0 || maxBytes > maxAdaptiveBatchBytes {
+		maxBytes = maxAdaptiveBatchBytes
+	}
+	s := &batchSizer{size: clampBatchSize(size), maxBytes: maxBytes, report: report}
+	if s.report != nil {
+		s.report(s.size)
+	}
+	return s
+}
+
+// resolveBatchSizer returns the batch sizer of a table sync with adaptive batch sizing, nil to keep
+// the configured batch size. Batches stay under the statement size the target dialect allows.
+func (e *DefaultSyncEngine) resolveBatchSizer(ctx context.Context, targetDB *sqlx.DB, d Dialect, tableName string, options *SyncOptions) *batchSizer {
+	if options == nil || !options.AdaptiveBatchSize {
+		return nil
+	}
+
+	maxAllowedPacket, err := d.MaxStatementSize(ctx, targetDB)
+	if err != nil {
+		e.logger.WithError(err).WithField("source_table", tableName).
+			Warn("Failed to read max_allowed_packet of the target database, assuming the default")
+		maxAllowedPacket = defaultMaxAllowedPacket
+	}
+
+	size := 1000
+	if options.BatchSize > 0 {
+		size = options.BatchSize
+	}
+	return newBatchSizer(size, maxAllowedPacket, func(size int) {
+		ReportTableBatchSize(ctx, tableName, size)
+	})
+}
+
+// next returns how many rows the next batch reads, fallback without a sizer
+func (s *batchSizer) next(fallback int) int {
+	if s == nil {
+		return fallback
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// write writes rows with write and adjusts the size of the next batches to how it went. Rows
+// the target refuses as larger than max_allowed_packet are split in halves, which are written instead.
+func (s *batchSizer) write(rows [][]interface{}, write func(rows [][]interface{}) error) error {
+	if s == nil {
+		return write(rows)
+	}
+
+	start := time.Now()
+	err := write(rows)
+	bytes := estimateBatchBytes(rows)
+	if isPacketTooLarge(err) && len(rows) > 1 {
+		s.shrinkBelow(bytes)
+		half := len(rows) / 2
+		if err := s.write(rows[:half], write); err != nil {
+			return err
+		}
+		return s.write(rows[half:], write)
+	}
+	if err == nil {
+		s.observe(len(rows), bytes, time.Since(start))
+	}
+	return err
+}
+
+// observe adjusts the batch size to a batch written in elapsed
+func (s *batchSizer) observe(rows int, bytes int64, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rows > 0 {
+		avg := bytes / int64(rows)
+		if s.rowBytes == 0 {
+			s.rowBytes = avg
+		} else {
+			s.rowBytes = (3*s.rowBytes + avg) / 4
+		}
+	}
+
+	size := s.size
+	switch {
+	case elapsed > adaptiveBatchLatency:
+		size /= 2
+	case elapsed < adaptiveBatchLatency/4 && rows >= s.size:
+		// Only a full batch tells that a larger one would be fast too
+		size += size/2 + 1
+	}
+	s.resize(size)
+}
+
+// shrinkBelow lowers the bytes of a batch under those of a batch the target refused
+func (s *batchSizer) shrinkBelow(bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bytes/2 < s.maxBytes {
+		s.maxBytes = bytes / 2
+	}
+	s.resize(s.size / 2)
+}
+
+// resize sets the batch size, within the limits and the bytes a batch stays under
+func (s *batchSizer) resize(size int) {
+	size = clampBatchSize(size)
+	if s.rowBytes > 0 && int64(size)*s.rowBytes > s.maxBytes {
+		size = clampBatchSize(int(s.maxBytes / s.rowBytes))
+	}
+	if size == s.size {
+		return
+	}
+	s.size = size
+	if s.report != nil {
+		s.report(size)
+	}
+}
+
+// clampBatchSize returns size within 1 and maxAdaptiveBatchSize
+func clampBatchSize(size int) int {
+	if size < 1 {
+		return 1
+	}
+	if size > maxAdaptiveBatchSize {
+		return maxAdaptiveBatchSize
+	}
+	return size
+}
+
+// estimateBatchBytes estimates the bytes rows take in a write statement
+func estimateBatchBytes(rows [][]interface{}) int64 {
+	var bytes int64
+	for _, row := range rows {
+		for _, value := range row {
+			// Each value has a few bytes of type, length or separator around it
+			bytes += 4
+			switch v := value.(type) {
+			case nil:
+			case []byte:
+				bytes += int64(len(v))
+			case string:
+				bytes += int64(len(v))
+			case time.Time:
+				bytes += 26
+			default:
+				bytes += 8
+			}
+		}
+	}
+	return bytes
+}
+
+// isPacketTooLarge reports whether a write failed for a statement larger than max_allowed_packet
+func isPacketTooLarge(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, mysql.ErrPktTooLarge) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errNetPacketTooLarge
+}
@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sizedRows returns rows of a single column holding width bytes
+func sizedRows(count, width int) [][]interface{} {
+	rows := make([][]interface{}, count)
+	for i := range rows {
+		rows[i] = []interface{}{make([]byte, width)}
+	}
+	return rows
+}
+
+func TestBatchSizer_AdaptsToLatencyAndRowBytes(t *testing.T) {
+	var reported []int
+	sizer := newBatchSizer(100, 1<<20, func(size int) { reported = append(reported, size) })
+	assert.Equal(t, 100, sizer.next(1000))
+
+	// A fast full batch grows the next ones, a fast partial batch doesn't
+	sizer.observe(100, 100*100, 10*time.Millisecond)
+	assert.Equal(t, 151, sizer.next(1000))
+	sizer.observe(20, 20*100, 10*time.Millisecond)
+	assert.Equal(t, 151, sizer.next(1000))
+
+	// A slow batch halves them
+	sizer.observe(151, 151*100, 2*time.Second)
+	assert.Equal(t, 75, sizer.next(1000))
+
+	// Wide rows keep a batch under half of max_allowed_packet
+	sizer.observe(75, 75*100*1024, 10*time.Millisecond)
+	assert.Less(t, sizer.next(1000), 75)
+	assert.LessOrEqual(t, int64(sizer.next(1000))*sizer.rowBytes, int64(512*1024))
+
+	assert.Equal(t, []int{100, 151, 75, sizer.next(1000)}, reported)
+
+	// Without a sizer the configured batch size is kept
+	var none *batchSizer
+	assert.Equal(t, 1000, none.next(1000))
+}
+
+func TestBatchSizer_SplitsBatchesOverThePacketLimit(t *testing.T) {
+	sizer := newBatchSizer(8, 64<<20, nil)
+
+	var written []int
+	write := func(rows [][]interface{}) error {
+		if len(rows) > 2 {
+			return fmt.Errorf("failed to execute batch write: %w", &mysql.MySQLError{Number: 1153, Message: "Got a packet bigger than 'max_allowed_packet' bytes"})
+		}
+		written = append(written, len(rows))
+		return nil
+	}
+
+	require.NoError(t, sizer.write(sizedRows(8, 1024), write))
+	assert.Equal(t, []int{2, 2, 2, 2}, written)
+	assert.Less(t, sizer.next(1000), 8)
+	assert.Less(t, sizer.maxBytes, estimateBatchBytes(sizedRows(4, 1024)))
+
+	// Other errors fail the write as they are
+	err := sizer.write(sizedRows(1, 10), func([][]interface{}) error { return assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestResolveBatchSizer(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+
+	assert.Nil(t, engine.resolveBatchSizer(context.Background(), targetDB, mysqlDialect{}, "orders", &SyncOptions{BatchSize: 500}))
+
+	var reported []int
+	ctx := WithTableBatchSizeReporter(context.Background(), func(tableName string, batchSize int) {
+		assert.Equal(t, "orders", tableName)
+		reported = append(reported, batchSize)
+	})
+	targetMock.ExpectQuery("SELECT @@max_allowed_packet").
+		WillReturnRows(sqlmock.NewRows([]string{"@@max_allowed_packet"}).AddRow(4194304))
+
+	sizer := engine.resolveBatchSizer(ctx, targetDB, mysqlDialect{}, "orders", &SyncOptions{BatchSize: 500, AdaptiveBatchSize: true})
+	require.NotNil(t, sizer)
+	assert.Equal(t, 500, sizer.next(1000))
+	assert.Equal(t, int64(2097152), sizer.maxBytes)
+	assert.Equal(t, []int{500}, reported)
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
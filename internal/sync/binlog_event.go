@@ -0,0 +1,452 @@
+package sync
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// Binlog event types used by the CDC reader (MySQL binary log format v4)
+const (
+	binlogQueryEvent              byte = 2
+	binlogRotateEvent             byte = 4
+	binlogFormatDescriptionEvent  byte = 15
+	binlogXIDEvent                byte = 16
+	binlogTableMapEvent           byte = 19
+	binlogWriteRowsEventV1        byte = 23
+	binlogUpdateRowsEventV1       byte = 24
+	binlogDeleteRowsEventV1       byte = 25
+	binlogHeartbeatEvent          byte = 27
+	binlogWriteRowsEventV2        byte = 30
+	binlogUpdateRowsEventV2       byte = 31
+	binlogDeleteRowsEventV2       byte = 32
+	binlogGTIDEvent               byte = 33
+	binlogTransactionPayloadEvent byte = 40
+)
+
+const (
+	binlogEventHeaderSize  = 19
+	binlogChecksumSize     = 4
+	binlogChecksumAlgCRC32 = 1
+)
+
+// BinlogRowAction is the kind of row change carried by a rows event
+type BinlogRowAction string
+
+const (
+	BinlogRowInsert BinlogRowAction = "insert"
+	BinlogRowUpdate BinlogRowAction = "update"
+	BinlogRowDelete BinlogRowAction = "delete"
+)
+
+// BinlogEventHeader is the common header of every binlog event
+type BinlogEventHeader struct {
+	Timestamp uint32
+	EventType byte
+	ServerID  uint32
+	EventSize uint32
+	LogPos    uint32 // Position of the next event in the binlog file
+	Flags     uint16
+}
+
+// BinlogEvent is a decoded binlog event. Event holds one of the binlog*
+// types below, or nil for event types the CDC reader does not need.
+type BinlogEvent struct {
+	Header BinlogEventHeader
+	Event  interface{}
+}
+
+// binlogRotate announces the binlog file that following events belong to
+type binlogRotate struct {
+	Position uint64
+	NextFile string
+}
+
+// binlogFormat is the format description event that starts every binlog file
+type binlogFormat struct {
+	BinlogVersion     uint16
+	ServerVersion     string
+	PostHeaderLengths []byte
+	ChecksumAlgorithm byte
+}
+
+// binlogQuery carries a statement, e.g. BEGIN, COMMIT or DDL
+type binlogQuery struct {
+	Schema string
+	Query  string
+}
+
+// binlogXID marks the commit of a transaction
+type binlogXID struct {
+	XID uint64
+}
+
+// binlogGTID announces the GTID of the next transaction
+type binlogGTID struct {
+	SID [16]byte
+	GNO int64
+}
+
+// BinlogTableMap describes the table that following rows events refer to
+type BinlogTableMap struct {
+	TableID     uint64
+	Schema      string
+	Table       string
+	ColumnTypes []byte
+	ColumnMeta  []uint16
+	Nullable    []bool
+}
+
+// BinlogRowChange is one changed row. Before is nil for inserts and After is
+// nil for deletes. Columns missing from a row image (binlog_row_image other
+// than FULL) are marked false in the matching Present slice.
+type BinlogRowChange struct {
+	Before        []interface{}
+	After         []interface{}
+	BeforePresent []bool
+	AfterPresent  []bool
+}
+
+// BinlogRowsEvent is a decoded write/update/delete rows event
+type BinlogRowsEvent struct {
+	Action BinlogRowAction
+	Table  *BinlogTableMap
+	Rows   []*BinlogRowChange
+}
+
+// binlogParser decodes raw binlog events. It keeps the state needed across
+// events: the checksum setting announced by the format description event and
+// the table maps referenced by rows events.
+type binlogParser struct {
+	checksum    bool
+	tableIDSize int
+	postHeaders []byte
+	tables      map[uint64]*BinlogTableMap
+}
+
+// newBinlogParser creates a parser. checksum tells whether events carry a
+// CRC32 trailer before the first format description event is seen.
+func newBinlogParser(checksum bool) *binlogParser {
+	return &binlogParser{
+		checksum:    checksum,
+		tableIDSize: 6,
+		tables:      make(map[uint64]*BinlogTableMap),
+	}
+}
+
+// parse decodes a single raw event, header included
+func (p *binlogParser) parse(data []byte) (*BinlogEvent, error) {
+	if len(data) < binlogEventHeaderSize {
+		return nil, fmt.Errorf("binlog event too short: %d bytes", len(data))
+	}
+
+	header := BinlogEventHeader{
+		Timestamp: binary.LittleEndian.Uint32(data[0:]),
+		EventType: data[4],
+		ServerID:  binary.LittleEndian.Uint32(data[5:]),
+		EventSize: binary.LittleEndian.Uint32(data[9:]),
+		LogPos:    binary.LittleEndian.Uint32(data[13:]),
+		Flags:     binary.LittleEndian.Uint16(data[17:]),
+	}
+	if int(header.EventSize) != len(data) {
+		return nil, fmt.Errorf("binlog event size mismatch: header says %d, got %d bytes", header.EventSize, len(data))
+	}
+
+	event := &BinlogEvent{Header: header}
+	body := data[binlogEventHeaderSize:]
+
+	// The format description event decides whether the events after it are checksummed
+	if header.EventType == binlogFormatDescriptionEvent {
+		format, err := p.parseFormatDescription(body)
+		if err != nil {
+			return nil, err
+		}
+		event.Event = format
+		return event, nil
+	}
+
+	if p.checksum {
+		if len(body) < binlogChecksumSize {
+			return nil, fmt.Errorf("binlog event too short for checksum")
+		}
+		expected := binary.LittleEndian.Uint32(data[len(data)-binlogChecksumSize:])
+		if actual := crc32.ChecksumIEEE(data[:len(data)-binlogChecksumSize]); actual != expected {
+			return nil, fmt.Errorf("binlog event checksum mismatch at position %d", header.LogPos)
+		}
+		body = body[:len(body)-binlogChecksumSize]
+	}
+
+	var err error
+	switch header.EventType {
+	case binlogRotateEvent:
+		event.Event, err = parseBinlogRotate(body)
+	case binlogQueryEvent:
+		event.Event, err = p.parseQuery(body)
+	case binlogXIDEvent:
+		if len(body) < 8 {
+			return nil, fmt.Errorf("xid event too short")
+		}
+		event.Event = &binlogXID{XID: binary.LittleEndian.Uint64(body)}
+	case binlogGTIDEvent:
+		event.Event, err = parseBinlogGTID(body)
+	case binlogTableMapEvent:
+		event.Event, err = p.parseTableMap(body)
+	case binlogWriteRowsEventV1, binlogWriteRowsEventV2:
+		event.Event, err = p.parseRows(body, header.EventType, BinlogRowInsert)
+	case binlogUpdateRowsEventV1, binlogUpdateRowsEventV2:
+		event.Event, err = p.parseRows(body, header.EventType, BinlogRowUpdate)
+	case binlogDeleteRowsEventV1, binlogDeleteRowsEventV2:
+		event.Event, err = p.parseRows(body, header.EventType, BinlogRowDelete)
+	case binlogTransactionPayloadEvent:
+		err = fmt.Errorf("compressed binlog transactions are not supported, disable binlog_transaction_compression on the source")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// parseFormatDescription decodes the format description event body
+func (p *binlogParser) parseFormatDescription(body []byte) (*binlogFormat, error) {
+	// binlog version (2) + server version (50) + create timestamp (4) + header length (1)
+	const fixedSize = 2 + 50 + 4 + 1
+	if len(body) < fixedSize {
+		return nil, fmt.Errorf("format description event too short")
+	}
+
+	format := &binlogFormat{
+		BinlogVersion: binary.LittleEndian.Uint16(body),
+		ServerVersion: strings.TrimRight(string(body[2:52]), "\x00"),
+	}
+	if format.BinlogVersion != 4 {
+		return nil, fmt.Errorf("unsupported binlog version %d", format.BinlogVersion)
+	}
+
+	// Since MySQL 5.6.1 the event ends with the checksum algorithm and a checksum
+	rest := body[fixedSize:]
+	if len(rest) >= 5 {
+		format.ChecksumAlgorithm = rest[len(rest)-5]
+		format.PostHeaderLengths = rest[:len(rest)-5]
+	} else {
+		format.PostHeaderLengths = rest
+	}
+
+	p.checksum = format.ChecksumAlgorithm == binlogChecksumAlgCRC32
+	p.postHeaders = format.PostHeaderLengths
+	p.tableIDSize = 6
+	if p.postHeaderLength(binlogTableMapEvent, 8) == 6 {
+		p.tableIDSize = 4
+	}
+	return format, nil
+}
+
+// postHeaderLength returns the post-header length announced for an event type
+func (p *binlogParser) postHeaderLength(eventType byte, fallback int) int {
+	if int(eventType) >= 1 && int(eventType) <= len(p.postHeaders) {
+		return int(p.postHeaders[eventType-1])
+	}
+	return fallback
+}
+
+func parseBinlogRotate(body []byte) (*binlogRotate, error) {
+	if len(body) < 8 {
+		return nil, fmt.Errorf("rotate event too short")
+	}
+	return &binlogRotate{
+		Position: binary.LittleEndian.Uint64(body),
+		NextFile: string(body[8:]),
+	}, nil
+}
+
+func parseBinlogGTID(body []byte) (*binlogGTID, error) {
+	if len(body) < 25 {
+		return nil, fmt.Errorf("gtid event too short")
+	}
+	event := &binlogGTID{GNO: int64(binary.LittleEndian.Uint64(body[17:]))}
+	copy(event.SID[:], body[1:17])
+	return event, nil
+}
+
+// parseQuery decodes a query event; only the schema and statement are kept
+func (p *binlogParser) parseQuery(body []byte) (*binlogQuery, error) {
+	postHeader := p.postHeaderLength(binlogQueryEvent, 13)
+	if len(body) < postHeader || postHeader < 13 {
+		return nil, fmt.Errorf("query event too short")
+	}
+	schemaLen := int(body[8])
+	statusLen := int(binary.LittleEndian.Uint16(body[11:]))
+
+	pos := postHeader + statusLen
+	if len(body) < pos+schemaLen+1 {
+		return nil, fmt.Errorf("query event too short")
+	}
+	return &binlogQuery{
+		Schema: string(body[pos : pos+schemaLen]),
+		Query:  string(body[pos+schemaLen+1:]),
+	}, nil
+}
+
+// parseTableMap decodes a table map event and remembers it for following rows events
+func (p *binlogParser) parseTableMap(body []byte) (*BinlogTableMap, error) {
+	r := &binlogReader{data: body}
+
+	table := &BinlogTableMap{TableID: r.uint(p.tableIDSize)}
+	r.skip(2) // flags
+
+	table.Schema = string(r.bytes(int(r.next())))
+	r.skip(1)
+	table.Table = string(r.bytes(int(r.next())))
+	r.skip(1)
+
+	columnCount := int(r.lenencInt())
+	table.ColumnTypes = append([]byte(nil), r.bytes(columnCount)...)
+	metaBlock := r.bytes(int(r.lenencInt()))
+	nullBitmap := r.bytes((columnCount + 7) / 8)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode table map event: %w", r.err)
+	}
+
+	meta, err := parseBinlogColumnMeta(table.ColumnTypes, metaBlock)
+	if err != nil {
+		return nil, err
+	}
+	table.ColumnMeta = meta
+
+	table.Nullable = make([]bool, columnCount)
+	for i := range table.Nullable {
+		table.Nullable[i] = bitmapIsSet(nullBitmap, i)
+	}
+
+	p.tables[table.TableID] = table
+	return table, nil
+}
+
+// parseRows decodes a write/update/delete rows event (v1 or v2)
+func (p *binlogParser) parseRows(body []byte, eventType byte, action BinlogRowAction) (*BinlogRowsEvent, error) {
+	r := &binlogReader{data: body}
+
+	tableID := r.uint(p.tableIDSize)
+	r.skip(2) // flags
+	if eventType >= binlogWriteRowsEventV2 {
+		extraLen := int(r.uint(2))
+		r.skip(extraLen - 2)
+	}
+
+	columnCount := int(r.lenencInt())
+	bitmapSize := (columnCount + 7) / 8
+	present := r.bytes(bitmapSize)
+	presentAfter := present
+	if action == BinlogRowUpdate {
+		presentAfter = r.bytes(bitmapSize)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode rows event: %w", r.err)
+	}
+
+	table, ok := p.tables[tableID]
+	if !ok {
+		return nil, fmt.Errorf("rows event references unknown table id %d", tableID)
+	}
+	if columnCount != len(table.ColumnTypes) {
+		return nil, fmt.Errorf("rows event for %s.%s has %d columns, table map has %d", table.Schema, table.Table, columnCount, len(table.ColumnTypes))
+	}
+
+	event := &BinlogRowsEvent{Action: action, Table: table}
+	for r.remaining() > 0 {
+		change := &BinlogRowChange{}
+		values, mask, err := decodeBinlogRow(r, table, present)
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case BinlogRowInsert:
+			change.After, change.AfterPresent = values, mask
+		case BinlogRowDelete:
+			change.Before, change.BeforePresent = values, mask
+		case BinlogRowUpdate:
+			change.Before, change.BeforePresent = values, mask
+			if change.After, change.AfterPresent, err = decodeBinlogRow(r, table, presentAfter); err != nil {
+				return nil, err
+			}
+		}
+		event.Rows = append(event.Rows, change)
+	}
+
+	return event, nil
+}
+
+// binlogReader is a bounds-checked little-endian reader over an event body.
+// The first out-of-range read sets err and all later reads return zero values.
+type binlogReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *binlogReader) remaining() int {
+	if r.err != nil {
+		return 0
+	}
+	return len(r.data) - r.pos
+}
+
+func (r *binlogReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of event data (need %d bytes at offset %d of %d)", n, r.pos, len(r.data))
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *binlogReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *binlogReader) next() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// uint reads an n-byte little-endian unsigned integer
+func (r *binlogReader) uint(n int) uint64 {
+	var v uint64
+	for i, b := range r.bytes(n) {
+		v |= uint64(b) << (8 * uint(i))
+	}
+	return v
+}
+
+// lenencInt reads a length-encoded integer
+func (r *binlogReader) lenencInt() uint64 {
+	switch first := r.next(); {
+	case first < 0xfb:
+		return uint64(first)
+	case first == 0xfc:
+		return r.uint(2)
+	case first == 0xfd:
+		return r.uint(3)
+	case first == 0xfe:
+		return r.uint(8)
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("invalid length-encoded integer prefix 0x%x", first)
+		}
+		return 0
+	}
+}
+
+// bitmapIsSet reports whether bit i is set in a little-endian bitmap
+func bitmapIsSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<uint(i%8)) != 0
+}
@@ -0,0 +1,152 @@
+package sync
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gtidInterval is a half-open range [Start, End) of transaction numbers
+type gtidInterval struct {
+	Start int64
+	End   int64
+}
+
+// GTIDSet is a MySQL GTID set, e.g. "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:7"
+type GTIDSet struct {
+	sets map[string][]gtidInterval // keyed by lower-case source UUID
+}
+
+// ParseGTIDSet parses a GTID set as reported by @@gtid_executed
+func ParseGTIDSet(s string) (*GTIDSet, error) {
+	set := &GTIDSet{sets: make(map[string][]gtidInterval)}
+
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\n", ""))
+	if s == "" {
+		return set, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid gtid set %q", part)
+		}
+		sid := strings.ToLower(fields[0])
+		if _, err := parseGTIDSourceID(sid); err != nil {
+			return nil, err
+		}
+		for _, rng := range fields[1:] {
+			interval, err := parseGTIDInterval(rng)
+			if err != nil {
+				return nil, err
+			}
+			set.addInterval(sid, interval)
+		}
+	}
+
+	return set, nil
+}
+
+func parseGTIDInterval(s string) (gtidInterval, error) {
+	bounds := strings.SplitN(s, "-", 2)
+	start, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil || start < 1 {
+		return gtidInterval{}, fmt.Errorf("invalid gtid interval %q", s)
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil || end < start {
+			return gtidInterval{}, fmt.Errorf("invalid gtid interval %q", s)
+		}
+	}
+	return gtidInterval{Start: start, End: end + 1}, nil
+}
+
+func parseGTIDSourceID(sid string) ([16]byte, error) {
+	var id [16]byte
+	raw, err := hex.DecodeString(strings.ReplaceAll(sid, "-", ""))
+	if err != nil || len(raw) != 16 {
+		return id, fmt.Errorf("invalid gtid source id %q", sid)
+	}
+	copy(id[:], raw)
+	return id, nil
+}
+
+func formatGTIDSourceID(id [16]byte) string {
+	h := hex.EncodeToString(id[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Add records a single transaction in the set
+func (s *GTIDSet) Add(sid [16]byte, gno int64) {
+	s.addInterval(formatGTIDSourceID(sid), gtidInterval{Start: gno, End: gno + 1})
+}
+
+// addInterval inserts an interval and merges overlapping or adjacent ranges
+func (s *GTIDSet) addInterval(sid string, interval gtidInterval) {
+	intervals := append(s.sets[sid], interval)
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start < intervals[j].Start })
+
+	merged := intervals[:1]
+	for _, next := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if next.Start <= last.End {
+			if next.End > last.End {
+				last.End = next.End
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	s.sets[sid] = merged
+}
+
+// String formats the set the way MySQL does
+func (s *GTIDSet) String() string {
+	sids := make([]string, 0, len(s.sets))
+	for sid := range s.sets {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+
+	parts := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		var sb strings.Builder
+		sb.WriteString(sid)
+		for _, interval := range s.sets[sid] {
+			sb.WriteString(":" + strconv.FormatInt(interval.Start, 10))
+			if interval.End-1 > interval.Start {
+				sb.WriteString("-" + strconv.FormatInt(interval.End-1, 10))
+			}
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// encode returns the binary form used by COM_BINLOG_DUMP_GTID
+func (s *GTIDSet) encode() []byte {
+	sids := make([]string, 0, len(s.sets))
+	for sid := range s.sets {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(len(sids)))
+	for _, sid := range sids {
+		id, _ := parseGTIDSourceID(sid)
+		buf = append(buf, id[:]...)
+
+		intervals := s.sets[sid]
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(len(intervals)))
+		for _, interval := range intervals {
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(interval.Start))
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(interval.End))
+		}
+	}
+	return buf
+}
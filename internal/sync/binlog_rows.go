@@ -0,0 +1,783 @@
+package sync
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MySQL column types as they appear in table map events
+const (
+	mysqlTypeDecimal    byte = 0
+	mysqlTypeTiny       byte = 1
+	mysqlTypeShort      byte = 2
+	mysqlTypeLong       byte = 3
+	mysqlTypeFloat      byte = 4
+	mysqlTypeDouble     byte = 5
+	mysqlTypeNull       byte = 6
+	mysqlTypeTimestamp  byte = 7
+	mysqlTypeLongLong   byte = 8
+	mysqlTypeInt24      byte = 9
+	mysqlTypeDate       byte = 10
+	mysqlTypeTime       byte = 11
+	mysqlTypeDateTime   byte = 12
+	mysqlTypeYear       byte = 13
+	mysqlTypeNewDate    byte = 14
+	mysqlTypeVarchar    byte = 15
+	mysqlTypeBit        byte = 16
+	mysqlTypeTimestamp2 byte = 17
+	mysqlTypeDateTime2  byte = 18
+	mysqlTypeTime2      byte = 19
+	mysqlTypeJSON       byte = 245
+	mysqlTypeNewDecimal byte = 246
+	mysqlTypeEnum       byte = 247
+	mysqlTypeSet        byte = 248
+	mysqlTypeTinyBlob   byte = 249
+	mysqlTypeMediumBlob byte = 250
+	mysqlTypeLongBlob   byte = 251
+	mysqlTypeBlob       byte = 252
+	mysqlTypeVarString  byte = 253
+	mysqlTypeString     byte = 254
+	mysqlTypeGeometry   byte = 255
+)
+
+// parseBinlogColumnMeta splits the table map metadata block into per-column values
+func parseBinlogColumnMeta(types []byte, block []byte) ([]uint16, error) {
+	meta := make([]uint16, len(types))
+	pos := 0
+	need := func(n int) error {
+		if pos+n > len(block) {
+			return fmt.Errorf("table map metadata too short")
+		}
+		return nil
+	}
+
+	for i, t := range types {
+		switch t {
+		case mysqlTypeString, mysqlTypeNewDecimal:
+			// Stored big-endian: real type/precision first, then length/scale
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			meta[i] = uint16(block[pos])<<8 | uint16(block[pos+1])
+			pos += 2
+		case mysqlTypeVarchar, mysqlTypeVarString, mysqlTypeBit:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			meta[i] = binary.LittleEndian.Uint16(block[pos:])
+			pos += 2
+		case mysqlTypeBlob, mysqlTypeFloat, mysqlTypeDouble, mysqlTypeGeometry, mysqlTypeJSON,
+			mysqlTypeTimestamp2, mysqlTypeDateTime2, mysqlTypeTime2:
+			if err := need(1); err != nil {
+				return nil, err
+			}
+			meta[i] = uint16(block[pos])
+			pos++
+		}
+	}
+
+	return meta, nil
+}
+
+// decodeBinlogRow decodes one row image. Columns not present in the image are
+// left nil and reported false in the returned mask.
+func decodeBinlogRow(r *binlogReader, table *BinlogTableMap, present []byte) ([]interface{}, []bool, error) {
+	columnCount := len(table.ColumnTypes)
+	mask := make([]bool, columnCount)
+	presentCount := 0
+	for i := 0; i < columnCount; i++ {
+		if bitmapIsSet(present, i) {
+			mask[i] = true
+			presentCount++
+		}
+	}
+
+	nullBitmap := r.bytes((presentCount + 7) / 8)
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("failed to decode row of %s.%s: %w", table.Schema, table.Table, r.err)
+	}
+
+	values := make([]interface{}, columnCount)
+	nullIndex := 0
+	for i := 0; i < columnCount; i++ {
+		if !mask[i] {
+			continue
+		}
+		isNull := bitmapIsSet(nullBitmap, nullIndex)
+		nullIndex++
+		if isNull {
+			continue
+		}
+
+		value, n, err := decodeBinlogValue(r.data[r.pos:], table.ColumnTypes[i], table.ColumnMeta[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode column %d of %s.%s: %w", i, table.Schema, table.Table, err)
+		}
+		r.skip(n)
+		if r.err != nil {
+			return nil, nil, fmt.Errorf("failed to decode column %d of %s.%s: %w", i, table.Schema, table.Table, r.err)
+		}
+		values[i] = value
+	}
+
+	return values, mask, nil
+}
+
+// decodeBinlogValue decodes a single column value and returns it with the number
+// of bytes consumed. Integers are returned signed; callers reinterpret unsigned
+// columns. Strings and blobs are returned as []byte, like rows scanned by sqlx.
+func decodeBinlogValue(data []byte, columnType byte, meta uint16) (interface{}, int, error) {
+	length := 0
+	if columnType == mysqlTypeString {
+		// ENUM and SET are logged as STRING with the real type in the metadata
+		if meta >= 256 {
+			b0, b1 := byte(meta>>8), byte(meta&0xff)
+			if b0&0x30 != 0x30 {
+				length = int(uint16(b1) | (uint16((b0&0x30)^0x30) << 4))
+				columnType = b0 | 0x30
+			} else {
+				length = int(b1)
+				columnType = b0
+			}
+		} else {
+			length = int(meta)
+		}
+	}
+
+	need := func(n int) error {
+		if n > len(data) {
+			return fmt.Errorf("value needs %d bytes, %d left", n, len(data))
+		}
+		return nil
+	}
+
+	switch columnType {
+	case mysqlTypeNull:
+		return nil, 0, nil
+
+	case mysqlTypeTiny:
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		return int64(int8(data[0])), 1, nil
+
+	case mysqlTypeShort:
+		if err := need(2); err != nil {
+			return nil, 0, err
+		}
+		return int64(int16(binary.LittleEndian.Uint16(data))), 2, nil
+
+	case mysqlTypeInt24:
+		if err := need(3); err != nil {
+			return nil, 0, err
+		}
+		v := int64(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16)
+		if v&0x800000 != 0 {
+			v -= 1 << 24
+		}
+		return v, 3, nil
+
+	case mysqlTypeLong:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return int64(int32(binary.LittleEndian.Uint32(data))), 4, nil
+
+	case mysqlTypeLongLong:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return int64(binary.LittleEndian.Uint64(data)), 8, nil
+
+	case mysqlTypeFloat:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(data)), 4, nil
+
+	case mysqlTypeDouble:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), 8, nil
+
+	case mysqlTypeNewDecimal:
+		return decodeBinlogDecimal(data, int(meta>>8), int(meta&0xff))
+
+	case mysqlTypeBit:
+		nbits := int(meta>>8)*8 + int(meta&0xff)
+		n := (nbits + 7) / 8
+		if err := need(n); err != nil {
+			return nil, 0, err
+		}
+		return int64(bigEndianUint(data[:n])), n, nil
+
+	case mysqlTypeTimestamp:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		sec := binary.LittleEndian.Uint32(data)
+		if sec == 0 {
+			return "0000-00-00 00:00:00", 4, nil
+		}
+		return time.Unix(int64(sec), 0).UTC().Format("2006-01-02 15:04:05"), 4, nil
+
+	case mysqlTypeTimestamp2:
+		n := 4 + fractionalBytes(int(meta))
+		if err := need(n); err != nil {
+			return nil, 0, err
+		}
+		sec := binary.BigEndian.Uint32(data)
+		micros := decodeFractional(data[4:n], int(meta))
+		if sec == 0 && micros == 0 {
+			return "0000-00-00 00:00:00" + formatFractional(0, int(meta)), n, nil
+		}
+		t := time.Unix(int64(sec), 0).UTC()
+		return t.Format("2006-01-02 15:04:05") + formatFractional(micros, int(meta)), n, nil
+
+	case mysqlTypeDateTime:
+		if err := need(8); err != nil {
+			return nil, 0, err
+		}
+		v := binary.LittleEndian.Uint64(data)
+		d, t := v/1000000, v%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, (d%10000)/100, d%100, t/10000, (t%10000)/100, t%100), 8, nil
+
+	case mysqlTypeDateTime2:
+		n := 5 + fractionalBytes(int(meta))
+		if err := need(n); err != nil {
+			return nil, 0, err
+		}
+		packed := int64(bigEndianUint(data[:5])) - 0x8000000000
+		micros := decodeFractional(data[5:n], int(meta))
+		ymd := packed >> 17
+		ym := ymd >> 5
+		hms := packed % (1 << 17)
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d%s",
+			ym/13, ym%13, ymd%(1<<5), hms>>12, (hms>>6)%(1<<6), hms%(1<<6), formatFractional(micros, int(meta))), n, nil
+
+	case mysqlTypeTime:
+		if err := need(3); err != nil {
+			return nil, 0, err
+		}
+		v := int(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16)
+		return fmt.Sprintf("%02d:%02d:%02d", v/10000, (v%10000)/100, v%100), 3, nil
+
+	case mysqlTypeTime2:
+		return decodeBinlogTime2(data, int(meta))
+
+	case mysqlTypeDate, mysqlTypeNewDate:
+		if err := need(3); err != nil {
+			return nil, 0, err
+		}
+		v := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+		return fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)&15, v&31), 3, nil
+
+	case mysqlTypeYear:
+		if err := need(1); err != nil {
+			return nil, 0, err
+		}
+		if data[0] == 0 {
+			return int64(0), 1, nil
+		}
+		return int64(data[0]) + 1900, 1, nil
+
+	case mysqlTypeEnum:
+		n := length
+		if n == 0 {
+			n = int(meta & 0xff)
+		}
+		if n != 1 && n != 2 {
+			return nil, 0, fmt.Errorf("invalid enum length %d", n)
+		}
+		if err := need(n); err != nil {
+			return nil, 0, err
+		}
+		// The 1-based member index; MySQL accepts it when inserted into an ENUM column
+		return int64(littleEndianUint(data[:n])), n, nil
+
+	case mysqlTypeSet:
+		n := length
+		if n == 0 {
+			n = int(meta & 0xff)
+		}
+		if err := need(n); err != nil {
+			return nil, 0, err
+		}
+		// The member bitmask; MySQL accepts it when inserted into a SET column
+		return int64(littleEndianUint(data[:n])), n, nil
+
+	case mysqlTypeVarchar, mysqlTypeVarString:
+		return decodeBinlogString(data, int(meta))
+
+	case mysqlTypeString:
+		return decodeBinlogString(data, length)
+
+	case mysqlTypeBlob, mysqlTypeTinyBlob, mysqlTypeMediumBlob, mysqlTypeLongBlob, mysqlTypeGeometry:
+		return decodeBinlogBlob(data, int(meta))
+
+	case mysqlTypeJSON:
+		raw, n, err := decodeBinlogBlob(data, int(meta))
+		if err != nil {
+			return nil, 0, err
+		}
+		text, err := decodeBinaryJSON(raw.([]byte))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode json value: %w", err)
+		}
+		return text, n, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported column type %d", columnType)
+}
+
+// decodeBinlogString decodes a CHAR/VARCHAR value with a 1 or 2 byte length prefix
+func decodeBinlogString(data []byte, maxLength int) (interface{}, int, error) {
+	prefix := 1
+	if maxLength >= 256 {
+		prefix = 2
+	}
+	if len(data) < prefix {
+		return nil, 0, fmt.Errorf("string value truncated")
+	}
+	n := int(littleEndianUint(data[:prefix]))
+	if len(data) < prefix+n {
+		return nil, 0, fmt.Errorf("string value truncated")
+	}
+	return append([]byte(nil), data[prefix:prefix+n]...), prefix + n, nil
+}
+
+// decodeBinlogBlob decodes a BLOB/TEXT value whose length prefix is lengthBytes long
+func decodeBinlogBlob(data []byte, lengthBytes int) (interface{}, int, error) {
+	if lengthBytes < 1 || lengthBytes > 4 {
+		return nil, 0, fmt.Errorf("invalid blob length size %d", lengthBytes)
+	}
+	if len(data) < lengthBytes {
+		return nil, 0, fmt.Errorf("blob value truncated")
+	}
+	n := int(littleEndianUint(data[:lengthBytes]))
+	if len(data) < lengthBytes+n {
+		return nil, 0, fmt.Errorf("blob value truncated")
+	}
+	return append([]byte(nil), data[lengthBytes:lengthBytes+n]...), lengthBytes + n, nil
+}
+
+// decimalDigitBytes is the storage size of 0-9 leftover decimal digits
+var decimalDigitBytes = [10]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// decodeBinlogDecimal decodes MySQL's packed DECIMAL format into its string form
+func decodeBinlogDecimal(data []byte, precision, scale int) (interface{}, int, error) {
+	const digitsPerWord = 9
+	integral := precision - scale
+	fullIntegral, partialIntegral := integral/digitsPerWord, integral%digitsPerWord
+	fullFractional, partialFractional := scale/digitsPerWord, scale%digitsPerWord
+
+	size := fullIntegral*4 + decimalDigitBytes[partialIntegral] + fullFractional*4 + decimalDigitBytes[partialFractional]
+	if size == 0 || len(data) < size {
+		return nil, 0, fmt.Errorf("decimal value truncated")
+	}
+
+	buf := append([]byte(nil), data[:size]...)
+	negative := buf[0]&0x80 == 0
+	buf[0] ^= 0x80
+	if negative {
+		for i := range buf {
+			buf[i] ^= 0xff
+		}
+	}
+
+	var sb strings.Builder
+	pos := 0
+	if n := decimalDigitBytes[partialIntegral]; n > 0 {
+		sb.WriteString(strconv.FormatUint(bigEndianUint(buf[pos:pos+n]), 10))
+		pos += n
+	}
+	for i := 0; i < fullIntegral; i++ {
+		sb.WriteString(fmt.Sprintf("%09d", binary.BigEndian.Uint32(buf[pos:])))
+		pos += 4
+	}
+
+	intPart := strings.TrimLeft(sb.String(), "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	result := intPart
+	if scale > 0 {
+		var frac strings.Builder
+		for i := 0; i < fullFractional; i++ {
+			frac.WriteString(fmt.Sprintf("%09d", binary.BigEndian.Uint32(buf[pos:])))
+			pos += 4
+		}
+		if n := decimalDigitBytes[partialFractional]; n > 0 {
+			frac.WriteString(fmt.Sprintf("%0*d", partialFractional, bigEndianUint(buf[pos:pos+n])))
+		}
+		result += "." + frac.String()
+	}
+
+	if negative {
+		result = "-" + result
+	}
+	return result, size, nil
+}
+
+// decodeBinlogTime2 decodes the TIME2 format (MySQL 5.6.4+)
+func decodeBinlogTime2(data []byte, fsp int) (interface{}, int, error) {
+	const intOffset = 0x800000
+	n := 3 + fractionalBytes(fsp)
+	if len(data) < n {
+		return nil, 0, fmt.Errorf("time value truncated")
+	}
+
+	var packed int64 // hours/minutes/seconds in the high 24 bits, microseconds in the low 24
+	switch fractionalBytes(fsp) {
+	case 1:
+		intPart := int64(bigEndianUint(data[:3])) - intOffset
+		frac := int64(data[3])
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x100
+		}
+		packed = intPart<<24 + frac*10000
+	case 2:
+		intPart := int64(bigEndianUint(data[:3])) - intOffset
+		frac := int64(bigEndianUint(data[3:5]))
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		packed = intPart<<24 + frac*100
+	case 3:
+		packed = int64(bigEndianUint(data[:6])) - 0x800000000000
+	default:
+		packed = (int64(bigEndianUint(data[:3])) - intOffset) << 24
+	}
+
+	sign := ""
+	if packed < 0 {
+		sign = "-"
+		packed = -packed
+	}
+	hms := packed >> 24
+	micros := packed % (1 << 24)
+	return fmt.Sprintf("%s%02d:%02d:%02d%s", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6), formatFractional(micros, fsp)), n, nil
+}
+
+// fractionalBytes is the storage size of fractional seconds with the given precision
+func fractionalBytes(fsp int) int {
+	return (fsp + 1) / 2
+}
+
+// decodeFractional returns the microseconds stored in a big-endian fractional part
+func decodeFractional(data []byte, fsp int) int64 {
+	n := fractionalBytes(fsp)
+	if n == 0 {
+		return 0
+	}
+	v := int64(bigEndianUint(data[:n]))
+	for i := n; i < 3; i++ {
+		v *= 100
+	}
+	return v
+}
+
+// formatFractional renders microseconds with fsp digits, or nothing when fsp is 0
+func formatFractional(micros int64, fsp int) string {
+	if fsp <= 0 {
+		return ""
+	}
+	for i := fsp; i < 6; i++ {
+		micros /= 10
+	}
+	return fmt.Sprintf(".%0*d", fsp, micros)
+}
+
+func bigEndianUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func littleEndianUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+// Binary JSON value types (MySQL 5.7+ JSON column storage)
+const (
+	jsonbSmallObject byte = 0x00
+	jsonbLargeObject byte = 0x01
+	jsonbSmallArray  byte = 0x02
+	jsonbLargeArray  byte = 0x03
+	jsonbLiteral     byte = 0x04
+	jsonbInt16       byte = 0x05
+	jsonbUint16      byte = 0x06
+	jsonbInt32       byte = 0x07
+	jsonbUint32      byte = 0x08
+	jsonbInt64       byte = 0x09
+	jsonbUint64      byte = 0x0a
+	jsonbDouble      byte = 0x0b
+	jsonbString      byte = 0x0c
+	jsonbOpaque      byte = 0x0f
+)
+
+// decodeBinaryJSON converts MySQL's binary JSON encoding to JSON text
+func decodeBinaryJSON(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "null", nil
+	}
+	value, err := decodeJSONBValue(data[0], data[1:])
+	if err != nil {
+		return "", err
+	}
+	text, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+func decodeJSONBValue(t byte, data []byte) (interface{}, error) {
+	switch t {
+	case jsonbSmallObject:
+		return decodeJSONBContainer(data, false, true)
+	case jsonbLargeObject:
+		return decodeJSONBContainer(data, true, true)
+	case jsonbSmallArray:
+		return decodeJSONBContainer(data, false, false)
+	case jsonbLargeArray:
+		return decodeJSONBContainer(data, true, false)
+	case jsonbLiteral:
+		if len(data) < 1 {
+			return nil, fmt.Errorf("json literal truncated")
+		}
+		switch data[0] {
+		case 0x00:
+			return nil, nil
+		case 0x01:
+			return true, nil
+		case 0x02:
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid json literal 0x%x", data[0])
+	case jsonbInt16, jsonbUint16:
+		if len(data) < 2 {
+			return nil, fmt.Errorf("json int16 truncated")
+		}
+		if t == jsonbInt16 {
+			return int64(int16(binary.LittleEndian.Uint16(data))), nil
+		}
+		return uint64(binary.LittleEndian.Uint16(data)), nil
+	case jsonbInt32, jsonbUint32:
+		if len(data) < 4 {
+			return nil, fmt.Errorf("json int32 truncated")
+		}
+		if t == jsonbInt32 {
+			return int64(int32(binary.LittleEndian.Uint32(data))), nil
+		}
+		return uint64(binary.LittleEndian.Uint32(data)), nil
+	case jsonbInt64, jsonbUint64:
+		if len(data) < 8 {
+			return nil, fmt.Errorf("json int64 truncated")
+		}
+		if t == jsonbInt64 {
+			return int64(binary.LittleEndian.Uint64(data)), nil
+		}
+		return binary.LittleEndian.Uint64(data), nil
+	case jsonbDouble:
+		if len(data) < 8 {
+			return nil, fmt.Errorf("json double truncated")
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+	case jsonbString:
+		n, size, err := decodeJSONBVarLength(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < size+n {
+			return nil, fmt.Errorf("json string truncated")
+		}
+		return string(data[size : size+n]), nil
+	case jsonbOpaque:
+		return decodeJSONBOpaque(data)
+	}
+	return nil, fmt.Errorf("unknown json value type 0x%x", t)
+}
+
+// decodeJSONBContainer decodes an object or array; offsets are relative to data
+func decodeJSONBContainer(data []byte, large, isObject bool) (interface{}, error) {
+	offsetSize := 2
+	if large {
+		offsetSize = 4
+	}
+	readOffset := func(pos int) (int, error) {
+		if pos+offsetSize > len(data) {
+			return 0, fmt.Errorf("json container truncated")
+		}
+		return int(littleEndianUint(data[pos : pos+offsetSize])), nil
+	}
+
+	count, err := readOffset(0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := readOffset(offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	if size > len(data) {
+		return nil, fmt.Errorf("json container truncated")
+	}
+	data = data[:size]
+
+	keyEntrySize := offsetSize + 2
+	valueEntrySize := 1 + offsetSize
+	pos := 2 * offsetSize
+
+	keys := make([]string, count)
+	if isObject {
+		for i := 0; i < count; i++ {
+			entry := pos + i*keyEntrySize
+			keyOffset, err := readOffset(entry)
+			if err != nil {
+				return nil, err
+			}
+			if entry+keyEntrySize > len(data) {
+				return nil, fmt.Errorf("json key entry truncated")
+			}
+			keyLen := int(binary.LittleEndian.Uint16(data[entry+offsetSize:]))
+			if keyOffset+keyLen > len(data) {
+				return nil, fmt.Errorf("json key truncated")
+			}
+			keys[i] = string(data[keyOffset : keyOffset+keyLen])
+		}
+		pos += count * keyEntrySize
+	}
+
+	values := make([]interface{}, count)
+	for i := 0; i < count; i++ {
+		entry := pos + i*valueEntrySize
+		if entry+valueEntrySize > len(data) {
+			return nil, fmt.Errorf("json value entry truncated")
+		}
+		t := data[entry]
+
+		// Small scalars are inlined in the value entry
+		inlined := t == jsonbLiteral || t == jsonbInt16 || t == jsonbUint16 ||
+			(large && (t == jsonbInt32 || t == jsonbUint32))
+		if inlined {
+			v, err := decodeJSONBValue(t, data[entry+1:entry+valueEntrySize])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+			continue
+		}
+
+		valueOffset, err := readOffset(entry + 1)
+		if err != nil {
+			return nil, err
+		}
+		if valueOffset >= len(data) {
+			return nil, fmt.Errorf("json value offset out of range")
+		}
+		v, err := decodeJSONBValue(t, data[valueOffset:])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	if !isObject {
+		return values, nil
+	}
+	object := make(map[string]interface{}, count)
+	for i, key := range keys {
+		object[key] = values[i]
+	}
+	return object, nil
+}
+
+// decodeJSONBVarLength decodes the variable-length size prefix of strings and opaque values
+func decodeJSONBVarLength(data []byte) (int, int, error) {
+	length := 0
+	for i := 0; i < 5 && i < len(data); i++ {
+		length |= int(data[i]&0x7f) << (7 * uint(i))
+		if data[i]&0x80 == 0 {
+			return length, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid json variable length")
+}
+
+// decodeJSONBOpaque decodes opaque values (DECIMAL, temporal types); other
+// opaque types are returned base64 encoded
+func decodeJSONBOpaque(data []byte) (interface{}, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("json opaque value truncated")
+	}
+	fieldType := data[0]
+	n, size, err := decodeJSONBVarLength(data[1:])
+	if err != nil {
+		return nil, err
+	}
+	start := 1 + size
+	if len(data) < start+n {
+		return nil, fmt.Errorf("json opaque value truncated")
+	}
+	payload := data[start : start+n]
+
+	switch fieldType {
+	case mysqlTypeNewDecimal:
+		if len(payload) < 2 {
+			return nil, fmt.Errorf("json decimal truncated")
+		}
+		v, _, err := decodeBinlogDecimal(payload[2:], int(payload[0]), int(payload[1]))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(v.(string)), nil
+	case mysqlTypeDate, mysqlTypeDateTime, mysqlTypeTimestamp, mysqlTypeTime:
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("json temporal value truncated")
+		}
+		return formatPackedTemporal(int64(binary.LittleEndian.Uint64(payload)), fieldType), nil
+	}
+	return "base64:type" + strconv.Itoa(int(fieldType)) + ":" + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// formatPackedTemporal renders MySQL's in-memory packed temporal representation
+func formatPackedTemporal(packed int64, fieldType byte) string {
+	sign := ""
+	if packed < 0 {
+		sign = "-"
+		packed = -packed
+	}
+	micros := packed % (1 << 24)
+	v := packed >> 24
+
+	if fieldType == mysqlTypeTime {
+		hms := v
+		return fmt.Sprintf("%s%02d:%02d:%02d.%06d", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6), micros)
+	}
+
+	ymd := v >> 17
+	ym := ymd >> 5
+	hms := v % (1 << 17)
+	date := fmt.Sprintf("%04d-%02d-%02d", ym/13, ym%13, ymd%(1<<5))
+	if fieldType == mysqlTypeDate {
+		return date
+	}
+	return fmt.Sprintf("%s %02d:%02d:%02d.%06d", date, hms>>12, (hms>>6)%(1<<6), hms%(1<<6), micros)
+}
@@ -0,0 +1,513 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// binlogEventSource yields raw binlog events (header included) in log order.
+// NextEvent returns io.EOF once the source has no more events.
+type binlogEventSource interface {
+	NextEvent(ctx context.Context) ([]byte, error)
+	Close() error
+}
+
+// binlogFileMagic starts every binlog file
+var binlogFileMagic = []byte{0xfe, 'b', 'i', 'n'}
+
+// binlogFileSource reads events from a binlog file, such as one written by
+// `mysqlbinlog --read-from-remote-server --raw`. Used to replay recorded
+// fixtures without a live server.
+type binlogFileSource struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// openBinlogFile opens a binlog file and checks its magic header
+func openBinlogFile(path string) (*binlogFileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binlog file: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	magic := make([]byte, len(binlogFileMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || !bytes.Equal(magic, binlogFileMagic) {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a binlog file", path)
+	}
+
+	return &binlogFileSource{file: file, reader: reader}, nil
+}
+
+// NextEvent reads the next event from the file
+func (s *binlogFileSource) NextEvent(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, binlogEventHeaderSize)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read binlog event header: %w", err)
+	}
+
+	size := int(binary.LittleEndian.Uint32(header[9:]))
+	if size < binlogEventHeaderSize {
+		return nil, fmt.Errorf("invalid binlog event size %d", size)
+	}
+
+	event := make([]byte, size)
+	copy(event, header)
+	if _, err := io.ReadFull(s.reader, event[binlogEventHeaderSize:]); err != nil {
+		return nil, fmt.Errorf("failed to read binlog event body: %w", err)
+	}
+	return event, nil
+}
+
+// Close closes the file
+func (s *binlogFileSource) Close() error {
+	return s.file.Close()
+}
+
+// binlogDumpRequest describes where a replication stream starts
+type binlogDumpRequest struct {
+	ServerID uint32
+	File     string
+	Position uint32
+	GTIDSet  *GTIDSet // When set, the stream starts after these transactions instead of File/Position
+	Checksum string   // Value of @@global.binlog_checksum on the source
+}
+
+// MySQL client/server protocol constants used by the replication client
+const (
+	clientLongPassword     uint32 = 0x00000001
+	clientLongFlag         uint32 = 0x00000004
+	clientProtocol41       uint32 = 0x00000200
+	clientSSL              uint32 = 0x00000800
+	clientTransactions     uint32 = 0x00002000
+	clientSecureConnection uint32 = 0x00008000
+	clientPluginAuth       uint32 = 0x00080000
+
+	comQuery          byte = 0x03
+	comBinlogDump     byte = 0x12
+	comBinlogDumpGTID byte = 0x1e
+
+	// binlogDumpNonBlock makes the server send EOF at the end of the binlog
+	// instead of waiting for new events
+	binlogDumpNonBlock    uint16 = 0x01
+	binlogDumpThroughGTID uint16 = 0x04
+
+	mysqlMaxPacketSize     = 1<<24 - 1
+	mysqlCharsetUTF8MB4    = 45
+	binlogReplicaIOTimeout = 5 * time.Minute
+)
+
+// binlogReplicationClient is a minimal MySQL replication client. It registers
+// as a replica and streams binlog events up to the current end of the binlog.
+type binlogReplicationClient struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence byte
+}
+
+// dialBinlogStream connects to the source server and requests a binlog dump.
+// The stream is non-blocking: NextEvent returns io.EOF once the reader has
+// caught up with the source.
+func dialBinlogStream(ctx context.Context, config *ConnectionConfig, request *binlogDumpRequest) (*binlogReplicationClient, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", config.Host, config.Port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to source server: %w", err)
+	}
+
+	client := &binlogReplicationClient{conn: conn, reader: bufio.NewReader(conn)}
+	if err := client.handshake(config); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	// Announce that we understand checksums, otherwise the server refuses to
+	// stream from a binlog with binlog_checksum=CRC32
+	checksum := request.Checksum
+	if checksum == "" {
+		checksum = "NONE"
+	}
+	query := fmt.Sprintf("SET @master_binlog_checksum = '%s', @source_binlog_checksum = '%s'", checksum, checksum)
+	if err := client.exec(query); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to set binlog checksum: %w", err)
+	}
+
+	if err := client.requestDump(request); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// NextEvent returns the next event from the replication stream
+func (c *binlogReplicationClient) NextEvent(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(binlogReplicaIOTimeout))
+	packet, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(packet) == 0:
+		return nil, fmt.Errorf("empty packet in binlog stream")
+	case packet[0] == 0x00:
+		return packet[1:], nil
+	case packet[0] == 0xfe && len(packet) < 9:
+		return nil, io.EOF
+	case packet[0] == 0xff:
+		return nil, parseMySQLError(packet)
+	}
+	return nil, fmt.Errorf("unexpected packet 0x%x in binlog stream", packet[0])
+}
+
+// Close closes the connection
+func (c *binlogReplicationClient) Close() error {
+	return c.conn.Close()
+}
+
+// handshake reads the server greeting, optionally upgrades to TLS and authenticates
+func (c *binlogReplicationClient) handshake(config *ConnectionConfig) error {
+	greeting, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read server greeting: %w", err)
+	}
+	if len(greeting) > 0 && greeting[0] == 0xff {
+		return parseMySQLError(greeting)
+	}
+
+	r := &binlogReader{data: greeting}
+	if version := r.next(); version != 10 {
+		return fmt.Errorf("unsupported protocol version %d", version)
+	}
+	if i := bytes.IndexByte(greeting[r.pos:], 0); i >= 0 {
+		r.skip(i + 1) // server version
+	}
+	r.skip(4) // connection id
+	scramble := append([]byte(nil), r.bytes(8)...)
+	r.skip(1)
+	capabilities := uint32(r.uint(2))
+	plugin := "mysql_native_password"
+	if r.remaining() > 0 {
+		r.skip(1 + 2) // charset, status
+		capabilities |= uint32(r.uint(2)) << 16
+		authDataLen := int(r.next())
+		r.skip(10)
+		if capabilities&clientSecureConnection != 0 {
+			n := authDataLen - 8
+			if n < 13 {
+				n = 13
+			}
+			part := r.bytes(n)
+			scramble = append(scramble, bytes.TrimRight(part, "\x00")...)
+		}
+		if capabilities&clientPluginAuth != 0 && r.remaining() > 0 {
+			rest := greeting[r.pos:]
+			if i := bytes.IndexByte(rest, 0); i >= 0 {
+				rest = rest[:i]
+			}
+			plugin = string(rest)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to parse server greeting: %w", r.err)
+	}
+
+	flags := clientLongPassword | clientLongFlag | clientProtocol41 | clientTransactions | clientSecureConnection | clientPluginAuth
+
+	secure := false
+	if config.SSL {
+		if capabilities&clientSSL == 0 {
+			return fmt.Errorf("source server does not support SSL")
+		}
+		flags |= clientSSL
+		request := make([]byte, 32)
+		binary.LittleEndian.PutUint32(request, flags)
+		request[8] = mysqlCharsetUTF8MB4
+		if err := c.writePacket(request); err != nil {
+			return err
+		}
+		tlsConn := tls.Client(c.conn, &tls.Config{ServerName: config.Host})
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("failed to establish TLS connection: %w", err)
+		}
+		c.conn = tlsConn
+		c.reader = bufio.NewReader(tlsConn)
+		secure = true
+	}
+
+	authResponse, err := scramblePassword(plugin, config.Password, scramble)
+	if err != nil {
+		return err
+	}
+
+	response := make([]byte, 32, 64+len(config.Username)+len(authResponse)+len(plugin))
+	binary.LittleEndian.PutUint32(response, flags)
+	response[8] = mysqlCharsetUTF8MB4
+	response = append(response, config.Username...)
+	response = append(response, 0, byte(len(authResponse)))
+	response = append(response, authResponse...)
+	response = append(response, plugin...)
+	response = append(response, 0)
+	if err := c.writePacket(response); err != nil {
+		return err
+	}
+
+	return c.authenticate(plugin, config.Password, scramble, secure)
+}
+
+// authenticate handles the server's replies to the handshake response
+func (c *binlogReplicationClient) authenticate(plugin, password string, scramble []byte, secure bool) error {
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return fmt.Errorf("failed to read authentication result: %w", err)
+		}
+		if len(packet) == 0 {
+			return fmt.Errorf("empty authentication packet")
+		}
+
+		switch packet[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return parseMySQLError(packet)
+		case 0xfe:
+			// Auth switch request: plugin name, then new scramble
+			rest := packet[1:]
+			i := bytes.IndexByte(rest, 0)
+			if i < 0 {
+				return fmt.Errorf("malformed auth switch request")
+			}
+			plugin = string(rest[:i])
+			scramble = bytes.TrimRight(rest[i+1:], "\x00")
+			response, err := scramblePassword(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(response); err != nil {
+				return err
+			}
+		case 0x01:
+			if plugin != "caching_sha2_password" || len(packet) < 2 {
+				return fmt.Errorf("unexpected authentication data for %s", plugin)
+			}
+			switch packet[1] {
+			case 3: // fast auth succeeded, OK packet follows
+			case 4: // full authentication required
+				if err := c.sendFullAuth(password, scramble, secure); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected caching_sha2_password state %d", packet[1])
+			}
+		default:
+			return fmt.Errorf("unexpected authentication packet 0x%x", packet[0])
+		}
+	}
+}
+
+// sendFullAuth sends the password for caching_sha2_password full authentication,
+// in clear text over TLS or RSA encrypted with the server's public key
+func (c *binlogReplicationClient) sendFullAuth(password string, scramble []byte, secure bool) error {
+	plain := append([]byte(password), 0)
+	if secure {
+		return c.writePacket(plain)
+	}
+
+	if err := c.writePacket([]byte{0x02}); err != nil {
+		return err
+	}
+	packet, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read server public key: %w", err)
+	}
+	if len(packet) == 0 || packet[0] != 0x01 {
+		return fmt.Errorf("unexpected reply to public key request")
+	}
+
+	block, _ := pem.Decode(packet[1:])
+	if block == nil {
+		return fmt.Errorf("invalid server public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid server public key: %w", err)
+	}
+	publicKey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("server public key is not an RSA key")
+	}
+
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	encrypted, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, plain, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	return c.writePacket(encrypted)
+}
+
+// exec runs a statement that does not return rows
+func (c *binlogReplicationClient) exec(query string) error {
+	c.sequence = 0
+	if err := c.writePacket(append([]byte{comQuery}, query...)); err != nil {
+		return err
+	}
+	packet, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(packet) > 0 && packet[0] == 0xff {
+		return parseMySQLError(packet)
+	}
+	if len(packet) == 0 || packet[0] != 0x00 {
+		return fmt.Errorf("unexpected reply to %q", query)
+	}
+	return nil
+}
+
+// requestDump sends COM_BINLOG_DUMP or COM_BINLOG_DUMP_GTID
+func (c *binlogReplicationClient) requestDump(request *binlogDumpRequest) error {
+	c.sequence = 0
+
+	var packet []byte
+	if request.GTIDSet != nil {
+		gtids := request.GTIDSet.encode()
+		packet = append(packet, comBinlogDumpGTID)
+		packet = binary.LittleEndian.AppendUint16(packet, binlogDumpNonBlock|binlogDumpThroughGTID)
+		packet = binary.LittleEndian.AppendUint32(packet, request.ServerID)
+		packet = binary.LittleEndian.AppendUint32(packet, 0) // no file name, the GTID set decides
+		packet = binary.LittleEndian.AppendUint64(packet, 4)
+		packet = binary.LittleEndian.AppendUint32(packet, uint32(len(gtids)))
+		packet = append(packet, gtids...)
+	} else {
+		packet = append(packet, comBinlogDump)
+		packet = binary.LittleEndian.AppendUint32(packet, request.Position)
+		packet = binary.LittleEndian.AppendUint16(packet, binlogDumpNonBlock)
+		packet = binary.LittleEndian.AppendUint32(packet, request.ServerID)
+		packet = append(packet, request.File...)
+	}
+
+	if err := c.writePacket(packet); err != nil {
+		return fmt.Errorf("failed to request binlog dump: %w", err)
+	}
+	return nil
+}
+
+// readPacket reads one logical packet, joining payloads split at 16MB
+func (c *binlogReplicationClient) readPacket() ([]byte, error) {
+	var payload []byte
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(c.reader, header); err != nil {
+			return nil, fmt.Errorf("failed to read packet header: %w", err)
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		c.sequence = header[3] + 1
+
+		start := len(payload)
+		payload = append(payload, make([]byte, length)...)
+		if _, err := io.ReadFull(c.reader, payload[start:]); err != nil {
+			return nil, fmt.Errorf("failed to read packet: %w", err)
+		}
+		if length < mysqlMaxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+// writePacket writes a packet using the current sequence number
+func (c *binlogReplicationClient) writePacket(payload []byte) error {
+	for {
+		n := len(payload)
+		if n > mysqlMaxPacketSize {
+			n = mysqlMaxPacketSize
+		}
+		header := []byte{byte(n), byte(n >> 8), byte(n >> 16), c.sequence}
+		c.sequence++
+		if _, err := c.conn.Write(append(header, payload[:n]...)); err != nil {
+			return fmt.Errorf("failed to write packet: %w", err)
+		}
+		payload = payload[n:]
+		if n < mysqlMaxPacketSize {
+			return nil
+		}
+	}
+}
+
+// parseMySQLError converts an ERR packet into an error
+func parseMySQLError(packet []byte) error {
+	if len(packet) < 3 {
+		return fmt.Errorf("mysql error")
+	}
+	code := binary.LittleEndian.Uint16(packet[1:])
+	message := packet[3:]
+	if len(message) > 0 && message[0] == '#' && len(message) >= 6 {
+		message = message[6:] // SQL state marker and state
+	}
+	return fmt.Errorf("mysql error %d: %s", code, message)
+}
+
+// scramblePassword computes the auth response for the given plugin
+func scramblePassword(plugin, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		stage1 := sha1.Sum([]byte(password))
+		stage2 := sha1.Sum(stage1[:])
+		h := sha1.New()
+		h.Write(scramble)
+		h.Write(stage2[:])
+		result := h.Sum(nil)
+		for i := range result {
+			result[i] ^= stage1[i]
+		}
+		return result, nil
+	case "caching_sha2_password":
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		stage1 := sha256.Sum256([]byte(password))
+		stage2 := sha256.Sum256(stage1[:])
+		h := sha256.New()
+		h.Write(stage2[:])
+		h.Write(scramble)
+		result := h.Sum(nil)
+		for i := range result {
+			result[i] ^= stage1[i]
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported authentication plugin %s", plugin)
+}
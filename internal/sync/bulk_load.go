@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// MySQL errors returned for LOAD DATA LOCAL INFILE when the server disables local_infile
+const (
+	errNotAllowedCommand      = 1148 // ER_NOT_ALLOWED_COMMAND, MySQL 5.7 and MariaDB
+	errClientLocalFilesDenied = 3948 // ER_CLIENT_LOCAL_FILES_DISABLED, MySQL 8.0
+)
+
+// bulkLoadSeq numbers the reader handlers of concurrent loads
+var bulkLoadSeq atomic.Uint64
+
+// bulkLoader loads the batches of a full copy with LOAD DATA LOCAL INFILE, until the target refuses it
+type bulkLoader struct {
+	refused atomic.Bool // The target disables local_infile, the remaining batches are inserted
+}
+
+// bulkLoadModifier returns the LOAD DATA modifier resolving rows whose key exists like the policy does,
+// false when only an INSERT can resolve them like it. A nil policy overwrites whole rows, which REPLACE
+// does too.
+func (p *writePolicy) bulkLoadModifier() (string, bool) {
+	if p == nil {
+		return "REPLACE", true
+	}
+	if p.conflict == ConflictResolutionSkip {
+		return "IGNORE", true
+	}
+	return "", false
+}
+
+// loadOrWriteRows writes rows of a full copy, with LOAD DATA when the loader is set and LOAD DATA
+// resolves existing rows like write does, otherwise or once the target refuses LOAD DATA with write
+func (e *DefaultSyncEngine) loadOrWriteRows(ctx context.Context, loader *bulkLoader, targetDB *sqlx.DB, targetDBName, tableName string, columns []string, rows [][]interface{}, modifier string, loadable bool, write func() error) error {
+	if loader == nil || !loadable || loader.refused.Load() {
+		return write()
+	}
+
+	err := e.loadRowsToDB(ctx, targetDB, targetDBName, tableName, columns, rows, modifier)
+	if err == nil || !isLocalInfileRefused(err) {
+		return err
+	}
+	if loader.refused.CompareAndSwap(false, true) {
+		e.logger.WithError(err).WithField("target_table", tableName).
+			Warn("Target database disables local_infile, inserting the remaining batches")
+	}
+	return write()
+}
+
+// loadRowsToDB loads rows holding their values in column order into a table of the target database
+// with LOAD DATA LOCAL INFILE, streaming them as tab separated values through a reader handler of the
+// driver
+func (e *DefaultSyncEngine) loadRowsToDB(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName string, columns []string, rows [][]interface{}, modifier string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("dbtaxi-%d", bulkLoadSeq.Add(1))
+	reader, writer := io.Pipe()
+	encoded := make(chan struct{})
+	go func() {
+		defer close(encoded)
+		writer.CloseWithError(writeTSV(writer, rows))
+	}()
+	// Closing the reader stops the encoder when the driver didn't read all rows; the rows are only
+	// handed back once it stopped reading them
+	defer func() {
+		reader.Close()
+		<-encoded
+	}()
+
+	mysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+	defer mysql.DeregisterReaderHandler(name)
+
+	if _, err := targetDB.ExecContext(ctx, loadDataStatement(name, targetDBName, tableName, columns, modifier)); err != nil {
+		return fmt.Errorf("failed to execute batch load: %w", err)
+	}
+	return nil
+}
+
+// loadDataStatement builds the LOAD DATA LOCAL INFILE statement reading the rows of a reader handler.
+// The file is read as binary so binary strings reach the table unchanged.
+func loadDataStatement(handler, targetDBName, tableName string, columns []string, modifier string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s'", handler))
+	if modifier != "" {
+		sb.WriteString(" " + modifier)
+	}
+	sb.WriteString(fmt.Sprintf(" INTO TABLE `%s`.`%s` CHARACTER SET binary", targetDBName, tableName))
+	sb.WriteString(" FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (")
+	sb.WriteString(quoteColumns(columns))
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// writeTSV writes rows in the format of LOAD DATA, tab separated and escaped with backslashes, NULL as \N
+func writeTSV(w io.Writer, rows [][]interface{}) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	buf := make([]byte, 0, 64)
+	for _, row := range rows {
+		for i, value := range row {
+			if i > 0 {
+				bw.WriteByte('\t')
+			}
+			buf = appendTSVValue(buf[:0], valueForUTF8MB3Insert(value))
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendTSVValue appends a value as a LOAD DATA field, formatted as the driver formats query arguments
+func appendTSVValue(buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, '\\', 'N')
+	case []byte:
+		return appendTSVEscaped(buf, v)
+	case string:
+		return appendTSVEscaped(buf, []byte(v))
+	case bool:
+		if v {
+			return append(buf, '1')
+		}
+		return append(buf, '0')
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case float32:
+		return strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+	case time.Time:
+		if v.IsZero() {
+			return append(buf, "0000-00-00"...)
+		}
+		return v.In(time.UTC).AppendFormat(buf, "2006-01-02 15:04:05.999999")
+	default:
+		return appendTSVEscaped(buf, []byte(fmt.Sprint(v)))
+	}
+}
+
+// appendTSVEscaped appends bytes escaping the characters LOAD DATA reads as field and line ends,
+// the escape character itself, NUL and Control+Z
+func appendTSVEscaped(buf []byte, data []byte) []byte {
+	for _, c := range data {
+		switch c {
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case 0:
+			buf = append(buf, '\\', '0')
+		case 0x1a:
+			buf = append(buf, '\\', 'Z')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// isLocalInfileRefused reports whether the target refused LOAD DATA LOCAL INFILE because it disables local_infile
+func isLocalInfileRefused(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == errNotAllowedCommand || mysqlErr.Number == errClientLocalFilesDenied
+}
@@ -0,0 +1,155 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTSV(t *testing.T) {
+	rows := [][]interface{}{
+		{int64(1), []byte("a\tb\nc\\d"), nil, []byte{0x00, 0x1a, 0xff, '\r'}, time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC), 9.5, true},
+		{uint64(2), "ok😀", "", []byte(`\N`), time.Time{}, float64(-0.25), false},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTSV(&buf, rows))
+	assert.Equal(t,
+		"1\ta\\tb\\nc\\\\d\t\\N\t\\0\\Z\xff\\r\t2024-01-02 03:04:05.6\t9.5\t1\n"+
+			"2\tok\t\t\\\\N\t0000-00-00\t-0.25\t0\n",
+		buf.String())
+
+	assert.Equal(t, "LOAD DATA LOCAL INFILE 'Reader::dbtaxi-7' REPLACE INTO TABLE `replica`.`orders` CHARACTER SET binary "+
+		`FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n' (`+"`id`, `note`)",
+		loadDataStatement("dbtaxi-7", "replica", "orders", []string{"id", "note"}, "REPLACE"))
+}
+
+func TestCopyTableByKeyset_BulkLoadFallsBackToInsert(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+	plan := &fullCopyPlan{
+		selectList:  "*",
+		primaryKeys: []string{"id"},
+		keyTypes:    []string{"int"},
+		keyTargets:  []string{"id"},
+		bulk:        &bulkLoader{},
+		dialect:     mysqlDialect{},
+	}
+	columns := []string{"id", "note"}
+
+	// The first chunk is loaded, which the target refuses, so it and the next chunk are inserted
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` ORDER BY `id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("1"), []byte("a")).AddRow([]byte("2"), nil))
+	targetMock.ExpectExec(regexp.QuoteMeta("LOAD DATA LOCAL INFILE 'Reader::dbtaxi-") + `\d+' REPLACE INTO TABLE ` + regexp.QuoteMeta("`replica`.`orders`")).
+		WillReturnError(&mysql.MySQLError{Number: 3948, Message: "Loading local data is disabled"})
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`, `note`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE (`id`) > (?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("3"), []byte("c")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`, `note`) VALUES (?, ?) ON DUPLICATE KEY UPDATE")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := engine.copyTableByKeyset(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, plan, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), processed)
+	assert.True(t, plan.bulk.refused.Load())
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestLoadOrWriteRows(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	loader := &bulkLoader{}
+	rows := [][]interface{}{{int64(1)}}
+	write := func() error { t.Fatal("unexpected INSERT"); return nil }
+
+	// Loaded with the modifier of the write policy
+	targetMock.ExpectExec(`LOAD DATA LOCAL INFILE 'Reader::dbtaxi-\d+' IGNORE INTO TABLE`).WillReturnResult(sqlmock.NewResult(0, 1))
+	modifier, loadable := (&writePolicy{conflict: ConflictResolutionSkip}).bulkLoadModifier()
+	require.NoError(t, engine.loadOrWriteRows(context.Background(), loader, targetDB, "replica", "orders", []string{"id"}, rows, modifier, loadable, write))
+
+	// Errors other than a disabled local_infile fail the batch
+	targetMock.ExpectExec("LOAD DATA LOCAL INFILE").WillReturnError(&mysql.MySQLError{Number: 1146, Message: "Table 'replica.orders' doesn't exist"})
+	err := engine.loadOrWriteRows(context.Background(), loader, targetDB, "replica", "orders", []string{"id"}, rows, "REPLACE", true, write)
+	assert.ErrorContains(t, err, "failed to execute batch load")
+	assert.False(t, loader.refused.Load())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	// A policy updating some columns can only be written with an INSERT
+	_, loadable = (&writePolicy{conflict: ConflictResolutionOverwrite, updateColumns: []string{"note"}}).bulkLoadModifier()
+	assert.False(t, loadable)
+}
+
+// benchmarkRows returns rows shaped like a typical table, with values that need escaping
+func benchmarkRows(count int) ([]string, [][]interface{}) {
+	columns := []string{"id", "customer", "note", "payload", "amount", "created_at"}
+	rows := make([][]interface{}, count)
+	for i := range rows {
+		rows[i] = []interface{}{
+			int64(i),
+			[]byte(fmt.Sprintf("customer-%d", i)),
+			[]byte("line one\nline two\twith a tab"),
+			bytes.Repeat([]byte{0x00, 0x7f, 0xff, '\\'}, 16),
+			float64(i) * 1.25,
+			time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	}
+	return columns, rows
+}
+
+// newBenchmarkTarget returns an engine and a target accepting any statement
+func newBenchmarkTarget(b *testing.B) (*DefaultSyncEngine, *sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(string, string) error { return nil })))
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return &DefaultSyncEngine{logger: logger}, sqlx.NewDb(db, "sqlmock"), mock
+}
+
+// BenchmarkWriteBatch compares the client side cost of writing a batch with a multi-row INSERT and with
+// LOAD DATA LOCAL INFILE
+func BenchmarkWriteBatch(b *testing.B) {
+	columns, rows := benchmarkRows(1000)
+	upsert := (*writePolicy)(nil).clause(mysqlDialect{}, columns)
+
+	b.Run("insert", func(b *testing.B) {
+		engine, targetDB, mock := newBenchmarkTarget(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, int64(len(rows))))
+			if err := engine.writeRowsToDB(context.Background(), targetDB, mysqlDialect{}, "replica", "orders", columns, rows, upsert); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("load_data", func(b *testing.B) {
+		engine, targetDB, mock := newBenchmarkTarget(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// sqlmock doesn't read the file of the statement, so the rows are encoded as the driver would read them
+			if err := writeTSV(io.Discard, rows); err != nil {
+				b.Fatal(err)
+			}
+			mock.ExpectExec("").WillReturnResult(sqlmock.NewResult(0, int64(len(rows))))
+			if err := engine.loadRowsToDB(context.Background(), targetDB, "replica", "orders", columns, rows, "REPLACE"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
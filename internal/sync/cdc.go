@@ -0,0 +1,813 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// cdcCheckpointMode marks checkpoint data written by CDC mappings
+	cdcCheckpointMode = "cdc"
+	// cdcCheckpointInterval is the number of committed source transactions between checkpoint saves
+	cdcCheckpointInterval = 1000
+)
+
+// errCDCResyncRequired is returned when the binlog can no longer be applied to
+// the target table, e.g. after DDL on the source table; the next run performs a full sync
+var errCDCResyncRequired = errors.New("binlog can no longer be applied to the target table")
+
+// checkCDCRowSelection rejects a row filter or where clause on a CDC mapping. The binlog holds every
+// change of the table, and a change moving a row in or out of the filter can't be told from the
+// row images alone, e.g. for a filter relative to the time of the sync.
+func checkCDCRowSelection(mapping *TableMapping) error {
+	if mapping.SyncMode == SyncModeCDC && (mapping.RowFilter != nil || mapping.WhereClause != "") {
+		return fmt.Errorf("row filters are not supported by cdc sync, which applies every change of the binlog")
+	}
+	return nil
+}
+
+// cdcPosition is a position in the source binlog. When GTIDSet is set it
+// takes precedence over File/Position when resuming.
+type cdcPosition struct {
+	File     string
+	Position uint32
+	GTIDSet  string
+}
+
+func (p cdcPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Position)
+}
+
+// cdcCheckpointData is stored in SyncCheckpoint.CheckpointData for CDC mappings
+type cdcCheckpointData struct {
+	Mode     string `json:"mode"`
+	File     string `json:"file"`
+	Position uint32 `json:"position"`
+	GTIDSet  string `json:"gtid_set,omitempty"`
+}
+
+// SyncCDC synchronizes a table from the source binlog. The first run copies
+// the table with SyncFull and records the binlog position; later runs apply
+// the row changes logged since that position and stop once caught up.
+func (e *DefaultSyncEngine) SyncCDC(ctx context.Context, job *SyncJob, mapping *TableMapping) error {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+	}).Info("Starting CDC table synchronization")
+
+	if err := checkCDCRowSelection(mapping); err != nil {
+		return err
+	}
+
+	endpoints, err := e.openSyncEndpoints(ctx, mapping)
+	if err != nil {
+		return err
+	}
+	defer endpoints.Close()
+
+	checksum, err := checkBinlogSource(ctx, endpoints.sourceDB)
+	if err != nil {
+		return err
+	}
+
+	// Only a mapping without a CDC checkpoint starts over with a full copy, a failure to read it fails the run
+	position, err := e.loadCDCPosition(ctx, mapping)
+	if err != nil {
+		return fmt.Errorf("failed to load CDC checkpoint: %w", err)
+	}
+	if position == nil {
+		return e.syncCDCInitial(ctx, job, mapping, endpoints.sourceDB)
+	}
+
+	return e.syncCDCChanges(ctx, job, mapping, endpoints, *position, checksum)
+}
+
+// syncCDCInitial copies the table and records the binlog position for later runs.
+// The position is captured before the copy starts so changes made during the
+// copy are replayed by the next run; replaying them is idempotent.
+func (e *DefaultSyncEngine) syncCDCInitial(ctx context.Context, job *SyncJob, mapping *TableMapping, sourceDB *sqlx.DB) error {
+	position, err := captureBinlogPosition(ctx, sourceDB)
+	if err != nil {
+		return err
+	}
+
+	// Binlog replay starts at the position captured above, so chunks copied by an earlier
+	// attempt before that position are not trusted and the copy always starts over
+	if err := e.syncFull(ctx, job, mapping, false); err != nil {
+		return err
+	}
+
+	if err := e.saveCDCCheckpoint(ctx, mapping, position); err != nil {
+		return err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"job_id":          job.ID,
+		"source_table":    mapping.SourceTable,
+		"binlog_position": position.String(),
+		"gtid_set":        position.GTIDSet,
+	}).Info("Initial CDC copy completed, binlog position recorded")
+
+	return nil
+}
+
+// syncCDCChanges streams the binlog from the saved position and applies the table's row changes
+func (e *DefaultSyncEngine) syncCDCChanges(ctx context.Context, job *SyncJob, mapping *TableMapping, endpoints *syncEndpoints, position cdcPosition, checksum string) error {
+	schema, err := e.getTableSchemaFromRemote(ctx, endpoints.sourceDB, mapping.SourceTable)
+	if err != nil {
+		return fmt.Errorf("failed to get table schema: %w", err)
+	}
+
+	primaryKeys, err := e.getPrimaryKeyColumns(ctx, endpoints.sourceDB, mapping.SourceTable)
+	if err != nil {
+		return fmt.Errorf("CDC requires a primary key: %w", err)
+	}
+
+	targetSchema, err := mapping.columnMapping().targetSchema(schema)
+	if err != nil {
+		return err
+	}
+	masker, err := newRowMasker(mapping, endpoints.config.Options, e.maskingSecret, schema, targetSchema)
+	if err != nil {
+		return err
+	}
+	if err := e.ensureTargetTableExistsInDB(ctx, endpoints.targetDB, endpoints.dialect, endpoints.targetDBName, mapping.TargetTable, targetSchema); err != nil {
+		return fmt.Errorf("failed to ensure target table exists: %w", err)
+	}
+	if err := e.evolveTargetSchema(ctx, endpoints.targetDB, endpoints.targetDBName, mapping, targetSchema, false); err != nil {
+		return err
+	}
+
+	request := &binlogDumpRequest{
+		ServerID: cdcServerID(mapping, endpoints.config.Options),
+		File:     position.File,
+		Position: position.Position,
+		Checksum: checksum,
+	}
+	if position.GTIDSet != "" {
+		if request.GTIDSet, err = ParseGTIDSet(position.GTIDSet); err != nil {
+			return fmt.Errorf("invalid GTID set in CDC checkpoint: %w", err)
+		}
+	}
+
+	source, err := dialBinlogStream(ctx, endpoints.sourceConnConfig, request)
+	if err != nil {
+		return fmt.Errorf("failed to open binlog stream: %w", err)
+	}
+	defer source.Close()
+
+	conn, err := endpoints.targetDB.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get target connection: %w", err)
+	}
+	defer conn.Close()
+
+	logger := e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+	})
+
+	applier, err := newCDCApplier(ctx, conn, endpoints.sourceDBName, endpoints.targetDBName, mapping, schema, primaryKeys, position, checksum != "NONE", logger)
+	if err != nil {
+		return err
+	}
+	applier.masker = masker
+	applier.saveCheckpoint = func(ctx context.Context, position cdcPosition) error {
+		return e.saveCDCCheckpoint(ctx, mapping, position)
+	}
+
+	if err := applier.run(ctx, source); err != nil {
+		if errors.Is(err, errCDCResyncRequired) {
+			// An empty position makes the next run start over with a full copy
+			if resetErr := e.saveCDCCheckpoint(ctx, mapping, cdcPosition{}); resetErr != nil {
+				logger.WithError(resetErr).Warn("Failed to reset CDC checkpoint")
+			}
+		}
+		return fmt.Errorf("failed to apply binlog changes: %w", err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"inserted_rows":   applier.inserted,
+		"updated_rows":    applier.updated,
+		"deleted_rows":    applier.deleted,
+		"binlog_position": applier.position.String(),
+	}).Info("CDC table synchronization completed successfully")
+
+	return nil
+}
+
+// loadCDCPosition returns the saved binlog position, or nil when the mapping has no CDC checkpoint
+func (e *DefaultSyncEngine) loadCDCPosition(ctx context.Context, mapping *TableMapping) (*cdcPosition, error) {
+	checkpoint, err := e.repo.GetCheckpoint(ctx, mapping.ID)
+	if errors.Is(err, ErrCheckpointNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || checkpoint.CheckpointData == "" {
+		return nil, nil
+	}
+
+	var data cdcCheckpointData
+	if err := json.Unmarshal([]byte(checkpoint.CheckpointData), &data); err != nil {
+		return nil, nil // Written by another sync mode
+	}
+	if data.Mode != cdcCheckpointMode || (data.File == "" && data.GTIDSet == "") {
+		return nil, nil
+	}
+
+	return &cdcPosition{File: data.File, Position: data.Position, GTIDSet: data.GTIDSet}, nil
+}
+
+// saveCDCCheckpoint stores the binlog position in the mapping's checkpoint
+func (e *DefaultSyncEngine) saveCDCCheckpoint(ctx context.Context, mapping *TableMapping, position cdcPosition) error {
+	data, err := json.Marshal(cdcCheckpointData{
+		Mode:     cdcCheckpointMode,
+		File:     position.File,
+		Position: position.Position,
+		GTIDSet:  position.GTIDSet,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal CDC checkpoint: %w", err)
+	}
+
+	now := time.Now()
+	checkpoint := &SyncCheckpoint{
+		ID:             mapping.ID,
+		TableMappingID: mapping.ID,
+		LastSyncTime:   now,
+		LastSyncValue:  position.String(),
+		CheckpointData: string(data),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := e.repo.CreateCheckpoint(ctx, checkpoint); err != nil {
+		return fmt.Errorf("failed to save CDC checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkBinlogSource verifies the source logs row events and returns its binlog checksum setting
+func checkBinlogSource(ctx context.Context, sourceDB *sqlx.DB) (string, error) {
+	var format string
+	if err := sourceDB.GetContext(ctx, &format, "SELECT @@global.binlog_format"); err != nil {
+		return "", fmt.Errorf("failed to read binlog_format: %w", err)
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return "", fmt.Errorf("CDC requires binlog_format=ROW on the source, got %s", format)
+	}
+
+	checksum := "NONE"
+	if err := sourceDB.GetContext(ctx, &checksum, "SELECT @@global.binlog_checksum"); err != nil {
+		checksum = "NONE" // Servers before 5.6.2 have no checksums
+	}
+	return strings.ToUpper(checksum), nil
+}
+
+// captureBinlogPosition reads the current binlog coordinates of the source
+func captureBinlogPosition(ctx context.Context, sourceDB *sqlx.DB) (cdcPosition, error) {
+	rows, err := sourceDB.QueryxContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		// MySQL 8.4 renamed the statement
+		if rows, err = sourceDB.QueryxContext(ctx, "SHOW BINARY LOG STATUS"); err != nil {
+			return cdcPosition{}, fmt.Errorf("failed to read binlog position: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return cdcPosition{}, fmt.Errorf("failed to read binlog position: %w", err)
+		}
+		return cdcPosition{}, fmt.Errorf("binary logging is not enabled on the source")
+	}
+
+	row := make(map[string]interface{})
+	if err := rows.MapScan(row); err != nil {
+		return cdcPosition{}, fmt.Errorf("failed to scan binlog position: %w", err)
+	}
+
+	position := cdcPosition{File: asString(row["File"])}
+	var offset int64
+	if _, err := fmt.Sscan(asString(row["Position"]), &offset); err != nil {
+		return cdcPosition{}, fmt.Errorf("invalid binlog position %v", row["Position"])
+	}
+	position.Position = uint32(offset)
+	position.GTIDSet = strings.ReplaceAll(asString(row["Executed_Gtid_Set"]), "\n", "")
+
+	return position, nil
+}
+
+// asString converts a scanned column value to a string
+func asString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(value)
+	case string:
+		return value
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// cdcServerID returns the replica server_id used for the binlog stream. It must
+// differ from the server_id of every server and replica in the topology.
+func cdcServerID(mapping *TableMapping, options *SyncOptions) uint32 {
+	if options != nil && options.CDCServerID != 0 {
+		return options.CDCServerID
+	}
+	// Stay clear of the small IDs usually assigned by hand
+	return crc32.ChecksumIEEE([]byte(mapping.ID))%(1<<31) + 1<<31
+}
+
+// cdcDDLPattern matches statements that change or remove an existing table
+var cdcDDLPattern = regexp.MustCompile("(?is)^\\s*(ALTER\\s+(ONLINE\\s+|IGNORE\\s+)?TABLE|DROP\\s+(TEMPORARY\\s+)?TABLE|TRUNCATE(\\s+TABLE)?|RENAME\\s+TABLE)\\s+(.*)$")
+
+// cdcApplier applies the row events of one source table to the target table.
+// Changes are applied in a target transaction that commits together with the
+// source transaction, so the saved position never points into a transaction.
+type cdcApplier struct {
+	logger       *logrus.Entry
+	conn         *sqlx.Conn
+	sourceSchema string
+	sourceTable  string
+	targetTable  string // Qualified and quoted target table name
+	columns      []string
+	targets      []string      // Target column per source column, empty if column rules leave it out
+	constants    []*ColumnRule // Constant columns written with every row
+	masker       *rowMasker    // Masks row images before they are applied, nil without masking rules
+	unsigned     []bool
+	pkIndexes    []int
+	upsertSQL    string
+	deleteSQL    string
+
+	parser      *binlogParser
+	position    cdcPosition
+	gtids       *GTIDSet
+	pendingGTID *binlogGTID
+	tx          *sqlx.Tx
+	commits     int
+
+	// saveCheckpoint persists the position of the last committed transaction
+	saveCheckpoint func(ctx context.Context, position cdcPosition) error
+
+	inserted int64
+	updated  int64
+	deleted  int64
+}
+
+// newCDCApplier prepares an applier for the mapping. conn is a dedicated target
+// connection; its session time zone is set to UTC because TIMESTAMP values in
+// the binlog are UTC.
+func newCDCApplier(ctx context.Context, conn *sqlx.Conn, sourceDBName, targetDBName string, mapping *TableMapping, schema *TableSchema, primaryKeys []string, position cdcPosition, checksum bool, logger *logrus.Entry) (*cdcApplier, error) {
+	a := &cdcApplier{
+		logger:         logger,
+		conn:           conn,
+		sourceSchema:   sourceDBName,
+		sourceTable:    mapping.SourceTable,
+		targetTable:    fmt.Sprintf("`%s`.`%s`", targetDBName, mapping.TargetTable),
+		parser:         newBinlogParser(checksum),
+		position:       position,
+		saveCheckpoint: func(context.Context, cdcPosition) error { return nil },
+	}
+
+	// Expression columns are computed by the source database, which binlog rows never pass through
+	columns := mapping.columnMapping()
+	if columns.hasExpressions() {
+		return nil, fmt.Errorf("CDC cannot compute expression columns of table %s, use a constant column or another sync mode", mapping.SourceTable)
+	}
+	if columns != nil {
+		a.constants = columns.derived
+	}
+
+	for _, column := range schema.Columns {
+		target, _ := columns.targetName(column.Name)
+		if columns == nil && column.GenerationExpression != "" {
+			// The target table computes generated columns itself
+			target = ""
+		}
+		a.columns = append(a.columns, column.Name)
+		a.targets = append(a.targets, target)
+		a.unsigned = append(a.unsigned, strings.Contains(strings.ToLower(column.Type), "unsigned"))
+	}
+
+	for _, pk := range primaryKeys {
+		index := -1
+		for i, column := range a.columns {
+			if column == pk {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("primary key column %s not found in table schema", pk)
+		}
+		if a.targets[index] == "" {
+			return nil, fmt.Errorf("CDC requires primary key column %s, but column rules exclude it", pk)
+		}
+		a.pkIndexes = append(a.pkIndexes, index)
+	}
+
+	if position.GTIDSet != "" {
+		gtids, err := ParseGTIDSet(position.GTIDSet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GTID set in CDC checkpoint: %w", err)
+		}
+		a.gtids = gtids
+	}
+
+	a.upsertSQL = a.buildUpsert()
+	a.deleteSQL = fmt.Sprintf("DELETE FROM %s WHERE %s", a.targetTable, a.pkCondition())
+
+	if _, err := conn.ExecContext(ctx, "SET time_zone = '+00:00'"); err != nil {
+		return nil, fmt.Errorf("failed to set target session time zone: %w", err)
+	}
+
+	return a, nil
+}
+
+// run applies events from the source until it is exhausted, then saves the
+// position of the last committed transaction. A transaction that is still
+// open at the end is rolled back and read again by the next run.
+func (a *cdcApplier) run(ctx context.Context, source binlogEventSource) error {
+	defer a.rollback()
+
+	for {
+		raw, err := source.NextEvent(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read binlog event after %s: %w", a.position, err)
+		}
+
+		event, err := a.parser.parse(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse binlog event after %s: %w", a.position, err)
+		}
+
+		if err := a.handle(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return a.saveCheckpoint(ctx, a.position)
+}
+
+// handle dispatches one decoded event
+func (a *cdcApplier) handle(ctx context.Context, event *BinlogEvent) error {
+	switch ev := event.Event.(type) {
+	case *binlogRotate:
+		a.position.File = ev.NextFile
+		a.position.Position = uint32(ev.Position)
+	case *binlogGTID:
+		a.pendingGTID = ev
+	case *binlogXID:
+		return a.commit(ctx, event.Header)
+	case *binlogQuery:
+		return a.handleQuery(ctx, ev, event.Header)
+	case *BinlogRowsEvent:
+		return a.applyRows(ctx, ev)
+	}
+	return nil
+}
+
+// handleQuery handles transaction control statements and DDL
+func (a *cdcApplier) handleQuery(ctx context.Context, ev *binlogQuery, header BinlogEventHeader) error {
+	query := strings.TrimSpace(ev.Query)
+	switch strings.ToUpper(query) {
+	case "BEGIN":
+		return nil
+	case "COMMIT":
+		return a.commit(ctx, header)
+	case "ROLLBACK":
+		a.rollback()
+		return nil
+	}
+
+	if a.affectsTable(ev.Schema, query) {
+		return fmt.Errorf("source table %s.%s was changed by DDL at %s:%d (%s): %w",
+			a.sourceSchema, a.sourceTable, a.position.File, header.LogPos, query, errCDCResyncRequired)
+	}
+
+	// Any other statement is a transaction of its own
+	return a.commit(ctx, header)
+}
+
+// affectsTable reports whether a DDL statement alters, drops, truncates or renames the source table
+func (a *cdcApplier) affectsTable(schema, query string) bool {
+	match := cdcDDLPattern.FindStringSubmatch(query)
+	if match == nil {
+		return false
+	}
+	targets := match[len(match)-1]
+	if schema != a.sourceSchema && !strings.Contains(targets, a.sourceSchema) {
+		return false
+	}
+
+	pattern := "(^|[\\s,.`])" + regexp.QuoteMeta(a.sourceTable) + "($|[\\s,.;`(])"
+	matched, _ := regexp.MatchString(pattern, targets)
+	return matched
+}
+
+// applyRows applies a rows event if it belongs to the source table
+func (a *cdcApplier) applyRows(ctx context.Context, ev *BinlogRowsEvent) error {
+	if ev.Table.Schema != a.sourceSchema || ev.Table.Table != a.sourceTable {
+		return nil
+	}
+	if len(ev.Table.ColumnTypes) != len(a.columns) {
+		return fmt.Errorf("binlog row for %s.%s has %d columns but the table has %d: %w",
+			a.sourceSchema, a.sourceTable, len(ev.Table.ColumnTypes), len(a.columns), errCDCResyncRequired)
+	}
+
+	tx, err := a.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range ev.Rows {
+		before := a.convertRow(ev.Table, row.Before)
+		after := a.convertRow(ev.Table, row.After)
+		if err := a.maskRow(before); err != nil {
+			return err
+		}
+		if err := a.maskRow(after); err != nil {
+			return err
+		}
+
+		switch ev.Action {
+		case BinlogRowInsert:
+			if err := a.upsert(ctx, tx, after); err != nil {
+				return err
+			}
+			a.inserted++
+		case BinlogRowUpdate:
+			if err := a.update(ctx, tx, before, row.BeforePresent, after, row.AfterPresent); err != nil {
+				return err
+			}
+			a.updated++
+		case BinlogRowDelete:
+			if err := a.delete(ctx, tx, before, row.BeforePresent); err != nil {
+				return err
+			}
+			a.deleted++
+		}
+	}
+
+	return nil
+}
+
+// upsert writes a full row image
+func (a *cdcApplier) upsert(ctx context.Context, tx *sqlx.Tx, values []interface{}) error {
+	args := make([]interface{}, 0, len(values))
+	for i, v := range values {
+		if a.targets[i] != "" {
+			args = append(args, valueForUTF8MB3Insert(v))
+		}
+	}
+	if _, err := tx.ExecContext(ctx, a.upsertSQL, args...); err != nil {
+		return fmt.Errorf("failed to apply binlog insert: %w", err)
+	}
+	return nil
+}
+
+// update applies an update. A changed primary key removes the old row first;
+// a partial after image (binlog_row_image=MINIMAL) only updates the logged columns.
+func (a *cdcApplier) update(ctx context.Context, tx *sqlx.Tx, before []interface{}, beforePresent []bool, after []interface{}, afterPresent []bool) error {
+	full := true
+	for _, present := range afterPresent {
+		full = full && present
+	}
+
+	if full {
+		if a.keyPresent(beforePresent) && !a.sameKey(before, after) {
+			if err := a.delete(ctx, tx, before, beforePresent); err != nil {
+				return err
+			}
+		}
+		return a.upsert(ctx, tx, after)
+	}
+
+	if !a.keyPresent(beforePresent) {
+		return fmt.Errorf("binlog update for %s.%s does not contain the primary key", a.sourceSchema, a.sourceTable)
+	}
+
+	var assignments []string
+	var args []interface{}
+	for i, column := range a.targets {
+		if afterPresent[i] && column != "" {
+			assignments = append(assignments, fmt.Sprintf("`%s` = ?", column))
+			args = append(args, valueForUTF8MB3Insert(after[i]))
+		}
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+	for _, index := range a.pkIndexes {
+		args = append(args, before[index])
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", a.targetTable, strings.Join(assignments, ", "), a.pkCondition())
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to apply binlog update: %w", err)
+	}
+	return nil
+}
+
+// delete removes the row identified by the primary key of a before image
+func (a *cdcApplier) delete(ctx context.Context, tx *sqlx.Tx, before []interface{}, present []bool) error {
+	if !a.keyPresent(present) {
+		return fmt.Errorf("binlog delete for %s.%s does not contain the primary key", a.sourceSchema, a.sourceTable)
+	}
+	args := make([]interface{}, 0, len(a.pkIndexes))
+	for _, index := range a.pkIndexes {
+		args = append(args, before[index])
+	}
+	if _, err := tx.ExecContext(ctx, a.deleteSQL, args...); err != nil {
+		return fmt.Errorf("failed to apply binlog delete: %w", err)
+	}
+	return nil
+}
+
+// begin returns the open target transaction, starting one if needed
+func (a *cdcApplier) begin(ctx context.Context) (*sqlx.Tx, error) {
+	if a.tx != nil {
+		return a.tx, nil
+	}
+	tx, err := a.conn.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin target transaction: %w", err)
+	}
+	a.tx = tx
+	return tx, nil
+}
+
+// commit commits the target transaction and advances the position past the source transaction
+func (a *cdcApplier) commit(ctx context.Context, header BinlogEventHeader) error {
+	if a.tx != nil {
+		if err := a.tx.Commit(); err != nil {
+			a.tx = nil
+			return fmt.Errorf("failed to commit target transaction: %w", err)
+		}
+		a.tx = nil
+		ReportTableProgress(ctx, a.sourceTable, TableStatusRunning, a.applied(), a.applied())
+	}
+
+	if header.LogPos > 0 {
+		a.position.Position = header.LogPos
+	}
+	if a.pendingGTID != nil {
+		if a.gtids != nil {
+			a.gtids.Add(a.pendingGTID.SID, a.pendingGTID.GNO)
+			a.position.GTIDSet = a.gtids.String()
+		}
+		a.pendingGTID = nil
+	}
+
+	a.commits++
+	if a.commits%cdcCheckpointInterval == 0 {
+		if err := a.saveCheckpoint(ctx, a.position); err != nil {
+			return err
+		}
+		a.logger.WithField("binlog_position", a.position.String()).Debug("CDC checkpoint saved")
+	}
+	return nil
+}
+
+// rollback discards the open target transaction, if any
+func (a *cdcApplier) rollback() {
+	if a.tx != nil {
+		a.tx.Rollback()
+		a.tx = nil
+	}
+	a.pendingGTID = nil
+}
+
+// applied is the number of row changes applied so far
+func (a *cdcApplier) applied() int64 {
+	return a.inserted + a.updated + a.deleted
+}
+
+// convertRow reinterprets integers of unsigned columns, which the binlog stores as signed
+func (a *cdcApplier) convertRow(table *BinlogTableMap, values []interface{}) []interface{} {
+	if values == nil {
+		return nil
+	}
+	for i, v := range values {
+		if n, ok := v.(int64); ok && a.unsigned[i] && n < 0 {
+			values[i] = unsignedBinlogInt(table.ColumnTypes[i], n)
+		}
+	}
+	return values
+}
+
+// maskRow masks the values of a row image in place by their target columns
+func (a *cdcApplier) maskRow(values []interface{}) error {
+	if a.masker == nil {
+		return nil
+	}
+	for i, v := range values {
+		if a.targets[i] == "" {
+			continue
+		}
+		masked, err := a.masker.maskValue(a.targets[i], v)
+		if err != nil {
+			return err
+		}
+		values[i] = masked
+	}
+	return nil
+}
+
+// unsignedBinlogInt converts a negative signed value to the unsigned value of the same width
+func unsignedBinlogInt(columnType byte, v int64) uint64 {
+	switch columnType {
+	case mysqlTypeTiny:
+		return uint64(uint8(v))
+	case mysqlTypeShort:
+		return uint64(uint16(v))
+	case mysqlTypeInt24:
+		return uint64(v) & 0xffffff
+	case mysqlTypeLong:
+		return uint64(uint32(v))
+	}
+	return uint64(v)
+}
+
+// keyPresent reports whether all primary key columns are in a row image
+func (a *cdcApplier) keyPresent(present []bool) bool {
+	for _, index := range a.pkIndexes {
+		if !present[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKey reports whether two row images have the same primary key
+func (a *cdcApplier) sameKey(before, after []interface{}) bool {
+	for _, index := range a.pkIndexes {
+		if !reflect.DeepEqual(before[index], after[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pkCondition returns the WHERE condition matching one row by primary key
+func (a *cdcApplier) pkCondition() string {
+	conditions := make([]string, len(a.pkIndexes))
+	for i, index := range a.pkIndexes {
+		conditions[i] = fmt.Sprintf("`%s` = ?", a.targets[index])
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// buildUpsert returns an INSERT ... ON DUPLICATE KEY UPDATE statement for a full row
+func (a *cdcApplier) buildUpsert() string {
+	isKey := make(map[int]bool, len(a.pkIndexes))
+	for _, index := range a.pkIndexes {
+		isKey[index] = true
+	}
+
+	var quoted, placeholders, updates []string
+	for i, column := range a.targets {
+		if column == "" {
+			continue
+		}
+		quoted = append(quoted, fmt.Sprintf("`%s`", column))
+		placeholders = append(placeholders, "?")
+		if !isKey[i] {
+			updates = append(updates, fmt.Sprintf("`%s` = VALUES(`%s`)", column, column))
+		}
+	}
+	for _, rule := range a.constants {
+		quoted = append(quoted, fmt.Sprintf("`%s`", rule.TargetColumn))
+		placeholders = append(placeholders, sqlStringLiteral(rule.Value))
+		updates = append(updates, fmt.Sprintf("`%s` = VALUES(`%s`)", rule.TargetColumn, rule.TargetColumn))
+	}
+	if len(updates) == 0 {
+		key := a.targets[a.pkIndexes[0]]
+		updates = append(updates, fmt.Sprintf("`%s` = `%s`", key, key))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		a.targetTable, strings.Join(quoted, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+}
@@ -0,0 +1,226 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// binlogOrdersSchema matches shop.orders in the binlog fixture
+var binlogOrdersSchema = &TableSchema{
+	Name: "orders",
+	Columns: []*ColumnInfo{
+		{Name: "id", Type: "int unsigned"},
+		{Name: "customer", Type: "varchar(64)"},
+		{Name: "amount", Type: "decimal(10,2)"},
+		{Name: "status", Type: "enum('new','paid','shipped')"},
+		{Name: "created_at", Type: "datetime"},
+		{Name: "note", Type: "json", Nullable: true},
+	},
+}
+
+// newTestCDCApplier creates an applier for shop.orders writing to a sqlmock target
+func newTestCDCApplier(t *testing.T, position cdcPosition) (*cdcApplier, sqlmock.Sqlmock) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	ctx := context.Background()
+	conn, err := targetDB.Connx(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	targetMock.ExpectExec(regexp.QuoteMeta("SET time_zone = '+00:00'")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders_copy", SyncMode: SyncModeCDC}
+	applier, err := newCDCApplier(ctx, conn, "shop", "replica", mapping, binlogOrdersSchema, []string{"id"}, position, false, logrus.NewEntry(engine.logger))
+	require.NoError(t, err)
+	return applier, targetMock
+}
+
+func TestCDCApplier_ReplaysFixture(t *testing.T) {
+	start := cdcPosition{File: "mysql-bin.000001", Position: 4, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1"}
+	applier, targetMock := newTestCDCApplier(t, start)
+
+	upsert := regexp.QuoteMeta("INSERT INTO `replica`.`orders_copy` (`id`, `customer`, `amount`, `status`, `created_at`, `note`) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE `customer` = VALUES(`customer`)")
+	note := `{"gift":true,"qty":2}`
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(upsert).
+		WithArgs(int64(1), []byte("alice"), "123.45", int64(1), "2024-03-15 10:20:30", note).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(upsert).
+		WithArgs(uint64(3000000000), []byte("bob"), "-7.50", int64(2), "2024-03-16 08:00:00", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectCommit()
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(upsert).
+		WithArgs(int64(1), []byte("alice"), "200.00", int64(3), "2024-03-15 10:20:30", note).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	targetMock.ExpectCommit()
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `replica`.`orders_copy` WHERE `id` = ?")).
+		WithArgs(uint64(3000000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectCommit()
+
+	var saved []cdcPosition
+	applier.saveCheckpoint = func(ctx context.Context, position cdcPosition) error {
+		saved = append(saved, position)
+		return nil
+	}
+
+	source, err := openBinlogFile(ordersBinlogFixture)
+	require.NoError(t, err)
+	defer source.Close()
+
+	require.NoError(t, applier.run(context.Background(), source))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	assert.Equal(t, int64(2), applier.inserted)
+	assert.Equal(t, int64(1), applier.updated)
+	assert.Equal(t, int64(1), applier.deleted)
+	assert.Equal(t, []cdcPosition{{
+		File:     "mysql-bin.000002",
+		Position: 4,
+		GTIDSet:  "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-4",
+	}}, saved)
+}
+
+func TestCDCApplier_PartialUpdate(t *testing.T) {
+	applier, targetMock := newTestCDCApplier(t, cdcPosition{File: "mysql-bin.000001", Position: 4})
+
+	// binlog_row_image=MINIMAL logs the key in the before image and changed columns in the after image
+	table := &BinlogTableMap{Schema: "shop", Table: "orders", ColumnTypes: make([]byte, 6)}
+	event := &BinlogRowsEvent{
+		Action: BinlogRowUpdate,
+		Table:  table,
+		Rows: []*BinlogRowChange{{
+			Before:        []interface{}{int64(1), nil, nil, nil, nil, nil},
+			BeforePresent: []bool{true, false, false, false, false, false},
+			After:         []interface{}{nil, nil, "9.99", nil, nil, nil},
+			AfterPresent:  []bool{false, false, true, false, false, false},
+		}},
+	}
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(regexp.QuoteMeta("UPDATE `replica`.`orders_copy` SET `amount` = ? WHERE `id` = ?")).
+		WithArgs("9.99", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectCommit()
+
+	ctx := context.Background()
+	require.NoError(t, applier.applyRows(ctx, event))
+	require.NoError(t, applier.commit(ctx, BinlogEventHeader{LogPos: 500}))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+	assert.Equal(t, uint32(500), applier.position.Position)
+}
+
+func TestCDCApplier_PrimaryKeyChange(t *testing.T) {
+	applier, targetMock := newTestCDCApplier(t, cdcPosition{File: "mysql-bin.000001", Position: 4})
+
+	full := []bool{true, true, true, true, true, true}
+	event := &BinlogRowsEvent{
+		Action: BinlogRowUpdate,
+		Table:  &BinlogTableMap{Schema: "shop", Table: "orders", ColumnTypes: make([]byte, 6)},
+		Rows: []*BinlogRowChange{{
+			Before:        []interface{}{int64(1), []byte("alice"), "1.00", int64(1), "2024-03-15 10:20:30", nil},
+			BeforePresent: full,
+			After:         []interface{}{int64(2), []byte("alice"), "1.00", int64(1), "2024-03-15 10:20:30", nil},
+			AfterPresent:  full,
+		}},
+	}
+
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `replica`.`orders_copy` WHERE `id` = ?")).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec("INSERT INTO `replica`.`orders_copy`").
+		WithArgs(int64(2), []byte("alice"), "1.00", int64(1), "2024-03-15 10:20:30", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectRollback()
+
+	require.NoError(t, applier.applyRows(context.Background(), event))
+	applier.rollback()
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestCDCApplier_StopsOnSchemaChange(t *testing.T) {
+	applier, _ := newTestCDCApplier(t, cdcPosition{File: "mysql-bin.000001", Position: 4})
+	ctx := context.Background()
+
+	err := applier.handleQuery(ctx, &binlogQuery{Schema: "shop", Query: "ALTER TABLE `orders` ADD COLUMN `x` INT"}, BinlogEventHeader{LogPos: 900})
+	assert.ErrorIs(t, err, errCDCResyncRequired)
+
+	err = applier.handleQuery(ctx, &binlogQuery{Schema: "other", Query: "TRUNCATE TABLE shop.orders"}, BinlogEventHeader{LogPos: 900})
+	assert.ErrorIs(t, err, errCDCResyncRequired)
+
+	// DDL on other tables, or a same-named table in another schema, is ignored
+	assert.NoError(t, applier.handleQuery(ctx, &binlogQuery{Schema: "shop", Query: "ALTER TABLE orders_archive ADD INDEX (id)"}, BinlogEventHeader{LogPos: 950}))
+	assert.NoError(t, applier.handleQuery(ctx, &binlogQuery{Schema: "other", Query: "DROP TABLE orders"}, BinlogEventHeader{LogPos: 1000}))
+	assert.Equal(t, uint32(1000), applier.position.Position)
+
+	event := &BinlogRowsEvent{
+		Action: BinlogRowInsert,
+		Table:  &BinlogTableMap{Schema: "shop", Table: "orders", ColumnTypes: make([]byte, 7)},
+	}
+	err = applier.applyRows(ctx, event)
+	assert.ErrorIs(t, err, errCDCResyncRequired)
+	assert.ErrorContains(t, err, "has 7 columns but the table has 6")
+}
+
+func TestCDCCheckpointRoundTrip(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	engine := &DefaultSyncEngine{repo: mockRepo, logger: logger}
+	ctx := context.Background()
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders"}
+
+	var stored *SyncCheckpoint
+	mockRepo.On("CreateCheckpoint", ctx, mock.AnythingOfType("*sync.SyncCheckpoint")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*SyncCheckpoint) }).
+		Return(nil)
+
+	position := cdcPosition{File: "mysql-bin.000042", Position: 1234, GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-9"}
+	require.NoError(t, engine.saveCDCCheckpoint(ctx, mapping, position))
+	require.NotNil(t, stored)
+	assert.Equal(t, "mapping-1", stored.TableMappingID)
+	assert.Equal(t, "mysql-bin.000042:1234", stored.LastSyncValue)
+
+	mockRepo.On("GetCheckpoint", ctx, "mapping-1").Return(stored, nil)
+	loaded, err := engine.loadCDCPosition(ctx, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, &position, loaded)
+
+	// Checkpoints written by incremental sync are not CDC positions
+	other := &TableMapping{ID: "mapping-2"}
+	mockRepo.On("GetCheckpoint", ctx, "mapping-2").Return(&SyncCheckpoint{LastSyncValue: "100"}, nil)
+	loaded, err = engine.loadCDCPosition(ctx, other)
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	// A mapping without a checkpoint starts with a full copy, a failure to read the checkpoint doesn't
+	mockRepo.On("GetCheckpoint", ctx, "mapping-3").Return((*SyncCheckpoint)(nil), fmt.Errorf("%w for table mapping: mapping-3", ErrCheckpointNotFound))
+	loaded, err = engine.loadCDCPosition(ctx, &TableMapping{ID: "mapping-3"})
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+	mockRepo.On("GetCheckpoint", ctx, "mapping-4").Return((*SyncCheckpoint)(nil), fmt.Errorf("failed to get checkpoint: %w", assert.AnError))
+	_, err = engine.loadCDCPosition(ctx, &TableMapping{ID: "mapping-4"})
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestCDCServerID(t *testing.T) {
+	mapping := &TableMapping{ID: "mapping-1"}
+
+	derived := cdcServerID(mapping, nil)
+	assert.GreaterOrEqual(t, derived, uint32(1<<31))
+	assert.Equal(t, derived, cdcServerID(mapping, &SyncOptions{}))
+	assert.Equal(t, uint32(4242), cdcServerID(mapping, &SyncOptions{CDCServerID: 4242}))
+}
@@ -38,13 +38,25 @@ type JobCheckpoint struct {
 
 // TableCheckpoint represents a checkpoint for table synchronization
 type TableCheckpoint struct {
-	TableName          string      `json:"table_name"`
-	LastProcessedID    interface{} `json:"last_processed_id,omitempty"`
-	LastProcessedValue string      `json:"last_processed_value,omitempty"`
-	ProcessedRows      int64       `json:"processed_rows"`
-	TotalRows          int64       `json:"total_rows"`
-	BatchNumber        int         `json:"batch_number"`
-	Timestamp          time.Time   `json:"timestamp"`
+	TableName          string       `json:"table_name"`
+	KeyColumns         []string     `json:"key_columns,omitempty"`  // Primary key of a keyset copy, LastProcessedID then holds the last copied key
+	TargetTable        string       `json:"target_table,omitempty"` // Table a keyset copy loads into, a shadow table when swapping
+	LastProcessedID    interface{}  `json:"last_processed_id,omitempty"`
+	Ranges             []*CopyRange `json:"ranges,omitempty"` // Key ranges of a parallel keyset copy, which has no single last copied key
+	LastProcessedValue string       `json:"last_processed_value,omitempty"`
+	ProcessedRows      int64        `json:"processed_rows"`
+	TotalRows          int64        `json:"total_rows"`
+	BatchNumber        int          `json:"batch_number"`
+	Timestamp          time.Time    `json:"timestamp"`
+}
+
+// CopyRange is a range of a parallel keyset copy, bounded on the leading primary key column
+type CopyRange struct {
+	Lower         string   `json:"lower,omitempty"`    // Exclusive lower bound, unbounded when empty
+	Upper         string   `json:"upper,omitempty"`    // Inclusive upper bound, unbounded when empty
+	LastKey       []string `json:"last_key,omitempty"` // Last copied key of the range
+	ProcessedRows int64    `json:"processed_rows"`
+	Done          bool     `json:"done,omitempty"`
 }
 
 // SaveJobCheckpoint saves a checkpoint for a job
@@ -0,0 +1,286 @@
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// derivedColumnTypePattern matches the column types accepted for constant and expression columns
+var derivedColumnTypePattern = regexp.MustCompile(`^(?i)[a-z]+(\(\d+(,\s*\d+)?\))?(\s+unsigned)?$`)
+
+// columnMapping applies the column rules of a table mapping. A nil columnMapping copies every
+// source column under its own name.
+type columnMapping struct {
+	include map[string]bool   // Lowercased source columns of include rules, nil without include rules
+	exclude map[string]bool   // Lowercased source columns of exclude rules
+	renames map[string]string // Target column per lowercased source column
+	derived []*ColumnRule     // Constant and expression rules, in rule order
+}
+
+// columnMapping builds the column mapping of the table mapping's rules, nil when it has none
+func (m *TableMapping) columnMapping() *columnMapping {
+	if len(m.ColumnRules) == 0 {
+		return nil
+	}
+
+	c := &columnMapping{
+		exclude: make(map[string]bool),
+		renames: make(map[string]string),
+	}
+	for _, rule := range m.ColumnRules {
+		source := strings.ToLower(rule.SourceColumn)
+		switch rule.RuleType {
+		case ColumnRuleInclude:
+			if c.include == nil {
+				c.include = make(map[string]bool)
+			}
+			c.include[source] = true
+		case ColumnRuleExclude:
+			c.exclude[source] = true
+		case ColumnRuleRename:
+			c.renames[source] = rule.TargetColumn
+		case ColumnRuleConstant, ColumnRuleExpression:
+			c.derived = append(c.derived, rule)
+		}
+	}
+	return c
+}
+
+// targetName returns the target column a source column is copied into, false if it is not copied
+func (c *columnMapping) targetName(source string) (string, bool) {
+	if c == nil {
+		return source, true
+	}
+	key := strings.ToLower(source)
+	if c.exclude[key] || (c.include != nil && !c.include[key]) {
+		return "", false
+	}
+	if target, ok := c.renames[key]; ok {
+		return target, true
+	}
+	return source, true
+}
+
+// hasExpressions reports whether any target column is computed by the source database
+func (c *columnMapping) hasExpressions() bool {
+	if c == nil {
+		return false
+	}
+	for _, rule := range c.derived {
+		if rule.RuleType == ColumnRuleExpression {
+			return true
+		}
+	}
+	return false
+}
+
+// selectList returns the select list reading the source table as the target table's columns,
+// so the column names of the result are the target column names. Without column rules generated
+// columns are left out, the target table computes them itself.
+func (c *columnMapping) selectList(schema *TableSchema) string {
+	if c == nil {
+		var columns []string
+		for _, col := range schema.Columns {
+			if col.GenerationExpression == "" {
+				columns = append(columns, col.Name)
+			}
+		}
+		if len(columns) == len(schema.Columns) {
+			return "*"
+		}
+		return quoteColumns(columns)
+	}
+
+	var items []string
+	for _, col := range schema.Columns {
+		target, ok := c.targetName(col.Name)
+		switch {
+		case !ok:
+		case target == col.Name:
+			items = append(items, fmt.Sprintf("`%s`", col.Name))
+		default:
+			items = append(items, fmt.Sprintf("`%s` AS `%s`", col.Name, target))
+		}
+	}
+	for _, rule := range c.derived {
+		items = append(items, fmt.Sprintf("%s AS `%s`", derivedColumnValue(rule), rule.TargetColumn))
+	}
+	return strings.Join(items, ", ")
+}
+
+// targetSchema returns the schema of the target table: copied columns under their target names
+// followed by the derived columns. Indexes and keys covering a column that is not copied are left out.
+// Generated columns become regular columns holding the copied values; CHECK constraints, partitioning
+// and indexes on expressions are left out, as their expressions name source columns.
+func (c *columnMapping) targetSchema(schema *TableSchema) (*TableSchema, error) {
+	if c == nil {
+		return schema, nil
+	}
+
+	target := &TableSchema{
+		Name:           schema.Name,
+		TableCharset:   schema.TableCharset,
+		TableCollation: schema.TableCollation,
+		Engine:         schema.Engine,
+		RowFormat:      schema.RowFormat,
+		Comment:        schema.Comment,
+	}
+	seen := make(map[string]bool)
+	addColumn := func(col *ColumnInfo) error {
+		key := strings.ToLower(col.Name)
+		if seen[key] {
+			return fmt.Errorf("column rules map more than one column to target column %s", col.Name)
+		}
+		seen[key] = true
+		target.Columns = append(target.Columns, col)
+		return nil
+	}
+
+	for _, col := range schema.Columns {
+		name, ok := c.targetName(col.Name)
+		if !ok {
+			continue
+		}
+		renamed := *col
+		renamed.Name = name
+		renamed.GenerationExpression = ""
+		renamed.Extra = strings.Join(strings.Fields(generatedExtra.ReplaceAllString(col.Extra, "")), " ")
+		if err := addColumn(&renamed); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range c.derived {
+		columnType := rule.ColumnType
+		if columnType == "" {
+			columnType = defaultDerivedColumnType
+		}
+		if err := addColumn(&ColumnInfo{Name: rule.TargetColumn, Type: columnType, Nullable: true}); err != nil {
+			return nil, err
+		}
+	}
+	if len(target.Columns) == 0 {
+		return nil, fmt.Errorf("column rules leave no columns to copy from table %s", schema.Name)
+	}
+
+	for _, idx := range schema.Indexes {
+		if hasExpressionKeyPart(idx) {
+			continue
+		}
+		if columns, ok := c.targetColumns(idx.Columns); ok {
+			mapped := *idx
+			mapped.Columns = columns
+			mapped.Parts = renameKeyParts(idx.Parts, idx.Columns, columns)
+			target.Indexes = append(target.Indexes, &mapped)
+		}
+	}
+	for _, key := range schema.Keys {
+		if columns, ok := c.targetColumns(key.Columns); ok {
+			mapped := *key
+			mapped.Columns = columns
+			target.Keys = append(target.Keys, &mapped)
+		}
+	}
+	for _, fk := range schema.ForeignKeys {
+		if columns, ok := c.targetColumns(fk.Columns); ok {
+			mapped := *fk
+			mapped.Columns = columns
+			target.ForeignKeys = append(target.ForeignKeys, &mapped)
+		}
+	}
+
+	return target, nil
+}
+
+// targetColumns maps source columns to their target names, false if any of them is not copied
+func (c *columnMapping) targetColumns(columns []string) ([]string, bool) {
+	mapped := make([]string, len(columns))
+	for i, col := range columns {
+		name, ok := c.targetName(col)
+		if !ok {
+			return nil, false
+		}
+		mapped[i] = name
+	}
+	return mapped, true
+}
+
+// renameKeyParts renames the columns key parts start with, keeping their prefix lengths and order
+func renameKeyParts(parts, sources, targets []string) []string {
+	if len(parts) != len(sources) {
+		return nil
+	}
+	renamed := make([]string, len(parts))
+	for i, part := range parts {
+		renamed[i] = part
+		if quoted := fmt.Sprintf("`%s`", sources[i]); strings.HasPrefix(part, quoted) {
+			renamed[i] = fmt.Sprintf("`%s`", targets[i]) + strings.TrimPrefix(part, quoted)
+		}
+	}
+	return renamed
+}
+
+// derivedColumnValue returns the SQL producing the value of a constant or expression column
+func derivedColumnValue(rule *ColumnRule) string {
+	if rule.RuleType == ColumnRuleExpression {
+		return "(" + rule.Value + ")"
+	}
+	return sqlStringLiteral(rule.Value)
+}
+
+// sqlStringLiteral quotes a value as a MySQL string literal
+func sqlStringLiteral(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(value) + "'"
+}
+
+// validateColumnRules validates the column rules of a table mapping
+func validateColumnRules(rules []*ColumnRule) error {
+	targets := make(map[string]bool)
+	for i, rule := range rules {
+		if rule == nil {
+			return fmt.Errorf("column rule %d is empty", i)
+		}
+
+		switch rule.RuleType {
+		case ColumnRuleInclude, ColumnRuleExclude, ColumnRuleRename:
+			if !isValidMySQLIdentifier(rule.SourceColumn) {
+				return fmt.Errorf("invalid source column for column rule %d: %s", i, rule.SourceColumn)
+			}
+		case ColumnRuleConstant, ColumnRuleExpression:
+			if rule.SourceColumn != "" {
+				return fmt.Errorf("%s column rule %d does not take a source column", rule.RuleType, i)
+			}
+		default:
+			return fmt.Errorf("invalid column rule type: %s", rule.RuleType)
+		}
+
+		switch rule.RuleType {
+		case ColumnRuleRename, ColumnRuleConstant, ColumnRuleExpression:
+			if !isValidMySQLIdentifier(rule.TargetColumn) {
+				return fmt.Errorf("invalid target column for column rule %d: %s", i, rule.TargetColumn)
+			}
+			key := strings.ToLower(rule.TargetColumn)
+			if targets[key] {
+				return fmt.Errorf("duplicate target column in column rules: %s", rule.TargetColumn)
+			}
+			targets[key] = true
+		default:
+			if rule.TargetColumn != "" {
+				return fmt.Errorf("%s column rule %d does not take a target column", rule.RuleType, i)
+			}
+		}
+
+		if rule.RuleType == ColumnRuleExpression && strings.TrimSpace(rule.Value) == "" {
+			return fmt.Errorf("expression column rule %d requires an expression", i)
+		}
+		if rule.ColumnType != "" {
+			if rule.RuleType != ColumnRuleConstant && rule.RuleType != ColumnRuleExpression {
+				return fmt.Errorf("%s column rule %d does not take a column type", rule.RuleType, i)
+			}
+			if !derivedColumnTypePattern.MatchString(rule.ColumnType) {
+				return fmt.Errorf("invalid column type for column rule %d: %s", i, rule.ColumnType)
+			}
+		}
+	}
+	return nil
+}
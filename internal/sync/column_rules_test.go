@@ -0,0 +1,207 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// customersSchema is the source schema the column rule tests map
+func customersSchema() *TableSchema {
+	return &TableSchema{
+		Name: "customers",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint", Extra: "auto_increment"},
+			{Name: "name", Type: "varchar(64)"},
+			{Name: "email", Type: "varchar(128)", Nullable: true},
+			{Name: "password_hash", Type: "char(60)"},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true, Type: "BTREE"},
+			{Name: "idx_email", Columns: []string{"email"}, Unique: true, Type: "BTREE"},
+			{Name: "idx_password", Columns: []string{"password_hash"}, Type: "BTREE"},
+		},
+		Keys: []*KeyInfo{
+			{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"id"}},
+			{Name: "idx_email", Type: "UNIQUE", Columns: []string{"email"}},
+		},
+	}
+}
+
+func customerRules() []*ColumnRule {
+	return []*ColumnRule{
+		{RuleType: ColumnRuleExclude, SourceColumn: "password_hash"},
+		{RuleType: ColumnRuleRename, SourceColumn: "id", TargetColumn: "customer_id"},
+		{RuleType: ColumnRuleConstant, TargetColumn: "region", Value: "eu'west"},
+		{RuleType: ColumnRuleExpression, TargetColumn: "email_domain", Value: "SUBSTRING_INDEX(email, '@', -1)", ColumnType: "varchar(128)"},
+	}
+}
+
+func TestColumnMapping_SelectList(t *testing.T) {
+	var none *columnMapping
+	assert.Equal(t, "*", none.selectList(customersSchema()))
+
+	mapping := &TableMapping{ColumnRules: customerRules()}
+	assert.Equal(t,
+		"`id` AS `customer_id`, `name`, `email`, 'eu''west' AS `region`, (SUBSTRING_INDEX(email, '@', -1)) AS `email_domain`",
+		mapping.columnMapping().selectList(customersSchema()))
+
+	// Once a column is included, only included columns are copied
+	included := &TableMapping{ColumnRules: []*ColumnRule{
+		{RuleType: ColumnRuleInclude, SourceColumn: "id"},
+		{RuleType: ColumnRuleInclude, SourceColumn: "Name"},
+	}}
+	assert.Equal(t, "`id`, `name`", included.columnMapping().selectList(customersSchema()))
+}
+
+func TestColumnMapping_TargetSchema(t *testing.T) {
+	mapping := &TableMapping{ColumnRules: customerRules()}
+	target, err := mapping.columnMapping().targetSchema(customersSchema())
+	require.NoError(t, err)
+
+	var names []string
+	for _, col := range target.Columns {
+		names = append(names, col.Name)
+	}
+	assert.Equal(t, []string{"customer_id", "name", "email", "region", "email_domain"}, names)
+	assert.Equal(t, &ColumnInfo{Name: "region", Type: defaultDerivedColumnType, Nullable: true}, target.Columns[3])
+	assert.Equal(t, "varchar(128)", target.Columns[4].Type)
+
+	// The index on the excluded column is left out, the key columns follow the rename
+	require.Len(t, target.Indexes, 2)
+	assert.Equal(t, []string{"customer_id"}, target.Indexes[0].Columns)
+	assert.Equal(t, "idx_email", target.Indexes[1].Name)
+	assert.Equal(t, []string{"customer_id"}, target.Keys[0].Columns)
+
+	engine := &DefaultSyncEngine{}
+	assert.Equal(t, "CREATE TABLE `replica`.`customers` (\n"+
+		"  `customer_id` bigint NOT NULL auto_increment,\n"+
+		"  `name` varchar(64) NOT NULL,\n"+
+		"  `email` varchar(128),\n"+
+		"  `region` varchar(255),\n"+
+		"  `email_domain` varchar(128),\n"+
+		"  PRIMARY KEY (`customer_id`),\n"+
+		"  UNIQUE KEY `idx_email` (`email`)\n)",
+		engine.buildCreateTableStatement("replica", "customers", target))
+
+	// The source schema itself is untouched
+	assert.Equal(t, "id", customersSchema().Columns[0].Name)
+
+	clash := &TableMapping{ColumnRules: []*ColumnRule{{RuleType: ColumnRuleRename, SourceColumn: "id", TargetColumn: "name"}}}
+	_, err = clash.columnMapping().targetSchema(customersSchema())
+	assert.ErrorContains(t, err, "target column name")
+}
+
+func TestValidateColumnRules(t *testing.T) {
+	assert.NoError(t, validateColumnRules(nil))
+	assert.NoError(t, validateColumnRules(customerRules()))
+
+	tests := []struct {
+		name string
+		rule *ColumnRule
+		err  string
+	}{
+		{"unknown type", &ColumnRule{RuleType: "split", SourceColumn: "id"}, "invalid column rule type"},
+		{"exclude without column", &ColumnRule{RuleType: ColumnRuleExclude}, "invalid source column"},
+		{"rename without target", &ColumnRule{RuleType: ColumnRuleRename, SourceColumn: "id"}, "invalid target column"},
+		{"invalid target", &ColumnRule{RuleType: ColumnRuleConstant, TargetColumn: "a-b"}, "invalid target column"},
+		{"constant with source", &ColumnRule{RuleType: ColumnRuleConstant, SourceColumn: "id", TargetColumn: "x"}, "does not take a source column"},
+		{"include with target", &ColumnRule{RuleType: ColumnRuleInclude, SourceColumn: "id", TargetColumn: "x"}, "does not take a target column"},
+		{"empty expression", &ColumnRule{RuleType: ColumnRuleExpression, TargetColumn: "x", Value: " "}, "requires an expression"},
+		{"invalid column type", &ColumnRule{RuleType: ColumnRuleConstant, TargetColumn: "x", ColumnType: "int; DROP TABLE t"}, "invalid column type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, validateColumnRules([]*ColumnRule{tt.rule}), tt.err)
+		})
+	}
+
+	duplicate := []*ColumnRule{
+		{RuleType: ColumnRuleRename, SourceColumn: "id", TargetColumn: "key"},
+		{RuleType: ColumnRuleConstant, TargetColumn: "KEY", Value: "1"},
+	}
+	assert.ErrorContains(t, validateColumnRules(duplicate), "duplicate target column")
+}
+
+func TestCopyTableByKeyset_RenamedKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	ctx := context.Background()
+
+	mapping := &TableMapping{
+		ID: "mapping-1", SourceTable: "customers", TargetTable: "customers",
+		ColumnRules: []*ColumnRule{
+			{RuleType: ColumnRuleInclude, SourceColumn: "id"},
+			{RuleType: ColumnRuleInclude, SourceColumn: "name"},
+			{RuleType: ColumnRuleRename, SourceColumn: "id", TargetColumn: "customer_id"},
+		},
+	}
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("customers").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+	plan, err := engine.planFullCopy(ctx, sourceDB, targetDB, mysqlDialect{}, "replica", mapping, customersSchema(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customer_id"}, plan.keyTargets)
+
+	// Chunks are bounded by the source key, read back from the renamed column
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` AS `customer_id`, `name` FROM `shop`.`customers` ORDER BY `id` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id", "name"}).AddRow([]byte("7"), []byte("alice")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`customers` (`customer_id`, `name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` AS `customer_id`, `name` FROM `shop`.`customers` WHERE (`id`) > (?) ORDER BY `id` LIMIT 1")).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"customer_id", "name"}))
+
+	processed, err := engine.copyTableByKeyset(ctx, sourceDB, "shop", targetDB, "replica", mapping, plan, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPlanFullCopy_ExcludedKeyScans(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, _ := newMockEngine(t)
+
+	mapping := &TableMapping{SourceTable: "customers", TargetTable: "customers",
+		ColumnRules: []*ColumnRule{{RuleType: ColumnRuleExclude, SourceColumn: "id"}}}
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("customers").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+	plan, err := engine.planFullCopy(context.Background(), sourceDB, targetDB, mysqlDialect{}, "replica", mapping, customersSchema(), true)
+	require.NoError(t, err)
+	assert.Empty(t, plan.primaryKeys)
+	assert.Equal(t, "`name`, `email`, `password_hash`", plan.selectList)
+}
+
+func TestCDCApplier_ColumnRules(t *testing.T) {
+	newApplier := func(t *testing.T, rules []*ColumnRule) (*cdcApplier, error) {
+		db, targetMock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		conn, err := sqlx.NewDb(db, "sqlmock").Connx(context.Background())
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		targetMock.ExpectExec(regexp.QuoteMeta("SET time_zone = '+00:00'")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mapping := &TableMapping{SourceTable: "customers", TargetTable: "customers", SyncMode: SyncModeCDC, ColumnRules: rules}
+		return newCDCApplier(context.Background(), conn, "shop", "replica", mapping, customersSchema(), []string{"id"},
+			cdcPosition{}, false, logrus.NewEntry(logrus.New()))
+	}
+
+	applier, err := newApplier(t, customerRules()[:3])
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO `replica`.`customers` (`customer_id`, `name`, `email`, `region`) VALUES (?, ?, ?, 'eu''west') "+
+		"ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `email` = VALUES(`email`), `region` = VALUES(`region`)", applier.upsertSQL)
+	assert.Equal(t, "DELETE FROM `replica`.`customers` WHERE `customer_id` = ?", applier.deleteSQL)
+
+	_, err = newApplier(t, customerRules())
+	assert.ErrorContains(t, err, "cannot compute expression columns")
+
+	_, err = newApplier(t, []*ColumnRule{{RuleType: ColumnRuleExclude, SourceColumn: "id"}})
+	assert.ErrorContains(t, err, "primary key column id")
+}
@@ -0,0 +1,323 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule describes when a scheduled sync should run
+type CronSchedule interface {
+	// Next returns the first activation time strictly after t, in t's location.
+	// A zero time means the schedule will never fire again.
+	Next(t time.Time) time.Time
+}
+
+// ParseCronSchedule parses a schedule expression. Supported forms:
+//   - standard 5-field cron: "minute hour day-of-month month day-of-week"
+//   - 6-field cron with a leading seconds field: "second minute hour day-of-month month day-of-week"
+//   - descriptors: @yearly (@annually), @monthly, @weekly, @daily (@midnight), @hourly
+//   - fixed intervals: "@every 15m", "@every 1h30m"
+//
+// Fields accept "*", "?", lists (1,15), ranges (1-5), steps (*/10, 0-30/5) and
+// month/weekday names (JAN-DEC, SUN-SAT). Day-of-week 7 is treated as Sunday.
+func ParseCronSchedule(spec string) (CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule expression")
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		return parseCronDescriptor(spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields in schedule %q, found %d", spec, len(fields))
+	}
+
+	schedule := &cronSpec{}
+	var err error
+	if schedule.second, err = parseCronField(fields[0], cronSeconds); err != nil {
+		return nil, err
+	}
+	if schedule.minute, err = parseCronField(fields[1], cronMinutes); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[2], cronHours); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = parseCronField(fields[3], cronDom); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[4], cronMonths); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = parseCronField(fields[5], cronDow); err != nil {
+		return nil, err
+	}
+
+	// Day-of-week 7 is an alias for Sunday
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	schedule.domRestricted = !isCronWildcard(fields[3])
+	schedule.dowRestricted = !isCronWildcard(fields[5])
+
+	return schedule, nil
+}
+
+// parseCronDescriptor handles the @-prefixed shorthand schedules
+func parseCronDescriptor(spec string) (CronSchedule, error) {
+	switch strings.ToLower(spec) {
+	case "@yearly", "@annually":
+		return ParseCronSchedule("0 0 0 1 1 *")
+	case "@monthly":
+		return ParseCronSchedule("0 0 0 1 * *")
+	case "@weekly":
+		return ParseCronSchedule("0 0 0 * * 0")
+	case "@daily", "@midnight":
+		return ParseCronSchedule("0 0 0 * * *")
+	case "@hourly":
+		return ParseCronSchedule("0 0 * * * *")
+	}
+
+	const everyPrefix = "@every "
+	if len(spec) > len(everyPrefix) && strings.EqualFold(spec[:len(everyPrefix)], everyPrefix) {
+		interval, err := time.ParseDuration(strings.TrimSpace(spec[len(everyPrefix):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in schedule %q: %w", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval in schedule %q must be at least 1s", spec)
+		}
+		return everySchedule{interval: interval.Truncate(time.Second)}, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized schedule descriptor: %s", spec)
+}
+
+// everySchedule fires at a fixed interval measured from the previous activation
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Next returns t plus the interval, rounded down to the second
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval - time.Duration(t.Nanosecond()))
+}
+
+// cronSpec is a parsed cron expression; each field is a bit set of allowed values
+type cronSpec struct {
+	second, minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted          bool
+}
+
+// Next returns the next time matching the expression after t. It walks the
+// fields from month down to second, resetting lower fields whenever a higher
+// one advances, and gives up after five years without a match (e.g. "0 0 30 2 *").
+func (s *cronSpec) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start at the next whole second
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	added := false
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// A DST transition at midnight can leave us at 01:00 or 23:00
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatches applies the usual cron rule: when both day-of-month and
+// day-of-week are restricted, a day matching either one is accepted
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// cronBounds describes the allowed range and names of a cron field
+type cronBounds struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	cronSeconds = cronBounds{name: "second", min: 0, max: 59}
+	cronMinutes = cronBounds{name: "minute", min: 0, max: 59}
+	cronHours   = cronBounds{name: "hour", min: 0, max: 23}
+	cronDom     = cronBounds{name: "day-of-month", min: 1, max: 31}
+	cronMonths  = cronBounds{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronBounds{name: "day-of-week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// isCronWildcard reports whether a field places no restriction on its unit
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField parses a comma-separated list of cron terms into a bit set
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, term := range strings.Split(field, ",") {
+		termBits, err := parseCronTerm(term, bounds)
+		if err != nil {
+			return 0, err
+		}
+		bits |= termBits
+	}
+	return bits, nil
+}
+
+// parseCronTerm parses a single term: "*", "?", "n", "a-b", optionally followed by "/step"
+func parseCronTerm(term string, bounds cronBounds) (uint64, error) {
+	if term == "" {
+		return 0, fmt.Errorf("empty %s term", bounds.name)
+	}
+
+	rangePart, stepPart, hasStep := strings.Cut(term, "/")
+	step := uint(1)
+	if hasStep {
+		n, err := strconv.ParseUint(stepPart, 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", stepPart, bounds.name)
+		}
+		step = uint(n)
+	}
+
+	var start, end uint
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		if rangePart == "?" && bounds.name != cronDom.name && bounds.name != cronDow.name {
+			return 0, fmt.Errorf("'?' is only allowed in day-of-month and day-of-week fields")
+		}
+		start, end = bounds.min, bounds.max
+		if bounds.name == cronDow.name {
+			end = 6 // "*" must not set the Sunday alias bit twice
+		}
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseCronValue(lo, bounds); err != nil {
+			return 0, err
+		}
+		if end, err = parseCronValue(hi, bounds); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid %s range %q: start is after end", bounds.name, rangePart)
+		}
+	default:
+		value, err := parseCronValue(rangePart, bounds)
+		if err != nil {
+			return 0, err
+		}
+		start = value
+		end = value
+		if hasStep {
+			end = bounds.max // "5/10" means every 10 starting at 5
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << v
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a number or a month/weekday name and checks its bounds
+func parseCronValue(value string, bounds cronBounds) (uint, error) {
+	if n, ok := bounds.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", bounds.name, value)
+	}
+	if uint(n) < bounds.min || uint(n) > bounds.max {
+		return 0, fmt.Errorf("%s value %d out of range [%d, %d]", bounds.name, n, bounds.min, bounds.max)
+	}
+	return uint(n), nil
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronSchedule_Next(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 20, 30, 0, time.UTC) // Friday
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"every minute", "* * * * *", base, time.Date(2024, 3, 15, 10, 21, 0, 0, time.UTC)},
+		{"minute step", "*/15 * * * *", base, time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"daily at 2am", "0 2 * * *", base, time.Date(2024, 3, 16, 2, 0, 0, 0, time.UTC)},
+		{"six fields with seconds", "45 20 10 * * *", base, time.Date(2024, 3, 15, 10, 20, 45, 0, time.UTC)},
+		{"weekday names", "0 9 * * MON-FRI", base, time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", base, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"month names and list", "0 0 1 jan,jul *", base, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 1 * SUN", base, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"hourly descriptor", "@hourly", base, time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"daily descriptor", "@daily", base, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"every interval", "@every 15m", base, base.Add(15 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule.Next(tt.from))
+		})
+	}
+}
+
+func TestParseCronSchedule_Timezone(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	schedule, err := ParseCronSchedule("0 2 * * *")
+	require.NoError(t, err)
+
+	from := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC) // 08:00 in Shanghai
+	next := schedule.Next(from.In(location))
+
+	assert.Equal(t, time.Date(2024, 3, 16, 2, 0, 0, 0, location), next)
+	assert.Equal(t, time.Date(2024, 3, 15, 18, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseCronSchedule_NeverFires(t *testing.T) {
+	schedule, err := ParseCronSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"? * * * *",
+		"@fortnightly",
+		"@every soon",
+		"@every 10ms",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseCronSchedule(spec)
+		assert.Error(t, err, "expected error for %q", spec)
+	}
+}
@@ -0,0 +1,211 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// serverVersionNumber matches the version number VERSION() starts with
+	serverVersionNumber = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+	// invisibleExtra matches the EXTRA marker of an invisible column
+	invisibleExtra = regexp.MustCompile(`(?i)\bINVISIBLE\b`)
+)
+
+// serverVersion is the flavor and version of a MySQL compatible server
+type serverVersion struct {
+	mariaDB             bool
+	major, minor, patch int
+}
+
+// parseServerVersion parses what SELECT VERSION() returns, e.g. 8.0.36, 5.7.44-log or 10.11.6-MariaDB-log
+func parseServerVersion(version string) serverVersion {
+	v := serverVersion{mariaDB: strings.Contains(strings.ToLower(version), "mariadb")}
+	if v.mariaDB {
+		// MariaDB 10 reports itself as 5.5.5-10.x to replication clients expecting MySQL 5
+		version = strings.TrimPrefix(version, "5.5.5-")
+	}
+	if m := serverVersionNumber.FindStringSubmatch(version); m != nil {
+		v.major, _ = strconv.Atoi(m[1])
+		v.minor, _ = strconv.Atoi(m[2])
+		v.patch, _ = strconv.Atoi(m[3])
+	}
+	return v
+}
+
+// atLeast reports whether the server version is at least major.minor.patch
+func (v serverVersion) atLeast(major, minor, patch int) bool {
+	if v.major != major {
+		return v.major > major
+	}
+	if v.minor != minor {
+		return v.minor > minor
+	}
+	return v.patch >= patch
+}
+
+// mysqlAtLeast reports whether the server is MySQL of at least major.minor.patch
+func (v serverVersion) mysqlAtLeast(major, minor, patch int) bool {
+	return !v.mariaDB && v.atLeast(major, minor, patch)
+}
+
+// mariaDBAtLeast reports whether the server is MariaDB of at least major.minor.patch
+func (v serverVersion) mariaDBAtLeast(major, minor, patch int) bool {
+	return v.mariaDB && v.atLeast(major, minor, patch)
+}
+
+// schemaForTarget translates a source schema to what the target server supports and logs what the
+// translation changed
+func (e *DefaultSyncEngine) schemaForTarget(ctx context.Context, targetDB *sqlx.DB, schema *TableSchema) *TableSchema {
+	translated, notes := e.translateForTarget(ctx, targetDB, schema)
+	e.logTranslationNotes(schema.Name, notes)
+	return translated
+}
+
+// translateForTarget translates a source schema to what the target server supports and returns a
+// note for every change that loses part of the definition. Foreign keys are only kept when the target
+// session doesn't check them (disable_foreign_key_checks): tables are created, emptied and loaded one
+// at a time, in an order their references need not follow.
+func (e *DefaultSyncEngine) translateForTarget(ctx context.Context, targetDB *sqlx.DB, schema *TableSchema) (*TableSchema, []string) {
+	var version string
+	var foreignKeyChecks int
+	var notes []string
+	translated := schema
+	if err := targetDB.QueryRowxContext(ctx, "SELECT VERSION(), @@SESSION.foreign_key_checks").Scan(&version, &foreignKeyChecks); err != nil {
+		notes = append(notes, fmt.Sprintf("Failed to read target server version, using the source schema as is: %v", err))
+		foreignKeyChecks = 1
+	} else {
+		translated, notes = translateTableSchema(schema, parseServerVersion(version))
+	}
+
+	if foreignKeyChecks != 0 && len(translated.ForeignKeys) > 0 {
+		withoutForeignKeys := *translated
+		withoutForeignKeys.ForeignKeys = nil
+		translated = &withoutForeignKeys
+		e.logger.WithField("table_name", schema.Name).Debug("Foreign keys left out, the target session checks foreign keys")
+	}
+	return translated, notes
+}
+
+// logTranslationNotes logs what translating a table's schema for the target server changed
+func (e *DefaultSyncEngine) logTranslationNotes(tableName string, notes []string) {
+	for _, note := range notes {
+		e.logger.WithField("table_name", tableName).Warn(note)
+	}
+}
+
+// translateTableSchema returns a copy of a schema without what the target server doesn't support:
+// the MySQL 8 collations are replaced by their closest older equivalent, and functional indexes,
+// expression defaults, invisible columns and CHECK constraints are left out where they're unknown.
+// It also returns a note for every change that loses part of the definition.
+func translateTableSchema(schema *TableSchema, target serverVersion) (*TableSchema, []string) {
+	translated := *schema
+	var notes []string
+	replaced := make(map[string]bool)
+	collation := func(name string) string {
+		mapped := translateCollation(name, target)
+		if mapped != name && !replaced[name] {
+			replaced[name] = true
+			notes = append(notes, fmt.Sprintf("Collation %s is not supported by the target server, using %s", name, mapped))
+		}
+		return mapped
+	}
+
+	translated.TableCollation = collation(schema.TableCollation)
+	translated.TableCharset = translateCharset(schema.TableCharset, target)
+
+	expressionDefaults := target.mysqlAtLeast(8, 0, 13) || target.mariaDBAtLeast(10, 2, 1)
+	invisibleColumns := target.mysqlAtLeast(8, 0, 23) || target.mariaDBAtLeast(10, 3, 3)
+	translated.Columns = make([]*ColumnInfo, len(schema.Columns))
+	for i, col := range schema.Columns {
+		copied := *col
+		copied.Collation = collation(col.Collation)
+		copied.CharacterSet = translateCharset(col.CharacterSet, target)
+		if strings.HasPrefix(copied.DefaultValue, "(") && !expressionDefaults {
+			copied.DefaultValue = ""
+			notes = append(notes, fmt.Sprintf("Expression default of column %s is not supported by the target server, left out", col.Name))
+		}
+		if invisibleExtra.MatchString(copied.Extra) && !invisibleColumns {
+			copied.Extra = strings.Join(strings.Fields(invisibleExtra.ReplaceAllString(copied.Extra, "")), " ")
+			notes = append(notes, fmt.Sprintf("Column %s is made visible, the target server has no invisible columns", col.Name))
+		}
+		translated.Columns[i] = &copied
+	}
+
+	functionalIndexes := target.mysqlAtLeast(8, 0, 13)
+	translated.Indexes = nil
+	for _, idx := range schema.Indexes {
+		if hasExpressionKeyPart(idx) && !functionalIndexes {
+			notes = append(notes, fmt.Sprintf("Functional index %s is not supported by the target server, left out", idx.Name))
+			continue
+		}
+		translated.Indexes = append(translated.Indexes, idx)
+	}
+
+	// MySQL parses CHECK constraints before 8.0.16 but ignores them, MariaDB enforces every one
+	checks := target.mysqlAtLeast(8, 0, 16) || target.mariaDBAtLeast(10, 2, 1)
+	translated.Checks = nil
+	for _, check := range schema.Checks {
+		switch {
+		case !checks:
+			notes = append(notes, fmt.Sprintf("CHECK constraint %s is not supported by the target server, left out", check.Name))
+		case check.NotEnforced && target.mariaDB:
+			notes = append(notes, fmt.Sprintf("CHECK constraint %s is not enforced and MariaDB has no unenforced constraints, left out", check.Name))
+		default:
+			translated.Checks = append(translated.Checks, check)
+		}
+	}
+
+	return &translated, notes
+}
+
+// hasExpressionKeyPart reports whether an index has a key part on an expression rather than a column
+func hasExpressionKeyPart(idx *IndexInfo) bool {
+	for _, column := range idx.Columns {
+		if column == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// translateCollation maps a collation to one the target server knows. The utf8mb4_0900 collations
+// of MySQL 8 and the uca1400 collations of MariaDB map to the UCA 4.0 based utf8mb4_unicode_ci, or
+// utf8mb4_bin for the case sensitive ones; utf8mb3 names map to the utf8 names older servers use.
+func translateCollation(collation string, target serverVersion) string {
+	name := strings.ToLower(collation)
+	switch {
+	case strings.HasPrefix(name, "utf8mb4_") && strings.Contains(name, "_0900_") && !target.mysqlAtLeast(8, 0, 1):
+		if strings.HasSuffix(name, "_bin") || strings.HasSuffix(name, "_as_cs") {
+			return "utf8mb4_bin"
+		}
+		return "utf8mb4_unicode_ci"
+	case strings.HasPrefix(name, "utf8mb4_") && strings.Contains(name, "_uca1400_") && !target.mariaDBAtLeast(10, 10, 1):
+		if target.mysqlAtLeast(8, 0, 1) {
+			if strings.HasSuffix(name, "_as_cs") {
+				return "utf8mb4_0900_as_cs"
+			}
+			return "utf8mb4_0900_ai_ci"
+		}
+		if strings.HasSuffix(name, "_as_cs") {
+			return "utf8mb4_bin"
+		}
+		return "utf8mb4_unicode_ci"
+	case strings.HasPrefix(name, "utf8mb3_") && !target.mysqlAtLeast(8, 0, 0) && !target.mariaDBAtLeast(10, 6, 1):
+		return "utf8_" + strings.TrimPrefix(name, "utf8mb3_")
+	}
+	return collation
+}
+
+// translateCharset maps the utf8mb3 character set to the utf8 name older servers use
+func translateCharset(charset string, target serverVersion) string {
+	if strings.EqualFold(charset, "utf8mb3") && !target.mysqlAtLeast(8, 0, 0) && !target.mariaDBAtLeast(10, 6, 1) {
+		return "utf8"
+	}
+	return charset
+}
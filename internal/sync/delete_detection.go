@@ -0,0 +1,324 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// deleteDetector finds target rows whose primary key no longer exists at the source.
+//
+// The target key space is walked in chunks of chunkSize keys. Only the upper bound of each
+// chunk is read from the target; both sides then return a row count and an XOR of per-key
+// hashes for the range, so unchanged ranges cost two aggregate queries. Target keys are only
+// fetched for ranges whose fingerprints differ, and are then looked up at the source.
+type deleteDetector struct {
+	sourceDB     *sqlx.DB
+	sourceTable  string       // Qualified and quoted
+	sourceFilter sqlCondition // Mapping row filter, rows outside it count as deleted
+	targetDB     *sqlx.DB
+	targetTable  string       // Qualified and quoted
+	targetFilter sqlCondition // Excludes soft-deleted rows
+	primaryKeys  []string     // Primary key columns of the source table
+	targetKeys   []string     // primaryKeys as named in the target table
+	chunkSize    int
+}
+
+// softDeleteColumn returns the target column soft delete detection sets, empty unless the mode is soft
+func (m *TableMapping) softDeleteColumn() string {
+	if m.DeleteDetection != DeleteDetectionSoft {
+		return ""
+	}
+	if m.SoftDeleteColumn == "" {
+		return defaultSoftDeleteColumn
+	}
+	return m.SoftDeleteColumn
+}
+
+// propagateDeletes runs the delete detection pass of an incremental sync and applies the
+// mapping's DeleteDetection mode to the orphaned target rows
+func (e *DefaultSyncEngine) propagateDeletes(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, options *SyncOptions) (int64, error) {
+	primaryKeys, err := e.getPrimaryKeyColumns(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get primary keys: %w", err)
+	}
+	targetKeys, ok := mapping.columnMapping().targetColumns(primaryKeys)
+	if !ok {
+		return 0, fmt.Errorf("delete detection requires every primary key column, but column rules exclude one of %v", primaryKeys)
+	}
+	// Masked target keys no longer match the source keys they are compared with
+	for _, rule := range mapping.MaskingRules {
+		for _, key := range targetKeys {
+			if strings.EqualFold(rule.Column, key) {
+				return 0, fmt.Errorf("delete detection cannot compare primary key column %s, which a masking rule rewrites", key)
+			}
+		}
+	}
+
+	softDeleteColumn := mapping.softDeleteColumn()
+	if softDeleteColumn != "" {
+		if err := e.ensureSoftDeleteColumn(ctx, targetDB, targetDBName, mapping.TargetTable, softDeleteColumn); err != nil {
+			return 0, err
+		}
+	}
+
+	chunkSize := 1000
+	if options != nil && options.BatchSize > 0 {
+		chunkSize = options.BatchSize
+	}
+
+	detector := &deleteDetector{
+		sourceDB:     sourceDB,
+		sourceTable:  fmt.Sprintf("`%s`.`%s`", sourceDBName, mapping.SourceTable),
+		sourceFilter: mapping.rowFilter(),
+		targetDB:     targetDB,
+		targetTable:  fmt.Sprintf("`%s`.`%s`", targetDBName, mapping.TargetTable),
+		primaryKeys:  primaryKeys,
+		targetKeys:   targetKeys,
+		chunkSize:    chunkSize,
+	}
+	if softDeleteColumn != "" {
+		detector.targetFilter = sqlCondition{clause: fmt.Sprintf("`%s` IS NULL", softDeleteColumn)}
+	}
+
+	var handled int64
+	err = detector.run(ctx, func(orphans [][]interface{}) error {
+		switch mapping.DeleteDetection {
+		case DeleteDetectionHard:
+			query, args := keyedStatement("DELETE FROM "+detector.targetTable, detector.targetKeys, orphans)
+			if _, err := targetDB.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to delete orphaned rows: %w", err)
+			}
+		case DeleteDetectionSoft:
+			prefix := fmt.Sprintf("UPDATE %s SET `%s` = CURRENT_TIMESTAMP", detector.targetTable, softDeleteColumn)
+			query, args := keyedStatement(prefix, detector.targetKeys, orphans)
+			if _, err := targetDB.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to soft delete orphaned rows: %w", err)
+			}
+		}
+		handled += int64(len(orphans))
+		return nil
+	})
+	if err != nil {
+		return handled, err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"source_table":     mapping.SourceTable,
+		"target_table":     mapping.TargetTable,
+		"delete_detection": mapping.DeleteDetection,
+		"deleted_rows":     handled,
+	}).Info("Delete detection completed")
+
+	return handled, nil
+}
+
+// ensureSoftDeleteColumn adds the soft delete column to the target table if it is missing
+func (e *DefaultSyncEngine) ensureSoftDeleteColumn(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName, column string) error {
+	var count int
+	checkQuery := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	if err := targetDB.GetContext(ctx, &count, checkQuery, targetDBName, tableName, column); err != nil {
+		return fmt.Errorf("failed to check soft delete column: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	alterQuery := fmt.Sprintf("ALTER TABLE `%s`.`%s` ADD COLUMN `%s` DATETIME NULL DEFAULT NULL", targetDBName, tableName, column)
+	if _, err := targetDB.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("failed to add soft delete column: %w", err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"target_table": tableName,
+		"column":       column,
+	}).Info("Added soft delete column to target table")
+
+	return nil
+}
+
+// run walks the target key space and calls handle with the orphaned keys of each differing chunk
+func (d *deleteDetector) run(ctx context.Context, handle func(orphans [][]interface{}) error) error {
+	var lower []interface{}
+	for {
+		upper, err := d.chunkUpperBound(ctx, lower)
+		if err != nil {
+			return err
+		}
+		r := keyRange{lower: lower, upper: upper}
+
+		differs, err := d.rangeDiffers(ctx, r)
+		if err != nil {
+			return err
+		}
+		if differs {
+			orphans, err := d.orphanedKeys(ctx, r)
+			if err != nil {
+				return err
+			}
+			if len(orphans) > 0 {
+				if err := handle(orphans); err != nil {
+					return err
+				}
+			}
+		}
+
+		if upper == nil {
+			return nil
+		}
+		lower = upper
+	}
+}
+
+// chunkUpperBound returns the chunkSize-th target key after lower, or nil for the last chunk
+func (d *deleteDetector) chunkUpperBound(ctx context.Context, lower []interface{}) ([]interface{}, error) {
+	where, args := keyRangeCondition(d.targetKeys, keyRange{lower: lower}, d.targetFilter)
+	keyList := quoteColumns(d.targetKeys)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT 1 OFFSET %d",
+		keyList, d.targetTable, where, keyList, d.chunkSize-1)
+
+	keys, err := queryKeys(ctx, d.targetDB, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk boundary: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+// rangeDiffers compares the key count and key hash of a range on both sides
+func (d *deleteDetector) rangeDiffers(ctx context.Context, r keyRange) (bool, error) {
+	sourceCount, sourceHash, err := d.fingerprint(ctx, d.sourceDB, d.sourceTable, d.primaryKeys, r, d.sourceFilter)
+	if err != nil {
+		return false, fmt.Errorf("failed to fingerprint source range: %w", err)
+	}
+	targetCount, targetHash, err := d.fingerprint(ctx, d.targetDB, d.targetTable, d.targetKeys, r, d.targetFilter)
+	if err != nil {
+		return false, fmt.Errorf("failed to fingerprint target range: %w", err)
+	}
+	return sourceCount != targetCount || sourceHash != targetHash, nil
+}
+
+// fingerprint returns the row count and the XOR of the 64-bit key hashes of a range
+func (d *deleteDetector) fingerprint(ctx context.Context, db *sqlx.DB, table string, keys []string, r keyRange, filter sqlCondition) (int64, uint64, error) {
+	where, args := keyRangeCondition(keys, r, filter)
+	query := fmt.Sprintf(
+		"SELECT COUNT(*), COALESCE(BIT_XOR(CAST(CONV(SUBSTRING(MD5(CONCAT_WS('|', %s)), 1, 16), 16, 10) AS UNSIGNED)), 0) FROM %s%s",
+		quoteColumns(keys), table, where)
+
+	var count int64
+	var hash uint64
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&count, &hash); err != nil {
+		return 0, 0, err
+	}
+	return count, hash, nil
+}
+
+// orphanedKeys returns the target keys of a range that are missing at the source
+func (d *deleteDetector) orphanedKeys(ctx context.Context, r keyRange) ([][]interface{}, error) {
+	where, args := keyRangeCondition(d.targetKeys, r, d.targetFilter)
+	keyList := quoteColumns(d.targetKeys)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", keyList, d.targetTable, where, keyList)
+	targetKeys, err := queryKeys(ctx, d.targetDB, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target keys: %w", err)
+	}
+	if len(targetKeys) == 0 {
+		return nil, nil
+	}
+
+	query, args = keyedStatement("SELECT "+quoteColumns(d.primaryKeys)+" FROM "+d.sourceTable, d.primaryKeys, targetKeys)
+	if d.sourceFilter.clause != "" {
+		query += fmt.Sprintf(" AND (%s)", d.sourceFilter.clause)
+		args = append(args, d.sourceFilter.args...)
+	}
+	sourceKeys, err := queryKeys(ctx, d.sourceDB, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up source keys: %w", err)
+	}
+
+	present := make(map[string]bool, len(sourceKeys))
+	for _, key := range sourceKeys {
+		present[keyString(key)] = true
+	}
+
+	var orphans [][]interface{}
+	for _, key := range targetKeys {
+		if !present[keyString(key)] {
+			orphans = append(orphans, key)
+		}
+	}
+	return orphans, nil
+}
+
+// queryKeys runs a query returning primary key tuples, bound for comparison under their column types
+func queryKeys(ctx context.Context, db *sqlx.DB, query string, args []interface{}) ([][]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]interface{}
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columnTypes))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		key := make([]interface{}, len(values))
+		for i, value := range values {
+			key[i] = bindKeyValue(columnTypes[i].DatabaseTypeName(), string(value))
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// keyedStatement appends a WHERE clause matching the given primary key tuples on columns to prefix
+func keyedStatement(prefix string, columns []string, keys [][]interface{}) (string, []interface{}) {
+	tuples := make([]string, len(keys))
+	args := make([]interface{}, 0, len(keys)*len(columns))
+	for i, key := range keys {
+		tuples[i] = "(" + keyPlaceholders(len(columns)) + ")"
+		args = append(args, key...)
+	}
+	return fmt.Sprintf("%s WHERE (%s) IN (%s)", prefix, quoteColumns(columns), strings.Join(tuples, ", ")), args
+}
+
+// keyString returns a map key for a primary key tuple. Values are folded the way the default
+// _ci PAD SPACE collations compare them, so a key MySQL considers equal is never reported as
+// orphaned; under a binary collation that at worst leaves a row for a later pass.
+func keyString(key []interface{}) string {
+	parts := make([]string, len(key))
+	for i, value := range key {
+		parts[i] = strings.ToLower(strings.TrimRight(fmt.Sprint(value), " "))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// deleteDetectionMessage describes the outcome of a delete detection pass for the job log
+func deleteDetectionMessage(mode DeleteDetection, deletedRows int64) string {
+	switch mode {
+	case DeleteDetectionHard:
+		return fmt.Sprintf("Deleted %d rows that no longer exist at the source", deletedRows)
+	case DeleteDetectionSoft:
+		return fmt.Sprintf("Soft-deleted %d rows that no longer exist at the source", deletedRows)
+	default:
+		return fmt.Sprintf("Found %d rows that no longer exist at the source", deletedRows)
+	}
+}
@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPropagateDeletes_Hard(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders_copy", DeleteDetection: DeleteDetectionHard}
+
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+	// Chunk (-inf, 2]: target has 1 and 2, the source only has 1
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `replica`.`orders_copy` ORDER BY `id` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(1, 10))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders_copy` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 30))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `replica`.`orders_copy` WHERE (`id`) <= (?) ORDER BY `id`")).WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("2"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id`) IN ((?), (?))")).WithArgs("1", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	targetMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `replica`.`orders_copy` WHERE (`id`) IN ((?))")).WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Last chunk (2, +inf): fingerprints match, so no keys are read
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `replica`.`orders_copy` WHERE (`id`) > (?) ORDER BY `id` LIMIT 1 OFFSET 1")).WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(1, 7))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders_copy` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(1, 7))
+
+	deleted, err := engine.propagateDeletes(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, &SyncOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPropagateDeletes_SoftWithCompositeKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{
+		SourceTable:      "order_items",
+		TargetTable:      "order_items",
+		WhereClause:      "status <> 'draft'",
+		DeleteDetection:  DeleteDetectionSoft,
+		SoftDeleteColumn: "removed_at",
+	}
+
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("order_items").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("order_id").AddRow("line"))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS")).WithArgs("replica", "order_items", "removed_at").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	targetMock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `replica`.`order_items` ADD COLUMN `removed_at` DATETIME NULL DEFAULT NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// A single chunk; the source filter and the soft delete column scope both sides
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `order_id`, `line` FROM `replica`.`order_items` WHERE (`removed_at` IS NULL) ORDER BY `order_id`, `line` LIMIT 1 OFFSET 999")).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "line"}))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`order_items` WHERE (status <> 'draft')")).
+		WillReturnRows(fingerprintRows(1, 5))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`order_items` WHERE (`removed_at` IS NULL)")).
+		WillReturnRows(fingerprintRows(2, 9))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `order_id`, `line` FROM `replica`.`order_items` WHERE (`removed_at` IS NULL) ORDER BY")).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "line"}).AddRow("7", "1").AddRow("7", "2"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `order_id`, `line` FROM `shop`.`order_items` WHERE (`order_id`, `line`) IN ((?, ?), (?, ?)) AND (status <> 'draft')")).
+		WithArgs("7", "1", "7", "2").
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "line"}).AddRow("7", "1"))
+	targetMock.ExpectExec(regexp.QuoteMeta("UPDATE `replica`.`order_items` SET `removed_at` = CURRENT_TIMESTAMP WHERE (`order_id`, `line`) IN ((?, ?))")).
+		WithArgs("7", "2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := engine.propagateDeletes(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPropagateDeletes_Report(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", DeleteDetection: DeleteDetectionReport}
+
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("code"))
+	targetMock.ExpectQuery("LIMIT 1 OFFSET 999").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}))
+	sourceMock.ExpectQuery("SELECT COUNT").WillReturnRows(fingerprintRows(1, 1))
+	targetMock.ExpectQuery("SELECT COUNT").WillReturnRows(fingerprintRows(3, 2))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `code` FROM `replica`.`orders` ORDER BY `code`")).
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("ABC ").AddRow("gone").AddRow("lost"))
+	// The source matches "ABC " under its case-insensitive collation
+	sourceMock.ExpectQuery("WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("abc"))
+
+	// Report mode doesn't touch the target
+	deleted, err := engine.propagateDeletes(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestReportTableDeletes(t *testing.T) {
+	// No reporter in context is a no-op
+	ReportTableDeletes(context.Background(), "orders", DeleteDetectionHard, 3)
+
+	var gotTable string
+	var gotMode DeleteDetection
+	var gotRows int64
+	ctx := WithTableDeleteReporter(context.Background(), func(tableName string, mode DeleteDetection, deletedRows int64) {
+		gotTable, gotMode, gotRows = tableName, mode, deletedRows
+	})
+	ReportTableDeletes(ctx, "orders", DeleteDetectionSoft, 3)
+
+	assert.Equal(t, "orders", gotTable)
+	assert.Equal(t, DeleteDetectionSoft, gotMode)
+	assert.Equal(t, int64(3), gotRows)
+	assert.Equal(t, "Soft-deleted 3 rows that no longer exist at the source", deleteDetectionMessage(gotMode, gotRows))
+}
+
+func TestValidateDeleteDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping *TableMapping
+		wantErr bool
+	}{
+		{"disabled", &TableMapping{}, false},
+		{"hard", &TableMapping{DeleteDetection: DeleteDetectionHard}, false},
+		{"soft with default column", &TableMapping{DeleteDetection: DeleteDetectionSoft}, false},
+		{"soft with column", &TableMapping{DeleteDetection: DeleteDetectionSoft, SoftDeleteColumn: "removed_at"}, false},
+		{"unknown mode", &TableMapping{DeleteDetection: "purge"}, true},
+		{"column without soft mode", &TableMapping{DeleteDetection: DeleteDetectionHard, SoftDeleteColumn: "removed_at"}, true},
+		{"invalid column", &TableMapping{DeleteDetection: DeleteDetectionSoft, SoftDeleteColumn: "removed-at"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDeleteDetection(tt.mapping)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
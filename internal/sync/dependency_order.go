@@ -0,0 +1,159 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// foreignKeyEdge is a foreign key of a table referencing a parent table of the same database
+type foreignKeyEdge struct {
+	Table           string `db:"table_name"`
+	ReferencedTable string `db:"referenced_table_name"`
+}
+
+// loadForeignKeys returns the foreign keys between the tables of a database
+func loadForeignKeys(ctx context.Context, db *sqlx.DB, dbName string) ([]foreignKeyEdge, error) {
+	query := `
+		SELECT DISTINCT kcu.TABLE_NAME AS table_name, kcu.REFERENCED_TABLE_NAME AS referenced_table_name
+		FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+		  ON kcu.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA
+		 AND kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+		 AND kcu.TABLE_NAME = rc.TABLE_NAME
+		WHERE rc.CONSTRAINT_SCHEMA = ? AND kcu.REFERENCED_TABLE_SCHEMA = rc.CONSTRAINT_SCHEMA
+		ORDER BY kcu.TABLE_NAME, kcu.REFERENCED_TABLE_NAME
+	`
+	var edges []foreignKeyEdge
+	if err := db.SelectContext(ctx, &edges, query, dbName); err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	return edges, nil
+}
+
+// orderTablesByDependencies orders tables so that each table comes after the tables its foreign
+// keys reference. Tables are grouped by their depth in the dependency graph: the tables of a group
+// only depend on earlier groups and can load together. Tables referencing each other in a cycle
+// can't be ordered; each cycle is reported and its tables share a group. Foreign keys of a table
+// to itself and to tables outside the list are ignored.
+func orderTablesByDependencies(tables []string, edges []foreignKeyEdge) *TableDependencyOrder {
+	index := make(map[string]int, len(tables))
+	for _, table := range tables {
+		if _, ok := index[table]; !ok {
+			index[table] = len(index)
+		}
+	}
+	names := make([]string, len(index))
+	for table, i := range index {
+		names[i] = table
+	}
+
+	order := &TableDependencyOrder{Dependencies: make(map[string][]string)}
+	parents := make([][]int, len(names))
+	for _, edge := range edges {
+		child, ok := index[edge.Table]
+		parent, okParent := index[edge.ReferencedTable]
+		if !ok || !okParent || child == parent {
+			continue
+		}
+		parents[child] = append(parents[child], parent)
+		order.Dependencies[edge.Table] = append(order.Dependencies[edge.Table], edge.ReferencedTable)
+	}
+
+	// Tables of a cycle form one strongly connected component, the components form an acyclic graph
+	components := stronglyConnectedComponents(parents)
+	component := make([]int, len(names))
+	for c, members := range components {
+		for _, table := range members {
+			component[table] = c
+		}
+		if len(members) > 1 {
+			cycle := make([]string, len(members))
+			for i, table := range members {
+				cycle[i] = names[table]
+			}
+			order.Cycles = append(order.Cycles, cycle)
+		}
+	}
+
+	// The depth of a component is one more than the deepest component it references
+	depth := make([]int, len(components))
+	var visit func(c int) int
+	done := make([]bool, len(components))
+	visit = func(c int) int {
+		if done[c] {
+			return depth[c]
+		}
+		done[c] = true
+		for _, table := range components[c] {
+			for _, parent := range parents[table] {
+				if p := component[parent]; p != c {
+					if d := visit(p) + 1; d > depth[c] {
+						depth[c] = d
+					}
+				}
+			}
+		}
+		return depth[c]
+	}
+	for table := range names {
+		d := visit(component[table])
+		for len(order.Groups) <= d {
+			order.Groups = append(order.Groups, nil)
+		}
+		order.Groups[d] = append(order.Groups[d], names[table])
+	}
+	return order
+}
+
+// stronglyConnectedComponents returns the strongly connected components of a graph given by the
+// outgoing edges of each node, with the nodes of each component in ascending order
+func stronglyConnectedComponents(edges [][]int) [][]int {
+	var components [][]int
+	indices := make([]int, len(edges))
+	lowLinks := make([]int, len(edges))
+	onStack := make([]bool, len(edges))
+	var stack []int
+	next := 1
+
+	var connect func(node int)
+	connect = func(node int) {
+		indices[node] = next
+		lowLinks[node] = next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range edges[node] {
+			if indices[target] == 0 {
+				connect(target)
+				lowLinks[node] = min(lowLinks[node], lowLinks[target])
+			} else if onStack[target] {
+				lowLinks[node] = min(lowLinks[node], indices[target])
+			}
+		}
+
+		if lowLinks[node] == indices[node] {
+			var members []int
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				members = append(members, top)
+				if top == node {
+					break
+				}
+			}
+			sort.Ints(members)
+			components = append(components, members)
+		}
+	}
+	for node := range edges {
+		if indices[node] == 0 {
+			connect(node)
+		}
+	}
+	return components
+}
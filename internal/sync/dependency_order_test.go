@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderTablesByDependencies(t *testing.T) {
+	// orders references customers and products, order_items references orders and products
+	edges := []foreignKeyEdge{
+		{Table: "order_items", ReferencedTable: "orders"},
+		{Table: "order_items", ReferencedTable: "products"},
+		{Table: "orders", ReferencedTable: "customers"},
+		{Table: "orders", ReferencedTable: "products"},
+		{Table: "categories", ReferencedTable: "categories"},
+		{Table: "products", ReferencedTable: "suppliers"},
+	}
+	order := orderTablesByDependencies([]string{"order_items", "orders", "products", "customers", "categories"}, edges)
+
+	assert.Equal(t, [][]string{{"products", "customers", "categories"}, {"orders"}, {"order_items"}}, order.Groups)
+	assert.Equal(t, map[string][]string{
+		"order_items": {"orders", "products"},
+		"orders":      {"customers", "products"},
+	}, order.Dependencies)
+	assert.Empty(t, order.Cycles)
+}
+
+func TestOrderTablesByDependencies_Cycles(t *testing.T) {
+	// employees and departments reference each other, audit references both
+	edges := []foreignKeyEdge{
+		{Table: "audit", ReferencedTable: "employees"},
+		{Table: "departments", ReferencedTable: "employees"},
+		{Table: "employees", ReferencedTable: "departments"},
+		{Table: "employees", ReferencedTable: "sites"},
+	}
+	order := orderTablesByDependencies([]string{"audit", "employees", "departments", "sites"}, edges)
+
+	assert.Equal(t, [][]string{{"sites"}, {"employees", "departments"}, {"audit"}}, order.Groups)
+	assert.Equal(t, [][]string{{"employees", "departments"}}, order.Cycles)
+}
+
+func TestLoadForeignKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc")).WithArgs("shop").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "referenced_table_name"}).AddRow("orders", "customers"))
+
+	edges, err := loadForeignKeys(context.Background(), sqlx.NewDb(db, "mysql"), "shop")
+	require.NoError(t, err)
+	assert.Equal(t, []foreignKeyEdge{{Table: "orders", ReferencedTable: "customers"}}, edges)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncManagerService_ApplyDependencyOrder(t *testing.T) {
+	mockRepo := &MockRepository{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := &SyncManagerService{Service: NewService(mockRepo, logger, nil)}
+	ctx := context.Background()
+
+	orders := &TableMapping{ID: "m1", SourceTable: "orders", SortOrder: 0}
+	customers := &TableMapping{ID: "m2", SourceTable: "customers", SortOrder: 1}
+	archive := &TableMapping{ID: "m3", SourceTable: "orders", TargetTable: "orders_archive", SortOrder: 1}
+
+	// Only mappings whose sort order changes are updated
+	mockRepo.On("UpdateTableMapping", ctx, "m1", orders).Return(nil)
+	mockRepo.On("UpdateTableMapping", ctx, "m2", customers).Return(nil)
+
+	order, err := service.applyDependencyOrder(ctx, []*TableMapping{orders, customers, archive},
+		[]foreignKeyEdge{{Table: "orders", ReferencedTable: "customers"}})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"customers"}, {"orders"}}, order.Groups)
+	assert.Equal(t, 1, orders.SortOrder)
+	assert.Equal(t, 0, customers.SortOrder)
+	assert.Equal(t, 1, archive.SortOrder)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateTableMapping", ctx, "m3", archive)
+}
+
+func TestSyncManagerService_ReorderTableMappings(t *testing.T) {
+	mockRepo := &MockRepository{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	service := &SyncManagerService{Service: NewService(mockRepo, logger, nil)}
+	ctx := context.Background()
+
+	// customers and products sync concurrently, as read back from the repository
+	customers := &TableMapping{ID: "m1", SourceTable: "customers", SortOrder: 0}
+	products := &TableMapping{ID: "m2", SourceTable: "products", SortOrder: 0, ConcurrentWithPrevious: true}
+	orders := &TableMapping{ID: "m3", SourceTable: "orders", SortOrder: 1}
+	payments := &TableMapping{ID: "m4", SourceTable: "payments", SortOrder: 2}
+	mockRepo.On("GetTableMappings", ctx, "config-1").Return([]*TableMapping{customers, products, orders, payments}, nil)
+	mockRepo.On("UpdateTableMapping", ctx, "m1", customers).Return(nil)
+	mockRepo.On("UpdateTableMapping", ctx, "m2", products).Return(nil)
+	mockRepo.On("UpdateTableMapping", ctx, "m3", orders).Return(nil)
+
+	// Moving orders first keeps the group, which now runs second; payments keeps its order
+	require.NoError(t, service.ReorderTableMappings(ctx, "config-1", []string{"m3", "m1", "m2"}))
+	assert.Equal(t, 0, orders.SortOrder)
+	assert.Equal(t, 1, customers.SortOrder)
+	assert.Equal(t, 1, products.SortOrder)
+	assert.Equal(t, 2, payments.SortOrder)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateTableMapping", ctx, "m4", payments)
+
+	// Unknown mappings are rejected before anything is saved
+	err := service.ReorderTableMappings(ctx, "config-1", []string{"m3", "m9"})
+	assert.ErrorContains(t, err, "table mapping not found: m9")
+	mockRepo.AssertNumberOfCalls(t, "UpdateTableMapping", 3)
+}
+
+func TestAssignSortOrders(t *testing.T) {
+	sortOrders := func(mappings []*TableMapping) []int {
+		orders := make([]int, len(mappings))
+		for i, mapping := range mappings {
+			orders[i] = mapping.SortOrder
+		}
+		return orders
+	}
+
+	// Sort orders follow the list, also when stored orders are sent back in a new order
+	a := &TableMapping{SourceTable: "a", SortOrder: 2}
+	b := &TableMapping{SourceTable: "b", SortOrder: 0}
+	c := &TableMapping{SourceTable: "c", SortOrder: 1}
+	assignSortOrders([]*TableMapping{a, b, c})
+	assert.Equal(t, []int{0, 1, 2}, sortOrders([]*TableMapping{a, b, c}))
+
+	// Mappings concurrent with the previous one join its group
+	mappings := []*TableMapping{
+		{SourceTable: "customers", ConcurrentWithPrevious: true},
+		{SourceTable: "products", ConcurrentWithPrevious: true},
+		{SourceTable: "orders"},
+		{SourceTable: "order_items"},
+		{SourceTable: "payments", ConcurrentWithPrevious: true},
+	}
+	assignSortOrders(mappings)
+	assert.Equal(t, []int{0, 0, 1, 2, 2}, sortOrders(mappings))
+
+	// Reading the mappings back marks the same groups, so saving them again keeps the orders
+	markConcurrentMappings(mappings)
+	assert.False(t, mappings[0].ConcurrentWithPrevious)
+	assignSortOrders(mappings)
+	assert.Equal(t, []int{0, 0, 1, 2, 2}, sortOrders(mappings))
+	assert.Equal(t, []bool{false, true, false, false, true}, []bool{
+		mappings[0].ConcurrentWithPrevious, mappings[1].ConcurrentWithPrevious, mappings[2].ConcurrentWithPrevious,
+		mappings[3].ConcurrentWithPrevious, mappings[4].ConcurrentWithPrevious,
+	})
+}
@@ -0,0 +1,255 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlDialect is the SQL of MySQL, the dialect of a mysql connection
+type mysqlDialect struct{}
+
+// localDialect is the dialect of the local database, which is MySQL
+var localDialect Dialect = mysqlDialect{}
+
+// dialectFor returns the dialect of the database a connection writes to
+func dialectFor(conn *ConnectionConfig) Dialect {
+	if conn != nil && conn.isSQLite() {
+		return sqliteDialect{}
+	}
+	return mysqlDialect{}
+}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (d mysqlDialect) TableName(database, table string) string {
+	return d.QuoteIdentifier(database) + "." + d.QuoteIdentifier(table)
+}
+
+// ColumnType returns the column's type as it is
+func (mysqlDialect) ColumnType(col *ColumnInfo) string {
+	return col.Type
+}
+
+// CreateTableStatement builds the statement from the schema as it is, keeping charsets, indexes,
+// foreign keys, checks, table options and partitioning
+func (mysqlDialect) CreateTableStatement(database, table string, schema *TableSchema) string {
+	var definitions []string
+
+	// Add columns (preserve source charset/collation for string columns)
+	for _, col := range schema.Columns {
+		definitions = append(definitions, columnDefinition(col))
+	}
+
+	// Add the primary key, from the indexes or else the keys
+	emitted := make(map[string]bool)
+	for _, idx := range schema.Indexes {
+		if idx.Name == "PRIMARY" {
+			definitions = append(definitions, indexDefinition(idx))
+			emitted[idx.Name] = true
+		}
+	}
+	for _, key := range schema.Keys {
+		if key.Type == "PRIMARY KEY" && !emitted["PRIMARY"] {
+			definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", quoteColumns(key.Columns)))
+			emitted["PRIMARY"] = true
+		}
+	}
+
+	// Add the other indexes, then unique keys that have no index listed
+	for _, idx := range schema.Indexes {
+		if !emitted[idx.Name] {
+			definitions = append(definitions, indexDefinition(idx))
+			emitted[idx.Name] = true
+		}
+	}
+	for _, key := range schema.Keys {
+		if key.Type == "UNIQUE" && !emitted[key.Name] {
+			definitions = append(definitions, fmt.Sprintf("UNIQUE KEY `%s` (%s)", key.Name, quoteColumns(key.Columns)))
+		}
+	}
+
+	for _, fk := range schema.ForeignKeys {
+		definitions = append(definitions, foreignKeyDefinition(fk))
+	}
+	for _, check := range schema.Checks {
+		definitions = append(definitions, checkDefinition(check))
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE `%s`.`%s` (\n  %s\n)", database, table, strings.Join(definitions, ",\n  "))
+	if options := tableOptions(schema); options != "" {
+		statement += " " + options
+	}
+	if schema.Partitioning != "" {
+		statement += "\n" + schema.Partitioning
+	}
+	return statement
+}
+
+// InsertStatement builds the statement with a placeholder per value. Values are adapted to utf8mb3
+// columns, see valueForUTF8MB3Insert.
+func (mysqlDialect) InsertStatement(database, table string, columns []string, batch []map[string]interface{}) (string, []interface{}) {
+	return mysqlInsertStatement(database, table, columns, len(batch), func(i, j int) interface{} {
+		return batch[i][columns[j]]
+	})
+}
+
+// InsertRowsStatement builds the statement like InsertStatement
+func (mysqlDialect) InsertRowsStatement(database, table string, columns []string, rows [][]interface{}) (string, []interface{}) {
+	return mysqlInsertStatement(database, table, columns, len(rows), func(i, j int) interface{} {
+		return rows[i][j]
+	})
+}
+
+// MaxInsertRows is unlimited, MySQL bounds a statement by its bytes
+func (mysqlDialect) MaxInsertRows(columns int) int {
+	return 0
+}
+
+// MaxStatementSize returns the max_allowed_packet of the server
+func (mysqlDialect) MaxStatementSize(ctx context.Context, db *sqlx.DB) (int64, error) {
+	var maxAllowedPacket int64
+	if err := db.GetContext(ctx, &maxAllowedPacket, "SELECT @@max_allowed_packet"); err != nil {
+		return 0, fmt.Errorf("failed to read max_allowed_packet: %w", err)
+	}
+	return maxAllowedPacket, nil
+}
+
+// mysqlInsertStatement builds a multi-row INSERT of rows rows, value returning the value of the
+// row i for the column j
+func mysqlInsertStatement(database, table string, columns []string, rows int, value func(i, j int) interface{}) (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", database, table))
+
+	// Add column names
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("`%s`", col))
+	}
+
+	sb.WriteString(") VALUES ")
+
+	// Add value placeholders
+	args := make([]interface{}, 0, rows*len(columns))
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j := range columns {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("?")
+			args = append(args, valueForUTF8MB3Insert(value(i, j)))
+		}
+		sb.WriteString(")")
+	}
+
+	return sb.String(), args
+}
+
+// UpsertClause returns ON DUPLICATE KEY UPDATE. MySQL finds the conflicting row by any unique key,
+// so the key columns only tell which columns are left as they are; without keys every column is
+// updated.
+func (d mysqlDialect) UpsertClause(columns, keyColumns []string) string {
+	return d.UpdateClause(keyColumns, columns, "")
+}
+
+// UpdateClause returns ON DUPLICATE KEY UPDATE of the update columns. With a newer column each
+// assignment keeps the stored value unless the inserted row is at least as new; MySQL assigns from
+// left to right, so the newer column is assigned last, after the others compared its stored value.
+func (mysqlDialect) UpdateClause(keyColumns, updateColumns []string, newerColumn string) string {
+	assign := func(col string) string {
+		if newerColumn == "" {
+			return fmt.Sprintf("`%s` = VALUES(`%s`)", col, col)
+		}
+		return fmt.Sprintf("`%s` = IF(VALUES(`%s`) >= `%s` OR `%s` IS NULL, VALUES(`%s`), `%s`)",
+			col, newerColumn, newerColumn, newerColumn, col, col)
+	}
+
+	var updates []string
+	updatesNewer := false
+	for _, col := range updateColumns {
+		switch {
+		case isKeyColumn(col, keyColumns):
+		case col == newerColumn:
+			updatesNewer = true
+		default:
+			updates = append(updates, assign(col))
+		}
+	}
+	if updatesNewer {
+		updates = append(updates, assign(newerColumn))
+	}
+	if len(updates) == 0 && len(keyColumns) > 0 {
+		// Every column is a key column, the row has nothing to update
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE `%s` = `%s`", keyColumns[0], keyColumns[0])
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+// SkipDuplicatesClause updates a key column to itself, which leaves the row as it is
+func (mysqlDialect) SkipDuplicatesClause(keyColumns []string) string {
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE `%s` = `%s`", keyColumns[0], keyColumns[0])
+}
+
+func (mysqlDialect) TableExists(ctx context.Context, db *sqlx.DB, database, table string) (bool, error) {
+	var count int
+	checkQuery := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	if err := db.GetContext(ctx, &count, checkQuery, database, table); err != nil {
+		return false, fmt.Errorf("failed to check if table exists: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (mysqlDialect) TableColumns(ctx context.Context, db *sqlx.DB, database, table string) ([]*ColumnInfo, error) {
+	var rows []struct {
+		Name       string `db:"COLUMN_NAME"`
+		ColumnType string `db:"COLUMN_TYPE"`
+		IsNullable string `db:"IS_NULLABLE"`
+	}
+	query := `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`
+	if err := db.SelectContext(ctx, &rows, query, database, table); err != nil {
+		return nil, fmt.Errorf("failed to get table columns: %w", err)
+	}
+
+	columns := make([]*ColumnInfo, len(rows))
+	for i, row := range rows {
+		columns[i] = &ColumnInfo{Name: row.Name, Type: row.ColumnType, Nullable: row.IsNullable == "YES"}
+	}
+	return columns, nil
+}
+
+// isKeyColumn reports whether a column is one of the key columns
+func isKeyColumn(col string, keyColumns []string) bool {
+	for _, key := range keyColumns {
+		if col == key {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictClause returns the clause ending an INSERT of a dialect that resolves rows whose key exists
+// per the conflict resolution. ConflictResolutionError has none, a duplicate fails the statement.
+func conflictClause(d Dialect, columns, primaryKeys []string, resolution ConflictResolution) string {
+	switch resolution {
+	case ConflictResolutionOverwrite:
+		return " " + d.UpsertClause(columns, primaryKeys)
+	case ConflictResolutionSkip:
+		return " " + d.SkipDuplicatesClause(primaryKeys)
+	}
+	return ""
+}
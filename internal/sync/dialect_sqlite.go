@@ -0,0 +1,228 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteMaxVariables is the most placeholders a SQLite statement can have
+const sqliteMaxVariables = 32766
+
+// sqliteDialect is the SQL of SQLite, the dialect of a sqlite connection. A SQLite file is a single
+// database, so table names ignore the database.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d sqliteDialect) TableName(database, table string) string {
+	return d.QuoteIdentifier(table)
+}
+
+// ColumnType maps a MySQL column type to the SQLite type affinity its values keep: integers are
+// INTEGER, floats REAL and binary columns BLOB. DECIMAL is TEXT, which keeps its precision, and so
+// is BIGINT UNSIGNED, whose values may not fit SQLite's signed integers, and dates and times,
+// written as MySQL prints them.
+func (sqliteDialect) ColumnType(col *ColumnInfo) string {
+	base := baseColumnType(col.Type)
+	switch {
+	case isUnsignedBigint(col.Type):
+		return "TEXT"
+	case isIntegerColumnType(col.Type) || base == "year":
+		return "INTEGER"
+	case base == "float" || base == "double" || base == "real":
+		return "REAL"
+	case strings.HasSuffix(base, "blob") || strings.HasSuffix(base, "binary") || base == "bit" ||
+		base == "geometry" || base == "point" || base == "linestring" || base == "polygon" ||
+		strings.HasPrefix(base, "multi") || base == "geometrycollection":
+		return "BLOB"
+	}
+	return "TEXT"
+}
+
+// CreateTableStatement builds the table with its columns, primary key and unique keys, followed by
+// the statements creating its other indexes, which SQLite can't declare in the table. Index names
+// are prefixed with the table's, SQLite's index names being unique in the database. Key parts on an
+// expression and prefix lengths are left out.
+func (d sqliteDialect) CreateTableStatement(database, table string, schema *TableSchema) string {
+	var definitions []string
+	for _, col := range schema.Columns {
+		definition := d.QuoteIdentifier(col.Name) + " " + d.ColumnType(col)
+		if !col.Nullable {
+			definition += " NOT NULL"
+		}
+		definitions = append(definitions, definition)
+	}
+	if keys := schema.primaryKey(); len(keys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", d.quoteColumns(keys)))
+	}
+
+	emitted := map[string]bool{"PRIMARY": true}
+	var indexes []string
+	for _, idx := range schema.Indexes {
+		if emitted[idx.Name] || len(idx.Columns) == 0 || hasExpressionKeyPart(idx) {
+			continue
+		}
+		emitted[idx.Name] = true
+		if idx.Unique {
+			definitions = append(definitions, fmt.Sprintf("UNIQUE (%s)", d.quoteColumns(idx.Columns)))
+			continue
+		}
+		indexes = append(indexes, fmt.Sprintf("CREATE INDEX %s ON %s (%s)",
+			d.QuoteIdentifier(table+"_"+idx.Name), d.TableName(database, table), d.quoteColumns(idx.Columns)))
+	}
+	for _, key := range schema.Keys {
+		if key.Type == "UNIQUE" && !emitted[key.Name] {
+			definitions = append(definitions, fmt.Sprintf("UNIQUE (%s)", d.quoteColumns(key.Columns)))
+		}
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", d.TableName(database, table), strings.Join(definitions, ",\n  "))
+	for _, index := range indexes {
+		statement += ";\n" + index
+	}
+	return statement
+}
+
+func (d sqliteDialect) quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdentifier(col)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// InsertStatement builds the statement with a placeholder per value. SQLite integers are signed, a
+// uint64 beyond the range of an int64 is bound as text.
+func (d sqliteDialect) InsertStatement(database, table string, columns []string, batch []map[string]interface{}) (string, []interface{}) {
+	return d.insertStatement(database, table, columns, len(batch), func(i, j int) interface{} {
+		return batch[i][columns[j]]
+	})
+}
+
+// InsertRowsStatement builds the statement like InsertStatement
+func (d sqliteDialect) InsertRowsStatement(database, table string, columns []string, rows [][]interface{}) (string, []interface{}) {
+	return d.insertStatement(database, table, columns, len(rows), func(i, j int) interface{} {
+		return rows[i][j]
+	})
+}
+
+// insertStatement builds a multi-row INSERT of rows rows, value returning the value of the row i for
+// the column j
+func (d sqliteDialect) insertStatement(database, table string, columns []string, rows int, value func(i, j int) interface{}) (string, []interface{}) {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	values := make([]string, rows)
+	args := make([]interface{}, 0, rows*len(columns))
+	for i := 0; i < rows; i++ {
+		values[i] = row
+		for j := range columns {
+			v := value(i, j)
+			if n, ok := v.(uint64); ok && n > math.MaxInt64 {
+				v = fmt.Sprint(n)
+			}
+			args = append(args, v)
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.TableName(database, table), d.quoteColumns(columns), strings.Join(values, ", ")), args
+}
+
+// MaxInsertRows keeps a statement within the placeholders SQLite allows
+func (sqliteDialect) MaxInsertRows(columns int) int {
+	if columns == 0 {
+		return 0
+	}
+	return sqliteMaxVariables / columns
+}
+
+// MaxStatementSize is unlimited, the values are bound rather than part of the statement text
+func (sqliteDialect) MaxStatementSize(ctx context.Context, db *sqlx.DB) (int64, error) {
+	return 0, nil
+}
+
+// UpsertClause returns ON CONFLICT DO UPDATE. Without key columns it resolves a conflict on any
+// unique key and updates every column.
+func (d sqliteDialect) UpsertClause(columns, keyColumns []string) string {
+	return d.UpdateClause(keyColumns, columns, "")
+}
+
+// UpdateClause returns ON CONFLICT DO UPDATE of the update columns, restricted by a WHERE on the
+// newer column when there is one. SQLite assigns every column from the stored row as it was.
+func (d sqliteDialect) UpdateClause(keyColumns, updateColumns []string, newerColumn string) string {
+	var updates []string
+	for _, col := range updateColumns {
+		if !isKeyColumn(col, keyColumns) {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", d.QuoteIdentifier(col), d.QuoteIdentifier(col)))
+		}
+	}
+	target := ""
+	if len(keyColumns) > 0 {
+		target = fmt.Sprintf(" (%s)", d.quoteColumns(keyColumns))
+	}
+	if len(updates) == 0 {
+		// Every column is a key column, the row has nothing to update
+		return "ON CONFLICT" + target + " DO NOTHING"
+	}
+	clause := "ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(updates, ", ")
+	if newerColumn != "" {
+		newer := d.QuoteIdentifier(newerColumn)
+		clause += fmt.Sprintf(" WHERE excluded.%s >= %s OR %s IS NULL", newer, newer, newer)
+	}
+	return clause
+}
+
+func (sqliteDialect) SkipDuplicatesClause(keyColumns []string) string {
+	return "ON CONFLICT DO NOTHING"
+}
+
+func (sqliteDialect) TableExists(ctx context.Context, db *sqlx.DB, database, table string) (bool, error) {
+	var count int
+	if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table); err != nil {
+		return false, fmt.Errorf("failed to check if table exists: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (d sqliteDialect) TableColumns(ctx context.Context, db *sqlx.DB, database, table string) ([]*ColumnInfo, error) {
+	var rows []struct {
+		CID          int            `db:"cid"`
+		Name         string         `db:"name"`
+		Type         string         `db:"type"`
+		NotNull      bool           `db:"notnull"`
+		DefaultValue sql.NullString `db:"dflt_value"`
+		PK           int            `db:"pk"`
+	}
+	if err := db.SelectContext(ctx, &rows, fmt.Sprintf("PRAGMA table_info(%s)", d.QuoteIdentifier(table))); err != nil {
+		return nil, fmt.Errorf("failed to get table columns: %w", err)
+	}
+
+	columns := make([]*ColumnInfo, len(rows))
+	for i, row := range rows {
+		columns[i] = &ColumnInfo{Name: row.Name, Type: row.Type, Nullable: !row.NotNull, DefaultValue: row.DefaultValue.String}
+	}
+	return columns, nil
+}
+
+// primaryKey returns the primary key columns of a schema, from its indexes or else its keys
+func (s *TableSchema) primaryKey() []string {
+	for _, idx := range s.Indexes {
+		if idx.Name == "PRIMARY" {
+			return idx.Columns
+		}
+	}
+	for _, key := range s.Keys {
+		if key.Type == "PRIMARY KEY" {
+			return key.Columns
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLDialect_Statements(t *testing.T) {
+	d := mysqlDialect{}
+	batch := []map[string]interface{}{{"id": 1, "note": "a"}, {"id": 2}}
+
+	query, args := d.InsertStatement("shop", "orders", []string{"id", "note"}, batch)
+	assert.Equal(t, "INSERT INTO `shop`.`orders` (`id`, `note`) VALUES (?, ?), (?, ?)", query)
+	assert.Equal(t, []interface{}{1, "a", 2, nil}, args)
+	rowsQuery, rowsArgs := d.InsertRowsStatement("shop", "orders", []string{"id", "note"}, [][]interface{}{{1, "a"}, {2, nil}})
+	assert.Equal(t, query, rowsQuery)
+	assert.Equal(t, args, rowsArgs)
+	assert.Equal(t, 0, d.MaxInsertRows(2))
+
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `note` = VALUES(`note`)", d.UpsertClause([]string{"id", "note"}, []string{"id"}))
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `note` = VALUES(`note`)", d.UpsertClause([]string{"id", "note"}, nil))
+	// A row of key columns only has nothing to update
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE `id` = `id`", d.UpsertClause([]string{"id"}, []string{"id"}))
+
+	assert.Equal(t, " ON DUPLICATE KEY UPDATE `id` = `id`", conflictClause(d, []string{"id", "note"}, []string{"id"}, ConflictResolutionSkip))
+	assert.Equal(t, "", conflictClause(d, []string{"id", "note"}, []string{"id"}, ConflictResolutionError))
+	assert.Equal(t, "`a``b`", d.QuoteIdentifier("a`b"))
+}
+
+func TestSQLiteDialect_Statements(t *testing.T) {
+	d := sqliteDialect{}
+	schema := &TableSchema{
+		Name: "orders",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint"}, {Name: "code", Type: "varchar(16)"},
+			{Name: "customer_id", Type: "int unsigned", Nullable: true}, {Name: "total", Type: "decimal(10,2)", Nullable: true},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true},
+			{Name: "uk_code", Columns: []string{"code"}, Unique: true},
+			{Name: "idx_customer", Columns: []string{"customer_id", "total"}},
+			{Name: "idx_expr", Columns: []string{""}},
+		},
+	}
+	assert.Equal(t, "CREATE TABLE \"orders\" (\n"+
+		"  \"id\" INTEGER NOT NULL,\n"+
+		"  \"code\" TEXT NOT NULL,\n"+
+		"  \"customer_id\" INTEGER,\n"+
+		"  \"total\" TEXT,\n"+
+		"  PRIMARY KEY (\"id\"),\n"+
+		"  UNIQUE (\"code\")\n"+
+		");\n"+
+		"CREATE INDEX \"orders_idx_customer\" ON \"orders\" (\"customer_id\", \"total\")", d.CreateTableStatement("shop", "orders", schema))
+
+	query, args := d.InsertStatement("shop", "orders", []string{"id", "code"}, []map[string]interface{}{
+		{"id": uint64(18446744073709551615), "code": "a"}, {"id": uint64(7), "code": nil},
+	})
+	assert.Equal(t, `INSERT INTO "orders" ("id", "code") VALUES (?, ?), (?, ?)`, query)
+	assert.Equal(t, []interface{}{"18446744073709551615", "a", uint64(7), nil}, args)
+	rowsQuery, rowsArgs := d.InsertRowsStatement("shop", "orders", []string{"id", "code"}, [][]interface{}{{uint64(18446744073709551615), "a"}, {uint64(7), nil}})
+	assert.Equal(t, query, rowsQuery)
+	assert.Equal(t, args, rowsArgs)
+	assert.Equal(t, sqliteMaxVariables/2, d.MaxInsertRows(2))
+
+	assert.Equal(t, `ON CONFLICT ("id") DO UPDATE SET "code" = excluded."code"`, d.UpsertClause([]string{"id", "code"}, []string{"id"}))
+	assert.Equal(t, `ON CONFLICT ("id") DO NOTHING`, d.UpsertClause([]string{"id"}, []string{"id"}))
+	assert.Equal(t, " ON CONFLICT DO NOTHING", conflictClause(d, []string{"id", "code"}, []string{"id"}, ConflictResolutionSkip))
+	assert.Equal(t, `ON CONFLICT ("id") DO UPDATE SET "code" = excluded."code" WHERE excluded."at" >= "at" OR "at" IS NULL`,
+		d.UpdateClause([]string{"id"}, []string{"id", "code"}, "at"))
+	assert.Equal(t, `"a""b"`, d.QuoteIdentifier(`a"b`))
+}
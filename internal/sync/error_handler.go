@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -23,6 +24,7 @@ const (
 	ErrorTypeSystemResource     ErrorType = "system_resource"
 	ErrorTypeDiskSpace          ErrorType = "disk_space"
 	ErrorTypeLockTimeout        ErrorType = "lock_timeout"
+	ErrorTypeMaskingPolicy      ErrorType = "masking_policy"
 	ErrorTypeUnknown            ErrorType = "unknown"
 )
 
@@ -125,6 +127,22 @@ func (eh *ErrorHandler) ClassifyError(err error, tableName string) *SyncError {
 		Timestamp: time.Now(),
 	}
 
+	// Unmasked columns the sync config requires to be masked must never be copied
+	if errors.Is(err, errMaskingRequired) {
+		syncErr.Type = ErrorTypeMaskingPolicy
+		syncErr.Severity = ErrorSeverityCritical
+		syncErr.Message = "Column requires a masking rule"
+		syncErr.Retryable = false
+		return syncErr
+	}
+	if errors.Is(err, errMaskingSecretRequired) {
+		syncErr.Type = ErrorTypeMaskingPolicy
+		syncErr.Severity = ErrorSeverityCritical
+		syncErr.Message = "Masking secret is not configured"
+		syncErr.Retryable = false
+		return syncErr
+	}
+
 	errMsg := strings.ToLower(err.Error())
 
 	// Lock timeout errors (check before general timeout)
@@ -413,6 +431,11 @@ func (eh *ErrorHandler) GetErrorSuggestion(err error) string {
 		return "Reduce transaction size or increase lock timeout. Consider running sync during off-peak hours."
 	case ErrorTypeSystemResource:
 		return "Reduce batch size and concurrent operations. Monitor system resources and consider upgrading hardware."
+	case ErrorTypeMaskingPolicy:
+		if errors.Is(err, errMaskingSecretRequired) {
+			return "Set sync.masking_secret, or DBT_SYNC_MASKING_SECRET, on the server to mask with hash, fake or date_shift."
+		}
+		return "Add a masking rule for the column to the table mapping, or exclude the column with a column rule."
 	default:
 		return "Review error logs for more details. Contact support if the issue persists."
 	}
@@ -0,0 +1,624 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// exportManifestName is the manifest listing the files of an exported table
+const exportManifestName = "manifest.json"
+
+// Kinds of export files: a full export holds every row, a delta the rows changed since the last export
+const (
+	exportKindFull  = "full"
+	exportKindDelta = "delta"
+)
+
+// exportManifest lists the files that make up an exported table. A full export replaces the list,
+// each incremental export adds the delta files it wrote, oldest first.
+type exportManifest struct {
+	Table       string               `json:"table"`
+	Format      FileFormat           `json:"format"`
+	Compression FileCompression      `json:"compression"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Files       []exportManifestFile `json:"files"`
+}
+
+// exportManifestFile is a file of an exported table
+type exportManifestFile struct {
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"`
+	JobID         string    `json:"job_id,omitempty"`
+	Rows          int64     `json:"rows"`
+	Bytes         int64     `json:"bytes"`
+	SHA256        string    `json:"sha256"`
+	WatermarkFrom string    `json:"watermark_from,omitempty"` // Delta only: rows changed after this watermark
+	WatermarkTo   string    `json:"watermark_to,omitempty"`   // Delta only: up to this one
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// withDefaults returns the file options with the defaults filled in
+func (o *FileOptions) withDefaults() FileOptions {
+	options := FileOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.Format == "" {
+		options.Format = FileFormatCSV
+	}
+	if options.Compression == "" {
+		options.Compression = FileCompressionNone
+	}
+	return options
+}
+
+// extension returns the file name extension of export files
+func (o FileOptions) extension() string {
+	if o.Format == FileFormatParquet {
+		return ".parquet"
+	}
+	switch o.Compression {
+	case FileCompressionGzip:
+		return "." + string(o.Format) + ".gz"
+	case FileCompressionZstd:
+		return "." + string(o.Format) + ".zst"
+	}
+	return "." + string(o.Format)
+}
+
+// validateFileConnection checks the settings of a file connection
+func validateFileConnection(config *ConnectionConfig) error {
+	if config.Path == "" {
+		return fmt.Errorf("path is required for a file connection")
+	}
+	if !filepath.IsAbs(config.Path) {
+		return fmt.Errorf("path of a file connection must be absolute: %s", config.Path)
+	}
+	options := config.FileOptions.withDefaults()
+	switch options.Format {
+	case FileFormatCSV, FileFormatJSONL, FileFormatParquet:
+	default:
+		return fmt.Errorf("invalid file format: %s", options.Format)
+	}
+	switch options.Compression {
+	case FileCompressionNone, FileCompressionGzip, FileCompressionZstd:
+	default:
+		return fmt.Errorf("unsupported file compression: %s", options.Compression)
+	}
+	if options.MaxFileSize < 0 {
+		return fmt.Errorf("max file size cannot be negative")
+	}
+	delimiter, quote := ",", "\""
+	if options.Delimiter != "" {
+		delimiter = options.Delimiter
+	}
+	if options.Quote != "" {
+		quote = options.Quote
+	}
+	if utf8.RuneCountInString(delimiter) != 1 || strings.ContainsAny(delimiter, "\r\n") {
+		return fmt.Errorf("file delimiter must be a single character: %q", delimiter)
+	}
+	if quote != fileQuoteNone {
+		if utf8.RuneCountInString(quote) != 1 || strings.ContainsAny(quote, "\r\n") {
+			return fmt.Errorf("file quote must be a single character or %s: %q", fileQuoteNone, quote)
+		}
+		if quote == delimiter {
+			return fmt.Errorf("file quote and delimiter must differ")
+		}
+	}
+	if _, err := sourceDecoder(options.Encoding); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkExportDirectory makes sure files can be written to the directory of a file connection,
+// creating it when missing
+func checkExportDirectory(path string) error {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	probe, err := os.CreateTemp(path, ".db-taxi-probe-*")
+	if err != nil {
+		return fmt.Errorf("export directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// exportDirectoryName checks a database or table name can be used as a directory name
+func exportDirectoryName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid export directory name: %q", name)
+	}
+	return nil
+}
+
+// syncConnections returns the sync config of a mapping with its source and target connections
+func (e *DefaultSyncEngine) syncConnections(ctx context.Context, mapping *TableMapping) (*SyncConfig, *ConnectionConfig, *ConnectionConfig, error) {
+	syncConfig, err := e.repo.GetSyncConfig(ctx, mapping.SyncConfigID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get sync config: %w", err)
+	}
+	sourceConnConfig, err := e.repo.GetConnection(ctx, syncConfig.SourceConnectionID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get source connection config: %w", err)
+	}
+	targetConnConfig, err := e.repo.GetConnection(ctx, syncConfig.TargetConnectionID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get target connection config: %w", err)
+	}
+	return syncConfig, sourceConnConfig, targetConnConfig, nil
+}
+
+// exportTable exports a table to the directory of a file connection, <path>/<target database>/<target
+// table>/. A full sync writes every row and replaces the files listed in the table's manifest. An
+// incremental sync writes every row the first time and then, on each run, a delta of the rows changed
+// since the last one, tracked by the same checkpoints as an incremental sync into a database.
+func (e *DefaultSyncEngine) exportTable(ctx context.Context, job *SyncJob, mapping *TableMapping, syncConfig *SyncConfig, source, target *ConnectionConfig) error {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+		"sync_mode":    mapping.SyncMode,
+		"path":         target.Path,
+	}).Info("Starting table export")
+
+	src, mapping, err := e.openExportSource(ctx, job, mapping, syncConfig, source)
+	if err != nil {
+		return err
+	}
+	defer src.db.Close()
+	if mapping.DeleteDetection != DeleteDetectionNone {
+		e.logger.WithField("source_table", mapping.SourceTable).Warn("Delete detection is not supported by file exports and is ignored")
+	}
+
+	targetDBName := syncConfig.TargetDatabase
+	if targetDBName == "" {
+		targetDBName = src.dbName
+	}
+	for _, name := range []string{targetDBName, mapping.TargetTable} {
+		if err := exportDirectoryName(name); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(target.Path, targetDBName, mapping.TargetTable)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	export := &tableExport{
+		job:          job,
+		dir:          dir,
+		sourceDB:     src.db,
+		sourceDBName: src.dbName,
+		options:      target.FileOptions.withDefaults(),
+		targetSchema: src.targetSchema,
+		masker:       src.masker,
+		selectList:   src.selectList,
+		batchSize:    src.batchSize,
+	}
+
+	var exported int64
+	switch mapping.SyncMode {
+	case SyncModeFull:
+		exported, err = e.exportAll(ctx, export, mapping, nil)
+	case SyncModeIncremental:
+		exported, err = e.exportIncremental(ctx, export, mapping)
+	default:
+		return fmt.Errorf("sync mode %s is not supported by file exports", mapping.SyncMode)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"job_id":        job.ID,
+		"source_table":  mapping.SourceTable,
+		"target_table":  mapping.TargetTable,
+		"exported_rows": exported,
+		"directory":     dir,
+	}).Info("Table export completed successfully")
+	return nil
+}
+
+// exportSource is the source table of a copy out of MySQL into something else than a MySQL table,
+// read with the column rules and masking rules of its mapping
+type exportSource struct {
+	db           *sqlx.DB
+	dbName       string
+	targetSchema *TableSchema
+	masker       *rowMasker
+	selectList   string
+	batchSize    int
+}
+
+// openExportSource connects to the source database of a mapping and prepares reading its table. It
+// returns the mapping with its row filter resolved.
+func (e *DefaultSyncEngine) openExportSource(ctx context.Context, job *SyncJob, mapping *TableMapping, syncConfig *SyncConfig, source *ConnectionConfig) (*exportSource, *TableMapping, error) {
+	sourceDBName := syncConfig.SourceDatabase
+	if sourceDBName == "" {
+		sourceDBName = source.Database
+	}
+	if sourceDBName == "" {
+		return nil, nil, fmt.Errorf("source database is required")
+	}
+
+	cc := *source
+	cc.Database = sourceDBName
+	sourceDB, err := e.connectToRemote(&cc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to source database: %w", err)
+	}
+
+	src := &exportSource{db: sourceDB, dbName: sourceDBName, batchSize: 1000}
+	if syncConfig.Options != nil && syncConfig.Options.BatchSize > 0 {
+		src.batchSize = syncConfig.Options.BatchSize
+	}
+	mapping, err = e.prepareExportSource(ctx, job, mapping, syncConfig, src)
+	if err != nil {
+		sourceDB.Close()
+		return nil, nil, err
+	}
+	return src, mapping, nil
+}
+
+// prepareExportSource reads the schema of the source table and sets up the columns read from it
+func (e *DefaultSyncEngine) prepareExportSource(ctx context.Context, job *SyncJob, mapping *TableMapping, syncConfig *SyncConfig, src *exportSource) (*TableMapping, error) {
+	schema, err := e.getTableSchemaFromRemote(ctx, src.db, mapping.SourceTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table schema: %w", err)
+	}
+	mapping, err = e.resolveRowFilter(ctx, job, mapping, schema)
+	if err != nil {
+		return nil, err
+	}
+	columns := mapping.columnMapping()
+	src.targetSchema, err = columns.targetSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	src.masker, err = newRowMasker(mapping, syncConfig.Options, e.maskingSecret, schema, src.targetSchema)
+	if err != nil {
+		return nil, err
+	}
+	src.selectList = columns.selectList(schema)
+	return mapping, nil
+}
+
+// tableExport is the export of a table in progress
+type tableExport struct {
+	job          *SyncJob
+	dir          string
+	sourceDB     *sqlx.DB
+	sourceDBName string
+	options      FileOptions
+	targetSchema *TableSchema
+	masker       *rowMasker
+	selectList   string
+	batchSize    int
+}
+
+// exportIncremental exports the rows of an incremental mapping changed since its checkpoint, or every
+// row when it has none yet
+func (e *DefaultSyncEngine) exportIncremental(ctx context.Context, export *tableExport, mapping *TableMapping) (int64, error) {
+	return e.copyIncremental(ctx, export.sourceDB, mapping, func(changed *changedRows) (int64, error) {
+		return e.exportAll(ctx, export, mapping, changed)
+	})
+}
+
+// copyIncremental copies the rows of an incremental mapping changed since its checkpoint with copyRows,
+// or every row, a nil delta, when it has none yet. The checkpoint is taken before the rows are read, so
+// a row changing while they are may be copied again by the next run but is never missed.
+func (e *DefaultSyncEngine) copyIncremental(ctx context.Context, sourceDB *sqlx.DB, mapping *TableMapping, copyRows func(changed *changedRows) (int64, error)) (int64, error) {
+	checkpoint, err := e.repo.GetCheckpoint(ctx, mapping.ID)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to load checkpoint, performing full copy instead")
+		checkpoint = nil
+	}
+
+	changeColumn, changeType, err := e.changeTrackingColumn(ctx, sourceDB, mapping)
+	if err != nil {
+		if checkpoint != nil && !isCopyCheckpoint(checkpoint) {
+			return 0, fmt.Errorf("failed to detect change tracking column: %w", err)
+		}
+		// Like createInitialCheckpoint, the first copy runs without a checkpoint to continue from
+		e.logger.WithError(err).Warn("Failed to detect change tracking column, checkpoint not created")
+		return copyRows(nil)
+	}
+	latest, err := e.latestCheckpoint(ctx, mapping, changeColumn, changeType, sourceDB)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get max change tracking value: %w", err)
+	}
+
+	if checkpoint == nil || isCopyCheckpoint(checkpoint) {
+		e.logger.Info("No checkpoint found, performing initial full copy")
+		copied, err := copyRows(nil)
+		if err != nil {
+			return 0, err
+		}
+		latest.CreatedAt = latest.UpdatedAt
+		if err := e.repo.CreateCheckpoint(ctx, latest); err != nil {
+			return 0, fmt.Errorf("failed to create checkpoint: %w", err)
+		}
+		return copied, nil
+	}
+
+	changed, err := e.changedRowsSince(ctx, sourceDB, mapping, changeColumn, changeType, checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	changed.upTo(latest)
+	copied, err := copyRows(changed)
+	if err != nil {
+		return 0, err
+	}
+	if err := e.repo.UpdateCheckpoint(ctx, mapping.ID, latest); err != nil {
+		return 0, fmt.Errorf("failed to update checkpoint: %w", err)
+	}
+	return copied, nil
+}
+
+// exportAll writes the rows of a table to new files and records them in the manifest: every row
+// selected by the row filter, or only the changed rows of a delta
+func (e *DefaultSyncEngine) exportAll(ctx context.Context, export *tableExport, mapping *TableMapping, changed *changedRows) (int64, error) {
+	manifest, err := readExportManifest(export.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	table := fmt.Sprintf("`%s`.`%s`", export.sourceDBName, mapping.SourceTable)
+	kind := exportKindFull
+	query := fmt.Sprintf("SELECT %s FROM %s%s", export.selectList, table, mapping.rowFilter().where())
+	args := mapping.rowFilter().args
+	if changed != nil {
+		kind = exportKindDelta
+		query, args = changed.query(export.selectList, table), changed.args
+	}
+
+	rows, err := export.sourceDB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query source rows: %w", err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get column names: %w", err)
+	}
+
+	files := &exportFileSet{
+		dir:     export.dir,
+		prefix:  fmt.Sprintf("%s-%s-%s", mapping.TargetTable, kind, time.Now().UTC().Format("20060102T150405Z")),
+		options: export.options,
+		columns: exportColumns(names, export.targetSchema),
+	}
+	defer files.abort()
+
+	var batch []map[string]interface{}
+	var exported int64
+	flush := func() error {
+		if err := export.masker.maskRows(batch); err != nil {
+			return err
+		}
+		for _, row := range batch {
+			if err := files.writeRow(row); err != nil {
+				return err
+			}
+		}
+		exported += int64(len(batch))
+		batch = batch[:0]
+		ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, exported, exported)
+		return nil
+	}
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := rows.MapScan(row); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		batch = append(batch, row)
+		if len(batch) >= export.batchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read source rows: %w", err)
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	// A full export of an empty table still writes a file, the table's snapshot is empty
+	if kind == exportKindFull && len(files.done) == 0 && files.current == nil {
+		if err := files.open(); err != nil {
+			return 0, err
+		}
+	}
+	if err := files.close(); err != nil {
+		return 0, err
+	}
+
+	for i := range files.done {
+		files.done[i].Kind = kind
+		files.done[i].JobID = export.job.ID
+		if changed != nil {
+			files.done[i].WatermarkFrom = changed.since
+			files.done[i].WatermarkTo = changed.until
+		}
+	}
+	replaced := manifest.Files
+	if kind == exportKindFull {
+		manifest.Files = nil
+	}
+	manifest.Table = mapping.TargetTable
+	manifest.Format = export.options.Format
+	manifest.Compression = export.options.Compression
+	manifest.UpdatedAt = time.Now().UTC()
+	manifest.Files = append(manifest.Files, files.done...)
+	if err := writeExportManifest(export.dir, manifest); err != nil {
+		return 0, err
+	}
+	files.done = nil
+
+	// Files of the previous exports go once the manifest no longer lists them
+	if kind == exportKindFull {
+		current := make(map[string]bool, len(manifest.Files))
+		for _, file := range manifest.Files {
+			current[file.Name] = true
+		}
+		for _, file := range replaced {
+			if current[file.Name] || exportDirectoryName(file.Name) != nil {
+				continue
+			}
+			if err := os.Remove(filepath.Join(export.dir, file.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				e.logger.WithError(err).WithField("file", file.Name).Warn("Failed to remove replaced export file")
+			}
+		}
+	}
+	return exported, nil
+}
+
+// exportFileSet writes the rows of an export to numbered files, starting the next file once one
+// reaches the maximum file size
+type exportFileSet struct {
+	dir     string
+	prefix  string
+	options FileOptions
+	columns []exportColumn
+	seq     int
+	current *exportFile
+	done    []exportManifestFile
+}
+
+// open starts the next file, skipping names already taken by an earlier export
+func (s *exportFileSet) open() error {
+	for {
+		s.seq++
+		name := fmt.Sprintf("%s-%04d%s", s.prefix, s.seq, s.options.extension())
+		if _, err := os.Stat(filepath.Join(s.dir, name)); errors.Is(err, os.ErrNotExist) {
+			file, err := createExportFile(s.dir, name, s.options, s.columns)
+			if err != nil {
+				return err
+			}
+			s.current = file
+			return nil
+		}
+	}
+}
+
+func (s *exportFileSet) writeRow(row map[string]interface{}) error {
+	if s.current == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	values := make([]interface{}, len(s.columns))
+	for i, col := range s.columns {
+		value, err := col.normalize(row[col.name])
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	if err := s.current.writeRow(values); err != nil {
+		return err
+	}
+	if s.options.MaxFileSize > 0 && s.current.size() >= s.options.MaxFileSize {
+		return s.close()
+	}
+	return nil
+}
+
+// close finishes the current file
+func (s *exportFileSet) close() error {
+	if s.current == nil {
+		return nil
+	}
+	file := s.current
+	s.current = nil
+	if err := file.finish(); err != nil {
+		return err
+	}
+	s.done = append(s.done, file.manifestEntry())
+	return nil
+}
+
+// abort removes the files of an export that failed, which no manifest lists
+func (s *exportFileSet) abort() {
+	if s.current != nil {
+		s.current.abort()
+		s.current = nil
+	}
+	for _, file := range s.done {
+		os.Remove(filepath.Join(s.dir, file.Name))
+	}
+	s.done = nil
+}
+
+// readExportManifest reads the manifest of an export directory, empty when there is none yet
+func readExportManifest(dir string) (*exportManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, exportManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &exportManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export manifest: %w", err)
+	}
+	var manifest exportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse export manifest %s: %w", filepath.Join(dir, exportManifestName), err)
+	}
+	return &manifest, nil
+}
+
+// writeExportManifest replaces the manifest of an export directory
+func writeExportManifest(dir string, manifest *exportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export manifest: %w", err)
+	}
+	tmp := filepath.Join(dir, "."+exportManifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, exportManifestName)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	return nil
+}
+
+// Value stores file options as JSON
+func (o FileOptions) Value() (driver.Value, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file options: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan reads file options stored as JSON
+func (o *FileOptions) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported file options type: %T", src)
+	}
+	return json.Unmarshal(data, o)
+}
@@ -0,0 +1,227 @@
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTableExport(t *testing.T, options FileOptions) (*DefaultSyncEngine, *tableExport, sqlmock.Sqlmock) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	return engine, &tableExport{
+		job:          &SyncJob{ID: "job-1"},
+		dir:          t.TempDir(),
+		sourceDB:     sourceDB,
+		sourceDBName: "shop",
+		options:      options.withDefaults(),
+		targetSchema: ordersSchema(),
+		selectList:   "*",
+		batchSize:    2,
+	}, sourceMock
+}
+
+func exportOrdersRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "note", "total", "photo", "shipped_on"}).
+		AddRow([]byte("1"), []byte(`say "hi", bye`), []byte("9.5"), []byte{0xff, 0x00}, []byte("2024-03-01")).
+		AddRow([]byte("2"), []byte(""), nil, nil, nil).
+		AddRow([]byte("3"), []byte("<b>&</b>"), []byte("1e-7"), []byte("x"), []byte("2024-03-02"))
+}
+
+func readExportFile(t *testing.T, export *tableExport, name string) string {
+	data, err := os.ReadFile(filepath.Join(export.dir, name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExportAll_CSV(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{})
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders`")).WillReturnRows(exportOrdersRows())
+	exported, err := engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), exported)
+
+	manifest, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	file := manifest.Files[0]
+	assert.Regexp(t, `^orders-full-\d{8}T\d{6}Z-0001\.csv$`, file.Name)
+	assert.Equal(t, exportKindFull, file.Kind)
+	assert.Equal(t, "job-1", file.JobID)
+	assert.Equal(t, int64(3), file.Rows)
+	assert.Len(t, file.SHA256, 64)
+
+	// NULL is an empty field, an empty string a quoted one
+	content := readExportFile(t, export, file.Name)
+	assert.Equal(t, "id,note,total,photo,shipped_on\r\n"+
+		"1,\"say \"\"hi\"\", bye\",9.5,/wA=,2024-03-01\r\n"+
+		"2,\"\",,,\r\n"+
+		"3,<b>&</b>,1e-07,eA==,2024-03-02\r\n", content)
+	assert.Equal(t, int64(len(content)), file.Bytes)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestExportAll_JSONLinesGzip(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{Format: FileFormatJSONL, Compression: FileCompressionGzip})
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders`")).WillReturnRows(exportOrdersRows())
+	_, err := engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+
+	manifest, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Regexp(t, `\.jsonl\.gz$`, manifest.Files[0].Name)
+
+	reader, err := gzip.NewReader(bytes.NewReader([]byte(readExportFile(t, export, manifest.Files[0].Name))))
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"note":"say \"hi\", bye","total":9.5,"photo":"/wA=","shipped_on":"2024-03-01"}`+"\n"+
+		`{"id":2,"note":"","total":null,"photo":null,"shipped_on":null}`+"\n"+
+		`{"id":3,"note":"<b>&</b>","total":1e-07,"photo":"eA==","shipped_on":"2024-03-02"}`+"\n", string(content))
+}
+
+func TestExportAll_CSVZstd(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{Compression: FileCompressionZstd})
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders`")).WillReturnRows(exportOrdersRows())
+	_, err := engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+
+	manifest, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Regexp(t, `\.csv\.zst$`, manifest.Files[0].Name)
+
+	// A file source reads the export back
+	file, err := openSourceFile(filepath.Join(export.dir, manifest.Files[0].Name), FileOptions{})
+	require.NoError(t, err)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "id,note,total,photo,shipped_on\r\n"+
+		"1,\"say \"\"hi\"\", bye\",9.5,/wA=,2024-03-01\r\n"+
+		"2,\"\",,,\r\n"+
+		"3,<b>&</b>,1e-07,eA==,2024-03-02\r\n", string(content))
+}
+
+func TestExportAll_RollsFilesAndReplacesFullExport(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{MaxFileSize: 1})
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", RowFilter: &RowFilter{Column: "id", Operator: FilterOpGreater, Operand: 0.0}}
+	mapping, err := engine.resolveRowFilter(context.Background(), nil, mapping, ordersSchema())
+	require.NoError(t, err)
+
+	// Every row fills a file
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE `id` > ?")).WithArgs(int64(0)).WillReturnRows(exportOrdersRows())
+	_, err = engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+	first, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, first.Files, 3)
+	assert.Regexp(t, `-0003\.csv$`, first.Files[2].Name)
+	assert.Equal(t, int64(1), first.Files[2].Rows)
+
+	// The next full export replaces the files, an empty table still has one
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE `id` > ?")).WithArgs(int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note", "total", "photo", "shipped_on"}))
+	_, err = engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+	second, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, second.Files, 1)
+	assert.Equal(t, "id,note,total,photo,shipped_on\r\n", readExportFile(t, export, second.Files[0].Name))
+	assert.Equal(t, int64(0), second.Files[0].Rows)
+
+	entries, err := os.ReadDir(export.dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{exportManifestName, second.Files[0].Name}, names)
+}
+
+func TestExportIncremental_WritesDeltaBetweenCheckpoints(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{})
+	mockRepo := new(MockRepository)
+	engine.repo = mockRepo
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeIncremental, TrackingColumn: "id"}
+	require.NoError(t, writeExportManifest(export.dir, &exportManifest{Table: "orders", Files: []exportManifestFile{{Name: "orders-full-20240301T000000Z-0001.csv", Kind: exportKindFull}}}))
+
+	mockRepo.On("GetCheckpoint", mock.Anything, "mapping-1").Return(&SyncCheckpoint{
+		CheckpointData: `{"mode":"key_watermark","watermark_columns":["id"],"watermark_types":["bigint"],"watermark_values":["1"]}`,
+	}, nil)
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT DATA_TYPE, EXTRA")).WithArgs("orders", "id").
+		WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE", "EXTRA"}).AddRow("bigint", "auto_increment"))
+	expectKeyColumnTypes(sourceMock, "id", "bigint")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `orders` ORDER BY `id` DESC LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow([]byte("3")))
+	expectKeyColumnTypes(sourceMock, "id", "bigint")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE (`id`) > (?) AND (`id`) <= (?) ORDER BY `id`")).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note", "total", "photo", "shipped_on"}).
+			AddRow([]byte("2"), []byte("b"), nil, nil, nil).
+			AddRow([]byte("3"), []byte("c"), nil, nil, nil))
+	mockRepo.On("UpdateCheckpoint", mock.Anything, "mapping-1", mock.MatchedBy(func(c *SyncCheckpoint) bool {
+		return c.LastSyncValue == "3"
+	})).Return(nil)
+
+	exported, err := engine.exportIncremental(context.Background(), export, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), exported)
+
+	// The delta is listed after the full export
+	manifest, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 2)
+	delta := manifest.Files[1]
+	assert.Regexp(t, `^orders-delta-`, delta.Name)
+	assert.Equal(t, exportKindDelta, delta.Kind)
+	assert.Equal(t, "1", delta.WatermarkFrom)
+	assert.Equal(t, "3", delta.WatermarkTo)
+	assert.Equal(t, "id,note,total,photo,shipped_on\r\n2,b,,,\r\n3,c,,,\r\n", readExportFile(t, export, delta.Name))
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestValidateFileConnection(t *testing.T) {
+	cm := &ConnectionManagerService{}
+	dir := t.TempDir()
+
+	assert.NoError(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: ConnectionTypeFile, Path: dir}))
+	assert.NoError(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: ConnectionTypeFile, Path: dir,
+		FileOptions: &FileOptions{Format: FileFormatParquet, Compression: FileCompressionGzip, MaxFileSize: 1 << 30}}))
+	assert.ErrorContains(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: ConnectionTypeFile}), "path is required")
+	assert.ErrorContains(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: ConnectionTypeFile, Path: "exports"}), "must be absolute")
+	assert.ErrorContains(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: ConnectionTypeFile, Path: dir,
+		FileOptions: &FileOptions{Compression: "lz4"}}), "unsupported file compression: lz4")
+	assert.ErrorContains(t, cm.validateConnectionConfig(&ConnectionConfig{Name: "lake", Type: "s3"}), "invalid connection type")
+
+	// File options are stored as JSON
+	options := FileOptions{Format: FileFormatJSONL, MaxFileSize: 1024}
+	value, err := options.Value()
+	require.NoError(t, err)
+	var stored FileOptions
+	require.NoError(t, stored.Scan([]byte(value.(string))))
+	assert.Equal(t, options, stored)
+	data, err := json.Marshal(&ConnectionConfig{Type: ConnectionTypeFile, FileOptions: &options})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"file_options":{"format":"jsonl","max_file_size":1024}`)
+
+	assert.NoError(t, checkExportDirectory(filepath.Join(dir, "nested")))
+}
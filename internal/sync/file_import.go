@@ -0,0 +1,651 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// importQuarantineDir is the directory of a file source the rows it cannot load are written to
+const importQuarantineDir = "_quarantine"
+
+// badRowErrors are the MySQL errors of a row whose values the target table can't hold. Any other
+// error, such as a deadlock, a lock wait timeout, a read-only server or a missing table, says
+// nothing about the row and fails the file instead of quarantining it.
+var badRowErrors = map[uint16]bool{
+	1048: true, // ER_BAD_NULL_ERROR
+	1062: true, // ER_DUP_ENTRY
+	1216: true, // ER_NO_REFERENCED_ROW
+	1264: true, // ER_WARN_DATA_OUT_OF_RANGE
+	1265: true, // WARN_DATA_TRUNCATED
+	1292: true, // ER_TRUNCATED_WRONG_VALUE
+	1364: true, // ER_NO_DEFAULT_FOR_FIELD
+	1366: true, // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+	1406: true, // ER_DATA_TOO_LONG
+	1452: true, // ER_NO_REFERENCED_ROW_2
+	3819: true, // ER_CHECK_CONSTRAINT_VIOLATED
+	4025: true, // ER_CONSTRAINT_FAILED, MariaDB
+}
+
+// badRowError returns the error of a write the target refused for the values of a row, nil for
+// any other error
+func badRowError(err error) *mysql.MySQLError {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && badRowErrors[mysqlErr.Number] {
+		return mysqlErr
+	}
+	return nil
+}
+
+// fileQuoteNone is the quote option of a file source whose CSV files don't quote fields
+const fileQuoteNone = "none"
+
+// validate checks the schema and options of a file source mapping
+func (s *FileSource) validate() error {
+	names := make(map[string]bool, len(s.Columns))
+	for _, col := range s.Columns {
+		if col.Name == "" || strings.Contains(col.Name, "`") {
+			return fmt.Errorf("invalid file column name: %q", col.Name)
+		}
+		if !derivedColumnTypePattern.MatchString(col.Type) {
+			return fmt.Errorf("invalid type of file column %s: %s", col.Name, col.Type)
+		}
+		names[strings.ToLower(col.Name)] = true
+	}
+	for _, name := range s.PrimaryKey {
+		if name == "" || (len(s.Columns) > 0 && !names[strings.ToLower(name)]) {
+			return fmt.Errorf("primary key column %s is not a file column", name)
+		}
+	}
+	if s.MaxBadRows < 0 {
+		return fmt.Errorf("max bad rows cannot be negative")
+	}
+	return nil
+}
+
+// validateFileMapping checks a table mapping can load the files of a file source. Columns are
+// renamed or left out with column rules; the files hold no values to filter or derive columns from.
+func validateFileMapping(mapping *TableMapping) error {
+	if err := validateFilePattern(mapping.SourceTable); err != nil {
+		return err
+	}
+	if mapping.RowFilter != nil || mapping.WhereClause != "" {
+		return fmt.Errorf("row filters are not supported by file sources")
+	}
+	for _, rule := range mapping.ColumnRules {
+		if rule.RuleType == ColumnRuleConstant || rule.RuleType == ColumnRuleExpression {
+			return fmt.Errorf("%s column rules are not supported by file sources", rule.RuleType)
+		}
+	}
+	if mapping.FileSource != nil {
+		return mapping.FileSource.validate()
+	}
+	return nil
+}
+
+// validateFilePattern checks the glob pattern of a file source's files stays inside its directory
+func validateFilePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("file pattern is required")
+	}
+	if filepath.IsAbs(pattern) {
+		return fmt.Errorf("file pattern must be relative to the connection path: %s", pattern)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if part == ".." {
+			return fmt.Errorf("file pattern cannot leave the connection path: %s", pattern)
+		}
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid file pattern %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// sourceFiles returns the files of a directory matching a pattern, relative to the directory and
+// sorted by name. Hidden files and the quarantine directory are left out.
+func sourceFiles(root, pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(root, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid file pattern %s: %w", pattern, err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		rel, err := filepath.Rel(root, match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source files: %w", err)
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if parts[0] == importQuarantineDir || strings.HasPrefix(parts[len(parts)-1], ".") {
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source files: %w", err)
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// importTable loads the files of a file source matching the mapping's source table pattern into the
+// target table, creating it from the mapping's file schema when missing. A full sync empties the
+// table, unless the write mode keeps its rows, and loads every file. An incremental sync loads the
+// files it hasn't loaded before, or whose content changed since. Rows that cannot be read or written
+// go to a quarantine file.
+func (e *DefaultSyncEngine) importTable(ctx context.Context, job *SyncJob, mapping *TableMapping, syncConfig *SyncConfig, source, target *ConnectionConfig) error {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_files": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+		"sync_mode":    mapping.SyncMode,
+		"path":         source.Path,
+	}).Info("Starting file import")
+
+	if err := validateFileMapping(mapping); err != nil {
+		return err
+	}
+	targetDBName := syncConfig.TargetDatabase
+	if targetDBName == "" {
+		targetDBName = target.Database
+	}
+	if targetDBName == "" {
+		return fmt.Errorf("target database is required")
+	}
+
+	files, err := sourceFiles(source.Path, mapping.SourceTable)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if mapping.SyncMode == SyncModeFull {
+			return fmt.Errorf("no files match %s in %s", mapping.SourceTable, source.Path)
+		}
+		e.logger.WithField("source_files", mapping.SourceTable).Info("No files to import")
+		return nil
+	}
+
+	imp := &tableImport{
+		job:       job,
+		mapping:   mapping,
+		root:      source.Path,
+		options:   source.FileOptions.withDefaults(),
+		settings:  FileSource{},
+		batchSize: 1000,
+	}
+	if mapping.FileSource != nil {
+		imp.settings = *mapping.FileSource
+	}
+	if syncConfig.Options != nil && syncConfig.Options.BatchSize > 0 {
+		imp.batchSize = syncConfig.Options.BatchSize
+	}
+
+	fileSchema, err := e.importSchema(imp, files[0])
+	if err != nil {
+		return err
+	}
+	columns := mapping.columnMapping()
+	targetSchema, err := columns.targetSchema(fileSchema)
+	if err != nil {
+		return err
+	}
+	imp.masker, err = newRowMasker(mapping, syncConfig.Options, e.maskingSecret, fileSchema, targetSchema)
+	if err != nil {
+		return err
+	}
+	// Files have no database to detect a tracking column in, upsert_newer compares the one the mapping names
+	trackingType := ""
+	for _, col := range fileSchema.Columns {
+		if mapping.TrackingColumn != "" && strings.EqualFold(col.Name, mapping.TrackingColumn) {
+			switch baseColumnType(col.Type) {
+			case "timestamp", "datetime", "date":
+				trackingType = "timestamp"
+			}
+		}
+	}
+	imp.policy, err = newWritePolicy(mapping, syncConfig.Options, targetSchema, mapping.TrackingColumn, trackingType)
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.ensureTargetDatabase(ctx, target, targetDBName, false); err != nil {
+		return err
+	}
+	cc := *target
+	cc.Database = targetDBName
+	imp.targetDB, err = e.connectToRemoteWithVariables(&cc, targetSessionVariables(syncConfig.Options))
+	if err != nil {
+		return fmt.Errorf("failed to connect to target database: %w", err)
+	}
+	defer imp.targetDB.Close()
+	imp.targetDialect = dialectFor(target)
+	imp.targetDBName = targetDBName
+
+	if err := e.ensureTargetTableExistsInDB(ctx, imp.targetDB, imp.targetDialect, targetDBName, mapping.TargetTable, targetSchema); err != nil {
+		return fmt.Errorf("failed to ensure target table exists: %w", err)
+	}
+	// Values are converted to the types of the table as it is, which may predate the file schema
+	tableSchema, err := e.getTableSchemaFromRemote(ctx, imp.targetDB, mapping.TargetTable)
+	if err != nil {
+		return fmt.Errorf("failed to get target table schema: %w", err)
+	}
+	if err := imp.mapColumns(fileSchema, columns, tableSchema); err != nil {
+		return err
+	}
+
+	loaded, rejected, err := e.importFiles(ctx, imp, files)
+	if err != nil {
+		return err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"job_id":        job.ID,
+		"source_files":  mapping.SourceTable,
+		"target_table":  mapping.TargetTable,
+		"loaded_rows":   loaded,
+		"rejected_rows": rejected,
+	}).Info("File import completed successfully")
+	return nil
+}
+
+// importFiles loads the files of an import in order, recording each file it loads. A full sync
+// empties the target table first unless the write mode keeps its rows, an incremental sync skips the
+// files recorded with the same content.
+func (e *DefaultSyncEngine) importFiles(ctx context.Context, imp *tableImport, files []string) (int64, int64, error) {
+	loadedFiles := make(map[string]bool)
+	switch imp.mapping.SyncMode {
+	case SyncModeFull:
+		if imp.mapping.keepsTargetRows() {
+			break
+		}
+		query := fmt.Sprintf("TRUNCATE TABLE `%s`.`%s`", imp.targetDBName, imp.mapping.TargetTable)
+		if _, err := imp.targetDB.ExecContext(ctx, query); err != nil {
+			return 0, 0, fmt.Errorf("failed to truncate target table: %w", err)
+		}
+	case SyncModeIncremental:
+		imported, err := e.repo.GetImportedFiles(ctx, imp.mapping.ID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get imported files: %w", err)
+		}
+		for _, file := range imported {
+			loadedFiles[file.FileName+"\x00"+file.SHA256] = true
+		}
+	default:
+		return 0, 0, fmt.Errorf("sync mode %s is not supported by file sources", imp.mapping.SyncMode)
+	}
+
+	var loaded, rejected int64
+	for _, name := range files {
+		checksum, size, err := fileChecksum(filepath.Join(imp.root, name))
+		if err != nil {
+			return 0, 0, err
+		}
+		if loadedFiles[name+"\x00"+checksum] {
+			e.logger.WithField("file", name).Debug("File already imported, skipping")
+			continue
+		}
+
+		fileLoaded, fileRejected, err := e.importFile(ctx, imp, name)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to import file %s: %w", name, err)
+		}
+		loaded += fileLoaded
+		rejected += fileRejected
+
+		record := &ImportedFile{
+			TableMappingID: imp.mapping.ID,
+			FileName:       name,
+			FileSize:       size,
+			SHA256:         checksum,
+			LoadedRows:     fileLoaded,
+			RejectedRows:   fileRejected,
+			JobID:          imp.job.ID,
+			ImportedAt:     time.Now(),
+		}
+		if err := e.repo.RecordImportedFile(ctx, record); err != nil {
+			return 0, 0, fmt.Errorf("failed to record imported file: %w", err)
+		}
+		e.logger.WithFields(logrus.Fields{
+			"file":          name,
+			"loaded_rows":   fileLoaded,
+			"rejected_rows": fileRejected,
+		}).Info("File imported")
+	}
+	return loaded, rejected, nil
+}
+
+// tableImport is the import of a file source's files into a table in progress
+type tableImport struct {
+	job           *SyncJob
+	mapping       *TableMapping
+	root          string
+	options       FileOptions
+	settings      FileSource
+	targetDB      *sqlx.DB
+	targetDialect Dialect
+	targetDBName  string
+	masker        *rowMasker
+	policy        *writePolicy
+	batchSize     int
+	columns       map[string]*importColumn // Per lowercased file column
+	order         []*importColumn          // Copied columns in file schema order
+}
+
+// importColumn is a column of the files and the target column it is loaded into
+type importColumn struct {
+	key    string      // Lowercased file column
+	target *ColumnInfo // Nil when column rules leave the column out
+}
+
+// importSchema returns the schema of the files, declared by the mapping or inferred from the
+// first rows of the first file
+func (e *DefaultSyncEngine) importSchema(imp *tableImport, first string) (*TableSchema, error) {
+	schema := &TableSchema{Name: imp.mapping.TargetTable}
+	if len(imp.settings.Columns) > 0 {
+		for _, col := range imp.settings.Columns {
+			schema.Columns = append(schema.Columns, &ColumnInfo{Name: col.Name, Type: col.Type, Nullable: true})
+		}
+	} else {
+		f, err := openSourceFile(filepath.Join(imp.root, first), imp.options)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		reader, err := newImportRecordReader(f, imp.options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", first, err)
+		}
+		if schema.Columns, err = inferFileColumns(reader, importInferenceRows); err != nil {
+			return nil, fmt.Errorf("failed to infer schema from file %s: %w", first, err)
+		}
+		e.logger.WithFields(logrus.Fields{
+			"file":    first,
+			"columns": len(schema.Columns),
+		}).Info("Inferred file schema")
+	}
+
+	if len(imp.settings.PrimaryKey) > 0 {
+		key := &KeyInfo{Name: "PRIMARY", Type: "PRIMARY KEY"}
+		for _, name := range imp.settings.PrimaryKey {
+			col := findColumn(schema.Columns, name)
+			if col == nil {
+				return nil, fmt.Errorf("primary key column %s is not a column of the files", name)
+			}
+			col.Nullable = false
+			key.Columns = append(key.Columns, col.Name)
+		}
+		schema.Keys = append(schema.Keys, key)
+	}
+	return schema, nil
+}
+
+// mapColumns matches the file columns to the columns of the target table
+func (imp *tableImport) mapColumns(fileSchema *TableSchema, columns *columnMapping, table *TableSchema) error {
+	imp.columns = make(map[string]*importColumn, len(fileSchema.Columns))
+	for _, col := range fileSchema.Columns {
+		column := &importColumn{key: strings.ToLower(col.Name)}
+		if name, ok := columns.targetName(col.Name); ok {
+			if column.target = findColumn(table.Columns, name); column.target == nil {
+				return fmt.Errorf("column %s is not in target table %s", name, imp.mapping.TargetTable)
+			}
+			imp.order = append(imp.order, column)
+		}
+		imp.columns[column.key] = column
+	}
+	return nil
+}
+
+// findColumn returns the column of a name, compared case-insensitively
+func findColumn(columns []*ColumnInfo, name string) *ColumnInfo {
+	for _, col := range columns {
+		if strings.EqualFold(col.Name, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// fileChecksum returns the SHA-256 and size of a file
+func fileChecksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read source file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// importFile loads the rows of a file in one transaction, returning how many were loaded and how many
+// quarantined. A file failing part way leaves none of its rows, so loading it again doesn't
+// duplicate those it loaded before.
+func (e *DefaultSyncEngine) importFile(ctx context.Context, imp *tableImport, name string) (int64, int64, error) {
+	f, err := openSourceFile(filepath.Join(imp.root, name), imp.options)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	reader, err := newImportRecordReader(f, imp.options)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// A CSV file loads the columns of its header, a JSON Lines file every column with missing keys NULL
+	columns := imp.order
+	if imp.options.Format != FileFormatJSONL {
+		columns = nil
+		for _, name := range reader.columns() {
+			column, ok := imp.columns[strings.ToLower(name)]
+			if !ok {
+				return 0, 0, fmt.Errorf("column %s is not a column of the file schema", name)
+			}
+			if column.target != nil {
+				columns = append(columns, column)
+			}
+		}
+	}
+	targetColumns := make([]string, len(columns))
+	for i, column := range columns {
+		targetColumns[i] = column.target.Name
+	}
+
+	tx, err := imp.targetDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	quarantine := newImportQuarantine(imp.root, imp.mapping.TargetTable, name)
+	defer quarantine.close()
+	var loaded, rejected int64
+	reject := func(record *importRecord, reason error) error {
+		rejected++
+		if err := quarantine.write(record, reason); err != nil {
+			return err
+		}
+		if imp.settings.MaxBadRows > 0 && rejected > int64(imp.settings.MaxBadRows) {
+			return fmt.Errorf("more than %d bad rows, see %s", imp.settings.MaxBadRows, quarantine.path)
+		}
+		return nil
+	}
+
+	var batch []map[string]interface{}
+	var records []*importRecord
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := imp.masker.maskRows(batch); err != nil {
+			return err
+		}
+		// A failed statement is rolled back on its own, the rows written before it stay in the transaction
+		err := e.writeBatchToDB(ctx, tx, imp.targetDialect, imp.targetDBName, imp.mapping.TargetTable, targetColumns, batch, imp.policy)
+		if err != nil && badRowError(err) == nil {
+			return err
+		}
+		if err == nil {
+			loaded += int64(len(batch))
+		} else {
+			// The server rejected a row of the batch, find it by loading the rows one by one
+			for i := range batch {
+				err := e.writeBatchToDB(ctx, tx, imp.targetDialect, imp.targetDBName, imp.mapping.TargetTable, targetColumns, batch[i:i+1], imp.policy)
+				if err == nil {
+					loaded++
+					continue
+				}
+				rowErr := badRowError(err)
+				if rowErr == nil {
+					return err
+				}
+				if err := reject(records[i], rowErr); err != nil {
+					return err
+				}
+			}
+		}
+		batch, records = batch[:0], records[:0]
+		ReportTableProgress(ctx, imp.mapping.SourceTable, TableStatusRunning, loaded, loaded)
+		return nil
+	}
+
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		row, err := imp.convert(record, columns)
+		if err != nil {
+			if err := reject(record, err); err != nil {
+				return 0, 0, err
+			}
+			continue
+		}
+		batch = append(batch, row)
+		records = append(records, record)
+		if len(batch) >= imp.batchSize {
+			if err := flush(); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit file: %w", err)
+	}
+	return loaded, rejected, nil
+}
+
+// convert converts the values of a row read from a file into a row of the target table
+func (imp *tableImport) convert(record *importRecord, columns []*importColumn) (map[string]interface{}, error) {
+	if record.err != nil {
+		return nil, record.err
+	}
+	for key := range record.values {
+		if _, ok := imp.columns[key]; !ok {
+			return nil, fmt.Errorf("column %s is not a column of the file schema", key)
+		}
+	}
+	row := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		value, err := convertImportValue(column.target, record.values[column.key])
+		if err != nil {
+			return nil, err
+		}
+		row[column.target.Name] = value
+	}
+	return row, nil
+}
+
+// importQuarantine is the quarantine file of a source file, <path>/_quarantine/<target table>/<file>.rejected.jsonl,
+// a JSON object per rejected row with its line, the reason and the row's text. It is only created
+// once a row is rejected, and the one of an earlier import of the file is removed.
+type importQuarantine struct {
+	path string
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newImportQuarantine(root, table, name string) *importQuarantine {
+	q := &importQuarantine{path: filepath.Join(root, importQuarantineDir, table, name+".rejected.jsonl")}
+	os.Remove(q.path)
+	return q
+}
+
+func (q *importQuarantine) write(record *importRecord, reason error) error {
+	if q.file == nil {
+		if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+			return fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		file, err := os.Create(q.path)
+		if err != nil {
+			return fmt.Errorf("failed to create quarantine file: %w", err)
+		}
+		q.file = file
+		q.enc = json.NewEncoder(file)
+		q.enc.SetEscapeHTML(false)
+	}
+	entry := struct {
+		Line   int    `json:"line"`
+		Error  string `json:"error"`
+		Record string `json:"record"`
+	}{record.line, reason.Error(), record.raw}
+	if err := q.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+	return nil
+}
+
+func (q *importQuarantine) close() {
+	if q.file != nil {
+		q.file.Close()
+	}
+}
+
+// Value stores a file source as JSON
+func (s FileSource) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file source: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan reads a file source stored as JSON
+func (s *FileSource) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported file source type: %T", src)
+	}
+	return json.Unmarshal(data, s)
+}
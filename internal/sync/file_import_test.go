@@ -0,0 +1,332 @@
+package sync
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func newTableImport(t *testing.T, mapping *TableMapping, options FileOptions, files map[string]string) (*DefaultSyncEngine, *tableImport, sqlmock.Sqlmock) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	root := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+	imp := &tableImport{
+		job:           &SyncJob{ID: "job-1"},
+		mapping:       mapping,
+		root:          root,
+		options:       options.withDefaults(),
+		targetDB:      targetDB,
+		targetDialect: mysqlDialect{},
+		targetDBName:  "replica",
+		batchSize:     100,
+	}
+	if mapping.FileSource != nil {
+		imp.settings = *mapping.FileSource
+	}
+	require.NoError(t, imp.mapColumns(ordersColumns("id", "note", "paid_at"), mapping.columnMapping(), ordersSchema()))
+	return engine, imp, targetMock
+}
+
+func bufioReader(s string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(s))
+}
+
+func readRecords(t *testing.T, reader importRecordReader) []*importRecord {
+	var records []*importRecord
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestCSVRecordReader(t *testing.T) {
+	input := "ID;Note;Paid_At\r\n" +
+		"1;'multi\r\nline; ''quoted''';2024-03-01 10:00:00\r\n" +
+		"\r\n" +
+		"2;'';\n" +
+		"3;bad'quote;x\n" +
+		"4;'closed'trailing;x\n" +
+		"5;too;many;fields\n" +
+		"6;last;2024-03-02"
+	reader, err := newCSVRecordReader(strings.NewReader(input), FileOptions{Delimiter: ";", Quote: "'"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Note", "Paid_At"}, reader.columns())
+
+	records := readRecords(t, reader)
+	require.Len(t, records, 6)
+	assert.Equal(t, map[string]interface{}{"id": "1", "note": "multi\r\nline; 'quoted'", "paid_at": "2024-03-01 10:00:00"}, records[0].values)
+	assert.Equal(t, 2, records[0].line)
+
+	// An empty quoted field is an empty string, an empty unquoted one NULL
+	assert.Equal(t, map[string]interface{}{"id": "2", "note": "", "paid_at": nil}, records[1].values)
+	assert.Equal(t, 5, records[1].line)
+
+	// Malformed rows are reported with their text and reading goes on with the next line
+	assert.EqualError(t, records[2].err, "quote in an unquoted field")
+	assert.Equal(t, "3;bad'quote;x", records[2].raw)
+	assert.EqualError(t, records[3].err, `unexpected 't' after a quoted field`)
+	assert.EqualError(t, records[4].err, "row has 4 fields, the header 3")
+	assert.Equal(t, map[string]interface{}{"id": "6", "note": "last", "paid_at": "2024-03-02"}, records[5].values)
+	assert.Equal(t, 9, records[5].line)
+
+	// Without quoting, quotes are text
+	reader, err = newCSVRecordReader(strings.NewReader("id,note\n1,\"hi\"\n"), FileOptions{Quote: fileQuoteNone})
+	require.NoError(t, err)
+	records = readRecords(t, reader)
+	require.Len(t, records, 1)
+	assert.Equal(t, `"hi"`, records[0].values["note"])
+}
+
+func TestJSONLRecordReader(t *testing.T) {
+	reader := &jsonlRecordReader{r: bufioReader("{\"Note\":\"hi\",\"id\":1,\"tags\":[1,2]}\n\n{\"id\":2.5,\"paid\":true,\"note\":null}\n[1]\n{\"id\":3,\"ID\":4}\n"), seen: make(map[string]bool)}
+	records := readRecords(t, reader)
+	require.Len(t, records, 4)
+	assert.Equal(t, map[string]interface{}{"note": "hi", "id": json.Number("1"), "tags": json.RawMessage("[1,2]")}, records[0].values)
+	assert.Equal(t, map[string]interface{}{"id": json.Number("2.5"), "paid": true, "note": nil}, records[1].values)
+	assert.Equal(t, 3, records[1].line)
+	assert.EqualError(t, records[2].err, "row is not a JSON object")
+	assert.EqualError(t, records[3].err, "row has key ID more than once")
+
+	// Columns keep the order of their first appearance
+	assert.Equal(t, []string{"Note", "id", "tags", "paid"}, reader.columns())
+}
+
+func TestInferFileColumns(t *testing.T) {
+	input := "id,amount,ratio,day,at,flag,name,empty\n" +
+		"1,10.5,1e3,2024-03-01,2024-03-01 10:00:00,yes,ann,\n" +
+		"-20,3,0.25,2024-03-02,2024-03-02,no,bob,\n"
+	reader, err := newCSVRecordReader(strings.NewReader(input), FileOptions{})
+	require.NoError(t, err)
+	columns, err := inferFileColumns(reader, importInferenceRows)
+	require.NoError(t, err)
+
+	types := make(map[string]string)
+	for _, col := range columns {
+		assert.True(t, col.Nullable)
+		types[col.Name] = col.Type
+	}
+	assert.Equal(t, map[string]string{
+		"id": "bigint", "amount": "decimal(3,1)", "ratio": "double", "day": "date", "at": "datetime",
+		"flag": "varchar(255)", "name": "varchar(255)", "empty": "varchar(255)",
+	}, types)
+
+	reader2 := &jsonlRecordReader{r: bufioReader(`{"ok":true,"doc":{"a":1},"long":"` + strings.Repeat("x", 300) + `"}` + "\n"), seen: make(map[string]bool)}
+	columns, err = inferFileColumns(reader2, importInferenceRows)
+	require.NoError(t, err)
+	assert.Equal(t, []*ColumnInfo{
+		{Name: "ok", Type: "tinyint(1)", Nullable: true},
+		{Name: "doc", Type: "json", Nullable: true},
+		{Name: "long", Type: "text", Nullable: true},
+	}, columns)
+}
+
+func TestConvertImportValue(t *testing.T) {
+	tests := []struct {
+		columnType string
+		value      interface{}
+		want       interface{}
+		err        string
+	}{
+		{"bigint", " 42", int64(42), ""},
+		{"bigint unsigned", "18446744073709551615", uint64(18446744073709551615), ""},
+		{"int", "4x", nil, `invalid value of integer column c: "4x"`},
+		{"tinyint(1)", true, int64(1), ""},
+		{"double", json.Number("1e-3"), 0.001, ""},
+		{"decimal(10,2)", "12.50", "12.50", ""},
+		{"decimal(10,2)", "NaN", nil, `invalid value of decimal column c: "NaN"`},
+		{"date", "2024-03-01T10:00:00Z", "2024-03-01", ""},
+		{"datetime(6)", "2024-03-01T10:00:00.5+02:00", "2024-03-01 08:00:00.5", ""},
+		{"timestamp", "yesterday", nil, `invalid value of timestamp column c: "yesterday"`},
+		{"blob", "/wA=", []byte{0xff, 0x00}, ""},
+		{"json", json.RawMessage(`{"a":1}`), `{"a":1}`, ""},
+		{"int", json.RawMessage(`[1]`), nil, "cannot load a JSON object or array into int column c"},
+		{"varchar(10)", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := convertImportValue(&ColumnInfo{Name: "c", Type: tt.columnType}, tt.value)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.columnType)
+			continue
+		}
+		require.NoError(t, err, tt.columnType)
+		assert.Equal(t, tt.want, got, tt.columnType)
+	}
+}
+
+func TestOpenSourceFile_GzipAndEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.csv.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	zw := gzip.NewWriter(f)
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String("id,note\n1,订单\n")
+	require.NoError(t, err)
+	_, err = zw.Write([]byte(encoded))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	file, err := openSourceFile(path, FileOptions{Encoding: "gbk"})
+	require.NoError(t, err)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "id,note\n1,订单\n", string(content))
+
+	_, err = openSourceFile(path, FileOptions{Encoding: "klingon"})
+	assert.EqualError(t, err, "unsupported file encoding: klingon")
+}
+
+func TestImportFile_QuarantinesBadRows(t *testing.T) {
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "*.csv", TargetTable: "orders", SyncMode: SyncModeFull,
+		ColumnRules: []*ColumnRule{{RuleType: ColumnRuleExclude, SourceColumn: "paid_at"}}}
+	engine, imp, targetMock := newTableImport(t, mapping, FileOptions{}, map[string]string{
+		"orders.csv": "note,id,paid_at\nfirst,1,2024-03-01\nsecond,two,2024-03-01\nthird,3,\nfourth,4,\n",
+	})
+
+	// The server rejects the batch, the rows are loaded one by one to find the one it rejects
+	upsert := regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`note`, `id`) VALUES ")
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(upsert).WithArgs("first", int64(1), "third", int64(3), "fourth", int64(4)).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '3'"})
+	targetMock.ExpectExec(upsert).WithArgs("first", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(upsert).WithArgs("third", int64(3)).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '3'"})
+	targetMock.ExpectExec(upsert).WithArgs("fourth", int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectCommit()
+
+	loaded, rejected, err := engine.importFile(context.Background(), imp, "orders.csv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), loaded)
+	assert.Equal(t, int64(2), rejected)
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(filepath.Join(imp.root, importQuarantineDir, "orders", "orders.csv.rejected.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"line":3,"error":"invalid value of integer column id: \"two\"","record":"second,two,2024-03-01"}`+"\n"+
+		`{"line":4,"error":"Error 1062: Duplicate entry '3'","record":"third,3,"}`+"\n", string(data))
+
+	// Too many bad rows fail the file
+	imp.settings.MaxBadRows = 1
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(upsert).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '3'"})
+	targetMock.ExpectExec(upsert).WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(upsert).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '3'"})
+	targetMock.ExpectRollback()
+	_, _, err = engine.importFile(context.Background(), imp, "orders.csv")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than 1 bad rows")
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	// A column outside the file schema fails the file
+	require.NoError(t, os.WriteFile(filepath.Join(imp.root, "orders.csv"), []byte("id,color\n1,red\n"), 0o644))
+	_, _, err = engine.importFile(context.Background(), imp, "orders.csv")
+	assert.EqualError(t, err, "column color is not a column of the file schema")
+}
+
+func TestImportFile_FailsOnServerErrors(t *testing.T) {
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "*.csv", TargetTable: "orders", SyncMode: SyncModeIncremental, WriteMode: WriteModeAppend}
+	engine, imp, targetMock := newTableImport(t, mapping, FileOptions{}, map[string]string{
+		"orders.csv": "id,note\n1,first\n2,second\n3,third\n",
+	})
+	imp.batchSize = 2
+	insert := regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`, `note`) VALUES ")
+
+	// An error that says nothing about the rows fails the file, and rolls back the rows it loaded so
+	// that loading it again doesn't append them twice
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(insert).WithArgs(int64(1), "first", int64(2), "second").WillReturnResult(sqlmock.NewResult(0, 2))
+	targetMock.ExpectExec(insert).WithArgs(int64(3), "third").
+		WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+	targetMock.ExpectRollback()
+
+	_, _, err := engine.importFile(context.Background(), imp, "orders.csv")
+	assert.ErrorContains(t, err, "Deadlock found")
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+	_, err = os.Stat(filepath.Join(imp.root, importQuarantineDir, "orders", "orders.csv.rejected.jsonl"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, badRowError(&mysql.MySQLError{Number: 1146, Message: "Table 'replica.orders' doesn't exist"}))
+	assert.Nil(t, badRowError(assert.AnError))
+	assert.NotNil(t, badRowError(fmt.Errorf("failed to execute batch write: %w", &mysql.MySQLError{Number: 1406, Message: "Data too long"})))
+}
+
+func TestImportFiles_IncrementalSkipsImportedFiles(t *testing.T) {
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "2024/*.jsonl", TargetTable: "orders", SyncMode: SyncModeIncremental}
+	engine, imp, targetMock := newTableImport(t, mapping, FileOptions{Format: FileFormatJSONL}, map[string]string{
+		"2024/01.jsonl": `{"id":1,"note":"old"}` + "\n",
+		"2024/02.jsonl": `{"id":2,"paid_at":"2024-03-01T10:00:00Z"}` + "\n",
+	})
+	files, err := sourceFiles(imp.root, mapping.SourceTable)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("2024", "01.jsonl"), filepath.Join("2024", "02.jsonl")}, files)
+
+	checksum, _, err := fileChecksum(filepath.Join(imp.root, files[0]))
+	require.NoError(t, err)
+	mockRepo := new(MockRepository)
+	engine.repo = mockRepo
+	mockRepo.On("GetImportedFiles", mock.Anything, "mapping-1").Return([]*ImportedFile{{FileName: files[0], SHA256: checksum}}, nil)
+	mockRepo.On("RecordImportedFile", mock.Anything, mock.MatchedBy(func(file *ImportedFile) bool {
+		return file.FileName == files[1] && file.LoadedRows == 1 && file.JobID == "job-1" && len(file.SHA256) == 64
+	})).Return(nil)
+
+	// A JSON Lines row loads every column, missing keys are NULL
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`, `note`, `paid_at`) VALUES (?, ?, ?)")).
+		WithArgs(int64(2), nil, "2024-03-01 10:00:00").WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectCommit()
+
+	loaded, rejected, err := engine.importFiles(context.Background(), imp, files)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), loaded)
+	assert.Equal(t, int64(0), rejected)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestValidateFileMapping(t *testing.T) {
+	assert.NoError(t, validateFileMapping(&TableMapping{SourceTable: "exports/*.csv.gz",
+		FileSource: &FileSource{Columns: []*FileColumn{{Name: "id", Type: "bigint"}}, PrimaryKey: []string{"ID"}}}))
+
+	tests := []struct {
+		mapping *TableMapping
+		err     string
+	}{
+		{&TableMapping{SourceTable: "/data/*.csv"}, "file pattern must be relative to the connection path"},
+		{&TableMapping{SourceTable: "../other/*.csv"}, "file pattern cannot leave the connection path"},
+		{&TableMapping{SourceTable: "[.csv"}, "invalid file pattern"},
+		{&TableMapping{SourceTable: "*.csv", RowFilter: &RowFilter{Column: "id", Operator: FilterOpEqual, Operand: 1.0}}, "row filters are not supported"},
+		{&TableMapping{SourceTable: "*.csv", ColumnRules: []*ColumnRule{{RuleType: ColumnRuleConstant, TargetColumn: "src", Value: "x"}}}, "constant column rules are not supported"},
+		{&TableMapping{SourceTable: "*.csv", FileSource: &FileSource{Columns: []*FileColumn{{Name: "id", Type: "bigint; DROP"}}}}, "invalid type of file column id"},
+		{&TableMapping{SourceTable: "*.csv", FileSource: &FileSource{Columns: []*FileColumn{{Name: "id", Type: "bigint"}}, PrimaryKey: []string{"code"}}}, "primary key column code is not a file column"},
+	}
+	for _, tt := range tests {
+		err := validateFileMapping(tt.mapping)
+		require.Error(t, err, tt.mapping.SourceTable)
+		assert.Contains(t, err.Error(), tt.err)
+	}
+
+	assert.NoError(t, validateFileConnection(&ConnectionConfig{Type: ConnectionTypeFile, Path: "/data", FileOptions: &FileOptions{Delimiter: "\t", Quote: fileQuoteNone, Encoding: "windows-1252"}}))
+	assert.Error(t, validateFileConnection(&ConnectionConfig{Type: ConnectionTypeFile, Path: "/data", FileOptions: &FileOptions{Delimiter: ";;"}}))
+	assert.Error(t, validateFileConnection(&ConnectionConfig{Type: ConnectionTypeFile, Path: "/data", FileOptions: &FileOptions{Quote: ","}}))
+}
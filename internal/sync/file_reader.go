@@ -0,0 +1,641 @@
+package sync
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// importInferenceRows is how many rows of the first file a file source's schema is inferred from
+const importInferenceRows = 1000
+
+// importRecord is a row read from a source file
+type importRecord struct {
+	line   int                    // Line the row starts on
+	raw    string                 // Text of the row, for the quarantine file
+	values map[string]interface{} // Per lowercased column: nil, a string, or from JSON Lines a bool, json.Number or json.RawMessage
+	err    error                  // Why the row cannot be read, it goes to quarantine
+}
+
+// importRecordReader reads the rows of a source file
+type importRecordReader interface {
+	// columns returns the columns of the file in file order, for JSON Lines those of the rows read so far
+	columns() []string
+	// next returns the next row, io.EOF after the last one
+	next() (*importRecord, error)
+}
+
+// sourceFile is a source file opened for reading
+type sourceFile struct {
+	file *os.File
+	gzip *gzip.Reader
+	zstd *zstd.Decoder
+	io.Reader
+}
+
+// openSourceFile opens a source file, decompressing a file named *.gz or *.zst and decoding it from the
+// connection's encoding. A byte order mark selects the UTF encoding it marks.
+func openSourceFile(path string, options FileOptions) (*sourceFile, error) {
+	decoder, err := sourceDecoder(options.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+
+	f := &sourceFile{file: file, Reader: file}
+	switch name := strings.ToLower(path); {
+	case strings.HasSuffix(name, ".gz"):
+		if f.gzip, err = gzip.NewReader(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read gzip file %s: %w", path, err)
+		}
+		f.Reader = f.gzip
+	case strings.HasSuffix(name, ".zst"):
+		if f.zstd, err = zstd.NewReader(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read zstd file %s: %w", path, err)
+		}
+		f.Reader = f.zstd
+	}
+	f.Reader = transform.NewReader(f.Reader, unicode.BOMOverride(decoder))
+	return f, nil
+}
+
+func (f *sourceFile) Close() error {
+	if f.gzip != nil {
+		f.gzip.Close()
+	}
+	if f.zstd != nil {
+		f.zstd.Close()
+	}
+	return f.file.Close()
+}
+
+// sourceDecoder returns the decoder of a file encoding. UTF-8 is read as it is.
+func sourceDecoder(encoding string) (transform.Transformer, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		return transform.Nop, nil
+	}
+	enc, err := htmlindex.Get(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported file encoding: %s", encoding)
+	}
+	return enc.NewDecoder(), nil
+}
+
+// newImportRecordReader reads a source file in the connection's format
+func newImportRecordReader(r io.Reader, options FileOptions) (importRecordReader, error) {
+	if options.Format == FileFormatJSONL {
+		return &jsonlRecordReader{r: bufio.NewReader(r), seen: make(map[string]bool)}, nil
+	}
+	return newCSVRecordReader(r, options)
+}
+
+// csvField is a field of a CSV row
+type csvField struct {
+	value  string
+	quoted bool
+}
+
+// csvRecordReader reads CSV with a header row naming the columns. A field may be quoted to hold the
+// delimiter, line breaks or, doubled, the quote character. An empty unquoted field is NULL, an empty
+// quoted one an empty string. A malformed row is reported and reading continues on the next line.
+type csvRecordReader struct {
+	r         *bufio.Reader
+	delimiter rune
+	quote     []byte // Empty when quotes are read as text
+	line      int    // Lines read so far
+	header    []string
+	keys      []string // Lowercased header
+}
+
+func newCSVRecordReader(r io.Reader, options FileOptions) (*csvRecordReader, error) {
+	c := &csvRecordReader{r: bufio.NewReaderSize(r, 64*1024), delimiter: ','}
+	if options.Delimiter != "" {
+		c.delimiter, _ = utf8.DecodeRuneInString(options.Delimiter)
+	}
+	switch options.Quote {
+	case "":
+		c.quote = []byte{'"'}
+	case fileQuoteNone:
+	default:
+		c.quote = []byte(options.Quote)
+	}
+
+	// An empty file has no columns and no rows
+	fields, raw, _, syntaxErr, err := c.readFields()
+	if err == io.EOF {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if syntaxErr != nil {
+		return nil, fmt.Errorf("invalid header row %q: %w", raw, syntaxErr)
+	}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		name := strings.TrimSpace(field.value)
+		key := strings.ToLower(name)
+		if name == "" {
+			return nil, fmt.Errorf("header row %q has an empty column name", raw)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("header row has column %s more than once", name)
+		}
+		seen[key] = true
+		c.header = append(c.header, name)
+		c.keys = append(c.keys, key)
+	}
+	return c, nil
+}
+
+func (c *csvRecordReader) columns() []string {
+	return c.header
+}
+
+func (c *csvRecordReader) next() (*importRecord, error) {
+	for {
+		fields, raw, line, syntaxErr, err := c.readFields()
+		if err != nil {
+			return nil, err
+		}
+		if raw == "" {
+			continue
+		}
+
+		record := &importRecord{line: line, raw: raw}
+		switch {
+		case syntaxErr != nil:
+			record.err = syntaxErr
+		case len(fields) != len(c.keys):
+			record.err = fmt.Errorf("row has %d fields, the header %d", len(fields), len(c.keys))
+		default:
+			record.values = make(map[string]interface{}, len(fields))
+			for i, field := range fields {
+				if field.value == "" && !field.quoted {
+					record.values[c.keys[i]] = nil
+				} else {
+					record.values[c.keys[i]] = field.value
+				}
+			}
+		}
+		return record, nil
+	}
+}
+
+// readFields reads the fields of the next row with the row's text, without its line break, and the
+// line it starts on. A syntax error is returned apart from read errors, the row is read to its end.
+func (c *csvRecordReader) readFields() (fields []csvField, raw string, line int, syntaxErr error, err error) {
+	var text, field strings.Builder
+	line = c.line + 1
+	quoted, inQuotes, afterQuotes := false, false, false
+	endField := func() {
+		fields = append(fields, csvField{value: field.String(), quoted: quoted})
+		field.Reset()
+		quoted, afterQuotes = false, false
+	}
+
+	for {
+		r, _, err := c.r.ReadRune()
+		if err == io.EOF {
+			if text.Len() == 0 {
+				return nil, "", 0, nil, io.EOF
+			}
+			if inQuotes && syntaxErr == nil {
+				syntaxErr = fmt.Errorf("quoted field is not closed")
+			}
+			c.line++
+			break
+		}
+		if err != nil {
+			return nil, "", 0, nil, fmt.Errorf("failed to read source file: %w", err)
+		}
+		text.WriteRune(r)
+
+		if inQuotes {
+			if c.isQuote(r) {
+				if next, err := c.r.Peek(len(c.quote)); err == nil && string(next) == string(c.quote) {
+					c.r.Discard(len(c.quote))
+					text.Write(c.quote)
+					field.WriteRune(r)
+					continue
+				}
+				inQuotes, afterQuotes = false, true
+				continue
+			}
+			if r == '\n' {
+				c.line++
+			}
+			field.WriteRune(r)
+			continue
+		}
+
+		if r == '\r' {
+			if next, err := c.r.Peek(1); err == nil && next[0] == '\n' {
+				continue
+			}
+		}
+		if r == '\n' {
+			c.line++
+			break
+		}
+		switch {
+		case r == c.delimiter:
+			endField()
+		case afterQuotes:
+			if syntaxErr == nil {
+				syntaxErr = fmt.Errorf("unexpected %q after a quoted field", r)
+			}
+		case c.isQuote(r) && field.Len() == 0:
+			inQuotes, quoted = true, true
+		case c.isQuote(r):
+			if syntaxErr == nil {
+				syntaxErr = fmt.Errorf("quote in an unquoted field")
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+
+	raw = strings.TrimSuffix(strings.TrimSuffix(text.String(), "\n"), "\r")
+	if raw == "" {
+		return nil, "", line, nil, nil
+	}
+	endField()
+	return fields, raw, line, syntaxErr, nil
+}
+
+func (c *csvRecordReader) isQuote(r rune) bool {
+	if len(c.quote) == 0 {
+		return false
+	}
+	q, _ := utf8.DecodeRune(c.quote)
+	return r == q
+}
+
+// jsonlRecordReader reads a JSON object per line. Keys are matched to columns case-insensitively, a
+// missing key is NULL.
+type jsonlRecordReader struct {
+	r      *bufio.Reader
+	line   int
+	seen   map[string]bool
+	header []string
+}
+
+func (j *jsonlRecordReader) columns() []string {
+	return j.header
+}
+
+func (j *jsonlRecordReader) next() (*importRecord, error) {
+	for {
+		text, err := j.r.ReadString('\n')
+		if err == io.EOF && text == "" {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read source file: %w", err)
+		}
+		j.line++
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		record := &importRecord{line: j.line, raw: text}
+		record.values, record.err = j.parse(text)
+		return record, nil
+	}
+}
+
+// parse reads the keys of a line's object in their order, so the columns of a file keep theirs
+func (j *jsonlRecordReader) parse(text string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("row is not a JSON object")
+	}
+
+	values := make(map[string]interface{})
+	var names []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		name := token.(string)
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		key := strings.ToLower(name)
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("row has key %s more than once", name)
+		}
+		value, err := jsonImportValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+		names = append(names, name)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON object")
+	}
+
+	for _, name := range names {
+		if key := strings.ToLower(name); !j.seen[key] {
+			j.seen[key] = true
+			j.header = append(j.header, name)
+		}
+	}
+	return values, nil
+}
+
+// jsonImportValue returns the value of a JSON value: nil, a string, a bool, a json.Number, or a
+// json.RawMessage holding an object or array
+func jsonImportValue(raw json.RawMessage) (interface{}, error) {
+	switch raw[0] {
+	case 'n':
+		return nil, nil
+	case 't', 'f':
+		return raw[0] == 't', nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("invalid JSON string: %w", err)
+		}
+		return s, nil
+	case '{', '[':
+		return raw, nil
+	}
+	return json.Number(raw), nil
+}
+
+var (
+	importIntegerPattern = regexp.MustCompile(`^[+-]?\d+$`)
+	importDecimalPattern = regexp.MustCompile(`^[+-]?(\d+)(?:\.(\d+))?$`)
+	importNumberPattern  = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+)
+
+// Layouts of the date and time values a file source reads
+var (
+	importDateLayout      = "2006-01-02"
+	importDatetimeLayouts = []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", time.RFC3339Nano, importDateLayout}
+)
+
+// parseImportTime parses a date or date and time, in UTC when it has an offset
+func parseImportTime(s string) (time.Time, bool) {
+	for _, layout := range importDatetimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// columnGuess narrows down the type of a column from its values
+type columnGuess struct {
+	values    int // Non-NULL values seen
+	boolean   bool
+	integer   bool
+	decimal   bool
+	double    bool
+	date      bool
+	datetime  bool
+	json      bool
+	digits    int // Integer digits of the decimal values
+	scale     int // Fraction digits of the decimal values
+	fraction  bool
+	maxLength int
+}
+
+func newColumnGuess() *columnGuess {
+	return &columnGuess{boolean: true, integer: true, decimal: true, double: true, date: true, datetime: true, json: true}
+}
+
+func (g *columnGuess) add(value interface{}) {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		g.values++
+		g.integer, g.decimal, g.double, g.date, g.datetime, g.json = false, false, false, false, false, false
+		g.maxLength = max(g.maxLength, 5)
+		return
+	case json.RawMessage:
+		g.values++
+		g.boolean, g.integer, g.decimal, g.double, g.date, g.datetime = false, false, false, false, false, false
+		g.maxLength = max(g.maxLength, utf8.RuneCount(v))
+		return
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	g.values++
+	g.boolean, g.json = false, false
+	g.maxLength = max(g.maxLength, utf8.RuneCountInString(s))
+	if g.integer {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil || !importIntegerPattern.MatchString(s) {
+			g.integer = false
+		}
+	}
+	if g.decimal {
+		if m := importDecimalPattern.FindStringSubmatch(s); m != nil {
+			g.digits = max(g.digits, len(strings.TrimLeft(m[1], "0")))
+			g.scale = max(g.scale, len(m[2]))
+		} else {
+			g.decimal = false
+		}
+	}
+	if g.double && !importNumberPattern.MatchString(s) {
+		g.double = false
+	}
+	if g.date {
+		if _, err := time.Parse(importDateLayout, s); err != nil {
+			g.date = false
+		}
+	}
+	if g.datetime {
+		if _, ok := parseImportTime(s); ok {
+			g.fraction = g.fraction || strings.Contains(s, ".")
+		} else {
+			g.datetime = false
+		}
+	}
+}
+
+// columnType returns the narrowest type holding every value seen, VARCHAR(255) when there were none
+func (g *columnGuess) columnType() string {
+	switch {
+	case g.values == 0:
+		return "varchar(255)"
+	case g.boolean:
+		return "tinyint(1)"
+	case g.integer:
+		return "bigint"
+	case g.decimal && g.digits+g.scale <= 65 && g.scale <= 30:
+		return fmt.Sprintf("decimal(%d,%d)", max(g.digits+g.scale, 1), g.scale)
+	case g.double:
+		return "double"
+	case g.date:
+		return "date"
+	case g.datetime && g.fraction:
+		return "datetime(6)"
+	case g.datetime:
+		return "datetime"
+	case g.json:
+		return "json"
+	case g.maxLength <= 255:
+		return "varchar(255)"
+	}
+	return "text"
+}
+
+// inferFileColumns infers the columns of a source file from its first rows. Every inferred column
+// is nullable.
+func inferFileColumns(reader importRecordReader, rows int) ([]*ColumnInfo, error) {
+	guesses := make(map[string]*columnGuess)
+	for i := 0; i < rows; i++ {
+		record, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.err != nil {
+			continue
+		}
+		for key, value := range record.values {
+			guess, ok := guesses[key]
+			if !ok {
+				guess = newColumnGuess()
+				guesses[key] = guess
+			}
+			guess.add(value)
+		}
+	}
+
+	var columns []*ColumnInfo
+	for _, name := range reader.columns() {
+		guess, ok := guesses[strings.ToLower(name)]
+		if !ok {
+			guess = newColumnGuess()
+		}
+		columns = append(columns, &ColumnInfo{Name: name, Type: guess.columnType(), Nullable: true})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns found")
+	}
+	return columns, nil
+}
+
+// convertImportValue converts a value read from a file into the value written to a target column
+func convertImportValue(col *ColumnInfo, value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if isIntegerColumnType(col.Type) || strings.HasPrefix(strings.ToLower(col.Type), "bit") {
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		s = strconv.FormatBool(v)
+	case json.RawMessage:
+		switch baseColumnType(col.Type) {
+		case "json", "text", "tinytext", "mediumtext", "longtext", "varchar", "char":
+			return string(v), nil
+		}
+		return nil, fmt.Errorf("cannot load a JSON object or array into %s column %s", col.Type, col.Name)
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	base := baseColumnType(col.Type)
+	switch {
+	case isIntegerColumnType(col.Type):
+		t := strings.TrimSpace(s)
+		if n, err := strconv.ParseInt(t, 10, 64); err == nil {
+			return n, nil
+		}
+		if n, err := strconv.ParseUint(t, 10, 64); err == nil {
+			return n, nil
+		}
+		return nil, fmt.Errorf("invalid value of integer column %s: %q", col.Name, s)
+	case base == "float" || base == "double" || base == "real":
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || !importNumberPattern.MatchString(strings.TrimSpace(s)) {
+			return nil, fmt.Errorf("invalid value of float column %s: %q", col.Name, s)
+		}
+		return n, nil
+	case base == "decimal" || base == "numeric":
+		t := strings.TrimSpace(s)
+		if !importNumberPattern.MatchString(t) {
+			return nil, fmt.Errorf("invalid value of decimal column %s: %q", col.Name, s)
+		}
+		return t, nil
+	case base == "date":
+		t, ok := parseImportTime(strings.TrimSpace(s))
+		if !ok {
+			return nil, fmt.Errorf("invalid value of date column %s: %q", col.Name, s)
+		}
+		return t.Format(importDateLayout), nil
+	case base == "datetime" || base == "timestamp":
+		t, ok := parseImportTime(strings.TrimSpace(s))
+		if !ok {
+			return nil, fmt.Errorf("invalid value of %s column %s: %q", base, col.Name, s)
+		}
+		return t.Format("2006-01-02 15:04:05.999999"), nil
+	case exportKindOf(col.Type) == exportBinary:
+		// Binary values are base64 encoded, as exports write them
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value of binary column %s", col.Name)
+		}
+		return data, nil
+	}
+	return s, nil
+}
+
+// baseColumnType returns the lowercased type name of a column type, without length or attributes
+func baseColumnType(columnType string) string {
+	base := strings.ToLower(columnType)
+	if i := strings.IndexAny(base, "( "); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
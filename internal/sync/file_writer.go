@@ -0,0 +1,399 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// exportKind is how an export column's values are written
+type exportKind int
+
+const (
+	exportText exportKind = iota
+	exportInteger
+	exportFloat
+	exportBinary
+	exportDate
+)
+
+// exportColumn is a column of an export file
+type exportColumn struct {
+	name       string
+	columnType string
+	kind       exportKind
+}
+
+// exportColumns returns the columns of the rows a query reads, typed from the target schema. Columns
+// the schema doesn't know, as a derived column may be, are written as text.
+func exportColumns(names []string, schema *TableSchema) []exportColumn {
+	types := make(map[string]string, len(schema.Columns))
+	for _, col := range schema.Columns {
+		types[strings.ToLower(col.Name)] = col.Type
+	}
+
+	columns := make([]exportColumn, len(names))
+	for i, name := range names {
+		columnType := types[strings.ToLower(name)]
+		columns[i] = exportColumn{name: name, columnType: columnType, kind: exportKindOf(columnType)}
+	}
+	return columns
+}
+
+// exportKindOf returns how values of a column type are written. DECIMAL is text, which keeps its precision.
+func exportKindOf(columnType string) exportKind {
+	base := baseColumnType(columnType)
+	switch {
+	case isIntegerColumnType(columnType):
+		return exportInteger
+	case base == "float" || base == "double" || base == "real":
+		return exportFloat
+	case base == "date":
+		return exportDate
+	case strings.HasSuffix(base, "blob") || strings.HasSuffix(base, "binary") || base == "bit":
+		return exportBinary
+	}
+	return exportText
+}
+
+// isUnsignedBigint reports whether a column is a BIGINT UNSIGNED, whose values may not fit an int64
+func isUnsignedBigint(columnType string) bool {
+	columnType = strings.ToLower(columnType)
+	return strings.Contains(columnType, "bigint") && strings.Contains(columnType, "unsigned")
+}
+
+// normalize converts a value read from MySQL into the value written for the column: nil, int64 or
+// uint64 for an integer, float64 for a float, []byte for a binary column and a string otherwise
+func (c exportColumn) normalize(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch c.kind {
+	case exportInteger:
+		switch v := value.(type) {
+		case int64, uint64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case []byte:
+			if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				return n, nil
+			}
+			n, err := strconv.ParseUint(string(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of integer column %s: %q", c.name, v)
+			}
+			return n, nil
+		}
+	case exportFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case []byte:
+			n, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of float column %s: %q", c.name, v)
+			}
+			return n, nil
+		}
+	case exportBinary:
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	case time.Time:
+		if c.kind == exportDate {
+			return v.Format("2006-01-02"), nil
+		}
+		return v.Format("2006-01-02 15:04:05.999999"), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// rowFileWriter writes the rows of an export file in its format
+type rowFileWriter interface {
+	writeRow(values []interface{}) error
+	close() error
+}
+
+// csvRowWriter writes RFC 4180 CSV with a header row. NULL is an empty field and an empty
+// string a quoted one, binary values are base64 encoded.
+type csvRowWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newCSVRowWriter(w *bufio.Writer, columns []exportColumn) (*csvRowWriter, error) {
+	cw := &csvRowWriter{w: w}
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	if err := cw.writeRow(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvRowWriter) writeRow(values []interface{}) error {
+	cw.buf = cw.buf[:0]
+	for i, value := range values {
+		if i > 0 {
+			cw.buf = append(cw.buf, ',')
+		}
+		var field string
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			field = v
+		case []byte:
+			field = base64.StdEncoding.EncodeToString(v)
+		case int64:
+			field = strconv.FormatInt(v, 10)
+		case uint64:
+			field = strconv.FormatUint(v, 10)
+		case float64:
+			field = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			field = fmt.Sprint(v)
+		}
+		if field == "" || strings.ContainsAny(field, ",\"\r\n") || field[0] == ' ' {
+			cw.buf = append(cw.buf, '"')
+			cw.buf = append(cw.buf, strings.ReplaceAll(field, `"`, `""`)...)
+			cw.buf = append(cw.buf, '"')
+			continue
+		}
+		cw.buf = append(cw.buf, field...)
+	}
+	cw.buf = append(cw.buf, '\r', '\n')
+	_, err := cw.w.Write(cw.buf)
+	return err
+}
+
+func (cw *csvRowWriter) close() error {
+	return nil
+}
+
+// jsonlRowWriter writes a JSON object per line, keys in column order. Numbers are written bare,
+// binary values base64 encoded.
+type jsonlRowWriter struct {
+	w       *bufio.Writer
+	keys    [][]byte
+	buf     bytes.Buffer
+	encoder *json.Encoder
+}
+
+func newJSONLRowWriter(w *bufio.Writer, columns []exportColumn) (*jsonlRowWriter, error) {
+	jw := &jsonlRowWriter{w: w}
+	jw.encoder = json.NewEncoder(&jw.buf)
+	jw.encoder.SetEscapeHTML(false)
+	for _, col := range columns {
+		jw.buf.Reset()
+		if err := jw.encoder.Encode(col.name); err != nil {
+			return nil, err
+		}
+		key := append([]byte{}, bytes.TrimSuffix(jw.buf.Bytes(), []byte("\n"))...)
+		jw.keys = append(jw.keys, append(key, ':'))
+	}
+	return jw, nil
+}
+
+func (jw *jsonlRowWriter) writeRow(values []interface{}) error {
+	jw.buf.Reset()
+	jw.buf.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			jw.buf.WriteByte(',')
+		}
+		jw.buf.Write(jw.keys[i])
+		switch v := value.(type) {
+		case nil:
+			jw.buf.WriteString("null")
+		case int64:
+			jw.buf.WriteString(strconv.FormatInt(v, 10))
+		case uint64:
+			jw.buf.WriteString(strconv.FormatUint(v, 10))
+		case float64:
+			jw.buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		case []byte:
+			jw.buf.WriteString(`"` + base64.StdEncoding.EncodeToString(v) + `"`)
+		default:
+			// Encode ends the value with a newline, dropped below
+			if err := jw.encoder.Encode(v); err != nil {
+				return fmt.Errorf("failed to encode value: %w", err)
+			}
+			jw.buf.Truncate(jw.buf.Len() - 1)
+		}
+	}
+	jw.buf.WriteString("}\n")
+	_, err := jw.w.Write(jw.buf.Bytes())
+	return err
+}
+
+func (jw *jsonlRowWriter) close() error {
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// exportFile is an export file being written. Rows go to a temporary file in the export directory
+// that is renamed once complete, so a reader never sees a partial file under its final name.
+type exportFile struct {
+	name    string
+	path    string
+	tmp     *os.File
+	hash    hashWriter
+	written *countingWriter
+	zip     io.WriteCloser // compresses the rows of a gzip or zstd file
+	buf     *bufio.Writer
+	rows    rowFileWriter
+	count   int64
+}
+
+// hashWriter is the checksum of the bytes of an export file
+type hashWriter interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+// createExportFile starts an export file in a directory
+func createExportFile(dir, name string, options FileOptions, columns []exportColumn) (*exportFile, error) {
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	f := &exportFile{name: name, path: filepath.Join(dir, name), tmp: tmp, hash: sha256.New()}
+	f.written = &countingWriter{w: io.MultiWriter(tmp, f.hash)}
+	var out io.Writer = f.written
+	if options.Format != FileFormatParquet {
+		switch options.Compression {
+		case FileCompressionGzip:
+			f.zip = gzip.NewWriter(out)
+		case FileCompressionZstd:
+			encoder, err := zstd.NewWriter(out)
+			if err != nil {
+				f.abort()
+				return nil, fmt.Errorf("failed to start zstd encoder: %w", err)
+			}
+			f.zip = encoder
+		}
+		if f.zip != nil {
+			out = f.zip
+		}
+	}
+	f.buf = bufio.NewWriterSize(out, 64*1024)
+
+	switch options.Format {
+	case FileFormatJSONL:
+		f.rows, err = newJSONLRowWriter(f.buf, columns)
+	case FileFormatParquet:
+		f.rows, err = newParquetWriter(f.buf, columns, options.Compression)
+	default:
+		f.rows, err = newCSVRowWriter(f.buf, columns)
+	}
+	if err != nil {
+		f.abort()
+		return nil, fmt.Errorf("failed to start export file: %w", err)
+	}
+	return f, nil
+}
+
+func (f *exportFile) writeRow(values []interface{}) error {
+	if err := f.rows.writeRow(values); err != nil {
+		return fmt.Errorf("failed to write export file %s: %w", f.name, err)
+	}
+	f.count++
+	return nil
+}
+
+// size returns the size of the file so far. Rows being compressed, or buffered for the next Parquet
+// row group, are only counted once written.
+func (f *exportFile) size() int64 {
+	if f.zip != nil {
+		return f.written.n
+	}
+	return f.written.n + int64(f.buf.Buffered())
+}
+
+// finish completes the file and moves it to its final name
+func (f *exportFile) finish() error {
+	err := f.rows.close()
+	if err == nil {
+		err = f.buf.Flush()
+	}
+	if err == nil && f.zip != nil {
+		err = f.zip.Close()
+	}
+	if err == nil {
+		err = f.tmp.Sync()
+	}
+	if closeErr := f.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.tmp.Name())
+		return fmt.Errorf("failed to write export file %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// abort removes the unfinished file
+func (f *exportFile) abort() {
+	if f.zip != nil {
+		f.zip.Close()
+	}
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
+
+// manifestEntry describes the finished file for the manifest
+func (f *exportFile) manifestEntry() exportManifestFile {
+	return exportManifestFile{
+		Name:      f.name,
+		Rows:      f.count,
+		Bytes:     f.written.n,
+		SHA256:    hex.EncodeToString(f.hash.Sum(nil)),
+		CreatedAt: time.Now().UTC(),
+	}
+}
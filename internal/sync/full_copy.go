@@ -0,0 +1,280 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// fullCopyPlan describes how a full sync copies a table
+type fullCopyPlan struct {
+	selectList  string           // Source columns read as the target table's columns
+	primaryKeys []string         // Key order of the copy, empty when the table has no primary key
+	keyTypes    []string         // Column types of primaryKeys
+	keyTargets  []string         // Names of primaryKeys in the copied rows
+	masker      *rowMasker       // Masks rows before they are written, nil without masking rules
+	resume      *TableCheckpoint // Last committed chunk of an interrupted copy, nil to start from an empty table
+	checkpoint  bool             // Persist each committed chunk so the copy can be resumed
+	policy      *writePolicy     // Writes rows into a target table that keeps its rows, nil to overwrite rows of a replayed chunk
+	bulk        *bulkLoader      // Loads batches with LOAD DATA LOCAL INFILE, nil to insert them
+	sizer       *batchSizer      // Sizes the batches, nil to copy batches of the configured size
+	dialect     Dialect          // Dialect of the target database
+}
+
+// planFullCopy decides how a full sync copies the source table and whether it resumes an interrupted copy
+func (e *DefaultSyncEngine) planFullCopy(ctx context.Context, sourceDB, targetDB *sqlx.DB, d Dialect, targetDBName string, mapping *TableMapping, schema *TableSchema, resumable bool) (*fullCopyPlan, error) {
+	columns := mapping.columnMapping()
+	plan := &fullCopyPlan{selectList: columns.selectList(schema), dialect: d}
+
+	primaryKeys, err := e.getPrimaryKeyColumns(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		e.logger.WithError(err).WithField("source_table", mapping.SourceTable).
+			Warn("No primary key available, copying the table in a single scan that cannot be resumed")
+		return plan, nil
+	}
+	keyTargets, ok := columns.targetColumns(primaryKeys)
+	if !ok {
+		e.logger.WithField("source_table", mapping.SourceTable).
+			Warn("Column rules exclude a primary key column, copying the table in a single scan that cannot be resumed")
+		return plan, nil
+	}
+	plan.primaryKeys = primaryKeys
+	plan.keyTargets = keyTargets
+	plan.keyTypes = make([]string, len(primaryKeys))
+	for i, key := range primaryKeys {
+		for _, col := range schema.Columns {
+			if col.Name == key {
+				plan.keyTypes[i] = col.Type
+			}
+		}
+	}
+	plan.checkpoint = resumable && e.checkpointManager != nil
+	if !plan.checkpoint {
+		return plan, nil
+	}
+
+	resume := e.loadCopyCheckpoint(ctx, mapping)
+	if resume == nil {
+		return plan, nil
+	}
+	if !sameColumns(resume.KeyColumns, primaryKeys) {
+		e.logger.WithFields(logrus.Fields{
+			"source_table":        mapping.SourceTable,
+			"checkpoint_key":      resume.KeyColumns,
+			"current_primary_key": primaryKeys,
+		}).Warn("Primary key changed since the copy was interrupted, starting over")
+		return plan, nil
+	}
+
+	if resume.TargetTable != "" && resume.TargetTable != mapping.TargetTable {
+		e.logger.WithFields(logrus.Fields{
+			"checkpoint_table": resume.TargetTable,
+			"target_table":     mapping.TargetTable,
+		}).Warn("Interrupted copy was loading another table, starting over")
+		return plan, nil
+	}
+
+	exists, err := d.TableExists(ctx, targetDB, targetDBName, mapping.TargetTable)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		e.logger.WithField("target_table", mapping.TargetTable).Warn("Target table of the interrupted copy is gone, starting over")
+		return plan, nil
+	}
+
+	plan.resume = resume
+	e.logger.WithFields(logrus.Fields{
+		"source_table":   mapping.SourceTable,
+		"processed_rows": resume.ProcessedRows,
+		"batch_number":   resume.BatchNumber,
+	}).Info("Resuming interrupted full table copy")
+
+	return plan, nil
+}
+
+// resumeKey returns the last copied key of the interrupted copy, typed for binding
+func (p *fullCopyPlan) resumeKey() ([]interface{}, error) {
+	values, ok := p.resume.LastProcessedID.([]interface{})
+	if !ok || len(values) != len(p.primaryKeys) {
+		return nil, fmt.Errorf("invalid copy checkpoint key: %v", p.resume.LastProcessedID)
+	}
+
+	key := make([]interface{}, len(values))
+	for i, value := range values {
+		key[i] = bindKeyValue(p.keyTypes[i], fmt.Sprint(value))
+	}
+	return key, nil
+}
+
+// copyTableByKeyset copies a table in primary key order, one chunk per query, so no long running
+// read holds a snapshot on the source and an interrupted copy can continue after its last chunk
+func (e *DefaultSyncEngine) copyTableByKeyset(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, plan *fullCopyPlan, batchSize int, totalRows int64) (int64, error) {
+	var lower []interface{}
+	processedRows := int64(0)
+	batchNumber := 0
+	if plan.resume != nil {
+		key, err := plan.resumeKey()
+		if err != nil {
+			return 0, err
+		}
+		lower = key
+		processedRows = plan.resume.ProcessedRows
+		batchNumber = plan.resume.BatchNumber
+		ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+	}
+
+	// Chunks are read while earlier ones are masked and written, and committed in key order
+	err := e.runRowPipeline(ctx, pipelineStages{
+		read:      e.keysetReader(sourceDB, sourceDBName, mapping, plan, mapping.rowFilter(), lower, batchSize),
+		transform: maskStage(plan.masker),
+		write: func(ctx context.Context, batch *rowBatch) error {
+			if len(batch.rows) == 0 {
+				return nil
+			}
+			if err := e.writeCopyBatch(ctx, targetDB, targetDBName, mapping.TargetTable, plan, batch, true); err != nil {
+				return fmt.Errorf("failed to insert batch: %w", err)
+			}
+
+			processedRows += int64(len(batch.rows))
+			batchNumber++
+			ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+
+			if plan.checkpoint {
+				e.saveCopyCheckpoint(ctx, mapping, &TableCheckpoint{
+					TableName:       mapping.SourceTable,
+					TargetTable:     mapping.TargetTable,
+					KeyColumns:      plan.primaryKeys,
+					LastProcessedID: formatKey(batch.last),
+					ProcessedRows:   processedRows,
+					TotalRows:       totalRows,
+					BatchNumber:     batchNumber,
+				})
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return processedRows, err
+	}
+
+	// The copy is complete, a later full sync starts from an empty table again
+	if plan.checkpoint && batchNumber > 0 {
+		e.saveCopyCheckpoint(ctx, mapping, &TableCheckpoint{
+			TableName:     mapping.SourceTable,
+			TargetTable:   mapping.TargetTable,
+			KeyColumns:    plan.primaryKeys,
+			ProcessedRows: processedRows,
+			TotalRows:     totalRows,
+			BatchNumber:   batchNumber,
+		})
+	}
+
+	return processedRows, nil
+}
+
+// keysetReader returns the read stage of a keyset copy, reading the chunks after the key lower in key
+// order and restricted by filter, one chunk per batch
+func (e *DefaultSyncEngine) keysetReader(sourceDB *sqlx.DB, sourceDBName string, mapping *TableMapping, plan *fullCopyPlan, filter sqlCondition, lower []interface{}, batchSize int) func(ctx context.Context, batch *rowBatch) (bool, error) {
+	keyList := quoteColumns(plan.primaryKeys)
+	return func(ctx context.Context, batch *rowBatch) (bool, error) {
+		size := plan.sizer.next(batchSize)
+		where, args := keyRangeCondition(plan.primaryKeys, keyRange{lower: lower}, filter)
+		query := fmt.Sprintf("SELECT %s FROM `%s`.`%s`%s ORDER BY %s LIMIT %d",
+			plan.selectList, sourceDBName, mapping.SourceTable, where, keyList, size)
+
+		if err := readBatch(ctx, sourceDB, query, args, batch); err != nil || len(batch.rows) == 0 {
+			return false, err
+		}
+
+		// The next chunk starts after the source key, read before a masked key column is rewritten
+		lower = batch.key(plan.keyTargets, plan.keyTypes)
+		batch.last = lower
+		return len(batch.rows) == size, nil
+	}
+}
+
+// writeCopyBatch writes a batch of a full copy. Rows of a keyset chunk replayed after an interruption
+// may already exist in the target and are overwritten, while a scan copies into an emptied table; a
+// target table that keeps its rows resolves them per the write policy.
+func (e *DefaultSyncEngine) writeCopyBatch(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName string, plan *fullCopyPlan, batch *rowBatch, replayable bool) error {
+	columns := batch.columns
+	clause := plan.policy.clause(plan.dialect, columns)
+	modifier, loadable := plan.policy.bulkLoadModifier()
+	if plan.policy == nil && !replayable {
+		// LOAD DATA LOCAL can't fail on a duplicate key like an INSERT does, it ignores the row
+		clause, modifier = "", "IGNORE"
+	}
+
+	return plan.sizer.write(batch.rows, func(rows [][]interface{}) error {
+		return e.loadOrWriteRows(ctx, plan.bulk, targetDB, targetDBName, tableName, columns, rows, modifier, loadable, func() error {
+			return e.writeRowsToDB(ctx, targetDB, plan.dialect, targetDBName, tableName, columns, rows, clause)
+		})
+	})
+}
+
+// loadCopyCheckpoint returns the checkpoint of an interrupted keyset copy of mapping, or nil
+func (e *DefaultSyncEngine) loadCopyCheckpoint(ctx context.Context, mapping *TableMapping) *TableCheckpoint {
+	if e.checkpointManager == nil {
+		return nil
+	}
+
+	// Incremental and CDC checkpoints share the row but carry no copy key,
+	// a finished copy keeps its key but neither the last copied row nor ranges
+	checkpoint, err := e.checkpointManager.LoadTableCheckpoint(ctx, mapping.ID)
+	if err != nil || checkpoint == nil || len(checkpoint.KeyColumns) == 0 ||
+		(checkpoint.LastProcessedID == nil && len(checkpoint.Ranges) == 0) {
+		return nil
+	}
+	// Ranges are bounded on an integer key column, a checkpoint with other bounds can't be resumed
+	if !validCopyRanges(checkpoint.Ranges) {
+		e.logger.WithField("source_table", mapping.SourceTable).Warn("Ignoring copy checkpoint with invalid key ranges")
+		return nil
+	}
+	return checkpoint
+}
+
+// isCopyCheckpoint reports whether a mapping checkpoint was written by a keyset copy rather
+// than by incremental sync, in which case incremental sync has no starting point yet
+func isCopyCheckpoint(checkpoint *SyncCheckpoint) bool {
+	var tableCheckpoint TableCheckpoint
+	if err := json.Unmarshal([]byte(checkpoint.CheckpointData), &tableCheckpoint); err != nil {
+		return false
+	}
+	return len(tableCheckpoint.KeyColumns) > 0
+}
+
+// saveCopyCheckpoint persists the progress of a keyset copy; failures only cost resumability
+func (e *DefaultSyncEngine) saveCopyCheckpoint(ctx context.Context, mapping *TableMapping, checkpoint *TableCheckpoint) {
+	if err := e.checkpointManager.SaveTableCheckpoint(ctx, mapping.ID, checkpoint); err != nil {
+		e.logger.WithError(err).WithFields(logrus.Fields{
+			"source_table": mapping.SourceTable,
+			"batch_number": checkpoint.BatchNumber,
+		}).Warn("Failed to save copy checkpoint")
+	}
+}
+
+// formatKey renders a primary key tuple for a checkpoint
+func formatKey(key []interface{}) []string {
+	values := make([]string, len(key))
+	for i, value := range key {
+		values[i] = formatKeyValue(value)
+	}
+	return values
+}
+
+// sameColumns reports whether two column lists are identical
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordCopyCheckpoints makes mockRepo store table checkpoints and returns the saved ones
+func recordCopyCheckpoints(t *testing.T, mockRepo *MockRepository, mappingID string) *[]TableCheckpoint {
+	var saved []TableCheckpoint
+	mockRepo.On("GetCheckpoint", mock.Anything, mappingID).Return(&SyncCheckpoint{}, nil)
+	mockRepo.On("UpdateCheckpoint", mock.Anything, mappingID, mock.AnythingOfType("*sync.SyncCheckpoint")).
+		Run(func(args mock.Arguments) {
+			var checkpoint TableCheckpoint
+			require.NoError(t, json.Unmarshal([]byte(args.Get(2).(*SyncCheckpoint).CheckpointData), &checkpoint))
+			saved = append(saved, checkpoint)
+		}).
+		Return(nil)
+	return &saved
+}
+
+func TestCopyTableByKeyset_CompositeKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mockRepo := new(MockRepository)
+	engine.checkpointManager = NewCheckpointManager(mockRepo, engine.logger)
+	saved := recordCopyCheckpoints(t, mockRepo, "mapping-1")
+
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "order_items", TargetTable: "order_items"}
+	plan := &fullCopyPlan{
+		selectList:  "*",
+		primaryKeys: []string{"order_id", "line"},
+		keyTypes:    []string{"int", "smallint unsigned"},
+		keyTargets:  []string{"order_id", "line"},
+		checkpoint:  true,
+		dialect:     mysqlDialect{},
+	}
+	columns := []string{"order_id", "line", "qty"}
+
+	// The MySQL text protocol returns keys as bytes, they are bound back as integers
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`order_items` ORDER BY `order_id`, `line` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow([]byte("9"), []byte("1"), []byte("3")).
+			AddRow([]byte("9"), []byte("2"), []byte("1")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`order_items` (`order_id`, `line`, `qty`) VALUES (?, ?, ?), (?, ?, ?) ON DUPLICATE KEY UPDATE")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`order_items` WHERE (`order_id`, `line`) > (?, ?) ORDER BY `order_id`, `line` LIMIT 2")).
+		WithArgs(int64(9), uint64(2)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte("10"), []byte("1"), []byte("5")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`order_items`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := engine.copyTableByKeyset(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, plan, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), processed)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	// One checkpoint per chunk, then one marking the copy complete
+	require.Len(t, *saved, 3)
+	assert.Equal(t, []string{"order_id", "line"}, (*saved)[0].KeyColumns)
+	assert.Equal(t, []interface{}{"9", "2"}, (*saved)[0].LastProcessedID)
+	assert.Equal(t, int64(2), (*saved)[0].ProcessedRows)
+	assert.Equal(t, []interface{}{"10", "1"}, (*saved)[1].LastProcessedID)
+	assert.Equal(t, 2, (*saved)[1].BatchNumber)
+	assert.Nil(t, (*saved)[2].LastProcessedID)
+	assert.Equal(t, int64(3), (*saved)[2].ProcessedRows)
+}
+
+func TestFullCopy_ResumesFromCheckpoint(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mockRepo := new(MockRepository)
+	engine.repo = mockRepo
+	engine.checkpointManager = NewCheckpointManager(mockRepo, engine.logger)
+	ctx := context.Background()
+
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", WhereClause: "status <> 'draft'"}
+	schema := &TableSchema{Name: "orders", Columns: []*ColumnInfo{{Name: "id", Type: "bigint"}, {Name: "status", Type: "varchar(16)"}}}
+	data, err := json.Marshal(&TableCheckpoint{
+		TableName:       "orders",
+		KeyColumns:      []string{"id"},
+		LastProcessedID: []string{"500"},
+		ProcessedRows:   500,
+		TotalRows:       501,
+		BatchNumber:     5,
+	})
+	require.NoError(t, err)
+
+	var saved []TableCheckpoint
+	mockRepo.On("GetCheckpoint", mock.Anything, "mapping-1").Return(&SyncCheckpoint{CheckpointData: string(data)}, nil)
+	mockRepo.On("UpdateCheckpoint", mock.Anything, "mapping-1", mock.AnythingOfType("*sync.SyncCheckpoint")).
+		Run(func(args mock.Arguments) {
+			var checkpoint TableCheckpoint
+			require.NoError(t, json.Unmarshal([]byte(args.Get(2).(*SyncCheckpoint).CheckpointData), &checkpoint))
+			saved = append(saved, checkpoint)
+		}).
+		Return(nil)
+
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES")).WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	plan, err := engine.planFullCopy(ctx, sourceDB, targetDB, mysqlDialect{}, "replica", mapping, schema, true)
+	require.NoError(t, err)
+	require.NotNil(t, plan.resume)
+
+	// The copy continues after the last committed key instead of row zero
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE (`id`) > (?) AND (status <> 'draft') ORDER BY `id` LIMIT 100")).
+		WithArgs(int64(500)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow([]byte("501"), []byte("paid")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders`")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := engine.copyTableByKeyset(ctx, sourceDB, "shop", targetDB, "replica", mapping, plan, 100, 501)
+	require.NoError(t, err)
+	assert.Equal(t, int64(501), processed)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	require.Len(t, saved, 2)
+	assert.Equal(t, []interface{}{"501"}, saved[0].LastProcessedID)
+	assert.Equal(t, 6, saved[0].BatchNumber)
+	assert.Nil(t, saved[1].LastProcessedID)
+
+	// A finished copy leaves incremental sync without a starting point
+	finished, err := json.Marshal(&saved[1])
+	require.NoError(t, err)
+	assert.True(t, isCopyCheckpoint(&SyncCheckpoint{CheckpointData: string(finished)}))
+	assert.False(t, isCopyCheckpoint(&SyncCheckpoint{LastSyncValue: "42"}))
+}
+
+func TestBindKeyValue(t *testing.T) {
+	assert.Equal(t, int64(-7), bindKeyValue("int(11)", "-7"))
+	assert.Equal(t, uint64(18446744073709551615), bindKeyValue("bigint unsigned", "18446744073709551615"))
+	assert.Equal(t, uint64(3), bindKeyValue("UNSIGNED BIGINT", []byte("3")))
+	assert.Equal(t, "0042", bindKeyValue("varchar(8)", []byte("0042")))
+	assert.Equal(t, "7", bindKeyValue("", "7"))
+}
@@ -2,6 +2,9 @@ package sync
 
 import (
 	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // ConnectionManager manages remote database connections
@@ -51,6 +54,12 @@ type SyncManager interface {
 	// StartSync starts a synchronization job
 	StartSync(ctx context.Context, configID string) (*SyncJob, error)
 
+	// StartVerify starts a job verifying the target tables of a sync configuration against their source tables
+	StartVerify(ctx context.Context, configID string, repair VerifyRepair) (*SyncJob, error)
+
+	// GetVerifyReports returns the per-table reports of a verify job
+	GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error)
+
 	// StopSync stops a running synchronization job
 	StopSync(ctx context.Context, jobID string) error
 
@@ -94,6 +103,14 @@ type SyncManager interface {
 	// ReorderTableMappings updates the sync order of table mappings by mapping IDs (order of IDs = execution order)
 	ReorderTableMappings(ctx context.Context, syncConfigID string, mappingIDs []string) error
 
+	// SortTableMappingsByDependencies orders the table mappings of a sync config by the foreign keys
+	// of their source tables, so parent tables load before the tables referencing them
+	SortTableMappingsByDependencies(ctx context.Context, syncConfigID string) (*TableDependencyOrder, error)
+
+	// PlanSync works out what a sync job of the configuration would do to each enabled table mapping,
+	// without writing anything
+	PlanSync(ctx context.Context, configID string) (*SyncPlan, error)
+
 	// GetJobProgress returns the current progress of a sync job
 	// Requirement 5.1: Real-time display of sync progress and status
 	GetJobProgress(ctx context.Context, jobID string) (*JobSummary, error)
@@ -191,6 +208,15 @@ type SyncEngine interface {
 
 	// CreateTargetTable creates target table with source schema
 	CreateTargetTable(ctx context.Context, localDB string, schema *TableSchema) error
+
+	// VerifyTable compares a table with its target table and repairs the target as the job's repair mode asks
+	VerifyTable(ctx context.Context, job *SyncJob, mapping *TableMapping) (*TableVerifyReport, error)
+
+	// SyncSchemaObjects replicates the views, routines, triggers and events selected by the config
+	SyncSchemaObjects(ctx context.Context, job *SyncJob, config *SyncConfig) ([]*SchemaObjectResult, error)
+
+	// PlanTable works out what syncing a table mapping would do, without writing anything
+	PlanTable(ctx context.Context, mapping *TableMapping) (*TablePlan, error)
 }
 
 // MonitoringService provides sync status monitoring and statistics collection
@@ -207,6 +233,12 @@ type MonitoringService interface {
 	// Requirement 5.1: Real-time display of sync progress and status
 	UpdateTableProgress(ctx context.Context, jobID, tableName string, status TableSyncStatus, processedRows, totalRows int64, errorMsg string) error
 
+	// UpdateTableDeletes records the outcome of the delete detection pass of a table sync
+	UpdateTableDeletes(ctx context.Context, jobID, tableName string, mode DeleteDetection, deletedRows int64) error
+
+	// UpdateTableBatchSize records a batch size adaptive batch sizing chose for a table sync
+	UpdateTableBatchSize(ctx context.Context, jobID, tableName string, batchSize int) error
+
 	// GetJobProgress returns the current progress of a sync job
 	// Requirement 5.1: Real-time display of sync progress and status
 	GetJobProgress(ctx context.Context, jobID string) (*JobSummary, error)
@@ -252,7 +284,9 @@ type Repository interface {
 	CreateSyncConfig(ctx context.Context, config *SyncConfig) error
 	GetSyncConfig(ctx context.Context, id string) (*SyncConfig, error)
 	GetSyncConfigs(ctx context.Context, connectionID string) ([]*SyncConfig, error)
+	GetAllSyncConfigs(ctx context.Context) ([]*SyncConfig, error)
 	UpdateSyncConfig(ctx context.Context, id string, config *SyncConfig) error
+	UpdateSyncConfigRunTimes(ctx context.Context, id string, lastRunAt, nextRunAt *time.Time) error
 	DeleteSyncConfig(ctx context.Context, id string) error
 
 	// Table mapping operations
@@ -268,11 +302,19 @@ type Repository interface {
 	GetJobHistory(ctx context.Context, limit, offset int) ([]*JobHistory, error)
 	GetJobsByStatus(ctx context.Context, status JobStatus) ([]*SyncJob, error)
 
+	// Verify report operations
+	CreateVerifyReport(ctx context.Context, report *TableVerifyReport) error
+	GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error)
+
 	// Checkpoint operations
 	CreateCheckpoint(ctx context.Context, checkpoint *SyncCheckpoint) error
 	GetCheckpoint(ctx context.Context, tableMappingID string) (*SyncCheckpoint, error)
 	UpdateCheckpoint(ctx context.Context, tableMappingID string, checkpoint *SyncCheckpoint) error
 
+	// Imported file operations
+	RecordImportedFile(ctx context.Context, file *ImportedFile) error
+	GetImportedFiles(ctx context.Context, tableMappingID string) ([]*ImportedFile, error)
+
 	// Log operations
 	CreateSyncLog(ctx context.Context, log *SyncLog) error
 	GetSyncLogs(ctx context.Context, jobID string) ([]*SyncLog, error)
@@ -284,33 +326,97 @@ type Repository interface {
 	DeleteDatabaseMapping(ctx context.Context, remoteConnectionID string) error
 }
 
+// Dialect is the SQL of a kind of database a sync writes to. MySQL is the dialect of every
+// database connection; a sqlite connection writes SQLite.
+type Dialect interface {
+	// Name returns the name of the dialect, e.g. mysql
+	Name() string
+	// QuoteIdentifier quotes a table or column name
+	QuoteIdentifier(name string) string
+	// TableName returns the name statements refer to a table of a database by
+	TableName(database, table string) string
+	// ColumnType maps the type of a MySQL column to the dialect's
+	ColumnType(col *ColumnInfo) string
+	// CreateTableStatement returns the CREATE TABLE statement of a table with a MySQL schema, followed
+	// by the statements creating the indexes the dialect can't declare in the table, separated by ";\n"
+	CreateTableStatement(database, table string, schema *TableSchema) string
+	// InsertStatement returns a multi-row INSERT of a batch of rows with its arguments
+	InsertStatement(database, table string, columns []string, batch []map[string]interface{}) (string, []interface{})
+	// InsertRowsStatement returns a multi-row INSERT of rows holding their values in column order with its arguments
+	InsertRowsStatement(database, table string, columns []string, rows [][]interface{}) (string, []interface{})
+	// MaxInsertRows returns how many rows of columns one INSERT can hold, 0 without a limit
+	MaxInsertRows(columns int) int
+	// MaxStatementSize returns the bytes a statement with its arguments can take, 0 without a limit
+	MaxStatementSize(ctx context.Context, db *sqlx.DB) (int64, error)
+	// UpsertClause returns the clause making an INSERT overwrite the non-key columns of rows with the same key
+	UpsertClause(columns, keyColumns []string) string
+	// UpdateClause returns the clause making an INSERT overwrite the given non-key columns of rows with
+	// the same key. With a newer column, a row is only overwritten when its value of that column in the
+	// INSERT isn't older than the one stored.
+	UpdateClause(keyColumns, updateColumns []string, newerColumn string) string
+	// SkipDuplicatesClause returns the clause making an INSERT keep rows with the same key as they are
+	SkipDuplicatesClause(keyColumns []string) string
+	// TableExists reports whether a table exists
+	TableExists(ctx context.Context, db *sqlx.DB, database, table string) (bool, error)
+	// TableColumns returns the columns of a table, typed as the database reports them
+	TableColumns(ctx context.Context, db *sqlx.DB, database, table string) ([]*ColumnInfo, error)
+}
+
 // TableSchema represents database table schema information
 type TableSchema struct {
-	Name           string        `json:"name"`
-	Columns        []*ColumnInfo `json:"columns"`
-	Indexes        []*IndexInfo  `json:"indexes"`
-	Keys           []*KeyInfo    `json:"keys"`
-	TableCharset   string        `json:"table_charset,omitempty"`   // e.g. utf8mb4, from source table
-	TableCollation string        `json:"table_collation,omitempty"` // e.g. utf8mb4_unicode_ci
+	Name           string             `json:"name"`
+	Columns        []*ColumnInfo      `json:"columns"`
+	Indexes        []*IndexInfo       `json:"indexes"`
+	Keys           []*KeyInfo         `json:"keys"`
+	TableCharset   string             `json:"table_charset,omitempty"`   // e.g. utf8mb4, from source table
+	TableCollation string             `json:"table_collation,omitempty"` // e.g. utf8mb4_unicode_ci
+	Engine         string             `json:"engine,omitempty"`
+	RowFormat      string             `json:"row_format,omitempty"` // As declared, empty for the engine default
+	Comment        string             `json:"comment,omitempty"`
+	ForeignKeys    []*ForeignKeyInfo  `json:"foreign_keys,omitempty"`
+	Checks         []*CheckConstraint `json:"checks,omitempty"`
+	Partitioning   string             `json:"partitioning,omitempty"` // PARTITION BY clause as SHOW CREATE TABLE prints it
 }
 
 // ColumnInfo represents table column information
 type ColumnInfo struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Nullable     bool   `json:"nullable"`
-	DefaultValue string `json:"default_value,omitempty"`
-	Extra        string `json:"extra,omitempty"`
-	CharacterSet string `json:"character_set,omitempty"` // for string columns, from source
-	Collation    string `json:"collation,omitempty"`     // for string columns, from source
+	Name                 string `json:"name"`
+	Type                 string `json:"type"`
+	Nullable             bool   `json:"nullable"`
+	DefaultValue         string `json:"default_value,omitempty"`
+	Extra                string `json:"extra,omitempty"`
+	CharacterSet         string `json:"character_set,omitempty"`         // for string columns, from source
+	Collation            string `json:"collation,omitempty"`             // for string columns, from source
+	GenerationExpression string `json:"generation_expression,omitempty"` // for generated columns, Extra tells VIRTUAL or STORED
+	Comment              string `json:"comment,omitempty"`
 }
 
 // IndexInfo represents table index information
 type IndexInfo struct {
 	Name    string   `json:"name"`
-	Columns []string `json:"columns"`
+	Columns []string `json:"columns"` // Empty for key parts on an expression
 	Unique  bool     `json:"unique"`
 	Type    string   `json:"type"`
+	Parts   []string `json:"parts,omitempty"` // Key parts as SHOW CREATE TABLE prints them, with prefix lengths, expressions and order
+	Comment string   `json:"comment,omitempty"`
+}
+
+// ForeignKeyInfo represents a foreign key of a table
+type ForeignKeyInfo struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema,omitempty"` // Only set for a table of another database
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// CheckConstraint represents a CHECK constraint of a table
+type CheckConstraint struct {
+	Name        string `json:"name"`
+	Expression  string `json:"expression"`
+	NotEnforced bool   `json:"not_enforced,omitempty"`
 }
 
 // KeyInfo represents table key information
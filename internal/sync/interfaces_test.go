@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 // TestConnectionManagerInterface tests that ConnectionManager interface is properly defined
@@ -509,6 +510,22 @@ func (m *mockSyncManager) StartSync(ctx context.Context, configID string) (*Sync
 	return nil, mockError("StartSync")
 }
 
+func (m *mockSyncManager) SortTableMappingsByDependencies(ctx context.Context, syncConfigID string) (*TableDependencyOrder, error) {
+	return nil, mockError("SortTableMappingsByDependencies")
+}
+
+func (m *mockSyncManager) PlanSync(ctx context.Context, configID string) (*SyncPlan, error) {
+	return nil, mockError("PlanSync")
+}
+
+func (m *mockSyncManager) StartVerify(ctx context.Context, configID string, repair VerifyRepair) (*SyncJob, error) {
+	return nil, mockError("StartVerify")
+}
+
+func (m *mockSyncManager) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	return nil, mockError("GetVerifyReports")
+}
+
 func (m *mockSyncManager) StopSync(ctx context.Context, jobID string) error {
 	return mockError("StopSync")
 }
@@ -659,6 +676,18 @@ func (m *mockSyncEngine) SyncIncremental(ctx context.Context, job *SyncJob, mapp
 	return mockError("SyncIncremental")
 }
 
+func (m *mockSyncEngine) VerifyTable(ctx context.Context, job *SyncJob, mapping *TableMapping) (*TableVerifyReport, error) {
+	return nil, mockError("VerifyTable")
+}
+
+func (m *mockSyncEngine) SyncSchemaObjects(ctx context.Context, job *SyncJob, config *SyncConfig) ([]*SchemaObjectResult, error) {
+	return nil, mockError("SyncSchemaObjects")
+}
+
+func (m *mockSyncEngine) PlanTable(ctx context.Context, mapping *TableMapping) (*TablePlan, error) {
+	return nil, mockError("PlanTable")
+}
+
 func (m *mockSyncEngine) ValidateData(ctx context.Context, mapping *TableMapping) error {
 	return mockError("ValidateData")
 }
@@ -705,10 +734,18 @@ func (m *mockRepository) GetSyncConfigs(ctx context.Context, connectionID string
 	return nil, mockError("GetSyncConfigs")
 }
 
+func (m *mockRepository) GetAllSyncConfigs(ctx context.Context) ([]*SyncConfig, error) {
+	return nil, mockError("GetAllSyncConfigs")
+}
+
 func (m *mockRepository) UpdateSyncConfig(ctx context.Context, id string, config *SyncConfig) error {
 	return mockError("UpdateSyncConfig")
 }
 
+func (m *mockRepository) UpdateSyncConfigRunTimes(ctx context.Context, id string, lastRunAt, nextRunAt *time.Time) error {
+	return mockError("UpdateSyncConfigRunTimes")
+}
+
 func (m *mockRepository) DeleteSyncConfig(ctx context.Context, id string) error {
 	return mockError("DeleteSyncConfig")
 }
@@ -749,6 +786,22 @@ func (m *mockRepository) GetJobsByStatus(ctx context.Context, status JobStatus)
 	return nil, mockError("GetJobsByStatus")
 }
 
+func (m *mockRepository) CreateVerifyReport(ctx context.Context, report *TableVerifyReport) error {
+	return mockError("CreateVerifyReport")
+}
+
+func (m *mockRepository) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	return nil, mockError("GetVerifyReports")
+}
+
+func (m *mockRepository) RecordImportedFile(ctx context.Context, file *ImportedFile) error {
+	return mockError("RecordImportedFile")
+}
+
+func (m *mockRepository) GetImportedFiles(ctx context.Context, tableMappingID string) ([]*ImportedFile, error) {
+	return nil, mockError("GetImportedFiles")
+}
+
 func (m *mockRepository) CreateCheckpoint(ctx context.Context, checkpoint *SyncCheckpoint) error {
 	return mockError("CreateCheckpoint")
 }
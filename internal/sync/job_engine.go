@@ -2,7 +2,9 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -460,7 +462,11 @@ func (w *JobWorker) processJob(job *SyncJob) {
 				}).Error("Job execution panicked")
 			}
 		}()
-		err = w.executeJob(ctx, job)
+		if job.Type == JobTypeVerify {
+			err = w.executeVerifyJob(ctx, job)
+		} else {
+			err = w.executeJob(ctx, job)
+		}
 	}()
 
 	// Update final job status
@@ -519,14 +525,19 @@ func (w *JobWorker) executeJob(ctx context.Context, job *SyncJob) error {
 	}
 
 	// Update job with total tables count
-	enabledTables := 0
+	var tables []*TableMapping
 	for _, table := range syncConfig.Tables {
-		if table.Enabled {
-			enabledTables++
+		if !table.Enabled {
+			w.logger.WithFields(logrus.Fields{
+				"job_id":       job.ID,
+				"source_table": table.SourceTable,
+			}).Debug("Skipping disabled table")
+			continue
 		}
+		tables = append(tables, table)
 	}
 
-	job.TotalTables = enabledTables
+	job.TotalTables = len(tables)
 	job.CompletedTables = 0
 	job.TotalRows = 0
 	job.ProcessedRows = 0
@@ -535,102 +546,360 @@ func (w *JobWorker) executeJob(ctx context.Context, job *SyncJob) error {
 		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job table counts")
 	}
 
-	// Process each enabled table
-	for _, tableMapping := range syncConfig.Tables {
-		if !tableMapping.Enabled {
-			w.logger.WithFields(logrus.Fields{
-				"job_id":       job.ID,
-				"source_table": tableMapping.SourceTable,
-			}).Debug("Skipping disabled table")
-			continue
+	// Tables of equal sort order sync concurrently up to the config's max concurrency, groups of
+	// tables run one after another in sort order
+	concurrency := 1
+	if syncConfig.Options != nil && syncConfig.Options.MaxConcurrency > 1 {
+		concurrency = syncConfig.Options.MaxConcurrency
+	}
+	pool := NewWorkerPool(concurrency)
+	var progressMutex sync.Mutex
+
+	for _, group := range groupTablesBySortOrder(tables) {
+		if err := w.syncTableGroup(ctx, job, syncConfig, group, pool, &progressMutex); err != nil {
+			return err
+		}
+	}
+
+	// Views, routines, triggers and events are created once the tables they use are loaded
+	if syncConfig.Options != nil && len(syncConfig.Options.SchemaObjects) > 0 {
+		if err := w.syncSchemaObjects(ctx, job, syncConfig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncSchemaObjects replicates the schema objects of a config and logs the outcome of each object.
+// Like a failed table, a failed object only stops the job when the config stops on errors.
+func (w *JobWorker) syncSchemaObjects(ctx context.Context, job *SyncJob, syncConfig *SyncConfig) error {
+	if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, "", "info", "Starting schema objects sync"); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log schema objects sync start")
+	}
+
+	results, err := w.engine.syncEngine.SyncSchemaObjects(ctx, job, syncConfig)
+	failed := 0
+	for _, result := range results {
+		level := "info"
+		switch result.Status {
+		case SchemaObjectFailed:
+			level = "error"
+			failed++
+		case SchemaObjectSkipped:
+			level = "warn"
+		}
+		if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, "", level, schemaObjectMessage(result)); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log schema object result")
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("schema objects sync failed: %w", err)
+	} else if failed > 0 {
+		err = fmt.Errorf("schema objects sync failed for %d objects", failed)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if logErr := w.engine.monitoring.LogJobEvent(ctx, job.ID, "", "error", err.Error()); logErr != nil {
+		w.logger.WithError(logErr).WithField("job_id", job.ID).Warn("Failed to log schema objects error")
+	}
+	if syncConfig.Options.ConflictResolution == ConflictResolutionError {
+		return err
+	}
+	w.logger.WithError(err).WithField("job_id", job.ID).Warn("Schema objects sync failed")
+	return nil
+}
+
+// schemaObjectMessage describes the outcome of replicating a schema object for the job log
+func schemaObjectMessage(result *SchemaObjectResult) string {
+	message := fmt.Sprintf("%s %s %s", strings.ToUpper(string(result.Type[:1]))+string(result.Type[1:]), result.Name, result.Status)
+	if result.Error != "" {
+		message += ": " + result.Error
+	}
+	return message
+}
+
+// groupTablesBySortOrder splits tables ordered by sort order into runs of equal sort order
+func groupTablesBySortOrder(tables []*TableMapping) [][]*TableMapping {
+	var groups [][]*TableMapping
+	for i, table := range tables {
+		if i == 0 || table.SortOrder != tables[i-1].SortOrder {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], table)
+	}
+	return groups
+}
+
+// syncTableGroup syncs a group of tables concurrently, each table taking a slot of the pool. An
+// error that stops the job cancels the tables of the group still running and is returned once
+// all of them have ended.
+func (w *JobWorker) syncTableGroup(ctx context.Context, job *SyncJob, syncConfig *SyncConfig, group []*TableMapping, pool *WorkerPool, progressMutex *sync.Mutex) error {
+	groupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var stopErr error
+	stop := func(err error) {
+		errMutex.Lock()
+		defer errMutex.Unlock()
+		if stopErr == nil {
+			stopErr = err
+			cancel()
+		}
+	}
+
+	for _, tableMapping := range group {
+		wg.Add(1)
+		go func(tableMapping *TableMapping) {
+			defer wg.Done()
+			pool.Acquire()
+			defer pool.Release()
+
+			defer func() {
+				if r := recover(); r != nil {
+					w.logger.WithFields(logrus.Fields{
+						"job_id":       job.ID,
+						"source_table": tableMapping.SourceTable,
+						"panic":        r,
+					}).Error("Table sync panicked")
+					stop(fmt.Errorf("table sync panicked for %s: %v", tableMapping.SourceTable, r))
+				}
+			}()
+
+			// Key range copies of the table take their extra workers from the same pool
+			if err := w.syncJobTable(withJobWorkerPool(groupCtx, pool), job, syncConfig, tableMapping, progressMutex); err != nil {
+				stop(err)
+			}
+		}(tableMapping)
+	}
+	wg.Wait()
+
+	// A cancelled job reports the cancellation rather than the tables it interrupted
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return stopErr
+}
+
+// syncJobTable syncs one table of a job and records its outcome. It returns an error only when the
+// job has to stop: when the job is cancelled, when the table violates a masking requirement, or
+// when the config stops on the first error.
+func (w *JobWorker) syncJobTable(ctx context.Context, job *SyncJob, syncConfig *SyncConfig, tableMapping *TableMapping, progressMutex *sync.Mutex) error {
+	// Check for cancellation
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	// Log table sync start
+	if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "info",
+		fmt.Sprintf("Starting sync for table %s", tableMapping.SourceTable)); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table sync start")
+	}
+
+	// Update table progress to running
+	if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
+		TableStatusRunning, 0, 0, ""); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
+	}
+
+	// 注入表进度 reporter，供 sync 引擎在同步过程中上报当前表行级进度（供 SSE 推送给前端）
+	tableCtx := WithTableProgressReporter(ctx, func(tableName string, status TableSyncStatus, processed, total int64) {
+		_ = w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableName, status, processed, total, "")
+	})
+	tableCtx = WithTableDeleteReporter(tableCtx, func(tableName string, mode DeleteDetection, deletedRows int64) {
+		_ = w.engine.monitoring.UpdateTableDeletes(ctx, job.ID, tableName, mode, deletedRows)
+		if deletedRows > 0 {
+			if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableName, "info", deleteDetectionMessage(mode, deletedRows)); err != nil {
+				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table deletes")
+			}
+		}
+	})
+	tableCtx = WithTableBatchSizeReporter(tableCtx, func(tableName string, batchSize int) {
+		_ = w.engine.monitoring.UpdateTableBatchSize(ctx, job.ID, tableName, batchSize)
+	})
+	tableCtx = WithTableSchemaChangeReporter(tableCtx, func(tableName, statement string, applied bool) {
+		level := "info"
+		if !applied {
+			level = "warn"
+		}
+		if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableName, level, schemaChangeMessage(statement, applied)); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log schema change")
 		}
+	})
 
-		// Check for cancellation
+	// Sync the table using sync engine
+	tableErr := w.engine.syncEngine.SyncTable(tableCtx, job, tableMapping)
+
+	if tableErr != nil {
+		// Handle table sync error based on sync options
+		errorMsg := fmt.Sprintf("Table sync failed: %v", tableErr)
+
+		// Log table error
+		if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "error", errorMsg); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table error")
+		}
+
+		// Update table progress to failed
+		if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
+			TableStatusFailed, 0, 0, errorMsg); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
+		}
+
+		// A table interrupted by a cancelled job or a failed table of its group doesn't count as synced
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Check error handling strategy; a violated masking requirement or a missing masking secret
+		// always stops the job
+		if errors.Is(tableErr, errMaskingRequired) || errors.Is(tableErr, errMaskingSecretRequired) ||
+			(syncConfig.Options != nil && syncConfig.Options.ConflictResolution == ConflictResolutionError) {
+			// Stop on first error
+			return fmt.Errorf("table sync failed for %s: %w", tableMapping.SourceTable, tableErr)
+		}
+
+		// Continue with other tables (skip or overwrite strategy)
+		w.logger.WithError(tableErr).WithFields(logrus.Fields{
+			"job_id":       job.ID,
+			"source_table": tableMapping.SourceTable,
+		}).Warn("Table sync failed, continuing with other tables")
+	} else {
+		// Table sync successful
+		if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "info",
+			fmt.Sprintf("Table sync completed successfully for %s", tableMapping.SourceTable)); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table success")
+		}
+
+		// Update table progress to completed
+		if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
+			TableStatusCompleted, 0, 0, ""); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
+		}
+	}
+
+	// Update job progress; tables of a group finish concurrently
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+
+	job.CompletedTables++
+	if err := w.engine.repo.UpdateSyncJob(ctx, job.ID, job); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job progress")
+	}
+
+	// Update monitoring progress
+	progress := &Progress{
+		TotalTables:     job.TotalTables,
+		CompletedTables: job.CompletedTables,
+		TotalRows:       job.TotalRows,
+		ProcessedRows:   job.ProcessedRows,
+	}
+	if job.TotalRows > 0 {
+		progress.Percentage = float64(job.ProcessedRows) / float64(job.TotalRows) * 100
+	} else {
+		progress.Percentage = float64(job.CompletedTables) / float64(job.TotalTables) * 100
+	}
+
+	if err := w.engine.monitoring.UpdateJobProgress(ctx, job.ID, progress); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job progress")
+	}
+
+	return nil
+}
+
+// executeVerifyJob verifies each enabled table of the config against its target table. Differences
+// don't fail the job; they are recorded in the per-table verify reports.
+func (w *JobWorker) executeVerifyJob(ctx context.Context, job *SyncJob) error {
+	syncConfig, err := w.engine.repo.GetSyncConfig(ctx, job.ConfigID)
+	if err != nil {
+		return fmt.Errorf("failed to get sync config: %w", err)
+	}
+
+	var tables []*TableMapping
+	for _, table := range syncConfig.Tables {
+		if table.Enabled {
+			tables = append(tables, table)
+		}
+	}
+
+	job.TotalTables = len(tables)
+	job.CompletedTables = 0
+	if err := w.engine.repo.UpdateSyncJob(ctx, job.ID, job); err != nil {
+		w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job table counts")
+	}
+
+	for _, tableMapping := range tables {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
 
-		// Log table sync start
 		if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "info",
-			fmt.Sprintf("Starting sync for table %s", tableMapping.SourceTable)); err != nil {
-			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table sync start")
+			fmt.Sprintf("Starting verification for table %s", tableMapping.SourceTable)); err != nil {
+			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table verify start")
 		}
-
-		// Update table progress to running
 		if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
 			TableStatusRunning, 0, 0, ""); err != nil {
 			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
 		}
 
-		// 注入表进度 reporter，供 sync 引擎在同步过程中上报当前表行级进度（供 SSE 推送给前端）
-		ctx = WithTableProgressReporter(ctx, func(tableName string, status TableSyncStatus, processed, total int64) {
+		tableCtx := WithTableProgressReporter(ctx, func(tableName string, status TableSyncStatus, processed, total int64) {
 			_ = w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableName, status, processed, total, "")
 		})
-
-		// Sync the table using sync engine
-		tableErr := w.engine.syncEngine.SyncTable(ctx, job, tableMapping)
+		report, tableErr := w.engine.syncEngine.VerifyTable(tableCtx, job, tableMapping)
+		if report != nil {
+			if err := w.engine.repo.CreateVerifyReport(ctx, report); err != nil {
+				w.logger.WithError(err).WithField("job_id", job.ID).Error("Failed to save verify report")
+			}
+		}
 
 		if tableErr != nil {
-			// Handle table sync error based on sync options
-			errorMsg := fmt.Sprintf("Table sync failed: %v", tableErr)
-
-			// Log table error
+			errorMsg := fmt.Sprintf("Table verification failed: %v", tableErr)
 			if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "error", errorMsg); err != nil {
 				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table error")
 			}
-
-			// Update table progress to failed
 			if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
 				TableStatusFailed, 0, 0, errorMsg); err != nil {
 				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
 			}
 
-			// Check error handling strategy
 			if syncConfig.Options != nil && syncConfig.Options.ConflictResolution == ConflictResolutionError {
-				// Stop on first error
-				return fmt.Errorf("table sync failed for %s: %w", tableMapping.SourceTable, tableErr)
+				return fmt.Errorf("table verification failed for %s: %w", tableMapping.SourceTable, tableErr)
 			}
-
-			// Continue with other tables (skip or overwrite strategy)
 			w.logger.WithError(tableErr).WithFields(logrus.Fields{
 				"job_id":       job.ID,
 				"source_table": tableMapping.SourceTable,
-			}).Warn("Table sync failed, continuing with other tables")
+			}).Warn("Table verification failed, continuing with other tables")
 		} else {
-			// Table sync successful
-			if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, "info",
-				fmt.Sprintf("Table sync completed successfully for %s", tableMapping.SourceTable)); err != nil {
-				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table success")
+			level := "info"
+			if report.Status == VerifyStatusMismatch {
+				level = "warn"
+			}
+			if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableMapping.SourceTable, level, verifyReportMessage(report)); err != nil {
+				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table verify result")
 			}
-
-			// Update table progress to completed
 			if err := w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableMapping.SourceTable,
-				TableStatusCompleted, 0, 0, ""); err != nil {
+				TableStatusCompleted, report.SourceRows, report.SourceRows, ""); err != nil {
 				w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update table progress")
 			}
 		}
 
-		// Update job progress
 		job.CompletedTables++
 		if err := w.engine.repo.UpdateSyncJob(ctx, job.ID, job); err != nil {
 			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job progress")
 		}
-
-		// Update monitoring progress
 		progress := &Progress{
 			TotalTables:     job.TotalTables,
 			CompletedTables: job.CompletedTables,
-			TotalRows:       job.TotalRows,
-			ProcessedRows:   job.ProcessedRows,
-		}
-		if job.TotalRows > 0 {
-			progress.Percentage = float64(job.ProcessedRows) / float64(job.TotalRows) * 100
-		} else {
-			progress.Percentage = float64(job.CompletedTables) / float64(job.TotalTables) * 100
+			Percentage:      float64(job.CompletedTables) / float64(job.TotalTables) * 100,
 		}
-
 		if err := w.engine.monitoring.UpdateJobProgress(ctx, job.ID, progress); err != nil {
 			w.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to update job progress")
 		}
@@ -639,6 +908,19 @@ func (w *JobWorker) executeJob(ctx context.Context, job *SyncJob) error {
 	return nil
 }
 
+// verifyReportMessage describes the outcome of a table verification for the job log
+func verifyReportMessage(report *TableVerifyReport) string {
+	if report.Status == VerifyStatusMatch {
+		return fmt.Sprintf("Table %s matches its target (%d rows in %d chunks)", report.SourceTable, report.SourceRows, report.Chunks)
+	}
+	message := fmt.Sprintf("Table %s differs from its target: %d missing, %d extra, %d different rows",
+		report.SourceTable, report.MissingRows, report.ExtraRows, report.DifferentRows)
+	if report.Status == VerifyStatusRepaired {
+		message += fmt.Sprintf(", %d rows repaired", report.RepairedRows)
+	}
+	return message
+}
+
 // SetWorkerCount updates the number of concurrent workers
 func (je *JobEngineService) SetWorkerCount(count int) error {
 	je.mutex.Lock()
@@ -136,6 +136,26 @@ func (w *JobWorker) executeJobWithRecovery(ctx context.Context, job *SyncJob) er
 		ctx = WithTableProgressReporter(ctx, func(tableName string, status TableSyncStatus, processed, total int64) {
 			_ = w.engine.monitoring.UpdateTableProgress(ctx, job.ID, tableName, status, processed, total, "")
 		})
+		ctx = WithTableDeleteReporter(ctx, func(tableName string, mode DeleteDetection, deletedRows int64) {
+			_ = w.engine.monitoring.UpdateTableDeletes(ctx, job.ID, tableName, mode, deletedRows)
+			if deletedRows > 0 {
+				if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableName, "info", deleteDetectionMessage(mode, deletedRows)); err != nil {
+					w.engine.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log table deletes")
+				}
+			}
+		})
+		ctx = WithTableBatchSizeReporter(ctx, func(tableName string, batchSize int) {
+			_ = w.engine.monitoring.UpdateTableBatchSize(ctx, job.ID, tableName, batchSize)
+		})
+		ctx = WithTableSchemaChangeReporter(ctx, func(tableName, statement string, applied bool) {
+			level := "info"
+			if !applied {
+				level = "warn"
+			}
+			if err := w.engine.monitoring.LogJobEvent(ctx, job.ID, tableName, level, schemaChangeMessage(statement, applied)); err != nil {
+				w.engine.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log schema change")
+			}
+		})
 
 		// Sync the table with retry logic
 		var tableErr error
@@ -26,6 +26,16 @@ func (m *MockMonitoringService) UpdateTableProgress(ctx context.Context, jobID,
 	return args.Error(0)
 }
 
+func (m *MockMonitoringService) UpdateTableDeletes(ctx context.Context, jobID, tableName string, mode DeleteDetection, deletedRows int64) error {
+	args := m.Called(ctx, jobID, tableName, mode, deletedRows)
+	return args.Error(0)
+}
+
+func (m *MockMonitoringService) UpdateTableBatchSize(ctx context.Context, jobID, tableName string, batchSize int) error {
+	args := m.Called(ctx, jobID, tableName, batchSize)
+	return args.Error(0)
+}
+
 func (m *MockMonitoringService) GetJobProgress(ctx context.Context, jobID string) (*JobSummary, error) {
 	args := m.Called(ctx, jobID)
 	return args.Get(0).(*JobSummary), args.Error(1)
@@ -86,6 +96,30 @@ func (m *MockSyncEngine) SyncIncremental(ctx context.Context, job *SyncJob, mapp
 	return args.Error(0)
 }
 
+func (m *MockSyncEngine) VerifyTable(ctx context.Context, job *SyncJob, mapping *TableMapping) (*TableVerifyReport, error) {
+	args := m.Called(ctx, job, mapping)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TableVerifyReport), args.Error(1)
+}
+
+func (m *MockSyncEngine) SyncSchemaObjects(ctx context.Context, job *SyncJob, config *SyncConfig) ([]*SchemaObjectResult, error) {
+	args := m.Called(ctx, job, config)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*SchemaObjectResult), args.Error(1)
+}
+
+func (m *MockSyncEngine) PlanTable(ctx context.Context, mapping *TableMapping) (*TablePlan, error) {
+	args := m.Called(ctx, mapping)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TablePlan), args.Error(1)
+}
+
 func (m *MockSyncEngine) ValidateData(ctx context.Context, mapping *TableMapping) error {
 	args := m.Called(ctx, mapping)
 	return args.Error(0)
@@ -2,6 +2,8 @@ package sync
 
 import (
 	"context"
+	"errors"
+	gosync "sync"
 	"testing"
 	"time"
 
@@ -204,3 +206,113 @@ func TestJobEngine_SetWorkerCount(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot change worker count while engine is running")
 }
+
+// groupSyncEngine records which tables sync at the same time
+type groupSyncEngine struct {
+	*MockSyncEngine
+	mu         gosync.Mutex
+	running    int
+	maxRunning int
+	finished   []string
+	startedAt  map[string]int // Tables finished when a table started
+	fail       map[string]error
+	block      map[string]bool
+	starting   *gosync.WaitGroup // Failing tables wait for the tables starting with them
+}
+
+func (e *groupSyncEngine) SyncTable(ctx context.Context, job *SyncJob, mapping *TableMapping) error {
+	e.mu.Lock()
+	e.running++
+	if e.running > e.maxRunning {
+		e.maxRunning = e.running
+	}
+	e.startedAt[mapping.SourceTable] = len(e.finished)
+	e.mu.Unlock()
+	if e.starting != nil {
+		e.starting.Done()
+	}
+
+	err := e.fail[mapping.SourceTable]
+	if err != nil && e.starting != nil {
+		e.starting.Wait()
+	}
+	if e.block[mapping.SourceTable] {
+		<-ctx.Done()
+		err = ctx.Err()
+	} else if err == nil {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	e.mu.Lock()
+	e.running--
+	e.finished = append(e.finished, mapping.SourceTable)
+	e.mu.Unlock()
+	return err
+}
+
+func TestJobWorker_ExecuteJob_SortOrderGroups(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockMonitoring := &MockMonitoringService{}
+	syncEngine := &groupSyncEngine{MockSyncEngine: &MockSyncEngine{}, startedAt: make(map[string]int)}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	engine := NewJobEngine(mockRepo, logger, mockMonitoring, syncEngine).(*JobEngineService)
+	worker := &JobWorker{engine: engine, logger: logger}
+
+	tables := []*TableMapping{
+		{ID: "m1", SourceTable: "users", Enabled: true},
+		{ID: "m2", SourceTable: "products", Enabled: true},
+		{ID: "m3", SourceTable: "logs"},
+		{ID: "m4", SourceTable: "categories", Enabled: true},
+		{ID: "m5", SourceTable: "orders", Enabled: true, SortOrder: 1},
+	}
+	job := &SyncJob{ID: "job-1", ConfigID: "config-1"}
+	ctx := context.Background()
+
+	mockRepo.On("GetSyncConfig", ctx, "config-1").Return(&SyncConfig{ID: "config-1", Enabled: true, Tables: tables,
+		Options: &SyncOptions{MaxConcurrency: 2}}, nil)
+	mockRepo.On("UpdateSyncJob", mock.Anything, "job-1", job).Return(nil)
+	mockMonitoring.On("LogJobEvent", mock.Anything, "job-1", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockMonitoring.On("UpdateTableProgress", mock.Anything, "job-1", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockMonitoring.On("UpdateJobProgress", mock.Anything, "job-1", mock.Anything).Return(nil)
+
+	// The first group syncs two tables at a time, orders waits for all of it
+	require.NoError(t, worker.executeJob(ctx, job))
+	assert.Equal(t, 4, job.TotalTables)
+	assert.Equal(t, 4, job.CompletedTables)
+	assert.Equal(t, 2, syncEngine.maxRunning)
+	assert.Equal(t, 3, syncEngine.startedAt["orders"])
+	assert.Equal(t, "orders", syncEngine.finished[3])
+	assert.NotContains(t, syncEngine.startedAt, "logs")
+
+	// Stopping on errors cancels the rest of the group and skips later groups
+	syncEngine.maxRunning, syncEngine.finished, syncEngine.startedAt = 0, nil, make(map[string]int)
+	syncEngine.fail = map[string]error{"products": errors.New("table products doesn't exist")}
+	syncEngine.block = map[string]bool{"users": true}
+	syncEngine.starting = &gosync.WaitGroup{}
+	syncEngine.starting.Add(2)
+	job = &SyncJob{ID: "job-1", ConfigID: "config-1"}
+	mockRepo.ExpectedCalls = nil
+	mockRepo.On("GetSyncConfig", ctx, "config-1").Return(&SyncConfig{ID: "config-1", Enabled: true, Tables: []*TableMapping{tables[0], tables[1], tables[4]},
+		Options: &SyncOptions{MaxConcurrency: 2, ConflictResolution: ConflictResolutionError}}, nil)
+	mockRepo.On("UpdateSyncJob", mock.Anything, "job-1", job).Return(nil)
+
+	err := worker.executeJob(ctx, job)
+	assert.ErrorContains(t, err, "table sync failed for products")
+	assert.Equal(t, 0, job.CompletedTables)
+	assert.ElementsMatch(t, []string{"users", "products"}, syncEngine.finished)
+	assert.NotContains(t, syncEngine.startedAt, "orders")
+	mockMonitoring.AssertCalled(t, "UpdateTableProgress", mock.Anything, "job-1", "users", TableStatusFailed,
+		int64(0), int64(0), "Table sync failed: context canceled")
+}
+
+func TestGroupTablesBySortOrder(t *testing.T) {
+	a := &TableMapping{SourceTable: "a"}
+	b := &TableMapping{SourceTable: "b"}
+	c := &TableMapping{SourceTable: "c", SortOrder: 2}
+	d := &TableMapping{SourceTable: "d", SortOrder: 3}
+
+	assert.Equal(t, [][]*TableMapping{{a, b}, {c}, {d}}, groupTablesBySortOrder([]*TableMapping{a, b, c, d}))
+	assert.Nil(t, groupTablesBySortOrder(nil))
+}
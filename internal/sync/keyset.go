@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyRange is the half-open key range (lower, upper]; nil bounds are unbounded
+type keyRange struct {
+	lower []interface{}
+	upper []interface{}
+}
+
+// keyRangeCondition builds the WHERE clause selecting a primary key range plus an optional filter.
+// Composite keys are compared as row constructors, which MySQL resolves with an index range scan.
+func keyRangeCondition(primaryKeys []string, r keyRange, filter sqlCondition) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if r.lower != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s) > (%s)", quoteColumns(primaryKeys), keyPlaceholders(len(primaryKeys))))
+		args = append(args, r.lower...)
+	}
+	if r.upper != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s) <= (%s)", quoteColumns(primaryKeys), keyPlaceholders(len(primaryKeys))))
+		args = append(args, r.upper...)
+	}
+	if filter.clause != "" {
+		conditions = append(conditions, "("+filter.clause+")")
+		args = append(args, filter.args...)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// quoteColumns returns a comma separated list of quoted column names
+func quoteColumns(columns []string) string {
+	return "`" + strings.Join(columns, "`, `") + "`"
+}
+
+// keyPlaceholders returns n comma separated placeholders
+func keyPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// formatKeyValue renders a primary key value for a checkpoint
+func formatKeyValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// bindKeyValue converts a primary key value read from MySQL, or restored from a checkpoint, into a
+// bind argument that compares under the column's type and collation. Integers are bound as integers
+// because MySQL compares an integer column with a string as floating point; everything else is bound
+// as text because []byte arguments compare as binary strings.
+func bindKeyValue(columnType string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, int64, uint64:
+		return v
+	}
+
+	text := formatKeyValue(value)
+	if isIntegerColumnType(columnType) {
+		if strings.Contains(strings.ToLower(columnType), "unsigned") {
+			if n, err := strconv.ParseUint(text, 10, 64); err == nil {
+				return n
+			}
+		} else if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	}
+	return text
+}
+
+// isIntegerColumnType reports whether a column type, as in a TableSchema or as reported by
+// the driver (e.g. "int(10) unsigned", "UNSIGNED BIGINT"), is an integer type
+func isIntegerColumnType(columnType string) bool {
+	fields := strings.FieldsFunc(strings.ToLower(columnType), func(r rune) bool { return r == ' ' || r == '(' })
+	for _, field := range fields {
+		switch field {
+		case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+			return true
+		}
+	}
+	return false
+}
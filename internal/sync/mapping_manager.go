@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -16,6 +17,8 @@ type MappingManagerImpl struct {
 	db     *sqlx.DB
 	repo   Repository
 	logger *logrus.Logger
+
+	allowRawWhereClause bool // Whether imported mappings may select rows with a raw SQL where clause
 }
 
 // NewMappingManager creates a new MappingManager instance
@@ -206,11 +209,12 @@ func (m *MappingManagerImpl) ImportConfig(ctx context.Context, config *ConfigExp
 		conn.ID = uuid.New().String()
 		conn.CreatedAt = time.Now()
 		conn.UpdatedAt = time.Now()
+		conn.Type = connectionType(conn)
 
 		// Use transaction directly for connection creation
 		query := `
-			INSERT INTO connections (id, name, host, port, username, password, database_name, ` + "`ssl`" + `)
-			VALUES (:id, :name, :host, :port, :username, :password, :database, :ssl)
+			INSERT INTO connections (id, name, connection_type, host, port, username, password, database_name, ` + "`ssl`" + `, path, file_options)
+			VALUES (:id, :name, :connection_type, :host, :port, :username, :password, :database, :ssl, :path, :file_options)
 		`
 		_, err = tx.NamedExecContext(ctx, query, conn)
 		if err != nil {
@@ -250,13 +254,22 @@ func (m *MappingManagerImpl) ImportConfig(ctx context.Context, config *ConfigExp
 		syncConfig.CreatedAt = time.Now()
 		syncConfig.UpdatedAt = time.Now()
 
+		// Options carry settings such as the masking requirements, which must survive the import
+		var optionsJSON []byte
+		if syncConfig.Options != nil {
+			optionsJSON, err = json.Marshal(syncConfig.Options)
+			if err != nil {
+				return fmt.Errorf("failed to marshal options of sync config '%s': %w", syncConfig.Name, err)
+			}
+		}
+
 		// Use transaction directly for sync config creation
 		query := `
 			INSERT INTO sync_configs (id, source_connection_id, target_connection_id, name, sync_mode, schedule, enabled, options)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		_, err = tx.ExecContext(ctx, query, syncConfig.ID, syncConfig.SourceConnectionID, syncConfig.TargetConnectionID, syncConfig.Name,
-			syncConfig.SyncMode, syncConfig.Schedule, syncConfig.Enabled, nil) // Simplified options for now
+			syncConfig.SyncMode, syncConfig.Schedule, syncConfig.Enabled, optionsJSON)
 		if err != nil {
 			return fmt.Errorf("failed to import sync config '%s': %w", syncConfig.Name, err)
 		}
@@ -270,16 +283,52 @@ func (m *MappingManagerImpl) ImportConfig(ctx context.Context, config *ConfigExp
 
 			// Use transaction directly for table mapping creation
 			query := `
-				INSERT INTO table_mappings (id, sync_config_id, source_table, target_table, sync_mode, enabled, where_clause, sort_order)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				INSERT INTO table_mappings (id, sync_config_id, source_table, target_table, sync_mode, enabled, where_clause, row_filter, sort_order,
+				                            delete_detection, soft_delete_column, schema_policy, approve_schema_changes,
+				                            tracking_column, lookback_seconds, write_mode, update_columns, file_source)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			`
 			sortOrder := tableMapping.SortOrder
 			_, err = tx.ExecContext(ctx, query, tableMapping.ID, tableMapping.SyncConfigID,
 				tableMapping.SourceTable, tableMapping.TargetTable, tableMapping.SyncMode,
-				tableMapping.Enabled, tableMapping.WhereClause, sortOrder)
+				tableMapping.Enabled, tableMapping.WhereClause, tableMapping.RowFilter, sortOrder,
+				tableMapping.DeleteDetection, tableMapping.SoftDeleteColumn,
+				tableMapping.SchemaPolicy, tableMapping.ApproveSchemaChanges,
+				tableMapping.TrackingColumn, tableMapping.LookbackSeconds, tableMapping.WriteMode, tableMapping.UpdateColumns,
+				tableMapping.FileSource)
 			if err != nil {
 				return fmt.Errorf("failed to import table mapping '%s': %w", tableMapping.SourceTable, err)
 			}
+
+			for i, rule := range tableMapping.ColumnRules {
+				rule.ID = uuid.New().String()
+				rule.TableMappingID = tableMapping.ID
+				rule.SortOrder = i
+				query := `
+					INSERT INTO column_mapping_rules (id, table_mapping_id, rule_type, source_column, target_column, value, column_type, sort_order)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				`
+				_, err = tx.ExecContext(ctx, query, rule.ID, rule.TableMappingID, rule.RuleType,
+					rule.SourceColumn, rule.TargetColumn, rule.Value, rule.ColumnType, rule.SortOrder)
+				if err != nil {
+					return fmt.Errorf("failed to import column rule for table '%s': %w", tableMapping.SourceTable, err)
+				}
+			}
+
+			for i, rule := range tableMapping.MaskingRules {
+				rule.ID = uuid.New().String()
+				rule.TableMappingID = tableMapping.ID
+				rule.SortOrder = i
+				query := `
+					INSERT INTO column_masking_rules (id, table_mapping_id, column_name, strategy, replacement, keep_first, keep_last, shift_days, sort_order)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				`
+				_, err = tx.ExecContext(ctx, query, rule.ID, rule.TableMappingID, rule.Column, rule.Strategy,
+					rule.Replacement, rule.KeepFirst, rule.KeepLast, rule.ShiftDays, rule.SortOrder)
+				if err != nil {
+					return fmt.Errorf("failed to import masking rule for table '%s': %w", tableMapping.SourceTable, err)
+				}
+			}
 		}
 	}
 
@@ -315,16 +364,22 @@ func (m *MappingManagerImpl) ValidateConfig(ctx context.Context, config *ConfigE
 		if conn.Name == "" {
 			return fmt.Errorf("connection name is required")
 		}
-		if conn.Host == "" {
+		switch {
+		case conn.isFile():
+			if err := validateFileConnection(conn); err != nil {
+				return fmt.Errorf("invalid file connection '%s': %w", conn.Name, err)
+			}
+		case conn.isSQLite():
+			if err := validateSQLiteConnection(conn); err != nil {
+				return fmt.Errorf("invalid sqlite connection '%s': %w", conn.Name, err)
+			}
+		case conn.Host == "":
 			return fmt.Errorf("connection host is required for connection '%s'", conn.Name)
-		}
-		if conn.Port <= 0 || conn.Port > 65535 {
+		case conn.Port <= 0 || conn.Port > 65535:
 			return fmt.Errorf("invalid port %d for connection '%s'", conn.Port, conn.Name)
-		}
-		if conn.Username == "" {
+		case conn.Username == "":
 			return fmt.Errorf("connection username is required for connection '%s'", conn.Name)
-		}
-		if conn.Database == "" {
+		case conn.Database == "":
 			return fmt.Errorf("connection database is required for connection '%s'", conn.Name)
 		}
 
@@ -370,9 +425,12 @@ func (m *MappingManagerImpl) ValidateConfig(ctx context.Context, config *ConfigE
 		syncConfigIDs[syncConfig.ID] = true
 
 		// Validate sync mode
-		if syncConfig.SyncMode != SyncModeFull && syncConfig.SyncMode != SyncModeIncremental {
+		if !isValidSyncMode(syncConfig.SyncMode) {
 			return fmt.Errorf("invalid sync mode '%s' for sync config '%s'", syncConfig.SyncMode, syncConfig.Name)
 		}
+		if err := validateRequireMasking(syncConfig.Options); err != nil {
+			return fmt.Errorf("invalid options for sync config '%s': %w", syncConfig.Name, err)
+		}
 
 		// Validate table mappings
 		tableNames := make(map[string]bool)
@@ -392,10 +450,46 @@ func (m *MappingManagerImpl) ValidateConfig(ctx context.Context, config *ConfigE
 			tableNames[tableMapping.SourceTable] = true
 
 			// Validate table sync mode
-			if tableMapping.SyncMode != SyncModeFull && tableMapping.SyncMode != SyncModeIncremental {
+			if !isValidSyncMode(tableMapping.SyncMode) {
 				return fmt.Errorf("invalid sync mode '%s' for table '%s' in sync config '%s'",
 					tableMapping.SyncMode, tableMapping.SourceTable, syncConfig.Name)
 			}
+
+			// Validate delete detection
+			if !isValidDeleteDetection(tableMapping.DeleteDetection) {
+				return fmt.Errorf("invalid delete detection '%s' for table '%s' in sync config '%s'",
+					tableMapping.DeleteDetection, tableMapping.SourceTable, syncConfig.Name)
+			}
+
+			// Validate schema policy
+			if !isValidSchemaPolicy(tableMapping.SchemaPolicy) {
+				return fmt.Errorf("invalid schema policy '%s' for table '%s' in sync config '%s'",
+					tableMapping.SchemaPolicy, tableMapping.SourceTable, syncConfig.Name)
+			}
+
+			// Validate change tracking
+			if err := validateChangeTracking(tableMapping); err != nil {
+				return fmt.Errorf("invalid change tracking for table '%s' in sync config '%s': %w",
+					tableMapping.SourceTable, syncConfig.Name, err)
+			}
+
+			// Validate row filter
+			if err := validateRowSelection(tableMapping, m.allowRawWhereClause); err != nil {
+				return fmt.Errorf("invalid row filter for table '%s' in sync config '%s': %w",
+					tableMapping.SourceTable, syncConfig.Name, err)
+			}
+
+			// Validate column rules
+			if err := validateColumnRules(tableMapping.ColumnRules); err != nil {
+				return fmt.Errorf("invalid column rules for table '%s' in sync config '%s': %w",
+					tableMapping.SourceTable, syncConfig.Name, err)
+			}
+
+			// Validate masking rules
+			if err := validateMaskingRules(tableMapping.MaskingRules); err != nil {
+				return fmt.Errorf("invalid masking rules for table '%s' in sync config '%s': %w",
+					tableMapping.SourceTable, syncConfig.Name, err)
+			}
 		}
 	}
 
@@ -59,11 +59,21 @@ func (m *MockRepository) GetSyncConfigs(ctx context.Context, connectionID string
 	return args.Get(0).([]*SyncConfig), args.Error(1)
 }
 
+func (m *MockRepository) GetAllSyncConfigs(ctx context.Context) ([]*SyncConfig, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*SyncConfig), args.Error(1)
+}
+
 func (m *MockRepository) UpdateSyncConfig(ctx context.Context, id string, config *SyncConfig) error {
 	args := m.Called(ctx, id, config)
 	return args.Error(0)
 }
 
+func (m *MockRepository) UpdateSyncConfigRunTimes(ctx context.Context, id string, lastRunAt, nextRunAt *time.Time) error {
+	args := m.Called(ctx, id, lastRunAt, nextRunAt)
+	return args.Error(0)
+}
+
 func (m *MockRepository) DeleteSyncConfig(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -114,6 +124,26 @@ func (m *MockRepository) GetJobsByStatus(ctx context.Context, status JobStatus)
 	return args.Get(0).([]*SyncJob), args.Error(1)
 }
 
+func (m *MockRepository) CreateVerifyReport(ctx context.Context, report *TableVerifyReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	args := m.Called(ctx, jobID)
+	return args.Get(0).([]*TableVerifyReport), args.Error(1)
+}
+
+func (m *MockRepository) RecordImportedFile(ctx context.Context, file *ImportedFile) error {
+	args := m.Called(ctx, file)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetImportedFiles(ctx context.Context, tableMappingID string) ([]*ImportedFile, error) {
+	args := m.Called(ctx, tableMappingID)
+	return args.Get(0).([]*ImportedFile), args.Error(1)
+}
+
 func (m *MockRepository) CreateCheckpoint(ctx context.Context, checkpoint *SyncCheckpoint) error {
 	args := m.Called(ctx, checkpoint)
 	return args.Error(0)
@@ -0,0 +1,433 @@
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errMaskingRequired is returned when a column the sync config requires to be masked has no masking rule
+var errMaskingRequired = errors.New("column requires a masking rule")
+
+// errMaskingSecretRequired is returned when a mapping masks columns with hash, fake or date_shift while
+// the server has no masking secret configured
+var errMaskingSecretRequired = errors.New("masking secret is not configured")
+
+const (
+	defaultMaskingReplacement = "***"
+	defaultMaskingKeepFirst   = 3
+	defaultMaskingKeepLast    = 4
+	defaultMaskingShiftDays   = 30
+)
+
+// columnLengthPattern extracts the length of character column types
+var columnLengthPattern = regexp.MustCompile(`^(?i)(var)?char\((\d+)\)`)
+
+// integerTypeMax is the largest value of each integer column type, which hashes of the column stay below
+var integerTypeMax = map[string]uint64{
+	"tinyint":   1<<7 - 1,
+	"smallint":  1<<15 - 1,
+	"mediumint": 1<<23 - 1,
+	"int":       1<<31 - 1,
+	"integer":   1<<31 - 1,
+	"bigint":    1<<63 - 1,
+}
+
+// maskingHashLength is the length of the hex hash the hash strategy writes to character columns
+const maskingHashLength = 2 * sha256.Size
+
+// maskingTextTypes are the text column types long enough for a full hash
+var maskingTextTypes = map[string]bool{"tinytext": true, "text": true, "mediumtext": true, "longtext": true}
+
+// maskingDateLayouts are the text formats date_shift recognizes, as MySQL returns them.
+// Parsing accepts fractional seconds after the seconds field.
+var maskingDateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02"}
+
+// rowMasker applies the masking rules of a table mapping to rows read from the source. A nil
+// rowMasker leaves rows unchanged.
+type rowMasker struct {
+	secret []byte
+	masks  map[string]*columnMask // By lowercased target column
+}
+
+// columnMask is a masking rule resolved against its target column
+type columnMask struct {
+	rule       *MaskingRule
+	length     int    // Character length of the target column, 0 if unknown
+	integerMax uint64 // Largest value of an integer target column, 0 for other types
+}
+
+// newRowMasker resolves the mapping's masking rules against the target schema and enforces the
+// sync config's RequireMasking patterns. The server's masking secret keys the hash, fake and
+// date_shift strategies, which fail without it. Primary and unique key columns only take a hash
+// their column holds in full, so distinct keys stay distinct. It returns nil when the mapping has
+// no masking rules.
+func newRowMasker(mapping *TableMapping, options *SyncOptions, secret string, schema, targetSchema *TableSchema) (*rowMasker, error) {
+	if err := checkRequiredMasking(mapping, options, schema); err != nil {
+		return nil, err
+	}
+	if len(mapping.MaskingRules) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]*ColumnInfo, len(targetSchema.Columns))
+	for _, col := range targetSchema.Columns {
+		columns[strings.ToLower(col.Name)] = col
+	}
+	constants := make(map[string]bool)
+	for _, rule := range mapping.ColumnRules {
+		if rule.RuleType == ColumnRuleConstant {
+			constants[strings.ToLower(rule.TargetColumn)] = true
+		}
+	}
+
+	keyColumns := make(map[string]bool)
+	for _, key := range targetSchema.Keys {
+		if key.Type == "PRIMARY KEY" || key.Type == "UNIQUE" {
+			for _, col := range key.Columns {
+				keyColumns[strings.ToLower(col)] = true
+			}
+		}
+	}
+
+	m := &rowMasker{secret: []byte(secret), masks: make(map[string]*columnMask)}
+	for _, rule := range mapping.MaskingRules {
+		key := strings.ToLower(rule.Column)
+		col, ok := columns[key]
+		if !ok || constants[key] {
+			return nil, fmt.Errorf("masking rule column %s is not a copied column of target table %s", rule.Column, mapping.TargetTable)
+		}
+		// Keyed with a value anyone can read, the strategies could be reversed by trying candidates
+		if secret == "" && (rule.Strategy == MaskingHash || rule.Strategy == MaskingFake || rule.Strategy == MaskingDateShift) {
+			return nil, fmt.Errorf("%w: %s masking of column %s needs sync.masking_secret", errMaskingSecretRequired, rule.Strategy, rule.Column)
+		}
+		mask := &columnMask{rule: rule}
+		if match := columnLengthPattern.FindStringSubmatch(col.Type); match != nil {
+			mask.length, _ = strconv.Atoi(match[2])
+		}
+		baseType := strings.ToLower(col.Type)
+		if i := strings.IndexAny(baseType, "( "); i >= 0 {
+			baseType = baseType[:i]
+		}
+		mask.integerMax = integerTypeMax[baseType]
+		// Every other strategy, a hash reduced to an integer range or a truncated hash can map distinct
+		// keys to the same value, merging their rows in the target
+		if keyColumns[key] && (rule.Strategy != MaskingHash || (mask.length < maskingHashLength && !maskingTextTypes[baseType])) {
+			return nil, fmt.Errorf("key column %s can only be masked with the hash strategy, into a char, varchar or text column of at least %d characters",
+				rule.Column, maskingHashLength)
+		}
+		m.masks[key] = mask
+	}
+	return m, nil
+}
+
+// checkRequiredMasking fails when a copied column matches a RequireMasking pattern, under its source
+// or its target name, without a masking rule on its target column
+func checkRequiredMasking(mapping *TableMapping, options *SyncOptions, schema *TableSchema) error {
+	if options == nil || len(options.RequireMasking) == 0 {
+		return nil
+	}
+
+	patterns := make([]*regexp.Regexp, len(options.RequireMasking))
+	for i, pattern := range options.RequireMasking {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("invalid masking requirement pattern %q: %w", pattern, err)
+		}
+		patterns[i] = re
+	}
+	masked := make(map[string]bool, len(mapping.MaskingRules))
+	for _, rule := range mapping.MaskingRules {
+		masked[strings.ToLower(rule.Column)] = true
+	}
+
+	check := func(target string, names ...string) error {
+		if masked[strings.ToLower(target)] {
+			return nil
+		}
+		for _, name := range names {
+			for i, re := range patterns {
+				if re.MatchString(name) {
+					return fmt.Errorf("%w: column %s of table %s matches %q", errMaskingRequired, name, mapping.SourceTable, options.RequireMasking[i])
+				}
+			}
+		}
+		return nil
+	}
+
+	columns := mapping.columnMapping()
+	for _, col := range schema.Columns {
+		target, ok := columns.targetName(col.Name)
+		if !ok {
+			continue
+		}
+		if err := check(target, col.Name, target); err != nil {
+			return err
+		}
+	}
+	for _, rule := range mapping.ColumnRules {
+		if rule.RuleType == ColumnRuleExpression {
+			if err := check(rule.TargetColumn, rule.TargetColumn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// masksAny reports whether the masker changes any of the given target columns
+func (m *rowMasker) masksAny(columns []string) bool {
+	if m == nil {
+		return false
+	}
+	for _, col := range columns {
+		if _, ok := m.masks[strings.ToLower(col)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// maskRows masks the rows of a batch in place, keyed by target column
+func (m *rowMasker) maskRows(rows []map[string]interface{}) error {
+	if m == nil {
+		return nil
+	}
+	for _, row := range rows {
+		for col, value := range row {
+			masked, err := m.maskValue(col, value)
+			if err != nil {
+				return err
+			}
+			row[col] = masked
+		}
+	}
+	return nil
+}
+
+// maskBatch masks rows holding their values in the order of columns in place, keyed by target column
+func (m *rowMasker) maskBatch(columns []string, rows [][]interface{}) error {
+	if m == nil {
+		return nil
+	}
+	for i, col := range columns {
+		if _, ok := m.masks[strings.ToLower(col)]; !ok {
+			continue
+		}
+		for _, row := range rows {
+			masked, err := m.maskValue(col, row[i])
+			if err != nil {
+				return err
+			}
+			row[i] = masked
+		}
+	}
+	return nil
+}
+
+// maskValue masks one value of a target column. NULL stays NULL.
+func (m *rowMasker) maskValue(column string, value interface{}) (interface{}, error) {
+	if m == nil || value == nil {
+		return value, nil
+	}
+	mask, ok := m.masks[strings.ToLower(column)]
+	if !ok {
+		return value, nil
+	}
+
+	rule := mask.rule
+	switch rule.Strategy {
+	case MaskingNullify:
+		return nil, nil
+	case MaskingRedact:
+		if rule.Replacement == "" {
+			return defaultMaskingReplacement, nil
+		}
+		return rule.Replacement, nil
+	case MaskingDateShift:
+		return m.shiftDate(column, rule, value)
+	}
+
+	text := maskingText(value)
+	var masked string
+	switch rule.Strategy {
+	case MaskingHash:
+		digest := m.digest("hash", text)
+		// Integer columns get an integer of their range
+		if mask.integerMax > 0 {
+			return binary.BigEndian.Uint64(digest) % (mask.integerMax + 1), nil
+		}
+		masked = hex.EncodeToString(digest)
+	case MaskingFake:
+		masked = m.fake(text)
+	case MaskingPartial:
+		masked = partialMask(text, rule)
+	default:
+		return nil, fmt.Errorf("unsupported masking strategy %s for column %s", rule.Strategy, column)
+	}
+
+	// A hash is longer than most columns it masks; truncated it still maps equal values alike
+	if mask.length > 0 && len(masked) > mask.length {
+		masked = masked[:mask.length]
+	}
+	return masked, nil
+}
+
+// digest returns the keyed HMAC of a value, separated by purpose so strategies don't share outputs
+func (m *rowMasker) digest(purpose, value string) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// fake replaces letters and digits with ones drawn from the value's digest, keeping case,
+// punctuation and length, so the fake value passes the same format checks as the original
+func (m *rowMasker) fake(value string) string {
+	var stream []byte
+	block := 0
+	next := func() int {
+		if len(stream) == 0 {
+			stream = m.digest(fmt.Sprintf("fake/%d", block), value)
+			block++
+		}
+		b := stream[0]
+		stream = stream[1:]
+		return int(b)
+	}
+
+	var sb strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteByte(byte('0' + next()%10))
+		case r >= 'a' && r <= 'z':
+			sb.WriteByte(byte('a' + next()%26))
+		case r >= 'A' && r <= 'Z':
+			sb.WriteByte(byte('A' + next()%26))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// shiftDate moves a date by a number of days derived from the value, between -ShiftDays and
+// ShiftDays but never zero. Text values keep their format.
+func (m *rowMasker) shiftDate(column string, rule *MaskingRule, value interface{}) (interface{}, error) {
+	maxDays := rule.ShiftDays
+	if maxDays <= 0 {
+		maxDays = defaultMaskingShiftDays
+	}
+
+	var t time.Time
+	layout := ""
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	default:
+		text := maskingText(value)
+		for _, candidate := range maskingDateLayouts {
+			parsed, err := time.Parse(candidate, text)
+			if err == nil {
+				t, layout = parsed, candidate
+				break
+			}
+		}
+		if layout == "" {
+			return nil, fmt.Errorf("date_shift masking of column %s: %q is not a date", column, text)
+		}
+	}
+
+	offset := int(binary.BigEndian.Uint64(m.digest("date_shift", t.Format(time.RFC3339Nano))) % uint64(2*maxDays))
+	days := offset - maxDays
+	if days >= 0 {
+		days++
+	}
+	shifted := t.AddDate(0, 0, days)
+
+	if layout == "" {
+		return shifted, nil
+	}
+	if dot := strings.LastIndexByte(maskingText(value), '.'); dot >= 0 && layout == maskingDateLayouts[0] {
+		layout += "." + strings.Repeat("0", len(maskingText(value))-dot-1)
+	}
+	return shifted.Format(layout), nil
+}
+
+// partialMask keeps the first and last characters of a value and replaces the rest with *
+func partialMask(value string, rule *MaskingRule) string {
+	keepFirst, keepLast := rule.KeepFirst, rule.KeepLast
+	if keepFirst == 0 && keepLast == 0 {
+		keepFirst, keepLast = defaultMaskingKeepFirst, defaultMaskingKeepLast
+	}
+
+	runes := []rune(value)
+	if keepFirst+keepLast >= len(runes) {
+		return strings.Repeat("*", len(runes))
+	}
+	for i := keepFirst; i < len(runes)-keepLast; i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// maskingText returns the text form of a value read from the source
+func maskingText(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(value)
+}
+
+// validateMaskingRules validates the masking rules of a table mapping
+func validateMaskingRules(rules []*MaskingRule) error {
+	columns := make(map[string]bool)
+	for i, rule := range rules {
+		if rule == nil {
+			return fmt.Errorf("masking rule %d is empty", i)
+		}
+		if !isValidMySQLIdentifier(rule.Column) {
+			return fmt.Errorf("invalid column for masking rule %d: %s", i, rule.Column)
+		}
+		if !isValidMaskingStrategy(rule.Strategy) {
+			return fmt.Errorf("invalid masking strategy: %s", rule.Strategy)
+		}
+		if rule.KeepFirst < 0 || rule.KeepLast < 0 || rule.ShiftDays < 0 {
+			return fmt.Errorf("masking rule %d has a negative setting", i)
+		}
+
+		key := strings.ToLower(rule.Column)
+		if columns[key] {
+			return fmt.Errorf("duplicate masking rule for column %s", rule.Column)
+		}
+		columns[key] = true
+	}
+	return nil
+}
+
+// validateRequireMasking validates the RequireMasking patterns of sync options
+func validateRequireMasking(options *SyncOptions) error {
+	if options == nil {
+		return nil
+	}
+	for _, pattern := range options.RequireMasking {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid masking requirement pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
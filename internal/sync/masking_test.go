@@ -0,0 +1,339 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contactsSchema is the source schema the masking tests mask
+func contactsSchema() *TableSchema {
+	return &TableSchema{
+		Name: "contacts",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint"},
+			{Name: "phone", Type: "varchar(11)"},
+			{Name: "email", Type: "varchar(64)", Nullable: true},
+			{Name: "birthday", Type: "date"},
+			{Name: "token", Type: "char(8)"},
+			{Name: "note", Type: "text"},
+		},
+		Keys: []*KeyInfo{{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"id"}}},
+	}
+}
+
+func newContactsMasker(t *testing.T, secret string, rules ...*MaskingRule) *rowMasker {
+	mapping := &TableMapping{SourceTable: "contacts", TargetTable: "contacts", MaskingRules: rules}
+	masker, err := newRowMasker(mapping, nil, secret, contactsSchema(), contactsSchema())
+	require.NoError(t, err)
+	return masker
+}
+
+func TestRowMasker_Strategies(t *testing.T) {
+	masker := newContactsMasker(t, "secret",
+		&MaskingRule{Column: "phone", Strategy: MaskingPartial},
+		&MaskingRule{Column: "email", Strategy: MaskingFake},
+		&MaskingRule{Column: "birthday", Strategy: MaskingDateShift, ShiftDays: 10},
+		&MaskingRule{Column: "token", Strategy: MaskingHash},
+		&MaskingRule{Column: "Note", Strategy: MaskingRedact},
+	)
+
+	row := map[string]interface{}{
+		"id":       []byte("7"),
+		"phone":    []byte("13812341234"),
+		"email":    []byte("Alice.Smith@example.com"),
+		"birthday": []byte("2024-02-29"),
+		"token":    "secret",
+		"note":     nil,
+	}
+	require.NoError(t, masker.maskRows([]map[string]interface{}{row}))
+
+	assert.Equal(t, []byte("7"), row["id"])
+	assert.Equal(t, "138****1234", row["phone"])
+	assert.Nil(t, row["note"], "NULL stays NULL")
+
+	// A fake value keeps the shape of the original
+	email := row["email"].(string)
+	assert.NotEqual(t, "Alice.Smith@example.com", email)
+	assert.Regexp(t, `^[A-Z][a-z]{4}\.[A-Z][a-z]{4}@[a-z]{7}\.[a-z]{3}$`, email)
+
+	shifted, err := time.Parse("2006-01-02", row["birthday"].(string))
+	require.NoError(t, err)
+	days := shifted.Sub(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	assert.True(t, days != 0 && days >= -10 && days <= 10, "shifted by %v days", days)
+
+	// A hash is truncated to the column length and the same for equal values
+	token := row["token"].(string)
+	assert.Len(t, token, 8)
+	again, err := masker.maskValue("token", []byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	// Another secret gives other values
+	other, err := newContactsMasker(t, "pepper", &MaskingRule{Column: "token", Strategy: MaskingHash}).maskValue("token", "secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	redacted, err := masker.maskValue("note", "call back")
+	require.NoError(t, err)
+	assert.Equal(t, "***", redacted)
+}
+
+func TestRowMasker_Values(t *testing.T) {
+	masker := newContactsMasker(t, "secret",
+		&MaskingRule{Column: "phone", Strategy: MaskingPartial, KeepFirst: 1, KeepLast: 1},
+		&MaskingRule{Column: "email", Strategy: MaskingNullify},
+		&MaskingRule{Column: "note", Strategy: MaskingRedact, Replacement: "[removed]"},
+		&MaskingRule{Column: "birthday", Strategy: MaskingDateShift},
+	)
+
+	tests := []struct {
+		column string
+		value  interface{}
+		want   interface{}
+	}{
+		{"phone", "12345", "1***5"},
+		{"phone", "12", "**"},
+		{"email", "a@b.c", nil},
+		{"note", "call back", "[removed]"},
+	}
+	for _, tt := range tests {
+		got, err := masker.maskValue(tt.column, tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s %v", tt.column, tt.value)
+	}
+
+	// Dates keep their type and text format, down to the fractional seconds
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	shifted, err := masker.maskValue("birthday", at)
+	require.NoError(t, err)
+	assert.IsType(t, time.Time{}, shifted)
+	assert.NotEqual(t, at, shifted)
+
+	text, err := masker.maskValue("birthday", []byte("2024-05-01 12:30:00.250"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2} 12:30:00\.250$`, text)
+
+	_, err = masker.maskValue("birthday", "yesterday")
+	assert.ErrorContains(t, err, "is not a date")
+
+	var none *rowMasker
+	require.NoError(t, none.maskRows([]map[string]interface{}{{"phone": "13812341234"}}))
+	assert.False(t, none.masksAny([]string{"phone"}))
+}
+
+func TestNewRowMasker(t *testing.T) {
+	schema := contactsSchema()
+	newMasker := func(mapping *TableMapping) (*rowMasker, error) {
+		target, err := mapping.columnMapping().targetSchema(schema)
+		require.NoError(t, err)
+		return newRowMasker(mapping, nil, "secret", schema, target)
+	}
+
+	masker, err := newMasker(&TableMapping{})
+	require.NoError(t, err)
+	assert.Nil(t, masker)
+
+	// Rules name the target column
+	masker, err = newMasker(&TableMapping{
+		ColumnRules:  []*ColumnRule{{RuleType: ColumnRuleRename, SourceColumn: "phone", TargetColumn: "mobile"}},
+		MaskingRules: []*MaskingRule{{Column: "mobile", Strategy: MaskingPartial}},
+	})
+	require.NoError(t, err)
+	assert.True(t, masker.masksAny([]string{"MOBILE"}))
+	assert.Equal(t, []byte("secret"), masker.secret)
+
+	_, err = newMasker(&TableMapping{MaskingRules: []*MaskingRule{{Column: "phone_number", Strategy: MaskingRedact}}})
+	assert.ErrorContains(t, err, "not a copied column")
+
+	_, err = newMasker(&TableMapping{
+		ColumnRules:  []*ColumnRule{{RuleType: ColumnRuleConstant, TargetColumn: "region", Value: "eu"}},
+		MaskingRules: []*MaskingRule{{Column: "region", Strategy: MaskingRedact}},
+	})
+	assert.ErrorContains(t, err, "not a copied column")
+
+	// Key columns only take a hash their column holds in full
+	keyed := contactsSchema()
+	keyed.Keys = append(keyed.Keys,
+		&KeyInfo{Name: "uk_email", Type: "UNIQUE", Columns: []string{"email"}},
+		&KeyInfo{Name: "uk_token", Type: "UNIQUE", Columns: []string{"token"}},
+		&KeyInfo{Name: "uk_note", Type: "UNIQUE", Columns: []string{"note"}},
+	)
+	keyRules := []struct {
+		column   string
+		strategy MaskingStrategy
+		ok       bool
+	}{
+		{"id", MaskingPartial, false},
+		{"id", MaskingHash, false},    // An integer range
+		{"token", MaskingHash, false}, // Truncated to char(8)
+		{"email", MaskingFake, false},
+		{"email", MaskingHash, true},
+		{"note", MaskingHash, true},
+		{"phone", MaskingPartial, true},
+	}
+	for _, tt := range keyRules {
+		mapping := &TableMapping{MaskingRules: []*MaskingRule{{Column: tt.column, Strategy: tt.strategy}}}
+		_, err = newRowMasker(mapping, nil, "secret", keyed, keyed)
+		if tt.ok {
+			assert.NoError(t, err, "%s %s", tt.column, tt.strategy)
+		} else {
+			assert.ErrorContains(t, err, "key column "+tt.column+" can only be masked with the hash strategy", tt.strategy)
+		}
+	}
+
+	// Without the server's secret only the unkeyed strategies mask
+	for _, strategy := range []MaskingStrategy{MaskingHash, MaskingFake, MaskingDateShift} {
+		mapping := &TableMapping{MaskingRules: []*MaskingRule{{Column: "token", Strategy: strategy}}}
+		_, err = newRowMasker(mapping, nil, "", schema, schema)
+		assert.ErrorIs(t, err, errMaskingSecretRequired)
+		assert.ErrorContains(t, err, "sync.masking_secret")
+	}
+	masker, err = newRowMasker(&TableMapping{MaskingRules: []*MaskingRule{{Column: "phone", Strategy: MaskingPartial}}}, nil, "", schema, schema)
+	require.NoError(t, err)
+	assert.NotNil(t, masker)
+}
+
+func TestCheckRequiredMasking(t *testing.T) {
+	options := &SyncOptions{RequireMasking: []string{`phone|mobile`, `^e-?mail$`}}
+	mapping := &TableMapping{SourceTable: "contacts", TargetTable: "contacts"}
+
+	err := checkRequiredMasking(mapping, options, contactsSchema())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errMaskingRequired))
+	assert.Contains(t, err.Error(), "column phone of table contacts")
+
+	mapping.MaskingRules = []*MaskingRule{{Column: "phone", Strategy: MaskingPartial}}
+	err = checkRequiredMasking(mapping, options, contactsSchema())
+	assert.ErrorContains(t, err, "column email of table contacts")
+
+	// Excluded columns are never copied and need no rule
+	mapping.ColumnRules = []*ColumnRule{{RuleType: ColumnRuleExclude, SourceColumn: "email"}}
+	assert.NoError(t, checkRequiredMasking(mapping, options, contactsSchema()))
+
+	// A renamed column is matched by its target name too, and masked under it
+	mapping.ColumnRules = []*ColumnRule{
+		{RuleType: ColumnRuleExclude, SourceColumn: "email"},
+		{RuleType: ColumnRuleRename, SourceColumn: "note", TargetColumn: "Mobile_Note"},
+	}
+	assert.ErrorContains(t, checkRequiredMasking(mapping, options, contactsSchema()), "Mobile_Note")
+	mapping.MaskingRules = append(mapping.MaskingRules, &MaskingRule{Column: "mobile_note", Strategy: MaskingRedact})
+	assert.NoError(t, checkRequiredMasking(mapping, options, contactsSchema()))
+
+	assert.NoError(t, checkRequiredMasking(&TableMapping{}, nil, contactsSchema()))
+}
+
+func TestClassifyError_MaskingRequired(t *testing.T) {
+	handler := NewErrorHandler(logrus.New(), nil, nil)
+	err := checkRequiredMasking(&TableMapping{SourceTable: "contacts"}, &SyncOptions{RequireMasking: []string{"phone"}}, contactsSchema())
+
+	syncErr := handler.ClassifyError(err, "contacts")
+	assert.Equal(t, ErrorTypeMaskingPolicy, syncErr.Type)
+	assert.Equal(t, ErrorSeverityCritical, syncErr.Severity)
+	assert.False(t, syncErr.Retryable)
+	assert.True(t, handler.ShouldStopJob(err))
+	assert.Contains(t, handler.GetErrorSuggestion(err), "masking rule")
+
+	mapping := &TableMapping{MaskingRules: []*MaskingRule{{Column: "token", Strategy: MaskingHash}}}
+	_, err = newRowMasker(mapping, nil, "", contactsSchema(), contactsSchema())
+	syncErr = handler.ClassifyError(err, "contacts")
+	assert.Equal(t, ErrorTypeMaskingPolicy, syncErr.Type)
+	assert.True(t, handler.ShouldStopJob(err))
+	assert.Contains(t, handler.GetErrorSuggestion(err), "DBT_SYNC_MASKING_SECRET")
+}
+
+func TestValidateMaskingRules(t *testing.T) {
+	assert.NoError(t, validateMaskingRules(nil))
+	assert.NoError(t, validateMaskingRules([]*MaskingRule{
+		{Column: "phone", Strategy: MaskingPartial, KeepFirst: 3, KeepLast: 4},
+		{Column: "birthday", Strategy: MaskingDateShift, ShiftDays: 90},
+	}))
+
+	tests := []struct {
+		name  string
+		rules []*MaskingRule
+		err   string
+	}{
+		{"empty rule", []*MaskingRule{nil}, "is empty"},
+		{"invalid column", []*MaskingRule{{Column: "a-b", Strategy: MaskingRedact}}, "invalid column"},
+		{"unknown strategy", []*MaskingRule{{Column: "phone", Strategy: "shuffle"}}, "invalid masking strategy"},
+		{"negative setting", []*MaskingRule{{Column: "phone", Strategy: MaskingPartial, KeepLast: -1}}, "negative setting"},
+		{"duplicate column", []*MaskingRule{{Column: "phone", Strategy: MaskingHash}, {Column: "PHONE", Strategy: MaskingRedact}}, "duplicate masking rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, validateMaskingRules(tt.rules), tt.err)
+		})
+	}
+
+	assert.NoError(t, validateRequireMasking(&SyncOptions{RequireMasking: []string{`(?i)^ssn$`}}))
+	assert.ErrorContains(t, validateRequireMasking(&SyncOptions{RequireMasking: []string{`phone(`}}), "invalid masking requirement pattern")
+}
+
+func TestCopyTableByKeyset_MaskedKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	ctx := context.Background()
+
+	mapping := &TableMapping{
+		ID: "mapping-1", SourceTable: "contacts", TargetTable: "contacts",
+		ColumnRules: []*ColumnRule{
+			{RuleType: ColumnRuleInclude, SourceColumn: "email"},
+			{RuleType: ColumnRuleInclude, SourceColumn: "phone"},
+		},
+		MaskingRules: []*MaskingRule{
+			{Column: "email", Strategy: MaskingHash},
+			{Column: "phone", Strategy: MaskingPartial},
+		},
+	}
+	schema := contactsSchema()
+	schema.Keys = []*KeyInfo{{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"email"}}}
+	target, err := mapping.columnMapping().targetSchema(schema)
+	require.NoError(t, err)
+	masker, err := newRowMasker(mapping, nil, "secret", schema, target)
+	require.NoError(t, err)
+
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("contacts").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("email"))
+	plan, err := engine.planFullCopy(ctx, sourceDB, targetDB, mysqlDialect{}, "replica", mapping, schema, false)
+	require.NoError(t, err)
+	plan.masker = masker
+
+	maskedEmail, err := masker.maskValue("email", []byte("alice@example.com"))
+	require.NoError(t, err)
+	assert.Len(t, maskedEmail, maskingHashLength, "key hashes are not truncated")
+
+	// Rows are written masked, the next chunk still starts after the source key
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `phone`, `email` FROM `shop`.`contacts` ORDER BY `email` LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"phone", "email"}).AddRow([]byte("13812341234"), []byte("alice@example.com")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`contacts` (`phone`, `email`) VALUES (?, ?)")).
+		WithArgs("138****1234", maskedEmail).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `phone`, `email` FROM `shop`.`contacts` WHERE (`email`) > (?) ORDER BY `email` LIMIT 1")).
+		WithArgs("alice@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"phone", "email"}))
+
+	processed, err := engine.copyTableByKeyset(ctx, sourceDB, "shop", targetDB, "replica", mapping, plan, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), processed)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPropagateDeletes_MaskedKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, _ := newMockEngine(t)
+
+	mapping := &TableMapping{SourceTable: "contacts", TargetTable: "contacts", DeleteDetection: DeleteDetectionHard,
+		MaskingRules: []*MaskingRule{{Column: "ID", Strategy: MaskingHash}}}
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("contacts").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+	_, err := engine.propagateDeletes(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, nil)
+	assert.ErrorContains(t, err, "masking rule rewrites")
+}
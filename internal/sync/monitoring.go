@@ -26,7 +26,7 @@ type JobMonitor struct {
 	ConfigID        string
 	StartTime       time.Time
 	LastUpdate      time.Time
-	CurrentTable    string
+	CurrentTables   []string // Tables being synced, in the order they started
 	TablesProgress  map[string]*TableProgress
 	TotalTables     int
 	CompletedTables int
@@ -161,9 +161,7 @@ func (m *MonitoringServiceImpl) UpdateTableProgress(ctx context.Context, jobID,
 		tableProgress.EndTime = &now
 	}
 
-	if status == TableStatusRunning {
-		monitor.CurrentTable = tableName
-	}
+	monitor.CurrentTables = updateCurrentTables(monitor.CurrentTables, tableName, status == TableStatusRunning)
 
 	monitor.LastUpdate = time.Now()
 
@@ -178,6 +176,107 @@ func (m *MonitoringServiceImpl) UpdateTableProgress(ctx context.Context, jobID,
 	return nil
 }
 
+// updateCurrentTables adds a table that is running to the current tables and removes one that isn't
+func updateCurrentTables(tables []string, tableName string, running bool) []string {
+	for i, name := range tables {
+		if name == tableName {
+			if running {
+				return tables
+			}
+			return append(tables[:i:i], tables[i+1:]...)
+		}
+	}
+	if running {
+		tables = append(tables, tableName)
+	}
+	return tables
+}
+
+// firstTable returns the current table that started first, empty when no table is running
+func firstTable(tables []string) string {
+	if len(tables) == 0 {
+		return ""
+	}
+	return tables[0]
+}
+
+// UpdateTableDeletes records the outcome of the delete detection pass of a table sync
+func (m *MonitoringServiceImpl) UpdateTableDeletes(ctx context.Context, jobID, tableName string, mode DeleteDetection, deletedRows int64) error {
+	m.jobsMutex.Lock()
+	defer m.jobsMutex.Unlock()
+
+	monitor, exists := m.activeJobs[jobID]
+	if !exists {
+		return fmt.Errorf("job monitor not found: %s", jobID)
+	}
+
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	tableProgress, exists := monitor.TablesProgress[tableName]
+	if !exists {
+		tableProgress = &TableProgress{
+			TableName: tableName,
+			StartTime: time.Now(),
+		}
+		monitor.TablesProgress[tableName] = tableProgress
+	}
+
+	tableProgress.DeleteDetection = mode
+	tableProgress.DeletedRows = deletedRows
+	monitor.LastUpdate = time.Now()
+
+	m.logger.WithFields(logrus.Fields{
+		"job_id":           jobID,
+		"table_name":       tableName,
+		"delete_detection": mode,
+		"deleted_rows":     deletedRows,
+	}).Debug("Updated table deletes")
+
+	return nil
+}
+
+// UpdateTableBatchSize records a batch size adaptive batch sizing chose for a table sync, along with
+// the smallest and largest sizes chosen so far
+func (m *MonitoringServiceImpl) UpdateTableBatchSize(ctx context.Context, jobID, tableName string, batchSize int) error {
+	m.jobsMutex.Lock()
+	defer m.jobsMutex.Unlock()
+
+	monitor, exists := m.activeJobs[jobID]
+	if !exists {
+		return fmt.Errorf("job monitor not found: %s", jobID)
+	}
+
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+
+	tableProgress, exists := monitor.TablesProgress[tableName]
+	if !exists {
+		tableProgress = &TableProgress{
+			TableName: tableName,
+			StartTime: time.Now(),
+		}
+		monitor.TablesProgress[tableName] = tableProgress
+	}
+
+	tableProgress.BatchSize = batchSize
+	if tableProgress.MinBatchSize == 0 || batchSize < tableProgress.MinBatchSize {
+		tableProgress.MinBatchSize = batchSize
+	}
+	if batchSize > tableProgress.MaxBatchSize {
+		tableProgress.MaxBatchSize = batchSize
+	}
+	monitor.LastUpdate = time.Now()
+
+	m.logger.WithFields(logrus.Fields{
+		"job_id":     jobID,
+		"table_name": tableName,
+		"batch_size": batchSize,
+	}).Debug("Updated table batch size")
+
+	return nil
+}
+
 // GetJobProgress returns the current progress of a sync job
 // Requirement 5.1: Real-time display of sync progress and status
 func (m *MonitoringServiceImpl) GetJobProgress(ctx context.Context, jobID string) (*JobSummary, error) {
@@ -224,7 +323,8 @@ func (m *MonitoringServiceImpl) GetJobProgress(ctx context.Context, jobID string
 		StartTime:       monitor.StartTime,
 		TotalTables:     monitor.TotalTables,
 		CompletedTables: monitor.CompletedTables,
-		CurrentTable:    monitor.CurrentTable,
+		CurrentTable:    firstTable(monitor.CurrentTables),
+		CurrentTables:   append([]string(nil), monitor.CurrentTables...),
 		TotalRows:       monitor.TotalRows,
 		ProcessedRows:   monitor.ProcessedRows,
 		ErrorCount:      monitor.ErrorCount,
@@ -235,14 +335,19 @@ func (m *MonitoringServiceImpl) GetJobProgress(ctx context.Context, jobID string
 	// Copy table progress
 	for name, progress := range monitor.TablesProgress {
 		summary.TableProgress[name] = &TableProgress{
-			TableName:     progress.TableName,
-			Status:        progress.Status,
-			StartTime:     progress.StartTime,
-			EndTime:       progress.EndTime,
-			TotalRows:     progress.TotalRows,
-			ProcessedRows: progress.ProcessedRows,
-			ErrorCount:    progress.ErrorCount,
-			LastError:     progress.LastError,
+			TableName:       progress.TableName,
+			Status:          progress.Status,
+			StartTime:       progress.StartTime,
+			EndTime:         progress.EndTime,
+			TotalRows:       progress.TotalRows,
+			ProcessedRows:   progress.ProcessedRows,
+			ErrorCount:      progress.ErrorCount,
+			LastError:       progress.LastError,
+			DeleteDetection: progress.DeleteDetection,
+			DeletedRows:     progress.DeletedRows,
+			BatchSize:       progress.BatchSize,
+			MinBatchSize:    progress.MinBatchSize,
+			MaxBatchSize:    progress.MaxBatchSize,
 		}
 	}
 
@@ -473,7 +578,8 @@ func (m *MonitoringServiceImpl) GetActiveJobs(ctx context.Context) ([]*JobSummar
 			StartTime:       monitor.StartTime,
 			TotalTables:     monitor.TotalTables,
 			CompletedTables: monitor.CompletedTables,
-			CurrentTable:    monitor.CurrentTable,
+			CurrentTable:    firstTable(monitor.CurrentTables),
+			CurrentTables:   append([]string(nil), monitor.CurrentTables...),
 			TotalRows:       monitor.TotalRows,
 			ProcessedRows:   monitor.ProcessedRows,
 			ErrorCount:      monitor.ErrorCount,
@@ -487,14 +593,19 @@ func (m *MonitoringServiceImpl) GetActiveJobs(ctx context.Context) ([]*JobSummar
 		// Copy table progress
 		for name, progress := range monitor.TablesProgress {
 			summary.TableProgress[name] = &TableProgress{
-				TableName:     progress.TableName,
-				Status:        progress.Status,
-				StartTime:     progress.StartTime,
-				EndTime:       progress.EndTime,
-				TotalRows:     progress.TotalRows,
-				ProcessedRows: progress.ProcessedRows,
-				ErrorCount:    progress.ErrorCount,
-				LastError:     progress.LastError,
+				TableName:       progress.TableName,
+				Status:          progress.Status,
+				StartTime:       progress.StartTime,
+				EndTime:         progress.EndTime,
+				TotalRows:       progress.TotalRows,
+				ProcessedRows:   progress.ProcessedRows,
+				ErrorCount:      progress.ErrorCount,
+				LastError:       progress.LastError,
+				DeleteDetection: progress.DeleteDetection,
+				DeletedRows:     progress.DeletedRows,
+				BatchSize:       progress.BatchSize,
+				MinBatchSize:    progress.MinBatchSize,
+				MaxBatchSize:    progress.MaxBatchSize,
 			}
 		}
 
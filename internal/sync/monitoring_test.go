@@ -108,6 +108,35 @@ func TestMonitoringService_UpdateTableProgress(t *testing.T) {
 	assert.Equal(t, int64(500), tableProgress.TotalRows)
 }
 
+func TestMonitoringService_CurrentTables(t *testing.T) {
+	mockRepo := &MockRepository{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	monitoring := NewMonitoringService(mockRepo, logger)
+	ctx := context.Background()
+	jobID := "test-job-1"
+	mockRepo.On("GetSyncJob", ctx, jobID).Return(&SyncJob{ID: jobID, ConfigID: "config-1"}, nil)
+	assert.NoError(t, monitoring.StartJobMonitoring(ctx, jobID, 3))
+
+	// Tables syncing concurrently are all current, in the order they started
+	assert.NoError(t, monitoring.UpdateTableProgress(ctx, jobID, "users", TableStatusRunning, 0, 0, ""))
+	assert.NoError(t, monitoring.UpdateTableProgress(ctx, jobID, "orders", TableStatusRunning, 0, 0, ""))
+	assert.NoError(t, monitoring.UpdateTableProgress(ctx, jobID, "users", TableStatusRunning, 50, 100, ""))
+	summary, err := monitoring.GetJobProgress(ctx, jobID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users", "orders"}, summary.CurrentTables)
+	assert.Equal(t, "users", summary.CurrentTable)
+
+	// Finished tables are no longer current
+	assert.NoError(t, monitoring.UpdateTableProgress(ctx, jobID, "users", TableStatusCompleted, 100, 100, ""))
+	assert.NoError(t, monitoring.UpdateTableProgress(ctx, jobID, "orders", TableStatusFailed, 0, 0, "boom"))
+	activeJobs, err := monitoring.GetActiveJobs(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, activeJobs[0].CurrentTables)
+	assert.Empty(t, activeJobs[0].CurrentTable)
+}
+
 func TestMonitoringService_FinishJobMonitoring(t *testing.T) {
 	mockRepo := &MockRepository{}
 	logger := logrus.New()
@@ -0,0 +1,376 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// parallelCopyMinRows is the row count from which a full copy splits a table into key ranges
+	parallelCopyMinRows = 100000
+
+	// copyRangesPerWorker is how many key ranges a parallel copy plans per worker, so that workers
+	// finishing early pick up the remaining ranges
+	copyRangesPerWorker = 4
+)
+
+type workerPoolContextKey struct{}
+
+// withJobWorkerPool returns a context that carries the worker pool the tables of a job take their
+// slots from
+func withJobWorkerPool(ctx context.Context, pool *WorkerPool) context.Context {
+	return context.WithValue(ctx, workerPoolContextKey{}, pool)
+}
+
+// jobWorkerPool returns the job's worker pool from ctx, or nil outside a job
+func jobWorkerPool(ctx context.Context) *WorkerPool {
+	pool, _ := ctx.Value(workerPoolContextKey{}).(*WorkerPool)
+	return pool
+}
+
+// planRange is a key range on the leading primary key column while a copy is planned
+type planRange struct {
+	lower, upper *big.Int // (lower, upper]
+	rows         int64    // Estimated rows
+}
+
+// planCopyRanges splits the source table into ranges of the leading primary key column for a
+// parallel copy. The ranges first divide MIN..MAX evenly; the optimizer's row estimate of each
+// range, which InnoDB takes from index dives, then splits dense ranges and merges sparse ones so
+// skewed keys still give ranges of similar size. It returns nil when the key can't be split.
+func (e *DefaultSyncEngine) planCopyRanges(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, mapping *TableMapping, plan *fullCopyPlan, workers int, totalRows int64) ([]*CopyRange, error) {
+	if len(plan.primaryKeys) == 0 || !isIntegerColumnType(plan.keyTypes[0]) {
+		return nil, nil
+	}
+	key := plan.primaryKeys[0]
+
+	query := fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM `%s`.`%s`", key, key, sourceDBName, mapping.SourceTable)
+	filter := mapping.rowFilter()
+	query += filter.where()
+	var minValue, maxValue sql.NullString
+	if err := sourceDB.QueryRowContext(ctx, query, filter.args...).Scan(&minValue, &maxValue); err != nil {
+		return nil, fmt.Errorf("failed to get key bounds: %w", err)
+	}
+	low, okLow := new(big.Int).SetString(minValue.String, 10)
+	high, okHigh := new(big.Int).SetString(maxValue.String, 10)
+	if !minValue.Valid || !maxValue.Valid || !okLow || !okHigh {
+		return nil, nil
+	}
+
+	count := int64(workers * copyRangesPerWorker)
+	ranges := splitKeyRange(new(big.Int).Sub(low, big.NewInt(1)), high, count)
+	if len(ranges) < 2 {
+		return nil, nil
+	}
+
+	// Even out skewed keys by the estimated rows of each range
+	targetRows := totalRows / count
+	if targetRows < 1 {
+		targetRows = 1
+	}
+	for _, r := range ranges {
+		rows, err := e.estimateRangeRows(ctx, sourceDB, sourceDBName, mapping.SourceTable, key, r)
+		if err != nil {
+			e.logger.WithError(err).WithField("source_table", mapping.SourceTable).
+				Warn("Failed to estimate key range rows, copying evenly split ranges")
+			return copyRanges(ranges), nil
+		}
+		r.rows = rows
+	}
+	var balanced []*planRange
+	for _, r := range ranges {
+		if r.rows > 2*targetRows {
+			pieces := splitKeyRange(r.lower, r.upper, (r.rows+targetRows-1)/targetRows)
+			for _, piece := range pieces {
+				piece.rows = r.rows / int64(len(pieces))
+			}
+			balanced = append(balanced, pieces...)
+			continue
+		}
+		if n := len(balanced); n > 0 && balanced[n-1].rows+r.rows <= targetRows {
+			balanced[n-1].upper = r.upper
+			balanced[n-1].rows += r.rows
+			continue
+		}
+		balanced = append(balanced, r)
+	}
+	if len(balanced) < 2 {
+		return nil, nil
+	}
+	return copyRanges(balanced), nil
+}
+
+// splitKeyRange splits the integer range (lower, upper] into up to count ranges of equal width
+func splitKeyRange(lower, upper *big.Int, count int64) []*planRange {
+	width := new(big.Int).Sub(upper, lower)
+	if width.Cmp(big.NewInt(count)) < 0 {
+		count = width.Int64()
+	}
+
+	ranges := make([]*planRange, 0, count)
+	previous := lower
+	for i := int64(1); i <= count; i++ {
+		bound := new(big.Int).Mul(width, big.NewInt(i))
+		bound.Quo(bound, big.NewInt(count)).Add(bound, lower)
+		ranges = append(ranges, &planRange{lower: previous, upper: bound})
+		previous = bound
+	}
+	return ranges
+}
+
+// copyRanges turns planned ranges into copy ranges. The outer ranges are left unbounded so rows
+// written beyond the key bounds while the copy runs are copied too.
+func copyRanges(ranges []*planRange) []*CopyRange {
+	result := make([]*CopyRange, len(ranges))
+	for i, r := range ranges {
+		result[i] = &CopyRange{Lower: r.lower.String(), Upper: r.upper.String()}
+	}
+	result[0].Lower = ""
+	result[len(result)-1].Upper = ""
+	return result
+}
+
+// estimateRangeRows returns the optimizer's estimate of the rows in a range of the key column
+func (e *DefaultSyncEngine) estimateRangeRows(ctx context.Context, sourceDB *sqlx.DB, sourceDBName, tableName, key string, r *planRange) (int64, error) {
+	query := fmt.Sprintf("SELECT 1 FROM `%s`.`%s` WHERE `%s` > %s AND `%s` <= %s",
+		sourceDBName, tableName, key, r.lower, key, r.upper)
+	return explainRowEstimate(ctx, sourceDB, query)
+}
+
+// explainRowEstimate returns the optimizer's estimate of the rows a query examines
+func explainRowEstimate(ctx context.Context, db *sqlx.DB, query string, args ...interface{}) (int64, error) {
+	rows, err := db.QueryxContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var estimate int64
+	for rows.Next() {
+		plan := make(map[string]interface{})
+		if err := rows.MapScan(plan); err != nil {
+			return 0, err
+		}
+		// The optimizer leaves rows NULL for a range it knows to be empty
+		if value, ok := plan["rows"]; ok && value != nil {
+			n, err := strconv.ParseInt(formatKeyValue(value), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid row estimate %v", value)
+			}
+			estimate += n
+		}
+	}
+	return estimate, rows.Err()
+}
+
+// condition returns the condition restricting the leading key column to the range, combined with
+// filter. The bounds are bound as arguments of the key column's type.
+func (r *CopyRange) condition(key, keyType string, filter sqlCondition) sqlCondition {
+	var bounds sqlCondition
+	add := func(c string, value string) {
+		if bounds.clause != "" {
+			bounds.clause += " AND "
+		}
+		bounds.clause += c
+		bounds.args = append(bounds.args, bindKeyValue(keyType, value))
+	}
+	if r.Lower != "" {
+		add(fmt.Sprintf("`%s` > ?", key), r.Lower)
+	}
+	if r.Upper != "" {
+		add(fmt.Sprintf("`%s` <= ?", key), r.Upper)
+	}
+	return filter.and(bounds)
+}
+
+// validCopyRanges reports whether the bounds of ranges read back from a checkpoint are integers
+func validCopyRanges(ranges []*CopyRange) bool {
+	for _, r := range ranges {
+		if r == nil {
+			return false
+		}
+		for _, bound := range []string{r.Lower, r.Upper} {
+			if _, ok := new(big.Int).SetString(bound, 10); bound != "" && !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// copyTableInRanges copies the key ranges of a table concurrently, at most workers at a time and,
+// within a job, only while the job's worker pool has slots to spare. Each range is copied in key
+// order chunk by chunk like a serial keyset copy; workers draw their own connections from the
+// source and target pools. A failing range is retried from its last committed
+// chunk on its own, and only when its retries are exhausted does the copy stop.
+func (e *DefaultSyncEngine) copyTableInRanges(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, plan *fullCopyPlan, ranges []*CopyRange, workers, batchSize int, totalRows int64) (int64, error) {
+	var processedRows int64
+	batchNumber := 0
+	if plan.resume != nil {
+		batchNumber = plan.resume.BatchNumber
+	}
+	for _, r := range ranges {
+		processedRows += r.ProcessedRows
+	}
+	ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+
+	e.logger.WithFields(logrus.Fields{
+		"source_table": mapping.SourceTable,
+		"ranges":       len(ranges),
+		"workers":      workers,
+	}).Info("Copying table in parallel key ranges")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	retryPolicy := e.copyRetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = DefaultRetryPolicy()
+	}
+	retrier := NewErrorHandler(e.logger, nil, nil)
+	retrier.SetRetryPolicy(retryPolicy)
+
+	// Committed chunks are recorded under mu, which keeps progress and checkpoints consistent
+	var mu sync.Mutex
+	var copyErr error
+	commit := func(r *CopyRange, copied int, last []interface{}, done bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if copied > 0 {
+			r.LastKey = formatKey(last)
+			r.ProcessedRows += int64(copied)
+			processedRows += int64(copied)
+			batchNumber++
+		}
+		r.Done = done
+		ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+
+		if plan.checkpoint {
+			snapshot := make([]*CopyRange, len(ranges))
+			for i, r := range ranges {
+				c := *r
+				snapshot[i] = &c
+			}
+			e.saveCopyCheckpoint(ctx, mapping, &TableCheckpoint{
+				TableName:     mapping.SourceTable,
+				TargetTable:   mapping.TargetTable,
+				KeyColumns:    plan.primaryKeys,
+				Ranges:        snapshot,
+				ProcessedRows: processedRows,
+				TotalRows:     totalRows,
+				BatchNumber:   batchNumber,
+			})
+		}
+	}
+
+	copyRange := func(r *CopyRange) {
+		err := retrier.RetryOperation(ctx, func() error {
+			return e.copyKeyRange(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, plan, r, batchSize, commit)
+		}, mapping.SourceTable)
+		if err != nil {
+			mu.Lock()
+			if copyErr == nil {
+				copyErr = fmt.Errorf("failed to copy key range (%s, %s]: %w", r.Lower, r.Upper, err)
+				cancel()
+			}
+			mu.Unlock()
+		}
+	}
+
+	pending := make(chan *CopyRange, len(ranges))
+	for _, r := range ranges {
+		if !r.Done {
+			pending <- r
+		}
+	}
+	close(pending)
+
+	// Within a job the first worker runs on the slot the table holds in the job's worker pool and
+	// the others take a slot of the same pool for each range, so that the tables of a group and
+	// their ranges together stay within the job's concurrency. Workers waiting for a slot give up
+	// once no range is left, so the table never waits on slots other tables hold.
+	pool := jobWorkerPool(ctx)
+	idle, drained := context.WithCancel(ctx)
+	defer drained()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(acquire bool) {
+			defer wg.Done()
+			for {
+				if acquire && !pool.AcquireUntil(idle.Done()) {
+					return
+				}
+				r, ok := <-pending
+				if ok && ctx.Err() == nil {
+					copyRange(r)
+				}
+				if acquire {
+					pool.Release()
+				}
+				if !ok {
+					drained()
+					return
+				}
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}(pool != nil && i > 0)
+	}
+	wg.Wait()
+	if copyErr != nil {
+		return processedRows, copyErr
+	}
+
+	// The copy is complete, a later full sync starts from an empty table again
+	if plan.checkpoint {
+		e.saveCopyCheckpoint(ctx, mapping, &TableCheckpoint{
+			TableName:     mapping.SourceTable,
+			TargetTable:   mapping.TargetTable,
+			KeyColumns:    plan.primaryKeys,
+			ProcessedRows: processedRows,
+			TotalRows:     totalRows,
+			BatchNumber:   batchNumber,
+		})
+	}
+
+	return processedRows, nil
+}
+
+// copyKeyRange copies the rows of a range after its last committed key and commits each chunk
+func (e *DefaultSyncEngine) copyKeyRange(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, plan *fullCopyPlan, r *CopyRange, batchSize int, commit func(r *CopyRange, copied int, last []interface{}, done bool)) error {
+	var lower []interface{}
+	if len(r.LastKey) > 0 {
+		if len(r.LastKey) != len(plan.primaryKeys) {
+			return fmt.Errorf("invalid copy range key: %v", r.LastKey)
+		}
+		lower = make([]interface{}, len(r.LastKey))
+		for i, value := range r.LastKey {
+			lower[i] = bindKeyValue(plan.keyTypes[i], value)
+		}
+	}
+	filter := r.condition(plan.primaryKeys[0], plan.keyTypes[0], mapping.rowFilter())
+
+	return e.runRowPipeline(ctx, pipelineStages{
+		read:      e.keysetReader(sourceDB, sourceDBName, mapping, plan, filter, lower, batchSize),
+		transform: maskStage(plan.masker),
+		write: func(ctx context.Context, batch *rowBatch) error {
+			if len(batch.rows) > 0 {
+				if err := e.writeCopyBatch(ctx, targetDB, targetDBName, mapping.TargetTable, plan, batch, true); err != nil {
+					return fmt.Errorf("failed to insert batch: %w", err)
+				}
+			}
+			commit(r, len(batch.rows), batch.last, batch.done)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,252 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ordersCopyPlan() *fullCopyPlan {
+	return &fullCopyPlan{selectList: "`id`", primaryKeys: []string{"id"}, keyTypes: []string{"bigint"}, keyTargets: []string{"id"}, dialect: mysqlDialect{}}
+}
+
+func explainRows(rows interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).AddRow(1, "SIMPLE", "orders", "range", rows)
+}
+
+func rangeBounds(ranges []*CopyRange) [][2]string {
+	bounds := make([][2]string, len(ranges))
+	for i, r := range ranges {
+		bounds[i] = [2]string{r.Lower, r.Upper}
+	}
+	return bounds
+}
+
+func TestSplitKeyRange(t *testing.T) {
+	ranges := splitKeyRange(big.NewInt(0), big.NewInt(10), 4)
+	var uppers []int64
+	for _, r := range ranges {
+		uppers = append(uppers, r.upper.Int64())
+	}
+	assert.Equal(t, []int64{2, 5, 7, 10}, uppers)
+	assert.Equal(t, int64(0), ranges[0].lower.Int64())
+	assert.Equal(t, int64(2), ranges[1].lower.Int64())
+
+	// A range narrower than count splits into single keys
+	assert.Len(t, splitKeyRange(big.NewInt(4), big.NewInt(6), 4), 2)
+}
+
+func TestPlanCopyRanges_SkewedKeys(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", WhereClause: "status = 'paid'"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `shop`.`orders` WHERE status = 'paid'")).
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow("1", "400"))
+
+	// Four even ranges, most rows in (200, 300]
+	for i, rows := range []interface{}{int64(10), nil, []byte("960"), int64(20)} {
+		lower, upper := i*100, (i+1)*100
+		sourceMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM `shop`.`orders` WHERE `id` > " +
+			big.NewInt(int64(lower)).String() + " AND `id` <= " + big.NewInt(int64(upper)).String())).
+			WillReturnRows(explainRows(rows))
+	}
+
+	ranges, err := engine.planCopyRanges(context.Background(), sourceDB, "shop", mapping, ordersCopyPlan(), 1, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"", "200"}, {"200", "225"}, {"225", "250"}, {"250", "275"}, {"275", "300"}, {"300", ""},
+	}, rangeBounds(ranges))
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestPlanCopyRanges_Unsplittable(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	// Keys that aren't integers are copied serially
+	plan := ordersCopyPlan()
+	plan.keyTypes = []string{"varchar(36)"}
+	ranges, err := engine.planCopyRanges(context.Background(), sourceDB, "shop", mapping, plan, 4, 1000000)
+	require.NoError(t, err)
+	assert.Nil(t, ranges)
+
+	// So is an empty table
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `shop`.`orders`")).
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(nil, nil))
+	ranges, err = engine.planCopyRanges(context.Background(), sourceDB, "shop", mapping, ordersCopyPlan(), 4, 1000000)
+	require.NoError(t, err)
+	assert.Nil(t, ranges)
+
+	// Failing estimates fall back to even ranges
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT MIN(`id`), MAX(`id`) FROM `shop`.`orders`")).
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow("-3", "4"))
+	sourceMock.ExpectQuery("EXPLAIN").WillReturnError(errors.New("EXPLAIN denied"))
+	ranges, err = engine.planCopyRanges(context.Background(), sourceDB, "shop", mapping, ordersCopyPlan(), 1, 1000000)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{{"", "-2"}, {"-2", "0"}, {"0", "2"}, {"2", ""}}, rangeBounds(ranges))
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestCopyRange_Condition(t *testing.T) {
+	assert.Equal(t, sqlCondition{clause: "`id` <= ?", args: []interface{}{int64(9)}}, (&CopyRange{Upper: "9"}).condition("id", "bigint", sqlCondition{}))
+	assert.Equal(t, sqlCondition{clause: "(`region` = ?) AND (`id` > ? AND `id` <= ?)", args: []interface{}{"eu", uint64(3), uint64(18446744073709551615)}},
+		(&CopyRange{Lower: "3", Upper: "18446744073709551615"}).condition("id", "bigint unsigned", sqlCondition{clause: "`region` = ?", args: []interface{}{"eu"}}))
+	assert.Equal(t, sqlCondition{}, (&CopyRange{}).condition("id", "bigint", sqlCondition{}))
+
+	// Bounds read back from a checkpoint are never part of the SQL text
+	condition := (&CopyRange{Lower: "0 OR 1=1"}).condition("id", "bigint", sqlCondition{})
+	assert.Equal(t, sqlCondition{clause: "`id` > ?", args: []interface{}{"0 OR 1=1"}}, condition)
+	assert.True(t, validCopyRanges([]*CopyRange{{Upper: "-2"}, {Lower: "-2", Upper: "18446744073709551615"}, {Lower: "18446744073709551615"}}))
+	assert.False(t, validCopyRanges([]*CopyRange{{Upper: "2"}, {Lower: "0 OR 1=1"}}))
+	assert.False(t, validCopyRanges([]*CopyRange{nil}))
+}
+
+func TestCopyTableInRanges_RetriesFailedRange(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	engine.copyRetryPolicy = &RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 1}
+	sourceMock.MatchExpectationsInOrder(false)
+	targetMock.MatchExpectationsInOrder(false)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+	ranges := []*CopyRange{{Upper: "2"}, {Lower: "2"}}
+
+	// (-inf, 2] fills a chunk and finds nothing after it
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id` <= ?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow([]byte("1")).AddRow([]byte("2")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`) VALUES (?), (?)")).
+		WithArgs([]byte("1"), []byte("2")).WillReturnResult(sqlmock.NewResult(0, 2))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id`) > (?) AND (`id` <= ?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(2), int64(2)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// (2, +inf) deadlocks once and is retried on its own
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id` > ?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(2)).WillReturnError(errors.New("Error 1213: Deadlock found when trying to get lock"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id` > ?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow([]byte("3")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`) VALUES (?)")).
+		WithArgs([]byte("3")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var mu gosync.Mutex
+	var reported []int64
+	ctx := WithTableProgressReporter(context.Background(), func(tableName string, status TableSyncStatus, processed, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, processed)
+	})
+
+	processed, err := engine.copyTableInRanges(ctx, sourceDB, "shop", targetDB, "replica", mapping, ordersCopyPlan(), ranges, 2, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), processed)
+	assert.Equal(t, int64(3), reported[len(reported)-1])
+	assert.Equal(t, &CopyRange{Upper: "2", LastKey: []string{"2"}, ProcessedRows: 2, Done: true}, ranges[0])
+	assert.Equal(t, &CopyRange{Lower: "2", LastKey: []string{"3"}, ProcessedRows: 1, Done: true}, ranges[1])
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestCopyTableInRanges_Resume(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+	plan := ordersCopyPlan()
+	plan.resume = &TableCheckpoint{BatchNumber: 2}
+	ranges := []*CopyRange{
+		{Upper: "2", LastKey: []string{"2"}, ProcessedRows: 2, Done: true},
+		{Lower: "2", LastKey: []string{"3"}, ProcessedRows: 1},
+	}
+
+	// Only the unfinished range is copied, after its last key
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id`) > (?) AND (`id` > ?) ORDER BY `id` LIMIT 2")).
+		WithArgs(int64(3), int64(2)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow([]byte("4")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`) VALUES (?)")).
+		WithArgs([]byte("4")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	processed, err := engine.copyTableInRanges(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, plan, ranges, 2, 2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), processed)
+	assert.True(t, ranges[1].Done)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestLoadCopyCheckpoint_Ranges(t *testing.T) {
+	engine := newTestEngine()
+	mockRepo := new(MockRepository)
+	engine.checkpointManager = NewCheckpointManager(mockRepo, engine.logger)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+	ctx := context.Background()
+
+	checkpointData := `{"table_name":"orders","key_columns":["id"],"ranges":[{"upper":"2","processed_rows":0},{"lower":"%s","processed_rows":0}]}`
+	mockRepo.On("GetCheckpoint", ctx, "mapping-1").Return(&SyncCheckpoint{CheckpointData: fmt.Sprintf(checkpointData, "2")}, nil).Once()
+	checkpoint := engine.loadCopyCheckpoint(ctx, mapping)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, []*CopyRange{{Upper: "2"}, {Lower: "2"}}, checkpoint.Ranges)
+
+	// A checkpoint whose bounds aren't integers is not resumed
+	mockRepo.On("GetCheckpoint", ctx, "mapping-1").Return(&SyncCheckpoint{CheckpointData: fmt.Sprintf(checkpointData, "2) OR (1=1")}, nil).Once()
+	assert.Nil(t, engine.loadCopyCheckpoint(ctx, mapping))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCopyTableInRanges_PermanentFailure(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, _ := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders`")).
+		WillReturnError(errors.New("Error 1054: Unknown column 'id' in 'field list'"))
+
+	_, err := engine.copyTableInRanges(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, ordersCopyPlan(),
+		[]*CopyRange{{Lower: "2"}}, 2, 2, 4)
+	assert.ErrorContains(t, err, "failed to copy key range (2, ]")
+	assert.ErrorContains(t, err, "Unknown column")
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestCopyTableInRanges_SharesJobWorkerPool(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	sourceMock.MatchExpectationsInOrder(false)
+	targetMock.MatchExpectationsInOrder(false)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+	ranges := []*CopyRange{{Upper: "1"}, {Lower: "1", Upper: "2"}, {Lower: "2"}}
+	conditions := []string{"`id` <= ?", "`id` > ? AND `id` <= ?", "`id` > ?"}
+	bounds := [][]driver.Value{{int64(1)}, {int64(1), int64(2)}, {int64(2)}}
+	for i, condition := range conditions {
+		sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (" + condition + ") ORDER BY `id` LIMIT 2")).
+			WithArgs(bounds[i]...).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(i + 1)))
+		targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`) VALUES (?)")).
+			WithArgs(int64(i + 1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// The table holds one slot of the job's pool and another table holds the other, so no extra
+	// range worker gets a slot and the ranges are copied one by one on the table's own slot
+	pool := NewWorkerPool(2)
+	pool.Acquire()
+	pool.Acquire()
+	ctx := withJobWorkerPool(context.Background(), pool)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := engine.copyTableInRanges(ctx, sourceDB, "shop", targetDB, "replica", mapping, ordersCopyPlan(), ranges, 4, 2, 3)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("range copy waited for slots held by other tables")
+	}
+	for _, r := range ranges {
+		assert.True(t, r.Done)
+	}
+	assert.Len(t, pool.semaphore, 2)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
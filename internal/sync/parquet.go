@@ -0,0 +1,426 @@
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Parquet physical types, encodings and codecs, see parquet-format's parquet.thrift
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+	parquetUTF8     = 0
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUncompressed = 0
+	parquetGzip         = 2
+	parquetZstd         = 6
+
+	parquetDataPage = 0
+)
+
+// parquetRowGroupRows is the number of rows buffered before they are written as a row group
+const parquetRowGroupRows = 10000
+
+// parquetColumn is a column of a Parquet file. Every column is optional, a NULL is a missing value.
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32 // -1 when the column has no logical type
+}
+
+// parquetColumnFor returns the Parquet column storing an export column: integers as INT64, floats
+// as DOUBLE, binary columns as plain BYTE_ARRAY and everything else, including DECIMAL, dates and
+// BIGINT UNSIGNED, which INT64 can't hold, as UTF8 text
+func parquetColumnFor(col exportColumn) parquetColumn {
+	switch {
+	case col.kind == exportInteger && !isUnsignedBigint(col.columnType):
+		return parquetColumn{name: col.name, physicalType: parquetInt64, convertedType: -1}
+	case col.kind == exportFloat:
+		return parquetColumn{name: col.name, physicalType: parquetDouble, convertedType: -1}
+	case col.kind == exportBinary:
+		return parquetColumn{name: col.name, physicalType: parquetByteArray, convertedType: -1}
+	}
+	return parquetColumn{name: col.name, physicalType: parquetByteArray, convertedType: parquetUTF8}
+}
+
+// parquetColumnChunk is the metadata of a column chunk written to the file
+type parquetColumnChunk struct {
+	offset           int64
+	values           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// parquetRowGroup is the metadata of a row group written to the file
+type parquetRowGroup struct {
+	chunks []parquetColumnChunk
+	rows   int64
+	size   int64
+}
+
+// parquetWriter writes a Parquet file with one data page per column chunk, PLAIN encoded values and
+// RLE definition levels. It is the subset of the format every reader supports.
+type parquetWriter struct {
+	w         io.Writer
+	columns   []parquetColumn
+	codec     int32
+	zstd      *zstd.Encoder
+	offset    int64
+	rows      [][]interface{}
+	rowGroups []parquetRowGroup
+	numRows   int64
+}
+
+// newParquetWriter starts a Parquet file, compressing pages with gzip or zstd when asked
+func newParquetWriter(w io.Writer, columns []exportColumn, compression FileCompression) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w, codec: parquetUncompressed}
+	switch compression {
+	case FileCompressionGzip:
+		pw.codec = parquetGzip
+	case FileCompressionZstd:
+		pw.codec = parquetZstd
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to start zstd encoder: %w", err)
+		}
+		pw.zstd = encoder
+	}
+	for _, col := range columns {
+		pw.columns = append(pw.columns, parquetColumnFor(col))
+	}
+	if err := pw.write([]byte("PAR1")); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(data []byte) error {
+	n, err := pw.w.Write(data)
+	pw.offset += int64(n)
+	return err
+}
+
+func (pw *parquetWriter) writeRow(values []interface{}) error {
+	pw.rows = append(pw.rows, values)
+	if len(pw.rows) >= parquetRowGroupRows {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group
+func (pw *parquetWriter) flush() error {
+	if len(pw.rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: int64(len(pw.rows))}
+	for i, col := range pw.columns {
+		chunk, err := pw.writeColumnChunk(i, col)
+		if err != nil {
+			return fmt.Errorf("failed to write parquet column %s: %w", col.name, err)
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.uncompressedSize
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += group.rows
+	pw.rows = pw.rows[:0]
+	return nil
+}
+
+// writeColumnChunk writes the buffered values of a column as a single data page
+func (pw *parquetWriter) writeColumnChunk(index int, col parquetColumn) (parquetColumnChunk, error) {
+	defined := make([]bool, len(pw.rows))
+	var values bytes.Buffer
+	for i, row := range pw.rows {
+		if row[index] == nil {
+			continue
+		}
+		defined[i] = true
+		if err := encodeParquetValue(&values, col, row[index]); err != nil {
+			return parquetColumnChunk{}, err
+		}
+	}
+
+	levels := encodeDefinitionLevels(defined)
+	page := make([]byte, 4, 4+len(levels)+values.Len())
+	binary.LittleEndian.PutUint32(page, uint32(len(levels)))
+	page = append(append(page, levels...), values.Bytes()...)
+
+	body, err := pw.compress(page)
+	if err != nil {
+		return parquetColumnChunk{}, err
+	}
+
+	var header thriftWriter
+	header.i32(1, parquetDataPage)
+	header.i32(2, int32(len(page)))
+	header.i32(3, int32(len(body)))
+	header.beginStruct(5) // DataPageHeader
+	header.i32(1, int32(len(pw.rows)))
+	header.i32(2, parquetPlain)
+	header.i32(3, parquetRLE)
+	header.i32(4, parquetRLE)
+	header.endStruct()
+	header.stop()
+
+	chunk := parquetColumnChunk{
+		offset:           pw.offset,
+		values:           int64(len(pw.rows)),
+		uncompressedSize: int64(header.buf.Len() + len(page)),
+		compressedSize:   int64(header.buf.Len() + len(body)),
+	}
+	if err := pw.write(header.buf.Bytes()); err != nil {
+		return parquetColumnChunk{}, err
+	}
+	if err := pw.write(body); err != nil {
+		return parquetColumnChunk{}, err
+	}
+	return chunk, nil
+}
+
+// compress compresses a page with the codec of the file
+func (pw *parquetWriter) compress(page []byte) ([]byte, error) {
+	switch pw.codec {
+	case parquetGzip:
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(page); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return compressed.Bytes(), nil
+	case parquetZstd:
+		return pw.zstd.EncodeAll(page, nil), nil
+	}
+	return page, nil
+}
+
+// encodeParquetValue appends a value PLAIN encoded for its column
+func encodeParquetValue(buf *bytes.Buffer, col parquetColumn, value interface{}) error {
+	var scratch [8]byte
+	switch col.physicalType {
+	case parquetInt64:
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("unexpected integer value %T", value)
+		}
+		binary.LittleEndian.PutUint64(scratch[:], uint64(v))
+		buf.Write(scratch[:])
+	case parquetDouble:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("unexpected float value %T", value)
+		}
+		binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(v))
+		buf.Write(scratch[:])
+	default:
+		var data []byte
+		switch v := value.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		case int64:
+			data = []byte(strconv.FormatInt(v, 10))
+		case uint64:
+			data = []byte(strconv.FormatUint(v, 10))
+		case float64:
+			data = []byte(strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			return fmt.Errorf("unexpected value %T", value)
+		}
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(data)))
+		buf.Write(scratch[:4])
+		buf.Write(data)
+	}
+	return nil
+}
+
+// encodeDefinitionLevels encodes the definition levels of an optional column, 1 for a value and 0
+// for NULL, as a single bit-packed run of the RLE/bit-packing hybrid encoding
+func encodeDefinitionLevels(defined []bool) []byte {
+	groups := (len(defined) + 7) / 8
+	out := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, d := range defined {
+		if d {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(out, packed...)
+}
+
+// close writes the remaining rows and the file footer
+func (pw *parquetWriter) close() error {
+	if pw.zstd != nil {
+		defer pw.zstd.Close()
+	}
+	if err := pw.flush(); err != nil {
+		return err
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1) // version
+	meta.beginList(2, thriftStruct, len(pw.columns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endElement()
+	for _, col := range pw.columns {
+		meta.beginElement()
+		meta.i32(1, col.physicalType)
+		meta.i32(3, parquetOptional)
+		meta.binary(4, col.name)
+		if col.convertedType >= 0 {
+			meta.i32(6, col.convertedType)
+		}
+		meta.endElement()
+	}
+	meta.i64(3, pw.numRows)
+	meta.beginList(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		meta.beginElement()
+		meta.beginList(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := pw.columns[i]
+			meta.beginElement()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3) // ColumnMetaData
+			meta.i32(1, col.physicalType)
+			meta.beginList(2, thriftI32, 2)
+			meta.listI32(parquetPlain)
+			meta.listI32(parquetRLE)
+			meta.beginList(3, thriftBinary, 1)
+			meta.listBinary(col.name)
+			meta.i32(4, pw.codec)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.uncompressedSize)
+			meta.i64(7, chunk.compressedSize)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endElement()
+		}
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.endElement()
+	}
+	meta.binary(6, "db-taxi")
+	meta.stop()
+
+	footer := meta.buf.Bytes()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	if err := pw.write(length[:]); err != nil {
+		return err
+	}
+	return pw.write([]byte("PAR1"))
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol structs of Parquet metadata
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField int16
+	stack     []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) field(id int16, fieldType byte) {
+	if delta := id - t.lastField; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.varint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	t.lastField = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.listBinary(v)
+}
+
+func (t *thriftWriter) beginList(id int16, elementType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elementType)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elementType)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (t *thriftWriter) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// beginStruct starts a struct field, beginElement a struct element of a list
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) beginElement() {
+	t.stack = append(t.stack, t.lastField)
+	t.lastField = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElement()
+}
+
+func (t *thriftWriter) endElement() {
+	t.stop()
+	t.lastField = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop ends the fields of the current struct
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
@@ -0,0 +1,325 @@
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readParquetFile reads a Parquet file back without the writer's code: it decodes the Thrift compact
+// footer and page headers, then the RLE/bit-packed definition levels and PLAIN values of every page.
+// UTF8 columns are read as strings, other byte arrays as []byte and NULLs as nil.
+func readParquetFile(t *testing.T, data []byte) (names []string, rows [][]interface{}) {
+	t.Helper()
+	require.GreaterOrEqual(t, len(data), 12)
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{data: data[len(data)-8-footerLength : len(data)-8]}
+	meta := footer.readStruct()
+	require.NoError(t, footer.err)
+	require.Empty(t, footer.data, "footer has trailing bytes")
+
+	type column struct {
+		physicalType int64
+		utf8         bool
+	}
+	var columns []column
+	for _, element := range meta[2].([]interface{})[1:] {
+		field := element.(map[int16]interface{})
+		require.Equal(t, int64(parquetOptional), field[3])
+		names = append(names, string(field[4].([]byte)))
+		converted, ok := field[6]
+		columns = append(columns, column{physicalType: field[1].(int64), utf8: ok && converted == int64(parquetUTF8)})
+	}
+
+	for _, element := range meta[4].([]interface{}) {
+		group := element.(map[int16]interface{})
+		groupRows := int(group[3].(int64))
+		chunks := group[1].([]interface{})
+		require.Len(t, chunks, len(columns))
+		first := len(rows)
+		for i := 0; i < groupRows; i++ {
+			rows = append(rows, make([]interface{}, len(columns)))
+		}
+
+		for c, chunk := range chunks {
+			chunkMeta := chunk.(map[int16]interface{})[3].(map[int16]interface{})
+			require.Equal(t, columns[c].physicalType, chunkMeta[1])
+			require.Equal(t, names[c], string(chunkMeta[3].([]interface{})[0].([]byte)))
+			codec := chunkMeta[4].(int64)
+			total := int(chunkMeta[5].(int64))
+			offset := int(chunkMeta[9].(int64))
+			end := offset + int(chunkMeta[7].(int64))
+
+			row := first
+			for row-first < total {
+				header := &thriftReader{data: data[offset:end]}
+				page := header.readStruct()
+				require.NoError(t, header.err)
+				require.Equal(t, int64(parquetDataPage), page[1])
+				body := header.data[:page[3].(int64)]
+				offset = end - len(header.data) + len(body)
+				decoded := decompressParquetPage(t, codec, body)
+				require.Len(t, decoded, int(page[2].(int64)))
+
+				dataPage := page[5].(map[int16]interface{})
+				values := int(dataPage[1].(int64))
+				require.Equal(t, int64(parquetPlain), dataPage[2])
+				levelsLength := binary.LittleEndian.Uint32(decoded)
+				levels := decodeHybridLevels(t, decoded[4:4+levelsLength], values)
+				plain := decoded[4+levelsLength:]
+				for _, level := range levels {
+					if level == 1 {
+						var value interface{}
+						value, plain = decodePlainValue(t, columns[c].physicalType, columns[c].utf8, plain)
+						rows[row][c] = value
+					}
+					row++
+				}
+				require.Empty(t, plain, "page has trailing values")
+			}
+			require.Equal(t, end, offset, "column chunk size")
+		}
+	}
+	require.Equal(t, int64(len(rows)), meta[3])
+	return names, rows
+}
+
+func decompressParquetPage(t *testing.T, codec int64, body []byte) []byte {
+	switch codec {
+	case parquetUncompressed:
+		return body
+	case parquetGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return decoded
+	case parquetZstd:
+		decoder, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		defer decoder.Close()
+		decoded, err := decoder.DecodeAll(body, nil)
+		require.NoError(t, err)
+		return decoded
+	}
+	t.Fatalf("unexpected codec %d", codec)
+	return nil
+}
+
+// decodeHybridLevels decodes definition levels of bit width 1 in the RLE/bit-packing hybrid encoding
+func decodeHybridLevels(t *testing.T, data []byte, count int) []int {
+	var levels []int
+	for len(levels) < count {
+		header, n := binary.Uvarint(data)
+		require.Greater(t, n, 0)
+		data = data[n:]
+		if header&1 == 1 {
+			groups := int(header >> 1)
+			require.GreaterOrEqual(t, len(data), groups)
+			for i := 0; i < groups*8; i++ {
+				levels = append(levels, int(data[i/8]>>(i%8)&1))
+			}
+			data = data[groups:]
+		} else {
+			for i := 0; i < int(header>>1); i++ {
+				levels = append(levels, int(data[0]))
+			}
+			data = data[1:]
+		}
+	}
+	require.Empty(t, data)
+	return levels[:count]
+}
+
+func decodePlainValue(t *testing.T, physicalType int64, utf8 bool, data []byte) (interface{}, []byte) {
+	switch physicalType {
+	case parquetInt64:
+		return int64(binary.LittleEndian.Uint64(data)), data[8:]
+	case parquetDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), data[8:]
+	case parquetByteArray:
+		length := binary.LittleEndian.Uint32(data)
+		value := data[4 : 4+length]
+		if utf8 {
+			return string(value), data[4+length:]
+		}
+		return append([]byte{}, value...), data[4+length:]
+	}
+	t.Fatalf("unexpected physical type %d", physicalType)
+	return nil, nil
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps of field IDs to values: integers as
+// int64, binaries as []byte, lists as []interface{} and structs as maps
+type thriftReader struct {
+	data []byte
+	err  error
+}
+
+func (r *thriftReader) byte() byte {
+	if len(r.data) == 0 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("bad varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for r.err == nil {
+		b := r.byte()
+		if b == 0 {
+			break
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		last = id
+		fields[id] = r.readValue(b & 0x0f)
+	}
+	return fields
+}
+
+func (r *thriftReader) readValue(fieldType byte) interface{} {
+	switch fieldType {
+	case 1, 2: // Booleans in a struct field
+		return fieldType == 1
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		length := int(r.varint())
+		if length > len(r.data) {
+			r.err = io.ErrUnexpectedEOF
+			return nil
+		}
+		value := r.data[:length]
+		r.data = r.data[length:]
+		return value
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size && r.err == nil; i++ {
+			list = append(list, r.readValue(header&0x0f))
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	r.err = fmt.Errorf("unexpected thrift type %d", fieldType)
+	return nil
+}
+
+func TestParquetWriter_RoundTrip(t *testing.T) {
+	schema := ordersSchema()
+	schema.Columns = append(schema.Columns, &ColumnInfo{Name: "hits", Type: "bigint unsigned"})
+	columns := exportColumns([]string{"id", "note", "total", "photo", "shipped_on", "hits"}, schema)
+
+	// One more row than a row group holds, so the file has two, with a NULL in every column
+	var want [][]interface{}
+	for i := 0; i <= parquetRowGroupRows; i++ {
+		row := []interface{}{int64(i - 5), fmt.Sprintf("订单 %d", i), float64(i) / 4, []byte{byte(i), 0x00, 0xff}, "2024-03-01", uint64(math.MaxUint64)}
+		if i%3 == 1 {
+			row[i%len(row)] = nil
+		}
+		want = append(want, row)
+	}
+	// Unsigned BIGINT is stored as text
+	wantRead := make([][]interface{}, len(want))
+	for i, row := range want {
+		wantRead[i] = append([]interface{}{}, row...)
+		if row[5] != nil {
+			wantRead[i][5] = "18446744073709551615"
+		}
+	}
+
+	for _, compression := range []FileCompression{FileCompressionNone, FileCompressionGzip, FileCompressionZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			var buf bytes.Buffer
+			pw, err := newParquetWriter(&buf, columns, compression)
+			require.NoError(t, err)
+			for _, row := range want {
+				require.NoError(t, pw.writeRow(row))
+			}
+			require.NoError(t, pw.close())
+			assert.Len(t, pw.rowGroups, 2)
+
+			names, rows := readParquetFile(t, buf.Bytes())
+			assert.Equal(t, []string{"id", "note", "total", "photo", "shipped_on", "hits"}, names)
+			require.Len(t, rows, len(wantRead))
+			for i := range rows {
+				require.Equal(t, wantRead[i], rows[i], "row %d", i)
+			}
+		})
+	}
+
+	// An empty file has a footer and no row groups
+	var buf bytes.Buffer
+	pw, err := newParquetWriter(&buf, columns, FileCompressionZstd)
+	require.NoError(t, err)
+	require.NoError(t, pw.close())
+	names, rows := readParquetFile(t, buf.Bytes())
+	assert.Len(t, names, 6)
+	assert.Empty(t, rows)
+}
+
+func TestExportAll_ParquetZstd(t *testing.T) {
+	engine, export, sourceMock := newTableExport(t, FileOptions{Format: FileFormatParquet, Compression: FileCompressionZstd})
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders`")).WillReturnRows(exportOrdersRows())
+	_, err := engine.exportAll(context.Background(), export, mapping, nil)
+	require.NoError(t, err)
+
+	manifest, err := readExportManifest(export.dir)
+	require.NoError(t, err)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, ".parquet", filepath.Ext(manifest.Files[0].Name))
+	assert.Equal(t, FileCompressionZstd, manifest.Compression)
+
+	names, rows := readParquetFile(t, []byte(readExportFile(t, export, manifest.Files[0].Name)))
+	assert.Equal(t, []string{"id", "note", "total", "photo", "shipped_on"}, names)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), `say "hi", bye`, 9.5, []byte{0xff, 0x00}, "2024-03-01"},
+		{int64(2), "", nil, nil, nil},
+		{int64(3), "<b>&</b>", 1e-7, []byte("x"), "2024-03-02"},
+	}, rows)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
@@ -0,0 +1,250 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlanTable works out what syncing a table mapping would do. It reads the source and target the
+// way SyncFull, SyncIncremental and SyncCDC do, but creates, alters and writes nothing.
+func (e *DefaultSyncEngine) PlanTable(ctx context.Context, mapping *TableMapping) (*TablePlan, error) {
+	endpoints, err := e.connectConfigEndpoints(ctx, mapping.SyncConfigID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer endpoints.Close()
+
+	return e.planTable(ctx, endpoints, mapping)
+}
+
+// planTable plans a table mapping on open endpoints. What would make the sync of the table fail is
+// a problem of the plan, an error means the plan itself could not be worked out.
+func (e *DefaultSyncEngine) planTable(ctx context.Context, endpoints *syncEndpoints, mapping *TableMapping) (*TablePlan, error) {
+	plan := &TablePlan{
+		MappingID:   mapping.ID,
+		SourceTable: mapping.SourceTable,
+		TargetTable: mapping.TargetTable,
+		SyncMode:    mapping.SyncMode,
+		Load:        TableLoadFull,
+	}
+	options := endpoints.config.Options
+	sourceDB := endpoints.sourceDB
+
+	schema, err := e.getTableSchemaFromRemote(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table schema: %w", err)
+	}
+	mapping, err = e.resolveRowFilter(ctx, nil, mapping, schema)
+	if err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+		return plan, nil
+	}
+	columns := mapping.columnMapping()
+	targetSchema, err := columns.targetSchema(schema)
+	if err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+		return plan, nil
+	}
+	if _, err := newRowMasker(mapping, options, e.maskingSecret, schema, targetSchema); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+	if mapping.keepsTargetRows() {
+		if options != nil && options.ShadowSwap {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("write mode %s keeps the rows of the target table and cannot be used with shadow swap", mapping.WriteMode))
+		}
+		if _, err := e.resolveWritePolicy(ctx, sourceDB, mapping, options, targetSchema); err != nil {
+			plan.Problems = append(plan.Problems, err.Error())
+		}
+	}
+
+	// Condition selecting the rows the sync reads, those changed since the watermark for an incremental load
+	where, args := keyRangeCondition(nil, keyRange{}, mapping.rowFilter())
+	switch mapping.SyncMode {
+	case SyncModeFull:
+	case SyncModeIncremental:
+		if changedWhere, changedArgs, ok := e.planIncremental(ctx, sourceDB, mapping, columns, plan); ok {
+			where, args = changedWhere, changedArgs
+		}
+	case SyncModeCDC:
+		e.planCDC(ctx, sourceDB, mapping, columns, plan)
+	default:
+		plan.Problems = append(plan.Problems, fmt.Sprintf("unsupported sync mode: %s", mapping.SyncMode))
+		return plan, nil
+	}
+
+	if err := e.planTarget(ctx, endpoints, mapping, schema, targetSchema, plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Load != TableLoadCDC {
+		estimate, err := e.estimateTransferRows(ctx, endpoints, mapping, where, args)
+		if err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("Failed to estimate the rows to transfer: %v", err))
+		}
+		plan.EstimatedRows = estimate
+	}
+
+	return plan, nil
+}
+
+// planIncremental fills in the change tracking column and watermark of an incremental mapping and
+// returns the condition selecting the rows changed since the watermark; false when the next run
+// copies the whole table
+func (e *DefaultSyncEngine) planIncremental(ctx context.Context, sourceDB *sqlx.DB, mapping *TableMapping, columns *columnMapping, plan *TablePlan) (string, []interface{}, bool) {
+	checkpoint, err := e.repo.GetCheckpoint(ctx, mapping.ID)
+	initial := err != nil || checkpoint == nil || isCopyCheckpoint(checkpoint)
+
+	if mapping.DeleteDetection != DeleteDetectionNone {
+		if primaryKeys, err := e.getPrimaryKeyColumns(ctx, sourceDB, mapping.SourceTable); err != nil {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("delete detection requires a primary key: %v", err))
+		} else if _, ok := columns.targetColumns(primaryKeys); !ok {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("delete detection requires every primary key column, but column rules exclude one of %v", primaryKeys))
+		}
+	}
+
+	changeColumn, changeType, err := e.changeTrackingColumn(ctx, sourceDB, mapping)
+	if err != nil {
+		if !initial {
+			plan.Problems = append(plan.Problems, err.Error())
+			return "", nil, false
+		}
+		// The initial copy runs, but without a checkpoint every later run copies the table again
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%v, every run copies the whole table", err))
+		return "", nil, false
+	}
+	plan.ChangeColumn = changeColumn
+	plan.ChangeType = changeType
+	if changeType == "primary_key" {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("No timestamp or auto_increment column, changes are tracked by primary key (%s): only rows inserted with a larger key are synced", changeColumn))
+	}
+	if mapping.LookbackSeconds > 0 && changeType != "timestamp" {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("Look-back window ignored, changes are tracked by %s column %s", changeType, changeColumn))
+	}
+	if initial {
+		return "", nil, false
+	}
+
+	plan.Load = TableLoadIncremental
+	changed, err := e.changedRowsSince(ctx, sourceDB, mapping, changeColumn, changeType, checkpoint)
+	if err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+		return "", nil, false
+	}
+	plan.Watermark = changed.since
+	return changed.where, changed.args, true
+}
+
+// planCDC checks the source can feed a CDC mapping and fills in the binlog position it continues from
+func (e *DefaultSyncEngine) planCDC(ctx context.Context, sourceDB *sqlx.DB, mapping *TableMapping, columns *columnMapping, plan *TablePlan) {
+	if _, err := checkBinlogSource(ctx, sourceDB); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+	if err := checkCDCRowSelection(mapping); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+	if columns.hasExpressions() {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("CDC cannot compute expression columns of table %s, use a constant column or another sync mode", mapping.SourceTable))
+	}
+	if primaryKeys, err := e.getPrimaryKeyColumns(ctx, sourceDB, mapping.SourceTable); err != nil {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("CDC requires a primary key: %v", err))
+	} else if _, ok := columns.targetColumns(primaryKeys); !ok {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("CDC requires every primary key column, but column rules exclude one of %v", primaryKeys))
+	}
+
+	position, err := e.loadCDCPosition(ctx, mapping)
+	if err == nil && position != nil {
+		plan.Load = TableLoadCDC
+		plan.Watermark = position.String()
+	}
+}
+
+// planTarget works out what happens to the target table before it's loaded, following
+// prepareFullCopyTarget for a full copy and ensureTargetTableExistsInDB otherwise
+func (e *DefaultSyncEngine) planTarget(ctx context.Context, endpoints *syncEndpoints, mapping *TableMapping, schema, targetSchema *TableSchema, plan *TablePlan) error {
+	targetDB, targetDBName := endpoints.targetDB, endpoints.targetDBName
+	if endpoints.targetDBMissing {
+		plan.DDL = append(plan.DDL, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", targetDBName))
+	}
+
+	loadMapping := mapping
+	rebuild, truncate := false, false
+	if plan.Load == TableLoadFull {
+		if endpoints.config.Options != nil && endpoints.config.Options.ShadowSwap {
+			shadowTable, err := shadowTableName(mapping.TargetTable)
+			if err != nil {
+				plan.Problems = append(plan.Problems, err.Error())
+				return nil
+			}
+			shadowMapping := *mapping
+			shadowMapping.TargetTable = shadowTable
+			loadMapping = &shadowMapping
+			rebuild = true
+		}
+
+		// The initial copy of a CDC mapping never resumes, see syncCDCInitial
+		copyPlan, err := e.planFullCopy(ctx, endpoints.sourceDB, targetDB, endpoints.dialect, targetDBName, loadMapping, schema, mapping.SyncMode != SyncModeCDC)
+		if err != nil {
+			return err
+		}
+		if copyPlan.resume != nil {
+			plan.Load = TableLoadResume
+		} else {
+			truncate = !mapping.keepsTargetRows()
+		}
+	}
+
+	if !rebuild && !endpoints.targetDBMissing {
+		exists, err := endpoints.dialect.TableExists(ctx, targetDB, targetDBName, loadMapping.TargetTable)
+		if err != nil {
+			return err
+		}
+		if exists {
+			e.planAlterTarget(ctx, endpoints, loadMapping, targetSchema, truncate, plan)
+			return nil
+		}
+	}
+
+	translated, notes := e.translateForTarget(ctx, targetDB, targetSchema)
+	plan.Warnings = append(plan.Warnings, notes...)
+	plan.Action = TableActionCreate
+	if rebuild {
+		plan.Action = TableActionRecreate
+		plan.DDL = append(plan.DDL, "DROP TABLE IF EXISTS "+endpoints.dialect.TableName(targetDBName, loadMapping.TargetTable))
+	}
+	plan.DDL = append(plan.DDL, endpoints.dialect.CreateTableStatement(targetDBName, loadMapping.TargetTable, translated))
+	return nil
+}
+
+// planAlterTarget fills in the schema changes evolveTargetSchema would make to an existing target table
+func (e *DefaultSyncEngine) planAlterTarget(ctx context.Context, endpoints *syncEndpoints, mapping *TableMapping, targetSchema *TableSchema, truncate bool, plan *TablePlan) {
+	plan.Action = TableActionNone
+	evolution, err := e.planSchemaEvolution(ctx, endpoints.targetDB, endpoints.targetDBName, mapping, targetSchema, truncate)
+	if err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+		return
+	}
+	plan.Warnings = append(plan.Warnings, evolution.notes...)
+	plan.Truncate = truncate
+
+	for _, change := range evolution.changes {
+		if !evolution.skips(change) {
+			plan.Action = TableActionAlter
+			plan.DDL = append(plan.DDL, change.statement)
+			continue
+		}
+		plan.SkippedDDL = append(plan.SkippedDDL, change.statement)
+		// Rows of the source column may not fit the target column the policy keeps
+		if change.retype {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("target column differs from the source and the additive schema policy keeps it: %s", change.statement))
+		}
+	}
+}
+
+// estimateTransferRows returns the optimizer's estimate of the rows a sync reads from the source,
+// those the WHERE clause selects
+func (e *DefaultSyncEngine) estimateTransferRows(ctx context.Context, endpoints *syncEndpoints, mapping *TableMapping, where string, args []interface{}) (int64, error) {
+	query := fmt.Sprintf("SELECT 1 FROM `%s`.`%s`%s", endpoints.sourceDBName, mapping.SourceTable, where)
+	return explainRowEstimate(ctx, endpoints.sourceDB, query, args...)
+}
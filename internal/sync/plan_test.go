@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newPlanTest returns a plan test's engine with the shop source and replica target endpoints.
+// No statement is expected to run, so any write of the plan fails the test.
+func newPlanTest(t *testing.T, options *SyncOptions) (*DefaultSyncEngine, *MockRepository, *syncEndpoints, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mockRepo := new(MockRepository)
+	engine.repo = mockRepo
+	endpoints := &syncEndpoints{
+		config:       &SyncConfig{ID: "config-1", Options: options},
+		sourceDB:     sourceDB,
+		sourceDBName: "shop",
+		targetDB:     targetDB,
+		targetDBName: "replica",
+		dialect:      mysqlDialect{},
+	}
+	return engine, mockRepo, endpoints, sourceMock, targetMock
+}
+
+func TestPlanTable_FullCopyCreatesTable(t *testing.T) {
+	engine, _, endpoints, sourceMock, targetMock := newPlanTest(t, nil)
+	engine.allowRawWhereClause = true
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeFull, WhereClause: "status = 'paid'"}
+
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note"), targetIndexRows())
+	expectPrimaryKey(sourceMock, "id")
+	targetMock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLES").WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+	expectTargetServer(targetMock, "5.7.44-log", 1)
+	sourceMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM `shop`.`orders` WHERE (status = 'paid')")).
+		WillReturnRows(explainRowsEstimate(1200))
+
+	plan, err := engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, TableLoadFull, plan.Load)
+	assert.Equal(t, TableActionCreate, plan.Action)
+	require.Len(t, plan.DDL, 1)
+	assert.Contains(t, plan.DDL[0], "CREATE TABLE `replica`.`orders`")
+	assert.Equal(t, int64(1200), plan.EstimatedRows)
+	assert.Empty(t, plan.Problems)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPlanTable_ShadowSwapRecreatesShadowTable(t *testing.T) {
+	engine, _, endpoints, sourceMock, targetMock := newPlanTest(t, &SyncOptions{ShadowSwap: true})
+	endpoints.targetDBMissing = true
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeFull}
+
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note"), targetIndexRows())
+	expectPrimaryKey(sourceMock, "id")
+	expectTargetServer(targetMock, "8.0.36", 1)
+	sourceMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM `shop`.`orders`")).WillReturnRows(explainRowsEstimate(10))
+
+	plan, err := engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, TableActionRecreate, plan.Action)
+	require.Len(t, plan.DDL, 3)
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS `replica`", plan.DDL[0])
+	assert.Equal(t, "DROP TABLE IF EXISTS `replica`.`orders__dbtaxi_new`", plan.DDL[1])
+	assert.Contains(t, plan.DDL[2], "CREATE TABLE `replica`.`orders__dbtaxi_new`")
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPlanTable_IncrementalAltersTable(t *testing.T) {
+	engine, mockRepo, endpoints, sourceMock, targetMock := newPlanTest(t, nil)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeIncremental}
+
+	mockRepo.On("GetCheckpoint", mock.Anything, "mapping-1").Return(&SyncCheckpoint{LastSyncValue: "41"}, nil)
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note").
+		AddRow("created_at", "datetime", "NO", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED", nil, nil), targetIndexRows())
+	sourceMock.ExpectQuery("modified_at").WithArgs("orders").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}))
+	sourceMock.ExpectQuery("auto_increment").WithArgs("orders").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	expectKeyColumnTypes(sourceMock, "id", "bigint")
+	targetMock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLES").WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+	expectTargetSchema(targetMock,
+		targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("note", "varchar(64)", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci"),
+		targetIndexRows())
+	sourceMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM `shop`.`orders` WHERE (`id`) > (?)")).WithArgs(int64(41)).
+		WillReturnRows(explainRowsEstimate(7))
+
+	plan, err := engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, TableLoadIncremental, plan.Load)
+	assert.Equal(t, "id", plan.ChangeColumn)
+	assert.Equal(t, "auto_increment", plan.ChangeType)
+	assert.Equal(t, "41", plan.Watermark)
+	assert.Equal(t, int64(7), plan.EstimatedRows)
+	assert.False(t, plan.Truncate)
+
+	// The new column is added, the narrower column is kept and reported
+	assert.Equal(t, TableActionAlter, plan.Action)
+	require.Len(t, plan.DDL, 1)
+	assert.Contains(t, plan.DDL[0], "ADD COLUMN `created_at`")
+	assert.Equal(t, []string{"ALTER TABLE `replica`.`orders` MODIFY COLUMN `note` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"}, plan.SkippedDDL)
+	require.Len(t, plan.Problems, 1)
+	assert.Contains(t, plan.Problems[0], "additive schema policy keeps it")
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestPlanTable_CDCProblems(t *testing.T) {
+	engine, mockRepo, endpoints, sourceMock, targetMock := newPlanTest(t, nil)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeCDC}
+
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note"), sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NON_UNIQUE", "INDEX_TYPE"}))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT @@global.binlog_format")).
+		WillReturnRows(sqlmock.NewRows([]string{"@@global.binlog_format"}).AddRow("MIXED"))
+	expectPrimaryKey(sourceMock)
+	mockRepo.On("GetCheckpoint", mock.Anything, "mapping-1").Return((*SyncCheckpoint)(nil), nil)
+	expectPrimaryKey(sourceMock)
+	targetMock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLES").WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+	expectTargetServer(targetMock, "8.0.36", 1)
+	sourceMock.ExpectQuery("EXPLAIN SELECT 1").WillReturnRows(explainRowsEstimate(3))
+
+	plan, err := engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, TableLoadFull, plan.Load) // No binlog position recorded yet
+	require.Len(t, plan.Problems, 2)
+	assert.Contains(t, plan.Problems[0], "binlog_format=ROW")
+	assert.Contains(t, plan.Problems[1], "CDC requires a primary key")
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestSyncManagerService_PlanSync(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockEngine := new(MockSyncEngine)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	manager := &SyncManagerService{Service: NewService(mockRepo, logger, nil), syncEngine: mockEngine}
+	ctx := context.Background()
+
+	orders := &TableMapping{ID: "m-1", SourceTable: "orders", TargetTable: "orders", Enabled: true}
+	archive := &TableMapping{ID: "m-2", SourceTable: "archive", TargetTable: "archive"}
+	users := &TableMapping{ID: "m-3", SourceTable: "users", TargetTable: "users", Enabled: true}
+	mockRepo.On("GetSyncConfig", ctx, "config-1").Return(&SyncConfig{ID: "config-1", Enabled: true,
+		Tables: []*TableMapping{orders, archive, users}}, nil)
+	mockEngine.On("PlanTable", ctx, orders).Return(&TablePlan{MappingID: "m-1", Action: TableActionNone}, nil)
+	mockEngine.On("PlanTable", ctx, users).Return(nil, errors.New("failed to get table schema: table users not found"))
+
+	// Disabled mappings are left out, a table that can't be planned blocks the job
+	plan, err := manager.PlanSync(ctx, "config-1")
+	require.NoError(t, err)
+	require.Len(t, plan.Tables, 2)
+	assert.Equal(t, "m-1", plan.Tables[0].MappingID)
+	assert.Equal(t, "users", plan.Tables[1].SourceTable)
+	assert.Equal(t, []string{"failed to get table schema: table users not found"}, plan.Tables[1].Problems)
+	assert.True(t, plan.Blocked)
+	mockEngine.AssertNotCalled(t, "PlanTable", ctx, archive)
+}
@@ -19,3 +19,56 @@ func ReportTableProgress(ctx context.Context, tableName string, status TableSync
 		r(tableName, status, processedRows, totalRows)
 	}
 }
+
+type deleteContextKey struct{}
+
+// TableDeleteReporter is called after the delete detection pass of an incremental sync with the number
+// of orphaned target rows that were deleted, soft-deleted or, in report mode, only found.
+type TableDeleteReporter func(tableName string, mode DeleteDetection, deletedRows int64)
+
+// WithTableDeleteReporter returns a context that carries the given delete reporter.
+func WithTableDeleteReporter(ctx context.Context, reporter TableDeleteReporter) context.Context {
+	return context.WithValue(ctx, deleteContextKey{}, reporter)
+}
+
+// ReportTableDeletes calls the delete reporter from ctx if present; no-op otherwise.
+func ReportTableDeletes(ctx context.Context, tableName string, mode DeleteDetection, deletedRows int64) {
+	if r, ok := ctx.Value(deleteContextKey{}).(TableDeleteReporter); ok && r != nil {
+		r(tableName, mode, deletedRows)
+	}
+}
+
+type batchSizeContextKey struct{}
+
+// TableBatchSizeReporter is called with every batch size adaptive batch sizing chooses for a table.
+type TableBatchSizeReporter func(tableName string, batchSize int)
+
+// WithTableBatchSizeReporter returns a context that carries the given batch size reporter.
+func WithTableBatchSizeReporter(ctx context.Context, reporter TableBatchSizeReporter) context.Context {
+	return context.WithValue(ctx, batchSizeContextKey{}, reporter)
+}
+
+// ReportTableBatchSize calls the batch size reporter from ctx if present; no-op otherwise.
+func ReportTableBatchSize(ctx context.Context, tableName string, batchSize int) {
+	if r, ok := ctx.Value(batchSizeContextKey{}).(TableBatchSizeReporter); ok && r != nil {
+		r(tableName, batchSize)
+	}
+}
+
+type schemaChangeContextKey struct{}
+
+// TableSchemaChangeReporter is called for every DDL statement schema evolution applied to a target
+// table, and for destructive statements it skipped because the schema policy doesn't allow them.
+type TableSchemaChangeReporter func(tableName, statement string, applied bool)
+
+// WithTableSchemaChangeReporter returns a context that carries the given schema change reporter.
+func WithTableSchemaChangeReporter(ctx context.Context, reporter TableSchemaChangeReporter) context.Context {
+	return context.WithValue(ctx, schemaChangeContextKey{}, reporter)
+}
+
+// ReportTableSchemaChange calls the schema change reporter from ctx if present; no-op otherwise.
+func ReportTableSchemaChange(ctx context.Context, tableName, statement string, applied bool) {
+	if r, ok := ctx.Value(schemaChangeContextKey{}).(TableSchemaChangeReporter); ok && r != nil {
+		r(tableName, statement, applied)
+	}
+}
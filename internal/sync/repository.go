@@ -5,22 +5,26 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 )
 
 // MySQLRepository implements the Repository interface using MySQL
 type MySQLRepository struct {
-	db     *sqlx.DB
-	logger *logrus.Logger
+	db      *sqlx.DB
+	dialect Dialect
+	logger  *logrus.Logger
 }
 
 // NewMySQLRepository creates a new MySQL repository instance
 func NewMySQLRepository(db *sqlx.DB, logger *logrus.Logger) Repository {
 	return &MySQLRepository{
-		db:     db,
-		logger: logger,
+		db:      db,
+		dialect: localDialect,
+		logger:  logger,
 	}
 }
 
@@ -29,19 +33,22 @@ func NewMySQLRepository(db *sqlx.DB, logger *logrus.Logger) Repository {
 func (r *MySQLRepository) CreateConnection(ctx context.Context, config *ConnectionConfig) error {
 	// Use a map to ensure proper field mapping for named parameters
 	params := map[string]interface{}{
-		"id":            config.ID,
-		"name":          config.Name,
-		"host":          config.Host,
-		"port":          config.Port,
-		"username":      config.Username,
-		"password":      config.Password,
-		"database_name": config.Database,
-		"ssl":           config.SSL,
+		"id":              config.ID,
+		"name":            config.Name,
+		"connection_type": connectionType(config),
+		"host":            config.Host,
+		"port":            config.Port,
+		"username":        config.Username,
+		"password":        config.Password,
+		"database_name":   config.Database,
+		"ssl":             config.SSL,
+		"path":            config.Path,
+		"file_options":    config.FileOptions,
 	}
 
 	query := `
-		INSERT INTO connections (id, name, host, port, username, password, database_name, ` + "`ssl`" + `)
-		VALUES (:id, :name, :host, :port, :username, :password, :database_name, :ssl)
+		INSERT INTO connections (id, name, connection_type, host, port, username, password, database_name, ` + r.dialect.QuoteIdentifier("ssl") + `, path, file_options)
+		VALUES (:id, :name, :connection_type, :host, :port, :username, :password, :database_name, :ssl, :path, :file_options)
 	`
 	_, err := r.db.NamedExecContext(ctx, query, params)
 	if err != nil {
@@ -53,7 +60,7 @@ func (r *MySQLRepository) CreateConnection(ctx context.Context, config *Connecti
 
 func (r *MySQLRepository) GetConnection(ctx context.Context, id string) (*ConnectionConfig, error) {
 	var config ConnectionConfig
-	query := "SELECT id, name, host, port, username, password, database_name, " + "`ssl`" + ", created_at, updated_at FROM connections WHERE id = ?"
+	query := "SELECT id, name, connection_type, host, port, username, password, database_name, " + r.dialect.QuoteIdentifier("ssl") + ", path, file_options, created_at, updated_at FROM connections WHERE id = ?"
 	err := r.db.GetContext(ctx, &config, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -67,7 +74,7 @@ func (r *MySQLRepository) GetConnection(ctx context.Context, id string) (*Connec
 
 func (r *MySQLRepository) GetConnections(ctx context.Context) ([]*ConnectionConfig, error) {
 	var configs []*ConnectionConfig
-	query := "SELECT id, name, host, port, username, password, database_name, " + "`ssl`" + ", created_at, updated_at FROM connections ORDER BY created_at DESC"
+	query := "SELECT id, name, connection_type, host, port, username, password, database_name, " + r.dialect.QuoteIdentifier("ssl") + ", path, file_options, created_at, updated_at FROM connections ORDER BY created_at DESC"
 	err := r.db.SelectContext(ctx, &configs, query)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get connections")
@@ -79,21 +86,24 @@ func (r *MySQLRepository) GetConnections(ctx context.Context) ([]*ConnectionConf
 func (r *MySQLRepository) UpdateConnection(ctx context.Context, id string, config *ConnectionConfig) error {
 	// Use a map to ensure proper field mapping for named parameters
 	params := map[string]interface{}{
-		"id":            id,
-		"name":          config.Name,
-		"host":          config.Host,
-		"port":          config.Port,
-		"username":      config.Username,
-		"password":      config.Password,
-		"database_name": config.Database,
-		"ssl":           config.SSL,
+		"id":              id,
+		"name":            config.Name,
+		"connection_type": connectionType(config),
+		"host":            config.Host,
+		"port":            config.Port,
+		"username":        config.Username,
+		"password":        config.Password,
+		"database_name":   config.Database,
+		"ssl":             config.SSL,
+		"path":            config.Path,
+		"file_options":    config.FileOptions,
 	}
 
 	query := `
 		UPDATE connections 
-		SET name = :name, host = :host, port = :port, username = :username, 
+		SET name = :name, connection_type = :connection_type, host = :host, port = :port, username = :username, 
 		    password = :password, database_name = :database_name, 
-		    ` + "`ssl`" + ` = :ssl, updated_at = CURRENT_TIMESTAMP
+		    ` + r.dialect.QuoteIdentifier("ssl") + ` = :ssl, path = :path, file_options = :file_options, updated_at = CURRENT_TIMESTAMP
 		WHERE id = :id
 	`
 
@@ -114,6 +124,14 @@ func (r *MySQLRepository) UpdateConnection(ctx context.Context, id string, confi
 	return nil
 }
 
+// connectionType returns the type stored for a connection, MySQL when none is given
+func connectionType(config *ConnectionConfig) ConnectionType {
+	if config.Type == "" {
+		return ConnectionTypeMySQL
+	}
+	return config.Type
+}
+
 func (r *MySQLRepository) DeleteConnection(ctx context.Context, id string) error {
 	query := `DELETE FROM connections WHERE id = ?`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -163,11 +181,11 @@ func (r *MySQLRepository) GetSyncConfig(ctx context.Context, id string) (*SyncCo
 	var config SyncConfig
 	var optionsJSON sql.NullString
 
-	query := `SELECT id, source_connection_id, target_connection_id, source_database, target_database, name, sync_mode, schedule, enabled, options, created_at, updated_at 
+	query := `SELECT id, source_connection_id, target_connection_id, source_database, target_database, name, sync_mode, schedule, enabled, options, last_run_at, next_run_at, created_at, updated_at 
 	          FROM sync_configs WHERE id = ?`
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&config.ID, &config.SourceConnectionID, &config.TargetConnectionID, &config.SourceDatabase, &config.TargetDatabase, &config.Name, &config.SyncMode,
-		&config.Schedule, &config.Enabled, &optionsJSON, &config.CreatedAt, &config.UpdatedAt,
+		&config.Schedule, &config.Enabled, &optionsJSON, &config.LastRunAt, &config.NextRunAt, &config.CreatedAt, &config.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -199,15 +217,38 @@ func (r *MySQLRepository) GetSyncConfig(ctx context.Context, id string) (*SyncCo
 }
 
 func (r *MySQLRepository) GetSyncConfigs(ctx context.Context, connectionID string) ([]*SyncConfig, error) {
-	var configs []*SyncConfig
-	query := `SELECT id, source_connection_id, target_connection_id, source_database, target_database, name, sync_mode, schedule, enabled, options, created_at, updated_at 
+	query := `SELECT id, source_connection_id, target_connection_id, source_database, target_database, name, sync_mode, schedule, enabled, options, last_run_at, next_run_at, created_at, updated_at 
 	          FROM sync_configs WHERE source_connection_id = ? OR target_connection_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, connectionID, connectionID)
+	configs, err := r.querySyncConfigs(ctx, query, connectionID, connectionID)
 	if err != nil {
 		r.logger.WithError(err).WithField("connection_id", connectionID).Error("Failed to get sync configs")
 		return nil, fmt.Errorf("failed to get sync configs: %w", err)
 	}
+	return configs, nil
+}
+
+// GetAllSyncConfigs returns every sync config regardless of connection, used by the scheduler
+func (r *MySQLRepository) GetAllSyncConfigs(ctx context.Context) ([]*SyncConfig, error) {
+	query := `SELECT id, source_connection_id, target_connection_id, source_database, target_database, name, sync_mode, schedule, enabled, options, last_run_at, next_run_at, created_at, updated_at 
+	          FROM sync_configs ORDER BY created_at DESC`
+
+	configs, err := r.querySyncConfigs(ctx, query)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get all sync configs")
+		return nil, fmt.Errorf("failed to get all sync configs: %w", err)
+	}
+	return configs, nil
+}
+
+// querySyncConfigs scans sync config rows selected with the standard column list and loads their table mappings
+func (r *MySQLRepository) querySyncConfigs(ctx context.Context, query string, args ...interface{}) ([]*SyncConfig, error) {
+	var configs []*SyncConfig
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	for rows.Next() {
@@ -215,7 +256,7 @@ func (r *MySQLRepository) GetSyncConfigs(ctx context.Context, connectionID strin
 		var optionsJSON sql.NullString
 
 		err := rows.Scan(&config.ID, &config.SourceConnectionID, &config.TargetConnectionID, &config.SourceDatabase, &config.TargetDatabase, &config.Name, &config.SyncMode,
-			&config.Schedule, &config.Enabled, &optionsJSON, &config.CreatedAt, &config.UpdatedAt)
+			&config.Schedule, &config.Enabled, &optionsJSON, &config.LastRunAt, &config.NextRunAt, &config.CreatedAt, &config.UpdatedAt)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan sync config")
 			continue
@@ -279,6 +320,17 @@ func (r *MySQLRepository) UpdateSyncConfig(ctx context.Context, id string, confi
 	return nil
 }
 
+// UpdateSyncConfigRunTimes records the scheduler's last and next run times for a sync config
+func (r *MySQLRepository) UpdateSyncConfigRunTimes(ctx context.Context, id string, lastRunAt, nextRunAt *time.Time) error {
+	// updated_at is kept as is: run times are scheduler state, not a config change
+	query := `UPDATE sync_configs SET last_run_at = ?, next_run_at = ?, updated_at = updated_at WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, lastRunAt, nextRunAt, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to update sync config run times")
+		return fmt.Errorf("failed to update sync config run times: %w", err)
+	}
+	return nil
+}
+
 func (r *MySQLRepository) DeleteSyncConfig(ctx context.Context, id string) error {
 	query := `DELETE FROM sync_configs WHERE id = ?`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -302,14 +354,33 @@ func (r *MySQLRepository) DeleteSyncConfig(ctx context.Context, id string) error
 
 func (r *MySQLRepository) CreateTableMapping(ctx context.Context, mapping *TableMapping) error {
 	query := `
-		INSERT INTO table_mappings (id, sync_config_id, source_table, target_table, sync_mode, enabled, where_clause, sort_order)
-		VALUES (:id, :sync_config_id, :source_table, :target_table, :sync_mode, :enabled, :where_clause, :sort_order)
+		INSERT INTO table_mappings (id, sync_config_id, source_table, target_table, sync_mode, enabled, where_clause, row_filter, sort_order,
+		                            delete_detection, soft_delete_column, schema_policy, approve_schema_changes,
+		                            tracking_column, lookback_seconds, write_mode, update_columns, file_source)
+		VALUES (:id, :sync_config_id, :source_table, :target_table, :sync_mode, :enabled, :where_clause, :row_filter, :sort_order,
+		        :delete_detection, :soft_delete_column, :schema_policy, :approve_schema_changes,
+		        :tracking_column, :lookback_seconds, :write_mode, :update_columns, :file_source)
 	`
-	_, err := r.db.NamedExecContext(ctx, query, mapping)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, query, mapping); err != nil {
 		r.logger.WithError(err).Error("Failed to create table mapping")
 		return fmt.Errorf("failed to create table mapping: %w", err)
 	}
+	if err := r.replaceColumnRules(ctx, tx, mapping.ID, mapping.ColumnRules); err != nil {
+		return err
+	}
+	if err := r.replaceMaskingRules(ctx, tx, mapping.ID, mapping.MaskingRules); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit table mapping: %w", err)
+	}
 	return nil
 }
 
@@ -321,6 +392,50 @@ func (r *MySQLRepository) GetTableMappings(ctx context.Context, syncConfigID str
 		r.logger.WithError(err).WithField("sync_config_id", syncConfigID).Error("Failed to get table mappings")
 		return nil, fmt.Errorf("failed to get table mappings: %w", err)
 	}
+	if len(mappings) == 0 {
+		return mappings, nil
+	}
+	markConcurrentMappings(mappings)
+
+	var rules []*ColumnRule
+	rulesQuery := `
+		SELECT r.* FROM column_mapping_rules r
+		JOIN table_mappings m ON m.id = r.table_mapping_id
+		WHERE m.sync_config_id = ?
+		ORDER BY r.table_mapping_id, r.sort_order ASC
+	`
+	if err := r.db.SelectContext(ctx, &rules, rulesQuery, syncConfigID); err != nil {
+		r.logger.WithError(err).WithField("sync_config_id", syncConfigID).Error("Failed to get column rules")
+		return nil, fmt.Errorf("failed to get column rules: %w", err)
+	}
+
+	var maskingRules []*MaskingRule
+	maskingQuery := `
+		SELECT r.* FROM column_masking_rules r
+		JOIN table_mappings m ON m.id = r.table_mapping_id
+		WHERE m.sync_config_id = ?
+		ORDER BY r.table_mapping_id, r.sort_order ASC
+	`
+	if err := r.db.SelectContext(ctx, &maskingRules, maskingQuery, syncConfigID); err != nil {
+		r.logger.WithError(err).WithField("sync_config_id", syncConfigID).Error("Failed to get masking rules")
+		return nil, fmt.Errorf("failed to get masking rules: %w", err)
+	}
+
+	byMapping := make(map[string]*TableMapping, len(mappings))
+	for _, mapping := range mappings {
+		byMapping[mapping.ID] = mapping
+	}
+	for _, rule := range rules {
+		if mapping, ok := byMapping[rule.TableMappingID]; ok {
+			mapping.ColumnRules = append(mapping.ColumnRules, rule)
+		}
+	}
+	for _, rule := range maskingRules {
+		if mapping, ok := byMapping[rule.TableMappingID]; ok {
+			mapping.MaskingRules = append(mapping.MaskingRules, rule)
+		}
+	}
+
 	return mappings, nil
 }
 
@@ -328,11 +443,23 @@ func (r *MySQLRepository) UpdateTableMapping(ctx context.Context, id string, map
 	query := `
 		UPDATE table_mappings 
 		SET source_table = :source_table, target_table = :target_table, sync_mode = :sync_mode, 
-		    enabled = :enabled, where_clause = :where_clause, sort_order = :sort_order, updated_at = CURRENT_TIMESTAMP
+		    enabled = :enabled, where_clause = :where_clause, row_filter = :row_filter, sort_order = :sort_order,
+		    delete_detection = :delete_detection, soft_delete_column = :soft_delete_column,
+		    schema_policy = :schema_policy, approve_schema_changes = :approve_schema_changes,
+		    tracking_column = :tracking_column, lookback_seconds = :lookback_seconds,
+		    write_mode = :write_mode, update_columns = :update_columns, file_source = :file_source,
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = :id
 	`
 	mapping.ID = id
-	result, err := r.db.NamedExecContext(ctx, query, mapping)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.NamedExecContext(ctx, query, mapping)
 	if err != nil {
 		r.logger.WithError(err).WithField("id", id).Error("Failed to update table mapping")
 		return fmt.Errorf("failed to update table mapping: %w", err)
@@ -346,6 +473,67 @@ func (r *MySQLRepository) UpdateTableMapping(ctx context.Context, id string, map
 		return fmt.Errorf("table mapping not found: %s", id)
 	}
 
+	// Callers that don't edit column rules leave them untouched
+	if mapping.ColumnRules != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM column_mapping_rules WHERE table_mapping_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete column rules: %w", err)
+		}
+		if err := r.replaceColumnRules(ctx, tx, id, mapping.ColumnRules); err != nil {
+			return err
+		}
+	}
+	if mapping.MaskingRules != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM column_masking_rules WHERE table_mapping_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete masking rules: %w", err)
+		}
+		if err := r.replaceMaskingRules(ctx, tx, id, mapping.MaskingRules); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit table mapping: %w", err)
+	}
+	return nil
+}
+
+// replaceMaskingRules inserts the masking rules of a table mapping in their list order
+func (r *MySQLRepository) replaceMaskingRules(ctx context.Context, tx *sqlx.Tx, tableMappingID string, rules []*MaskingRule) error {
+	query := `
+		INSERT INTO column_masking_rules (id, table_mapping_id, column_name, strategy, replacement, keep_first, keep_last, shift_days, sort_order)
+		VALUES (:id, :table_mapping_id, :column_name, :strategy, :replacement, :keep_first, :keep_last, :shift_days, :sort_order)
+	`
+	for i, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		rule.TableMappingID = tableMappingID
+		rule.SortOrder = i
+		if _, err := tx.NamedExecContext(ctx, query, rule); err != nil {
+			r.logger.WithError(err).WithField("table_mapping_id", tableMappingID).Error("Failed to create masking rule")
+			return fmt.Errorf("failed to create masking rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceColumnRules inserts the column rules of a table mapping in their list order
+func (r *MySQLRepository) replaceColumnRules(ctx context.Context, tx *sqlx.Tx, tableMappingID string, rules []*ColumnRule) error {
+	query := `
+		INSERT INTO column_mapping_rules (id, table_mapping_id, rule_type, source_column, target_column, value, column_type, sort_order)
+		VALUES (:id, :table_mapping_id, :rule_type, :source_column, :target_column, :value, :column_type, :sort_order)
+	`
+	for i, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		rule.TableMappingID = tableMappingID
+		rule.SortOrder = i
+		if _, err := tx.NamedExecContext(ctx, query, rule); err != nil {
+			r.logger.WithError(err).WithField("table_mapping_id", tableMappingID).Error("Failed to create column rule")
+			return fmt.Errorf("failed to create column rule: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -372,9 +560,12 @@ func (r *MySQLRepository) DeleteTableMapping(ctx context.Context, id string) err
 
 func (r *MySQLRepository) CreateSyncJob(ctx context.Context, job *SyncJob) error {
 	query := `
-		INSERT INTO sync_jobs (id, config_id, status, start_time, total_tables, completed_tables, total_rows, processed_rows, error_message)
-		VALUES (:id, :config_id, :status, :start_time, :total_tables, :completed_tables, :total_rows, :processed_rows, :error_message)
+		INSERT INTO sync_jobs (id, config_id, status, start_time, total_tables, completed_tables, total_rows, processed_rows, error_message, job_type, verify_repair)
+		VALUES (:id, :config_id, :status, :start_time, :total_tables, :completed_tables, :total_rows, :processed_rows, :error_message, :job_type, :verify_repair)
 	`
+	if job.Type == "" {
+		job.Type = JobTypeSync
+	}
 	_, err := r.db.NamedExecContext(ctx, query, job)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to create sync job")
@@ -459,7 +650,7 @@ func (r *MySQLRepository) GetJobHistory(ctx context.Context, limit, offset int)
 		var job SyncJob
 		err := rows.Scan(&job.ID, &job.ConfigID, &job.Status, &job.StartTime, &job.EndTime,
 			&job.TotalTables, &job.CompletedTables, &job.TotalRows, &job.ProcessedRows,
-			&job.Error, &job.CreatedAt, &h.ConfigName, &h.ConnectionName)
+			&job.Error, &job.CreatedAt, &job.Type, &job.Repair, &h.ConfigName, &h.ConnectionName)
 		if err != nil {
 			r.logger.WithError(err).Error("Failed to scan job history")
 			continue
@@ -483,18 +674,89 @@ func (r *MySQLRepository) GetJobsByStatus(ctx context.Context, status JobStatus)
 	return jobs, nil
 }
 
+// Verify report operations
+
+func (r *MySQLRepository) CreateVerifyReport(ctx context.Context, report *TableVerifyReport) error {
+	query := `
+		INSERT INTO table_verify_reports (id, job_id, table_mapping_id, source_table, target_table, status, chunks, mismatched_chunks,
+		                                  source_rows, target_rows, missing_rows, extra_rows, different_rows, repaired_rows,
+		                                  skipped_columns, repair_sql, repair_truncated, error_message, started_at, finished_at)
+		VALUES (:id, :job_id, :table_mapping_id, :source_table, :target_table, :status, :chunks, :mismatched_chunks,
+		        :source_rows, :target_rows, :missing_rows, :extra_rows, :different_rows, :repaired_rows,
+		        :skipped_columns, :repair_sql, :repair_truncated, :error_message, :started_at, :finished_at)
+	`
+	if report.ID == "" {
+		report.ID = uuid.New().String()
+	}
+	_, err := r.db.NamedExecContext(ctx, query, report)
+	if err != nil {
+		r.logger.WithError(err).WithField("job_id", report.JobID).Error("Failed to create verify report")
+		return fmt.Errorf("failed to create verify report: %w", err)
+	}
+	return nil
+}
+
+func (r *MySQLRepository) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	var reports []*TableVerifyReport
+	query := `
+		SELECT id, job_id, table_mapping_id, source_table, target_table, status, chunks, mismatched_chunks,
+		       source_rows, target_rows, missing_rows, extra_rows, different_rows, repaired_rows,
+		       COALESCE(skipped_columns, '') AS skipped_columns, COALESCE(repair_sql, '') AS repair_sql, repair_truncated,
+		       COALESCE(error_message, '') AS error_message, started_at, finished_at
+		FROM table_verify_reports
+		WHERE job_id = ?
+		ORDER BY started_at
+	`
+	if err := r.db.SelectContext(ctx, &reports, query, jobID); err != nil {
+		r.logger.WithError(err).WithField("job_id", jobID).Error("Failed to get verify reports")
+		return nil, fmt.Errorf("failed to get verify reports: %w", err)
+	}
+	return reports, nil
+}
+
+// Imported file operations
+
+// RecordImportedFile records a file a table mapping loaded, replacing the record of an earlier load
+// of the same content
+func (r *MySQLRepository) RecordImportedFile(ctx context.Context, file *ImportedFile) error {
+	query := `
+		INSERT INTO imported_files (table_mapping_id, file_name, sha256, file_size, loaded_rows, rejected_rows, job_id, imported_at)
+		VALUES (:table_mapping_id, :file_name, :sha256, :file_size, :loaded_rows, :rejected_rows, :job_id, :imported_at)
+	` + r.dialect.UpdateClause([]string{"table_mapping_id", "file_name", "sha256"},
+		[]string{"file_size", "loaded_rows", "rejected_rows", "job_id", "imported_at"}, "")
+	if _, err := r.db.NamedExecContext(ctx, query, file); err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"table_mapping_id": file.TableMappingID,
+			"file_name":        file.FileName,
+		}).Error("Failed to record imported file")
+		return fmt.Errorf("failed to record imported file: %w", err)
+	}
+	return nil
+}
+
+func (r *MySQLRepository) GetImportedFiles(ctx context.Context, tableMappingID string) ([]*ImportedFile, error) {
+	var files []*ImportedFile
+	query := `
+		SELECT table_mapping_id, file_name, sha256, file_size, loaded_rows, rejected_rows, job_id, imported_at
+		FROM imported_files
+		WHERE table_mapping_id = ?
+		ORDER BY file_name, imported_at
+	`
+	if err := r.db.SelectContext(ctx, &files, query, tableMappingID); err != nil {
+		r.logger.WithError(err).WithField("table_mapping_id", tableMappingID).Error("Failed to get imported files")
+		return nil, fmt.Errorf("failed to get imported files: %w", err)
+	}
+	return files, nil
+}
+
 // Checkpoint operations
 
 func (r *MySQLRepository) CreateCheckpoint(ctx context.Context, checkpoint *SyncCheckpoint) error {
 	query := `
 		INSERT INTO sync_checkpoints (id, table_mapping_id, last_sync_time, last_sync_value, checkpoint_data)
 		VALUES (:id, :table_mapping_id, :last_sync_time, :last_sync_value, :checkpoint_data)
-		ON DUPLICATE KEY UPDATE
-		last_sync_time = VALUES(last_sync_time),
-		last_sync_value = VALUES(last_sync_value),
-		checkpoint_data = VALUES(checkpoint_data),
-		updated_at = CURRENT_TIMESTAMP
-	`
+	` + r.dialect.UpdateClause([]string{"table_mapping_id"},
+		[]string{"last_sync_time", "last_sync_value", "checkpoint_data"}, "") + ", updated_at = CURRENT_TIMESTAMP"
 	_, err := r.db.NamedExecContext(ctx, query, checkpoint)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to create checkpoint")
@@ -509,7 +771,7 @@ func (r *MySQLRepository) GetCheckpoint(ctx context.Context, tableMappingID stri
 	err := r.db.GetContext(ctx, &checkpoint, query, tableMappingID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("checkpoint not found for table mapping: %s", tableMappingID)
+			return nil, fmt.Errorf("%w for table mapping: %s", ErrCheckpointNotFound, tableMappingID)
 		}
 		r.logger.WithError(err).WithField("table_mapping_id", tableMappingID).Error("Failed to get checkpoint")
 		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
@@ -574,10 +836,7 @@ func (r *MySQLRepository) CreateDatabaseMapping(ctx context.Context, mapping *Da
 	query := `
 		INSERT INTO database_mappings (remote_connection_id, local_database_name)
 		VALUES (:remote_connection_id, :local_database_name)
-		ON DUPLICATE KEY UPDATE
-		local_database_name = VALUES(local_database_name),
-		created_at = CURRENT_TIMESTAMP
-	`
+	` + r.dialect.UpdateClause([]string{"remote_connection_id", "local_database_name"}, []string{"local_database_name"}, "") + ", created_at = CURRENT_TIMESTAMP"
 	_, err := r.db.NamedExecContext(ctx, query, mapping)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to create database mapping")
@@ -0,0 +1,314 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlCondition is a WHERE condition and the arguments bound to its placeholders
+type sqlCondition struct {
+	clause string
+	args   []interface{}
+}
+
+// and returns both conditions combined, either may be empty
+func (c sqlCondition) and(other sqlCondition) sqlCondition {
+	switch {
+	case other.clause == "":
+		return c
+	case c.clause == "":
+		return other
+	}
+	args := append(append([]interface{}{}, c.args...), other.args...)
+	return sqlCondition{clause: "(" + c.clause + ") AND (" + other.clause + ")", args: args}
+}
+
+// where returns the condition as a WHERE clause, empty when there is no condition
+func (c sqlCondition) where() string {
+	if c.clause == "" {
+		return ""
+	}
+	return " WHERE " + c.clause
+}
+
+// rowFilter returns the condition selecting the source rows a mapping syncs: the filter resolved
+// for the current run, or the raw where clause of a mapping that wasn't resolved
+func (m *TableMapping) rowFilter() sqlCondition {
+	if m.filter != nil {
+		return *m.filter
+	}
+	return sqlCondition{clause: m.WhereClause}
+}
+
+// filterTemplate matches a template variable value, e.g. {{now - 30d}} or {{last_sync_time}}
+var filterTemplate = regexp.MustCompile(`^\{\{\s*(now|job_start|last_sync_time)\s*(?:([+-])\s*(\d+)\s*([smhdw]))?\s*\}\}$`)
+
+// filterUnits are the units a template variable offset may use
+var filterUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// filterVariables are the values of the template variables of a row filter
+type filterVariables struct {
+	now          time.Time
+	jobStart     time.Time
+	lastSyncTime time.Time // Zero before the first incremental sync, rendered as the Unix epoch
+}
+
+// validate checks the structure of a row filter without a table schema, as when it's saved
+func (f *RowFilter) validate() error {
+	if len(f.Conditions) > 0 || f.Logic != "" {
+		if f.Column != "" || f.Operator != "" || f.Operand != nil {
+			return fmt.Errorf("row filter group cannot have a column, operator or value")
+		}
+		if f.Logic != "" && f.Logic != FilterLogicAnd && f.Logic != FilterLogicOr {
+			return fmt.Errorf("invalid row filter logic: %s", f.Logic)
+		}
+		if len(f.Conditions) == 0 {
+			return fmt.Errorf("row filter group requires conditions")
+		}
+		for _, condition := range f.Conditions {
+			if condition == nil {
+				return fmt.Errorf("row filter condition cannot be empty")
+			}
+			if err := condition.validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !isValidMySQLIdentifier(f.Column) {
+		return fmt.Errorf("invalid row filter column: %s", f.Column)
+	}
+	_, err := f.render(map[string]bool{strings.ToLower(f.Column): true}, filterVariables{})
+	return err
+}
+
+// usesVariable reports whether a row filter refers to a template variable
+func (f *RowFilter) usesVariable(name string) bool {
+	for _, condition := range f.Conditions {
+		if condition != nil && condition.usesVariable(name) {
+			return true
+		}
+	}
+	values, _ := f.Operand.([]interface{})
+	for _, value := range append(values, f.Operand) {
+		if text, ok := value.(string); ok {
+			if m := filterTemplate.FindStringSubmatch(text); m != nil && m[1] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render renders a row filter as a condition with bind parameters. Columns must be among the
+// source columns, given lower case.
+func (f *RowFilter) render(columns map[string]bool, vars filterVariables) (sqlCondition, error) {
+	if len(f.Conditions) > 0 {
+		separator := " AND "
+		if f.Logic == FilterLogicOr {
+			separator = " OR "
+		}
+		var clauses []string
+		var args []interface{}
+		for _, member := range f.Conditions {
+			if member == nil {
+				return sqlCondition{}, fmt.Errorf("row filter condition cannot be empty")
+			}
+			condition, err := member.render(columns, vars)
+			if err != nil {
+				return sqlCondition{}, err
+			}
+			clauses = append(clauses, "("+condition.clause+")")
+			args = append(args, condition.args...)
+		}
+		return sqlCondition{clause: strings.Join(clauses, separator), args: args}, nil
+	}
+
+	if !columns[strings.ToLower(f.Column)] {
+		return sqlCondition{}, fmt.Errorf("row filter column %s not found in source table", f.Column)
+	}
+	column := fmt.Sprintf("`%s`", f.Column)
+
+	switch f.Operator {
+	case FilterOpIsNull, FilterOpIsNotNull:
+		if f.Operand != nil {
+			return sqlCondition{}, fmt.Errorf("row filter operator %s takes no value", f.Operator)
+		}
+		if f.Operator == FilterOpIsNull {
+			return sqlCondition{clause: column + " IS NULL"}, nil
+		}
+		return sqlCondition{clause: column + " IS NOT NULL"}, nil
+
+	case FilterOpIn, FilterOpNotIn, FilterOpBetween:
+		values, ok := f.Operand.([]interface{})
+		if !ok || len(values) == 0 {
+			return sqlCondition{}, fmt.Errorf("row filter operator %s requires a list value", f.Operator)
+		}
+		if f.Operator == FilterOpBetween && len(values) != 2 {
+			return sqlCondition{}, fmt.Errorf("row filter operator between requires a lower and an upper bound")
+		}
+		args := make([]interface{}, len(values))
+		for i, value := range values {
+			arg, err := filterArg(value, vars)
+			if err != nil {
+				return sqlCondition{}, err
+			}
+			args[i] = arg
+		}
+		switch f.Operator {
+		case FilterOpIn:
+			return sqlCondition{clause: fmt.Sprintf("%s IN (%s)", column, keyPlaceholders(len(args))), args: args}, nil
+		case FilterOpNotIn:
+			return sqlCondition{clause: fmt.Sprintf("%s NOT IN (%s)", column, keyPlaceholders(len(args))), args: args}, nil
+		}
+		return sqlCondition{clause: column + " BETWEEN ? AND ?", args: args}, nil
+	}
+
+	comparisons := map[FilterOperator]string{
+		FilterOpEqual:        "=",
+		FilterOpNotEqual:     "<>",
+		FilterOpGreater:      ">",
+		FilterOpGreaterEqual: ">=",
+		FilterOpLess:         "<",
+		FilterOpLessEqual:    "<=",
+		FilterOpLike:         "LIKE",
+		FilterOpNotLike:      "NOT LIKE",
+	}
+	comparison, ok := comparisons[f.Operator]
+	if !ok {
+		return sqlCondition{}, fmt.Errorf("invalid row filter operator: %s", f.Operator)
+	}
+	if f.Operand == nil {
+		return sqlCondition{}, fmt.Errorf("row filter operator %s requires a value, use is_null to match NULL", f.Operator)
+	}
+	arg, err := filterArg(f.Operand, vars)
+	if err != nil {
+		return sqlCondition{}, err
+	}
+	return sqlCondition{clause: fmt.Sprintf("%s %s ?", column, comparison), args: []interface{}{arg}}, nil
+}
+
+// filterArg converts a row filter value decoded from JSON into a bind argument, resolving
+// template variables. Whole numbers are bound as integers, which MySQL compares exactly.
+func filterArg(value interface{}, vars filterVariables) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		m := filterTemplate.FindStringSubmatch(v)
+		if m == nil {
+			return nil, fmt.Errorf("invalid row filter template: %s", v)
+		}
+		t := vars.now
+		switch m[1] {
+		case "job_start":
+			t = vars.jobStart
+		case "last_sync_time":
+			t = vars.lastSyncTime
+			if t.IsZero() {
+				t = time.Unix(0, 0)
+			}
+		}
+		if m[2] != "" {
+			n, err := strconv.Atoi(m[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid row filter template: %s", v)
+			}
+			offset := time.Duration(n) * filterUnits[m[4]]
+			if m[2] == "-" {
+				offset = -offset
+			}
+			t = t.Add(offset)
+		}
+		return t, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	case bool, json.Number:
+		return v, nil
+	}
+	return nil, fmt.Errorf("invalid row filter value: %v", value)
+}
+
+// resolveRowFilter returns a copy of the mapping whose row filter, and where clause when the
+// configuration allows one, are rendered against the source schema for a sync run. The job's
+// start and the mapping's last incremental sync are the values of the template variables.
+func (e *DefaultSyncEngine) resolveRowFilter(ctx context.Context, job *SyncJob, mapping *TableMapping, schema *TableSchema) (*TableMapping, error) {
+	if mapping.WhereClause != "" && !e.allowRawWhereClause {
+		return nil, fmt.Errorf("raw where clause of table %s is not allowed, use a row filter or set sync.allow_raw_where_clause", mapping.SourceTable)
+	}
+
+	filter := sqlCondition{clause: mapping.WhereClause}
+	if mapping.RowFilter != nil {
+		vars := filterVariables{now: time.Now()}
+		vars.jobStart = vars.now
+		if job != nil && !job.StartTime.IsZero() {
+			vars.jobStart = job.StartTime
+		}
+		if mapping.RowFilter.usesVariable("last_sync_time") {
+			// Before the first sync there is no checkpoint and every row is after the last sync
+			checkpoint, err := e.repo.GetCheckpoint(ctx, mapping.ID)
+			if err != nil && !errors.Is(err, ErrCheckpointNotFound) {
+				return nil, fmt.Errorf("failed to load checkpoint for row filter: %w", err)
+			}
+			if checkpoint != nil && !isCopyCheckpoint(checkpoint) {
+				vars.lastSyncTime = checkpoint.LastSyncTime
+			}
+		}
+
+		columns := make(map[string]bool, len(schema.Columns))
+		for _, col := range schema.Columns {
+			columns[strings.ToLower(col.Name)] = true
+		}
+		rendered, err := mapping.RowFilter.render(columns, vars)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row filter of table %s: %w", mapping.SourceTable, err)
+		}
+		filter = filter.and(rendered)
+	}
+
+	resolved := *mapping
+	resolved.filter = &filter
+	return &resolved, nil
+}
+
+// Value stores a row filter as JSON
+func (f RowFilter) Value() (driver.Value, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal row filter: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan reads a row filter stored as JSON
+func (f *RowFilter) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported row filter type: %T", src)
+	}
+	return json.Unmarshal(data, f)
+}
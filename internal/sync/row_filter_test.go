@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeRowFilter(t *testing.T, data string) *RowFilter {
+	var filter RowFilter
+	require.NoError(t, json.Unmarshal([]byte(data), &filter))
+	return &filter
+}
+
+func TestResolveRowFilter_RendersGroupsWithBindParameters(t *testing.T) {
+	engine, _, _, _, _ := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", RowFilter: decodeRowFilter(t, `{
+		"conditions": [
+			{"column": "status", "operator": "in", "value": ["paid", "shipped"]},
+			{"column": "total", "operator": "between", "value": [10, 99.5]},
+			{"logic": "or", "conditions": [
+				{"column": "deleted_at", "operator": "is_null"},
+				{"column": "region", "operator": "not_like", "value": "eu-%' OR 1=1 --"}
+			]}
+		]
+	}`)}
+
+	resolved, err := engine.resolveRowFilter(context.Background(), &SyncJob{}, mapping, ordersSchema())
+	require.NoError(t, err)
+	assert.Equal(t, sqlCondition{
+		clause: "(`status` IN (?, ?)) AND (`total` BETWEEN ? AND ?) AND ((`deleted_at` IS NULL) OR (`region` NOT LIKE ?))",
+		args:   []interface{}{"paid", "shipped", int64(10), 99.5, "eu-%' OR 1=1 --"},
+	}, resolved.rowFilter())
+
+	// The stored mapping is left as it is
+	assert.Nil(t, mapping.filter)
+	assert.Equal(t, sqlCondition{}, mapping.rowFilter())
+}
+
+func TestResolveRowFilter_TemplateVariables(t *testing.T) {
+	engine, _, _, _, _ := newMockEngine(t)
+	mockRepo := new(MockRepository)
+	engine.repo = mockRepo
+	lastSync := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	jobStart := time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC)
+	mockRepo.On("GetCheckpoint", mock.Anything, "mapping-1").Return(&SyncCheckpoint{LastSyncTime: lastSync, LastSyncValue: "41"}, nil)
+
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", RowFilter: &RowFilter{Conditions: []*RowFilter{
+		{Column: "updated_at", Operator: FilterOpGreaterEqual, Operand: "{{ last_sync_time - 1h }}"},
+		{Column: "updated_at", Operator: FilterOpLess, Operand: "{{job_start}}"},
+		{Column: "updated_at", Operator: FilterOpGreater, Operand: "{{now - 30d}}"},
+	}}}
+
+	before := time.Now()
+	resolved, err := engine.resolveRowFilter(context.Background(), &SyncJob{StartTime: jobStart}, mapping, ordersSchema())
+	require.NoError(t, err)
+	filter := resolved.rowFilter()
+	require.Len(t, filter.args, 3)
+	assert.Equal(t, lastSync.Add(-time.Hour), filter.args[0])
+	assert.Equal(t, jobStart, filter.args[1])
+	assert.WithinDuration(t, before.Add(-30*24*time.Hour), filter.args[2].(time.Time), time.Minute)
+	mockRepo.AssertExpectations(t)
+
+	// Before the first incremental sync the repository has no checkpoint and every row is after the last sync
+	metaDB, metaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer metaDB.Close()
+	engine.repo = NewMySQLRepository(sqlx.NewDb(metaDB, "sqlmock"), engine.logger)
+	checkpointQuery := regexp.QuoteMeta("SELECT * FROM sync_checkpoints WHERE table_mapping_id = ?")
+	metaMock.ExpectQuery(checkpointQuery).WithArgs("mapping-1").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	resolved, err = engine.resolveRowFilter(context.Background(), nil, mapping, ordersSchema())
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(0, 0).Add(-time.Hour), resolved.rowFilter().args[0])
+
+	// Other failures to read the checkpoint fail the sync
+	metaMock.ExpectQuery(checkpointQuery).WithArgs("mapping-1").WillReturnError(assert.AnError)
+	_, err = engine.resolveRowFilter(context.Background(), nil, mapping, ordersSchema())
+	assert.ErrorContains(t, err, "failed to load checkpoint for row filter")
+	assert.NoError(t, metaMock.ExpectationsWereMet())
+}
+
+func TestResolveRowFilter_Rejects(t *testing.T) {
+	engine, _, _, _, _ := newMockEngine(t)
+	schema := ordersSchema()
+
+	tests := []struct {
+		name    string
+		mapping *TableMapping
+		err     string
+	}{
+		{"unknown column", &TableMapping{SourceTable: "orders", RowFilter: &RowFilter{Column: "status) OR (1", Operator: FilterOpEqual, Operand: "paid"}},
+			"row filter column status) OR (1 not found in source table"},
+		{"unknown template", &TableMapping{SourceTable: "orders", RowFilter: &RowFilter{Column: "updated_at", Operator: FilterOpGreater, Operand: "{{yesterday}}"}},
+			"invalid row filter template: {{yesterday}}"},
+		{"between needs two bounds", &TableMapping{SourceTable: "orders", RowFilter: &RowFilter{Column: "total", Operator: FilterOpBetween, Operand: []interface{}{1.0}}},
+			"requires a lower and an upper bound"},
+		{"null comparison", &TableMapping{SourceTable: "orders", RowFilter: &RowFilter{Column: "status", Operator: FilterOpEqual}},
+			"use is_null to match NULL"},
+		{"raw where clause", &TableMapping{SourceTable: "orders", WhereClause: "1=1"},
+			"raw where clause of table orders is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.resolveRowFilter(context.Background(), nil, tt.mapping, schema)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	// An allowed raw clause is combined with the row filter
+	engine.allowRawWhereClause = true
+	resolved, err := engine.resolveRowFilter(context.Background(), nil, &TableMapping{SourceTable: "orders", WhereClause: "total > 0",
+		RowFilter: &RowFilter{Column: "status", Operator: FilterOpNotEqual, Operand: "draft"}}, schema)
+	require.NoError(t, err)
+	assert.Equal(t, sqlCondition{clause: "(total > 0) AND (`status` <> ?)", args: []interface{}{"draft"}}, resolved.rowFilter())
+}
+
+func TestRowFilter_StoredAsJSON(t *testing.T) {
+	filter := decodeRowFilter(t, `{"logic": "or", "conditions": [{"column": "status", "operator": "eq", "value": "paid"}, {"column": "total", "operator": "gt", "value": 100}]}`)
+	require.NoError(t, filter.validate())
+
+	value, err := filter.Value()
+	require.NoError(t, err)
+	var stored RowFilter
+	require.NoError(t, stored.Scan([]byte(value.(string))))
+	assert.Equal(t, filter, &stored)
+
+	assert.Error(t, (&RowFilter{Logic: "xor", Conditions: filter.Conditions}).validate())
+	assert.Error(t, (&RowFilter{Column: "status", Operator: "regexp", Operand: ".*"}).validate())
+	assert.Error(t, validateRowSelection(&TableMapping{WhereClause: "status = 'paid'"}, false))
+	assert.NoError(t, validateRowSelection(&TableMapping{WhereClause: "status = 'paid'"}, true))
+	assert.ErrorContains(t, validateRowSelection(&TableMapping{SyncMode: SyncModeCDC, WhereClause: "status = 'paid'"}, true), "not supported by cdc sync")
+	assert.ErrorContains(t, validateRowSelection(&TableMapping{SyncMode: SyncModeCDC, RowFilter: &RowFilter{Column: "status", Operator: FilterOpEqual, Operand: "paid"}}, true), "not supported by cdc sync")
+}
+
+func TestPlanTable_RowFilter(t *testing.T) {
+	engine, _, endpoints, sourceMock, targetMock := newPlanTest(t, nil)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeFull,
+		RowFilter: &RowFilter{Column: "note", Operator: FilterOpLike, Operand: "vip%"}}
+
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note"), targetIndexRows())
+	expectPrimaryKey(sourceMock, "id")
+	targetMock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLES").WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+	expectTargetServer(targetMock, "8.0.36", 1)
+	sourceMock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM `shop`.`orders` WHERE (`note` LIKE ?)")).WithArgs("vip%").
+		WillReturnRows(explainRowsEstimate(40))
+
+	plan, err := engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), plan.EstimatedRows)
+	assert.Empty(t, plan.Problems)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+
+	// A raw where clause is a problem of the plan unless the configuration allows it
+	mapping = &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SyncMode: SyncModeFull, WhereClause: "1=1"}
+	expectTableSchema(sourceMock, ordersColumnRows("id", "note"), targetIndexRows())
+	plan, err = engine.planTable(context.Background(), endpoints, mapping)
+	require.NoError(t, err)
+	require.Len(t, plan.Problems, 1)
+	assert.Contains(t, plan.Problems[0], "sync.allow_raw_where_clause")
+}
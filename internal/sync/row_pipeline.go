@@ -0,0 +1,251 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// pipelineDepth is how many batches a row pipeline holds at once: one per stage, plus one queued
+// between the reader and the transform stage
+const pipelineDepth = 4
+
+// rowBatch is a batch of rows read from the source, each row holding its values in column order.
+// Its buffers are reused by the batches read after it has been written.
+type rowBatch struct {
+	columns []string
+	rows    [][]interface{}
+	values  []interface{} // Backing array of rows
+	dest    []interface{} // Scan destinations of a row
+	last    []interface{} // Source key of the last row, read before a masked key column is rewritten
+	done    bool          // The source has no rows after this batch
+}
+
+// reset empties the batch for rows of columns, keeping its buffers
+func (b *rowBatch) reset(columns []string) {
+	b.columns = columns
+	b.rows = b.rows[:0]
+	b.values = b.values[:0]
+	b.last = nil
+	if cap(b.dest) < len(columns) {
+		b.dest = make([]interface{}, len(columns))
+	}
+	b.dest = b.dest[:len(columns)]
+}
+
+// scan appends the current row of rows to the batch
+func (b *rowBatch) scan(rows *sqlx.Rows) error {
+	width := len(b.columns)
+	start := len(b.values)
+	if start+width > cap(b.values) {
+		// Rows already read keep the filled array, the following ones go to a larger one
+		b.values = make([]interface{}, 0, 2*cap(b.values)+64*width)
+		start = 0
+	}
+	b.values = b.values[:start+width]
+	row := b.values[start : start+width : start+width]
+	for i := range row {
+		row[i] = nil
+		b.dest[i] = &row[i]
+	}
+	if err := rows.Scan(b.dest...); err != nil {
+		b.values = b.values[:start]
+		return fmt.Errorf("failed to scan row: %w", err)
+	}
+	b.rows = append(b.rows, row)
+	return nil
+}
+
+// key returns the values of the key columns of the last row, typed for binding
+func (b *rowBatch) key(keyColumns, keyTypes []string) []interface{} {
+	row := b.rows[len(b.rows)-1]
+	key := make([]interface{}, len(keyColumns))
+	for i, keyColumn := range keyColumns {
+		for j, col := range b.columns {
+			if col == keyColumn {
+				key[i] = bindKeyValue(keyTypes[i], row[j])
+			}
+		}
+	}
+	return key
+}
+
+// pipelineStages are the stages of a row pipeline
+type pipelineStages struct {
+	read      func(ctx context.Context, batch *rowBatch) (bool, error) // Fills a batch, false once the source has no more rows
+	transform func(batch *rowBatch) error                              // Rewrites the rows of a batch before they are written
+	write     func(ctx context.Context, batch *rowBatch) error         // Writes a batch, called with an empty batch when the source ends on a full one
+}
+
+// runRowPipeline reads, transforms and writes batches concurrently, each stage in its own goroutine
+// and connected to the next by a bounded channel. Batches are written in the order they are read.
+// Once pipelineDepth batches are in flight a stage that falls behind blocks the stages before it,
+// and the reader waits while memory usage is high. The first error of a stage stops the others.
+func (e *DefaultSyncEngine) runRowPipeline(ctx context.Context, stages pipelineStages) error {
+	pipelineCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	free := make(chan *rowBatch, pipelineDepth)
+	for i := 0; i < pipelineDepth; i++ {
+		free <- &rowBatch{}
+	}
+	read := make(chan *rowBatch, 1)
+	transformed := make(chan *rowBatch)
+
+	var once sync.Once
+	var pipelineErr error
+	fail := func(err error) {
+		once.Do(func() {
+			pipelineErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		defer close(read)
+		for {
+			var batch *rowBatch
+			select {
+			case batch = <-free:
+			case <-pipelineCtx.Done():
+				return
+			}
+			if err := e.waitForMemory(pipelineCtx); err != nil {
+				fail(err)
+				return
+			}
+
+			more, err := stages.read(pipelineCtx, batch)
+			if err != nil {
+				fail(err)
+				return
+			}
+			batch.done = !more
+			if len(batch.rows) > 0 || batch.done {
+				select {
+				case read <- batch:
+				case <-pipelineCtx.Done():
+					return
+				}
+			}
+			if !more {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		defer close(transformed)
+		for batch := range read {
+			if err := stages.transform(batch); err != nil {
+				fail(err)
+				return
+			}
+			select {
+			case transformed <- batch:
+			case <-pipelineCtx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for batch := range transformed {
+			if err := stages.write(pipelineCtx, batch); err != nil {
+				fail(err)
+				return
+			}
+			free <- batch
+		}
+	}()
+
+	wg.Wait()
+	if pipelineErr == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return pipelineErr
+}
+
+// memoryMonitor returns the memory monitor of the engine's batch processor, nil without one
+func (e *DefaultSyncEngine) memoryMonitor() *MemoryMonitor {
+	if e.batchProcessor == nil {
+		return nil
+	}
+	return e.batchProcessor.memoryMonitor
+}
+
+// waitForMemory pauses while memory usage is high, to let the garbage collector free the batches
+// already written
+func (e *DefaultSyncEngine) waitForMemory(ctx context.Context) error {
+	monitor := e.memoryMonitor()
+	for monitor != nil && monitor.ShouldPause() {
+		e.logger.Warn("Memory usage high, pausing to allow GC")
+		runtime.GC()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
+
+// readBatch reads the rows of a bounded query into a batch
+func readBatch(ctx context.Context, db *sqlx.DB, query string, args []interface{}, batch *rowBatch) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query source data: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column names: %w", err)
+	}
+	batch.reset(columns)
+	for rows.Next() {
+		if err := batch.scan(rows); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read source data: %w", err)
+	}
+	return nil
+}
+
+// cursorReader returns the read stage of a pipeline reading the rows of an open query with columns
+func cursorReader(rows *sqlx.Rows, columns []string, sizer *batchSizer, batchSize int) func(ctx context.Context, batch *rowBatch) (bool, error) {
+	return func(ctx context.Context, batch *rowBatch) (bool, error) {
+		batch.reset(columns)
+		size := sizer.next(batchSize)
+		for len(batch.rows) < size {
+			if !rows.Next() {
+				if err := rows.Err(); err != nil {
+					return false, fmt.Errorf("failed to read source data: %w", err)
+				}
+				return false, nil
+			}
+			if err := batch.scan(rows); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// maskStage returns the transform stage of a pipeline masking the rows of its batches
+func maskStage(masker *rowMasker) func(batch *rowBatch) error {
+	return func(batch *rowBatch) error {
+		return masker.maskBatch(batch.columns, batch.rows)
+	}
+}
@@ -0,0 +1,247 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader returns a read stage filling batches of size rows numbered from 0, until total rows are read
+func countingReader(total, size int, reads *atomic.Int64) func(ctx context.Context, batch *rowBatch) (bool, error) {
+	next := 0
+	return func(ctx context.Context, batch *rowBatch) (bool, error) {
+		reads.Add(1)
+		batch.reset([]string{"id"})
+		for len(batch.rows) < size && next < total {
+			batch.values = append(batch.values, int64(next))
+			batch.rows = append(batch.rows, batch.values[len(batch.values)-1:])
+			next++
+		}
+		return next < total, nil
+	}
+}
+
+func TestRunRowPipeline_WritesBatchesInOrder(t *testing.T) {
+	engine := newTestEngine()
+	var reads atomic.Int64
+
+	var written []int64
+	var done []bool
+	err := engine.runRowPipeline(context.Background(), pipelineStages{
+		read: countingReader(25, 10, &reads),
+		transform: func(batch *rowBatch) error {
+			for _, row := range batch.rows {
+				row[0] = row[0].(int64) * 2
+			}
+			return nil
+		},
+		write: func(ctx context.Context, batch *rowBatch) error {
+			for _, row := range batch.rows {
+				written = append(written, row[0].(int64))
+			}
+			done = append(done, batch.done)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, written, 25)
+	for i, value := range written {
+		assert.Equal(t, int64(2*i), value)
+	}
+	assert.Equal(t, []bool{false, false, true}, done)
+}
+
+func TestRunRowPipeline_StopsOnError(t *testing.T) {
+	engine := newTestEngine()
+	var reads atomic.Int64
+
+	writes := 0
+	err := engine.runRowPipeline(context.Background(), pipelineStages{
+		read:      countingReader(1000, 1, &reads),
+		transform: func(batch *rowBatch) error { return nil },
+		write: func(ctx context.Context, batch *rowBatch) error {
+			writes++
+			if writes == 2 {
+				return fmt.Errorf("failed to execute batch write: %w", assert.AnError)
+			}
+			return nil
+		},
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 2, writes)
+	// The reader stops once the batches in flight are all taken
+	assert.LessOrEqual(t, reads.Load(), int64(2+pipelineDepth))
+
+	// A failing transform stops the pipeline before the batch is written
+	err = engine.runRowPipeline(context.Background(), pipelineStages{
+		read:      countingReader(1000, 1, &reads),
+		transform: func(batch *rowBatch) error { return assert.AnError },
+		write: func(ctx context.Context, batch *rowBatch) error {
+			t.Fatal("unexpected write")
+			return nil
+		},
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRunRowPipeline_BoundsBatchesInFlight(t *testing.T) {
+	engine := newTestEngine()
+	var reads atomic.Int64
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.runRowPipeline(ctx, pipelineStages{
+			read:      countingReader(1000, 1, &reads),
+			transform: func(batch *rowBatch) error { return nil },
+			write: func(ctx context.Context, batch *rowBatch) error {
+				select {
+				case <-release:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+	}()
+
+	// A blocked writer holds back the reader once every batch is in flight
+	assert.Eventually(t, func() bool { return reads.Load() == pipelineDepth }, time.Second, time.Millisecond)
+	assert.Never(t, func() bool { return reads.Load() > pipelineDepth }, 50*time.Millisecond, time.Millisecond)
+
+	// Each written batch lets one more be read
+	release <- struct{}{}
+	assert.Eventually(t, func() bool { return reads.Load() == pipelineDepth+1 }, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("pipeline didn't stop on cancel")
+	}
+}
+
+func TestRowBatch_ReusesBuffers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sourceDB := sqlx.NewDb(db, "sqlmock")
+
+	query := "SELECT * FROM `shop`.`orders`"
+	batch := &rowBatch{}
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note"}).AddRow(int64(1), []byte("a")).AddRow(int64(2), nil))
+	require.NoError(t, readBatch(context.Background(), sourceDB, query, nil, batch))
+	assert.Equal(t, [][]interface{}{{int64(1), []byte("a")}, {int64(2), nil}}, batch.rows)
+	assert.Equal(t, []interface{}{int64(2)}, batch.key([]string{"id"}, []string{"bigint"}))
+	values := &batch.values[0]
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note"}).AddRow(int64(3), []byte("c")))
+	require.NoError(t, readBatch(context.Background(), sourceDB, query, nil, batch))
+	assert.Equal(t, [][]interface{}{{int64(3), []byte("c")}}, batch.rows)
+	assert.Same(t, values, &batch.values[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// benchmarkSource returns a source answering every query with count rows after a delay, like a remote server
+func benchmarkSource(b *testing.B, columns []string, rows [][]interface{}, delay time.Duration) (*sqlx.DB, func()) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(string, string) error { return nil })))
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	expect := func() {
+		result := sqlmock.NewRows(columns)
+		for _, row := range rows {
+			values := make([]driver.Value, len(row))
+			for i, value := range row {
+				values[i] = value
+			}
+			result.AddRow(values...)
+		}
+		mock.ExpectQuery("").WillDelayFor(delay).WillReturnRows(result)
+	}
+	return sqlx.NewDb(db, "sqlmock"), expect
+}
+
+// BenchmarkCopyBatches compares copying batches sequentially with rows read into maps, as full copies
+// did before, against the row pipeline reading into reused column ordered buffers. Reads and writes
+// each take a millisecond, which the pipeline overlaps.
+func BenchmarkCopyBatches(b *testing.B) {
+	columns, rows := benchmarkRows(500)
+	const batches = 8
+	const delay = time.Millisecond
+	query := "SELECT * FROM `shop`.`orders` LIMIT 500"
+
+	b.Run("sequential_maps", func(b *testing.B) {
+		engine, targetDB, targetMock := newBenchmarkTarget(b)
+		sourceDB, expect := benchmarkSource(b, columns, rows, delay)
+		ctx := context.Background()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for n := 0; n < batches; n++ {
+				expect()
+				targetMock.ExpectExec("").WillDelayFor(delay).WillReturnResult(sqlmock.NewResult(0, int64(len(rows))))
+
+				result, err := sourceDB.QueryxContext(ctx, query)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var batch []map[string]interface{}
+				for result.Next() {
+					row := make(map[string]interface{})
+					if err := result.MapScan(row); err != nil {
+						b.Fatal(err)
+					}
+					batch = append(batch, row)
+				}
+				result.Close()
+				if err := engine.writeBatchToDB(ctx, targetDB, mysqlDialect{}, "replica", "orders", columns, batch, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("pipeline", func(b *testing.B) {
+		engine, targetDB, targetMock := newBenchmarkTarget(b)
+		sourceDB, expect := benchmarkSource(b, columns, rows, delay)
+		ctx := context.Background()
+		upsert := (*writePolicy)(nil).clause(mysqlDialect{}, columns)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for n := 0; n < batches; n++ {
+				expect()
+				targetMock.ExpectExec("").WillDelayFor(delay).WillReturnResult(sqlmock.NewResult(0, int64(len(rows))))
+			}
+			read := 0
+			err := engine.runRowPipeline(ctx, pipelineStages{
+				read: func(ctx context.Context, batch *rowBatch) (bool, error) {
+					read++
+					return read < batches, readBatch(ctx, sourceDB, query, nil, batch)
+				},
+				transform: func(batch *rowBatch) error { return nil },
+				write: func(ctx context.Context, batch *rowBatch) error {
+					return engine.writeRowsToDB(ctx, targetDB, mysqlDialect{}, "replica", "orders", columns, batch.rows, upsert)
+				},
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
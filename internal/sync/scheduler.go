@@ -0,0 +1,325 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// schedulerTickInterval is how often due schedules are checked
+	schedulerTickInterval = time.Second
+	// schedulerRefreshInterval is how often sync configs are reloaded to pick up schedule changes
+	schedulerRefreshInterval = 30 * time.Second
+	// schedulerMisfireGrace is how late a run may start before it counts as missed
+	schedulerMisfireGrace = time.Minute
+)
+
+// Scheduler starts sync jobs for enabled configs according to their Schedule.
+// Run times are persisted on the sync config so missed runs can be detected
+// after a restart and handled according to the config's MisfirePolicy.
+type Scheduler struct {
+	repo        Repository
+	syncManager SyncManager
+	jobEngine   JobEngine
+	logger      *logrus.Logger
+
+	// entries is only touched by the scheduling loop
+	entries     map[string]*scheduleEntry
+	lastRefresh time.Time
+	now         func() time.Time
+
+	running  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+}
+
+// scheduleEntry holds the parsed schedule and run state for one sync config
+type scheduleEntry struct {
+	configID      string
+	spec          string
+	timezone      string
+	misfirePolicy MisfirePolicy
+	schedule      CronSchedule
+	location      *time.Location
+	lastRun       *time.Time
+	nextRun       time.Time
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(repo Repository, syncManager SyncManager, jobEngine JobEngine, logger *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		repo:        repo,
+		syncManager: syncManager,
+		jobEngine:   jobEngine,
+		logger:      logger,
+		entries:     make(map[string]*scheduleEntry),
+		now:         time.Now,
+	}
+}
+
+// Start starts the scheduling loop
+func (s *Scheduler) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.running {
+		return fmt.Errorf("scheduler is already running")
+	}
+
+	s.running = true
+	s.stopChan = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.run()
+
+	s.logger.Info("Sync scheduler started")
+	return nil
+}
+
+// Stop stops the scheduling loop and waits for it to exit
+func (s *Scheduler) Stop() error {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return fmt.Errorf("scheduler is not running")
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+
+	s.logger.Info("Sync scheduler stopped")
+	return nil
+}
+
+// run is the scheduling loop
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.tick(ctx)
+
+	ticker := time.NewTicker(schedulerTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.tick(ctx)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// tick reloads configs when the refresh interval has passed and fires due schedules
+func (s *Scheduler) tick(ctx context.Context) {
+	now := s.now()
+	if s.lastRefresh.IsZero() || now.Sub(s.lastRefresh) >= schedulerRefreshInterval {
+		if err := s.refresh(ctx, now); err != nil {
+			s.logger.WithError(err).Error("Failed to refresh sync schedules")
+		}
+		s.lastRefresh = now
+	}
+	s.runDue(ctx, now)
+}
+
+// refresh synchronizes the in-memory entries with the sync configs in the repository
+func (s *Scheduler) refresh(ctx context.Context, now time.Time) error {
+	configs, err := s.repo.GetAllSyncConfigs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load sync configs: %w", err)
+	}
+
+	seen := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if !config.Enabled || config.Schedule == "" {
+			delete(s.entries, config.ID)
+			if config.NextRunAt != nil {
+				s.persistRunTimes(ctx, config.ID, config.LastRunAt, nil)
+			}
+			continue
+		}
+		seen[config.ID] = true
+
+		timezone, misfirePolicy := "", MisfirePolicySkip
+		if config.Options != nil {
+			timezone = config.Options.Timezone
+			if config.Options.MisfirePolicy != "" {
+				misfirePolicy = config.Options.MisfirePolicy
+			}
+		}
+
+		existing, ok := s.entries[config.ID]
+		if ok && existing.spec == config.Schedule && existing.timezone == timezone {
+			existing.misfirePolicy = misfirePolicy
+			continue
+		}
+
+		entry, err := newScheduleEntry(config.ID, config.Schedule, timezone, misfirePolicy)
+		if err != nil {
+			delete(s.entries, config.ID)
+			s.logger.WithError(err).WithField("sync_config_id", config.ID).Warn("Ignoring invalid sync schedule")
+			continue
+		}
+		entry.lastRun = config.LastRunAt
+
+		// A newly loaded entry keeps its persisted next run, so runs that fell
+		// due while the service was down are seen as misfires. A changed
+		// schedule always starts over from now.
+		if !ok && config.NextRunAt != nil {
+			entry.nextRun = *config.NextRunAt
+		} else {
+			entry.nextRun = entry.schedule.Next(now.In(entry.location))
+		}
+		s.entries[config.ID] = entry
+
+		if config.NextRunAt == nil || !config.NextRunAt.Equal(entry.nextRun) {
+			s.persistRunTimes(ctx, config.ID, entry.lastRun, scheduledTime(entry.nextRun))
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"sync_config_id": config.ID,
+			"schedule":       entry.spec,
+			"timezone":       entry.location.String(),
+			"next_run_at":    entry.nextRun,
+		}).Info("Sync schedule loaded")
+	}
+
+	for id := range s.entries {
+		if !seen[id] {
+			delete(s.entries, id)
+		}
+	}
+
+	return nil
+}
+
+// runDue starts jobs for every entry whose next run time has been reached
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	for _, entry := range s.entries {
+		if entry.nextRun.IsZero() || now.Before(entry.nextRun) {
+			continue
+		}
+
+		logger := s.logger.WithFields(logrus.Fields{
+			"sync_config_id": entry.configID,
+			"scheduled_at":   entry.nextRun,
+		})
+
+		switch {
+		case now.Sub(entry.nextRun) > schedulerMisfireGrace && entry.misfirePolicy != MisfirePolicyRunOnce:
+			logger.Warn("Scheduled sync run was missed, skipping to the next occurrence")
+		default:
+			s.fire(ctx, entry, now, logger)
+		}
+
+		entry.nextRun = entry.schedule.Next(now.In(entry.location))
+		s.persistRunTimes(ctx, entry.configID, entry.lastRun, scheduledTime(entry.nextRun))
+	}
+}
+
+// fire starts a sync job for the entry unless a previous run is still in progress
+func (s *Scheduler) fire(ctx context.Context, entry *scheduleEntry, now time.Time, logger *logrus.Entry) {
+	busy, err := s.hasActiveJob(ctx, entry.configID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to check for running jobs, skipping scheduled run")
+		return
+	}
+	if busy {
+		logger.Warn("Previous sync run is still in progress, skipping scheduled run")
+		return
+	}
+
+	job, err := s.syncManager.StartSync(ctx, entry.configID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to start scheduled sync")
+		return
+	}
+
+	lastRun := now
+	entry.lastRun = &lastRun
+	logger.WithField("job_id", job.ID).Info("Scheduled sync started")
+}
+
+// hasActiveJob reports whether a pending or running job exists for the config
+func (s *Scheduler) hasActiveJob(ctx context.Context, configID string) (bool, error) {
+	for _, status := range []JobStatus{JobStatusPending, JobStatusRunning} {
+		jobs, err := s.jobEngine.GetJobsByStatus(ctx, status)
+		if err != nil {
+			return false, err
+		}
+		for _, job := range jobs {
+			if job.ConfigID == configID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// persistRunTimes stores run times on the sync config, logging rather than failing on errors
+func (s *Scheduler) persistRunTimes(ctx context.Context, configID string, lastRunAt, nextRunAt *time.Time) {
+	if err := s.repo.UpdateSyncConfigRunTimes(ctx, configID, lastRunAt, nextRunAt); err != nil {
+		s.logger.WithError(err).WithField("sync_config_id", configID).Warn("Failed to persist sync schedule run times")
+	}
+}
+
+// newScheduleEntry parses a schedule and resolves its timezone
+func newScheduleEntry(configID, spec, timezone string, misfirePolicy MisfirePolicy) (*scheduleEntry, error) {
+	schedule, err := ParseCronSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := loadScheduleLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduleEntry{
+		configID:      configID,
+		spec:          spec,
+		timezone:      timezone,
+		misfirePolicy: misfirePolicy,
+		schedule:      schedule,
+		location:      location,
+	}, nil
+}
+
+// loadScheduleLocation resolves a timezone name, defaulting to the server's local time
+func loadScheduleLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	return location, nil
+}
+
+// scheduledTime converts a next run time to its persisted form; zero means never
+func scheduledTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ValidateSchedule checks that a schedule expression and timezone can be used by the scheduler
+func ValidateSchedule(spec, timezone string) error {
+	if _, err := ParseCronSchedule(spec); err != nil {
+		return err
+	}
+	_, err := loadScheduleLocation(timezone)
+	return err
+}
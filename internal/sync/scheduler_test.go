@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// schedulerSyncManager records StartSync calls made by the scheduler
+type schedulerSyncManager struct {
+	SyncManager
+	started []string
+}
+
+func (m *schedulerSyncManager) StartSync(ctx context.Context, configID string) (*SyncJob, error) {
+	m.started = append(m.started, configID)
+	return &SyncJob{ID: "job-" + configID, ConfigID: configID, Status: JobStatusPending}, nil
+}
+
+// schedulerJobEngine serves GetJobsByStatus from a fixed job list
+type schedulerJobEngine struct {
+	JobEngine
+	jobs []*SyncJob
+}
+
+func (e *schedulerJobEngine) GetJobsByStatus(ctx context.Context, status JobStatus) ([]*SyncJob, error) {
+	var jobs []*SyncJob
+	for _, job := range e.jobs {
+		if job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func newTestScheduler(repo Repository, jobs []*SyncJob) (*Scheduler, *schedulerSyncManager) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	syncManager := &schedulerSyncManager{}
+	return NewScheduler(repo, syncManager, &schedulerJobEngine{jobs: jobs}, logger), syncManager
+}
+
+func TestScheduler_RunsDueConfig(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	due := now.Add(-10 * time.Second)
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetAllSyncConfigs", ctx).Return([]*SyncConfig{
+		{ID: "scheduled", Enabled: true, Schedule: "*/5 * * * *", NextRunAt: &due, Options: &SyncOptions{Timezone: "UTC"}},
+		{ID: "manual", Enabled: true},
+		{ID: "disabled", Enabled: false, Schedule: "* * * * *"},
+	}, nil)
+	mockRepo.On("UpdateSyncConfigRunTimes", ctx, "scheduled", mock.Anything, mock.Anything).Return(nil)
+
+	scheduler, syncManager := newTestScheduler(mockRepo, nil)
+	assert.NoError(t, scheduler.refresh(ctx, now))
+	scheduler.runDue(ctx, now)
+
+	assert.Equal(t, []string{"scheduled"}, syncManager.started)
+
+	entry := scheduler.entries["scheduled"]
+	assert.Equal(t, now, *entry.lastRun)
+	assert.Equal(t, time.Date(2024, 3, 15, 10, 5, 0, 0, time.UTC), entry.nextRun)
+	mockRepo.AssertCalled(t, "UpdateSyncConfigRunTimes", ctx, "scheduled", &now, &entry.nextRun)
+}
+
+func TestScheduler_MisfirePolicy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	missed := now.Add(-3 * time.Hour)
+
+	tests := []struct {
+		policy      MisfirePolicy
+		expectStart bool
+	}{
+		{"", false},
+		{MisfirePolicySkip, false},
+		{MisfirePolicyRunOnce, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetAllSyncConfigs", ctx).Return([]*SyncConfig{
+				{ID: "cfg", Enabled: true, Schedule: "@hourly", NextRunAt: &missed, Options: &SyncOptions{Timezone: "UTC", MisfirePolicy: tt.policy}},
+			}, nil)
+			mockRepo.On("UpdateSyncConfigRunTimes", ctx, "cfg", mock.Anything, mock.Anything).Return(nil)
+
+			scheduler, syncManager := newTestScheduler(mockRepo, nil)
+			assert.NoError(t, scheduler.refresh(ctx, now))
+			scheduler.runDue(ctx, now)
+
+			assert.Equal(t, tt.expectStart, len(syncManager.started) == 1)
+			assert.Equal(t, now.Add(time.Hour), scheduler.entries["cfg"].nextRun)
+		})
+	}
+}
+
+func TestScheduler_PreventsOverlap(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Second)
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetAllSyncConfigs", ctx).Return([]*SyncConfig{
+		{ID: "cfg", Enabled: true, Schedule: "@every 1m", NextRunAt: &due},
+	}, nil)
+	mockRepo.On("UpdateSyncConfigRunTimes", ctx, "cfg", mock.Anything, mock.Anything).Return(nil)
+
+	running := []*SyncJob{{ID: "previous", ConfigID: "cfg", Status: JobStatusRunning}}
+	scheduler, syncManager := newTestScheduler(mockRepo, running)
+	assert.NoError(t, scheduler.refresh(ctx, now))
+	scheduler.runDue(ctx, now)
+
+	assert.Empty(t, syncManager.started)
+	assert.Nil(t, scheduler.entries["cfg"].lastRun)
+	assert.True(t, scheduler.entries["cfg"].nextRun.After(now))
+}
+
+func TestScheduler_RefreshClearsRemovedSchedule(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	next := now.Add(time.Hour)
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetAllSyncConfigs", ctx).Return([]*SyncConfig{
+		{ID: "cfg", Enabled: true, Schedule: "", NextRunAt: &next},
+	}, nil)
+	mockRepo.On("UpdateSyncConfigRunTimes", ctx, "cfg", (*time.Time)(nil), (*time.Time)(nil)).Return(nil)
+
+	scheduler, _ := newTestScheduler(mockRepo, nil)
+	scheduler.entries["cfg"] = &scheduleEntry{configID: "cfg", nextRun: next}
+
+	assert.NoError(t, scheduler.refresh(ctx, now))
+	assert.NotContains(t, scheduler.entries, "cfg")
+	mockRepo.AssertExpectations(t)
+}
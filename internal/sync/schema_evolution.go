@@ -0,0 +1,342 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// errSchemaApprovalRequired is returned when the approve schema policy holds back destructive changes
+var errSchemaApprovalRequired = errors.New("destructive schema changes require approval")
+
+// integerDisplayWidth matches the display width MySQL 8 no longer reports for integer types
+var integerDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// schemaChange is a single DDL statement moving a target table towards the source schema
+type schemaChange struct {
+	statement   string
+	destructive bool // May lose data or change what readers of the target table see
+	retype      bool // Changes the definition of a column both tables have
+}
+
+// diffTableSchemas returns the statements that bring target in line with source, in the order they
+// have to be applied. Target-only columns in keep, like a soft delete column, are left alone.
+func diffTableSchemas(targetDBName string, source, target *TableSchema, keep map[string]bool) []schemaChange {
+	alter := fmt.Sprintf("ALTER TABLE `%s`.`%s` ", targetDBName, target.Name)
+
+	targetColumns := make(map[string]*ColumnInfo, len(target.Columns))
+	for _, col := range target.Columns {
+		targetColumns[strings.ToLower(col.Name)] = col
+	}
+	sourceColumns := make(map[string]bool, len(source.Columns))
+	for _, col := range source.Columns {
+		sourceColumns[strings.ToLower(col.Name)] = true
+	}
+
+	var modified, added, dropped []schemaChange
+	for i, col := range source.Columns {
+		existing, ok := targetColumns[strings.ToLower(col.Name)]
+		if !ok {
+			position := " FIRST"
+			if i > 0 {
+				position = fmt.Sprintf(" AFTER `%s`", source.Columns[i-1].Name)
+			}
+			added = append(added, schemaChange{statement: alter + "ADD COLUMN " + columnDefinition(col) + position})
+			continue
+		}
+		if !sameColumnDefinition(col, existing) {
+			modified = append(modified, schemaChange{statement: alter + "MODIFY COLUMN " + columnDefinition(col), destructive: true, retype: true})
+		}
+	}
+	for _, col := range target.Columns {
+		if !sourceColumns[strings.ToLower(col.Name)] && !keep[strings.ToLower(col.Name)] {
+			dropped = append(dropped, schemaChange{statement: fmt.Sprintf("%sDROP COLUMN `%s`", alter, col.Name), destructive: true})
+		}
+	}
+
+	targetIndexes := make(map[string]*IndexInfo, len(target.Indexes))
+	for _, idx := range target.Indexes {
+		targetIndexes[idx.Name] = idx
+	}
+	sourceIndexes := make(map[string]bool, len(source.Indexes))
+	for _, idx := range source.Indexes {
+		sourceIndexes[idx.Name] = true
+	}
+
+	var indexChanges, addedIndexes []schemaChange
+	for _, idx := range sortedIndexes(target.Indexes) {
+		if !sourceIndexes[idx.Name] {
+			indexChanges = append(indexChanges, schemaChange{statement: alter + dropIndexClause(idx), destructive: true})
+		}
+	}
+	for _, idx := range sortedIndexes(source.Indexes) {
+		existing, ok := targetIndexes[idx.Name]
+		switch {
+		case !ok:
+			addedIndexes = append(addedIndexes, schemaChange{statement: alter + "ADD " + indexDefinition(idx)})
+		case !sameIndexDefinition(idx, existing):
+			indexChanges = append(indexChanges, schemaChange{
+				statement:   alter + dropIndexClause(existing) + ", ADD " + indexDefinition(idx),
+				destructive: true,
+			})
+		}
+	}
+
+	// Indexes go before the columns they may cover are dropped, and are added once their columns exist
+	changes := append(modified, added...)
+	changes = append(changes, indexChanges...)
+	changes = append(changes, dropped...)
+	return append(changes, addedIndexes...)
+}
+
+// schemaEvolution is what evolveTargetSchema does to an existing target table
+type schemaEvolution struct {
+	changes          []schemaChange
+	notes            []string // What translating the source schema for the target server changed
+	applyDestructive bool     // Destructive changes run as well, not only the additive ones
+	usesApproval     bool     // Destructive changes run on the mapping's approval, which is used up
+}
+
+// skips reports whether the schema policy leaves a change out
+func (ev *schemaEvolution) skips(change schemaChange) bool {
+	return change.destructive && !ev.applyDestructive
+}
+
+// planSchemaEvolution compares an existing target table with the source schema and checks the
+// changes against the mapping's schema policy, without changing anything. With truncate the table
+// is emptied first; destructive changes then cost no data, so the additive policy applies them as well.
+func (e *DefaultSyncEngine) planSchemaEvolution(ctx context.Context, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, schema *TableSchema, truncate bool) (*schemaEvolution, error) {
+	target, err := e.getTableSchemaFromRemote(ctx, targetDB, mapping.TargetTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target table schema: %w", err)
+	}
+	schema, notes := e.translateForTarget(ctx, targetDB, schema)
+
+	keep := make(map[string]bool)
+	if col := mapping.softDeleteColumn(); col != "" {
+		keep[strings.ToLower(col)] = true
+	}
+	// A write mode keeping the target rows leaves the columns only the target has to their owners
+	if mapping.keepsTargetRows() {
+		for _, col := range target.Columns {
+			keep[strings.ToLower(col.Name)] = true
+		}
+	}
+	changes := diffTableSchemas(targetDBName, schema, target, keep)
+
+	policy := mapping.SchemaPolicy
+	if policy == "" {
+		policy = SchemaPolicyAdditive
+	}
+
+	var destructive []string
+	for _, change := range changes {
+		if change.destructive {
+			destructive = append(destructive, change.statement)
+		}
+	}
+
+	switch {
+	case policy == SchemaPolicyFail && len(changes) > 0:
+		statements := make([]string, len(changes))
+		for i, change := range changes {
+			statements[i] = change.statement
+		}
+		return nil, fmt.Errorf("target table %s no longer matches the source schema: %s", mapping.TargetTable, strings.Join(statements, "; "))
+	case policy == SchemaPolicyApprove && len(destructive) > 0 && !mapping.ApproveSchemaChanges:
+		return nil, fmt.Errorf("%w for target table %s: %s", errSchemaApprovalRequired, mapping.TargetTable, strings.Join(destructive, "; "))
+	}
+
+	return &schemaEvolution{
+		changes:          changes,
+		notes:            notes,
+		applyDestructive: truncate || policy == SchemaPolicyApprove,
+		usesApproval:     policy == SchemaPolicyApprove && len(destructive) > 0,
+	}, nil
+}
+
+// evolveTargetSchema brings an existing target table in line with the source schema as far as the
+// mapping's schema policy allows, recording every statement in the job log. With truncate the table
+// is emptied once the policy accepted the changes.
+func (e *DefaultSyncEngine) evolveTargetSchema(ctx context.Context, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, schema *TableSchema, truncate bool) error {
+	evolution, err := e.planSchemaEvolution(ctx, targetDB, targetDBName, mapping, schema, truncate)
+	if err != nil {
+		return err
+	}
+	e.logTranslationNotes(schema.Name, evolution.notes)
+
+	if truncate {
+		truncateQuery := fmt.Sprintf("TRUNCATE TABLE `%s`.`%s`", targetDBName, mapping.TargetTable)
+		if _, err := targetDB.ExecContext(ctx, truncateQuery); err != nil {
+			return fmt.Errorf("failed to truncate target table: %w", err)
+		}
+	}
+
+	for _, change := range evolution.changes {
+		if evolution.skips(change) {
+			e.logger.WithFields(logrus.Fields{
+				"target_table": mapping.TargetTable,
+				"statement":    change.statement,
+			}).Warn("Destructive schema change skipped by additive schema policy")
+			ReportTableSchemaChange(ctx, mapping.SourceTable, change.statement, false)
+			continue
+		}
+
+		if _, err := targetDB.ExecContext(ctx, change.statement); err != nil {
+			return fmt.Errorf("failed to apply schema change %q: %w", change.statement, err)
+		}
+		e.logger.WithFields(logrus.Fields{
+			"target_table": mapping.TargetTable,
+			"statement":    change.statement,
+		}).Info("Applied schema change")
+		ReportTableSchemaChange(ctx, mapping.SourceTable, change.statement, true)
+	}
+
+	// An approval covers the changes pending now, later destructive changes need a new one
+	if evolution.usesApproval {
+		approved := *mapping
+		approved.ApproveSchemaChanges = false
+		if err := e.repo.UpdateTableMapping(ctx, mapping.ID, &approved); err != nil {
+			e.logger.WithError(err).WithField("mapping_id", mapping.ID).Warn("Failed to reset schema change approval")
+		} else {
+			mapping.ApproveSchemaChanges = false
+		}
+	}
+
+	return nil
+}
+
+// prepareFullCopyTarget readies the table a full sync loads into. An interrupted copy keeps its table,
+// an existing target table is emptied, unless the mapping's write mode keeps its rows, and evolved to
+// the source schema, and a missing or shadow table is created from scratch.
+func (e *DefaultSyncEngine) prepareFullCopyTarget(ctx context.Context, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, schema *TableSchema, plan *fullCopyPlan, rebuild bool) error {
+	if plan.resume != nil {
+		return e.evolveTargetSchema(ctx, targetDB, targetDBName, mapping, schema, false)
+	}
+
+	if !rebuild {
+		exists, err := plan.dialect.TableExists(ctx, targetDB, targetDBName, mapping.TargetTable)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return e.evolveTargetSchema(ctx, targetDB, targetDBName, mapping, schema, !mapping.keepsTargetRows())
+		}
+	}
+
+	if err := e.createOrRecreateTargetTableInDB(ctx, targetDB, plan.dialect, targetDBName, mapping.TargetTable, schema); err != nil {
+		return fmt.Errorf("failed to create target table: %w", err)
+	}
+	return nil
+}
+
+// schemaChangeMessage describes a schema change for the job log
+func schemaChangeMessage(statement string, applied bool) string {
+	if applied {
+		return fmt.Sprintf("Applied schema change: %s", statement)
+	}
+	return fmt.Sprintf("Skipped destructive schema change, the additive schema policy only applies it to an emptied table: %s", statement)
+}
+
+// sameColumnDefinition reports whether two columns match in type, nullability and collation,
+// ignoring differences in how MySQL versions report them
+func sameColumnDefinition(a, b *ColumnInfo) bool {
+	if normalizeColumnType(a.Type) != normalizeColumnType(b.Type) || a.Nullable != b.Nullable {
+		return false
+	}
+	if a.Collation != "" && b.Collation != "" && normalizeCollation(a.Collation) != normalizeCollation(b.Collation) {
+		return false
+	}
+	return true
+}
+
+// normalizeColumnType lowercases a column type and drops integer display widths
+func normalizeColumnType(columnType string) string {
+	return integerDisplayWidth.ReplaceAllString(strings.ToLower(strings.TrimSpace(columnType)), "$1")
+}
+
+// normalizeCollation maps the utf8mb3 names of MySQL 8 to their utf8 aliases
+func normalizeCollation(collation string) string {
+	return strings.Replace(strings.ToLower(collation), "utf8mb3_", "utf8_", 1)
+}
+
+// sameIndexDefinition reports whether two indexes cover the same columns with the same kind.
+// When both list their key parts, prefix lengths, expressions and order have to match as well.
+func sameIndexDefinition(a, b *IndexInfo) bool {
+	if a.Unique != b.Unique || indexKind(a) != indexKind(b) || len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i := range a.Columns {
+		if !strings.EqualFold(a.Columns[i], b.Columns[i]) {
+			return false
+		}
+	}
+	if len(a.Parts) > 0 && len(b.Parts) > 0 {
+		for i := range a.Parts {
+			if i >= len(b.Parts) || normalizeKeyPart(a.Parts[i]) != normalizeKeyPart(b.Parts[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalizeKeyPart lowercases a key part and drops its whitespace
+func normalizeKeyPart(part string) string {
+	return strings.Join(strings.Fields(strings.ToLower(part)), "")
+}
+
+// indexKind returns FULLTEXT or SPATIAL for those index types, empty for regular indexes
+func indexKind(idx *IndexInfo) string {
+	switch kind := strings.ToUpper(idx.Type); kind {
+	case "FULLTEXT", "SPATIAL":
+		return kind
+	}
+	return ""
+}
+
+// indexDefinition builds the definition of an index as used by CREATE TABLE and ALTER TABLE ... ADD
+func indexDefinition(idx *IndexInfo) string {
+	columns := quoteColumns(idx.Columns)
+	if len(idx.Parts) > 0 {
+		columns = strings.Join(idx.Parts, ", ")
+	}
+
+	var definition string
+	switch {
+	case idx.Name == "PRIMARY":
+		definition = fmt.Sprintf("PRIMARY KEY (%s)", columns)
+	case indexKind(idx) != "":
+		definition = fmt.Sprintf("%s KEY `%s` (%s)", indexKind(idx), idx.Name, columns)
+	case idx.Unique:
+		definition = fmt.Sprintf("UNIQUE KEY `%s` (%s)", idx.Name, columns)
+	default:
+		definition = fmt.Sprintf("KEY `%s` (%s)", idx.Name, columns)
+	}
+	if idx.Comment != "" {
+		definition += " COMMENT " + sqlStringLiteral(idx.Comment)
+	}
+	return definition
+}
+
+// dropIndexClause builds the ALTER TABLE clause dropping an index
+func dropIndexClause(idx *IndexInfo) string {
+	if idx.Name == "PRIMARY" {
+		return "DROP PRIMARY KEY"
+	}
+	return fmt.Sprintf("DROP INDEX `%s`", idx.Name)
+}
+
+// sortedIndexes returns indexes ordered by name, so changes come out the same whatever order the schemas list them in
+func sortedIndexes(indexes []*IndexInfo) []*IndexInfo {
+	sorted := make([]*IndexInfo, len(indexes))
+	copy(sorted, indexes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
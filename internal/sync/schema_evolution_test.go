@@ -0,0 +1,160 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTableSchemas(t *testing.T) {
+	target := &TableSchema{
+		Name: "orders",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint(20)", Extra: "auto_increment"},
+			{Name: "note", Type: "varchar(64)", Nullable: true, CharacterSet: "utf8mb4", Collation: "utf8mb4_general_ci"},
+			{Name: "legacy", Type: "int"},
+			{Name: "deleted_at", Type: "datetime", Nullable: true},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true, Type: "BTREE"},
+			{Name: "idx_legacy", Columns: []string{"legacy"}, Type: "BTREE"},
+		},
+	}
+
+	changes := diffTableSchemas("replica", ordersColumns("id", "note", "created_at"), target, map[string]bool{"deleted_at": true})
+
+	// The integer display width is not a difference, the widened varchar is
+	expected := []schemaChange{
+		{statement: "ALTER TABLE `replica`.`orders` MODIFY COLUMN `note` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci", destructive: true, retype: true},
+		{statement: "ALTER TABLE `replica`.`orders` ADD COLUMN `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER `note`"},
+		{statement: "ALTER TABLE `replica`.`orders` DROP INDEX `idx_legacy`", destructive: true},
+		{statement: "ALTER TABLE `replica`.`orders` DROP COLUMN `legacy`", destructive: true},
+		{statement: "ALTER TABLE `replica`.`orders` ADD KEY `idx_created` (`created_at`)"},
+	}
+	assert.Equal(t, expected, changes)
+
+	assert.Empty(t, diffTableSchemas("replica", ordersColumns("id", "note", "created_at"), ordersColumns("id", "note", "created_at"), nil))
+}
+
+func TestDiffTableSchemas_ChangedIndex(t *testing.T) {
+	target := ordersColumns("id", "note", "created_at")
+	target.Indexes[1] = &IndexInfo{Name: "idx_created", Columns: []string{"created_at"}, Unique: true, Type: "BTREE"}
+	target.Indexes = append(target.Indexes, &IndexInfo{Name: "ft_note", Columns: []string{"note"}, Type: "FULLTEXT"})
+
+	source := ordersColumns("id", "note", "created_at")
+	source.Indexes = append(source.Indexes, &IndexInfo{Name: "ft_note", Columns: []string{"note"}, Type: "FULLTEXT"})
+
+	changes := diffTableSchemas("replica", source, target, nil)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "ALTER TABLE `replica`.`orders` DROP INDEX `idx_created`, ADD KEY `idx_created` (`created_at`)", changes[0].statement)
+	assert.True(t, changes[0].destructive)
+	assert.Equal(t, "FULLTEXT KEY `ft_note` (`note`)", indexDefinition(source.Indexes[2]))
+}
+
+func TestEvolveTargetSchema_AdditiveSkipsDestructive(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	expectTargetSchema(targetMock,
+		targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("note", "varchar(64)", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci"),
+		targetIndexRows())
+	targetMock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `replica`.`orders` ADD COLUMN `created_at`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `replica`.`orders` ADD KEY `idx_created`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var logged []string
+	var skipped []string
+	ctx := WithTableSchemaChangeReporter(context.Background(), func(tableName, statement string, applied bool) {
+		if applied {
+			logged = append(logged, statement)
+		} else {
+			skipped = append(skipped, statement)
+		}
+	})
+
+	require.NoError(t, engine.evolveTargetSchema(ctx, targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), false))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+	assert.Len(t, logged, 2)
+	assert.Equal(t, []string{"ALTER TABLE `replica`.`orders` MODIFY COLUMN `note` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"}, skipped)
+}
+
+func TestEvolveTargetSchema_FullSyncAppliesDestructive(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders"}
+
+	expectTargetSchema(targetMock,
+		targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("note", "varchar(255)", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci").
+			AddRow("created_at", "datetime", "NO", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED", nil, nil).
+			AddRow("legacy", "int", "YES", nil, "", nil, nil),
+		targetIndexRows().AddRow("idx_created", "created_at", 1, "BTREE"))
+
+	// The table is emptied before the destructive change, so the additive policy applies it
+	targetMock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE `replica`.`orders`")).WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `replica`.`orders` DROP COLUMN `legacy`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, engine.evolveTargetSchema(context.Background(), targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), true))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestEvolveTargetSchema_Policies(t *testing.T) {
+	legacyColumns := func() *sqlmock.Rows {
+		return targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("note", "varchar(255)", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci").
+			AddRow("created_at", "datetime", "NO", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED", nil, nil).
+			AddRow("legacy", "int", "YES", nil, "", nil, nil)
+	}
+	indexes := func() *sqlmock.Rows { return targetIndexRows().AddRow("idx_created", "created_at", 1, "BTREE") }
+
+	t.Run("fail", func(t *testing.T) {
+		engine, _, _, targetDB, targetMock := newMockEngine(t)
+		mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", SchemaPolicy: SchemaPolicyFail}
+		expectTargetSchema(targetMock, legacyColumns(), indexes())
+
+		// Nothing is truncated or altered
+		err := engine.evolveTargetSchema(context.Background(), targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), true)
+		assert.ErrorContains(t, err, "DROP COLUMN `legacy`")
+		assert.NoError(t, targetMock.ExpectationsWereMet())
+	})
+
+	t.Run("approve without approval", func(t *testing.T) {
+		engine, _, _, targetDB, targetMock := newMockEngine(t)
+		mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", SchemaPolicy: SchemaPolicyApprove}
+		expectTargetSchema(targetMock, legacyColumns(), indexes())
+
+		err := engine.evolveTargetSchema(context.Background(), targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), false)
+		assert.ErrorIs(t, err, errSchemaApprovalRequired)
+		assert.NoError(t, targetMock.ExpectationsWereMet())
+	})
+
+	t.Run("approve with approval", func(t *testing.T) {
+		engine, _, _, targetDB, targetMock := newMockEngine(t)
+		mockRepo := new(MockRepository)
+		engine.repo = mockRepo
+		mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", SchemaPolicy: SchemaPolicyApprove, ApproveSchemaChanges: true}
+		expectTargetSchema(targetMock, legacyColumns(), indexes())
+		targetMock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `replica`.`orders` DROP COLUMN `legacy`")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		// The approval is used up
+		mockRepo.On("UpdateTableMapping", mock.Anything, "mapping-1", mock.MatchedBy(func(m *TableMapping) bool {
+			return !m.ApproveSchemaChanges && m.SchemaPolicy == SchemaPolicyApprove
+		})).Return(nil)
+
+		require.NoError(t, engine.evolveTargetSchema(context.Background(), targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), false))
+		assert.NoError(t, targetMock.ExpectationsWereMet())
+		mockRepo.AssertExpectations(t)
+		assert.False(t, mapping.ApproveSchemaChanges)
+	})
+}
@@ -0,0 +1,335 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// schemaObjectTypeOrder is the order in which schema objects are created on the target. Views may
+// call functions, and triggers and events may call procedures and query views.
+var schemaObjectTypeOrder = []SchemaObjectType{
+	SchemaObjectFunction,
+	SchemaObjectProcedure,
+	SchemaObjectView,
+	SchemaObjectTrigger,
+	SchemaObjectEvent,
+}
+
+// definerClause matches the DEFINER clause of a CREATE statement
+var definerClause = regexp.MustCompile("(?i)\\s+DEFINER\\s*=\\s*(`[^`]*`|'[^']*'|[^\\s@]+)@(`[^`]*`|'[^']*'|\\S+)")
+
+// eventStatus matches the ENABLE or DISABLE clause SHOW CREATE EVENT prints after ON COMPLETION
+var eventStatus = regexp.MustCompile("(?is)^(.*?\\bON\\s+COMPLETION\\s+(?:NOT\\s+)?PRESERVE)(\\s+(?:ENABLE|DISABLE(?:\\s+ON\\s+(?:SLAVE|REPLICA))?)\\b)?")
+
+// disabledOnReplica matches the DISABLE ON REPLICA clause of an event
+var disabledOnReplica = regexp.MustCompile("(?i)\\bDISABLE\\s+ON\\s+REPLICA\\b")
+
+// eventBody matches the DO keyword starting the body of an event without an ON COMPLETION clause
+var eventBody = regexp.MustCompile("(?i)\\sDO\\s")
+
+// schemaObject is a view, routine, trigger or event of a database
+type schemaObject struct {
+	Type       SchemaObjectType
+	Name       string
+	Table      string            // Table of a trigger
+	Definition string            // CREATE statement
+	Session    map[string]string // Session variables the object was created under, such as sql_mode
+}
+
+// SyncSchemaObjects replicates the views, routines, triggers and events of the source database
+// that the config's schema objects option selects. Objects missing on the target are created,
+// objects whose definition differs are replaced and matching ones are left alone. DEFINER clauses
+// are dropped so the target user becomes the definer, and references to the source database point
+// to the target database. Events are created DISABLE ON SLAVE unless the config enables them, so
+// scheduled purges and rollups don't change the synced data on the target. The outcome is reported
+// per object; only failing to read the object lists ends the step early.
+func (e *DefaultSyncEngine) SyncSchemaObjects(ctx context.Context, job *SyncJob, config *SyncConfig) ([]*SchemaObjectResult, error) {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":         job.ID,
+		"sync_config_id": config.ID,
+	}).Info("Starting schema objects synchronization")
+
+	endpoints, err := e.openConfigEndpoints(ctx, config.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer endpoints.Close()
+
+	selected := make(map[SchemaObjectType]bool)
+	enableEvents := false
+	if config.Options != nil {
+		for _, objectType := range config.Options.SchemaObjects {
+			selected[objectType] = true
+		}
+		enableEvents = config.Options.EnableEvents
+	}
+
+	// Triggers follow their tables to the target tables of the enabled mappings
+	targetTables := make(map[string]string)
+	for _, mapping := range config.Tables {
+		if mapping.Enabled {
+			targetTables[mapping.SourceTable] = mapping.TargetTable
+		}
+	}
+
+	// Session variables are set per object, so all objects are created over one connection
+	conn, err := endpoints.targetDB.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target connection: %w", err)
+	}
+	defer conn.Close()
+
+	var results []*SchemaObjectResult
+	for _, objectType := range schemaObjectTypeOrder {
+		if !selected[objectType] {
+			continue
+		}
+
+		objects, err := listSchemaObjects(ctx, endpoints.sourceDB, endpoints.sourceDBName, objectType)
+		if err != nil {
+			return results, err
+		}
+		existing, err := listSchemaObjects(ctx, endpoints.targetDB, endpoints.targetDBName, objectType)
+		if err != nil {
+			return results, err
+		}
+		exists := make(map[string]bool, len(existing))
+		for _, object := range existing {
+			exists[object.Name] = true
+		}
+
+		var loaded []*schemaObject
+		for _, object := range objects {
+			targetTable, ok := targetTables[object.Table]
+			if objectType == SchemaObjectTrigger && !ok {
+				results = append(results, &SchemaObjectResult{Type: objectType, Name: object.Name, Status: SchemaObjectSkipped,
+					Error: fmt.Sprintf("table %s is not synced", object.Table)})
+				continue
+			}
+			if err := showCreateSchemaObject(ctx, endpoints.sourceDB, endpoints.sourceDBName, object); err != nil {
+				results = append(results, &SchemaObjectResult{Type: objectType, Name: object.Name, Status: SchemaObjectFailed, Error: err.Error()})
+				continue
+			}
+			object.Definition = rewriteSchemaObject(object.Definition, endpoints.sourceDBName, endpoints.targetDBName)
+			if objectType == SchemaObjectTrigger {
+				object.Definition = rewriteTriggerTable(object.Definition, object.Table, targetTable)
+			}
+			if objectType == SchemaObjectEvent && !enableEvents {
+				object.Definition = disableEvent(object.Definition)
+			}
+			loaded = append(loaded, object)
+		}
+		if objectType == SchemaObjectView {
+			loaded = orderViews(loaded)
+		}
+
+		for _, object := range loaded {
+			result := e.replicateSchemaObject(ctx, conn, endpoints, object, exists[object.Name])
+			if result.Status == SchemaObjectFailed {
+				e.logger.WithFields(logrus.Fields{
+					"job_id": job.ID,
+					"type":   object.Type,
+					"name":   object.Name,
+					"error":  result.Error,
+				}).Warn("Failed to replicate schema object")
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// replicateSchemaObject creates an object on the target or replaces it when its definition differs
+func (e *DefaultSyncEngine) replicateSchemaObject(ctx context.Context, conn *sqlx.Conn, endpoints *syncEndpoints, object *schemaObject, exists bool) *SchemaObjectResult {
+	result := &SchemaObjectResult{Type: object.Type, Name: object.Name, Status: SchemaObjectCreated}
+	if exists {
+		current := &schemaObject{Type: object.Type, Name: object.Name}
+		if err := showCreateSchemaObject(ctx, endpoints.targetDB, endpoints.targetDBName, current); err == nil &&
+			normalizeSchemaObject(current.Definition) == normalizeSchemaObject(object.Definition) {
+			result.Status = SchemaObjectUnchanged
+			return result
+		}
+		result.Status = SchemaObjectUpdated
+	}
+
+	if err := applySchemaObject(ctx, conn, object, exists); err != nil {
+		result.Status = SchemaObjectFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// applySchemaObject runs the statements creating or replacing an object under its session variables
+func applySchemaObject(ctx context.Context, conn *sqlx.Conn, object *schemaObject, replace bool) error {
+	for name, value := range object.Session {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION %s = ?", name), value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+
+	statements := []string{object.Definition}
+	if replace {
+		if object.Type == SchemaObjectView {
+			statements = []string{"CREATE OR REPLACE " + strings.TrimPrefix(object.Definition, "CREATE ")}
+		} else {
+			drop := fmt.Sprintf("DROP %s IF EXISTS `%s`", strings.ToUpper(string(object.Type)), object.Name)
+			statements = []string{drop, object.Definition}
+		}
+	}
+	for _, statement := range statements {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create %s %s: %w", object.Type, object.Name, err)
+		}
+	}
+	return nil
+}
+
+// listSchemaObjects returns the objects of one type in a database, without their definitions
+func listSchemaObjects(ctx context.Context, db *sqlx.DB, dbName string, objectType SchemaObjectType) ([]*schemaObject, error) {
+	var query string
+	args := []interface{}{dbName}
+	switch objectType {
+	case SchemaObjectView:
+		query = "SELECT TABLE_NAME AS name, '' AS table_name FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME"
+	case SchemaObjectFunction, SchemaObjectProcedure:
+		query = "SELECT ROUTINE_NAME AS name, '' AS table_name FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ? AND ROUTINE_TYPE = ? ORDER BY ROUTINE_NAME"
+		args = append(args, strings.ToUpper(string(objectType)))
+	case SchemaObjectTrigger:
+		query = "SELECT TRIGGER_NAME AS name, EVENT_OBJECT_TABLE AS table_name FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ? " +
+			"ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER"
+	case SchemaObjectEvent:
+		query = "SELECT EVENT_NAME AS name, '' AS table_name FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME"
+	default:
+		return nil, fmt.Errorf("unsupported schema object type: %s", objectType)
+	}
+
+	var rows []struct {
+		Name  string `db:"name"`
+		Table string `db:"table_name"`
+	}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list %ss: %w", objectType, err)
+	}
+	objects := make([]*schemaObject, len(rows))
+	for i, row := range rows {
+		objects[i] = &schemaObject{Type: objectType, Name: row.Name, Table: row.Table}
+	}
+	return objects, nil
+}
+
+// showCreateSchemaObject reads the CREATE statement and session variables of an object
+func showCreateSchemaObject(ctx context.Context, db *sqlx.DB, dbName string, object *schemaObject) error {
+	keyword := strings.ToUpper(string(object.Type))
+	rows, err := db.QueryxContext(ctx, fmt.Sprintf("SHOW CREATE %s `%s`.`%s`", keyword, dbName, object.Name))
+	if err != nil {
+		return fmt.Errorf("failed to show create %s: %w", object.Type, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to show create %s: %w", object.Type, err)
+		}
+		return fmt.Errorf("%s not found: %s", object.Type, object.Name)
+	}
+	result := make(map[string]interface{})
+	if err := rows.MapScan(result); err != nil {
+		return fmt.Errorf("failed to scan %s definition: %w", object.Type, err)
+	}
+
+	column := "Create " + keyword[:1] + strings.ToLower(keyword[1:])
+	if object.Type == SchemaObjectTrigger {
+		column = "SQL Original Statement"
+	}
+	// The definition of a routine is NULL to users without the privilege to read it
+	definition, ok := result[column]
+	if !ok || definition == nil {
+		return fmt.Errorf("no privilege to read the definition of %s %s", object.Type, object.Name)
+	}
+	object.Definition = formatKeyValue(definition)
+
+	object.Session = make(map[string]string)
+	for _, variable := range []string{"sql_mode", "time_zone"} {
+		if value, ok := result[variable]; ok && value != nil {
+			object.Session[variable] = formatKeyValue(value)
+		}
+	}
+	return nil
+}
+
+// rewriteSchemaObject drops the DEFINER clause of a CREATE statement and points references to the
+// source database to the target database
+func rewriteSchemaObject(definition, sourceDBName, targetDBName string) string {
+	definition = definerClause.ReplaceAllString(definition, "")
+	if sourceDBName == targetDBName {
+		return definition
+	}
+	reference := regexp.MustCompile("`" + regexp.QuoteMeta(sourceDBName) + "`\\.|\\b" + regexp.QuoteMeta(sourceDBName) + "\\.")
+	return reference.ReplaceAllLiteralString(definition, "`"+targetDBName+"`.")
+}
+
+// rewriteTriggerTable points a CREATE TRIGGER statement to the target table of its table
+func rewriteTriggerTable(definition, sourceTable, targetTable string) string {
+	if sourceTable == targetTable {
+		return definition
+	}
+	table := regexp.MustCompile("(?i)(\\sON\\s+)((`[^`]+`|\\w+)\\.)?(`" + regexp.QuoteMeta(sourceTable) + "`|" + regexp.QuoteMeta(sourceTable) + "\\b)")
+	loc := table.FindStringSubmatchIndex(definition)
+	if loc == nil {
+		return definition
+	}
+	return definition[:loc[3]] + "`" + targetTable + "`" + definition[loc[1]:]
+}
+
+// disableEvent makes a CREATE EVENT statement create the event DISABLE ON SLAVE, as replication
+// does, replacing the ENABLE or DISABLE clause it has
+func disableEvent(definition string) string {
+	if loc := eventStatus.FindStringSubmatchIndex(definition); loc != nil {
+		return definition[:loc[3]] + " DISABLE ON SLAVE" + definition[loc[1]:]
+	}
+	if loc := eventBody.FindStringIndex(definition); loc != nil {
+		return definition[:loc[0]] + " DISABLE ON SLAVE" + definition[loc[0]:]
+	}
+	return definition
+}
+
+// normalizeSchemaObject reduces a CREATE statement to what two equal definitions have in common.
+// Newer servers print DISABLE ON SLAVE as DISABLE ON REPLICA.
+func normalizeSchemaObject(definition string) string {
+	definition = disabledOnReplica.ReplaceAllString(definerClause.ReplaceAllString(definition, ""), "DISABLE ON SLAVE")
+	return strings.Join(strings.Fields(definition), " ")
+}
+
+// orderViews orders views so that views come after the views they select from
+func orderViews(views []*schemaObject) []*schemaObject {
+	byName := make(map[string]*schemaObject, len(views))
+	names := make([]string, len(views))
+	for i, view := range views {
+		byName[view.Name] = view
+		names[i] = view.Name
+	}
+
+	// SHOW CREATE VIEW quotes every identifier, so a view referencing another contains its quoted name
+	var edges []foreignKeyEdge
+	for _, view := range views {
+		for _, other := range views {
+			if other != view && strings.Contains(view.Definition, "`"+other.Name+"`") {
+				edges = append(edges, foreignKeyEdge{Table: view.Name, ReferencedTable: other.Name})
+			}
+		}
+	}
+
+	ordered := make([]*schemaObject, 0, len(views))
+	for _, group := range orderTablesByDependencies(names, edges).Groups {
+		for _, name := range group {
+			ordered = append(ordered, byName[name])
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteSchemaObject(t *testing.T) {
+	view := "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`10.0.%` SQL SECURITY DEFINER VIEW `paid_orders` AS " +
+		"select `shop`.`orders`.`id` AS `id` from `shop`.`orders` where (`shop`.`orders`.`status` = 'paid')"
+	assert.Equal(t, "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `paid_orders` AS "+
+		"select `replica`.`orders`.`id` AS `id` from `replica`.`orders` where (`replica`.`orders`.`status` = 'paid')",
+		rewriteSchemaObject(view, "shop", "replica"))
+
+	// Routine bodies keep the references as written
+	procedure := "CREATE DEFINER='root'@'localhost' PROCEDURE `archive`()\nBEGIN\n  INSERT INTO shop.archive SELECT * FROM shop.orders;\nEND"
+	assert.Equal(t, "CREATE PROCEDURE `archive`()\nBEGIN\n  INSERT INTO `replica`.archive SELECT * FROM `replica`.orders;\nEND",
+		rewriteSchemaObject(procedure, "shop", "replica"))
+
+	// Other databases whose names end in the source name are left alone
+	assert.Equal(t, "CREATE VIEW `v` AS select * from myshop.orders", rewriteSchemaObject("CREATE VIEW `v` AS select * from myshop.orders", "shop", "replica"))
+}
+
+func TestRewriteTriggerTable(t *testing.T) {
+	trigger := "CREATE TRIGGER `orders_bi` BEFORE INSERT ON orders FOR EACH ROW SET NEW.created_on = CURDATE()"
+	assert.Equal(t, "CREATE TRIGGER `orders_bi` BEFORE INSERT ON `orders_copy` FOR EACH ROW SET NEW.created_on = CURDATE()",
+		rewriteTriggerTable(trigger, "orders", "orders_copy"))
+	assert.Equal(t, "CREATE TRIGGER `t` AFTER DELETE ON `orders_copy` FOR EACH ROW DELETE FROM items WHERE order_id = OLD.id",
+		rewriteTriggerTable("CREATE TRIGGER `t` AFTER DELETE ON `replica`.`orders` FOR EACH ROW DELETE FROM items WHERE order_id = OLD.id", "orders", "orders_copy"))
+	assert.Equal(t, trigger, rewriteTriggerTable(trigger, "orders", "orders"))
+}
+
+func TestDisableEvent(t *testing.T) {
+	// SHOW CREATE EVENT prints the status after ON COMPLETION, a body mentioning ENABLE is left alone
+	event := "CREATE EVENT `purge` ON SCHEDULE EVERY 1 DAY STARTS '2024-03-01 00:00:00' ON COMPLETION NOT PRESERVE ENABLE " +
+		"COMMENT 'nightly' DO DELETE FROM `logs` WHERE note = 'ENABLE'"
+	assert.Equal(t, "CREATE EVENT `purge` ON SCHEDULE EVERY 1 DAY STARTS '2024-03-01 00:00:00' ON COMPLETION NOT PRESERVE DISABLE ON SLAVE "+
+		"COMMENT 'nightly' DO DELETE FROM `logs` WHERE note = 'ENABLE'", disableEvent(event))
+	assert.Equal(t, "CREATE EVENT `e` ON SCHEDULE AT '2024-03-01 00:00:00' ON COMPLETION PRESERVE DISABLE ON SLAVE DO SELECT 1",
+		disableEvent("CREATE EVENT `e` ON SCHEDULE AT '2024-03-01 00:00:00' ON COMPLETION PRESERVE DISABLE ON REPLICA DO SELECT 1"))
+	assert.Equal(t, "CREATE EVENT `e` ON SCHEDULE EVERY 1 HOUR DISABLE ON SLAVE DO SELECT 1", disableEvent("CREATE EVENT `e` ON SCHEDULE EVERY 1 HOUR DO SELECT 1"))
+
+	// A target server printing DISABLE ON REPLICA has the same definition
+	assert.Equal(t, normalizeSchemaObject(disableEvent(event)),
+		normalizeSchemaObject(strings.Replace(disableEvent(event), "DISABLE ON SLAVE", "DISABLE ON REPLICA", 1)))
+}
+
+func TestOrderViews(t *testing.T) {
+	top := &schemaObject{Name: "top_customers", Definition: "CREATE VIEW `top_customers` AS select * from `customer_totals` limit 10"}
+	totals := &schemaObject{Name: "customer_totals", Definition: "CREATE VIEW `customer_totals` AS select `customer_id` from `paid_orders`"}
+	paid := &schemaObject{Name: "paid_orders", Definition: "CREATE VIEW `paid_orders` AS select * from `orders`"}
+
+	assert.Equal(t, []*schemaObject{paid, totals, top}, orderViews([]*schemaObject{top, totals, paid}))
+}
+
+func TestReplicateSchemaObject(t *testing.T) {
+	engine, sourceDB, _, targetDB, targetMock := newMockEngine(t)
+	endpoints := &syncEndpoints{sourceDB: sourceDB, sourceDBName: "shop", targetDB: targetDB, targetDBName: "replica", dialect: mysqlDialect{}}
+	ctx := context.Background()
+	conn, err := targetDB.Connx(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	showView := regexp.QuoteMeta("SHOW CREATE VIEW `replica`.`paid_orders`")
+	viewColumns := []string{"View", "Create View", "character_set_client", "collation_connection"}
+	view := &schemaObject{Type: SchemaObjectView, Name: "paid_orders",
+		Definition: "CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `paid_orders` AS select 1 AS `id`"}
+
+	// A view that matches apart from its definer is left alone
+	targetMock.ExpectQuery(showView).WillReturnRows(sqlmock.NewRows(viewColumns).AddRow("paid_orders",
+		"CREATE ALGORITHM=UNDEFINED DEFINER=`sync`@`%` SQL SECURITY DEFINER VIEW `paid_orders` AS select 1 AS `id`", "utf8mb4", "utf8mb4_general_ci"))
+	result := engine.replicateSchemaObject(ctx, conn, endpoints, view, true)
+	assert.Equal(t, &SchemaObjectResult{Type: SchemaObjectView, Name: "paid_orders", Status: SchemaObjectUnchanged}, result)
+
+	// A differing view is replaced in place
+	targetMock.ExpectQuery(showView).WillReturnRows(sqlmock.NewRows(viewColumns).AddRow("paid_orders",
+		"CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `paid_orders` AS select 2 AS `id`", "utf8mb4", "utf8mb4_general_ci"))
+	targetMock.ExpectExec(regexp.QuoteMeta("CREATE OR REPLACE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `paid_orders` AS select 1 AS `id`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	result = engine.replicateSchemaObject(ctx, conn, endpoints, view, true)
+	assert.Equal(t, SchemaObjectUpdated, result.Status)
+
+	// A procedure is dropped and created again under its sql_mode
+	procedure := &schemaObject{Type: SchemaObjectProcedure, Name: "archive", Definition: "CREATE PROCEDURE `archive`() SELECT 1",
+		Session: map[string]string{"sql_mode": "STRICT_TRANS_TABLES"}}
+	targetMock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE PROCEDURE `replica`.`archive`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure"}).AddRow("archive", "", "CREATE PROCEDURE `archive`() SELECT 2"))
+	targetMock.ExpectExec(regexp.QuoteMeta("SET SESSION sql_mode = ?")).WithArgs("STRICT_TRANS_TABLES").WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("DROP PROCEDURE IF EXISTS `archive`")).WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("CREATE PROCEDURE `archive`() SELECT 1")).WillReturnResult(sqlmock.NewResult(0, 0))
+	result = engine.replicateSchemaObject(ctx, conn, endpoints, procedure, true)
+	assert.Equal(t, SchemaObjectUpdated, result.Status)
+
+	// A failing statement is reported on the object
+	event := &schemaObject{Type: SchemaObjectEvent, Name: "purge", Definition: "CREATE EVENT `purge` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `replica`.`logs`"}
+	targetMock.ExpectExec("CREATE EVENT").WillReturnError(errors.New("Error 1577: Cannot proceed because system tables used by Event Scheduler were found damaged"))
+	result = engine.replicateSchemaObject(ctx, conn, endpoints, event, false)
+	assert.Equal(t, SchemaObjectFailed, result.Status)
+	assert.Contains(t, result.Error, "failed to create event purge")
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestShowCreateSchemaObject(t *testing.T) {
+	_, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	ctx := context.Background()
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE FUNCTION `shop`.`net_price`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Function", "sql_mode", "Create Function", "character_set_client"}).
+			AddRow("net_price", []byte("ONLY_FULL_GROUP_BY"), []byte("CREATE FUNCTION `net_price`(p DECIMAL(10,2)) RETURNS decimal(10,2) RETURN p / 1.2"), "utf8mb4"))
+	function := &schemaObject{Type: SchemaObjectFunction, Name: "net_price"}
+	require.NoError(t, showCreateSchemaObject(ctx, sourceDB, "shop", function))
+	assert.Equal(t, "CREATE FUNCTION `net_price`(p DECIMAL(10,2)) RETURNS decimal(10,2) RETURN p / 1.2", function.Definition)
+	assert.Equal(t, map[string]string{"sql_mode": "ONLY_FULL_GROUP_BY"}, function.Session)
+
+	// Without the privilege to read it the definition is NULL
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE PROCEDURE `shop`.`archive`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Procedure", "sql_mode", "Create Procedure"}).AddRow("archive", "", nil))
+	err := showCreateSchemaObject(ctx, sourceDB, "shop", &schemaObject{Type: SchemaObjectProcedure, Name: "archive"})
+	assert.ErrorContains(t, err, "no privilege to read the definition of procedure archive")
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestJobWorker_SyncSchemaObjects(t *testing.T) {
+	mockMonitoring := &MockMonitoringService{}
+	mockSyncEngine := &MockSyncEngine{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	engine := NewJobEngine(&MockRepository{}, logger, mockMonitoring, mockSyncEngine).(*JobEngineService)
+	worker := &JobWorker{engine: engine, logger: logger}
+	job := &SyncJob{ID: "job-1", ConfigID: "config-1"}
+	config := &SyncConfig{ID: "config-1", Options: &SyncOptions{SchemaObjects: []SchemaObjectType{SchemaObjectView, SchemaObjectTrigger}}}
+	ctx := context.Background()
+
+	mockMonitoring.On("LogJobEvent", ctx, "job-1", "", mock.Anything, mock.Anything).Return(nil)
+	mockSyncEngine.On("SyncSchemaObjects", ctx, job, config).Return([]*SchemaObjectResult{
+		{Type: SchemaObjectView, Name: "paid_orders", Status: SchemaObjectCreated},
+		{Type: SchemaObjectTrigger, Name: "logs_bi", Status: SchemaObjectSkipped, Error: "table logs is not synced"},
+		{Type: SchemaObjectTrigger, Name: "orders_bi", Status: SchemaObjectFailed, Error: "Error 1064"},
+	}, nil)
+
+	// Each object is logged, a failed object doesn't fail the job
+	require.NoError(t, worker.syncSchemaObjects(ctx, job, config))
+	mockMonitoring.AssertCalled(t, "LogJobEvent", ctx, "job-1", "", "info", "View paid_orders created")
+	mockMonitoring.AssertCalled(t, "LogJobEvent", ctx, "job-1", "", "warn", "Trigger logs_bi skipped: table logs is not synced")
+	mockMonitoring.AssertCalled(t, "LogJobEvent", ctx, "job-1", "", "error", "Trigger orders_bi failed: Error 1064")
+
+	// Unless the config stops on errors
+	config.Options.ConflictResolution = ConflictResolutionError
+	err := worker.syncSchemaObjects(ctx, job, config)
+	assert.ErrorContains(t, err, "schema objects sync failed for 1 objects")
+}
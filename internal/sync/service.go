@@ -284,6 +284,40 @@ func (s *ConnectionManagerService) TestConnectionConfig(ctx context.Context, con
 func (s *ConnectionManagerService) testRemoteConnection(ctx context.Context, config *ConnectionConfig) (*ConnectionStatus, error) {
 	start := time.Now()
 
+	// A file connection is reachable when its directory can be written
+	if config.isFile() {
+		if err := checkExportDirectory(config.Path); err != nil {
+			return &ConnectionStatus{
+				Connected: false,
+				LastCheck: time.Now(),
+				Error:     err.Error(),
+			}, err
+		}
+		return &ConnectionStatus{
+			Connected: true,
+			LastCheck: time.Now(),
+			Latency:   time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	// A sqlite connection is reachable when its database file can be opened
+	if config.isSQLite() {
+		db, err := openSQLite(config.Path)
+		if err != nil {
+			return &ConnectionStatus{
+				Connected: false,
+				LastCheck: time.Now(),
+				Error:     err.Error(),
+			}, err
+		}
+		db.Close()
+		return &ConnectionStatus{
+			Connected: true,
+			LastCheck: time.Now(),
+			Latency:   time.Since(start).Milliseconds(),
+		}, nil
+	}
+
 	// Try to get pooled connection first
 	db := s.getPooledConnection(config)
 	if db == nil {
@@ -669,6 +703,15 @@ func (s *ConnectionManagerService) validateConnectionConfig(config *ConnectionCo
 	if config.Name == "" {
 		return fmt.Errorf("connection name is required")
 	}
+	switch config.Type {
+	case "", ConnectionTypeMySQL:
+	case ConnectionTypeFile:
+		return validateFileConnection(config)
+	case ConnectionTypeSQLite:
+		return validateSQLiteConnection(config)
+	default:
+		return fmt.Errorf("invalid connection type: %s", config.Type)
+	}
 	if config.Host == "" {
 		return fmt.Errorf("host is required")
 	}
@@ -732,6 +775,9 @@ type SyncManagerService struct {
 	*Service
 	monitoring MonitoringService
 	jobEngine  JobEngine
+	syncEngine SyncEngine
+
+	allowRawWhereClause bool // Whether mappings may select rows with a raw SQL where clause
 }
 
 // NewSyncManager creates a new sync manager service
@@ -772,12 +818,12 @@ func (s *SyncManagerService) CreateSyncConfig(ctx context.Context, config *SyncC
 	}
 
 	// Create table mappings (order preserved by sort_order)
-	for i, mapping := range config.Tables {
+	assignSortOrders(config.Tables)
+	for _, mapping := range config.Tables {
 		if mapping.ID == "" {
 			mapping.ID = uuid.New().String()
 		}
 		mapping.SyncConfigID = config.ID
-		mapping.SortOrder = i
 		mapping.CreatedAt = now
 		mapping.UpdatedAt = now
 
@@ -860,6 +906,34 @@ func (s *SyncManagerService) DeleteSyncConfig(ctx context.Context, id string) er
 }
 
 func (s *SyncManagerService) StartSync(ctx context.Context, configID string) (*SyncJob, error) {
+	return s.startJob(ctx, configID, JobTypeSync, VerifyRepairNone)
+}
+
+func (s *SyncManagerService) StartVerify(ctx context.Context, configID string, repair VerifyRepair) (*SyncJob, error) {
+	if !isValidVerifyRepair(repair) {
+		return nil, fmt.Errorf("invalid verify repair mode: %s", repair)
+	}
+	return s.startJob(ctx, configID, JobTypeVerify, repair)
+}
+
+func (s *SyncManagerService) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	job, err := s.repo.GetSyncJob(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync job: %w", err)
+	}
+	if job.Type != JobTypeVerify {
+		return nil, fmt.Errorf("job %s is not a verify job", jobID)
+	}
+
+	reports, err := s.repo.GetVerifyReports(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verify reports: %w", err)
+	}
+	return reports, nil
+}
+
+// startJob creates a job of the given type for a sync configuration and submits it to the job engine
+func (s *SyncManagerService) startJob(ctx context.Context, configID string, jobType JobType, repair VerifyRepair) (*SyncJob, error) {
 	// Get sync configuration
 	syncConfig, err := s.repo.GetSyncConfig(ctx, configID)
 	if err != nil {
@@ -884,6 +958,8 @@ func (s *SyncManagerService) StartSync(ctx context.Context, configID string) (*S
 			TotalTables: len(syncConfig.Tables),
 		},
 		CreatedAt: time.Now(),
+		Type:      jobType,
+		Repair:    repair,
 	}
 
 	// Save job to repository
@@ -897,7 +973,11 @@ func (s *SyncManagerService) StartSync(ctx context.Context, configID string) (*S
 	}
 
 	// Log job event
-	if err := s.monitoring.LogJobEvent(ctx, job.ID, "", "info", "Sync job created"); err != nil {
+	message := "Sync job created"
+	if jobType == JobTypeVerify {
+		message = "Verify job created"
+	}
+	if err := s.monitoring.LogJobEvent(ctx, job.ID, "", "info", message); err != nil {
 		s.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to log job event")
 	}
 
@@ -921,9 +1001,10 @@ func (s *SyncManagerService) StartSync(ctx context.Context, configID string) (*S
 
 		s.logger.WithFields(logrus.Fields{
 			"job_id":         job.ID,
+			"job_type":       jobType,
 			"sync_config_id": configID,
 			"total_tables":   len(syncConfig.Tables),
-		}).Info("Sync job submitted to engine successfully")
+		}).Info("Job submitted to engine successfully")
 	} else {
 		s.logger.WithField("job_id", job.ID).Warn("Job engine not available, job will remain in pending state")
 	}
@@ -986,16 +1067,64 @@ func (s *SyncManagerService) validateSyncConfig(config *SyncConfig) error {
 			return fmt.Errorf("target table is required for mapping %d", i)
 		}
 		// Validate sync mode
-		if mapping.SyncMode != SyncModeFull && mapping.SyncMode != SyncModeIncremental {
+		if !isValidSyncMode(mapping.SyncMode) {
 			return fmt.Errorf("invalid sync mode for mapping %d: %s", i, mapping.SyncMode)
 		}
+		if err := validateDeleteDetection(mapping); err != nil {
+			return fmt.Errorf("invalid delete detection for mapping %d: %w", i, err)
+		}
+		if !isValidSchemaPolicy(mapping.SchemaPolicy) {
+			return fmt.Errorf("invalid schema policy for mapping %d: %s", i, mapping.SchemaPolicy)
+		}
+		if err := validateChangeTracking(mapping); err != nil {
+			return fmt.Errorf("invalid change tracking for mapping %d: %w", i, err)
+		}
+		if err := validateWriteMode(mapping); err != nil {
+			return fmt.Errorf("invalid write mode for mapping %d: %w", i, err)
+		}
+		if mapping.keepsTargetRows() && config.Options != nil && config.Options.ShadowSwap {
+			return fmt.Errorf("write mode %s of mapping %d keeps the rows of the target table and cannot be used with shadow swap", mapping.WriteMode, i)
+		}
+		if err := validateRowSelection(mapping, s.allowRawWhereClause); err != nil {
+			return fmt.Errorf("invalid row filter for mapping %d: %w", i, err)
+		}
+		if err := validateColumnRules(mapping.ColumnRules); err != nil {
+			return fmt.Errorf("invalid column rules for mapping %d: %w", i, err)
+		}
+		if err := validateMaskingRules(mapping.MaskingRules); err != nil {
+			return fmt.Errorf("invalid masking rules for mapping %d: %w", i, err)
+		}
 	}
 
 	// Validate sync mode
-	if config.SyncMode != SyncModeFull && config.SyncMode != SyncModeIncremental {
+	if !isValidSyncMode(config.SyncMode) {
 		return fmt.Errorf("invalid sync mode: %s", config.SyncMode)
 	}
 
+	// Validate schedule
+	timezone := ""
+	if config.Options != nil {
+		timezone = config.Options.Timezone
+		switch config.Options.MisfirePolicy {
+		case "", MisfirePolicySkip, MisfirePolicyRunOnce:
+		default:
+			return fmt.Errorf("invalid misfire policy: %s", config.Options.MisfirePolicy)
+		}
+		for _, objectType := range config.Options.SchemaObjects {
+			if !isValidSchemaObjectType(objectType) {
+				return fmt.Errorf("invalid schema object type: %s", objectType)
+			}
+		}
+	}
+	if err := validateRequireMasking(config.Options); err != nil {
+		return err
+	}
+	if config.Schedule != "" {
+		if err := ValidateSchedule(config.Schedule, timezone); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1226,9 +1355,15 @@ func (s *SyncManagerService) AddTableMapping(ctx context.Context, syncConfigID s
 		mapping.ID = uuid.New().String()
 	}
 
-	// Set sort_order to append at end
+	// Set sort_order to append at end, in the last group when the mapping is concurrent with it
 	existing, _ := s.repo.GetTableMappings(ctx, syncConfigID)
-	mapping.SortOrder = len(existing)
+	mapping.SortOrder = 0
+	if len(existing) > 0 {
+		mapping.SortOrder = existing[len(existing)-1].SortOrder
+		if !mapping.ConcurrentWithPrevious {
+			mapping.SortOrder++
+		}
+	}
 
 	// Set sync config ID and timestamps
 	mapping.SyncConfigID = syncConfigID
@@ -1335,7 +1470,7 @@ func (s *SyncManagerService) ToggleTableMapping(ctx context.Context, mappingID s
 // Requirement 3.3: Configure table sync rules (full/incremental mode)
 func (s *SyncManagerService) SetTableSyncMode(ctx context.Context, mappingID string, syncMode SyncMode) error {
 	// Validate sync mode
-	if syncMode != SyncModeFull && syncMode != SyncModeIncremental {
+	if !isValidSyncMode(syncMode) {
 		return fmt.Errorf("invalid sync mode: %s", syncMode)
 	}
 
@@ -1372,7 +1507,9 @@ func (s *SyncManagerService) SetTableSyncMode(ctx context.Context, mappingID str
 	return nil
 }
 
-// ReorderTableMappings updates the sync order of table mappings (order of mappingIDs = execution order)
+// ReorderTableMappings updates the sync order of table mappings (order of mappingIDs = execution order).
+// Mappings keep their concurrent_with_previous flag, so concurrency groups move with their tables;
+// mappings missing from mappingIDs run after the listed ones, in their current order.
 func (s *SyncManagerService) ReorderTableMappings(ctx context.Context, syncConfigID string, mappingIDs []string) error {
 	if len(mappingIDs) == 0 {
 		return nil
@@ -1387,17 +1524,32 @@ func (s *SyncManagerService) ReorderTableMappings(ctx context.Context, syncConfi
 	for _, m := range mappings {
 		byID[m.ID] = m
 	}
-
-	for i, id := range mappingIDs {
+	ordered := make([]*TableMapping, 0, len(mappings))
+	for _, id := range mappingIDs {
 		m, ok := byID[id]
 		if !ok {
 			return fmt.Errorf("table mapping not found: %s", id)
 		}
-		if m.SortOrder == i {
+		delete(byID, id)
+		ordered = append(ordered, m)
+	}
+	for _, m := range mappings {
+		if _, ok := byID[m.ID]; ok {
+			ordered = append(ordered, m)
+		}
+	}
+
+	previous := make(map[string]int, len(ordered))
+	for _, m := range ordered {
+		previous[m.ID] = m.SortOrder
+	}
+	assignSortOrders(ordered)
+
+	for _, m := range ordered {
+		if m.SortOrder == previous[m.ID] {
 			continue
 		}
-		m.SortOrder = i
-		if err := s.repo.UpdateTableMapping(ctx, id, m); err != nil {
+		if err := s.repo.UpdateTableMapping(ctx, m.ID, m); err != nil {
 			return fmt.Errorf("failed to update table mapping order: %w", err)
 		}
 	}
@@ -1410,6 +1562,133 @@ func (s *SyncManagerService) ReorderTableMappings(ctx context.Context, syncConfi
 	return nil
 }
 
+// SortTableMappingsByDependencies assigns the sort order of the table mappings of a sync config
+// from the foreign keys between their source tables. Tables of one dependency depth share a sort
+// order, so a job syncs them concurrently after the tables they reference. Tables referencing each
+// other in a cycle share a sort order too; the cycles are returned and need foreign key checks
+// disabled on the target.
+func (s *SyncManagerService) SortTableMappingsByDependencies(ctx context.Context, syncConfigID string) (*TableDependencyOrder, error) {
+	config, err := s.repo.GetSyncConfig(ctx, syncConfigID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync config: %w", err)
+	}
+
+	connectionConfig, err := s.repo.GetConnection(ctx, config.SourceConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection config: %w", err)
+	}
+	database := config.SourceDatabase
+	if database == "" {
+		database = connectionConfig.Database
+	}
+	cc := *connectionConfig
+	cc.Database = database
+
+	db, err := s.createRemoteConnection(&cc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote database: %w", err)
+	}
+	defer db.Close()
+
+	edges, err := loadForeignKeys(ctx, db, database)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.applyDependencyOrder(ctx, config.Tables, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order.Cycles) > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"sync_config_id": syncConfigID,
+			"cycles":         order.Cycles,
+		}).Warn("Table mappings have cyclic foreign keys")
+	}
+	s.logger.WithFields(logrus.Fields{
+		"sync_config_id": syncConfigID,
+		"groups":         len(order.Groups),
+	}).Info("Table mappings sorted by dependencies")
+
+	return order, nil
+}
+
+// PlanSync works out what a sync job of the configuration would do, planning the enabled table
+// mappings in the order the job syncs them. A table that can't be planned is reported as a problem.
+func (s *SyncManagerService) PlanSync(ctx context.Context, configID string) (*SyncPlan, error) {
+	if s.syncEngine == nil {
+		return nil, fmt.Errorf("sync engine not available")
+	}
+
+	config, err := s.repo.GetSyncConfig(ctx, configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync config: %w", err)
+	}
+
+	plan := &SyncPlan{ConfigID: configID, Tables: []*TablePlan{}}
+	if !config.Enabled {
+		plan.Problems = append(plan.Problems, "sync config is disabled")
+	}
+
+	for _, mapping := range config.Tables {
+		if !mapping.Enabled {
+			continue
+		}
+
+		tablePlan, err := s.syncEngine.PlanTable(ctx, mapping)
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"sync_config_id": configID,
+				"source_table":   mapping.SourceTable,
+			}).Warn("Failed to plan table sync")
+			tablePlan = &TablePlan{
+				MappingID:   mapping.ID,
+				SourceTable: mapping.SourceTable,
+				TargetTable: mapping.TargetTable,
+				SyncMode:    mapping.SyncMode,
+				Problems:    []string{err.Error()},
+			}
+		}
+		plan.Tables = append(plan.Tables, tablePlan)
+	}
+
+	plan.Blocked = len(plan.Problems) > 0
+	for _, tablePlan := range plan.Tables {
+		if len(tablePlan.Problems) > 0 {
+			plan.Blocked = true
+		}
+	}
+	return plan, nil
+}
+
+// applyDependencyOrder sets the sort order of each mapping to the group of its source table
+func (s *SyncManagerService) applyDependencyOrder(ctx context.Context, mappings []*TableMapping, edges []foreignKeyEdge) (*TableDependencyOrder, error) {
+	tables := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		tables[i] = mapping.SourceTable
+	}
+	order := orderTablesByDependencies(tables, edges)
+
+	group := make(map[string]int)
+	for i, tables := range order.Groups {
+		for _, table := range tables {
+			group[table] = i
+		}
+	}
+	for _, mapping := range mappings {
+		sortOrder := group[mapping.SourceTable]
+		if mapping.SortOrder == sortOrder {
+			continue
+		}
+		mapping.SortOrder = sortOrder
+		if err := s.repo.UpdateTableMapping(ctx, mapping.ID, mapping); err != nil {
+			return nil, fmt.Errorf("failed to update table mapping order: %w", err)
+		}
+	}
+	return order, nil
+}
+
 // validateTableMapping validates a table mapping configuration
 func (s *SyncManagerService) validateTableMapping(mapping *TableMapping) error {
 	if mapping.SourceTable == "" {
@@ -1418,9 +1697,35 @@ func (s *SyncManagerService) validateTableMapping(mapping *TableMapping) error {
 	if mapping.TargetTable == "" {
 		return fmt.Errorf("target table is required")
 	}
-	if mapping.SyncMode != SyncModeFull && mapping.SyncMode != SyncModeIncremental {
+	if !isValidSyncMode(mapping.SyncMode) {
 		return fmt.Errorf("invalid sync mode: %s", mapping.SyncMode)
 	}
+	if err := validateDeleteDetection(mapping); err != nil {
+		return err
+	}
+	if !isValidSchemaPolicy(mapping.SchemaPolicy) {
+		return fmt.Errorf("invalid schema policy: %s", mapping.SchemaPolicy)
+	}
+	if err := validateChangeTracking(mapping); err != nil {
+		return err
+	}
+	if err := validateWriteMode(mapping); err != nil {
+		return err
+	}
+	if err := validateRowSelection(mapping, s.allowRawWhereClause); err != nil {
+		return err
+	}
+	if err := validateColumnRules(mapping.ColumnRules); err != nil {
+		return err
+	}
+	if err := validateMaskingRules(mapping.MaskingRules); err != nil {
+		return err
+	}
+	if mapping.FileSource != nil {
+		if err := mapping.FileSource.validate(); err != nil {
+			return err
+		}
+	}
 
 	// Validate target table name format (MySQL identifier rules)
 	if !isValidMySQLIdentifier(mapping.TargetTable) {
@@ -1430,6 +1735,90 @@ func (s *SyncManagerService) validateTableMapping(mapping *TableMapping) error {
 	return nil
 }
 
+// assignSortOrders numbers table mappings by their position in the list. A mapping marked as
+// concurrent with the previous one shares its sort order, so a job syncs both in one group.
+func assignSortOrders(mappings []*TableMapping) {
+	for i, mapping := range mappings {
+		switch {
+		case i == 0:
+			mapping.SortOrder = 0
+		case mapping.ConcurrentWithPrevious:
+			mapping.SortOrder = mappings[i-1].SortOrder
+		default:
+			mapping.SortOrder = mappings[i-1].SortOrder + 1
+		}
+	}
+}
+
+// markConcurrentMappings marks the mappings, ordered by sort order, that share the sort order of
+// the mapping before them, so that a config read and saved back keeps its groups
+func markConcurrentMappings(mappings []*TableMapping) {
+	for i, mapping := range mappings {
+		mapping.ConcurrentWithPrevious = i > 0 && mapping.SortOrder == mappings[i-1].SortOrder
+	}
+}
+
+// validateDeleteDetection validates the delete detection settings of a table mapping
+func validateDeleteDetection(mapping *TableMapping) error {
+	if !isValidDeleteDetection(mapping.DeleteDetection) {
+		return fmt.Errorf("invalid delete detection: %s", mapping.DeleteDetection)
+	}
+	if mapping.SoftDeleteColumn != "" {
+		if mapping.DeleteDetection != DeleteDetectionSoft {
+			return fmt.Errorf("soft delete column requires soft delete detection")
+		}
+		if !isValidMySQLIdentifier(mapping.SoftDeleteColumn) {
+			return fmt.Errorf("invalid soft delete column name: %s", mapping.SoftDeleteColumn)
+		}
+	}
+	return nil
+}
+
+// validateChangeTracking validates the change tracking settings of a table mapping
+func validateChangeTracking(mapping *TableMapping) error {
+	if mapping.TrackingColumn != "" && !isValidMySQLIdentifier(mapping.TrackingColumn) {
+		return fmt.Errorf("invalid tracking column name: %s", mapping.TrackingColumn)
+	}
+	if mapping.LookbackSeconds < 0 {
+		return fmt.Errorf("lookback seconds must not be negative: %d", mapping.LookbackSeconds)
+	}
+	return nil
+}
+
+// validateWriteMode validates the write mode of a table mapping and the columns it updates
+func validateWriteMode(mapping *TableMapping) error {
+	if !isValidWriteMode(mapping.WriteMode) {
+		return fmt.Errorf("invalid write mode: %s", mapping.WriteMode)
+	}
+	if mapping.WriteMode != WriteModeDefault && mapping.SyncMode == SyncModeCDC {
+		return fmt.Errorf("write mode %s is not supported by cdc sync, which applies the changes of the binlog as they are", mapping.WriteMode)
+	}
+	if len(mapping.UpdateColumns) > 0 && mapping.WriteMode != WriteModeUpsert && mapping.WriteMode != WriteModeUpsertNewer {
+		return fmt.Errorf("update columns require the upsert or upsert_newer write mode")
+	}
+	for _, col := range mapping.UpdateColumns {
+		if !isValidMySQLIdentifier(col) {
+			return fmt.Errorf("invalid update column name: %s", col)
+		}
+	}
+	return nil
+}
+
+// validateRowSelection validates the row filter of a table mapping and rejects a raw where clause
+// unless the configuration allows one
+func validateRowSelection(mapping *TableMapping, allowRawWhereClause bool) error {
+	if mapping.WhereClause != "" && !allowRawWhereClause {
+		return fmt.Errorf("raw where clause is not allowed, use a row filter or set sync.allow_raw_where_clause")
+	}
+	if err := checkCDCRowSelection(mapping); err != nil {
+		return err
+	}
+	if mapping.RowFilter != nil {
+		return mapping.RowFilter.validate()
+	}
+	return nil
+}
+
 // updateTableMappings handles the update of table mappings when sync config is updated
 func (s *SyncManagerService) updateTableMappings(ctx context.Context, syncConfigID string, existingMappings, newMappings []*TableMapping) error {
 	// Create maps for easier comparison
@@ -1455,9 +1844,9 @@ func (s *SyncManagerService) updateTableMappings(ctx context.Context, syncConfig
 	}
 
 	// Update existing and create new mappings (order = index in newMappings)
+	assignSortOrders(newMappings)
 	now := time.Now()
-	for i, mapping := range newMappings {
-		mapping.SortOrder = i
+	for _, mapping := range newMappings {
 		if mapping.ID == "" {
 			// New mapping
 			mapping.ID = uuid.New().String()
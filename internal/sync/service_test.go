@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -529,6 +530,24 @@ func (r *testRepository) GetSyncConfigs(ctx context.Context, connectionID string
 	return configs, nil
 }
 
+func (r *testRepository) GetAllSyncConfigs(ctx context.Context) ([]*SyncConfig, error) {
+	var configs []*SyncConfig
+	for _, config := range r.syncConfigs {
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (r *testRepository) UpdateSyncConfigRunTimes(ctx context.Context, id string, lastRunAt, nextRunAt *time.Time) error {
+	config, exists := r.syncConfigs[id]
+	if !exists {
+		return mockError("sync config not found")
+	}
+	config.LastRunAt = lastRunAt
+	config.NextRunAt = nextRunAt
+	return nil
+}
+
 func (r *testRepository) UpdateSyncConfig(ctx context.Context, id string, config *SyncConfig) error {
 	if _, exists := r.syncConfigs[id]; !exists {
 		return mockError("sync config not found")
@@ -601,6 +620,22 @@ func (r *testRepository) GetJobsByStatus(ctx context.Context, status JobStatus)
 	return jobs, nil
 }
 
+func (r *testRepository) CreateVerifyReport(ctx context.Context, report *TableVerifyReport) error {
+	return nil // Simplified for testing
+}
+
+func (r *testRepository) GetVerifyReports(ctx context.Context, jobID string) ([]*TableVerifyReport, error) {
+	return nil, nil // Simplified for testing
+}
+
+func (r *testRepository) RecordImportedFile(ctx context.Context, file *ImportedFile) error {
+	return nil // Simplified for testing
+}
+
+func (r *testRepository) GetImportedFiles(ctx context.Context, tableMappingID string) ([]*ImportedFile, error) {
+	return nil, nil // Simplified for testing
+}
+
 func (r *testRepository) CreateCheckpoint(ctx context.Context, checkpoint *SyncCheckpoint) error {
 	return nil // Simplified for testing
 }
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	shadowTableSuffix = "__dbtaxi_new"
+	oldTableSuffix    = "__dbtaxi_old"
+
+	// maxTableNameLength is the MySQL identifier limit for table names
+	maxTableNameLength = 64
+)
+
+// shadowTableName returns the table a shadow swap full sync loads into
+func shadowTableName(targetTable string) (string, error) {
+	if len(targetTable)+len(shadowTableSuffix) > maxTableNameLength {
+		return "", fmt.Errorf("target table name %s is too long for a shadow table, at most %d characters are allowed",
+			targetTable, maxTableNameLength-len(shadowTableSuffix))
+	}
+	return targetTable + shadowTableSuffix, nil
+}
+
+// oldTableName returns the name the replaced live table gets during a swap
+func oldTableName(targetTable string) string {
+	return targetTable + oldTableSuffix
+}
+
+// validateShadowTable checks a loaded shadow table before it replaces the live table:
+// it must have every source column and hold as many rows as the source currently selects
+func (e *DefaultSyncEngine) validateShadowTable(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, schema *TableSchema) error {
+	var shadowColumns []string
+	columnsQuery := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	if err := targetDB.SelectContext(ctx, &shadowColumns, columnsQuery, targetDBName, mapping.TargetTable); err != nil {
+		return fmt.Errorf("failed to get shadow table columns: %w", err)
+	}
+
+	present := make(map[string]bool, len(shadowColumns))
+	for _, col := range shadowColumns {
+		present[col] = true
+	}
+	for _, col := range schema.Columns {
+		if !present[col.Name] {
+			return fmt.Errorf("shadow table %s is missing column %s, keeping the live table", mapping.TargetTable, col.Name)
+		}
+	}
+
+	filter := mapping.rowFilter()
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`%s", sourceDBName, mapping.SourceTable, filter.where())
+	var sourceRows int64
+	if err := sourceDB.GetContext(ctx, &sourceRows, countQuery, filter.args...); err != nil {
+		return fmt.Errorf("failed to get row count: %w", err)
+	}
+
+	var shadowRows int64
+	shadowQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", targetDBName, mapping.TargetTable)
+	if err := targetDB.GetContext(ctx, &shadowRows, shadowQuery); err != nil {
+		return fmt.Errorf("failed to get shadow table row count: %w", err)
+	}
+
+	if shadowRows != sourceRows {
+		return fmt.Errorf("shadow table %s has %d rows but the source has %d, keeping the live table",
+			mapping.TargetTable, shadowRows, sourceRows)
+	}
+
+	return nil
+}
+
+// swapShadowTable replaces the live target table with its loaded shadow table in a single
+// RENAME TABLE, so readers see either the old or the new copy but never a partial one
+func (e *DefaultSyncEngine) swapShadowTable(ctx context.Context, targetDB *sqlx.DB, d Dialect, targetDBName, targetTable string, keepOld bool) error {
+	// A cancelled job leaves the live table as it is
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shadowTable := targetTable + shadowTableSuffix
+	oldTable := oldTableName(targetTable)
+
+	liveExists, err := d.TableExists(ctx, targetDB, targetDBName, targetTable)
+	if err != nil {
+		return err
+	}
+
+	var renameQuery string
+	if liveExists {
+		// Only the copy replaced by the previous swap can be in the way
+		dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", targetDBName, oldTable)
+		if _, err := targetDB.ExecContext(ctx, dropQuery); err != nil {
+			return fmt.Errorf("failed to drop previous old table: %w", err)
+		}
+		renameQuery = fmt.Sprintf("RENAME TABLE `%s`.`%s` TO `%s`.`%s`, `%s`.`%s` TO `%s`.`%s`",
+			targetDBName, targetTable, targetDBName, oldTable,
+			targetDBName, shadowTable, targetDBName, targetTable)
+	} else {
+		renameQuery = fmt.Sprintf("RENAME TABLE `%s`.`%s` TO `%s`.`%s`",
+			targetDBName, shadowTable, targetDBName, targetTable)
+	}
+
+	if _, err := targetDB.ExecContext(ctx, renameQuery); err != nil {
+		return fmt.Errorf("failed to swap shadow table: %w", err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"target_db":    targetDBName,
+		"target_table": targetTable,
+		"keep_old":     keepOld,
+	}).Info("Shadow table swapped in")
+
+	if !liveExists || keepOld {
+		return nil
+	}
+
+	// The swap already succeeded, a leftover old table is only cleaned up by the next swap
+	dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", targetDBName, oldTable)
+	if _, err := targetDB.ExecContext(ctx, dropQuery); err != nil {
+		e.logger.WithError(err).WithField("old_table", oldTable).Warn("Failed to drop replaced table after swap")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTableExistsQuery = "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES"
+
+func TestShadowTableName(t *testing.T) {
+	name, err := shadowTableName("orders")
+	require.NoError(t, err)
+	assert.Equal(t, "orders__dbtaxi_new", name)
+	assert.Equal(t, "orders__dbtaxi_old", oldTableName("orders"))
+
+	_, err = shadowTableName(strings.Repeat("t", 53))
+	assert.Error(t, err)
+}
+
+func TestSwapShadowTable_ReplacesLiveTable(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+
+	targetMock.ExpectQuery(regexp.QuoteMeta(testTableExistsQuery)).WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	targetMock.ExpectExec(regexp.QuoteMeta("DROP TABLE IF EXISTS `replica`.`orders__dbtaxi_old`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("RENAME TABLE `replica`.`orders` TO `replica`.`orders__dbtaxi_old`, `replica`.`orders__dbtaxi_new` TO `replica`.`orders`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectExec(regexp.QuoteMeta("DROP TABLE IF EXISTS `replica`.`orders__dbtaxi_old`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, engine.swapShadowTable(context.Background(), targetDB, mysqlDialect{}, "replica", "orders", false))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestSwapShadowTable_FirstLoadKeepsOld(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+
+	// Without a live table the shadow table is just renamed, there is nothing to keep
+	targetMock.ExpectQuery(regexp.QuoteMeta(testTableExistsQuery)).WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	targetMock.ExpectExec(regexp.QuoteMeta("RENAME TABLE `replica`.`orders__dbtaxi_new` TO `replica`.`orders`")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, engine.swapShadowTable(context.Background(), targetDB, mysqlDialect{}, "replica", "orders", true))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestSwapShadowTable_CancelledLeavesLiveTable(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := engine.swapShadowTable(ctx, targetDB, mysqlDialect{}, "replica", "orders", false)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestValidateShadowTable(t *testing.T) {
+	schema := &TableSchema{Name: "orders", Columns: []*ColumnInfo{{Name: "id"}, {Name: "total"}}}
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders__dbtaxi_new", WhereClause: "total > 0"}
+
+	t.Run("valid", func(t *testing.T) {
+		engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+		targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS")).WithArgs("replica", "orders__dbtaxi_new").
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("total"))
+		sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `shop`.`orders` WHERE total > 0")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+		targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `replica`.`orders__dbtaxi_new`")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+		assert.NoError(t, engine.validateShadowTable(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, schema))
+	})
+
+	t.Run("missing column", func(t *testing.T) {
+		engine, sourceDB, _, targetDB, targetMock := newMockEngine(t)
+		targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS")).
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+		err := engine.validateShadowTable(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, schema)
+		assert.ErrorContains(t, err, "missing column total")
+	})
+
+	t.Run("row count mismatch", func(t *testing.T) {
+		engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+		targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS")).
+			WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id").AddRow("total"))
+		sourceMock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+		targetMock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(40))
+
+		err := engine.validateShadowTable(context.Background(), sourceDB, "shop", targetDB, "replica", mapping, schema)
+		assert.ErrorContains(t, err, "has 40 rows but the source has 42")
+	})
+}
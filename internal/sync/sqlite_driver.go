@@ -0,0 +1,10 @@
+//go:build cgo
+
+package sync
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the database/sql driver opening SQLite files, empty when the build has none
+const sqliteDriver = "sqlite3"
@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package sync
+
+// sqliteDriver is empty, the SQLite driver is built with cgo and this build has none
+const sqliteDriver = ""
@@ -0,0 +1,260 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// errNoSQLiteDriver is returned for sqlite connections by a build without the SQLite driver
+var errNoSQLiteDriver = errors.New("sqlite connections need a build with the SQLite driver, which requires CGO_ENABLED=1")
+
+// sqliteWriters serializes the writes to each SQLite file, which has a single writer at a time
+var sqliteWriters = struct {
+	sync.Mutex
+	files map[string]*sync.Mutex
+}{files: make(map[string]*sync.Mutex)}
+
+// lockSQLiteFile waits for the other writers to a SQLite file and returns the function releasing it
+func lockSQLiteFile(path string) func() {
+	sqliteWriters.Lock()
+	lock, ok := sqliteWriters.files[path]
+	if !ok {
+		lock = &sync.Mutex{}
+		sqliteWriters.files[path] = lock
+	}
+	sqliteWriters.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// validateSQLiteConnection checks the settings of a sqlite connection
+func validateSQLiteConnection(config *ConnectionConfig) error {
+	if sqliteDriver == "" {
+		return errNoSQLiteDriver
+	}
+	if config.Path == "" {
+		return fmt.Errorf("path is required for a sqlite connection")
+	}
+	if !filepath.IsAbs(config.Path) {
+		return fmt.Errorf("path of a sqlite connection must be absolute: %s", config.Path)
+	}
+	if strings.Contains(config.Path, "?") {
+		return fmt.Errorf("path of a sqlite connection cannot contain '?': %s", config.Path)
+	}
+	return nil
+}
+
+// openSQLite opens the database file of a sqlite connection, creating it and its directory when missing
+func openSQLite(path string) (*sqlx.DB, error) {
+	if sqliteDriver == "" {
+		return nil, errNoSQLiteDriver
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
+	}
+	db, err := sqlx.Open(sqliteDriver, path+"?_busy_timeout=30000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	return db, nil
+}
+
+// tableSnapshot is the copy of a table into a SQLite file in progress
+type tableSnapshot struct {
+	job     *SyncJob
+	source  *exportSource
+	db      *sqlx.DB
+	dialect Dialect
+	table   string
+	policy  *writePolicy // Resolves rows already in a table that keeps its rows
+	sizer   *batchSizer  // Sizes the batches, nil to copy batches of the configured size
+}
+
+// snapshotTable copies a table into the database file of a sqlite connection, a portable single-file
+// snapshot of the mapped database. A full sync replaces the table in one transaction, unless the
+// mapping's write mode keeps its rows. An incremental sync copies every row the first time and then
+// writes the rows changed since the last run, tracked by the same checkpoints as an incremental sync
+// into a database; it needs a primary key. Rows already in the table are resolved per the write policy.
+func (e *DefaultSyncEngine) snapshotTable(ctx context.Context, job *SyncJob, mapping *TableMapping, syncConfig *SyncConfig, source, target *ConnectionConfig) error {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+		"sync_mode":    mapping.SyncMode,
+		"write_mode":   mapping.WriteMode,
+		"path":         target.Path,
+	}).Info("Starting table snapshot")
+
+	src, mapping, err := e.openExportSource(ctx, job, mapping, syncConfig, source)
+	if err != nil {
+		return err
+	}
+	defer src.db.Close()
+	if mapping.DeleteDetection != DeleteDetectionNone {
+		e.logger.WithField("source_table", mapping.SourceTable).Warn("Delete detection is not supported by sqlite targets and is ignored")
+	}
+	policy, err := e.resolveWritePolicy(ctx, src.db, mapping, syncConfig.Options, src.targetSchema)
+	if err != nil {
+		return err
+	}
+
+	db, err := openSQLite(target.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer lockSQLiteFile(target.Path)()
+
+	d := dialectFor(target)
+	snapshot := &tableSnapshot{
+		job:     job,
+		source:  src,
+		db:      db,
+		dialect: d,
+		table:   mapping.TargetTable,
+		policy:  policy,
+		sizer:   e.resolveBatchSizer(ctx, db, d, mapping.SourceTable, syncConfig.Options),
+	}
+	var copied int64
+	switch mapping.SyncMode {
+	case SyncModeFull:
+		copied, err = e.snapshotRows(ctx, snapshot, mapping, nil)
+	case SyncModeIncremental:
+		if len(src.targetSchema.primaryKey()) == 0 {
+			return fmt.Errorf("incremental sync into a sqlite connection requires table %s to have a primary key", mapping.SourceTable)
+		}
+		copied, err = e.copyIncremental(ctx, src.db, mapping, func(changed *changedRows) (int64, error) {
+			return e.snapshotRows(ctx, snapshot, mapping, changed)
+		})
+	default:
+		return fmt.Errorf("sync mode %s is not supported by sqlite targets", mapping.SyncMode)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+		"copied_rows":  copied,
+		"path":         target.Path,
+	}).Info("Table snapshot completed successfully")
+	return nil
+}
+
+// snapshotRows copies rows into the SQLite table in one transaction: every row selected by the row
+// filter into a new table replacing the previous one, or the changed rows of a delta. A delta copies
+// every row when the table is missing. A table whose mapping keeps its rows is created when missing
+// and written into as it is. Rows are read, masked and written by the row pipeline.
+func (e *DefaultSyncEngine) snapshotRows(ctx context.Context, snapshot *tableSnapshot, mapping *TableMapping, changed *changedRows) (int64, error) {
+	src, d := snapshot.source, snapshot.dialect
+	exists, err := d.TableExists(ctx, snapshot.db, "", snapshot.table)
+	if err != nil {
+		return 0, err
+	}
+	if changed != nil && !exists {
+		e.logger.WithField("target_table", snapshot.table).Warn("Snapshot table is missing, copying every row")
+		changed = nil
+	}
+	replace := changed == nil && !mapping.keepsTargetRows()
+
+	table := fmt.Sprintf("`%s`.`%s`", src.dbName, mapping.SourceTable)
+	query := fmt.Sprintf("SELECT %s FROM %s%s", src.selectList, table, mapping.rowFilter().where())
+	args := mapping.rowFilter().args
+	if changed != nil {
+		query, args = changed.query(src.selectList, table), changed.args
+	}
+
+	rows, err := src.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query source rows: %w", err)
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get column names: %w", err)
+	}
+	columns := exportColumns(names, src.targetSchema)
+
+	tx, err := snapshot.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if replace {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+d.TableName("", snapshot.table)); err != nil {
+			return 0, fmt.Errorf("failed to drop table: %w", err)
+		}
+	}
+	if replace || !exists {
+		if _, err := tx.ExecContext(ctx, d.CreateTableStatement("", snapshot.table, src.targetSchema)); err != nil {
+			return 0, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	// Rows changed again since the last run, or written into a table that keeps its rows, may already be in it
+	var clause string
+	if !replace {
+		clause = snapshot.policy.clause(d, names)
+	}
+
+	var copied int64
+	err = e.runRowPipeline(ctx, pipelineStages{
+		read: cursorReader(rows, names, snapshot.sizer, src.batchSize),
+		transform: func(batch *rowBatch) error {
+			if err := src.masker.maskBatch(batch.columns, batch.rows); err != nil {
+				return err
+			}
+			return normalizeRows(columns, batch.rows)
+		},
+		write: func(ctx context.Context, batch *rowBatch) error {
+			err := snapshot.sizer.write(batch.rows, func(rows [][]interface{}) error {
+				return e.writeRowsToDB(ctx, tx, d, "", snapshot.table, names, rows, clause)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
+			}
+			copied += int64(len(batch.rows))
+			ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, copied, copied)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return copied, nil
+}
+
+// normalizeRows converts the values of rows read from MySQL into the values written for their columns
+func normalizeRows(columns []exportColumn, rows [][]interface{}) error {
+	for _, row := range rows {
+		for i, col := range columns {
+			value, err := col.normalize(row[i])
+			if err != nil {
+				return err
+			}
+			row[i] = value
+		}
+	}
+	return nil
+}
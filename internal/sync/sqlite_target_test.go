@@ -0,0 +1,245 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqliteTypesSchema has a column of each kind of MySQL type the SQLite dialect maps
+func sqliteTypesSchema() *TableSchema {
+	return &TableSchema{
+		Name: "items",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "int"},
+			{Name: "flag", Type: "tinyint(1)", Nullable: true},
+			{Name: "counter", Type: "bigint unsigned", Nullable: true},
+			{Name: "born", Type: "year", Nullable: true},
+			{Name: "ratio", Type: "float", Nullable: true},
+			{Name: "score", Type: "double", Nullable: true},
+			{Name: "price", Type: "decimal(20,2)", Nullable: true},
+			{Name: "name", Type: "varchar(32)"},
+			{Name: "body", Type: "text", Nullable: true},
+			{Name: "state", Type: "enum('new','done')", Nullable: true},
+			{Name: "doc", Type: "json", Nullable: true},
+			{Name: "day", Type: "date", Nullable: true},
+			{Name: "at", Type: "datetime(3)", Nullable: true},
+			{Name: "changed", Type: "timestamp", Nullable: true},
+			{Name: "clock", Type: "time", Nullable: true},
+			{Name: "photo", Type: "blob", Nullable: true},
+			{Name: "hash", Type: "varbinary(16)", Nullable: true},
+			{Name: "bits", Type: "bit(8)", Nullable: true},
+			{Name: "place", Type: "point", Nullable: true},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true},
+			{Name: "uk_name", Columns: []string{"name"}, Unique: true},
+			{Name: "idx_day", Columns: []string{"day"}},
+		},
+	}
+}
+
+func sqliteTypesColumns() []string {
+	var names []string
+	for _, col := range sqliteTypesSchema().Columns {
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+func newTableSnapshot(t *testing.T) (*DefaultSyncEngine, *tableSnapshot, sqlmock.Sqlmock) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	db, err := openSQLite(filepath.Join(t.TempDir(), "snapshots", "shop.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return engine, &tableSnapshot{
+		job: &SyncJob{ID: "job-1"},
+		source: &exportSource{
+			db:           sourceDB,
+			dbName:       "shop",
+			targetSchema: sqliteTypesSchema(),
+			selectList:   "*",
+			batchSize:    2,
+		},
+		db:      db,
+		dialect: sqliteDialect{},
+		table:   "items",
+	}, sourceMock
+}
+
+func TestSnapshotRows_MapsMySQLTypes(t *testing.T) {
+	engine, snapshot, sourceMock := newTableSnapshot(t)
+	mapping := &TableMapping{SourceTable: "items", TargetTable: "items"}
+
+	// Rows as the MySQL driver reads them, text except for binary columns
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`items`")).WillReturnRows(
+		sqlmock.NewRows(sqliteTypesColumns()).
+			AddRow([]byte("1"), []byte("1"), []byte("18446744073709551615"), []byte("2024"), []byte("1.5"), []byte("-0.25"),
+				[]byte("12345678901234567.89"), []byte("first"), []byte("long text"), []byte("done"), []byte(`{"a": 1}`),
+				[]byte("2024-03-01"), []byte("2024-03-01 10:00:00.123"), []byte("2024-03-01 10:00:00"), []byte("-01:30:00"),
+				[]byte{0xff, 0x00}, []byte{0x01, 0x02}, []byte{0x81}, []byte{0x00, 0x01}).
+			AddRow([]byte("2"), nil, []byte("7"), nil, nil, nil, nil, []byte("second"), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).
+			AddRow([]byte("3"), []byte("0"), nil, nil, nil, nil, []byte("0.10"), []byte("third"), []byte(""), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	copied, err := engine.snapshotRows(context.Background(), snapshot, mapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), copied)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+
+	// The table has the mapped types and the keys of the source
+	columns, err := snapshot.dialect.TableColumns(context.Background(), snapshot.db, "", "items")
+	require.NoError(t, err)
+	types := make(map[string]string)
+	for _, col := range columns {
+		types[col.Name] = col.Type
+	}
+	assert.Equal(t, map[string]string{
+		"id": "INTEGER", "flag": "INTEGER", "counter": "TEXT", "born": "INTEGER", "ratio": "REAL", "score": "REAL",
+		"price": "TEXT", "name": "TEXT", "body": "TEXT", "state": "TEXT", "doc": "TEXT", "day": "TEXT", "at": "TEXT",
+		"changed": "TEXT", "clock": "TEXT", "photo": "BLOB", "hash": "BLOB", "bits": "BLOB", "place": "BLOB",
+	}, types)
+	assert.False(t, columns[0].Nullable)
+	assert.False(t, columns[7].Nullable)
+	var indexes []string
+	require.NoError(t, snapshot.db.Select(&indexes, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'items' AND sql IS NOT NULL"))
+	assert.Equal(t, []string{"items_idx_day"}, indexes)
+	_, err = snapshot.db.Exec(`INSERT INTO "items" ("id", "name") VALUES (9, 'first')`)
+	assert.Error(t, err, "the unique key is kept")
+
+	// Values keep their storage class and precision
+	row := make(map[string]interface{})
+	require.NoError(t, snapshot.db.QueryRowx(`SELECT typeof(id) AS id, typeof(flag) AS flag, typeof(counter) AS counter,
+		typeof(born) AS born, typeof(ratio) AS ratio, typeof(price) AS price, typeof(at) AS at, typeof(photo) AS photo,
+		typeof(bits) AS bits FROM "items" WHERE id = 1`).MapScan(row))
+	assert.Equal(t, map[string]interface{}{
+		"id": "integer", "flag": "integer", "counter": "text", "born": "integer", "ratio": "real",
+		"price": "text", "at": "text", "photo": "blob", "bits": "blob",
+	}, row)
+
+	var got struct {
+		Counter string  `db:"counter"`
+		Born    int64   `db:"born"`
+		Ratio   float64 `db:"ratio"`
+		Score   float64 `db:"score"`
+		Price   string  `db:"price"`
+		Doc     string  `db:"doc"`
+		At      string  `db:"at"`
+		Clock   string  `db:"clock"`
+		Photo   []byte  `db:"photo"`
+		Bits    []byte  `db:"bits"`
+	}
+	require.NoError(t, snapshot.db.Get(&got, `SELECT counter, born, ratio, score, price, doc, at, clock, photo, bits FROM "items" WHERE id = 1`))
+	assert.Equal(t, "18446744073709551615", got.Counter)
+	assert.Equal(t, int64(2024), got.Born)
+	assert.Equal(t, 1.5, got.Ratio)
+	assert.Equal(t, -0.25, got.Score)
+	assert.Equal(t, "12345678901234567.89", got.Price)
+	assert.Equal(t, `{"a": 1}`, got.Doc)
+	assert.Equal(t, "2024-03-01 10:00:00.123", got.At)
+	assert.Equal(t, "-01:30:00", got.Clock)
+	assert.Equal(t, []byte{0xff, 0x00}, got.Photo)
+	assert.Equal(t, []byte{0x81}, got.Bits)
+
+	// NULL stays NULL and an empty string empty
+	var nulls, empty int
+	require.NoError(t, snapshot.db.Get(&nulls, `SELECT COUNT(*) FROM "items" WHERE id = 2 AND flag IS NULL AND price IS NULL AND photo IS NULL`))
+	require.NoError(t, snapshot.db.Get(&empty, `SELECT COUNT(*) FROM "items" WHERE id = 3 AND body = '' AND price = '0.10'`))
+	assert.Equal(t, 1, nulls)
+	assert.Equal(t, 1, empty)
+}
+
+func TestSnapshotRows_DeltaUpsertsByPrimaryKey(t *testing.T) {
+	engine, snapshot, sourceMock := newTableSnapshot(t)
+	snapshot.source.targetSchema = &TableSchema{Name: "items", Columns: []*ColumnInfo{{Name: "id", Type: "int"}, {Name: "name", Type: "varchar(32)", Nullable: true}},
+		Keys: []*KeyInfo{{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"id"}}}}
+	mapping := &TableMapping{SourceTable: "items", TargetTable: "items"}
+	changed := &changedRows{watermark: &keyWatermark{Mode: keyWatermarkMode, Columns: []string{"id"}}, where: " WHERE `id` > ?", args: []interface{}{int64(1)}}
+
+	// Without the table, a delta copies every row
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`items`")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("1"), []byte("one")).AddRow([]byte("2"), []byte("two")))
+	_, err := engine.snapshotRows(context.Background(), snapshot, mapping, changed)
+	require.NoError(t, err)
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`items` WHERE `id` > ? ORDER BY `id`")).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("2"), []byte("TWO")).AddRow([]byte("3"), nil))
+	copied, err := engine.snapshotRows(context.Background(), snapshot, mapping, changed)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), copied)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+
+	var rows []struct {
+		ID   int64   `db:"id"`
+		Name *string `db:"name"`
+	}
+	require.NoError(t, snapshot.db.Select(&rows, `SELECT id, name FROM "items" ORDER BY id`))
+	require.Len(t, rows, 3)
+	assert.Equal(t, "one", *rows[0].Name)
+	assert.Equal(t, "TWO", *rows[1].Name)
+	assert.Nil(t, rows[2].Name)
+}
+
+func TestSnapshotRows_WriteModeKeepsRows(t *testing.T) {
+	engine, snapshot, sourceMock := newTableSnapshot(t)
+	schema := &TableSchema{Name: "items", Columns: []*ColumnInfo{{Name: "id", Type: "int"}, {Name: "name", Type: "varchar(32)", Nullable: true}},
+		Keys: []*KeyInfo{{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"id"}}}}
+	snapshot.source.targetSchema = schema
+	mapping := &TableMapping{SourceTable: "items", TargetTable: "items", WriteMode: WriteModeAppend}
+	policy, err := newWritePolicy(mapping, nil, schema, "", "")
+	require.NoError(t, err)
+	snapshot.policy = policy
+
+	// The missing table is created, then written into as it is: rows already in it are skipped
+	query := regexp.QuoteMeta("SELECT * FROM `shop`.`items`")
+	sourceMock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("1"), []byte("one")).AddRow([]byte("2"), []byte("two")))
+	_, err = engine.snapshotRows(context.Background(), snapshot, mapping, nil)
+	require.NoError(t, err)
+	_, err = snapshot.db.Exec(`INSERT INTO "items" ("id", "name") VALUES (9, 'local')`)
+	require.NoError(t, err)
+
+	sourceMock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("2"), []byte("TWO")).AddRow([]byte("3"), []byte("three")))
+	copied, err := engine.snapshotRows(context.Background(), snapshot, mapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), copied)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+
+	var names []string
+	require.NoError(t, snapshot.db.Select(&names, `SELECT name FROM "items" ORDER BY id`))
+	assert.Equal(t, []string{"one", "two", "three", "local"}, names)
+}
+
+func TestWriteRowsToDB_SplitsSQLiteInserts(t *testing.T) {
+	engine := newTestEngine()
+	db, err := openSQLite(filepath.Join(t.TempDir(), "shop.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE "items" ("id" INTEGER PRIMARY KEY, "name" TEXT)`)
+	require.NoError(t, err)
+
+	// More rows than the placeholders of one statement allow are written by several INSERTs
+	rows := make([][]interface{}, sqliteMaxVariables/2+10)
+	for i := range rows {
+		rows[i] = []interface{}{int64(i), "item"}
+	}
+	require.NoError(t, engine.writeRowsToDB(context.Background(), db, sqliteDialect{}, "", "items", []string{"id", "name"}, rows, ""))
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM "items"`))
+	assert.Equal(t, len(rows), count)
+}
+
+func TestValidateSQLiteConnection(t *testing.T) {
+	assert.NoError(t, validateSQLiteConnection(&ConnectionConfig{Type: ConnectionTypeSQLite, Path: "/data/shop.db"}))
+	assert.EqualError(t, validateSQLiteConnection(&ConnectionConfig{Type: ConnectionTypeSQLite}), "path is required for a sqlite connection")
+	assert.Error(t, validateSQLiteConnection(&ConnectionConfig{Type: ConnectionTypeSQLite, Path: "shop.db"}))
+	assert.Error(t, validateSQLiteConnection(&ConnectionConfig{Type: ConnectionTypeSQLite, Path: "/data/shop.db?mode=ro"}))
+
+	assert.EqualError(t, checkDatabaseConnections(&ConnectionConfig{Name: "shop"}, &ConnectionConfig{Name: "snap", Type: ConnectionTypeSQLite}),
+		"target connection snap is a sqlite connection, which only full and incremental syncs can copy into")
+}
@@ -22,6 +22,7 @@ type Manager struct {
 	mappingManager     MappingManager
 	jobEngine          JobEngine
 	syncEngine         SyncEngine
+	scheduler          *Scheduler
 	migrationsExecuted bool // Tracks whether migrations have been executed
 }
 
@@ -39,6 +40,10 @@ func NewManager(cfg *config.Config, db *sqlx.DB, logger *logrus.Logger) (*Manage
 
 	// Create sync engine
 	syncEngine := NewSyncEngine(db, repo, logger)
+	if engine, ok := syncEngine.(*DefaultSyncEngine); ok {
+		engine.allowRawWhereClause = cfg.Sync.AllowRawWhereClause
+		engine.maskingSecret = cfg.Sync.MaskingSecret
+	}
 
 	// Create job engine
 	jobEngine := NewJobEngine(repo, logger, monitoring, syncEngine)
@@ -48,11 +53,19 @@ func NewManager(cfg *config.Config, db *sqlx.DB, logger *logrus.Logger) (*Manage
 	syncManager := NewSyncManager(repo, logger, db, jobEngine, monitoring)
 	mappingManager := NewMappingManager(db, repo, logger)
 
-	// Set job engine reference in sync manager
+	// Set job engine and sync engine references in sync manager
 	if syncMgrService, ok := syncManager.(*SyncManagerService); ok {
 		syncMgrService.jobEngine = jobEngine
+		syncMgrService.syncEngine = syncEngine
+		syncMgrService.allowRawWhereClause = cfg.Sync.AllowRawWhereClause
+	}
+	if mappingMgr, ok := mappingManager.(*MappingManagerImpl); ok {
+		mappingMgr.allowRawWhereClause = cfg.Sync.AllowRawWhereClause
 	}
 
+	// Create scheduler for configs with a cron schedule
+	scheduler := NewScheduler(repo, syncManager, jobEngine, logger)
+
 	manager := &Manager{
 		config:            cfg,
 		logger:            logger,
@@ -63,6 +76,7 @@ func NewManager(cfg *config.Config, db *sqlx.DB, logger *logrus.Logger) (*Manage
 		mappingManager:    mappingManager,
 		syncEngine:        syncEngine,
 		jobEngine:         jobEngine,
+		scheduler:         scheduler,
 	}
 
 	logger.Info("Sync system manager initialized successfully")
@@ -96,6 +110,13 @@ func (m *Manager) Initialize(ctx context.Context) error {
 	}
 	m.logger.Info("Job engine started successfully")
 
+	// Start scheduler once the job engine can accept jobs
+	if m.scheduler != nil {
+		if err := m.scheduler.Start(); err != nil {
+			return fmt.Errorf("failed to start scheduler: %w", err)
+		}
+	}
+
 	m.logger.Info("Sync system initialized successfully")
 	return nil
 }
@@ -129,6 +150,13 @@ func (m *Manager) GetSyncEngine() SyncEngine {
 func (m *Manager) Shutdown(ctx context.Context) error {
 	m.logger.Info("Shutting down sync system...")
 
+	// Stop scheduler first so no new jobs are submitted
+	if m.scheduler != nil {
+		if err := m.scheduler.Stop(); err != nil {
+			m.logger.WithError(err).Error("Failed to stop scheduler")
+		}
+	}
+
 	// Stop job engine
 	if m.jobEngine != nil {
 		if err := m.jobEngine.Stop(); err != nil {
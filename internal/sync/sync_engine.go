@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -14,10 +15,15 @@ import (
 
 // DefaultSyncEngine implements the SyncEngine interface
 type DefaultSyncEngine struct {
-	localDB        *sqlx.DB
-	repo           Repository
-	logger         *logrus.Logger
-	batchProcessor *BatchProcessor
+	localDB           *sqlx.DB
+	repo              Repository
+	logger            *logrus.Logger
+	batchProcessor    *BatchProcessor
+	checkpointManager *CheckpointManager
+	copyRetryPolicy   *RetryPolicy // Retries of a failed parallel copy range, DefaultRetryPolicy when nil
+
+	allowRawWhereClause bool   // Whether mappings may select rows with a raw SQL where clause
+	maskingSecret       string // Key of hash, fake and date_shift masking, from sync.masking_secret
 }
 
 // NewSyncEngine creates a new sync engine instance
@@ -31,20 +37,22 @@ func NewSyncEngine(localDB *sqlx.DB, repo Repository, logger *logrus.Logger) Syn
 	}
 
 	return &DefaultSyncEngine{
-		localDB:        localDB,
-		repo:           repo,
-		logger:         logger,
-		batchProcessor: NewBatchProcessor(localDB, logger, batchConfig),
+		localDB:           localDB,
+		repo:              repo,
+		logger:            logger,
+		batchProcessor:    NewBatchProcessor(localDB, logger, batchConfig),
+		checkpointManager: NewCheckpointManager(repo, logger),
 	}
 }
 
 // NewSyncEngineWithConfig creates a new sync engine with custom batch processor configuration
 func NewSyncEngineWithConfig(localDB *sqlx.DB, repo Repository, logger *logrus.Logger, batchConfig *BatchProcessorConfig) SyncEngine {
 	return &DefaultSyncEngine{
-		localDB:        localDB,
-		repo:           repo,
-		logger:         logger,
-		batchProcessor: NewBatchProcessor(localDB, logger, batchConfig),
+		localDB:           localDB,
+		repo:              repo,
+		logger:            logger,
+		batchProcessor:    NewBatchProcessor(localDB, logger, batchConfig),
+		checkpointManager: NewCheckpointManager(repo, logger),
 	}
 }
 
@@ -57,11 +65,37 @@ func (e *DefaultSyncEngine) SyncTable(ctx context.Context, job *SyncJob, mapping
 		"sync_mode":    mapping.SyncMode,
 	}).Info("Starting table synchronization")
 
+	// Full and incremental syncs from a file connection load its files, into one export the table to
+	// files and into a sqlite connection copy it into its database file
+	switch mapping.SyncMode {
+	case SyncModeFull, SyncModeIncremental:
+		syncConfig, source, target, err := e.syncConnections(ctx, mapping)
+		if err != nil {
+			return err
+		}
+		switch {
+		case source.isSQLite():
+			return fmt.Errorf("source connection %s is a sqlite connection, which can only be a sync target", source.Name)
+		case source.isFile() && (target.isFile() || target.isSQLite()):
+			return fmt.Errorf("files of a file connection can only be loaded into a MySQL connection")
+		case target.isFile() && mapping.WriteMode != WriteModeDefault:
+			return fmt.Errorf("write mode %s requires a database target connection, %s is a file connection", mapping.WriteMode, target.Name)
+		case source.isFile():
+			return e.importTable(ctx, job, mapping, syncConfig, source, target)
+		case target.isFile():
+			return e.exportTable(ctx, job, mapping, syncConfig, source, target)
+		case target.isSQLite():
+			return e.snapshotTable(ctx, job, mapping, syncConfig, source, target)
+		}
+	}
+
 	switch mapping.SyncMode {
 	case SyncModeFull:
 		return e.SyncFull(ctx, job, mapping)
 	case SyncModeIncremental:
 		return e.SyncIncremental(ctx, job, mapping)
+	case SyncModeCDC:
+		return e.SyncCDC(ctx, job, mapping)
 	default:
 		return fmt.Errorf("unsupported sync mode: %s", mapping.SyncMode)
 	}
@@ -70,102 +104,96 @@ func (e *DefaultSyncEngine) SyncTable(ctx context.Context, job *SyncJob, mapping
 // SyncFull performs full table synchronization
 // Requirement 4.2: Execute full sync - copy table structure and sync all data
 func (e *DefaultSyncEngine) SyncFull(ctx context.Context, job *SyncJob, mapping *TableMapping) error {
+	return e.syncFull(ctx, job, mapping, true)
+}
+
+// syncFull performs full table synchronization. A resumable copy continues an interrupted
+// copy of the mapping from its last committed chunk instead of recreating the target table.
+func (e *DefaultSyncEngine) syncFull(ctx context.Context, job *SyncJob, mapping *TableMapping, resumable bool) error {
 	e.logger.WithFields(logrus.Fields{
 		"job_id":       job.ID,
 		"source_table": mapping.SourceTable,
 		"target_table": mapping.TargetTable,
 	}).Info("Starting full table synchronization")
 
-	// Get sync config to retrieve connection info
-	syncConfig, err := e.repo.GetSyncConfig(ctx, mapping.SyncConfigID)
+	endpoints, err := e.openSyncEndpoints(ctx, mapping)
 	if err != nil {
-		return fmt.Errorf("failed to get sync config: %w", err)
+		return err
 	}
+	defer endpoints.Close()
+	syncConfig := endpoints.config
+	sourceDB, sourceDBName := endpoints.sourceDB, endpoints.sourceDBName
+	targetDB, targetDBName := endpoints.targetDB, endpoints.targetDBName
 
-	// Get source connection config
-	sourceConnConfig, err := e.repo.GetConnection(ctx, syncConfig.SourceConnectionID)
+	// Get table schema from source database
+	schema, err := e.getTableSchemaFromRemote(ctx, sourceDB, mapping.SourceTable)
 	if err != nil {
-		return fmt.Errorf("failed to get source connection config: %w", err)
+		return fmt.Errorf("failed to get table schema: %w", err)
 	}
-
-	// Get target connection config
-	targetConnConfig, err := e.repo.GetConnection(ctx, syncConfig.TargetConnectionID)
+	mapping, err = e.resolveRowFilter(ctx, job, mapping, schema)
 	if err != nil {
-		return fmt.Errorf("failed to get target connection config: %w", err)
+		return err
 	}
-
-	// Determine source/target database names (prefer sync config; fallback to connection config for backward compatibility)
-	sourceDBName := syncConfig.SourceDatabase
-	if sourceDBName == "" {
-		sourceDBName = sourceConnConfig.Database
-	}
-	targetDBName := syncConfig.TargetDatabase
-	if targetDBName == "" {
-		targetDBName = targetConnConfig.Database
-	}
-	if targetDBName == "" {
-		// If still empty, default to source db name
-		targetDBName = sourceDBName
+	targetSchema, err := mapping.columnMapping().targetSchema(schema)
+	if err != nil {
+		return err
 	}
-	if sourceDBName == "" || targetDBName == "" {
-		return fmt.Errorf("source/target database is required")
+	masker, err := newRowMasker(mapping, syncConfig.Options, e.maskingSecret, schema, targetSchema)
+	if err != nil {
+		return err
 	}
 
-	// Ensure target database exists (auto-create if missing)
-	{
-		serverConn := *targetConnConfig
-		serverConn.Database = ""
-		adminDB, err := e.connectToRemote(&serverConn)
+	// With shadow swap the copy is loaded next to the live table, which readers keep using until the swap
+	loadMapping := mapping
+	shadowSwap := syncConfig.Options != nil && syncConfig.Options.ShadowSwap
+	if shadowSwap {
+		shadowTable, err := shadowTableName(mapping.TargetTable)
 		if err != nil {
-			return fmt.Errorf("failed to connect to target server: %w", err)
-		}
-		if err := e.ensureDatabaseExists(ctx, adminDB, targetDBName); err != nil {
-			adminDB.Close()
-			return fmt.Errorf("failed to ensure target database exists: %w", err)
+			return err
 		}
-		adminDB.Close()
+		shadowMapping := *mapping
+		shadowMapping.TargetTable = shadowTable
+		loadMapping = &shadowMapping
 	}
 
-	// Connect to source database
-	{
-		cc := *sourceConnConfig
-		cc.Database = sourceDBName
-		sourceConnConfig = &cc
-	}
-	sourceDB, err := e.connectToRemote(sourceConnConfig)
+	plan, err := e.planFullCopy(ctx, sourceDB, targetDB, endpoints.dialect, targetDBName, loadMapping, schema, resumable)
 	if err != nil {
-		return fmt.Errorf("failed to connect to source database: %w", err)
+		return err
 	}
-	defer sourceDB.Close()
-
-	// Connect to target database
-	{
-		cc := *targetConnConfig
-		cc.Database = targetDBName
-		targetConnConfig = &cc
+	plan.masker = masker
+	if mapping.keepsTargetRows() {
+		if shadowSwap {
+			return fmt.Errorf("write mode %s keeps the rows of the target table and cannot be used with shadow swap", mapping.WriteMode)
+		}
+		if plan.policy, err = e.resolveWritePolicy(ctx, sourceDB, mapping, syncConfig.Options, targetSchema); err != nil {
+			return err
+		}
 	}
-	targetDB, err := e.connectToRemote(targetConnConfig)
-	if err != nil {
-		return fmt.Errorf("failed to connect to target database: %w", err)
+	if syncConfig.Options != nil && syncConfig.Options.BulkLoad {
+		plan.bulk = &bulkLoader{}
 	}
-	defer targetDB.Close()
+	plan.sizer = e.resolveBatchSizer(ctx, targetDB, endpoints.dialect, mapping.SourceTable, syncConfig.Options)
 
-	// Get table schema from source database
-	schema, err := e.getTableSchemaFromRemote(ctx, sourceDB, mapping.SourceTable)
-	if err != nil {
-		return fmt.Errorf("failed to get table schema: %w", err)
-	}
-
-	// Create or recreate target table in target database
-	if err := e.createOrRecreateTargetTableInDB(ctx, targetDB, targetDBName, mapping.TargetTable, schema); err != nil {
-		return fmt.Errorf("failed to create target table: %w", err)
+	// Empty and evolve the target table, or create it; a shadow table is always rebuilt, and a table
+	// whose mapping keeps its rows is only evolved
+	if err := e.prepareFullCopyTarget(ctx, targetDB, targetDBName, loadMapping, targetSchema, plan, shadowSwap); err != nil {
+		return err
 	}
 
 	// Sync all data from source to target
-	if err := e.syncAllDataBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, syncConfig.Options); err != nil {
+	if err := e.syncAllDataBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, targetDBName, loadMapping, syncConfig.Options, plan); err != nil {
 		return fmt.Errorf("failed to sync data: %w", err)
 	}
 
+	if shadowSwap {
+		if err := e.validateShadowTable(ctx, sourceDB, sourceDBName, targetDB, targetDBName, loadMapping, targetSchema); err != nil {
+			return err
+		}
+		if err := e.swapShadowTable(ctx, targetDB, endpoints.dialect, targetDBName, mapping.TargetTable, syncConfig.Options.KeepOldTable); err != nil {
+			return err
+		}
+	}
+
 	e.logger.WithFields(logrus.Fields{
 		"job_id":       job.ID,
 		"source_table": mapping.SourceTable,
@@ -184,99 +212,40 @@ func (e *DefaultSyncEngine) SyncIncremental(ctx context.Context, job *SyncJob, m
 		"target_table": mapping.TargetTable,
 	}).Info("Starting incremental table synchronization")
 
-	// Get sync config to retrieve connection info
-	syncConfig, err := e.repo.GetSyncConfig(ctx, mapping.SyncConfigID)
-	if err != nil {
-		return fmt.Errorf("failed to get sync config: %w", err)
-	}
-
-	// Get source connection config
-	sourceConnConfig, err := e.repo.GetConnection(ctx, syncConfig.SourceConnectionID)
-	if err != nil {
-		return fmt.Errorf("failed to get source connection config: %w", err)
-	}
-
-	// Get target connection config
-	targetConnConfig, err := e.repo.GetConnection(ctx, syncConfig.TargetConnectionID)
-	if err != nil {
-		return fmt.Errorf("failed to get target connection config: %w", err)
-	}
-
-	// Determine source/target database names (prefer sync config; fallback to connection config for backward compatibility)
-	sourceDBName := syncConfig.SourceDatabase
-	if sourceDBName == "" {
-		sourceDBName = sourceConnConfig.Database
-	}
-	targetDBName := syncConfig.TargetDatabase
-	if targetDBName == "" {
-		targetDBName = targetConnConfig.Database
-	}
-	if targetDBName == "" {
-		targetDBName = sourceDBName
-	}
-	if sourceDBName == "" || targetDBName == "" {
-		return fmt.Errorf("source/target database is required")
-	}
-
-	// Ensure target database exists (auto-create if missing)
-	{
-		serverConn := *targetConnConfig
-		serverConn.Database = ""
-		adminDB, err := e.connectToRemote(&serverConn)
-		if err != nil {
-			return fmt.Errorf("failed to connect to target server: %w", err)
-		}
-		if err := e.ensureDatabaseExists(ctx, adminDB, targetDBName); err != nil {
-			adminDB.Close()
-			return fmt.Errorf("failed to ensure target database exists: %w", err)
-		}
-		adminDB.Close()
-	}
-
-	// Connect to source database
-	{
-		cc := *sourceConnConfig
-		cc.Database = sourceDBName
-		sourceConnConfig = &cc
-	}
-	sourceDB, err := e.connectToRemote(sourceConnConfig)
-	if err != nil {
-		return fmt.Errorf("failed to connect to source database: %w", err)
-	}
-	defer sourceDB.Close()
-
-	// Connect to target database
-	{
-		cc := *targetConnConfig
-		cc.Database = targetDBName
-		targetConnConfig = &cc
-	}
-	targetDB, err := e.connectToRemote(targetConnConfig)
+	endpoints, err := e.openSyncEndpoints(ctx, mapping)
 	if err != nil {
-		return fmt.Errorf("failed to connect to target database: %w", err)
+		return err
 	}
-	defer targetDB.Close()
+	defer endpoints.Close()
+	syncConfig := endpoints.config
+	sourceDB, sourceDBName := endpoints.sourceDB, endpoints.sourceDBName
+	targetDB, targetDBName := endpoints.targetDB, endpoints.targetDBName
 
 	// Load checkpoint to determine last sync point
 	checkpoint, err := e.repo.GetCheckpoint(ctx, mapping.ID)
+	if errors.Is(err, ErrCheckpointNotFound) {
+		checkpoint, err = nil, nil
+	}
 	if err != nil {
 		e.logger.WithError(err).Warn("Failed to load checkpoint, performing full sync instead")
 		// If no checkpoint exists, fall back to full sync
 		return e.SyncFull(ctx, job, mapping)
 	}
 
-	// If no checkpoint exists, perform full sync
-	if checkpoint == nil {
+	// If no checkpoint exists, or only a full copy has run so far, perform full sync
+	if checkpoint == nil || isCopyCheckpoint(checkpoint) {
 		e.logger.Info("No checkpoint found, performing initial full sync")
+		// The checkpoint is taken before the copy reads the rows, so a row committed while they are
+		// read is synced again by the next run rather than missed
+		initial := e.initialCheckpoint(ctx, job, mapping, sourceDB)
 		if err := e.SyncFull(ctx, job, mapping); err != nil {
 			return err
 		}
-		// Create initial checkpoint after full sync
-		return e.createInitialCheckpoint(ctx, mapping, sourceDB)
+		return e.createInitialCheckpoint(ctx, mapping, initial)
 	}
 
 	// Detect change tracking column (timestamp or auto-increment ID)
-	changeColumn, changeType, err := e.detectChangeTrackingColumn(ctx, sourceDB, mapping.SourceTable)
+	changeColumn, changeType, err := e.changeTrackingColumn(ctx, sourceDB, mapping)
 	if err != nil {
 		return fmt.Errorf("failed to detect change tracking column: %w", err)
 	}
@@ -292,19 +261,50 @@ func (e *DefaultSyncEngine) SyncIncremental(ctx context.Context, job *SyncJob, m
 	if err != nil {
 		return fmt.Errorf("failed to get table schema: %w", err)
 	}
+	mapping, err = e.resolveRowFilter(ctx, job, mapping, schema)
+	if err != nil {
+		return err
+	}
+
+	columns := mapping.columnMapping()
+	targetSchema, err := columns.targetSchema(schema)
+	if err != nil {
+		return err
+	}
+	masker, err := newRowMasker(mapping, syncConfig.Options, e.maskingSecret, schema, targetSchema)
+	if err != nil {
+		return err
+	}
 
 	// Check if target table exists, create if not
-	if err := e.ensureTargetTableExistsInDB(ctx, targetDB, targetDBName, mapping.TargetTable, schema); err != nil {
+	if err := e.ensureTargetTableExistsInDB(ctx, targetDB, endpoints.dialect, targetDBName, mapping.TargetTable, targetSchema); err != nil {
 		return fmt.Errorf("failed to ensure target table exists: %w", err)
 	}
+	if err := e.evolveTargetSchema(ctx, targetDB, targetDBName, mapping, targetSchema, false); err != nil {
+		return err
+	}
+
+	policy, err := newWritePolicy(mapping, syncConfig.Options, targetSchema, changeColumn, changeType)
+	if err != nil {
+		return err
+	}
+	sizer := e.resolveBatchSizer(ctx, targetDB, endpoints.dialect, mapping.SourceTable, syncConfig.Options)
+
+	// The new checkpoint is taken before the changed rows are read, and bounds them: a row committed
+	// while they are read is past it and synced by the next run
+	latest, err := e.latestCheckpoint(ctx, mapping, changeColumn, changeType, sourceDB)
+	if err != nil {
+		return fmt.Errorf("failed to get max change tracking value: %w", err)
+	}
 
 	// Sync incremental changes based on change tracking type
 	var syncedRows int64
+	selectList := columns.selectList(schema)
 	switch changeType {
 	case "timestamp":
-		syncedRows, err = e.syncIncrementalByTimestampBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, changeColumn, checkpoint, syncConfig.Options)
-	case "auto_increment":
-		syncedRows, err = e.syncIncrementalByIDBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, changeColumn, checkpoint, syncConfig.Options)
+		syncedRows, err = e.syncIncrementalByTimestampBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, endpoints.dialect, targetDBName, mapping, selectList, masker, policy, sizer, changeColumn, checkpoint, latest, syncConfig.Options)
+	case "auto_increment", "primary_key":
+		syncedRows, err = e.syncIncrementalByIDBetweenDBs(ctx, sourceDB, sourceDBName, targetDB, endpoints.dialect, targetDBName, mapping, selectList, masker, policy, sizer, changeColumn, changeType, checkpoint, latest, syncConfig.Options)
 	default:
 		return fmt.Errorf("unsupported change tracking type: %s", changeType)
 	}
@@ -314,15 +314,26 @@ func (e *DefaultSyncEngine) SyncIncremental(ctx context.Context, job *SyncJob, m
 	}
 
 	// Update checkpoint with new sync time
-	if err := e.updateCheckpoint(ctx, mapping, changeColumn, sourceDB); err != nil {
+	if err := e.updateCheckpoint(ctx, mapping, changeColumn, latest); err != nil {
 		e.logger.WithError(err).Warn("Failed to update checkpoint")
 	}
 
+	// Propagate rows deleted at the source when the mapping opts in
+	var deletedRows int64
+	if mapping.DeleteDetection != DeleteDetectionNone {
+		deletedRows, err = e.propagateDeletes(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, syncConfig.Options)
+		if err != nil {
+			return fmt.Errorf("failed to propagate deletes: %w", err)
+		}
+		ReportTableDeletes(ctx, mapping.SourceTable, mapping.DeleteDetection, deletedRows)
+	}
+
 	e.logger.WithFields(logrus.Fields{
 		"job_id":       job.ID,
 		"source_table": mapping.SourceTable,
 		"target_table": mapping.TargetTable,
 		"synced_rows":  syncedRows,
+		"deleted_rows": deletedRows,
 	}).Info("Incremental table synchronization completed successfully")
 
 	return nil
@@ -353,6 +364,9 @@ func (e *DefaultSyncEngine) ValidateData(ctx context.Context, mapping *TableMapp
 	if err != nil {
 		return fmt.Errorf("failed to get target connection config: %w", err)
 	}
+	if err := checkDatabaseConnections(sourceConnConfig, targetConnConfig); err != nil {
+		return err
+	}
 
 	// Determine source/target database names (prefer sync config; fallback to connection config for backward compatibility)
 	sourceDBName := syncConfig.SourceDatabase
@@ -395,13 +409,20 @@ func (e *DefaultSyncEngine) ValidateData(ctx context.Context, mapping *TableMapp
 	}
 	defer targetDB.Close()
 
-	// Validate row counts between source and target
-	sourceCountQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", sourceDBName, mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		sourceCountQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
+	schema, err := e.getTableSchemaFromRemote(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		return fmt.Errorf("failed to get table schema: %w", err)
 	}
+	mapping, err = e.resolveRowFilter(ctx, nil, mapping, schema)
+	if err != nil {
+		return err
+	}
+
+	// Validate row counts between source and target
+	filter := mapping.rowFilter()
+	sourceCountQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`%s", sourceDBName, mapping.SourceTable, filter.where())
 	var sourceCount int64
-	if err := sourceDB.GetContext(ctx, &sourceCount, sourceCountQuery); err != nil {
+	if err := sourceDB.GetContext(ctx, &sourceCount, sourceCountQuery, filter.args...); err != nil {
 		return fmt.Errorf("failed to get source row count: %w", err)
 	}
 
@@ -446,8 +467,180 @@ func (e *DefaultSyncEngine) CreateTargetTable(ctx context.Context, localDB strin
 	return e.createOrRecreateTargetTable(ctx, localDB, schema.Name, schema)
 }
 
+// syncEndpoints holds the open source and target databases for a table mapping
+type syncEndpoints struct {
+	config           *SyncConfig
+	sourceConnConfig *ConnectionConfig
+	sourceDB         *sqlx.DB
+	sourceDBName     string
+	targetDB         *sqlx.DB
+	targetDBName     string
+	targetDBMissing  bool    // Planning only: the target database doesn't exist yet, targetDB has none selected
+	dialect          Dialect // Dialect of the target database
+}
+
+// Close closes both database connections
+func (s *syncEndpoints) Close() {
+	s.sourceDB.Close()
+	s.targetDB.Close()
+}
+
+// openSyncEndpoints resolves the sync config and connections of a mapping,
+// makes sure the target database exists and connects to source and target
+func (e *DefaultSyncEngine) openSyncEndpoints(ctx context.Context, mapping *TableMapping) (*syncEndpoints, error) {
+	return e.openConfigEndpoints(ctx, mapping.SyncConfigID)
+}
+
+// openConfigEndpoints resolves the sync config and its connections, makes sure the target
+// database exists and connects to source and target
+func (e *DefaultSyncEngine) openConfigEndpoints(ctx context.Context, syncConfigID string) (*syncEndpoints, error) {
+	return e.connectConfigEndpoints(ctx, syncConfigID, false)
+}
+
+// connectConfigEndpoints connects to the source and target of a sync config. When planning, a missing
+// target database is left missing and the target connection selects no database.
+func (e *DefaultSyncEngine) connectConfigEndpoints(ctx context.Context, syncConfigID string, planning bool) (*syncEndpoints, error) {
+	// Get sync config to retrieve connection info
+	syncConfig, err := e.repo.GetSyncConfig(ctx, syncConfigID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync config: %w", err)
+	}
+
+	// Get source connection config
+	sourceConnConfig, err := e.repo.GetConnection(ctx, syncConfig.SourceConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connection config: %w", err)
+	}
+
+	// Get target connection config
+	targetConnConfig, err := e.repo.GetConnection(ctx, syncConfig.TargetConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target connection config: %w", err)
+	}
+	if err := checkDatabaseConnections(sourceConnConfig, targetConnConfig); err != nil {
+		return nil, err
+	}
+
+	// Determine source/target database names (prefer sync config; fallback to connection config for backward compatibility)
+	sourceDBName := syncConfig.SourceDatabase
+	if sourceDBName == "" {
+		sourceDBName = sourceConnConfig.Database
+	}
+	targetDBName := syncConfig.TargetDatabase
+	if targetDBName == "" {
+		targetDBName = targetConnConfig.Database
+	}
+	if targetDBName == "" {
+		// If still empty, default to source db name
+		targetDBName = sourceDBName
+	}
+	if sourceDBName == "" || targetDBName == "" {
+		return nil, fmt.Errorf("source/target database is required")
+	}
+
+	// Ensure target database exists (auto-create if missing), a plan only looks
+	targetDBMissing, err := e.ensureTargetDatabase(ctx, targetConnConfig, targetDBName, planning)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to source database
+	{
+		cc := *sourceConnConfig
+		cc.Database = sourceDBName
+		sourceConnConfig = &cc
+	}
+	sourceDB, err := e.connectToRemote(sourceConnConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to source database: %w", err)
+	}
+
+	// Connect to target database
+	{
+		cc := *targetConnConfig
+		cc.Database = targetDBName
+		if targetDBMissing {
+			cc.Database = ""
+		}
+		targetConnConfig = &cc
+	}
+	targetDB, err := e.connectToRemoteWithVariables(targetConnConfig, targetSessionVariables(syncConfig.Options))
+	if err != nil {
+		sourceDB.Close()
+		return nil, fmt.Errorf("failed to connect to target database: %w", err)
+	}
+
+	return &syncEndpoints{
+		config:           syncConfig,
+		sourceConnConfig: sourceConnConfig,
+		sourceDB:         sourceDB,
+		sourceDBName:     sourceDBName,
+		targetDB:         targetDB,
+		targetDBName:     targetDBName,
+		targetDBMissing:  targetDBMissing,
+		dialect:          dialectFor(targetConnConfig),
+	}, nil
+}
+
+// ensureTargetDatabase creates the target database of a sync when it is missing. When planning, a
+// missing database is only reported.
+func (e *DefaultSyncEngine) ensureTargetDatabase(ctx context.Context, targetConnConfig *ConnectionConfig, targetDBName string, planning bool) (bool, error) {
+	serverConn := *targetConnConfig
+	serverConn.Database = ""
+	adminDB, err := e.connectToRemote(&serverConn)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to target server: %w", err)
+	}
+	defer adminDB.Close()
+
+	if planning {
+		exists, err := e.databaseExists(ctx, adminDB, targetDBName)
+		if err != nil {
+			return false, err
+		}
+		return !exists, nil
+	}
+	if err := e.ensureDatabaseExists(ctx, adminDB, targetDBName); err != nil {
+		return false, fmt.Errorf("failed to ensure target database exists: %w", err)
+	}
+	return false, nil
+}
+
+// targetSessionVariables returns the system variables the target sessions of a sync set
+func targetSessionVariables(options *SyncOptions) map[string]string {
+	if options != nil && options.DisableForeignKeyChecks {
+		return map[string]string{"foreign_key_checks": "0"}
+	}
+	return nil
+}
+
+// checkDatabaseConnections checks both connections of a sync reach a MySQL server. File and sqlite
+// connections only take part in full and incremental syncs, see importTable, exportTable and
+// snapshotTable.
+func checkDatabaseConnections(source, target *ConnectionConfig) error {
+	if source.isFile() {
+		return fmt.Errorf("source connection %s is a file connection, which only full and incremental syncs can load from", source.Name)
+	}
+	if target.isFile() {
+		return fmt.Errorf("target connection %s is a file connection, which only full and incremental syncs can export to", target.Name)
+	}
+	if source.isSQLite() {
+		return fmt.Errorf("source connection %s is a sqlite connection, which can only be a sync target", source.Name)
+	}
+	if target.isSQLite() {
+		return fmt.Errorf("target connection %s is a sqlite connection, which only full and incremental syncs can copy into", target.Name)
+	}
+	return nil
+}
+
 // connectToRemote establishes a connection to the remote database
 func (e *DefaultSyncEngine) connectToRemote(config *ConnectionConfig) (*sqlx.DB, error) {
+	return e.connectToRemoteWithVariables(config, nil)
+}
+
+// connectToRemoteWithVariables establishes a connection to the remote database whose sessions
+// set the given system variables when they connect
+func (e *DefaultSyncEngine) connectToRemoteWithVariables(config *ConnectionConfig, variables map[string]string) (*sqlx.DB, error) {
 	mysqlConfig := mysql.Config{
 		User:                 config.Username,
 		Passwd:               config.Password,
@@ -462,6 +655,9 @@ func (e *DefaultSyncEngine) connectToRemote(config *ConnectionConfig) (*sqlx.DB,
 		Loc:                  time.UTC,
 		Params:               map[string]string{"charset": "utf8mb4"},
 	}
+	for name, value := range variables {
+		mysqlConfig.Params[name] = value
+	}
 
 	if config.SSL {
 		mysqlConfig.TLSConfig = "true"
@@ -574,7 +770,8 @@ func (e *DefaultSyncEngine) getTableSchemaFromRemote(ctx context.Context, remote
 
 	indexMap := make(map[string]*IndexInfo)
 	for indexRows.Next() {
-		var indexName, columnName, indexType string
+		var indexName, indexType string
+		var columnName sql.NullString // NULL for a key part on an expression
 		var nonUnique int
 
 		if err := indexRows.Scan(&indexName, &columnName, &nonUnique, &indexType); err != nil {
@@ -582,21 +779,18 @@ func (e *DefaultSyncEngine) getTableSchemaFromRemote(ctx context.Context, remote
 		}
 
 		if idx, exists := indexMap[indexName]; exists {
-			idx.Columns = append(idx.Columns, columnName)
+			idx.Columns = append(idx.Columns, columnName.String)
 		} else {
 			indexMap[indexName] = &IndexInfo{
 				Name:    indexName,
-				Columns: []string{columnName},
+				Columns: []string{columnName.String},
 				Unique:  (nonUnique == 0),
 				Type:    indexType,
 			}
+			schema.Indexes = append(schema.Indexes, indexMap[indexName])
 		}
 	}
 
-	for _, idx := range indexMap {
-		schema.Indexes = append(schema.Indexes, idx)
-	}
-
 	// Get key information (PRIMARY, FOREIGN, UNIQUE)
 	keyQuery := `
 		SELECT 
@@ -631,6 +825,13 @@ func (e *DefaultSyncEngine) getTableSchemaFromRemote(ctx context.Context, remote
 		})
 	}
 
+	// Complete the schema with what INFORMATION_SCHEMA doesn't report the same way on all versions
+	statement, err := showCreateTable(ctx, remoteDB, tableName)
+	if err != nil {
+		return nil, err
+	}
+	applyCreateTable(schema, statement)
+
 	return schema, nil
 }
 
@@ -647,26 +848,13 @@ func (e *DefaultSyncEngine) createOrRecreateTargetTable(ctx context.Context, loc
 	}
 
 	// Drop existing table if it exists
-	dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", localDB, tableName)
+	dropQuery := "DROP TABLE IF EXISTS " + localDialect.TableName(localDB, tableName)
 	if _, err := e.localDB.ExecContext(ctx, dropQuery); err != nil {
 		return fmt.Errorf("failed to drop existing table: %w", err)
 	}
 
 	// Build CREATE TABLE statement
-	createQuery := e.buildCreateTableStatement(localDB, tableName, schema)
-
-	// ======= 【添加下面这段代码来打印 SQL】 =======
-	fmt.Println("\n==========================================")
-	fmt.Printf("DEBUG: 准备在目标库执行的 SQL 语句如下：\n%s\n", createQuery)
-	fmt.Println("==========================================\n")
-	// =============================================
-
-	// ======= 核心修复代码开始 =======
-	// 将 SQL 中的 DEFAULT_GENERATED 替换为空格
-	createQuery = strings.ReplaceAll(createQuery, "DEFAULT_GENERATED", "")
-	// 如果存在多个空格，可以简单处理一下（可选）
-	createQuery = strings.ReplaceAll(createQuery, "  ", " ")
-	// ======= 核心修复代码结束 =======
+	createQuery := e.buildCreateTableStatement(localDB, tableName, e.schemaForTarget(ctx, e.localDB, schema))
 
 	// Execute CREATE TABLE
 	if _, err := e.localDB.ExecContext(ctx, createQuery); err != nil {
@@ -681,66 +869,38 @@ func (e *DefaultSyncEngine) createOrRecreateTargetTable(ctx context.Context, loc
 	return nil
 }
 
-// buildCreateTableStatement builds a CREATE TABLE statement from schema
+// buildCreateTableStatement builds a CREATE TABLE statement of the local database from schema
 func (e *DefaultSyncEngine) buildCreateTableStatement(localDB, tableName string, schema *TableSchema) string {
-	var sb strings.Builder
-
-	sb.WriteString(fmt.Sprintf("CREATE TABLE `%s`.`%s` (\n", localDB, tableName))
-
-	// Add columns (preserve source charset/collation for string columns)
-	for i, col := range schema.Columns {
-		if i > 0 {
-			sb.WriteString(",\n")
-		}
-		sb.WriteString(fmt.Sprintf("  `%s` %s", col.Name, col.Type))
-
-		if col.CharacterSet != "" && col.Collation != "" {
-			sb.WriteString(fmt.Sprintf(" CHARACTER SET %s COLLATE %s", col.CharacterSet, col.Collation))
-		}
-
-		if !col.Nullable {
-			sb.WriteString(" NOT NULL")
-		}
+	return localDialect.CreateTableStatement(localDB, tableName, schema)
+}
 
-		if col.DefaultValue != "" {
-			sb.WriteString(fmt.Sprintf(" DEFAULT %s", col.DefaultValue))
-		}
+// columnDefinition builds the definition of a column as used by CREATE TABLE and ALTER TABLE
+func columnDefinition(col *ColumnInfo) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("`%s` %s", col.Name, col.Type))
 
-		if col.Extra != "" {
-			sb.WriteString(fmt.Sprintf(" %s", col.Extra))
-		}
+	if col.CharacterSet != "" && col.Collation != "" {
+		sb.WriteString(fmt.Sprintf(" CHARACTER SET %s COLLATE %s", col.CharacterSet, col.Collation))
 	}
 
-	// Add primary key
-	for _, key := range schema.Keys {
-		if key.Type == "PRIMARY KEY" {
-			sb.WriteString(",\n")
-			sb.WriteString(fmt.Sprintf("  PRIMARY KEY (`%s`)", strings.Join(key.Columns, "`, `")))
-			break
-		}
+	if col.GenerationExpression != "" {
+		sb.WriteString(fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", col.GenerationExpression, generatedStorage(col)))
 	}
 
-	// Add unique keys
-	for _, key := range schema.Keys {
-		if key.Type == "UNIQUE" {
-			sb.WriteString(",\n")
-			sb.WriteString(fmt.Sprintf("  UNIQUE KEY `%s` (`%s`)", key.Name, strings.Join(key.Columns, "`, `")))
-		}
+	if !col.Nullable {
+		sb.WriteString(" NOT NULL")
 	}
 
-	// Add indexes (excluding primary and unique which are already added)
-	for _, idx := range schema.Indexes {
-		if idx.Name != "PRIMARY" && !idx.Unique {
-			sb.WriteString(",\n")
-			sb.WriteString(fmt.Sprintf("  KEY `%s` (`%s`)", idx.Name, strings.Join(idx.Columns, "`, `")))
-		}
+	if col.DefaultValue != "" && col.GenerationExpression == "" {
+		sb.WriteString(fmt.Sprintf(" DEFAULT %s", defaultClauseValue(col)))
 	}
 
-	sb.WriteString("\n)")
+	if extra := columnAttributes(col.Extra); extra != "" {
+		sb.WriteString(fmt.Sprintf(" %s", extra))
+	}
 
-	// Table-level charset/collation: keep target consistent with source
-	if schema.TableCharset != "" && schema.TableCollation != "" {
-		sb.WriteString(fmt.Sprintf(" ENGINE=InnoDB DEFAULT CHARSET=%s COLLATE=%s", schema.TableCharset, schema.TableCollation))
+	if col.Comment != "" {
+		sb.WriteString(fmt.Sprintf(" COMMENT %s", sqlStringLiteral(col.Comment)))
 	}
 
 	return sb.String()
@@ -755,13 +915,11 @@ func (e *DefaultSyncEngine) syncAllData(ctx context.Context, remoteDB *sqlx.DB,
 	}).Info("Starting data synchronization")
 
 	// Get total row count to determine if we should use optimized batch processing
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		countQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`%s", mapping.SourceTable, filter.where())
 
 	var totalRows int64
-	if err := remoteDB.GetContext(ctx, &totalRows, countQuery); err != nil {
+	if err := remoteDB.GetContext(ctx, &totalRows, countQuery, filter.args...); err != nil {
 		return fmt.Errorf("failed to get row count: %w", err)
 	}
 
@@ -807,10 +965,7 @@ func (e *DefaultSyncEngine) syncAllData(ctx context.Context, remoteDB *sqlx.DB,
 	}
 
 	// Build SELECT query with optional WHERE clause
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	selectQuery := fmt.Sprintf("SELECT * FROM `%s`%s", mapping.SourceTable, filter.where())
 
 	e.logger.WithFields(logrus.Fields{
 		"source_table": mapping.SourceTable,
@@ -819,7 +974,7 @@ func (e *DefaultSyncEngine) syncAllData(ctx context.Context, remoteDB *sqlx.DB,
 	}).Info("Starting batch data transfer")
 
 	// Query all data from source
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery)
+	rows, err := remoteDB.QueryxContext(ctx, selectQuery, filter.args...)
 	if err != nil {
 		return fmt.Errorf("failed to query source data: %w", err)
 	}
@@ -886,39 +1041,10 @@ func (e *DefaultSyncEngine) insertBatch(ctx context.Context, localDB, tableName
 		return nil
 	}
 
-	// Build INSERT statement
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	// Add column names
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
-
-	sb.WriteString(") VALUES ")
-
-	// Add value placeholders
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
 	// Execute INSERT
-	if _, err := e.localDB.ExecContext(ctx, sb.String(), args...); err != nil {
+	if _, err := e.localDB.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch insert: %w", err)
 	}
 
@@ -947,8 +1073,42 @@ func (e *DefaultSyncEngine) ensureLocalDatabaseExists(ctx context.Context, local
 	return nil
 }
 
+// changeTrackingColumn returns the change tracking column of a table mapping: its tracking column
+// when set, which must be a timestamp or auto_increment column, otherwise the detected one
+func (e *DefaultSyncEngine) changeTrackingColumn(ctx context.Context, remoteDB *sqlx.DB, mapping *TableMapping) (string, string, error) {
+	if mapping.TrackingColumn == "" {
+		return e.detectChangeTrackingColumn(ctx, remoteDB, mapping.SourceTable)
+	}
+
+	query := `
+		SELECT DATA_TYPE, EXTRA
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_NAME = ?
+		AND COLUMN_NAME = ?
+	`
+	var dataType, extra string
+	if err := remoteDB.QueryRowxContext(ctx, query, mapping.SourceTable, mapping.TrackingColumn).Scan(&dataType, &extra); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", fmt.Errorf("tracking column %s not found in table %s", mapping.TrackingColumn, mapping.SourceTable)
+		}
+		return "", "", fmt.Errorf("failed to query tracking column: %w", err)
+	}
+
+	switch strings.ToLower(dataType) {
+	case "timestamp", "datetime", "date":
+		return mapping.TrackingColumn, "timestamp", nil
+	}
+	if strings.Contains(strings.ToLower(extra), "auto_increment") {
+		return mapping.TrackingColumn, "auto_increment", nil
+	}
+	return "", "", fmt.Errorf("tracking column %s of table %s must be a timestamp, datetime, date or auto_increment column", mapping.TrackingColumn, mapping.SourceTable)
+}
+
 // detectChangeTrackingColumn detects the column to use for change tracking
-// Returns column name, type (timestamp/auto_increment), and error
+// Returns column name, type (timestamp/auto_increment/primary_key), and error.
+// A primary_key column is the comma separated primary key of a table without a timestamp or
+// auto-increment column, e.g. a UUID or composite key.
 func (e *DefaultSyncEngine) detectChangeTrackingColumn(ctx context.Context, remoteDB *sqlx.DB, tableName string) (string, string, error) {
 	// First, try to find a timestamp column (updated_at, modified_at, etc.)
 	timestampQuery := `
@@ -992,12 +1152,23 @@ func (e *DefaultSyncEngine) detectChangeTrackingColumn(ctx context.Context, remo
 		return autoIncrementColumn, "auto_increment", nil
 	}
 
+	// Fall back to the primary key, which only picks up new rows when keys are inserted in
+	// increasing order, e.g. time ordered UUIDs or ULIDs
+	primaryKeys, err := e.getPrimaryKeyColumns(ctx, remoteDB, tableName)
+	if err == nil {
+		e.logger.WithFields(logrus.Fields{
+			"table_name":   tableName,
+			"primary_keys": primaryKeys,
+		}).Warn("No timestamp or auto_increment column, tracking changes by primary key: only rows inserted with a larger key are synced")
+		return strings.Join(primaryKeys, ","), "primary_key", nil
+	}
+
 	// If neither found, return error
-	return "", "", fmt.Errorf("no suitable change tracking column found (need timestamp or auto_increment column)")
+	return "", "", fmt.Errorf("no suitable change tracking column found (need timestamp, auto_increment or primary key column)")
 }
 
 // syncIncrementalByTimestamp syncs data based on timestamp column
-func (e *DefaultSyncEngine) syncIncrementalByTimestamp(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, timestampColumn string, checkpoint *SyncCheckpoint, options *SyncOptions) (int64, error) {
+func (e *DefaultSyncEngine) syncIncrementalByTimestamp(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, timestampColumn string, checkpoint, latest *SyncCheckpoint, options *SyncOptions) (int64, error) {
 	e.logger.WithFields(logrus.Fields{
 		"source_table":     mapping.SourceTable,
 		"timestamp_column": timestampColumn,
@@ -1010,15 +1181,14 @@ func (e *DefaultSyncEngine) syncIncrementalByTimestamp(ctx context.Context, remo
 		batchSize = options.BatchSize
 	}
 
-	// Build SELECT query for changed records
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` > ?", mapping.SourceTable, timestampColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	// Select the rows changed since the (timestamp, primary key) watermark
+	changed, err := e.changedRowsSince(ctx, remoteDB, mapping, timestampColumn, "timestamp", checkpoint)
+	if err != nil {
+		return 0, err
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", timestampColumn)
 
 	// Query changed data from source
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery, checkpoint.LastSyncTime)
+	rows, err := remoteDB.QueryxContext(ctx, changed.query("*", fmt.Sprintf("`%s`", mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query changed data: %w", err)
 	}
@@ -1081,7 +1251,7 @@ func (e *DefaultSyncEngine) syncIncrementalByTimestamp(ctx context.Context, remo
 }
 
 // syncIncrementalByID syncs data based on auto-increment ID column
-func (e *DefaultSyncEngine) syncIncrementalByID(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, idColumn string, checkpoint *SyncCheckpoint, options *SyncOptions) (int64, error) {
+func (e *DefaultSyncEngine) syncIncrementalByID(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, idColumn, changeType string, checkpoint, latest *SyncCheckpoint, options *SyncOptions) (int64, error) {
 	e.logger.WithFields(logrus.Fields{
 		"source_table":    mapping.SourceTable,
 		"id_column":       idColumn,
@@ -1094,21 +1264,14 @@ func (e *DefaultSyncEngine) syncIncrementalByID(ctx context.Context, remoteDB *s
 		batchSize = options.BatchSize
 	}
 
-	// Parse last sync value as integer
-	var lastID int64
-	if checkpoint.LastSyncValue != "" {
-		fmt.Sscanf(checkpoint.LastSyncValue, "%d", &lastID)
-	}
-
-	// Build SELECT query for new records
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` > ?", mapping.SourceTable, idColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	// Select the rows after the last synced key, typed like the key columns
+	changed, err := e.changedRowsSince(ctx, remoteDB, mapping, idColumn, changeType, checkpoint)
+	if err != nil {
+		return 0, err
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", idColumn)
 
 	// Query new data from source
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery, lastID)
+	rows, err := remoteDB.QueryxContext(ctx, changed.query("*", fmt.Sprintf("`%s`", mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query new data: %w", err)
 	}
@@ -1210,119 +1373,57 @@ func (e *DefaultSyncEngine) upsertBatch(ctx context.Context, localDB, tableName
 	}
 
 	// Determine conflict resolution strategy
-	conflictResolution := ConflictResolutionOverwrite
-	if options != nil {
-		conflictResolution = options.ConflictResolution
-	}
-
-	// Build INSERT statement
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	// Add column names
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
-
-	sb.WriteString(") VALUES ")
+	conflictResolution := options.conflictResolution()
 
-	// Add value placeholders
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
 	// Add conflict resolution clause
-	switch conflictResolution {
-	case ConflictResolutionOverwrite:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		first := true
-		for _, col := range columns {
-			// Skip primary key columns in UPDATE clause
-			isPrimaryKey := false
-			for _, pk := range primaryKeys {
-				if col == pk {
-					isPrimaryKey = true
-					break
-				}
-			}
-			if isPrimaryKey {
-				continue
-			}
-
-			if !first {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(fmt.Sprintf("`%s` = VALUES(`%s`)", col, col))
-			first = false
-		}
-	case ConflictResolutionSkip:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		// Update a dummy column to effectively skip the update
-		sb.WriteString(fmt.Sprintf("`%s` = `%s`", primaryKeys[0], primaryKeys[0]))
-	case ConflictResolutionError:
-		// No ON DUPLICATE KEY clause, will error on conflict
-	}
+	query += conflictClause(localDialect, columns, primaryKeys, conflictResolution)
 
 	// Execute INSERT/UPSERT
-	if _, err := e.localDB.ExecContext(ctx, sb.String(), args...); err != nil {
+	if _, err := e.localDB.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch upsert: %w", err)
 	}
 
 	return nil
 }
 
-// createInitialCheckpoint creates an initial checkpoint after full sync
-func (e *DefaultSyncEngine) createInitialCheckpoint(ctx context.Context, mapping *TableMapping, remoteDB *sqlx.DB) error {
+// initialCheckpoint returns the checkpoint an incremental mapping continues from after its initial
+// full sync, taken before the sync reads the rows; nil when the mapping has no change tracking column
+func (e *DefaultSyncEngine) initialCheckpoint(ctx context.Context, job *SyncJob, mapping *TableMapping, remoteDB *sqlx.DB) *SyncCheckpoint {
 	// Detect change tracking column
-	changeColumn, changeType, err := e.detectChangeTrackingColumn(ctx, remoteDB, mapping.SourceTable)
+	changeColumn, changeType, err := e.changeTrackingColumn(ctx, remoteDB, mapping)
 	if err != nil {
 		e.logger.WithError(err).Warn("Failed to detect change tracking column, checkpoint not created")
 		return nil // Don't fail the sync if checkpoint creation fails
 	}
 
-	// Get the maximum value of the change tracking column
-	var maxValue interface{}
-	var query string
-
-	switch changeType {
-	case "timestamp":
-		query = fmt.Sprintf("SELECT MAX(`%s`) FROM `%s`", changeColumn, mapping.SourceTable)
-	case "auto_increment":
-		query = fmt.Sprintf("SELECT MAX(`%s`) FROM `%s`", changeColumn, mapping.SourceTable)
+	// The watermark is the largest key of the rows the row filter selects
+	schema, err := e.getTableSchemaFromRemote(ctx, remoteDB, mapping.SourceTable)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to get table schema, checkpoint not created")
+		return nil
 	}
-
-	if err := remoteDB.GetContext(ctx, &maxValue, query); err != nil {
-		e.logger.WithError(err).Warn("Failed to get max change tracking value")
+	mapping, err = e.resolveRowFilter(ctx, job, mapping, schema)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to resolve row filter, checkpoint not created")
 		return nil
 	}
 
-	// Create checkpoint
-	checkpoint := &SyncCheckpoint{
-		ID:             mapping.ID,
-		TableMappingID: mapping.ID,
-		LastSyncTime:   time.Now(),
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+	checkpoint, err := e.latestCheckpoint(ctx, mapping, changeColumn, changeType, remoteDB)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to get max change tracking value")
+		return nil
 	}
+	return checkpoint
+}
 
-	if maxValue != nil {
-		checkpoint.LastSyncValue = fmt.Sprintf("%v", maxValue)
+// createInitialCheckpoint creates the initial checkpoint after full sync
+func (e *DefaultSyncEngine) createInitialCheckpoint(ctx context.Context, mapping *TableMapping, checkpoint *SyncCheckpoint) error {
+	if checkpoint == nil {
+		return nil
 	}
+	checkpoint.CreatedAt = checkpoint.UpdatedAt
 
 	if err := e.repo.CreateCheckpoint(ctx, checkpoint); err != nil {
 		e.logger.WithError(err).Warn("Failed to create initial checkpoint")
@@ -1331,35 +1432,14 @@ func (e *DefaultSyncEngine) createInitialCheckpoint(ctx context.Context, mapping
 
 	e.logger.WithFields(logrus.Fields{
 		"table_mapping_id": mapping.ID,
-		"change_column":    changeColumn,
-		"max_value":        maxValue,
+		"max_value":        checkpoint.LastSyncValue,
 	}).Info("Initial checkpoint created")
 
 	return nil
 }
 
-// updateCheckpoint updates the checkpoint after incremental sync
-func (e *DefaultSyncEngine) updateCheckpoint(ctx context.Context, mapping *TableMapping, changeColumn string, remoteDB *sqlx.DB) error {
-	// Get the maximum value of the change tracking column
-	query := fmt.Sprintf("SELECT MAX(`%s`) FROM `%s`", changeColumn, mapping.SourceTable)
-
-	var maxValue interface{}
-	if err := remoteDB.GetContext(ctx, &maxValue, query); err != nil {
-		return fmt.Errorf("failed to get max change tracking value: %w", err)
-	}
-
-	// Update checkpoint
-	checkpoint := &SyncCheckpoint{
-		ID:             mapping.ID,
-		TableMappingID: mapping.ID,
-		LastSyncTime:   time.Now(),
-		UpdatedAt:      time.Now(),
-	}
-
-	if maxValue != nil {
-		checkpoint.LastSyncValue = fmt.Sprintf("%v", maxValue)
-	}
-
+// updateCheckpoint stores the checkpoint taken before an incremental sync read the changed rows
+func (e *DefaultSyncEngine) updateCheckpoint(ctx context.Context, mapping *TableMapping, changeColumn string, checkpoint *SyncCheckpoint) error {
 	if err := e.repo.UpdateCheckpoint(ctx, mapping.ID, checkpoint); err != nil {
 		return fmt.Errorf("failed to update checkpoint: %w", err)
 	}
@@ -1367,12 +1447,33 @@ func (e *DefaultSyncEngine) updateCheckpoint(ctx context.Context, mapping *Table
 	e.logger.WithFields(logrus.Fields{
 		"table_mapping_id": mapping.ID,
 		"change_column":    changeColumn,
-		"max_value":        maxValue,
+		"max_value":        checkpoint.LastSyncValue,
 	}).Debug("Checkpoint updated")
 
 	return nil
 }
 
+// latestCheckpoint returns a checkpoint holding the largest value of the change tracking column among
+// the rows the mapping's row filter selects, stored as a typed watermark in CheckpointData, see
+// keyWatermark. A timestamp is stored with the primary key of its row, so the next sync continues
+// between rows sharing the timestamp.
+func (e *DefaultSyncEngine) latestCheckpoint(ctx context.Context, mapping *TableMapping, changeColumn, changeType string, remoteDB *sqlx.DB) (*SyncCheckpoint, error) {
+	watermark, err := e.trackingWatermarkKey(ctx, remoteDB, mapping.SourceTable, changeColumn, changeType)
+	if err != nil {
+		return nil, err
+	}
+	if err := watermark.loadLatest(ctx, remoteDB, mapping.SourceTable, mapping.rowFilter()); err != nil {
+		return nil, err
+	}
+	checkpoint, err := watermark.checkpoint(mapping)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.LastSyncTime = time.Now()
+	checkpoint.UpdatedAt = time.Now()
+	return checkpoint, nil
+}
+
 // ensureTargetTableExists ensures the target table exists, creating it if necessary
 func (e *DefaultSyncEngine) ensureTargetTableExists(ctx context.Context, localDB, tableName string, schema *TableSchema) error {
 	// Check if table exists
@@ -1399,13 +1500,11 @@ func (e *DefaultSyncEngine) ensureTargetTableExists(ctx context.Context, localDB
 // Requirement 7.5: Provide data validation and comparison functionality
 func (e *DefaultSyncEngine) validateRowCounts(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping) error {
 	// Get source row count
-	sourceCountQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		sourceCountQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	sourceCountQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`%s", mapping.SourceTable, filter.where())
 
 	var sourceCount int64
-	if err := remoteDB.GetContext(ctx, &sourceCount, sourceCountQuery); err != nil {
+	if err := remoteDB.GetContext(ctx, &sourceCount, sourceCountQuery, filter.args...); err != nil {
 		return fmt.Errorf("failed to get source row count: %w", err)
 	}
 
@@ -1469,16 +1568,11 @@ func (e *DefaultSyncEngine) validateDataChecksums(ctx context.Context, remoteDB
 	concatExpr := strings.Join(concatCols, ", '|', ")
 
 	pkCols := strings.Join(primaryKeys, "`, `")
+	filter := mapping.rowFilter()
 	sourceChecksumQuery := fmt.Sprintf(
-		"SELECT MD5(CONCAT(%s)) as checksum FROM `%s` ORDER BY `%s`",
-		concatExpr, mapping.SourceTable, pkCols,
+		"SELECT MD5(CONCAT(%s)) as checksum FROM `%s`%s ORDER BY `%s`",
+		concatExpr, mapping.SourceTable, filter.where(), pkCols,
 	)
-	if mapping.WhereClause != "" {
-		sourceChecksumQuery = fmt.Sprintf(
-			"SELECT MD5(CONCAT(%s)) as checksum FROM `%s` WHERE %s ORDER BY `%s`",
-			concatExpr, mapping.SourceTable, mapping.WhereClause, pkCols,
-		)
-	}
 
 	targetChecksumQuery := fmt.Sprintf(
 		"SELECT MD5(CONCAT(%s)) as checksum FROM `%s`.`%s` ORDER BY `%s`",
@@ -1487,7 +1581,7 @@ func (e *DefaultSyncEngine) validateDataChecksums(ctx context.Context, remoteDB
 
 	// Get source checksums
 	var sourceChecksums []string
-	if err := remoteDB.SelectContext(ctx, &sourceChecksums, sourceChecksumQuery); err != nil {
+	if err := remoteDB.SelectContext(ctx, &sourceChecksums, sourceChecksumQuery, filter.args...); err != nil {
 		return fmt.Errorf("failed to get source checksums: %w", err)
 	}
 
@@ -1599,7 +1693,7 @@ func (e *transactionalSyncEngine) syncIncrementalWithTx(ctx context.Context, job
 	}
 
 	// Detect change tracking column
-	changeColumn, changeType, err := e.detectChangeTrackingColumn(ctx, remoteDB, mapping.SourceTable)
+	changeColumn, changeType, err := e.changeTrackingColumn(ctx, remoteDB, mapping)
 	if err != nil {
 		return fmt.Errorf("failed to detect change tracking column: %w", err)
 	}
@@ -1615,8 +1709,8 @@ func (e *transactionalSyncEngine) syncIncrementalWithTx(ctx context.Context, job
 	switch changeType {
 	case "timestamp":
 		syncedRows, err = e.syncIncrementalByTimestampWithTx(ctx, remoteDB, connConfig.Database, mapping, changeColumn, checkpoint, primaryKeys, syncConfig.Options)
-	case "auto_increment":
-		syncedRows, err = e.syncIncrementalByIDWithTx(ctx, remoteDB, connConfig.Database, mapping, changeColumn, checkpoint, primaryKeys, syncConfig.Options)
+	case "auto_increment", "primary_key":
+		syncedRows, err = e.syncIncrementalByIDWithTx(ctx, remoteDB, connConfig.Database, mapping, changeColumn, changeType, checkpoint, primaryKeys, syncConfig.Options)
 	default:
 		return fmt.Errorf("unsupported change tracking type: %s", changeType)
 	}
@@ -1642,13 +1736,11 @@ func (e *transactionalSyncEngine) syncAllDataWithTx(ctx context.Context, remoteD
 	}
 
 	// Build SELECT query
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`", mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	selectQuery := fmt.Sprintf("SELECT * FROM `%s`%s", mapping.SourceTable, filter.where())
 
 	// Query data from source
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery)
+	rows, err := remoteDB.QueryxContext(ctx, selectQuery, filter.args...)
 	if err != nil {
 		return fmt.Errorf("failed to query source data: %w", err)
 	}
@@ -1703,35 +1795,9 @@ func (e *transactionalSyncEngine) insertBatchWithTx(ctx context.Context, localDB
 		return nil
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
-
-	sb.WriteString(") VALUES ")
-
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
-	if _, err := e.tx.ExecContext(ctx, sb.String(), args...); err != nil {
+	if _, err := e.tx.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch insert: %w", err)
 	}
 
@@ -1745,13 +1811,12 @@ func (e *transactionalSyncEngine) syncIncrementalByTimestampWithTx(ctx context.C
 		batchSize = options.BatchSize
 	}
 
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` > ?", mapping.SourceTable, timestampColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	changed, err := e.changedRowsSince(ctx, remoteDB, mapping, timestampColumn, "timestamp", checkpoint)
+	if err != nil {
+		return 0, err
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", timestampColumn)
 
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery, checkpoint.LastSyncTime)
+	rows, err := remoteDB.QueryxContext(ctx, changed.query("*", fmt.Sprintf("`%s`", mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query changed data: %w", err)
 	}
@@ -1793,24 +1858,18 @@ func (e *transactionalSyncEngine) syncIncrementalByTimestampWithTx(ctx context.C
 }
 
 // syncIncrementalByIDWithTx syncs incremental data by ID within transaction
-func (e *transactionalSyncEngine) syncIncrementalByIDWithTx(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, idColumn string, checkpoint *SyncCheckpoint, primaryKeys []string, options *SyncOptions) (int64, error) {
+func (e *transactionalSyncEngine) syncIncrementalByIDWithTx(ctx context.Context, remoteDB *sqlx.DB, localDB string, mapping *TableMapping, idColumn, changeType string, checkpoint *SyncCheckpoint, primaryKeys []string, options *SyncOptions) (int64, error) {
 	batchSize := 1000
 	if options != nil && options.BatchSize > 0 {
 		batchSize = options.BatchSize
 	}
 
-	var lastID int64
-	if checkpoint.LastSyncValue != "" {
-		fmt.Sscanf(checkpoint.LastSyncValue, "%d", &lastID)
-	}
-
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` > ?", mapping.SourceTable, idColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	changed, err := e.changedRowsSince(ctx, remoteDB, mapping, idColumn, changeType, checkpoint)
+	if err != nil {
+		return 0, err
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", idColumn)
 
-	rows, err := remoteDB.QueryxContext(ctx, selectQuery, lastID)
+	rows, err := remoteDB.QueryxContext(ctx, changed.query("*", fmt.Sprintf("`%s`", mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query new data: %w", err)
 	}
@@ -1857,71 +1916,14 @@ func (e *transactionalSyncEngine) upsertBatchWithTx(ctx context.Context, localDB
 		return nil
 	}
 
-	conflictResolution := ConflictResolutionOverwrite
-	if options != nil {
-		conflictResolution = options.ConflictResolution
-	}
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", localDB, tableName))
-
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
-	}
-
-	sb.WriteString(") VALUES ")
+	conflictResolution := options.conflictResolution()
 
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
-	}
+	query, args := localDialect.InsertStatement(localDB, tableName, columns, batch)
 
 	// Add conflict resolution clause
-	// Requirement 7.2: Handle data conflicts according to configured strategy
-	switch conflictResolution {
-	case ConflictResolutionOverwrite:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		first := true
-		for _, col := range columns {
-			isPrimaryKey := false
-			for _, pk := range primaryKeys {
-				if col == pk {
-					isPrimaryKey = true
-					break
-				}
-			}
-			if isPrimaryKey {
-				continue
-			}
-
-			if !first {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(fmt.Sprintf("`%s` = VALUES(`%s`)", col, col))
-			first = false
-		}
-	case ConflictResolutionSkip:
-		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		sb.WriteString(fmt.Sprintf("`%s` = `%s`", primaryKeys[0], primaryKeys[0]))
-	case ConflictResolutionError:
-		// No ON DUPLICATE KEY clause, will error on conflict
-	}
+	query += conflictClause(localDialect, columns, primaryKeys, conflictResolution)
 
-	if _, err := e.tx.ExecContext(ctx, sb.String(), args...); err != nil {
+	if _, err := e.tx.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("failed to execute batch upsert: %w", err)
 	}
 
@@ -2022,7 +2024,7 @@ type DataConflict struct {
 }
 
 // createOrRecreateTargetTableInDB creates or recreates the target table in the specified database connection
-func (e *DefaultSyncEngine) createOrRecreateTargetTableInDB(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName string, schema *TableSchema) error {
+func (e *DefaultSyncEngine) createOrRecreateTargetTableInDB(ctx context.Context, targetDB *sqlx.DB, d Dialect, targetDBName, tableName string, schema *TableSchema) error {
 	e.logger.WithFields(logrus.Fields{
 		"target_db":  targetDBName,
 		"table_name": tableName,
@@ -2034,17 +2036,13 @@ func (e *DefaultSyncEngine) createOrRecreateTargetTableInDB(ctx context.Context,
 	}
 
 	// Drop existing table if it exists
-	dropQuery := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", targetDBName, tableName)
+	dropQuery := "DROP TABLE IF EXISTS " + d.TableName(targetDBName, tableName)
 	if _, err := targetDB.ExecContext(ctx, dropQuery); err != nil {
 		return fmt.Errorf("failed to drop existing table: %w", err)
 	}
 
 	// Build CREATE TABLE statement
-	createQuery := e.buildCreateTableStatement(targetDBName, tableName, schema)
-
-	// Fix DEFAULT_GENERATED issue
-	createQuery = strings.ReplaceAll(createQuery, "DEFAULT_GENERATED", "")
-	createQuery = strings.ReplaceAll(createQuery, "  ", " ")
+	createQuery := d.CreateTableStatement(targetDBName, tableName, e.schemaForTarget(ctx, targetDB, schema))
 
 	// Execute CREATE TABLE
 	if _, err := targetDB.ExecContext(ctx, createQuery); err != nil {
@@ -2060,17 +2058,16 @@ func (e *DefaultSyncEngine) createOrRecreateTargetTableInDB(ctx context.Context,
 }
 
 // ensureTargetTableExistsInDB ensures the target table exists in the specified database connection
-func (e *DefaultSyncEngine) ensureTargetTableExistsInDB(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName string, schema *TableSchema) error {
+func (e *DefaultSyncEngine) ensureTargetTableExistsInDB(ctx context.Context, targetDB *sqlx.DB, d Dialect, targetDBName, tableName string, schema *TableSchema) error {
 	// Check if table exists
-	var count int
-	checkQuery := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
-	if err := targetDB.GetContext(ctx, &count, checkQuery, targetDBName, tableName); err != nil {
-		return fmt.Errorf("failed to check if table exists: %w", err)
+	exists, err := d.TableExists(ctx, targetDB, targetDBName, tableName)
+	if err != nil {
+		return err
 	}
 
-	if count == 0 {
+	if !exists {
 		// Table doesn't exist, create it
-		return e.createOrRecreateTargetTableInDB(ctx, targetDB, targetDBName, tableName, schema)
+		return e.createOrRecreateTargetTableInDB(ctx, targetDB, d, targetDBName, tableName, schema)
 	}
 
 	return nil
@@ -2078,14 +2075,12 @@ func (e *DefaultSyncEngine) ensureTargetTableExistsInDB(ctx context.Context, tar
 
 // ensureDatabaseExists ensures the database exists in the specified connection
 func (e *DefaultSyncEngine) ensureDatabaseExists(ctx context.Context, db *sqlx.DB, dbName string) error {
-	// Check if database exists
-	var count int
-	checkQuery := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?"
-	if err := db.GetContext(ctx, &count, checkQuery, dbName); err != nil {
-		return fmt.Errorf("failed to check if database exists: %w", err)
+	exists, err := e.databaseExists(ctx, db, dbName)
+	if err != nil {
+		return err
 	}
 
-	if count == 0 {
+	if !exists {
 		// Database doesn't exist, create it
 		createQuery := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", dbName)
 		if _, err := db.ExecContext(ctx, createQuery); err != nil {
@@ -2097,8 +2092,18 @@ func (e *DefaultSyncEngine) ensureDatabaseExists(ctx context.Context, db *sqlx.D
 	return nil
 }
 
+// databaseExists checks whether a database exists on the server of a connection
+func (e *DefaultSyncEngine) databaseExists(ctx context.Context, db *sqlx.DB, dbName string) (bool, error) {
+	var count int
+	checkQuery := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?"
+	if err := db.GetContext(ctx, &count, checkQuery, dbName); err != nil {
+		return false, fmt.Errorf("failed to check if database exists: %w", err)
+	}
+	return count > 0, nil
+}
+
 // syncAllDataBetweenDBs synchronizes all data from source database to target database
-func (e *DefaultSyncEngine) syncAllDataBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, options *SyncOptions) error {
+func (e *DefaultSyncEngine) syncAllDataBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, options *SyncOptions, plan *fullCopyPlan) error {
 	e.logger.WithFields(logrus.Fields{
 		"source_table": mapping.SourceTable,
 		"target_table": mapping.TargetTable,
@@ -2107,13 +2112,11 @@ func (e *DefaultSyncEngine) syncAllDataBetweenDBs(ctx context.Context, sourceDB
 	}).Info("Starting data synchronization between databases")
 
 	// Get total row count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", sourceDBName, mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		countQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
+	filter := mapping.rowFilter()
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`%s", sourceDBName, mapping.SourceTable, filter.where())
 
 	var totalRows int64
-	if err := sourceDB.GetContext(ctx, &totalRows, countQuery); err != nil {
+	if err := sourceDB.GetContext(ctx, &totalRows, countQuery, filter.args...); err != nil {
 		return fmt.Errorf("failed to get row count: %w", err)
 	}
 
@@ -2125,56 +2128,33 @@ func (e *DefaultSyncEngine) syncAllDataBetweenDBs(ctx context.Context, sourceDB
 		batchSize = options.BatchSize
 	}
 
-	// Build SELECT query
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`.`%s`", sourceDBName, mapping.SourceTable)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" WHERE %s", mapping.WhereClause)
-	}
-
-	// Query all data from source
-	rows, err := sourceDB.QueryxContext(ctx, selectQuery)
-	if err != nil {
-		return fmt.Errorf("failed to query source data: %w", err)
-	}
-	defer rows.Close()
-
-	// Get column names
-	columns, err := rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to get column names: %w", err)
+	// A resumed parallel copy continues its ranges; large tables are split into new ones
+	workers := 1
+	if options != nil && options.MaxConcurrency > 1 {
+		workers = options.MaxConcurrency
 	}
-
-	// Prepare batch insert
-	var batch []map[string]interface{}
-	processedRows := int64(0)
-
-	for rows.Next() {
-		// Scan row into map
-		rowData := make(map[string]interface{})
-		if err := rows.MapScan(rowData); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		batch = append(batch, rowData)
-
-		// Insert batch when it reaches batch size
-		if len(batch) >= batchSize {
-			if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-				return fmt.Errorf("failed to insert batch: %w", err)
-			}
-			processedRows += int64(len(batch))
-			ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
-			batch = batch[:0] // Clear batch
+	var ranges []*CopyRange
+	var err error
+	if plan.resume != nil {
+		ranges = plan.resume.Ranges
+	} else if workers > 1 && totalRows >= parallelCopyMinRows {
+		if ranges, err = e.planCopyRanges(ctx, sourceDB, sourceDBName, mapping, plan, workers, totalRows); err != nil {
+			return err
 		}
 	}
 
-	// Insert remaining rows
-	if len(batch) > 0 {
-		if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-			return fmt.Errorf("failed to insert final batch: %w", err)
-		}
-		processedRows += int64(len(batch))
-		ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+	// Tables with a primary key are copied in key order, chunk by chunk; others in a single scan
+	var processedRows int64
+	switch {
+	case len(ranges) > 0:
+		processedRows, err = e.copyTableInRanges(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, plan, ranges, workers, batchSize, totalRows)
+	case len(plan.primaryKeys) > 0:
+		processedRows, err = e.copyTableByKeyset(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, plan, batchSize, totalRows)
+	default:
+		processedRows, err = e.copyTableByScan(ctx, sourceDB, sourceDBName, targetDB, targetDBName, mapping, plan, batchSize, totalRows)
+	}
+	if err != nil {
+		return err
 	}
 
 	e.logger.WithFields(logrus.Fields{
@@ -2187,53 +2167,51 @@ func (e *DefaultSyncEngine) syncAllDataBetweenDBs(ctx context.Context, sourceDB
 	return nil
 }
 
-// insertBatchToDB inserts a batch of rows into the target table in the specified database connection
-func (e *DefaultSyncEngine) insertBatchToDB(ctx context.Context, targetDB *sqlx.DB, targetDBName, tableName string, columns []string, batch []map[string]interface{}) error {
-	if len(batch) == 0 {
-		return nil
-	}
-
-	// Build INSERT statement
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s`.`%s` (", targetDBName, tableName))
+// copyTableByScan copies a table without a primary key with a single unbounded SELECT
+func (e *DefaultSyncEngine) copyTableByScan(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, plan *fullCopyPlan, batchSize int, totalRows int64) (int64, error) {
+	// Build SELECT query
+	filter := mapping.rowFilter()
+	selectQuery := fmt.Sprintf("SELECT %s FROM `%s`.`%s`%s", plan.selectList, sourceDBName, mapping.SourceTable, filter.where())
 
-	// Add column names
-	for i, col := range columns {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString(fmt.Sprintf("`%s`", col))
+	// Query all data from source
+	rows, err := sourceDB.QueryxContext(ctx, selectQuery, filter.args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query source data: %w", err)
 	}
+	defer rows.Close()
 
-	sb.WriteString(") VALUES ")
-
-	// Add value placeholders
-	args := make([]interface{}, 0, len(batch)*len(columns))
-	for i := range batch {
-		if i > 0 {
-			sb.WriteString(", ")
-		}
-		sb.WriteString("(")
-		for j, col := range columns {
-			if j > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("?")
-			args = append(args, valueForUTF8MB3Insert(batch[i][col]))
-		}
-		sb.WriteString(")")
+	// Get column names
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get column names: %w", err)
 	}
 
-	// Execute INSERT
-	if _, err := targetDB.ExecContext(ctx, sb.String(), args...); err != nil {
-		return fmt.Errorf("failed to execute batch insert: %w", err)
+	// Rows are read while earlier batches are masked and written
+	processedRows := int64(0)
+	err = e.runRowPipeline(ctx, pipelineStages{
+		read:      cursorReader(rows, columns, plan.sizer, batchSize),
+		transform: maskStage(plan.masker),
+		write: func(ctx context.Context, batch *rowBatch) error {
+			if len(batch.rows) == 0 {
+				return nil
+			}
+			if err := e.writeCopyBatch(ctx, targetDB, targetDBName, mapping.TargetTable, plan, batch, false); err != nil {
+				return fmt.Errorf("failed to insert batch: %w", err)
+			}
+			processedRows += int64(len(batch.rows))
+			ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, processedRows, totalRows)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return processedRows, nil
 }
 
 // syncIncrementalByTimestampBetweenDBs performs incremental sync by timestamp between two databases
-func (e *DefaultSyncEngine) syncIncrementalByTimestampBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, timestampColumn string, checkpoint *SyncCheckpoint, options *SyncOptions) (int64, error) {
+func (e *DefaultSyncEngine) syncIncrementalByTimestampBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, d Dialect, targetDBName string, mapping *TableMapping, selectList string, masker *rowMasker, policy *writePolicy, sizer *batchSizer, timestampColumn string, checkpoint, latest *SyncCheckpoint, options *SyncOptions) (int64, error) {
 	e.logger.WithFields(logrus.Fields{
 		"source_table":     mapping.SourceTable,
 		"timestamp_column": timestampColumn,
@@ -2246,15 +2224,18 @@ func (e *DefaultSyncEngine) syncIncrementalByTimestampBetweenDBs(ctx context.Con
 		batchSize = options.BatchSize
 	}
 
-	// Build SELECT query
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`.`%s` WHERE `%s` > ?", sourceDBName, mapping.SourceTable, timestampColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	// Select the rows changed since the (timestamp, primary key) watermark
+	changed, err := e.changedRowsSince(ctx, sourceDB, mapping, timestampColumn, "timestamp", checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	// Rows past the checkpoint taken before they are read are left to the next run
+	if latest != nil {
+		changed.upTo(latest)
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", timestampColumn)
 
 	// Query incremental data from source
-	rows, err := sourceDB.QueryxContext(ctx, selectQuery, checkpoint.LastSyncTime)
+	rows, err := sourceDB.QueryxContext(ctx, changed.query(selectList, fmt.Sprintf("`%s`.`%s`", sourceDBName, mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query incremental data: %w", err)
 	}
@@ -2266,35 +2247,25 @@ func (e *DefaultSyncEngine) syncIncrementalByTimestampBetweenDBs(ctx context.Con
 		return 0, fmt.Errorf("failed to get column names: %w", err)
 	}
 
-	// Prepare batch insert
-	var batch []map[string]interface{}
+	// Rows changed again, or read again in the look-back window, may already be in the target
+	clause := policy.clause(d, columns)
 	syncedRows := int64(0)
-
-	for rows.Next() {
-		// Scan row into map
-		rowData := make(map[string]interface{})
-		if err := rows.MapScan(rowData); err != nil {
-			return 0, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		batch = append(batch, rowData)
-
-		// Insert batch when it reaches batch size
-		if len(batch) >= batchSize {
-			if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-				return 0, fmt.Errorf("failed to insert batch: %w", err)
+	err = e.runRowPipeline(ctx, pipelineStages{
+		read:      cursorReader(rows, columns, sizer, batchSize),
+		transform: maskStage(masker),
+		write: func(ctx context.Context, batch *rowBatch) error {
+			err := sizer.write(batch.rows, func(rows [][]interface{}) error {
+				return e.writeRowsToDB(ctx, targetDB, d, targetDBName, mapping.TargetTable, columns, rows, clause)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
 			}
-			syncedRows += int64(len(batch))
-			batch = batch[:0] // Clear batch
-		}
-	}
-
-	// Insert remaining rows
-	if len(batch) > 0 {
-		if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-			return 0, fmt.Errorf("failed to insert final batch: %w", err)
-		}
-		syncedRows += int64(len(batch))
+			syncedRows += int64(len(batch.rows))
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	e.logger.WithFields(logrus.Fields{
@@ -2307,7 +2278,7 @@ func (e *DefaultSyncEngine) syncIncrementalByTimestampBetweenDBs(ctx context.Con
 }
 
 // syncIncrementalByIDBetweenDBs performs incremental sync by ID between two databases
-func (e *DefaultSyncEngine) syncIncrementalByIDBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, targetDBName string, mapping *TableMapping, idColumn string, checkpoint *SyncCheckpoint, options *SyncOptions) (int64, error) {
+func (e *DefaultSyncEngine) syncIncrementalByIDBetweenDBs(ctx context.Context, sourceDB *sqlx.DB, sourceDBName string, targetDB *sqlx.DB, d Dialect, targetDBName string, mapping *TableMapping, selectList string, masker *rowMasker, policy *writePolicy, sizer *batchSizer, idColumn, changeType string, checkpoint, latest *SyncCheckpoint, options *SyncOptions) (int64, error) {
 	e.logger.WithFields(logrus.Fields{
 		"source_table":    mapping.SourceTable,
 		"id_column":       idColumn,
@@ -2320,21 +2291,19 @@ func (e *DefaultSyncEngine) syncIncrementalByIDBetweenDBs(ctx context.Context, s
 		batchSize = options.BatchSize
 	}
 
-	// Parse last sync ID
-	var lastID int64
-	if checkpoint.LastSyncValue != "" {
-		fmt.Sscanf(checkpoint.LastSyncValue, "%d", &lastID)
+	// Select the rows after the last synced key, typed like the key columns: an integer of any
+	// width, a character key such as a UUID, or every column of a composite key
+	changed, err := e.changedRowsSince(ctx, sourceDB, mapping, idColumn, changeType, checkpoint)
+	if err != nil {
+		return 0, err
 	}
-
-	// Build SELECT query
-	selectQuery := fmt.Sprintf("SELECT * FROM `%s`.`%s` WHERE `%s` > ?", sourceDBName, mapping.SourceTable, idColumn)
-	if mapping.WhereClause != "" {
-		selectQuery += fmt.Sprintf(" AND (%s)", mapping.WhereClause)
+	// Rows past the checkpoint taken before they are read are left to the next run
+	if latest != nil {
+		changed.upTo(latest)
 	}
-	selectQuery += fmt.Sprintf(" ORDER BY `%s`", idColumn)
 
 	// Query incremental data from source
-	rows, err := sourceDB.QueryxContext(ctx, selectQuery, lastID)
+	rows, err := sourceDB.QueryxContext(ctx, changed.query(selectList, fmt.Sprintf("`%s`.`%s`", sourceDBName, mapping.SourceTable)), changed.args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to query incremental data: %w", err)
 	}
@@ -2346,35 +2315,25 @@ func (e *DefaultSyncEngine) syncIncrementalByIDBetweenDBs(ctx context.Context, s
 		return 0, fmt.Errorf("failed to get column names: %w", err)
 	}
 
-	// Prepare batch insert
-	var batch []map[string]interface{}
+	// Rows are read while earlier batches are masked and written
+	clause := policy.clause(d, columns)
 	syncedRows := int64(0)
-
-	for rows.Next() {
-		// Scan row into map
-		rowData := make(map[string]interface{})
-		if err := rows.MapScan(rowData); err != nil {
-			return 0, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		batch = append(batch, rowData)
-
-		// Insert batch when it reaches batch size
-		if len(batch) >= batchSize {
-			if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-				return 0, fmt.Errorf("failed to insert batch: %w", err)
+	err = e.runRowPipeline(ctx, pipelineStages{
+		read:      cursorReader(rows, columns, sizer, batchSize),
+		transform: maskStage(masker),
+		write: func(ctx context.Context, batch *rowBatch) error {
+			err := sizer.write(batch.rows, func(rows [][]interface{}) error {
+				return e.writeRowsToDB(ctx, targetDB, d, targetDBName, mapping.TargetTable, columns, rows, clause)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
 			}
-			syncedRows += int64(len(batch))
-			batch = batch[:0] // Clear batch
-		}
-	}
-
-	// Insert remaining rows
-	if len(batch) > 0 {
-		if err := e.insertBatchToDB(ctx, targetDB, targetDBName, mapping.TargetTable, columns, batch); err != nil {
-			return 0, fmt.Errorf("failed to insert final batch: %w", err)
-		}
-		syncedRows += int64(len(batch))
+			syncedRows += int64(len(batch.rows))
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	e.logger.WithFields(logrus.Fields{
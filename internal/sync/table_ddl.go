@@ -0,0 +1,406 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// generatedExtra matches the EXTRA marker of a generated column
+	generatedExtra = regexp.MustCompile(`(?i)\b(VIRTUAL|STORED|PERSISTENT) GENERATED\b`)
+	// defaultGeneratedExtra matches the EXTRA marker MySQL 8 puts on columns with an expression default
+	defaultGeneratedExtra = regexp.MustCompile(`(?i)\bDEFAULT_GENERATED\b`)
+	// defaultKeyword matches defaults that are SQL keywords or functions rather than values
+	defaultKeyword = regexp.MustCompile(`(?i)^(NULL|CURRENT_TIMESTAMP|NOW|LOCALTIME|LOCALTIMESTAMP)(\(\d*\))?$`)
+	// defaultLiteral matches defaults that are SQL literals or expressions already
+	defaultLiteral = regexp.MustCompile(`(?i)^('|\(|[bx]'|_\w+'|0x[0-9a-f]+$|-?\d+(\.\d+)?$)`)
+	// foreignKeyAction matches the referential actions of a foreign key
+	foreignKeyAction = regexp.MustCompile(`(?i)\bON (DELETE|UPDATE) (RESTRICT|CASCADE|SET NULL|NO ACTION|SET DEFAULT)\b`)
+	// versionComment matches the version comment MySQL wraps the partitioning clause in
+	versionComment = regexp.MustCompile(`^/\*!\d+\s*`)
+)
+
+// showCreateTable returns the CREATE TABLE statement of a table of the connection's database
+func showCreateTable(ctx context.Context, db *sqlx.DB, tableName string) (string, error) {
+	var name, statement string
+	if err := db.QueryRowxContext(ctx, fmt.Sprintf("SHOW CREATE TABLE `%s`", tableName)).Scan(&name, &statement); err != nil {
+		return "", fmt.Errorf("failed to show create table %s: %w", tableName, err)
+	}
+	return statement, nil
+}
+
+// applyCreateTable completes a schema read from INFORMATION_SCHEMA with what only SHOW CREATE TABLE
+// prints faithfully on every server version: generation expressions, defaults as SQL, comments, key
+// parts, foreign keys, CHECK constraints, table options and partitioning. Indexes are put in the
+// order of the statement.
+func applyCreateTable(schema *TableSchema, statement string) {
+	columns := make(map[string]*ColumnInfo, len(schema.Columns))
+	for _, col := range schema.Columns {
+		columns[strings.ToLower(col.Name)] = col
+	}
+	indexes := make(map[string]*IndexInfo, len(schema.Indexes))
+	for _, idx := range schema.Indexes {
+		indexes[strings.ToLower(idx.Name)] = idx
+	}
+	position := make(map[*IndexInfo]int)
+
+	// Each column, index and constraint is printed on a line of its own, the table options follow
+	// the closing parenthesis and the partitioning clause the table options
+	lines := strings.Split(statement, "\n")
+	i := 1
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, ")") {
+			break
+		}
+		tokens := sqlTokens(strings.TrimSuffix(line, ","))
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch first := strings.ToUpper(tokens[0]); {
+		case strings.HasPrefix(tokens[0], "`"):
+			if col, ok := columns[strings.ToLower(unquoteIdentifier(tokens[0]))]; ok {
+				applyColumnTokens(col, tokens[1:])
+			}
+		case first == "CONSTRAINT":
+			applyConstraintTokens(schema, tokens)
+		default:
+			name, parts, comment := parseIndexTokens(tokens)
+			if idx, ok := indexes[strings.ToLower(name)]; ok && parts != nil {
+				idx.Parts = parts
+				idx.Comment = comment
+				position[idx] = len(position)
+			}
+		}
+	}
+
+	if i < len(lines) {
+		applyTableOptionTokens(schema, sqlTokens(strings.TrimPrefix(strings.TrimSpace(lines[i]), ")")))
+		partitioning := strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+		if versionComment.MatchString(partitioning) {
+			partitioning = strings.TrimSpace(strings.TrimSuffix(versionComment.ReplaceAllString(partitioning, ""), "*/"))
+		}
+		schema.Partitioning = partitioning
+	}
+
+	sort.SliceStable(schema.Indexes, func(a, b int) bool {
+		pa, okA := position[schema.Indexes[a]]
+		pb, okB := position[schema.Indexes[b]]
+		if okA && okB {
+			return pa < pb
+		}
+		return okA && !okB
+	})
+}
+
+// applyColumnTokens reads the generation expression, default and comment of a column definition
+func applyColumnTokens(col *ColumnInfo, tokens []string) {
+	for i := 0; i < len(tokens)-1; i++ {
+		switch strings.ToUpper(tokens[i]) {
+		case "AS":
+			if strings.HasPrefix(tokens[i+1], "(") {
+				col.GenerationExpression = stripParentheses(tokens[i+1])
+				i++
+			}
+		case "DEFAULT":
+			col.DefaultValue = tokens[i+1]
+			i++
+		case "COMMENT":
+			col.Comment = unquoteString(tokens[i+1])
+			i++
+		}
+	}
+}
+
+// parseIndexTokens returns the name, key parts and comment of an index definition
+func parseIndexTokens(tokens []string) (name string, parts []string, comment string) {
+	if strings.EqualFold(tokens[0], "PRIMARY") {
+		name = "PRIMARY"
+	}
+	for i, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, "`") && name == "" && parts == nil:
+			name = unquoteIdentifier(token)
+		case strings.HasPrefix(token, "(") && parts == nil:
+			for _, part := range splitTopLevel(stripParentheses(token)) {
+				parts = append(parts, strings.TrimSpace(part))
+			}
+		case strings.EqualFold(token, "COMMENT") && i+1 < len(tokens):
+			comment = unquoteString(tokens[i+1])
+		}
+	}
+	return name, parts, comment
+}
+
+// applyConstraintTokens adds the foreign key or CHECK constraint of a CONSTRAINT definition
+func applyConstraintTokens(schema *TableSchema, tokens []string) {
+	if len(tokens) < 4 {
+		return
+	}
+	name := unquoteIdentifier(tokens[1])
+
+	switch strings.ToUpper(tokens[2]) {
+	case "CHECK":
+		schema.Checks = append(schema.Checks, &CheckConstraint{
+			Name:        name,
+			Expression:  stripParentheses(tokens[3]),
+			NotEnforced: strings.Contains(strings.ToUpper(strings.Join(tokens[4:], " ")), "NOT ENFORCED"),
+		})
+	case "FOREIGN":
+		fk := &ForeignKeyInfo{Name: name}
+		for i := 3; i < len(tokens); i++ {
+			switch {
+			case strings.HasPrefix(tokens[i], "(") && fk.Columns == nil:
+				fk.Columns = identifierList(tokens[i])
+			case strings.EqualFold(tokens[i], "REFERENCES") && i+2 < len(tokens):
+				reference := strings.SplitN(tokens[i+1], "`.`", 2)
+				if len(reference) == 2 {
+					fk.ReferencedSchema = unquoteIdentifier(reference[0] + "`")
+					fk.ReferencedTable = unquoteIdentifier("`" + reference[1])
+				} else {
+					fk.ReferencedTable = unquoteIdentifier(tokens[i+1])
+				}
+				fk.ReferencedColumns = identifierList(tokens[i+2])
+				i += 2
+			}
+		}
+		for _, action := range foreignKeyAction.FindAllStringSubmatch(strings.Join(tokens, " "), -1) {
+			if strings.EqualFold(action[1], "DELETE") {
+				fk.OnDelete = strings.ToUpper(action[2])
+			} else {
+				fk.OnUpdate = strings.ToUpper(action[2])
+			}
+		}
+		schema.ForeignKeys = append(schema.ForeignKeys, fk)
+	}
+}
+
+// applyTableOptionTokens reads the engine, row format and comment from the table options
+func applyTableOptionTokens(schema *TableSchema, tokens []string) {
+	for _, token := range tokens {
+		option, value, ok := strings.Cut(token, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(option)) {
+		case "ENGINE":
+			schema.Engine = value
+		case "ROW_FORMAT":
+			schema.RowFormat = strings.ToUpper(value)
+		case "COMMENT":
+			schema.Comment = unquoteString(value)
+		}
+	}
+}
+
+// sqlTokens splits a definition at whitespace outside of quotes and parentheses, so a quoted
+// string, an identifier or a parenthesized list is a single token
+func sqlTokens(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(s) {
+				i++
+				current.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case (c == ' ' || c == '\t') && depth == 0:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// splitTopLevel splits a list at commas outside of quotes and parentheses
+func splitTopLevel(s string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// stripParentheses removes the parentheses around a parenthesized token
+func stripParentheses(token string) string {
+	if strings.HasPrefix(token, "(") && strings.HasSuffix(token, ")") {
+		return token[1 : len(token)-1]
+	}
+	return token
+}
+
+// identifierList returns the identifiers of a parenthesized list like (`a`,`b`)
+func identifierList(token string) []string {
+	var names []string
+	for _, item := range splitTopLevel(stripParentheses(token)) {
+		names = append(names, unquoteIdentifier(strings.TrimSpace(item)))
+	}
+	return names
+}
+
+// unquoteIdentifier removes the backticks around an identifier
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) >= 2 && strings.HasPrefix(identifier, "`") && strings.HasSuffix(identifier, "`") {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], "``", "`")
+	}
+	return identifier
+}
+
+// unquoteString returns the value of a quoted SQL string, undoing doubled quotes and backslash escapes
+func unquoteString(literal string) string {
+	if len(literal) < 2 || literal[0] != '\'' || literal[len(literal)-1] != '\'' {
+		return literal
+	}
+	body := literal[1 : len(literal)-1]
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\'' && i+1 < len(body) && body[i+1] == '\'':
+			i++
+		case c == '\\' && i+1 < len(body):
+			i++
+			switch body[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			case '0':
+				c = 0
+			case 'Z':
+				c = 26
+			default:
+				c = body[i]
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// columnAttributes returns the EXTRA attributes of a column that are part of its DDL, without the
+// markers MySQL reports for generated columns and expression defaults
+func columnAttributes(extra string) string {
+	extra = defaultGeneratedExtra.ReplaceAllString(generatedExtra.ReplaceAllString(extra, ""), "")
+	return strings.Join(strings.Fields(extra), " ")
+}
+
+// generatedStorage returns STORED or VIRTUAL for a generated column
+func generatedStorage(col *ColumnInfo) string {
+	extra := strings.ToUpper(col.Extra)
+	if strings.Contains(extra, "STORED") || strings.Contains(extra, "PERSISTENT") {
+		return "STORED"
+	}
+	return "VIRTUAL"
+}
+
+// defaultClauseValue returns the value of a column's DEFAULT clause. Defaults read from SHOW CREATE
+// TABLE are SQL already; a bare value from INFORMATION_SCHEMA is quoted, or parenthesized when
+// MySQL 8 marks it as an expression.
+func defaultClauseValue(col *ColumnInfo) string {
+	value := col.DefaultValue
+	switch {
+	case defaultKeyword.MatchString(value), defaultLiteral.MatchString(value):
+		return value
+	case defaultGeneratedExtra.MatchString(col.Extra):
+		return "(" + value + ")"
+	}
+	return sqlStringLiteral(value)
+}
+
+// foreignKeyDefinition builds the definition of a foreign key as used by CREATE TABLE
+func foreignKeyDefinition(fk *ForeignKeyInfo) string {
+	table := fmt.Sprintf("`%s`", fk.ReferencedTable)
+	if fk.ReferencedSchema != "" {
+		table = fmt.Sprintf("`%s`.`%s`", fk.ReferencedSchema, fk.ReferencedTable)
+	}
+	definition := fmt.Sprintf("CONSTRAINT `%s` FOREIGN KEY (%s) REFERENCES %s (%s)",
+		fk.Name, quoteColumns(fk.Columns), table, quoteColumns(fk.ReferencedColumns))
+	if fk.OnDelete != "" {
+		definition += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		definition += " ON UPDATE " + fk.OnUpdate
+	}
+	return definition
+}
+
+// checkDefinition builds the definition of a CHECK constraint as used by CREATE TABLE
+func checkDefinition(check *CheckConstraint) string {
+	definition := fmt.Sprintf("CONSTRAINT `%s` CHECK (%s)", check.Name, check.Expression)
+	if check.NotEnforced {
+		definition += " NOT ENFORCED"
+	}
+	return definition
+}
+
+// tableOptions builds the table options of CREATE TABLE
+func tableOptions(schema *TableSchema) string {
+	engine := schema.Engine
+	if engine == "" && schema.TableCharset != "" && schema.TableCollation != "" {
+		engine = "InnoDB"
+	}
+
+	var options []string
+	if engine != "" {
+		options = append(options, "ENGINE="+engine)
+	}
+	if schema.TableCharset != "" && schema.TableCollation != "" {
+		options = append(options, fmt.Sprintf("DEFAULT CHARSET=%s COLLATE=%s", schema.TableCharset, schema.TableCollation))
+	}
+	if schema.RowFormat != "" && schema.RowFormat != "DEFAULT" {
+		options = append(options, "ROW_FORMAT="+schema.RowFormat)
+	}
+	if schema.Comment != "" {
+		options = append(options, "COMMENT="+sqlStringLiteral(schema.Comment))
+	}
+	return strings.Join(options, " ")
+}
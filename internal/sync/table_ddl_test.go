@@ -0,0 +1,279 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ordersCreateTable is SHOW CREATE TABLE of an orders table on MySQL 8
+const ordersCreateTable = "CREATE TABLE `orders` (\n" +
+	"  `id` bigint NOT NULL AUTO_INCREMENT,\n" +
+	"  `customer_id` bigint NOT NULL,\n" +
+	"  `email` varchar(255) COLLATE utf8mb4_0900_ai_ci DEFAULT '' COMMENT 'Contact, it''s optional',\n" +
+	"  `qty` int NOT NULL DEFAULT '1',\n" +
+	"  `price` decimal(10,2) NOT NULL,\n" +
+	"  `total` decimal(12,2) GENERATED ALWAYS AS ((`qty` * `price`)) STORED,\n" +
+	"  `ref` binary(16) DEFAULT (uuid_to_bin(uuid())),\n" +
+	"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+	"  PRIMARY KEY (`id`,`created_at`),\n" +
+	"  KEY `idx_customer` (`customer_id`,`created_at` DESC),\n" +
+	"  KEY `idx_email_lower` ((lower(`email`))),\n" +
+	"  KEY `idx_email` (`email`(32)) COMMENT 'prefix, (32)',\n" +
+	"  CONSTRAINT `fk_customer` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`) ON DELETE CASCADE,\n" +
+	"  CONSTRAINT `fk_audit` FOREIGN KEY (`id`) REFERENCES `audit`.`entries` (`order_id`) ON DELETE SET NULL ON UPDATE NO ACTION,\n" +
+	"  CONSTRAINT `chk_qty` CHECK ((`qty` > 0)),\n" +
+	"  CONSTRAINT `chk_price` CHECK ((`price` >= 0)) /*!80016 NOT ENFORCED */\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Customer orders'\n" +
+	"/*!50500 PARTITION BY RANGE  COLUMNS(created_at)\n" +
+	"(PARTITION p2024 VALUES LESS THAN ('2025-01-01') ENGINE = InnoDB,\n" +
+	" PARTITION pmax VALUES LESS THAN (MAXVALUE) ENGINE = InnoDB) */"
+
+// ordersInformationSchema is the orders table as INFORMATION_SCHEMA reports it
+func ordersInformationSchema() *TableSchema {
+	return &TableSchema{
+		Name:           "orders",
+		TableCharset:   "utf8mb4",
+		TableCollation: "utf8mb4_0900_ai_ci",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint", Extra: "auto_increment"},
+			{Name: "customer_id", Type: "bigint"},
+			{Name: "email", Type: "varchar(255)", Nullable: true, CharacterSet: "utf8mb4", Collation: "utf8mb4_0900_ai_ci"},
+			{Name: "qty", Type: "int", DefaultValue: "1"},
+			{Name: "price", Type: "decimal(10,2)"},
+			{Name: "total", Type: "decimal(12,2)", Nullable: true, Extra: "STORED GENERATED"},
+			{Name: "ref", Type: "binary(16)", Nullable: true, DefaultValue: "uuid_to_bin(uuid())", Extra: "DEFAULT_GENERATED"},
+			{Name: "created_at", Type: "datetime", DefaultValue: "CURRENT_TIMESTAMP", Extra: "DEFAULT_GENERATED"},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "idx_customer", Columns: []string{"customer_id", "created_at"}, Type: "BTREE"},
+			{Name: "idx_email", Columns: []string{"email"}, Type: "BTREE"},
+			{Name: "idx_email_lower", Columns: []string{""}, Type: "BTREE"},
+			{Name: "PRIMARY", Columns: []string{"id", "created_at"}, Unique: true, Type: "BTREE"},
+		},
+		Keys: []*KeyInfo{{Name: "PRIMARY", Type: "PRIMARY KEY", Columns: []string{"id", "created_at"}}},
+	}
+}
+
+func TestApplyCreateTable(t *testing.T) {
+	schema := ordersInformationSchema()
+	applyCreateTable(schema, ordersCreateTable)
+
+	email := schema.Columns[2]
+	assert.Equal(t, "''", email.DefaultValue)
+	assert.Equal(t, "Contact, it's optional", email.Comment)
+	assert.Equal(t, "'1'", schema.Columns[3].DefaultValue)
+	assert.Equal(t, "(`qty` * `price`)", schema.Columns[5].GenerationExpression)
+	assert.Equal(t, "(uuid_to_bin(uuid()))", schema.Columns[6].DefaultValue)
+
+	// Indexes follow the statement, with their key parts as printed
+	var names []string
+	for _, idx := range schema.Indexes {
+		names = append(names, idx.Name)
+	}
+	assert.Equal(t, []string{"PRIMARY", "idx_customer", "idx_email_lower", "idx_email"}, names)
+	assert.Equal(t, []string{"`customer_id`", "`created_at` DESC"}, schema.Indexes[1].Parts)
+	assert.Equal(t, []string{"(lower(`email`))"}, schema.Indexes[2].Parts)
+	assert.Equal(t, []string{"`email`(32)"}, schema.Indexes[3].Parts)
+	assert.Equal(t, "prefix, (32)", schema.Indexes[3].Comment)
+
+	assert.Equal(t, []*ForeignKeyInfo{
+		{Name: "fk_customer", Columns: []string{"customer_id"}, ReferencedTable: "customers", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE"},
+		{Name: "fk_audit", Columns: []string{"id"}, ReferencedSchema: "audit", ReferencedTable: "entries", ReferencedColumns: []string{"order_id"},
+			OnDelete: "SET NULL", OnUpdate: "NO ACTION"},
+	}, schema.ForeignKeys)
+	assert.Equal(t, []*CheckConstraint{
+		{Name: "chk_qty", Expression: "(`qty` > 0)"},
+		{Name: "chk_price", Expression: "(`price` >= 0)", NotEnforced: true},
+	}, schema.Checks)
+
+	assert.Equal(t, "InnoDB", schema.Engine)
+	assert.Equal(t, "COMPRESSED", schema.RowFormat)
+	assert.Equal(t, "Customer orders", schema.Comment)
+	assert.Equal(t, "PARTITION BY RANGE  COLUMNS(created_at)\n"+
+		"(PARTITION p2024 VALUES LESS THAN ('2025-01-01') ENGINE = InnoDB,\n"+
+		" PARTITION pmax VALUES LESS THAN (MAXVALUE) ENGINE = InnoDB)", schema.Partitioning)
+}
+
+func TestBuildCreateTableStatement_FromCreateTable(t *testing.T) {
+	schema := ordersInformationSchema()
+	applyCreateTable(schema, ordersCreateTable)
+
+	engine := &DefaultSyncEngine{}
+	assert.Equal(t, "CREATE TABLE `replica`.`orders` (\n"+
+		"  `id` bigint NOT NULL auto_increment,\n"+
+		"  `customer_id` bigint NOT NULL,\n"+
+		"  `email` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT '' COMMENT 'Contact, it''s optional',\n"+
+		"  `qty` int NOT NULL DEFAULT '1',\n"+
+		"  `price` decimal(10,2) NOT NULL,\n"+
+		"  `total` decimal(12,2) GENERATED ALWAYS AS ((`qty` * `price`)) STORED,\n"+
+		"  `ref` binary(16) DEFAULT (uuid_to_bin(uuid())),\n"+
+		"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,\n"+
+		"  PRIMARY KEY (`id`, `created_at`),\n"+
+		"  KEY `idx_customer` (`customer_id`, `created_at` DESC),\n"+
+		"  KEY `idx_email_lower` ((lower(`email`))),\n"+
+		"  KEY `idx_email` (`email`(32)) COMMENT 'prefix, (32)',\n"+
+		"  CONSTRAINT `fk_customer` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`) ON DELETE CASCADE,\n"+
+		"  CONSTRAINT `fk_audit` FOREIGN KEY (`id`) REFERENCES `audit`.`entries` (`order_id`) ON DELETE SET NULL ON UPDATE NO ACTION,\n"+
+		"  CONSTRAINT `chk_qty` CHECK ((`qty` > 0)),\n"+
+		"  CONSTRAINT `chk_price` CHECK ((`price` >= 0)) NOT ENFORCED\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci ROW_FORMAT=COMPRESSED COMMENT='Customer orders'\n"+
+		"PARTITION BY RANGE  COLUMNS(created_at)\n"+
+		"(PARTITION p2024 VALUES LESS THAN ('2025-01-01') ENGINE = InnoDB,\n"+
+		" PARTITION pmax VALUES LESS THAN (MAXVALUE) ENGINE = InnoDB)",
+		engine.buildCreateTableStatement("replica", "orders", schema))
+}
+
+func TestColumnDefinition_Defaults(t *testing.T) {
+	// Values from INFORMATION_SCHEMA are quoted unless they are SQL already
+	assert.Equal(t, "`status` varchar(16) NOT NULL DEFAULT 'new'", columnDefinition(&ColumnInfo{Name: "status", Type: "varchar(16)", DefaultValue: "new"}))
+	assert.Equal(t, "`note` varchar(16) DEFAULT 'it''s'", columnDefinition(&ColumnInfo{Name: "note", Type: "varchar(16)", Nullable: true, DefaultValue: "it's"}))
+	assert.Equal(t, "`qty` int NOT NULL DEFAULT -1", columnDefinition(&ColumnInfo{Name: "qty", Type: "int", DefaultValue: "-1"}))
+	assert.Equal(t, "`flag` bit(1) NOT NULL DEFAULT b'0'", columnDefinition(&ColumnInfo{Name: "flag", Type: "bit(1)", DefaultValue: "b'0'"}))
+	assert.Equal(t, "`id` char(36) NOT NULL DEFAULT (uuid())",
+		columnDefinition(&ColumnInfo{Name: "id", Type: "char(36)", DefaultValue: "uuid()", Extra: "DEFAULT_GENERATED"}))
+	assert.Equal(t, "`updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP",
+		columnDefinition(&ColumnInfo{Name: "updated_at", Type: "timestamp", DefaultValue: "CURRENT_TIMESTAMP", Extra: "DEFAULT_GENERATED on update CURRENT_TIMESTAMP"}))
+	// MariaDB quotes its defaults itself
+	assert.Equal(t, "`seen_at` datetime DEFAULT current_timestamp()",
+		columnDefinition(&ColumnInfo{Name: "seen_at", Type: "datetime", Nullable: true, DefaultValue: "current_timestamp()"}))
+}
+
+func TestGetTableSchemaFromRemote_FunctionalIndex(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+
+	sourceMock.ExpectQuery("SELECT TABLE_COLLATION").WithArgs("customers").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_COLLATION"}).AddRow("utf8mb4_0900_ai_ci"))
+	sourceMock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("customers").
+		WillReturnRows(targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("email", "varchar(255)", "YES", nil, "", "utf8mb4", "utf8mb4_0900_ai_ci"))
+	sourceMock.ExpectQuery("FROM INFORMATION_SCHEMA.STATISTICS").WithArgs("customers").
+		WillReturnRows(sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NON_UNIQUE", "INDEX_TYPE"}).
+			AddRow("idx_email_lower", nil, 1, "BTREE").
+			AddRow("PRIMARY", "id", 0, "BTREE"))
+	sourceMock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS").WithArgs("customers").
+		WillReturnRows(sqlmock.NewRows([]string{"CONSTRAINT_NAME", "CONSTRAINT_TYPE"}).AddRow("PRIMARY", "PRIMARY KEY"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE TABLE `customers`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).AddRow("customers", "CREATE TABLE `customers` (\n"+
+			"  `id` bigint NOT NULL AUTO_INCREMENT,\n"+
+			"  `email` varchar(255) DEFAULT NULL,\n"+
+			"  PRIMARY KEY (`id`),\n"+
+			"  KEY `idx_email_lower` ((lower(`email`)))\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci"))
+
+	schema, err := engine.getTableSchemaFromRemote(context.Background(), sourceDB, "customers")
+	require.NoError(t, err)
+	assert.Equal(t, []*IndexInfo{
+		{Name: "PRIMARY", Columns: []string{"id"}, Unique: true, Type: "BTREE", Parts: []string{"`id`"}},
+		{Name: "idx_email_lower", Columns: []string{""}, Type: "BTREE", Parts: []string{"(lower(`email`))"}},
+	}, schema.Indexes)
+	assert.Equal(t, "NULL", schema.Columns[1].DefaultValue)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestParseServerVersion(t *testing.T) {
+	assert.Equal(t, serverVersion{major: 8, minor: 0, patch: 36}, parseServerVersion("8.0.36"))
+	assert.Equal(t, serverVersion{major: 5, minor: 7, patch: 44}, parseServerVersion("5.7.44-log"))
+	assert.Equal(t, serverVersion{mariaDB: true, major: 10, minor: 6, patch: 12}, parseServerVersion("5.5.5-10.6.12-MariaDB-1:10.6.12+maria~ubu2004"))
+	assert.True(t, parseServerVersion("8.0.13").mysqlAtLeast(8, 0, 13))
+	assert.False(t, parseServerVersion("10.11.6-MariaDB").mysqlAtLeast(8, 0, 0))
+}
+
+func TestTranslateTableSchema(t *testing.T) {
+	schema := ordersInformationSchema()
+	applyCreateTable(schema, ordersCreateTable)
+	schema.Columns[1].Extra = "INVISIBLE"
+
+	// MySQL 8 takes the schema as it is
+	translated, notes := translateTableSchema(schema, parseServerVersion("8.0.36"))
+	assert.Empty(t, notes)
+	assert.Equal(t, schema.Columns[2], translated.Columns[2])
+	assert.Len(t, translated.Indexes, 4)
+	assert.Len(t, translated.Checks, 2)
+
+	// MySQL 5.7 knows neither the 0900 collations, functional indexes, expression defaults nor CHECK constraints
+	translated, notes = translateTableSchema(schema, parseServerVersion("5.7.44-log"))
+	assert.Equal(t, "utf8mb4_unicode_ci", translated.TableCollation)
+	assert.Equal(t, "utf8mb4_unicode_ci", translated.Columns[2].Collation)
+	assert.Equal(t, "", translated.Columns[1].Extra)
+	assert.Equal(t, "", translated.Columns[6].DefaultValue)
+	assert.Equal(t, "CURRENT_TIMESTAMP", translated.Columns[7].DefaultValue)
+	assert.Len(t, translated.Indexes, 3)
+	assert.Empty(t, translated.Checks)
+	assert.Equal(t, []string{
+		"Collation utf8mb4_0900_ai_ci is not supported by the target server, using utf8mb4_unicode_ci",
+		"Column customer_id is made visible, the target server has no invisible columns",
+		"Expression default of column ref is not supported by the target server, left out",
+		"Functional index idx_email_lower is not supported by the target server, left out",
+		"CHECK constraint chk_qty is not supported by the target server, left out",
+		"CHECK constraint chk_price is not supported by the target server, left out",
+	}, notes)
+
+	// MariaDB enforces every CHECK constraint and has no functional indexes
+	translated, notes = translateTableSchema(schema, parseServerVersion("10.11.6-MariaDB"))
+	assert.Equal(t, "utf8mb4_unicode_ci", translated.TableCollation)
+	assert.Equal(t, []*CheckConstraint{schema.Checks[0]}, translated.Checks)
+	assert.Len(t, translated.Indexes, 3)
+	assert.Len(t, notes, 3)
+
+	// The source schema itself is untouched
+	assert.Equal(t, "utf8mb4_0900_ai_ci", schema.Columns[2].Collation)
+	assert.Len(t, schema.Indexes, 4)
+}
+
+func TestTranslateCollation(t *testing.T) {
+	mysql57 := parseServerVersion("5.7.44")
+	mysql8 := parseServerVersion("8.0.36")
+	mariaDB := parseServerVersion("10.5.23-MariaDB")
+
+	assert.Equal(t, "utf8mb4_bin", translateCollation("utf8mb4_0900_bin", mariaDB))
+	assert.Equal(t, "utf8mb4_bin", translateCollation("utf8mb4_0900_as_cs", mysql57))
+	assert.Equal(t, "utf8mb4_unicode_ci", translateCollation("utf8mb4_de_pb_0900_ai_ci", mysql57))
+	assert.Equal(t, "utf8mb4_0900_ai_ci", translateCollation("utf8mb4_uca1400_ai_ci", mysql8))
+	assert.Equal(t, "utf8_general_ci", translateCollation("utf8mb3_general_ci", mariaDB))
+	assert.Equal(t, "utf8mb3_general_ci", translateCollation("utf8mb3_general_ci", mysql8))
+	assert.Equal(t, "utf8", translateCharset("utf8mb3", mysql57))
+	assert.Equal(t, "latin1_swedish_ci", translateCollation("latin1_swedish_ci", mysql57))
+}
+
+func TestSchemaForTarget_ForeignKeys(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	schema := ordersInformationSchema()
+	applyCreateTable(schema, ordersCreateTable)
+	ctx := context.Background()
+
+	// A target session checking foreign keys gets none, tables load in any order
+	expectTargetServer(targetMock, "8.0.36", 1)
+	assert.Empty(t, engine.schemaForTarget(ctx, targetDB, schema).ForeignKeys)
+
+	// With foreign key checks disabled they are created
+	expectTargetServer(targetMock, "8.0.36", 0)
+	assert.Len(t, engine.schemaForTarget(ctx, targetDB, schema).ForeignKeys, 2)
+	assert.Len(t, schema.ForeignKeys, 2)
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestColumnMapping_GeneratedColumns(t *testing.T) {
+	schema := ordersInformationSchema()
+	applyCreateTable(schema, ordersCreateTable)
+
+	// Without column rules the target computes generated columns
+	var none *columnMapping
+	assert.Equal(t, "`id`, `customer_id`, `email`, `qty`, `price`, `ref`, `created_at`", none.selectList(schema))
+
+	// With them the values are copied into a regular column
+	mapping := &TableMapping{ColumnRules: []*ColumnRule{{RuleType: ColumnRuleRename, SourceColumn: "email", TargetColumn: "contact"}}}
+	target, err := mapping.columnMapping().targetSchema(schema)
+	require.NoError(t, err)
+	assert.Equal(t, "`total` decimal(12,2)", columnDefinition(target.Columns[5]))
+	assert.Equal(t, []string{"`contact`(32)"}, target.Indexes[2].Parts)
+	assert.Len(t, target.Indexes, 3)
+	assert.Empty(t, target.Checks)
+	assert.Empty(t, target.Partitioning)
+	assert.Len(t, target.ForeignKeys, 2)
+}
@@ -0,0 +1,165 @@
+package sync
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrimaryKeyQuery = "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE"
+
+// newTestEngine creates an engine that only logs errors, for tests that don't query a database
+func newTestEngine() *DefaultSyncEngine {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return &DefaultSyncEngine{logger: logger}
+}
+
+// newMockEngine creates an engine with sqlmock source and target databases
+func newMockEngine(t *testing.T) (*DefaultSyncEngine, *sqlx.DB, sqlmock.Sqlmock, *sqlx.DB, sqlmock.Sqlmock) {
+	source, sourceMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { source.Close() })
+
+	target, targetMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { target.Close() })
+
+	return newTestEngine(), sqlx.NewDb(source, "sqlmock"), sourceMock, sqlx.NewDb(target, "sqlmock"), targetMock
+}
+
+// ordersSchema is the orders table of the shop source database the tests sync: an auto-increment
+// key, text, numeric, binary and temporal columns, and an index on created_at
+func ordersSchema() *TableSchema {
+	return &TableSchema{
+		Name: "orders",
+		Columns: []*ColumnInfo{
+			{Name: "id", Type: "bigint", Extra: "auto_increment"},
+			{Name: "note", Type: "varchar(255)", Nullable: true, CharacterSet: "utf8mb4", Collation: "utf8mb4_general_ci"},
+			{Name: "status", Type: "varchar(16)", Nullable: true, CharacterSet: "utf8mb4", Collation: "utf8mb4_general_ci"},
+			{Name: "region", Type: "varchar(16)", Nullable: true, CharacterSet: "utf8mb4", Collation: "utf8mb4_general_ci"},
+			{Name: "total", Type: "double", Nullable: true},
+			{Name: "photo", Type: "blob", Nullable: true},
+			{Name: "shipped_on", Type: "date", Nullable: true},
+			{Name: "paid_at", Type: "datetime", Nullable: true},
+			{Name: "deleted_at", Type: "datetime", Nullable: true},
+			{Name: "updated_at", Type: "datetime", Nullable: true},
+			{Name: "created_at", Type: "datetime", DefaultValue: "CURRENT_TIMESTAMP", Extra: "DEFAULT_GENERATED"},
+		},
+		Indexes: []*IndexInfo{
+			{Name: "PRIMARY", Columns: []string{"id"}, Unique: true, Type: "BTREE"},
+			{Name: "idx_created", Columns: []string{"created_at"}, Type: "BTREE"},
+		},
+	}
+}
+
+// ordersColumns returns the orders table with only the named columns, in that order, and the
+// indexes covering them
+func ordersColumns(names ...string) *TableSchema {
+	full := ordersSchema()
+	schema := &TableSchema{Name: full.Name}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		col := findColumn(full.Columns, name)
+		if col == nil {
+			panic("orders has no column " + name)
+		}
+		schema.Columns = append(schema.Columns, col)
+		selected[strings.ToLower(name)] = true
+	}
+	for _, idx := range full.Indexes {
+		covered := true
+		for _, col := range idx.Columns {
+			covered = covered && selected[strings.ToLower(col)]
+		}
+		if covered {
+			schema.Indexes = append(schema.Indexes, idx)
+		}
+	}
+	return schema
+}
+
+// ordersColumnRows returns the INFORMATION_SCHEMA.COLUMNS rows of the named orders columns
+func ordersColumnRows(names ...string) *sqlmock.Rows {
+	rows := targetColumnRows()
+	for _, col := range ordersColumns(names...).Columns {
+		nullable := "NO"
+		if col.Nullable {
+			nullable = "YES"
+		}
+		rows.AddRow(col.Name, col.Type, nullable, nullString(col.DefaultValue), col.Extra, nullString(col.CharacterSet), nullString(col.Collation))
+	}
+	return rows
+}
+
+// nullString returns nil for an empty string, as INFORMATION_SCHEMA reports a missing value
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func targetColumnRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_DEFAULT", "EXTRA", "CHARACTER_SET_NAME", "COLLATION_NAME"})
+}
+
+func targetIndexRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"INDEX_NAME", "COLUMN_NAME", "NON_UNIQUE", "INDEX_TYPE"}).AddRow("PRIMARY", "id", 0, "BTREE")
+}
+
+// expectTargetSchema mocks the queries evolveTargetSchema runs to read the orders target table
+// and the target server version
+func expectTargetSchema(targetMock sqlmock.Sqlmock, columns *sqlmock.Rows, indexes *sqlmock.Rows) {
+	expectTableSchema(targetMock, columns, indexes)
+	expectTargetServer(targetMock, "8.0.36", 1)
+}
+
+// expectTableSchema mocks the queries getTableSchemaFromRemote reads the orders table with
+func expectTableSchema(mock sqlmock.Sqlmock, columns *sqlmock.Rows, indexes *sqlmock.Rows) {
+	mock.ExpectQuery("SELECT TABLE_COLLATION").WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_COLLATION"}).AddRow("utf8mb4_general_ci"))
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("orders").WillReturnRows(columns)
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.STATISTICS").WithArgs("orders").WillReturnRows(indexes)
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS").WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"CONSTRAINT_NAME", "CONSTRAINT_TYPE"}))
+	mock.ExpectQuery(regexp.QuoteMeta("SHOW CREATE TABLE `orders`")).
+		WillReturnRows(sqlmock.NewRows([]string{"Table", "Create Table"}).
+			AddRow("orders", "CREATE TABLE `orders` (\n  `id` bigint NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB"))
+}
+
+// expectTargetServer mocks the query schemaForTarget reads the target server with
+func expectTargetServer(targetMock sqlmock.Sqlmock, version string, foreignKeyChecks int) {
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION(), @@SESSION.foreign_key_checks")).
+		WillReturnRows(sqlmock.NewRows([]string{"VERSION()", "@@SESSION.foreign_key_checks"}).AddRow(version, foreignKeyChecks))
+}
+
+func expectPrimaryKey(mock sqlmock.Sqlmock, columns ...string) {
+	rows := sqlmock.NewRows([]string{"COLUMN_NAME"})
+	for _, column := range columns {
+		rows.AddRow(column)
+	}
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE").WithArgs("orders").WillReturnRows(rows)
+}
+
+// expectKeyColumnTypes expects the column type query of the orders table, given name and type pairs
+func expectKeyColumnTypes(mock sqlmock.Sqlmock, nameTypePairs ...string) {
+	rows := sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE"})
+	for i := 0; i < len(nameTypePairs); i += 2 {
+		rows.AddRow(nameTypePairs[i], nameTypePairs[i+1])
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COLUMN_NAME, COLUMN_TYPE")).WithArgs("orders").WillReturnRows(rows)
+}
+
+func explainRowsEstimate(rows int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "select_type", "table", "type", "rows"}).AddRow(1, "SIMPLE", "orders", "ALL", rows)
+}
+
+func fingerprintRows(count int64, hash uint64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count", "hash"}).AddRow(count, hash)
+}
@@ -11,6 +11,7 @@ var (
 	ErrSyncConfigNotFound   = errors.New("sync config not found")
 	ErrTableMappingNotFound = errors.New("table mapping not found")
 	ErrJobNotFound          = errors.New("job not found")
+	ErrCheckpointNotFound   = errors.New("checkpoint not found")
 	ErrInvalidConfig        = errors.New("invalid configuration")
 	ErrConnectionFailed     = errors.New("connection failed")
 )
@@ -21,6 +22,166 @@ type SyncMode string
 const (
 	SyncModeFull        SyncMode = "full"
 	SyncModeIncremental SyncMode = "incremental"
+	SyncModeCDC         SyncMode = "cdc" // Initial full copy, then binlog change data capture
+)
+
+// isValidSyncMode reports whether mode is a supported sync mode
+func isValidSyncMode(mode SyncMode) bool {
+	switch mode {
+	case SyncModeFull, SyncModeIncremental, SyncModeCDC:
+		return true
+	}
+	return false
+}
+
+// DeleteDetection defines how incremental sync handles target rows whose source rows were deleted
+type DeleteDetection string
+
+const (
+	DeleteDetectionNone   DeleteDetection = ""       // Deletes are not detected
+	DeleteDetectionHard   DeleteDetection = "hard"   // Orphaned target rows are deleted
+	DeleteDetectionSoft   DeleteDetection = "soft"   // Orphaned target rows get their soft delete column set
+	DeleteDetectionReport DeleteDetection = "report" // Orphaned target rows are only counted and logged
+)
+
+// defaultSoftDeleteColumn is used when a soft delete mapping doesn't name its column
+const defaultSoftDeleteColumn = "deleted_at"
+
+// isValidDeleteDetection reports whether mode is a supported delete detection mode
+func isValidDeleteDetection(mode DeleteDetection) bool {
+	switch mode {
+	case DeleteDetectionNone, DeleteDetectionHard, DeleteDetectionSoft, DeleteDetectionReport:
+		return true
+	}
+	return false
+}
+
+// FilterOperator defines how a row filter condition compares a source column with its value
+type FilterOperator string
+
+const (
+	FilterOpEqual        FilterOperator = "eq"
+	FilterOpNotEqual     FilterOperator = "ne"
+	FilterOpGreater      FilterOperator = "gt"
+	FilterOpGreaterEqual FilterOperator = "gte"
+	FilterOpLess         FilterOperator = "lt"
+	FilterOpLessEqual    FilterOperator = "lte"
+	FilterOpIn           FilterOperator = "in"          // Value is a non-empty list
+	FilterOpNotIn        FilterOperator = "not_in"      // Value is a non-empty list
+	FilterOpLike         FilterOperator = "like"        // Value is a LIKE pattern
+	FilterOpNotLike      FilterOperator = "not_like"    // Value is a LIKE pattern
+	FilterOpBetween      FilterOperator = "between"     // Value is a list of the lower and upper bound
+	FilterOpIsNull       FilterOperator = "is_null"     // No value
+	FilterOpIsNotNull    FilterOperator = "is_not_null" // No value
+)
+
+// FilterLogic defines how a row filter group combines its conditions
+type FilterLogic string
+
+const (
+	FilterLogicAnd FilterLogic = "and"
+	FilterLogicOr  FilterLogic = "or"
+)
+
+// RowFilter selects the source rows a table mapping syncs. A group combines its conditions with
+// Logic, a condition compares Column with Operator and its value. Values are bound as parameters; a
+// string value may be a template variable such as {{last_sync_time}}, {{job_start}} or {{now - 30d}}.
+type RowFilter struct {
+	Logic      FilterLogic  `json:"logic,omitempty"`      // and (default) or or
+	Conditions []*RowFilter `json:"conditions,omitempty"` // Members of a group
+
+	Column   string         `json:"column,omitempty"`
+	Operator FilterOperator `json:"operator,omitempty"`
+	Operand  interface{}    `json:"value,omitempty"` // A scalar, or a list for in, not_in and between
+}
+
+// SchemaPolicy defines how changes of the source table schema are applied to the target table
+type SchemaPolicy string
+
+const (
+	SchemaPolicyAdditive SchemaPolicy = "additive" // Additive changes are applied, destructive ones are skipped and logged (default)
+	SchemaPolicyApprove  SchemaPolicy = "approve"  // Additive changes are applied, destructive ones fail the sync until approved
+	SchemaPolicyFail     SchemaPolicy = "fail"     // Any schema difference fails the sync
+)
+
+// isValidSchemaPolicy reports whether policy is a supported schema policy, empty meaning additive
+func isValidSchemaPolicy(policy SchemaPolicy) bool {
+	switch policy {
+	case "", SchemaPolicyAdditive, SchemaPolicyApprove, SchemaPolicyFail:
+		return true
+	}
+	return false
+}
+
+// WriteMode defines how a sync writes rows into a target table that may already hold them. Every
+// mode but the default keeps the target table's rows and target-only columns on a full sync.
+type WriteMode string
+
+const (
+	WriteModeDefault     WriteMode = ""             // Rows whose key exists follow the conflict resolution, a full sync replaces the table's rows
+	WriteModeAppend      WriteMode = "append"       // Rows whose key exists are skipped, existing rows are never changed
+	WriteModeUpsert      WriteMode = "upsert"       // Rows whose key exists get their update columns overwritten
+	WriteModeUpsertNewer WriteMode = "upsert_newer" // Like upsert, but only when the row's tracking column isn't older than the target's
+)
+
+// isValidWriteMode reports whether mode is a supported write mode
+func isValidWriteMode(mode WriteMode) bool {
+	switch mode {
+	case WriteModeDefault, WriteModeAppend, WriteModeUpsert, WriteModeUpsertNewer:
+		return true
+	}
+	return false
+}
+
+// SchemaObjectType is a kind of schema object besides tables that a sync replicates to the target
+type SchemaObjectType string
+
+const (
+	SchemaObjectView      SchemaObjectType = "view"
+	SchemaObjectFunction  SchemaObjectType = "function"
+	SchemaObjectProcedure SchemaObjectType = "procedure"
+	SchemaObjectTrigger   SchemaObjectType = "trigger"
+	SchemaObjectEvent     SchemaObjectType = "event"
+)
+
+// isValidSchemaObjectType reports whether t is a supported schema object type
+func isValidSchemaObjectType(t SchemaObjectType) bool {
+	switch t {
+	case SchemaObjectView, SchemaObjectFunction, SchemaObjectProcedure, SchemaObjectTrigger, SchemaObjectEvent:
+		return true
+	}
+	return false
+}
+
+// SchemaObjectStatus is the outcome of replicating one schema object
+type SchemaObjectStatus string
+
+const (
+	SchemaObjectCreated   SchemaObjectStatus = "created"   // The object didn't exist on the target
+	SchemaObjectUpdated   SchemaObjectStatus = "updated"   // The object differed on the target and was replaced
+	SchemaObjectUnchanged SchemaObjectStatus = "unchanged" // The object on the target already matches
+	SchemaObjectSkipped   SchemaObjectStatus = "skipped"   // The object belongs to a table the config doesn't sync
+	SchemaObjectFailed    SchemaObjectStatus = "failed"
+)
+
+// TableAction is what a sync does to the target table of a mapping before loading it
+type TableAction string
+
+const (
+	TableActionCreate   TableAction = "create"   // The target table doesn't exist yet
+	TableActionRecreate TableAction = "recreate" // The target table is dropped and created again, e.g. a shadow table
+	TableActionAlter    TableAction = "alter"    // The existing target table is changed to match the source schema
+	TableActionNone     TableAction = "none"     // The existing target table already matches
+)
+
+// TableLoad is how a sync moves the rows of a mapping
+type TableLoad string
+
+const (
+	TableLoadFull        TableLoad = "full"        // Copies every row of the source table
+	TableLoadResume      TableLoad = "resume"      // Continues an interrupted full copy after its last committed chunk
+	TableLoadIncremental TableLoad = "incremental" // Copies the rows changed since the watermark
+	TableLoadCDC         TableLoad = "cdc"         // Replays the binlog from the recorded position
 )
 
 // ConflictResolution defines how to handle data conflicts
@@ -32,6 +193,15 @@ const (
 	ConflictResolutionError     ConflictResolution = "error"
 )
 
+// MisfirePolicy defines how the scheduler handles runs that were missed,
+// e.g. because the service was down when they were due
+type MisfirePolicy string
+
+const (
+	MisfirePolicySkip    MisfirePolicy = "skip"     // Drop missed runs and wait for the next occurrence
+	MisfirePolicyRunOnce MisfirePolicy = "run_once" // Run once to catch up, however many runs were missed
+)
+
 // JobStatus defines the status of a sync job
 type JobStatus string
 
@@ -43,18 +213,124 @@ const (
 	JobStatusCancelled JobStatus = "cancelled"
 )
 
+// JobType defines what a job does with the tables of its sync config
+type JobType string
+
+const (
+	JobTypeSync   JobType = "sync"   // Copy the tables from source to target (default)
+	JobTypeVerify JobType = "verify" // Compare source and target tables and optionally repair the target
+)
+
+// VerifyRepair defines what a verify job does with the differences it finds
+type VerifyRepair string
+
+const (
+	VerifyRepairNone  VerifyRepair = ""      // Differences are only counted in the report
+	VerifyRepairEmit  VerifyRepair = "emit"  // The statements repairing the target are saved in the report
+	VerifyRepairApply VerifyRepair = "apply" // The target is repaired
+)
+
+// isValidVerifyRepair reports whether repair is a supported verify repair mode
+func isValidVerifyRepair(repair VerifyRepair) bool {
+	switch repair {
+	case VerifyRepairNone, VerifyRepairEmit, VerifyRepairApply:
+		return true
+	}
+	return false
+}
+
 // ConnectionConfig represents a remote database connection configuration
 type ConnectionConfig struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Host      string    `json:"host" db:"host"`
-	Port      int       `json:"port" db:"port"`
-	Username  string    `json:"username" db:"username"`
-	Password  string    `json:"password" db:"password"`
-	Database  string    `json:"database" db:"database_name"`
-	SSL       bool      `json:"ssl" db:"ssl"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        string         `json:"id" db:"id"`
+	Name      string         `json:"name" db:"name"`
+	Type      ConnectionType `json:"type,omitempty" db:"connection_type"` // mysql (default), file or sqlite
+	Host      string         `json:"host" db:"host"`
+	Port      int            `json:"port" db:"port"`
+	Username  string         `json:"username" db:"username"`
+	Password  string         `json:"password" db:"password"`
+	Database  string         `json:"database" db:"database_name"`
+	SSL       bool           `json:"ssl" db:"ssl"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+
+	// Directory and file settings of a file connection, or the database file of a sqlite connection
+	Path        string       `json:"path,omitempty" db:"path"`
+	FileOptions *FileOptions `json:"file_options,omitempty" db:"file_options"`
+}
+
+// isFile reports whether the connection is a local directory rather than a MySQL server
+func (c *ConnectionConfig) isFile() bool {
+	return c.Type == ConnectionTypeFile
+}
+
+// isSQLite reports whether the connection is a SQLite database file rather than a MySQL server
+func (c *ConnectionConfig) isSQLite() bool {
+	return c.Type == ConnectionTypeSQLite
+}
+
+// ConnectionType is the kind of endpoint a connection reaches
+type ConnectionType string
+
+const (
+	ConnectionTypeMySQL  ConnectionType = "mysql"
+	ConnectionTypeFile   ConnectionType = "file"   // A local directory tables are exported to, or loaded from, as files
+	ConnectionTypeSQLite ConnectionType = "sqlite" // A local SQLite database file tables are snapshotted into
+)
+
+// FileFormat is the format of the files of a file connection
+type FileFormat string
+
+const (
+	FileFormatCSV     FileFormat = "csv"
+	FileFormatJSONL   FileFormat = "jsonl"
+	FileFormatParquet FileFormat = "parquet"
+)
+
+// FileCompression compresses export files. Parquet files compress their pages instead of the whole file.
+type FileCompression string
+
+const (
+	FileCompressionNone FileCompression = "none"
+	FileCompressionGzip FileCompression = "gzip"
+	FileCompressionZstd FileCompression = "zstd"
+)
+
+// FileOptions configures the files of a file connection
+type FileOptions struct {
+	Format      FileFormat      `json:"format,omitempty"`        // csv (default), jsonl or parquet; a file source reads csv or jsonl
+	Compression FileCompression `json:"compression,omitempty"`   // none (default), gzip or zstd; a file source decompresses files named *.gz or *.zst
+	MaxFileSize int64           `json:"max_file_size,omitempty"` // Bytes after which the next rows go to a new file, 0 never rolls
+
+	// Reading the files of a file source
+	Delimiter string `json:"delimiter,omitempty"` // CSV field delimiter, defaults to a comma
+	Quote     string `json:"quote,omitempty"`     // CSV quote character, defaults to a double quote, "none" reads quotes as text
+	Encoding  string `json:"encoding,omitempty"`  // Character encoding, e.g. gbk or windows-1252, defaults to UTF-8
+}
+
+// FileSource describes how a table mapping of a file source connection loads its files. The
+// mapping's source table is a glob pattern of the files, relative to the connection's path.
+type FileSource struct {
+	Columns    []*FileColumn `json:"columns,omitempty"`      // Schema of the files, inferred from their first rows when empty
+	PrimaryKey []string      `json:"primary_key,omitempty"`  // Primary key of a target table created for the files
+	MaxBadRows int           `json:"max_bad_rows,omitempty"` // A file with more quarantined rows fails, 0 accepts any number
+}
+
+// FileColumn is a column of the files of a file source
+type FileColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // MySQL column type, e.g. bigint or decimal(10,2)
+}
+
+// ImportedFile records a file a table mapping has loaded, so incremental syncs skip it until its content changes
+type ImportedFile struct {
+	TableMappingID string    `json:"table_mapping_id" db:"table_mapping_id"`
+	FileName       string    `json:"file_name" db:"file_name"` // Relative to the connection's path
+	FileSize       int64     `json:"file_size" db:"file_size"`
+	SHA256         string    `json:"sha256" db:"sha256"`
+	LoadedRows     int64     `json:"loaded_rows" db:"loaded_rows"`
+	RejectedRows   int64     `json:"rejected_rows" db:"rejected_rows"` // Rows written to the quarantine file instead
+	JobID          string    `json:"job_id" db:"job_id"`
+	ImportedAt     time.Time `json:"imported_at" db:"imported_at"`
 }
 
 // Connection represents a database connection with status
@@ -84,22 +360,114 @@ type SyncConfig struct {
 	Schedule           string          `json:"schedule" db:"schedule"`
 	Enabled            bool            `json:"enabled" db:"enabled"`
 	Options            *SyncOptions    `json:"options"`
+	LastRunAt          *time.Time      `json:"last_run_at,omitempty" db:"last_run_at"` // Last time the scheduler started a job for this config
+	NextRunAt          *time.Time      `json:"next_run_at,omitempty" db:"next_run_at"` // Next planned scheduler run, nil when not scheduled
 	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
 }
 
 // TableMapping represents the mapping between source and target tables
 type TableMapping struct {
-	ID           string    `json:"id" db:"id"`
-	SyncConfigID string    `json:"sync_config_id" db:"sync_config_id"`
-	SourceTable  string    `json:"source_table" db:"source_table"`
-	TargetTable  string    `json:"target_table" db:"target_table"`
-	SyncMode     SyncMode  `json:"sync_mode" db:"sync_mode"`
-	Enabled      bool      `json:"enabled" db:"enabled"`
-	WhereClause  string    `json:"where_clause,omitempty" db:"where_clause"`
-	SortOrder    int       `json:"sort_order" db:"sort_order"` // User-defined order for sync execution (lower first)
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID           string   `json:"id" db:"id"`
+	SyncConfigID string   `json:"sync_config_id" db:"sync_config_id"`
+	SourceTable  string   `json:"source_table" db:"source_table"`
+	TargetTable  string   `json:"target_table" db:"target_table"`
+	SyncMode     SyncMode `json:"sync_mode" db:"sync_mode"`
+	Enabled      bool     `json:"enabled" db:"enabled"`
+	WhereClause  string   `json:"where_clause,omitempty" db:"where_clause"` // Raw SQL condition, only accepted when sync.allow_raw_where_clause is set
+	SortOrder    int      `json:"sort_order" db:"sort_order"`               // Order for sync execution (lower first), numbered by position when a config is saved
+
+	ConcurrentWithPrevious bool `json:"concurrent_with_previous,omitempty" db:"-"` // Syncs in one group with the mapping before it, derived from equal sort orders on read
+
+	RowFilter *RowFilter `json:"row_filter,omitempty" db:"row_filter"` // Structured condition on source rows, stored as JSON
+
+	FileSource *FileSource `json:"file_source,omitempty" db:"file_source"` // Schema and options of a mapping loading files, stored as JSON
+
+	DeleteDetection  DeleteDetection `json:"delete_detection,omitempty" db:"delete_detection"`     // How rows deleted at the source are propagated by incremental sync
+	SoftDeleteColumn string          `json:"soft_delete_column,omitempty" db:"soft_delete_column"` // Target column set when DeleteDetection is soft, defaults to deleted_at
+
+	SchemaPolicy         SchemaPolicy `json:"schema_policy,omitempty" db:"schema_policy"`                   // How source schema changes reach the target table, defaults to additive
+	ApproveSchemaChanges bool         `json:"approve_schema_changes,omitempty" db:"approve_schema_changes"` // Apply pending destructive changes once under the approve policy
+
+	TrackingColumn  string `json:"tracking_column,omitempty" db:"tracking_column"`   // Source column incremental sync tracks changes by, detected when empty
+	LookbackSeconds int    `json:"lookback_seconds,omitempty" db:"lookback_seconds"` // Seconds before a timestamp watermark incremental sync reads again
+
+	WriteMode     WriteMode  `json:"write_mode,omitempty" db:"write_mode"`         // How rows are written into the target table, defaults to following the conflict resolution
+	UpdateColumns ColumnList `json:"update_columns,omitempty" db:"update_columns"` // Target columns the upsert write modes update, every written column when empty; stored as JSON
+
+	// Column rules, stored in column_mapping_rules. On update nil keeps the stored rules, an empty list removes them
+	ColumnRules []*ColumnRule `json:"column_rules,omitempty" db:"-"`
+
+	// Masking rules, stored in column_masking_rules. On update nil keeps the stored rules, an empty list removes them
+	MaskingRules []*MaskingRule `json:"masking_rules,omitempty" db:"-"`
+
+	// Row filter and where clause rendered for a sync run, see resolveRowFilter
+	filter *sqlCondition
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ColumnRuleType defines what a column rule does to the columns of a table mapping
+type ColumnRuleType string
+
+const (
+	ColumnRuleInclude    ColumnRuleType = "include"    // Once any include rule exists, only included source columns are copied
+	ColumnRuleExclude    ColumnRuleType = "exclude"    // The source column is not copied
+	ColumnRuleRename     ColumnRuleType = "rename"     // The source column is copied into TargetColumn
+	ColumnRuleConstant   ColumnRuleType = "constant"   // TargetColumn holds the fixed Value
+	ColumnRuleExpression ColumnRuleType = "expression" // TargetColumn holds the SQL expression Value, evaluated by the source database
+)
+
+// defaultDerivedColumnType is the target type of constant and expression columns without a ColumnType
+const defaultDerivedColumnType = "varchar(255)"
+
+// ColumnRule represents a column-level rule of a table mapping
+type ColumnRule struct {
+	ID             string         `json:"id" db:"id"`
+	TableMappingID string         `json:"table_mapping_id" db:"table_mapping_id"`
+	RuleType       ColumnRuleType `json:"rule_type" db:"rule_type"`
+	SourceColumn   string         `json:"source_column,omitempty" db:"source_column"` // Column of the source table, for include, exclude and rename
+	TargetColumn   string         `json:"target_column,omitempty" db:"target_column"` // Column of the target table, for rename, constant and expression
+	Value          string         `json:"value,omitempty" db:"value"`                 // Constant value or SQL expression
+	ColumnType     string         `json:"column_type,omitempty" db:"column_type"`     // Target column type of a constant or expression column, defaults to varchar(255)
+	SortOrder      int            `json:"sort_order" db:"sort_order"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// MaskingStrategy defines how a masking rule replaces the values of a column
+type MaskingStrategy string
+
+const (
+	MaskingRedact    MaskingStrategy = "redact"     // Replaced by Replacement, *** by default
+	MaskingNullify   MaskingStrategy = "nullify"    // Replaced by NULL
+	MaskingFake      MaskingStrategy = "fake"       // Letters and digits replaced by deterministic random ones, keeping the format
+	MaskingHash      MaskingStrategy = "hash"       // Salted HMAC-SHA256 in hex, equal values stay equal across tables
+	MaskingPartial   MaskingStrategy = "partial"    // All but the first KeepFirst and last KeepLast characters replaced by *
+	MaskingDateShift MaskingStrategy = "date_shift" // Dates moved by a deterministic number of days within ShiftDays
+)
+
+// isValidMaskingStrategy checks if the masking strategy is valid
+func isValidMaskingStrategy(strategy MaskingStrategy) bool {
+	switch strategy {
+	case MaskingRedact, MaskingNullify, MaskingFake, MaskingHash, MaskingPartial, MaskingDateShift:
+		return true
+	}
+	return false
+}
+
+// MaskingRule represents the masking of one target column of a table mapping
+type MaskingRule struct {
+	ID             string          `json:"id" db:"id"`
+	TableMappingID string          `json:"table_mapping_id" db:"table_mapping_id"`
+	Column         string          `json:"column" db:"column_name"` // Target column, after column rules
+	Strategy       MaskingStrategy `json:"strategy" db:"strategy"`
+	Replacement    string          `json:"replacement,omitempty" db:"replacement"` // Value written by redact
+	KeepFirst      int             `json:"keep_first,omitempty" db:"keep_first"`   // Leading characters partial keeps, 3 when both keep values are 0
+	KeepLast       int             `json:"keep_last,omitempty" db:"keep_last"`     // Trailing characters partial keeps, 4 when both keep values are 0
+	ShiftDays      int             `json:"shift_days,omitempty" db:"shift_days"`   // Largest shift of date_shift in days, defaults to 30
+	SortOrder      int             `json:"sort_order" db:"sort_order"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
 }
 
 // SyncOptions represents synchronization options
@@ -108,22 +476,119 @@ type SyncOptions struct {
 	MaxConcurrency     int                `json:"max_concurrency"`
 	EnableCompression  bool               `json:"enable_compression"`
 	ConflictResolution ConflictResolution `json:"conflict_resolution"`
+	Timezone           string             `json:"timezone,omitempty"`       // IANA zone used to evaluate Schedule, defaults to server local time
+	MisfirePolicy      MisfirePolicy      `json:"misfire_policy,omitempty"` // What to do with runs missed while the scheduler was down
+	CDCServerID        uint32             `json:"cdc_server_id,omitempty"`  // Replica server_id used by CDC, derived from the mapping ID when 0
+	ShadowSwap         bool               `json:"shadow_swap,omitempty"`    // Load full syncs into a shadow table and swap it in atomically
+	KeepOldTable       bool               `json:"keep_old_table,omitempty"` // Keep the replaced table as <target>__dbtaxi_old after a swap
+	BulkLoad           bool               `json:"bulk_load,omitempty"`      // Load full copies with LOAD DATA LOCAL INFILE, inserting when the target disables local_infile
+
+	// Batching
+	AdaptiveBatchSize bool `json:"adaptive_batch_size,omitempty"` // Size batches by row bytes and write latency, starting at BatchSize
+
+	// Schema objects
+	SchemaObjects []SchemaObjectType `json:"schema_objects,omitempty"` // Views, routines, triggers and events replicated after the table data
+	EnableEvents  bool               `json:"enable_events,omitempty"`  // Keep replicated events enabled, by default they are created DISABLE ON SLAVE
+
+	// Foreign keys
+	DisableForeignKeyChecks bool `json:"disable_foreign_key_checks,omitempty"` // Write with FOREIGN_KEY_CHECKS=0, for tables that can't load in dependency order
+
+	// Masking
+	RequireMasking []string `json:"require_masking,omitempty"` // Column name patterns (regular expressions) that must have a masking rule
+}
+
+// SchemaObjectResult is the outcome of replicating one schema object of a sync job
+type SchemaObjectResult struct {
+	Type   SchemaObjectType   `json:"type"`
+	Name   string             `json:"name"`
+	Status SchemaObjectStatus `json:"status"`
+	Error  string             `json:"error,omitempty"`
+}
+
+// TableDependencyOrder is the order of the source tables of a sync config by their foreign keys
+type TableDependencyOrder struct {
+	Groups       [][]string          `json:"groups"`                 // Tables by load order, each group only references earlier groups
+	Dependencies map[string][]string `json:"dependencies,omitempty"` // Mapped tables each table references
+	Cycles       [][]string          `json:"cycles,omitempty"`       // Tables referencing each other in a cycle
+}
+
+// TablePlan is what a sync would do to one table mapping, worked out without writing anything
+type TablePlan struct {
+	MappingID     string      `json:"mapping_id"`
+	SourceTable   string      `json:"source_table"`
+	TargetTable   string      `json:"target_table"`
+	SyncMode      SyncMode    `json:"sync_mode"`
+	Load          TableLoad   `json:"load,omitempty"`
+	Action        TableAction `json:"action,omitempty"`
+	Truncate      bool        `json:"truncate,omitempty"`      // The target table is emptied before the copy
+	DDL           []string    `json:"ddl,omitempty"`           // Statements run on the target before loading
+	SkippedDDL    []string    `json:"skipped_ddl,omitempty"`   // Changes the schema policy leaves out
+	ChangeColumn  string      `json:"change_column,omitempty"` // Column incremental syncs track changes by
+	ChangeType    string      `json:"change_type,omitempty"`   // timestamp or auto_increment
+	Watermark     string      `json:"watermark,omitempty"`     // Last synced change value or binlog position
+	EstimatedRows int64       `json:"estimated_rows"`          // Optimizer estimate of the rows to transfer
+	Problems      []string    `json:"problems,omitempty"`      // What makes the sync of the table fail
+	Warnings      []string    `json:"warnings,omitempty"`      // What the sync does differently than asked
+}
+
+// SyncPlan is what a sync job of a config would do, table by table in sync order
+type SyncPlan struct {
+	ConfigID string       `json:"config_id"`
+	Tables   []*TablePlan `json:"tables"`
+	Problems []string     `json:"problems,omitempty"` // What makes the whole job fail
+	Blocked  bool         `json:"blocked"`            // The config or a table has problems, the job would fail
 }
 
 // SyncJob represents a synchronization job
 type SyncJob struct {
-	ID              string     `json:"id" db:"id"`
-	ConfigID        string     `json:"config_id" db:"config_id"`
-	Status          JobStatus  `json:"status" db:"status"`
-	Progress        *Progress  `json:"progress"`
-	StartTime       time.Time  `json:"start_time" db:"start_time"`
-	EndTime         *time.Time `json:"end_time,omitempty" db:"end_time"`
-	TotalTables     int        `json:"total_tables" db:"total_tables"`
-	CompletedTables int        `json:"completed_tables" db:"completed_tables"`
-	TotalRows       int64      `json:"total_rows" db:"total_rows"`
-	ProcessedRows   int64      `json:"processed_rows" db:"processed_rows"`
-	Error           string     `json:"error,omitempty" db:"error_message"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ID              string       `json:"id" db:"id"`
+	ConfigID        string       `json:"config_id" db:"config_id"`
+	Status          JobStatus    `json:"status" db:"status"`
+	Progress        *Progress    `json:"progress"`
+	StartTime       time.Time    `json:"start_time" db:"start_time"`
+	EndTime         *time.Time   `json:"end_time,omitempty" db:"end_time"`
+	TotalTables     int          `json:"total_tables" db:"total_tables"`
+	CompletedTables int          `json:"completed_tables" db:"completed_tables"`
+	TotalRows       int64        `json:"total_rows" db:"total_rows"`
+	ProcessedRows   int64        `json:"processed_rows" db:"processed_rows"`
+	Error           string       `json:"error,omitempty" db:"error_message"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+	Type            JobType      `json:"type" db:"job_type"`
+	Repair          VerifyRepair `json:"repair,omitempty" db:"verify_repair"` // Repair mode of a verify job
+}
+
+// VerifyStatus is the outcome of verifying one table
+type VerifyStatus string
+
+const (
+	VerifyStatusMatch    VerifyStatus = "match"    // Source and target hold the same rows
+	VerifyStatusMismatch VerifyStatus = "mismatch" // Differences were found and left in place
+	VerifyStatusRepaired VerifyStatus = "repaired" // Differences were found and repaired
+	VerifyStatusFailed   VerifyStatus = "failed"   // The table could not be verified
+)
+
+// TableVerifyReport is the persisted result of verifying one table mapping in a verify job
+type TableVerifyReport struct {
+	ID               string       `json:"id" db:"id"`
+	JobID            string       `json:"job_id" db:"job_id"`
+	TableMappingID   string       `json:"table_mapping_id" db:"table_mapping_id"`
+	SourceTable      string       `json:"source_table" db:"source_table"`
+	TargetTable      string       `json:"target_table" db:"target_table"`
+	Status           VerifyStatus `json:"status" db:"status"`
+	Chunks           int64        `json:"chunks" db:"chunks"`
+	MismatchedChunks int64        `json:"mismatched_chunks" db:"mismatched_chunks"`
+	SourceRows       int64        `json:"source_rows" db:"source_rows"`
+	TargetRows       int64        `json:"target_rows" db:"target_rows"`
+	MissingRows      int64        `json:"missing_rows" db:"missing_rows"`     // Source rows absent from the target
+	ExtraRows        int64        `json:"extra_rows" db:"extra_rows"`         // Target rows absent from the source
+	DifferentRows    int64        `json:"different_rows" db:"different_rows"` // Rows whose values differ
+	RepairedRows     int64        `json:"repaired_rows" db:"repaired_rows"`
+	SkippedColumns   string       `json:"skipped_columns,omitempty" db:"skipped_columns"` // Comma separated target columns left out of the comparison
+	RepairSQL        string       `json:"repair_sql,omitempty" db:"repair_sql"`           // Repair statements of an emit job, one per line
+	RepairTruncated  bool         `json:"repair_truncated,omitempty" db:"repair_truncated"`
+	Error            string       `json:"error,omitempty" db:"error_message"`
+	StartedAt        time.Time    `json:"started_at" db:"started_at"`
+	FinishedAt       time.Time    `json:"finished_at" db:"finished_at"`
 }
 
 // Progress represents synchronization progress
@@ -226,7 +691,8 @@ type JobSummary struct {
 	Duration        *time.Duration            `json:"duration,omitempty"`
 	TotalTables     int                       `json:"total_tables"`
 	CompletedTables int                       `json:"completed_tables"`
-	CurrentTable    string                    `json:"current_table,omitempty"` // 当前正在同步的表名
+	CurrentTable    string                    `json:"current_table,omitempty"`  // 最早开始的正在同步的表名，兼容只读取单个表名的客户端
+	CurrentTables   []string                  `json:"current_tables,omitempty"` // 当前正在同步的表名（并发同步时有多个）
 	TotalRows       int64                     `json:"total_rows"`
 	ProcessedRows   int64                     `json:"processed_rows"`
 	ProgressPercent float64                   `json:"progress_percent"`
@@ -245,4 +711,11 @@ type TableProgress struct {
 	ProcessedRows int64           `json:"processed_rows"`
 	ErrorCount    int             `json:"error_count"`
 	LastError     string          `json:"last_error,omitempty"`
+
+	DeleteDetection DeleteDetection `json:"delete_detection,omitempty"` // Set once the delete detection pass has run
+	DeletedRows     int64           `json:"deleted_rows,omitempty"`     // Orphaned target rows handled by that pass; report mode only counts them
+
+	BatchSize    int `json:"batch_size,omitempty"`     // Rows per batch last chosen by adaptive batch sizing
+	MinBatchSize int `json:"min_batch_size,omitempty"` // Smallest batch size it chose
+	MaxBatchSize int `json:"max_batch_size,omitempty"` // Largest batch size it chose
 }
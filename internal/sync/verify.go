@@ -0,0 +1,609 @@
+package sync
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// verifyLeafRows is the row count at which a mismatching range is compared row by row
+	verifyLeafRows = 64
+
+	// maxRepairStatements caps the repair statements an emit job saves for one table
+	maxRepairStatements = 10000
+)
+
+// tableVerifier compares a source table with its target table in the manner of pt-table-checksum.
+//
+// The source key space is walked in chunks of chunkSize keys. Both sides return a row count and an
+// XOR of 64-bit row hashes for each chunk, so a matching chunk costs two aggregate queries. A chunk
+// whose checksums differ is bisected at its middle key until at most leafRows rows remain on either
+// side, and the row hashes of those rows are then compared key by key. BLOB and TEXT columns take
+// part through their MD5, so no row value is ever concatenated whole.
+type tableVerifier struct {
+	sourceDB      *sqlx.DB
+	sourceTable   string       // Qualified and quoted
+	sourceFilter  sqlCondition // Mapping row filter
+	sourceKeys    []string
+	sourceColumns []string // SQL producing the compared columns at the source
+	targetDB      *sqlx.DB
+	targetTable   string       // Qualified and quoted
+	targetFilter  sqlCondition // Excludes soft-deleted rows
+	targetKeys    []string
+	targetColumns []string // SQL producing the compared columns at the target, in sourceColumns order
+	chunkSize     int
+	leafRows      int
+
+	// progress is called with the number of source rows compared so far, may be nil
+	progress func(sourceRows int64)
+}
+
+// rangeChecksum is the row count and the XOR of the row hashes of a key range
+type rangeChecksum struct {
+	count int64
+	hash  uint64
+}
+
+// rowDiff holds the differing rows of a compared range by primary key
+type rowDiff struct {
+	missing   [][]interface{} // Source keys absent from the target
+	extra     [][]interface{} // Target keys absent from the source
+	different [][]interface{} // Source keys whose rows differ
+}
+
+// verifyStats counts what a verifier compared
+type verifyStats struct {
+	chunks           int64
+	mismatchedChunks int64
+	sourceRows       int64
+	targetRows       int64
+}
+
+// VerifyTable compares a table with its target table in checksummed primary key chunks. Depending on
+// the job's repair mode, the statements bringing the target back in line are saved in the report or
+// applied. The report is returned even when verification fails.
+func (e *DefaultSyncEngine) VerifyTable(ctx context.Context, job *SyncJob, mapping *TableMapping) (*TableVerifyReport, error) {
+	e.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"source_table": mapping.SourceTable,
+		"target_table": mapping.TargetTable,
+		"repair":       job.Repair,
+	}).Info("Starting table verification")
+
+	report := &TableVerifyReport{
+		JobID:          job.ID,
+		TableMappingID: mapping.ID,
+		SourceTable:    mapping.SourceTable,
+		TargetTable:    mapping.TargetTable,
+		StartedAt:      time.Now(),
+	}
+	err := e.verifyTable(ctx, job, mapping, report)
+	report.FinishedAt = time.Now()
+
+	switch {
+	case err != nil:
+		report.Status = VerifyStatusFailed
+		report.Error = err.Error()
+	case report.MissingRows+report.ExtraRows+report.DifferentRows == 0:
+		report.Status = VerifyStatusMatch
+	case job.Repair == VerifyRepairApply:
+		report.Status = VerifyStatusRepaired
+	default:
+		report.Status = VerifyStatusMismatch
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"job_id":         job.ID,
+		"source_table":   mapping.SourceTable,
+		"status":         report.Status,
+		"missing_rows":   report.MissingRows,
+		"extra_rows":     report.ExtraRows,
+		"different_rows": report.DifferentRows,
+		"repaired_rows":  report.RepairedRows,
+	}).Info("Table verification completed")
+
+	return report, err
+}
+
+// verifyTable runs the verification of a mapping and fills in the report
+func (e *DefaultSyncEngine) verifyTable(ctx context.Context, job *SyncJob, mapping *TableMapping, report *TableVerifyReport) error {
+	endpoints, err := e.openSyncEndpoints(ctx, mapping)
+	if err != nil {
+		return err
+	}
+	defer endpoints.Close()
+	syncConfig := endpoints.config
+	sourceDB, sourceDBName := endpoints.sourceDB, endpoints.sourceDBName
+	targetDB, targetDBName := endpoints.targetDB, endpoints.targetDBName
+
+	schema, err := e.getTableSchemaFromRemote(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		return fmt.Errorf("failed to get table schema: %w", err)
+	}
+	mapping, err = e.resolveRowFilter(ctx, job, mapping, schema)
+	if err != nil {
+		return err
+	}
+	columns := mapping.columnMapping()
+	targetSchema, err := columns.targetSchema(schema)
+	if err != nil {
+		return err
+	}
+	masker, err := newRowMasker(mapping, syncConfig.Options, e.maskingSecret, schema, targetSchema)
+	if err != nil {
+		return err
+	}
+
+	primaryKeys, err := e.getPrimaryKeyColumns(ctx, sourceDB, mapping.SourceTable)
+	if err != nil {
+		return fmt.Errorf("verification requires a primary key: %w", err)
+	}
+	targetKeys, ok := columns.targetColumns(primaryKeys)
+	if !ok {
+		return fmt.Errorf("verification requires every primary key column, but column rules exclude one of %v", primaryKeys)
+	}
+	if masker.masksAny(targetKeys) {
+		return fmt.Errorf("verification cannot compare primary key %v, which a masking rule rewrites", targetKeys)
+	}
+
+	sourceColumns, targetColumns, skipped := verifyColumns(mapping, schema, masker)
+	report.SkippedColumns = strings.Join(skipped, ",")
+
+	chunkSize := 1000
+	if syncConfig.Options != nil && syncConfig.Options.BatchSize > 0 {
+		chunkSize = syncConfig.Options.BatchSize
+	}
+
+	var totalRows int64
+	estimateQuery := "SELECT COALESCE(TABLE_ROWS, 0) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	if err := sourceDB.GetContext(ctx, &totalRows, estimateQuery, sourceDBName, mapping.SourceTable); err != nil {
+		e.logger.WithError(err).WithField("source_table", mapping.SourceTable).Warn("Failed to estimate table rows")
+	}
+	ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, 0, totalRows)
+
+	verifier := &tableVerifier{
+		sourceDB:      sourceDB,
+		sourceTable:   fmt.Sprintf("`%s`.`%s`", sourceDBName, mapping.SourceTable),
+		sourceFilter:  mapping.rowFilter(),
+		sourceKeys:    primaryKeys,
+		sourceColumns: sourceColumns,
+		targetDB:      targetDB,
+		targetTable:   fmt.Sprintf("`%s`.`%s`", targetDBName, mapping.TargetTable),
+		targetKeys:    targetKeys,
+		targetColumns: targetColumns,
+		chunkSize:     chunkSize,
+		leafRows:      verifyLeafRows,
+		progress: func(sourceRows int64) {
+			ReportTableProgress(ctx, mapping.SourceTable, TableStatusRunning, sourceRows, totalRows)
+		},
+	}
+
+	// Soft-deleted target rows no longer stand for source rows
+	softDeleteColumn := mapping.softDeleteColumn()
+	if softDeleteColumn != "" {
+		exists, err := e.columnExists(ctx, targetDB, targetDBName, mapping.TargetTable, softDeleteColumn)
+		if err != nil {
+			return err
+		}
+		if !exists && job.Repair == VerifyRepairApply {
+			if err := e.ensureSoftDeleteColumn(ctx, targetDB, targetDBName, mapping.TargetTable, softDeleteColumn); err != nil {
+				return err
+			}
+			exists = true
+		}
+		if exists {
+			verifier.targetFilter = sqlCondition{clause: fmt.Sprintf("`%s` IS NULL", softDeleteColumn)}
+		} else {
+			softDeleteColumn = ""
+		}
+	}
+
+	repairer := &verifyRepairer{
+		engine:           e,
+		verifier:         verifier,
+		mode:             job.Repair,
+		selectList:       columns.selectList(schema),
+		masker:           masker,
+		targetDialect:    endpoints.dialect,
+		targetDBName:     targetDBName,
+		targetTableName:  mapping.TargetTable,
+		targetTable:      verifier.targetTable,
+		softDeleteColumn: softDeleteColumn,
+	}
+
+	var stats verifyStats
+	err = verifier.run(ctx, &stats, func(diff *rowDiff) error {
+		report.MissingRows += int64(len(diff.missing))
+		report.ExtraRows += int64(len(diff.extra))
+		report.DifferentRows += int64(len(diff.different))
+		return repairer.repair(ctx, diff)
+	})
+	report.Chunks = stats.chunks
+	report.MismatchedChunks = stats.mismatchedChunks
+	report.SourceRows = stats.sourceRows
+	report.TargetRows = stats.targetRows
+	report.RepairedRows = repairer.repaired
+	report.RepairSQL = strings.Join(repairer.statements, "\n")
+	report.RepairTruncated = repairer.truncated
+	return err
+}
+
+// verifyColumns returns the SQL producing each compared column at the source and at the target.
+// Masked columns can't match their source values and are returned as skipped.
+func verifyColumns(mapping *TableMapping, schema *TableSchema, masker *rowMasker) (source, target, skipped []string) {
+	add := func(sourceExpr, targetColumn, columnType string) {
+		if masker.masksAny([]string{targetColumn}) {
+			skipped = append(skipped, targetColumn)
+			return
+		}
+		targetExpr := fmt.Sprintf("`%s`", targetColumn)
+		if isLargeObjectType(columnType) {
+			sourceExpr = "MD5(" + sourceExpr + ")"
+			targetExpr = "MD5(" + targetExpr + ")"
+		}
+		source = append(source, sourceExpr)
+		target = append(target, targetExpr)
+	}
+
+	columns := mapping.columnMapping()
+	for _, col := range schema.Columns {
+		if name, ok := columns.targetName(col.Name); ok {
+			add(fmt.Sprintf("`%s`", col.Name), name, col.Type)
+		}
+	}
+	if columns != nil {
+		for _, rule := range columns.derived {
+			add(derivedColumnValue(rule), rule.TargetColumn, rule.ColumnType)
+		}
+	}
+	return source, target, skipped
+}
+
+// isLargeObjectType reports whether a column type is a BLOB or TEXT type
+func isLargeObjectType(columnType string) bool {
+	t := strings.ToLower(columnType)
+	return strings.Contains(t, "blob") || strings.Contains(t, "text")
+}
+
+// rowHashExpression returns the SQL hashing a row of the given column expressions. A trailing
+// ISNULL flag per column tells NULL apart from the empty string, which CONCAT_WS skips alike.
+func rowHashExpression(columns []string) string {
+	nulls := make([]string, len(columns))
+	for i, col := range columns {
+		nulls[i] = "ISNULL(" + col + ")"
+	}
+	return fmt.Sprintf("MD5(CONCAT_WS('#', %s, CONCAT(%s)))", strings.Join(columns, ", "), strings.Join(nulls, ", "))
+}
+
+// run walks the source key space and calls handle with the differing rows of each mismatching chunk
+func (v *tableVerifier) run(ctx context.Context, stats *verifyStats, handle func(diff *rowDiff) error) error {
+	var lower []interface{}
+	for {
+		upper, err := v.chunkUpperBound(ctx, lower)
+		if err != nil {
+			return err
+		}
+		r := keyRange{lower: lower, upper: upper}
+
+		source, target, err := v.checksums(ctx, r)
+		if err != nil {
+			return err
+		}
+		stats.chunks++
+		stats.sourceRows += source.count
+		stats.targetRows += target.count
+		if source != target {
+			stats.mismatchedChunks++
+			if err := v.bisect(ctx, r, source, target, handle); err != nil {
+				return err
+			}
+		}
+		if v.progress != nil {
+			v.progress(stats.sourceRows)
+		}
+
+		if upper == nil {
+			return nil
+		}
+		lower = upper
+	}
+}
+
+// chunkUpperBound returns the chunkSize-th source key after lower, or nil for the last chunk
+func (v *tableVerifier) chunkUpperBound(ctx context.Context, lower []interface{}) ([]interface{}, error) {
+	where, args := keyRangeCondition(v.sourceKeys, keyRange{lower: lower}, v.sourceFilter)
+	keyList := quoteColumns(v.sourceKeys)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT 1 OFFSET %d",
+		keyList, v.sourceTable, where, keyList, v.chunkSize-1)
+
+	keys, err := queryKeys(ctx, v.sourceDB, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk boundary: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+// checksums returns the checksums of a key range on both sides
+func (v *tableVerifier) checksums(ctx context.Context, r keyRange) (rangeChecksum, rangeChecksum, error) {
+	source, err := v.checksum(ctx, v.sourceDB, v.sourceTable, v.sourceKeys, v.sourceColumns, r, v.sourceFilter)
+	if err != nil {
+		return source, source, fmt.Errorf("failed to checksum source range: %w", err)
+	}
+	target, err := v.checksum(ctx, v.targetDB, v.targetTable, v.targetKeys, v.targetColumns, r, v.targetFilter)
+	if err != nil {
+		return source, target, fmt.Errorf("failed to checksum target range: %w", err)
+	}
+	return source, target, nil
+}
+
+// checksum returns the row count and the XOR of the 64-bit row hashes of a range
+func (v *tableVerifier) checksum(ctx context.Context, db *sqlx.DB, table string, keys, columns []string, r keyRange, filter sqlCondition) (rangeChecksum, error) {
+	where, args := keyRangeCondition(keys, r, filter)
+	query := fmt.Sprintf(
+		"SELECT COUNT(*), COALESCE(BIT_XOR(CAST(CONV(SUBSTRING(%s, 1, 16), 16, 10) AS UNSIGNED)), 0) FROM %s%s",
+		rowHashExpression(columns), table, where)
+
+	var c rangeChecksum
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&c.count, &c.hash); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// bisect narrows a mismatching range down to ranges small enough to compare row by row
+func (v *tableVerifier) bisect(ctx context.Context, r keyRange, source, target rangeChecksum, handle func(diff *rowDiff) error) error {
+	rows := source.count
+	if target.count > rows {
+		rows = target.count
+	}
+	if rows <= int64(v.leafRows) {
+		return v.compareRows(ctx, r, handle)
+	}
+
+	// Split at the middle key of the side holding more rows, so that side halves every step
+	mid, err := v.middleKey(ctx, r, source.count >= target.count, rows/2)
+	if err != nil {
+		return err
+	}
+	if mid == nil {
+		// The range shrank since it was checksummed
+		return v.compareRows(ctx, r, handle)
+	}
+
+	for _, half := range []keyRange{{lower: r.lower, upper: mid}, {lower: mid, upper: r.upper}} {
+		source, target, err := v.checksums(ctx, half)
+		if err != nil {
+			return err
+		}
+		if source != target {
+			if err := v.bisect(ctx, half, source, target, handle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// middleKey returns the offset-th key of a range on one side, nil if the range holds fewer keys
+func (v *tableVerifier) middleKey(ctx context.Context, r keyRange, fromSource bool, offset int64) ([]interface{}, error) {
+	db, table, keys, filter := v.targetDB, v.targetTable, v.targetKeys, v.targetFilter
+	if fromSource {
+		db, table, keys, filter = v.sourceDB, v.sourceTable, v.sourceKeys, v.sourceFilter
+	}
+	where, args := keyRangeCondition(keys, r, filter)
+	keyList := quoteColumns(keys)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT 1 OFFSET %d", keyList, table, where, keyList, offset-1)
+
+	found, err := queryKeys(ctx, db, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read middle key: %w", err)
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return found[0], nil
+}
+
+// compareRows compares the row hashes of a range key by key and hands the differences to handle
+func (v *tableVerifier) compareRows(ctx context.Context, r keyRange, handle func(diff *rowDiff) error) error {
+	sourceRows, err := v.rowHashes(ctx, v.sourceDB, v.sourceTable, v.sourceKeys, v.sourceColumns, r, v.sourceFilter)
+	if err != nil {
+		return fmt.Errorf("failed to read source row hashes: %w", err)
+	}
+	targetRows, err := v.rowHashes(ctx, v.targetDB, v.targetTable, v.targetKeys, v.targetColumns, r, v.targetFilter)
+	if err != nil {
+		return fmt.Errorf("failed to read target row hashes: %w", err)
+	}
+
+	targetHashes := make(map[string]interface{}, len(targetRows))
+	for _, row := range targetRows {
+		key := row[:len(row)-1]
+		targetHashes[keyString(key)] = row[len(row)-1]
+	}
+
+	diff := &rowDiff{}
+	for _, row := range sourceRows {
+		key := row[:len(row)-1]
+		hash, ok := targetHashes[keyString(key)]
+		switch {
+		case !ok:
+			diff.missing = append(diff.missing, key)
+		case hash != row[len(row)-1]:
+			diff.different = append(diff.different, key)
+		}
+		delete(targetHashes, keyString(key))
+	}
+	for _, row := range targetRows {
+		key := row[:len(row)-1]
+		if _, ok := targetHashes[keyString(key)]; ok {
+			diff.extra = append(diff.extra, key)
+		}
+	}
+
+	if len(diff.missing)+len(diff.extra)+len(diff.different) == 0 {
+		return nil
+	}
+	return handle(diff)
+}
+
+// rowHashes returns the key tuples of a range, each followed by its row hash
+func (v *tableVerifier) rowHashes(ctx context.Context, db *sqlx.DB, table string, keys, columns []string, r keyRange, filter sqlCondition) ([][]interface{}, error) {
+	where, args := keyRangeCondition(keys, r, filter)
+	keyList := quoteColumns(keys)
+	query := fmt.Sprintf("SELECT %s, %s FROM %s%s ORDER BY %s", keyList, rowHashExpression(columns), table, where, keyList)
+	return queryKeys(ctx, db, query, args)
+}
+
+// verifyRepairer saves or applies the statements repairing the differences a verifier finds.
+// Missing and different rows are read again from the source, masked and upserted; extra rows are
+// deleted, or soft-deleted when the mapping detects deletes softly.
+type verifyRepairer struct {
+	engine           *DefaultSyncEngine
+	verifier         *tableVerifier
+	mode             VerifyRepair
+	selectList       string // Source columns read as the target table's columns
+	masker           *rowMasker
+	targetDialect    Dialect
+	targetDBName     string
+	targetTableName  string
+	targetTable      string // Qualified and quoted
+	softDeleteColumn string
+
+	statements []string
+	truncated  bool
+	repaired   int64
+}
+
+// repair handles the differences of one compared range
+func (rp *verifyRepairer) repair(ctx context.Context, diff *rowDiff) error {
+	if rp.mode == VerifyRepairNone {
+		return nil
+	}
+	v := rp.verifier
+
+	if keys := append(append([][]interface{}{}, diff.missing...), diff.different...); len(keys) > 0 {
+		query, args := keyedStatement(fmt.Sprintf("SELECT %s FROM %s", rp.selectList, v.sourceTable), v.sourceKeys, keys)
+		if v.sourceFilter.clause != "" {
+			query += fmt.Sprintf(" AND (%s)", v.sourceFilter.clause)
+			args = append(args, v.sourceFilter.args...)
+		}
+		batch := &rowBatch{}
+		if err := readBatch(ctx, v.sourceDB, query, args, batch); err != nil {
+			return err
+		}
+		if err := rp.masker.maskBatch(batch.columns, batch.rows); err != nil {
+			return err
+		}
+
+		if rp.mode == VerifyRepairApply {
+			clause := (*writePolicy)(nil).clause(rp.targetDialect, batch.columns)
+			if err := rp.engine.writeRowsToDB(ctx, v.targetDB, rp.targetDialect, rp.targetDBName, rp.targetTableName, batch.columns, batch.rows, clause); err != nil {
+				return fmt.Errorf("failed to repair target rows: %w", err)
+			}
+			rp.repaired += int64(len(batch.rows))
+		} else {
+			for _, row := range batch.rows {
+				rp.emit(repairUpsertStatement(rp.targetTable, batch.columns, row))
+			}
+		}
+	}
+
+	if len(diff.extra) > 0 {
+		prefix := "DELETE FROM " + rp.targetTable
+		if rp.softDeleteColumn != "" {
+			prefix = fmt.Sprintf("UPDATE %s SET `%s` = CURRENT_TIMESTAMP", rp.targetTable, rp.softDeleteColumn)
+		}
+
+		if rp.mode == VerifyRepairApply {
+			query, args := keyedStatement(prefix, v.targetKeys, diff.extra)
+			if _, err := v.targetDB.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to remove extra target rows: %w", err)
+			}
+			rp.repaired += int64(len(diff.extra))
+		} else {
+			for _, key := range diff.extra {
+				rp.emit(fmt.Sprintf("%s WHERE (%s) = (%s);", prefix, quoteColumns(v.targetKeys), sqlLiterals(key)))
+			}
+		}
+	}
+	return nil
+}
+
+// emit saves a repair statement, up to maxRepairStatements
+func (rp *verifyRepairer) emit(statement string) {
+	if len(rp.statements) >= maxRepairStatements {
+		rp.truncated = true
+		return
+	}
+	rp.statements = append(rp.statements, statement)
+}
+
+// repairUpsertStatement renders the upsert of one row with literal values
+func repairUpsertStatement(table string, columns []string, row []interface{}) string {
+	updates := make([]string, len(columns))
+	for i, col := range columns {
+		updates[i] = fmt.Sprintf("`%s` = VALUES(`%s`)", col, col)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s;",
+		table, quoteColumns(columns), sqlLiterals(row), strings.Join(updates, ", "))
+}
+
+// sqlLiterals renders values as a comma separated list of MySQL literals
+func sqlLiterals(values []interface{}) string {
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literals[i] = sqlLiteral(value)
+	}
+	return strings.Join(literals, ", ")
+}
+
+// sqlLiteral renders a value read from MySQL as a literal. Bytes that aren't valid UTF-8 are
+// written as a hex literal so binary values survive the round trip.
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if !utf8.Valid(v) {
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+		return sqlStringLiteral(string(v))
+	case string:
+		return sqlStringLiteral(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return sqlStringLiteral(v.Format("2006-01-02 15:04:05.999999"))
+	}
+	return sqlStringLiteral(fmt.Sprint(value))
+}
+
+// columnExists reports whether a table of the target database has a column
+func (e *DefaultSyncEngine) columnExists(ctx context.Context, db *sqlx.DB, dbName, tableName, column string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	if err := db.GetContext(ctx, &count, query, dbName, tableName, column); err != nil {
+		return false, fmt.Errorf("failed to check column %s: %w", column, err)
+	}
+	return count > 0, nil
+}
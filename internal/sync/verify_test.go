@@ -0,0 +1,309 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVerifier(sourceDB, targetDB *sqlx.DB, chunkSize, leafRows int) *tableVerifier {
+	return &tableVerifier{
+		sourceDB:      sourceDB,
+		sourceTable:   "`shop`.`orders`",
+		sourceKeys:    []string{"id"},
+		sourceColumns: []string{"`id`", "`total`"},
+		targetDB:      targetDB,
+		targetTable:   "`replica`.`orders`",
+		targetKeys:    []string{"id"},
+		targetColumns: []string{"`id`", "`total`"},
+		chunkSize:     chunkSize,
+		leafRows:      leafRows,
+	}
+}
+
+func hashRows(rows ...[2]string) *sqlmock.Rows {
+	result := sqlmock.NewRows([]string{"id", "hash"})
+	for _, row := range rows {
+		result.AddRow(row[0], row[1])
+	}
+	return result
+}
+
+func TestRowHashExpression(t *testing.T) {
+	assert.Equal(t, "MD5(CONCAT_WS('#', `id`, MD5(`body`), CONCAT(ISNULL(`id`), ISNULL(MD5(`body`)))))",
+		rowHashExpression([]string{"`id`", "MD5(`body`)"}))
+}
+
+func TestVerifyColumns(t *testing.T) {
+	mapping := &TableMapping{
+		SourceTable: "customers",
+		ColumnRules: customerRules()[:3],
+		MaskingRules: []*MaskingRule{
+			{Column: "name", Strategy: MaskingRedact},
+		},
+	}
+	schema := customersSchema()
+	schema.Columns = append(schema.Columns, &ColumnInfo{Name: "notes", Type: "mediumtext", Nullable: true})
+	targetSchema, err := mapping.columnMapping().targetSchema(schema)
+	require.NoError(t, err)
+	masker, err := newRowMasker(mapping, nil, "secret", schema, targetSchema)
+	require.NoError(t, err)
+
+	source, target, skipped := verifyColumns(mapping, schema, masker)
+	assert.Equal(t, []string{"`id`", "`email`", "MD5(`notes`)", "'eu''west'"}, source)
+	assert.Equal(t, []string{"`customer_id`", "`email`", "MD5(`notes`)", "`region`"}, target)
+	assert.Equal(t, []string{"name"}, skipped)
+}
+
+func TestTableVerifier_Match(t *testing.T) {
+	_, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	verifier := newTestVerifier(sourceDB, targetDB, 2, 64)
+
+	// Chunk (-inf, 2]
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` ORDER BY `id` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 99))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 99))
+
+	// Last chunk (2, +inf)
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id`) > (?) ORDER BY `id` LIMIT 1 OFFSET 1")).WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(1, 7))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(1, 7))
+
+	var stats verifyStats
+	var progress []int64
+	verifier.progress = func(sourceRows int64) { progress = append(progress, sourceRows) }
+	err := verifier.run(context.Background(), &stats, func(diff *rowDiff) error {
+		t.Fatal("matching tables reported a difference")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, verifyStats{chunks: 2, sourceRows: 3, targetRows: 3}, stats)
+	assert.Equal(t, []int64{2, 3}, progress)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestTableVerifier_BisectsToRows(t *testing.T) {
+	_, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	verifier := newTestVerifier(sourceDB, targetDB, 100, 1)
+
+	// A single chunk: the source holds 1..4, the target 1, 2, a changed 3 and 5 in place of 4
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` ORDER BY `id` LIMIT 1 OFFSET 99")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COALESCE(BIT_XOR(")).WillReturnRows(fingerprintRows(4, 10))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COALESCE(BIT_XOR(")).WillReturnRows(fingerprintRows(4, 11))
+
+	// Split at the second source key; (-inf, 2] matches
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` ORDER BY `id` LIMIT 1 OFFSET 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 3))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) <= (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 3))
+
+	// (2, +inf) differs and is split again at 3
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 9))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) > (?)")).WithArgs("2").
+		WillReturnRows(fingerprintRows(2, 8))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `shop`.`orders` WHERE (`id`) > (?) ORDER BY `id` LIMIT 1 OFFSET 0")).WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("3"))
+
+	// (2, 3] holds one row per side, compared row by row
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) > (?) AND (`id`) <= (?)")).WithArgs("2", "3").
+		WillReturnRows(fingerprintRows(1, 5))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) > (?) AND (`id`) <= (?)")).WithArgs("2", "3").
+		WillReturnRows(fingerprintRows(1, 6))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, MD5(CONCAT_WS(")).WithArgs("2", "3").
+		WillReturnRows(hashRows([2]string{"3", "aa"}))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, MD5(CONCAT_WS(")).WithArgs("2", "3").
+		WillReturnRows(hashRows([2]string{"3", "bb"}))
+
+	// (3, +inf) holds 4 at the source and 5 at the target
+	sourceMock.ExpectQuery(regexp.QuoteMeta("FROM `shop`.`orders` WHERE (`id`) > (?)")).WithArgs("3").
+		WillReturnRows(fingerprintRows(1, 4))
+	targetMock.ExpectQuery(regexp.QuoteMeta("FROM `replica`.`orders` WHERE (`id`) > (?)")).WithArgs("3").
+		WillReturnRows(fingerprintRows(1, 2))
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, MD5(CONCAT_WS(")).WithArgs("3").
+		WillReturnRows(hashRows([2]string{"4", "cc"}))
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, MD5(CONCAT_WS(")).WithArgs("3").
+		WillReturnRows(hashRows([2]string{"5", "cc"}))
+
+	var stats verifyStats
+	var diffs []*rowDiff
+	err := verifier.run(context.Background(), &stats, func(diff *rowDiff) error {
+		diffs = append(diffs, diff)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, verifyStats{chunks: 1, mismatchedChunks: 1, sourceRows: 4, targetRows: 4}, stats)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, &rowDiff{different: [][]interface{}{{"3"}}}, diffs[0])
+	assert.Equal(t, &rowDiff{missing: [][]interface{}{{"4"}}, extra: [][]interface{}{{"5"}}}, diffs[1])
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestVerifyRepairer_Emit(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	verifier := newTestVerifier(sourceDB, targetDB, 100, 64)
+	verifier.sourceFilter = sqlCondition{clause: "status = 'paid'"}
+	repairer := &verifyRepairer{
+		engine:      engine,
+		verifier:    verifier,
+		mode:        VerifyRepairEmit,
+		selectList:  "`id`, `total`, `note`",
+		targetTable: verifier.targetTable,
+	}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, `total`, `note` FROM `shop`.`orders` WHERE (`id`) IN ((?), (?)) AND (status = 'paid')")).
+		WithArgs("4", "3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total", "note"}).
+			AddRow(int64(3), []byte("9.50"), nil).
+			AddRow(int64(4), []byte("1.00"), []byte{0xff, 0x00}))
+
+	err := repairer.repair(context.Background(), &rowDiff{
+		missing:   [][]interface{}{{"4"}},
+		extra:     [][]interface{}{{"5"}},
+		different: [][]interface{}{{"3"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"INSERT INTO `replica`.`orders` (`id`, `total`, `note`) VALUES (3, '9.50', NULL) " +
+			"ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `total` = VALUES(`total`), `note` = VALUES(`note`);",
+		"INSERT INTO `replica`.`orders` (`id`, `total`, `note`) VALUES (4, '1.00', X'ff00') " +
+			"ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `total` = VALUES(`total`), `note` = VALUES(`note`);",
+		"DELETE FROM `replica`.`orders` WHERE (`id`) = ('5');",
+	}, repairer.statements)
+	assert.Zero(t, repairer.repaired)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+
+	// Statements beyond the cap are dropped and flagged
+	repairer.statements = make([]string, maxRepairStatements)
+	repairer.emit("DELETE")
+	assert.Len(t, repairer.statements, maxRepairStatements)
+	assert.True(t, repairer.truncated)
+}
+
+func TestVerifyRepairer_Apply(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	verifier := newTestVerifier(sourceDB, targetDB, 100, 64)
+	repairer := &verifyRepairer{
+		engine:           engine,
+		verifier:         verifier,
+		mode:             VerifyRepairApply,
+		selectList:       "`id`, `total`",
+		targetDialect:    mysqlDialect{},
+		targetDBName:     "replica",
+		targetTableName:  "orders",
+		targetTable:      verifier.targetTable,
+		softDeleteColumn: "deleted_at",
+	}
+
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id`, `total` FROM `shop`.`orders` WHERE (`id`) IN ((?))")).WithArgs("4").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(int64(4), []byte("1.00")))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`, `total`) VALUES (?, ?) ON DUPLICATE KEY UPDATE")).
+		WithArgs(int64(4), []byte("1.00")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(regexp.QuoteMeta("UPDATE `replica`.`orders` SET `deleted_at` = CURRENT_TIMESTAMP WHERE (`id`) IN ((?))")).WithArgs("5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repairer.repair(context.Background(), &rowDiff{
+		missing: [][]interface{}{{"4"}},
+		extra:   [][]interface{}{{"5"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), repairer.repaired)
+	assert.Empty(t, repairer.statements)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, "NULL"},
+		{int64(-3), "-3"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{1.5, "1.5"},
+		{true, "1"},
+		{"it's", "'it''s'"},
+		{[]byte("text"), "'text'"},
+		{[]byte{0x00, 0xfe}, "X'00fe'"},
+		{time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC), "'2024-03-01 08:30:00'"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sqlLiteral(tt.value))
+	}
+}
+
+func TestJobWorker_ExecuteVerifyJob(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockMonitoring := &MockMonitoringService{}
+	mockSyncEngine := &MockSyncEngine{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	engine := NewJobEngine(mockRepo, logger, mockMonitoring, mockSyncEngine).(*JobEngineService)
+	worker := &JobWorker{engine: engine, logger: logger}
+
+	orders := &TableMapping{ID: "m1", SourceTable: "orders", TargetTable: "orders", Enabled: true}
+	users := &TableMapping{ID: "m2", SourceTable: "users", TargetTable: "users", Enabled: true}
+	skipped := &TableMapping{ID: "m3", SourceTable: "logs", TargetTable: "logs"}
+	job := &SyncJob{ID: "job-1", ConfigID: "config-1", Type: JobTypeVerify}
+	ctx := context.Background()
+
+	mockRepo.On("GetSyncConfig", ctx, "config-1").Return(&SyncConfig{ID: "config-1", Tables: []*TableMapping{orders, skipped, users}}, nil)
+	mockRepo.On("UpdateSyncJob", ctx, "job-1", job).Return(nil)
+	mockMonitoring.On("LogJobEvent", ctx, "job-1", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockMonitoring.On("UpdateTableProgress", ctx, "job-1", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockMonitoring.On("UpdateJobProgress", ctx, "job-1", mock.Anything).Return(nil)
+
+	// A mismatch is reported, a failing table is recorded and skipped
+	mismatch := &TableVerifyReport{JobID: "job-1", SourceTable: "orders", Status: VerifyStatusMismatch, MissingRows: 2}
+	failed := &TableVerifyReport{JobID: "job-1", SourceTable: "users", Status: VerifyStatusFailed, Error: "no primary key"}
+	mockSyncEngine.On("VerifyTable", mock.Anything, job, orders).Return(mismatch, nil)
+	mockSyncEngine.On("VerifyTable", mock.Anything, job, users).Return(failed, errors.New("no primary key"))
+	mockRepo.On("CreateVerifyReport", ctx, mismatch).Return(nil)
+	mockRepo.On("CreateVerifyReport", ctx, failed).Return(nil)
+
+	require.NoError(t, worker.executeVerifyJob(ctx, job))
+	assert.Equal(t, 2, job.TotalTables)
+	assert.Equal(t, 2, job.CompletedTables)
+	mockRepo.AssertExpectations(t)
+	mockSyncEngine.AssertExpectations(t)
+	mockMonitoring.AssertCalled(t, "LogJobEvent", ctx, "job-1", "orders", "warn",
+		"Table orders differs from its target: 2 missing, 0 extra, 0 different rows")
+
+	// Stopping on errors fails the job at the first failing table
+	job = &SyncJob{ID: "job-1", ConfigID: "config-1", Type: JobTypeVerify}
+	mockRepo.ExpectedCalls = nil
+	mockSyncEngine.ExpectedCalls = nil
+	mockRepo.On("GetSyncConfig", ctx, "config-1").Return(&SyncConfig{ID: "config-1", Tables: []*TableMapping{users, orders},
+		Options: &SyncOptions{ConflictResolution: ConflictResolutionError}}, nil)
+	mockRepo.On("UpdateSyncJob", ctx, "job-1", job).Return(nil)
+	mockRepo.On("CreateVerifyReport", ctx, failed).Return(nil)
+	mockSyncEngine.On("VerifyTable", mock.Anything, job, users).Return(failed, errors.New("no primary key"))
+
+	err := worker.executeVerifyJob(ctx, job)
+	assert.ErrorContains(t, err, "table verification failed for users")
+	assert.Equal(t, 0, job.CompletedTables)
+}
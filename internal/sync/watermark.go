@@ -0,0 +1,265 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// keyWatermarkMode marks checkpoint data holding the last synced key of an ID based incremental sync
+const keyWatermarkMode = "key_watermark"
+
+// keyWatermark is the largest key an incremental sync has synced, stored in SyncCheckpoint.CheckpointData.
+// Values are kept as text next to the column types so they bind the way the key columns compare,
+// whether the key is a BIGINT UNSIGNED, a CHAR(36) UUID, composite, or a timestamp and primary key.
+type keyWatermark struct {
+	Mode    string   `json:"mode"`
+	Columns []string `json:"watermark_columns"`
+	Types   []string `json:"watermark_types"`
+	Values  []string `json:"watermark_values,omitempty"` // Empty until the table has rows
+}
+
+// trackedKeyColumns splits the change column of an ID based incremental sync into its key columns:
+// the auto-increment column, or the comma separated primary key of a table without one
+func trackedKeyColumns(changeColumn string) []string {
+	return strings.Split(changeColumn, ",")
+}
+
+// loadWatermarkKey returns an empty watermark for the key an ID based incremental sync orders by
+func loadWatermarkKey(ctx context.Context, db *sqlx.DB, tableName, changeColumn string) (*keyWatermark, error) {
+	columns := trackedKeyColumns(changeColumn)
+
+	rows, err := db.QueryxContext(ctx, `
+		SELECT COLUMN_NAME, COLUMN_TYPE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_NAME = ?
+	`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query key column types: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes := make(map[string]string)
+	for rows.Next() {
+		var name, columnType string
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return nil, fmt.Errorf("failed to scan key column type: %w", err)
+		}
+		columnTypes[strings.ToLower(name)] = columnType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read key column types: %w", err)
+	}
+
+	key := &keyWatermark{Mode: keyWatermarkMode, Columns: columns, Types: make([]string, len(columns))}
+	for i, column := range columns {
+		columnType, ok := columnTypes[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("key column %s not found in table %s", column, tableName)
+		}
+		key.Types[i] = columnType
+	}
+	return key, nil
+}
+
+// trackingWatermarkKey returns an empty watermark for the change tracking column of an incremental
+// sync. A timestamp column is followed by the primary key, which orders rows sharing a timestamp so
+// that a checkpoint taken between them skips none.
+func (e *DefaultSyncEngine) trackingWatermarkKey(ctx context.Context, db *sqlx.DB, tableName, changeColumn, changeType string) (*keyWatermark, error) {
+	keyColumns := changeColumn
+	if changeType == "timestamp" {
+		primaryKeys, err := e.getPrimaryKeyColumns(ctx, db, tableName)
+		if err != nil {
+			e.logger.WithError(err).WithField("table_name", tableName).
+				Warn("No primary key to break timestamp ties, rows changed in the same second as the checkpoint may be skipped")
+		}
+		for _, column := range primaryKeys {
+			if column != changeColumn {
+				keyColumns += "," + column
+			}
+		}
+	}
+	return loadWatermarkKey(ctx, db, tableName, keyColumns)
+}
+
+// restore returns the last synced key a checkpoint holds for the key columns, nil to sync from the
+// start. Checkpoints written before watermarks were typed keep a single column value in LastSyncValue.
+func (w *keyWatermark) restore(checkpoint *SyncCheckpoint) []string {
+	if values, typed := w.restoreTyped(checkpoint); typed {
+		return values
+	}
+	if len(w.Columns) == 1 && checkpoint.LastSyncValue != "" {
+		return []string{checkpoint.LastSyncValue}
+	}
+	return nil
+}
+
+// restoreTyped returns the last synced key of a typed watermark and whether the checkpoint holds one
+func (w *keyWatermark) restoreTyped(checkpoint *SyncCheckpoint) ([]string, bool) {
+	var data keyWatermark
+	if err := json.Unmarshal([]byte(checkpoint.CheckpointData), &data); err != nil || data.Mode != keyWatermarkMode {
+		return nil, false
+	}
+	// A watermark of another key says nothing about this one
+	if !sameColumns(data.Columns, w.Columns) || len(data.Values) != len(w.Columns) {
+		return nil, true
+	}
+	return data.Values, true
+}
+
+// bind converts watermark values into bind arguments typed like the key columns
+func (w *keyWatermark) bind(values []string) []interface{} {
+	if values == nil {
+		return nil
+	}
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = bindKeyValue(w.Types[i], value)
+	}
+	return args
+}
+
+// changedRows selects the rows an incremental sync reads: those after the checkpoint's watermark
+type changedRows struct {
+	watermark *keyWatermark
+	where     string
+	args      []interface{}
+	since     string // The watermark rows are read after, empty when every row is read
+	until     string // The watermark rows are read up to, empty when there is no upper bound
+}
+
+// changedRowsSince returns the rows changed since a checkpoint. Rows after the last synced key are
+// read, comparing a composite key as a row constructor. With a look-back window, timestamp tracking
+// reads again every row of the last seconds before the watermark instead, catching rows of
+// transactions that committed after later ones were synced.
+func (e *DefaultSyncEngine) changedRowsSince(ctx context.Context, db *sqlx.DB, mapping *TableMapping, changeColumn, changeType string, checkpoint *SyncCheckpoint) (*changedRows, error) {
+	watermark, err := e.trackingWatermarkKey(ctx, db, mapping.SourceTable, changeColumn, changeType)
+	if err != nil {
+		return nil, err
+	}
+	rows := &changedRows{watermark: watermark}
+
+	if changeType != "timestamp" {
+		lastKey := watermark.restore(checkpoint)
+		rows.where, rows.args = keyRangeCondition(watermark.Columns, keyRange{lower: watermark.bind(lastKey)}, mapping.rowFilter())
+		rows.since = strings.Join(lastKey, ", ")
+		return rows, nil
+	}
+
+	lastKey, typed := watermark.restoreTyped(checkpoint)
+	lookback := time.Duration(mapping.LookbackSeconds) * time.Second
+	var since interface{}
+	switch {
+	case !typed:
+		// Checkpoints written before timestamp watermarks were typed hold the time of the last sync
+		if checkpoint.LastSyncTime.IsZero() {
+			break
+		}
+		last := checkpoint.LastSyncTime.Add(-lookback)
+		since = last
+		rows.since = last.Format("2006-01-02 15:04:05")
+	case lastKey == nil:
+	case lookback == 0:
+		rows.where, rows.args = keyRangeCondition(watermark.Columns, keyRange{lower: watermark.bind(lastKey)}, mapping.rowFilter())
+		rows.since = strings.Join(lastKey, ", ")
+		return rows, nil
+	default:
+		last, err := parseWatermarkTime(lastKey[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp watermark %q: %w", lastKey[0], err)
+		}
+		rows.since = last.Add(-lookback).Format("2006-01-02 15:04:05.999999")
+		since = rows.since
+	}
+
+	var lower []interface{}
+	if since != nil {
+		lower = []interface{}{since}
+	}
+	rows.where, rows.args = keyRangeCondition(watermark.Columns[:1], keyRange{lower: lower}, mapping.rowFilter())
+	return rows, nil
+}
+
+// upTo bounds the changed rows by the watermark of a later checkpoint, so the rows read are exactly
+// those between the two checkpoints
+func (r *changedRows) upTo(checkpoint *SyncCheckpoint) {
+	values, typed := r.watermark.restoreTyped(checkpoint)
+	if !typed || values == nil {
+		return
+	}
+	condition := fmt.Sprintf("(%s) <= (%s)", quoteColumns(r.watermark.Columns), keyPlaceholders(len(values)))
+	if r.where == "" {
+		r.where = " WHERE " + condition
+	} else {
+		r.where += " AND " + condition
+	}
+	r.args = append(r.args, r.watermark.bind(values)...)
+	r.until = strings.Join(values, ", ")
+}
+
+// query returns the query reading the changed rows in watermark order
+func (r *changedRows) query(selectList, table string) string {
+	return fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", selectList, table, r.where, quoteColumns(r.watermark.Columns))
+}
+
+// parseWatermarkTime parses the value of a timestamp, datetime or date column kept in a watermark
+func parseWatermarkTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format")
+}
+
+// loadLatest sets the watermark to the largest key of the source rows selected by filter, leaving it
+// empty when every row has a NULL timestamp
+func (w *keyWatermark) loadLatest(ctx context.Context, db *sqlx.DB, tableName string, filter sqlCondition) error {
+	order := make([]string, len(w.Columns))
+	for i, column := range w.Columns {
+		order[i] = fmt.Sprintf("`%s` DESC", column)
+	}
+	query := fmt.Sprintf("SELECT %s FROM `%s`%s ORDER BY %s LIMIT 1", quoteColumns(w.Columns), tableName, filter.where(), strings.Join(order, ", "))
+
+	rows, err := db.QueryxContext(ctx, query, filter.args...)
+	if err != nil {
+		return fmt.Errorf("failed to query latest key: %w", err)
+	}
+	defer rows.Close()
+
+	w.Values = nil
+	if rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return fmt.Errorf("failed to scan latest key: %w", err)
+		}
+		keyValues := make([]string, len(values))
+		for i, value := range values {
+			if value == nil {
+				return rows.Err()
+			}
+			keyValues[i] = formatKeyValue(value)
+		}
+		w.Values = keyValues
+	}
+	return rows.Err()
+}
+
+// checkpoint returns the incremental checkpoint of a mapping holding the watermark
+func (w *keyWatermark) checkpoint(mapping *TableMapping) (*SyncCheckpoint, error) {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key watermark: %w", err)
+	}
+	return &SyncCheckpoint{
+		ID:             mapping.ID,
+		TableMappingID: mapping.ID,
+		LastSyncValue:  strings.Join(w.Values, ","),
+		CheckpointData: string(data),
+	}, nil
+}
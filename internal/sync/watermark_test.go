@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unsignedConverter passes uint64 arguments through the way the MySQL driver does, the default
+// converter rejects those with the high bit set
+type unsignedConverter struct{}
+
+func (unsignedConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if n, ok := v.(uint64); ok {
+		return n, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestDetectChangeTrackingColumn_PrimaryKeyFallback(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+
+	sourceMock.ExpectQuery("modified_at").WithArgs("orders").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}))
+	sourceMock.ExpectQuery("auto_increment").WithArgs("orders").WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}))
+	sourceMock.ExpectQuery(testPrimaryKeyQuery).WithArgs("orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("tenant_id").AddRow("order_uuid"))
+
+	column, changeType, err := engine.detectChangeTrackingColumn(context.Background(), sourceDB, "orders")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_id,order_uuid", column)
+	assert.Equal(t, "primary_key", changeType)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestSyncIncrementalByIDBetweenDBs_CompositeKey(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders", WhereClause: "status = 'paid'"}
+	checkpoint := &SyncCheckpoint{
+		LastSyncValue:  "7,0190a6f2-5c1e-7000-8000-000000000001",
+		CheckpointData: `{"mode":"key_watermark","watermark_columns":["tenant_id","order_uuid"],"watermark_types":["int unsigned","char(36)"],"watermark_values":["7","0190a6f2-5c1e-7000-8000-000000000001"]}`,
+	}
+
+	expectKeyColumnTypes(sourceMock, "tenant_id", "int unsigned", "order_uuid", "char(36)", "status", "varchar(16)")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `tenant_id`, `order_uuid`, `status` FROM `shop`.`orders` WHERE (`tenant_id`, `order_uuid`) > (?, ?) AND (status = 'paid') ORDER BY `tenant_id`, `order_uuid`")).
+		WithArgs(uint64(7), "0190a6f2-5c1e-7000-8000-000000000001").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "order_uuid", "status"}).
+			AddRow(7, "0190a6f2-5c1e-7000-8000-000000000002", "paid").
+			AddRow(8, "0190a6f2-5c1e-7000-8000-000000000000", "paid"))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`tenant_id`, `order_uuid`, `status`)")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	synced, err := engine.syncIncrementalByIDBetweenDBs(context.Background(), sourceDB, "shop", targetDB, mysqlDialect{}, "replica", mapping,
+		"`tenant_id`, `order_uuid`, `status`", nil, nil, nil, "tenant_id,order_uuid", "primary_key", checkpoint, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), synced)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestSyncIncrementalByIDBetweenDBs_UnsignedBeyondInt64(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	source, sourceMock, err := sqlmock.New(sqlmock.ValueConverterOption(unsignedConverter{}))
+	require.NoError(t, err)
+	defer source.Close()
+	sourceDB := sqlx.NewDb(source, "sqlmock")
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+
+	// A checkpoint written before watermarks were typed only has LastSyncValue
+	checkpoint := &SyncCheckpoint{LastSyncValue: "18446744073709551000"}
+
+	expectKeyColumnTypes(sourceMock, "id", "bigint unsigned")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE (`id`) > (?) ORDER BY `id`")).
+		WithArgs(uint64(18446744073709551000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	synced, err := engine.syncIncrementalByIDBetweenDBs(context.Background(), sourceDB, "shop", targetDB, mysqlDialect{}, "replica", mapping,
+		"*", nil, nil, nil, "id", "auto_increment", checkpoint, nil, nil)
+	require.NoError(t, err)
+	assert.Zero(t, synced)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestLatestCheckpoint_KeyWatermarkRoundTrip(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders"}
+
+	expectKeyColumnTypes(sourceMock, "tenant_id", "int unsigned", "order_uuid", "char(36)")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `tenant_id`, `order_uuid` FROM `orders` ORDER BY `tenant_id` DESC, `order_uuid` DESC LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "order_uuid"}).AddRow([]byte("8"), []byte("0190a6f2-5c1e-7000-8000-000000000000")))
+
+	checkpoint, err := engine.latestCheckpoint(context.Background(), mapping, "tenant_id,order_uuid", "primary_key", sourceDB)
+	require.NoError(t, err)
+	assert.Equal(t, "mapping-1", checkpoint.TableMappingID)
+	assert.Equal(t, "8,0190a6f2-5c1e-7000-8000-000000000000", checkpoint.LastSyncValue)
+
+	var data keyWatermark
+	require.NoError(t, json.Unmarshal([]byte(checkpoint.CheckpointData), &data))
+	assert.Equal(t, keyWatermarkMode, data.Mode)
+	assert.Equal(t, []string{"int unsigned", "char(36)"}, data.Types)
+
+	// The watermark is restored for the same key only
+	key := &keyWatermark{Mode: keyWatermarkMode, Columns: []string{"tenant_id", "order_uuid"}, Types: data.Types}
+	assert.Equal(t, []string{"8", "0190a6f2-5c1e-7000-8000-000000000000"}, key.restore(checkpoint))
+	assert.Equal(t, []interface{}{uint64(8), "0190a6f2-5c1e-7000-8000-000000000000"}, key.bind(key.restore(checkpoint)))
+	other := &keyWatermark{Mode: keyWatermarkMode, Columns: []string{"order_uuid"}, Types: []string{"char(36)"}}
+	assert.Nil(t, other.restore(checkpoint))
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestLatestCheckpoint_AppliesRowFilter(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", WhereClause: "status = 'paid'"}
+
+	// Rows the mapping doesn't sync don't move the watermark
+	expectKeyColumnTypes(sourceMock, "id", "bigint")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT `id` FROM `orders` WHERE status = 'paid' ORDER BY `id` DESC LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(9)))
+
+	checkpoint, err := engine.latestCheckpoint(context.Background(), mapping, "id", "auto_increment", sourceDB)
+	require.NoError(t, err)
+	assert.Equal(t, "9", checkpoint.LastSyncValue)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestSyncIncrementalByIDBetweenDBs_BoundedByLatestCheckpoint(t *testing.T) {
+	engine, sourceDB, sourceMock, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{ID: "mapping-1", SourceTable: "orders", TargetTable: "orders"}
+	watermark := func(id string) *SyncCheckpoint {
+		return &SyncCheckpoint{
+			LastSyncValue:  id,
+			CheckpointData: `{"mode":"key_watermark","watermark_columns":["id"],"watermark_types":["bigint"],"watermark_values":["` + id + `"]}`,
+		}
+	}
+
+	// Rows committed after the new checkpoint was taken are left to the next run, which starts from it
+	expectKeyColumnTypes(sourceMock, "id", "bigint")
+	sourceMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `shop`.`orders` WHERE (`id`) > (?) AND (`id`) <= (?) ORDER BY `id`")).
+		WithArgs(int64(5), int64(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(6)).AddRow(int64(9)))
+	targetMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `replica`.`orders` (`id`) VALUES (?), (?)")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	synced, err := engine.syncIncrementalByIDBetweenDBs(context.Background(), sourceDB, "shop", targetDB, mysqlDialect{}, "replica", mapping,
+		"*", nil, nil, nil, "id", "auto_increment", watermark("5"), watermark("9"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), synced)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestChangeTrackingColumn_MappingOverride(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	trackingQuery := regexp.QuoteMeta("SELECT DATA_TYPE, EXTRA")
+
+	sourceMock.ExpectQuery(trackingQuery).WithArgs("orders", "paid_at").
+		WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE", "EXTRA"}).AddRow("datetime", ""))
+	column, changeType, err := engine.changeTrackingColumn(context.Background(), sourceDB, &TableMapping{SourceTable: "orders", TrackingColumn: "paid_at"})
+	require.NoError(t, err)
+	assert.Equal(t, "paid_at", column)
+	assert.Equal(t, "timestamp", changeType)
+
+	sourceMock.ExpectQuery(trackingQuery).WithArgs("orders", "note").
+		WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE", "EXTRA"}).AddRow("varchar", ""))
+	_, _, err = engine.changeTrackingColumn(context.Background(), sourceDB, &TableMapping{SourceTable: "orders", TrackingColumn: "note"})
+	assert.ErrorContains(t, err, "must be a timestamp, datetime, date or auto_increment column")
+
+	sourceMock.ExpectQuery(trackingQuery).WithArgs("orders", "gone").WillReturnRows(sqlmock.NewRows([]string{"DATA_TYPE", "EXTRA"}))
+	_, _, err = engine.changeTrackingColumn(context.Background(), sourceDB, &TableMapping{SourceTable: "orders", TrackingColumn: "gone"})
+	assert.ErrorContains(t, err, "tracking column gone not found in table orders")
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestChangedRowsSince_TimestampTieBreaker(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	checkpoint := &SyncCheckpoint{
+		CheckpointData: `{"mode":"key_watermark","watermark_columns":["updated_at","id"],"watermark_types":["datetime","bigint"],"watermark_values":["2024-01-15 10:30:00","41"]}`,
+	}
+
+	// Rows updated in the same second as the watermark but with a larger key are read
+	expectPrimaryKey(sourceMock, "id")
+	expectKeyColumnTypes(sourceMock, "id", "bigint", "updated_at", "datetime")
+	changed, err := engine.changedRowsSince(context.Background(), sourceDB, &TableMapping{SourceTable: "orders"}, "updated_at", "timestamp", checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE (`updated_at`, `id`) > (?, ?) ORDER BY `updated_at`, `id`", changed.query("*", "`orders`"))
+	assert.Equal(t, []interface{}{"2024-01-15 10:30:00", int64(41)}, changed.args)
+	assert.Equal(t, "2024-01-15 10:30:00, 41", changed.since)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
+
+func TestChangedRowsSince_Lookback(t *testing.T) {
+	engine, sourceDB, sourceMock, _, _ := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", WhereClause: "status = 'paid'", LookbackSeconds: 300}
+	checkpoint := &SyncCheckpoint{
+		CheckpointData: `{"mode":"key_watermark","watermark_columns":["updated_at","id"],"watermark_types":["datetime","bigint"],"watermark_values":["2024-01-15 10:30:00","41"]}`,
+	}
+
+	// The last five minutes before the watermark are read again
+	expectPrimaryKey(sourceMock, "id")
+	expectKeyColumnTypes(sourceMock, "id", "bigint", "updated_at", "datetime")
+	changed, err := engine.changedRowsSince(context.Background(), sourceDB, mapping, "updated_at", "timestamp", checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, " WHERE (`updated_at`) > (?) AND (status = 'paid')", changed.where)
+	assert.Equal(t, []interface{}{"2024-01-15 10:25:00"}, changed.args)
+	assert.NoError(t, sourceMock.ExpectationsWereMet())
+}
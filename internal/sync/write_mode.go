@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ColumnList is a list of column names, stored as JSON
+type ColumnList []string
+
+// Value stores a column list as JSON, an empty list as NULL
+func (l ColumnList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal column list: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan reads a column list stored as JSON
+func (l *ColumnList) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported column list type: %T", src)
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// conflictResolution returns how writes resolve rows whose key exists, overwrite unless set
+func (o *SyncOptions) conflictResolution() ConflictResolution {
+	if o == nil || o.ConflictResolution == "" {
+		return ConflictResolutionOverwrite
+	}
+	return o.ConflictResolution
+}
+
+// keepsTargetRows reports whether a full sync of the mapping writes into the target table as it is,
+// keeping its rows and target-only columns, instead of replacing its rows
+func (m *TableMapping) keepsTargetRows() bool {
+	return m.WriteMode != WriteModeDefault
+}
+
+// writePolicy is how a sync writes rows into a target table that may already hold them
+type writePolicy struct {
+	conflict      ConflictResolution // What happens to a row whose key exists
+	keyColumns    []string           // Primary key of the target table, never updated
+	updateColumns []string           // Columns an overwrite updates, every written column when empty
+	newerColumn   string             // Target tracking column; an overwrite skips rows whose stored value is newer
+}
+
+// newWritePolicy returns the write policy of a mapping loading a table with the target schema. The
+// default write mode follows the conflict resolution of the options; the upsert_newer mode needs the
+// source tracking column, which has to be a timestamp.
+func newWritePolicy(mapping *TableMapping, options *SyncOptions, targetSchema *TableSchema, trackingColumn, trackingType string) (*writePolicy, error) {
+	policy := &writePolicy{conflict: options.conflictResolution(), keyColumns: targetSchema.primaryKey()}
+	switch mapping.WriteMode {
+	case WriteModeDefault:
+		return policy, nil
+	case WriteModeAppend:
+		policy.conflict = ConflictResolutionSkip
+		return policy, nil
+	case WriteModeUpsert, WriteModeUpsertNewer:
+		policy.conflict = ConflictResolutionOverwrite
+	default:
+		return nil, fmt.Errorf("invalid write mode: %s", mapping.WriteMode)
+	}
+
+	written := make(map[string]string, len(targetSchema.Columns))
+	for _, col := range targetSchema.Columns {
+		written[strings.ToLower(col.Name)] = col.Name
+	}
+	for _, col := range mapping.UpdateColumns {
+		name, ok := written[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("update column %s is not a column the mapping writes to table %s", col, mapping.TargetTable)
+		}
+		policy.updateColumns = append(policy.updateColumns, name)
+	}
+
+	if mapping.WriteMode == WriteModeUpsertNewer {
+		if trackingType != "timestamp" {
+			return nil, fmt.Errorf("write mode %s requires a timestamp tracking column for table %s", mapping.WriteMode, mapping.SourceTable)
+		}
+		target, ok := mapping.columnMapping().targetName(trackingColumn)
+		if !ok {
+			return nil, fmt.Errorf("write mode %s requires tracking column %s, but column rules exclude it", mapping.WriteMode, trackingColumn)
+		}
+		policy.newerColumn = target
+	}
+	return policy, nil
+}
+
+// resolveWritePolicy returns the write policy of a mapping, looking up its tracking column when the
+// write mode compares it
+func (e *DefaultSyncEngine) resolveWritePolicy(ctx context.Context, sourceDB *sqlx.DB, mapping *TableMapping, options *SyncOptions, targetSchema *TableSchema) (*writePolicy, error) {
+	var trackingColumn, trackingType string
+	if mapping.WriteMode == WriteModeUpsertNewer {
+		var err error
+		if trackingColumn, trackingType, err = e.changeTrackingColumn(ctx, sourceDB, mapping); err != nil {
+			return nil, fmt.Errorf("failed to detect change tracking column: %w", err)
+		}
+	}
+	return newWritePolicy(mapping, options, targetSchema, trackingColumn, trackingType)
+}
+
+// clause returns the clause ending an INSERT of columns that resolves rows whose key exists. A nil
+// policy overwrites every column.
+func (p *writePolicy) clause(d Dialect, columns []string) string {
+	if p == nil {
+		return " " + d.UpsertClause(columns, nil)
+	}
+
+	// MySQL leaves a row as it is by setting any column to itself
+	anchor := p.keyColumns
+	if len(anchor) == 0 {
+		anchor = columns
+	}
+	switch p.conflict {
+	case ConflictResolutionError:
+		return ""
+	case ConflictResolutionSkip:
+		return " " + d.SkipDuplicatesClause(anchor)
+	}
+
+	updates := columns
+	if len(p.updateColumns) > 0 {
+		updates = nil
+		for _, col := range columns {
+			if isKeyColumn(col, p.updateColumns) {
+				updates = append(updates, col)
+			}
+		}
+	}
+	for _, col := range updates {
+		if !isKeyColumn(col, p.keyColumns) {
+			return " " + d.UpdateClause(p.keyColumns, updates, p.newerColumn)
+		}
+	}
+	// None of the written columns is updated
+	return " " + d.SkipDuplicatesClause(anchor)
+}
+
+// writeRowsToDB inserts rows holding their values in column order into a table of the target database,
+// or of a transaction on it, ending each INSERT with clause. Rows are split into as many INSERTs as the
+// dialect needs.
+func (e *DefaultSyncEngine) writeRowsToDB(ctx context.Context, targetDB sqlx.ExecerContext, d Dialect, targetDBName, tableName string, columns []string, rows [][]interface{}, clause string) error {
+	limit := d.MaxInsertRows(len(columns))
+	for len(rows) > 0 {
+		chunk := rows
+		if limit > 0 && len(chunk) > limit {
+			chunk = chunk[:limit]
+		}
+		rows = rows[len(chunk):]
+
+		query, args := d.InsertRowsStatement(targetDBName, tableName, columns, chunk)
+		if _, err := targetDB.ExecContext(ctx, query+clause, args...); err != nil {
+			return fmt.Errorf("failed to execute batch write: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeBatchToDB inserts a batch of rows into a table of the target database, or of a transaction
+// on it, resolving rows whose key exists per the write policy
+func (e *DefaultSyncEngine) writeBatchToDB(ctx context.Context, targetDB sqlx.ExecerContext, d Dialect, targetDBName, tableName string, columns []string, batch []map[string]interface{}, policy *writePolicy) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	query, args := d.InsertStatement(targetDBName, tableName, columns, batch)
+	query += policy.clause(d, columns)
+	if _, err := targetDB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to execute batch write: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWritePolicy_Clauses(t *testing.T) {
+	columns := []string{"id", "note", "created_at"}
+	newer := "IF(VALUES(`created_at`) >= `created_at` OR `created_at` IS NULL, VALUES(`%s`), `%s`)"
+
+	tests := []struct {
+		name          string
+		mapping       *TableMapping
+		options       *SyncOptions
+		trackingType  string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "default overwrites the non-key columns",
+			mapping:  &TableMapping{},
+			expected: " ON DUPLICATE KEY UPDATE `note` = VALUES(`note`), `created_at` = VALUES(`created_at`)",
+		},
+		{
+			name:     "default follows skip",
+			mapping:  &TableMapping{},
+			options:  &SyncOptions{ConflictResolution: ConflictResolutionSkip},
+			expected: " ON DUPLICATE KEY UPDATE `id` = `id`",
+		},
+		{
+			name:     "default follows error",
+			mapping:  &TableMapping{},
+			options:  &SyncOptions{ConflictResolution: ConflictResolutionError},
+			expected: "",
+		},
+		{
+			name:     "append skips existing rows whatever the conflict resolution",
+			mapping:  &TableMapping{WriteMode: WriteModeAppend},
+			options:  &SyncOptions{ConflictResolution: ConflictResolutionOverwrite},
+			expected: " ON DUPLICATE KEY UPDATE `id` = `id`",
+		},
+		{
+			name:     "upsert updates the listed columns",
+			mapping:  &TableMapping{WriteMode: WriteModeUpsert, UpdateColumns: ColumnList{"NOTE"}},
+			options:  &SyncOptions{ConflictResolution: ConflictResolutionSkip},
+			expected: " ON DUPLICATE KEY UPDATE `note` = VALUES(`note`)",
+		},
+		{
+			name:     "upsert of key columns only leaves rows as they are",
+			mapping:  &TableMapping{WriteMode: WriteModeUpsert, UpdateColumns: ColumnList{"id"}},
+			expected: " ON DUPLICATE KEY UPDATE `id` = `id`",
+		},
+		{
+			name:         "upsert_newer compares the tracking column, assigned last",
+			mapping:      &TableMapping{WriteMode: WriteModeUpsertNewer, TrackingColumn: "created_at"},
+			trackingType: "timestamp",
+			expected: " ON DUPLICATE KEY UPDATE `note` = " + fmt.Sprintf(newer, "note", "note") +
+				", `created_at` = " + fmt.Sprintf(newer, "created_at", "created_at"),
+		},
+		{
+			name:          "upsert_newer needs a timestamp",
+			mapping:       &TableMapping{SourceTable: "orders", WriteMode: WriteModeUpsertNewer},
+			trackingType:  "auto_increment",
+			expectedError: "write mode upsert_newer requires a timestamp tracking column for table orders",
+		},
+		{
+			name:          "update columns are written columns",
+			mapping:       &TableMapping{TargetTable: "orders", WriteMode: WriteModeUpsert, UpdateColumns: ColumnList{"notes"}},
+			expectedError: "update column notes is not a column the mapping writes to table orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newWritePolicy(tt.mapping, tt.options, ordersColumns("id", "note", "created_at"), "created_at", tt.trackingType)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.clause(mysqlDialect{}, columns))
+		})
+	}
+
+	// Without a policy every column is overwritten, like a replayed chunk of a full copy
+	var policy *writePolicy
+	assert.Equal(t, " ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `note` = VALUES(`note`), `created_at` = VALUES(`created_at`)",
+		policy.clause(mysqlDialect{}, columns))
+}
+
+func TestPrepareFullCopyTarget_WriteModeKeepsRowsAndColumns(t *testing.T) {
+	engine, _, _, targetDB, targetMock := newMockEngine(t)
+	mapping := &TableMapping{SourceTable: "orders", TargetTable: "orders", WriteMode: WriteModeUpsert,
+		SchemaPolicy: SchemaPolicyApprove, ApproveSchemaChanges: true}
+
+	targetMock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES")).WithArgs("replica", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+	expectTargetSchema(targetMock,
+		targetColumnRows().
+			AddRow("id", "bigint", "NO", nil, "auto_increment", nil, nil).
+			AddRow("note", "varchar(255)", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci").
+			AddRow("created_at", "datetime", "NO", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED", nil, nil).
+			AddRow("reviewer_notes", "text", "YES", nil, "", "utf8mb4", "utf8mb4_general_ci"),
+		targetIndexRows().AddRow("idx_created", "created_at", 1, "BTREE"))
+
+	// Neither TRUNCATE nor DROP COLUMN of the column only the target has
+	require.NoError(t, engine.prepareFullCopyTarget(context.Background(), targetDB, "replica", mapping, ordersColumns("id", "note", "created_at"), &fullCopyPlan{dialect: mysqlDialect{}}, false))
+	assert.NoError(t, targetMock.ExpectationsWereMet())
+}
+
+func TestValidateWriteMode(t *testing.T) {
+	assert.NoError(t, validateWriteMode(&TableMapping{SyncMode: SyncModeIncremental, WriteMode: WriteModeUpsert, UpdateColumns: ColumnList{"status"}}))
+	assert.EqualError(t, validateWriteMode(&TableMapping{WriteMode: "merge"}), "invalid write mode: merge")
+	assert.EqualError(t, validateWriteMode(&TableMapping{WriteMode: WriteModeAppend, UpdateColumns: ColumnList{"status"}}),
+		"update columns require the upsert or upsert_newer write mode")
+	assert.Error(t, validateWriteMode(&TableMapping{WriteMode: WriteModeUpsert, UpdateColumns: ColumnList{"a`b"}}))
+	assert.Error(t, validateWriteMode(&TableMapping{SyncMode: SyncModeCDC, WriteMode: WriteModeAppend}))
+
+	var columns ColumnList
+	require.NoError(t, columns.Scan([]byte(`["status","note"]`)))
+	assert.Equal(t, ColumnList{"status", "note"}, columns)
+	value, err := ColumnList(nil).Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}